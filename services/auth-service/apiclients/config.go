@@ -0,0 +1,130 @@
+package apiclients
+
+import (
+	"context"
+	"fmt"
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/constants"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Discovery types of upstreams
+const (
+	DiscoveryStatic = "static"
+	DiscoveryDNSSRV = "dns-srv"
+	DiscoveryFile   = "file"
+)
+
+// defaultRefreshInterval is the interval between lookups of dynamically discovered upstreams without one configured
+const defaultRefreshInterval = 30 * time.Second
+
+// UpstreamsFromConfig builds the registry of the configured upstreams and resolves their instances once.
+// When user-service is not among them, the base URL of the internal security configuration is used as
+// its only instance. It returns an error for an upstream configuration that Config.Validate would reject.
+func UpstreamsFromConfig(ctx context.Context, cfg config.Config) (*Upstreams, error) {
+	upstreams := make([]*Upstream, 0, len(cfg.Upstreams)+1)
+	for name, upstreamCfg := range cfg.Upstreams {
+		upstream, err := newUpstream(ctx, name, upstreamCfg)
+		if err != nil {
+			return nil, fmt.Errorf("upstream %s: %w", name, err)
+		}
+		upstreams = append(upstreams, upstream)
+	}
+
+	if _, ok := cfg.Upstreams[constants.UpstreamUserService]; !ok && cfg.InternalSecurity.BaseUrl != "" {
+		baseURL, err := url.Parse(cfg.InternalSecurity.BaseUrl)
+		if err != nil {
+			return nil, fmt.Errorf("internal security base URL: %w", err)
+		}
+		if baseURL.Host == "" {
+			return nil, fmt.Errorf("internal security base URL %q has no host", cfg.InternalSecurity.BaseUrl)
+		}
+		upstreams = append(upstreams, NewStaticUpstream(constants.UpstreamUserService, baseURL.Scheme, baseURL.Host))
+	}
+	return NewUpstreams(upstreams...), nil
+}
+
+// newUpstream builds one upstream from its configuration
+func newUpstream(ctx context.Context, name string, cfg config.UpstreamConfig) (*Upstream, error) {
+	refresh := cfg.RefreshInterval.Duration()
+
+	switch cfg.Discovery {
+	case DiscoveryStatic, "":
+		if len(cfg.Endpoints) == 0 {
+			return nil, fmt.Errorf("no endpoints configured")
+		}
+		for _, endpoint := range cfg.Endpoints {
+			if _, _, err := net.SplitHostPort(endpoint); err != nil {
+				return nil, err
+			}
+		}
+		return NewUpstream(ctx, name, cfg.Scheme, StaticResolver(cfg.Endpoints), 0), nil
+	case DiscoveryDNSSRV:
+		if cfg.SRV == "" {
+			return nil, fmt.Errorf("no SRV record configured")
+		}
+		if refresh <= 0 {
+			refresh = defaultRefreshInterval
+		}
+		return NewUpstream(ctx, name, cfg.Scheme, SRVResolver{Name: cfg.SRV}, refresh), nil
+	case DiscoveryFile:
+		if cfg.File == "" {
+			return nil, fmt.Errorf("no endpoints file configured")
+		}
+		if refresh <= 0 {
+			refresh = defaultRefreshInterval
+		}
+		return NewUpstream(ctx, name, cfg.Scheme, FileResolver{Path: cfg.File}, refresh), nil
+	default:
+		return nil, fmt.Errorf("unknown discovery type %q", cfg.Discovery)
+	}
+}
+
+// PoliciesFromConfig builds the outbound call policies from the application configuration.
+// Unset fields of the default fall back to DefaultPolicy, and unset fields of a target to the default.
+func PoliciesFromConfig(cfg config.OutboundConfig) Policies {
+	policies := Policies{
+		Default: policyFromConfig(cfg.Default, DefaultPolicy()),
+		Targets: make(map[string]Policy, len(cfg.Targets)),
+	}
+	for name, targetCfg := range cfg.Targets {
+		policies.Targets[name] = policyFromConfig(targetCfg, policies.Default)
+	}
+	return policies
+}
+
+// policyFromConfig overlays the configured fields of one policy on its fallback
+func policyFromConfig(cfg config.OutboundPolicyConfig, fallback Policy) Policy {
+	policy := fallback
+	if cfg.MaxAttempts > 0 {
+		policy.MaxAttempts = cfg.MaxAttempts
+	}
+	if cfg.MaxConcurrent > 0 {
+		policy.MaxConcurrent = cfg.MaxConcurrent
+	}
+	if cfg.FailureThreshold > 0 {
+		policy.FailureThreshold = cfg.FailureThreshold
+	}
+	if cfg.HalfOpenProbes > 0 {
+		policy.HalfOpenProbes = cfg.HalfOpenProbes
+	}
+
+	durations := []struct {
+		value config.Duration
+		dest  *time.Duration
+	}{
+		{cfg.Timeout, &policy.Timeout},
+		{cfg.InitialBackoff, &policy.InitialBackoff},
+		{cfg.MaxBackoff, &policy.MaxBackoff},
+		{cfg.QueueTimeout, &policy.QueueTimeout},
+		{cfg.OpenTimeout, &policy.OpenTimeout},
+	}
+	for _, d := range durations {
+		if d.value > 0 {
+			*d.dest = d.value.Duration()
+		}
+	}
+	return policy
+}
@@ -2,40 +2,56 @@ package apiclients
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"expvar"
 	config "github.com/Mir00r/auth-service/configs"
-	"io/ioutil"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/logging"
+	"github.com/Mir00r/auth-service/metrics"
+	"github.com/Mir00r/auth-service/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 )
 
 // WebClientConfig holds configuration for the HTTP client
 type WebClientConfig struct {
-	BaseURL        string
 	Timeout        time.Duration
 	Headers        map[string]string
 	AuthMiddleware func(req *http.Request)
 
-	//BaseURL  string
 	//Username string
 	//Password string
 	//HTTPClient *http.Client
 }
 
-// WebClient is a generic HTTP client structure
+// WebClient is a generic HTTP client structure. Calls are addressed by upstream name and balanced across
+// the discovered instances of the upstream under the resilience policy of each instance; copies of a WebClient
+// share the circuit breakers and bulkheads of the instances.
 type WebClient struct {
-	Config WebClientConfig
-	Client *http.Client
+	Config    WebClientConfig
+	Client    *http.Client
+	Upstreams *Upstreams
+	Policies  Policies
+	targets   *targetRegistry
 }
 
-// NewWebClient initializes a new WebClient
-func NewWebClient() WebClient {
+// NewWebClient initializes a new WebClient calling the given upstreams
+func NewWebClient(upstreams *Upstreams) WebClient {
+	policies := PoliciesFromConfig(config.AppConfig.Outbound)
 	return WebClient{
 		Config: WebClientConfig{
-			BaseURL: config.AppConfig.InternalSecurity.BaseUrl,
-			Timeout: 10 * time.Second,
+			Timeout: policies.Default.Timeout,
 			Headers: map[string]string{
 				"Authorization": "Basic " + basicAuth(config.AppConfig.InternalSecurity.UserName, config.AppConfig.InternalSecurity.Password),
 			},
@@ -46,24 +62,87 @@ func NewWebClient() WebClient {
 			//	Timeout: 10 * time.Second,
 			//},
 		},
-		Client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		// Timeouts are applied per attempt by the policy of the target
+		Client:    &http.Client{},
+		Upstreams: upstreams,
+	}.WithPolicies(policies)
+}
+
+// WithPolicies returns a copy of the client that calls its targets under the given policies,
+// with circuit breakers and bulkheads of its own
+func (wc WebClient) WithPolicies(policies Policies) WebClient {
+	wc.Policies = policies
+	wc.targets = newTargetRegistry(policies)
+	return wc
+}
+
+// Stats returns the circuit breaker state and call counters of every instance called so far
+func (wc *WebClient) Stats() []TargetStats {
+	if wc.targets == nil {
+		return []TargetStats{}
 	}
+	return wc.targets.stats()
 }
 
-// GetEndpoint dynamically resolves endpoints
-func (wc *WebClient) GetEndpoint(endpoint string) string {
-	return wc.Config.BaseURL + endpoint
+// PublishStats exposes Stats as an expvar variable with the given name
+func (wc *WebClient) PublishStats(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return wc.Stats()
+	}))
 }
 
-// Send sends a request and decodes the response
-func (wc *WebClient) Send(method, endpoint string, body interface{}, response interface{}) error {
-	url := wc.GetEndpoint(endpoint)
+// CallOption adjusts a single call made by Send
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	idempotent  bool
+	bearerToken string
+}
+
+// Idempotent marks a call whose method is not idempotent by definition, such as a POST,
+// as safe to retry because the target treats a replay of the same request as the original
+func Idempotent() CallOption {
+	return func(o *callOptions) {
+		o.idempotent = true
+	}
+}
+
+// BearerToken authenticates a call with the JWT of a signed-in user instead of the internal credentials
+func BearerToken(token string) CallOption {
+	return func(o *callOptions) {
+		o.bearerToken = token
+	}
+}
+
+// Send sends a request to path on an instance of the named upstream and decodes the response.
+//
+// Instances are picked round-robin, passing over those ejected because their circuit breaker is open,
+// and a retry goes to another instance when there is one. The call gives up when ctx is done, and the time
+// remaining until the deadline of ctx or of the attempt is sent in the X-Request-Timeout-Ms header so the
+// target can stop working on a call nobody waits for. Every attempt is recorded as a client span, and the
+// trace context and the request ID of ctx are sent along in the traceparent and X-Request-Id headers.
+// The duration of the call, retries included, is observed by upstream and outcome.
+// Idempotent calls are retried with exponential backoff and jitter when the target cannot be reached
+// or answers 429, 502, 503 or 504. Calls are rejected with an *UnavailableError without reaching the target
+// while its circuit breaker is open or its bulkhead is full, and non-2xx answers are returned as a *StatusError.
+func (wc *WebClient) Send(ctx context.Context, method, upstream, path string, body interface{}, response interface{}, opts ...CallOption) (err error) {
+	start := time.Now()
+	defer func() {
+		metrics.OutboundDuration.WithLabelValues(upstream, metrics.Method(method), outboundOutcome(err)).Observe(time.Since(start).Seconds())
+	}()
+
+	u, err := wc.Upstreams.Get(upstream)
+	if err != nil {
+		return err
+	}
+
+	call := callOptions{idempotent: idempotentMethods[method]}
+	for _, opt := range opts {
+		opt(&call)
+	}
 
 	// Serialize body if present
 	var requestBody []byte
-	var err error
 	if body != nil {
 		requestBody, err = json.Marshal(body)
 		if err != nil {
@@ -71,9 +150,76 @@ func (wc *WebClient) Send(method, endpoint string, body interface{}, response in
 		}
 	}
 
+	targets := wc.registry()
+	tried := map[string]bool{}
+	attempts := 1
+	for attempt := 1; ; attempt++ {
+		t, err := targets.pick(u, tried)
+		if err != nil {
+			return err
+		}
+		tried[t.name] = true
+		if attempt == 1 && call.idempotent && t.policy.MaxAttempts > 1 {
+			attempts = t.policy.MaxAttempts
+		}
+
+		err = wc.attempt(ctx, u.Name, t, call, method, u.url(t.name, path), requestBody, response)
+		if err == nil || attempt >= attempts || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+		t.retries.Add(1)
+
+		// Once every instance was tried, retries go round them again
+		if len(tried) >= len(u.Instances()) {
+			clear(tried)
+		}
+
+		backoff := time.NewTimer(t.policy.backoff(attempt))
+		select {
+		case <-backoff.C:
+		case <-ctx.Done():
+			backoff.Stop()
+			return err
+		}
+	}
+}
+
+// attempt performs one request to the target, recorded as a client span whose trace context is sent along
+func (wc *WebClient) attempt(ctx context.Context, upstream string, t *target, call callOptions, method, requestURL string, requestBody []byte, response interface{}) (err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, method+" "+upstream,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.URLFull(requestURL),
+			semconv.ServerAddress(t.name),
+			semconv.PeerService(upstream),
+		),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	done, err := t.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The attempt ends at its own timeout or the caller's deadline, whichever comes first
+	attemptCtx := ctx
+	if t.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, t.policy.Timeout)
+		defer cancel()
+	}
+
 	// Create HTTP request
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(attemptCtx, method, requestURL, bytes.NewReader(requestBody))
 	if err != nil {
+		done(outcomeAbandoned)
 		return err
 	}
 
@@ -82,30 +228,49 @@ func (wc *WebClient) Send(method, endpoint string, body interface{}, response in
 		req.Header.Set(key, value)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(attemptCtx, propagation.HeaderCarrier(req.Header))
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		req.Header.Set(constants.RequestIDHeader, requestID)
+	}
+	if deadline, ok := attemptCtx.Deadline(); ok {
+		req.Header.Set(constants.RequestTimeoutHeader, strconv.FormatInt(time.Until(deadline).Milliseconds(), 10))
+	}
 
 	// Apply authentication middlewares
 	if wc.Config.AuthMiddleware != nil {
 		wc.Config.AuthMiddleware(req)
 	}
+	if call.bearerToken != "" {
+		req.Header.Set(constants.Authorization, constants.Bearer+call.bearerToken)
+	}
 
 	// Perform HTTP request
 	resp, err := wc.Client.Do(req)
 	if err != nil {
+		done(failedOutcome(ctx))
 		return err
 	}
 	defer resp.Body.Close()
-
-	// Check for non-200 status codes
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return errors.New("received non-2xx response: " + resp.Status)
-	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
 
 	// Parse response body
-	responseBody, err := ioutil.ReadAll(resp.Body)
+	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
+		done(failedOutcome(ctx))
 		return err
 	}
 
+	// Check for non-200 status codes; only server errors count against the target
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if resp.StatusCode >= 500 {
+			done(failedOutcome(ctx))
+		} else {
+			done(outcomeSuccess)
+		}
+		return newStatusError(t.name, resp, responseBody)
+	}
+	done(outcomeSuccess)
+
 	// Deserialize response
 	if response != nil {
 		if err := json.Unmarshal(responseBody, response); err != nil {
@@ -116,6 +281,95 @@ func (wc *WebClient) Send(method, endpoint string, body interface{}, response in
 	return nil
 }
 
+// failedOutcome blames a failed attempt on the target unless the caller gave up first
+func failedOutcome(ctx context.Context) outcome {
+	if ctx.Err() != nil {
+		return outcomeAbandoned
+	}
+	return outcomeFailure
+}
+
+// registry returns the shared state of the instances, or a fresh one for a client built without NewWebClient
+func (wc *WebClient) registry() *targetRegistry {
+	if wc.targets == nil {
+		return newTargetRegistry(wc.Policies)
+	}
+	return wc.targets
+}
+
+// idempotentMethods lists the methods that are safe to retry by definition
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// retryable reports whether a failed attempt may succeed when repeated
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	// The target was not reached or did not answer in time
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// maxErrorBody bounds the raw error body kept on a StatusError
+const maxErrorBody = 1024
+
+// ErrorBody is the error envelope answered by the internal services
+type ErrorBody struct {
+	Code       int             `json:"code"`
+	CodeStatus string          `json:"codeStatus"`
+	Message    string          `json:"message"`
+	Data       json.RawMessage `json:"data,omitempty"`
+}
+
+// StatusError is returned by Send when the upstream answers with a non-2xx status
+type StatusError struct {
+	Target     string
+	StatusCode int
+	Status     string
+	Body       *ErrorBody // Decoded error body, nil when the answer was not an error envelope
+	RawBody    string     // Error body as received, truncated
+}
+
+// newStatusError builds the StatusError of a non-2xx answer
+func newStatusError(target string, resp *http.Response, body []byte) *StatusError {
+	statusErr := &StatusError{
+		Target:     target,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RawBody:    string(body[:min(len(body), maxErrorBody)]),
+	}
+
+	var errorBody ErrorBody
+	if err := json.Unmarshal(body, &errorBody); err == nil && (errorBody.Message != "" || errorBody.Code != 0) {
+		statusErr.Body = &errorBody
+	}
+	return statusErr
+}
+
+// Error implements the error interface
+func (e *StatusError) Error() string {
+	msg := "received non-2xx response: " + e.Status
+	if e.Target != "" {
+		msg = "received non-2xx response from " + e.Target + ": " + e.Status
+	}
+	if e.Body != nil && e.Body.Message != "" {
+		msg += ": " + e.Body.Message
+	}
+	return msg
+}
+
 // Example usage of BasicAuthMiddleware
 func BasicAuthMiddleware(username, password string) func(req *http.Request) {
 	return func(req *http.Request) {
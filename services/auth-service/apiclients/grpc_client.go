@@ -0,0 +1,257 @@
+package apiclients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/logging"
+	"github.com/Mir00r/auth-service/metrics"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// upstreamScheme is the target scheme of gRPC connections to an upstream, as in upstream:///user-service-grpc
+const upstreamScheme = "upstream"
+
+// maxGRPCAttempts is the most attempts gRPC accepts in a retry policy
+const maxGRPCAttempts = 5
+
+// GRPCCredentials authenticate the calls of a gRPC connection with basic auth in the authorization metadata
+type GRPCCredentials struct {
+	Username string
+	Password string
+}
+
+// NewGRPCConn creates a client connection to the named upstream. Calls are balanced round-robin across the
+// instances discovered for the upstream and carry the credentials, a request ID, the trace context and a deadline
+// of at most the timeout of the upstream's policy, which covers the retries as well. Their duration is observed
+// by method and status code.
+// Calls to the methods of idempotentServices, given by full service name, are retried with the backoff of the
+// policy when the instance is unavailable; other calls are only retried by gRPC when they never reached an instance.
+func NewGRPCConn(upstreams *Upstreams, name string, policy Policy, creds GRPCCredentials, idempotentServices ...string) (*grpc.ClientConn, error) {
+	u, err := upstreams.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceConfig, err := grpcServiceConfig(policy, idempotentServices)
+	if err != nil {
+		return nil, err
+	}
+
+	transportCreds := insecure.NewCredentials()
+	if u.Scheme == "https" {
+		transportCreds = credentials.NewTLS(nil)
+	}
+
+	return grpc.NewClient(upstreamScheme+":///"+name,
+		grpc.WithResolvers(upstreamResolverBuilder{upstreams: upstreams}),
+		grpc.WithTransportCredentials(transportCreds),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(
+			grpcMetrics(name),
+			grpcTracing(),
+			grpcLogging(),
+			grpcTimeout(policy.Timeout),
+			grpcBasicAuth(creds),
+		),
+	)
+}
+
+// grpcServiceConfig returns the service config balancing round-robin and retrying the idempotent services
+func grpcServiceConfig(policy Policy, idempotentServices []string) (string, error) {
+	type methodConfig struct {
+		Name        []map[string]string `json:"name"`
+		RetryPolicy map[string]any      `json:"retryPolicy"`
+	}
+	serviceConfig := struct {
+		LoadBalancingConfig []map[string]any `json:"loadBalancingConfig"`
+		MethodConfig        []methodConfig   `json:"methodConfig,omitempty"`
+	}{
+		LoadBalancingConfig: []map[string]any{{"round_robin": map[string]any{}}},
+	}
+
+	if attempts := min(policy.MaxAttempts, maxGRPCAttempts); attempts > 1 && len(idempotentServices) > 0 {
+		names := make([]map[string]string, 0, len(idempotentServices))
+		for _, service := range idempotentServices {
+			names = append(names, map[string]string{"service": service})
+		}
+		serviceConfig.MethodConfig = []methodConfig{{
+			Name: names,
+			RetryPolicy: map[string]any{
+				"maxAttempts":          attempts,
+				"initialBackoff":       durationJSON(policy.InitialBackoff),
+				"maxBackoff":           durationJSON(max(policy.MaxBackoff, policy.InitialBackoff)),
+				"backoffMultiplier":    2,
+				"retryableStatusCodes": []string{"UNAVAILABLE", "RESOURCE_EXHAUSTED"},
+			},
+		}}
+	}
+
+	encoded, err := json.Marshal(serviceConfig)
+	return string(encoded), err
+}
+
+// durationJSON formats a duration as a service config duration such as 0.1s
+func durationJSON(d time.Duration) string {
+	return fmt.Sprintf("%gs", max(d, time.Millisecond).Seconds())
+}
+
+// grpcMetrics observes the duration of every call to the upstream, retries included, by method and status code
+func grpcMetrics(upstream string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		metrics.OutboundDuration.WithLabelValues(upstream, method, status.Code(err).String()).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// grpcTracing sends a request ID with every call, continuing one already set on the outgoing metadata or
+// carried by the context of the incoming request
+func grpcTracing() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if len(md.Get(constants.RequestIDHeader)) == 0 {
+			requestID := logging.RequestID(ctx)
+			if requestID == "" {
+				requestID = uuid.NewString()
+			}
+			ctx = metadata.AppendToOutgoingContext(ctx, constants.RequestIDHeader, requestID)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// grpcLogging logs every call with its outcome and duration
+func grpcLogging() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		md, _ := metadata.FromOutgoingContext(ctx)
+		slog.InfoContext(logging.WithRequestID(ctx, firstValue(md.Get(constants.RequestIDHeader))), "gRPC call",
+			"method", method, "code", status.Code(err).String(), "duration_ms", float64(time.Since(start).Microseconds())/1000)
+		return err
+	}
+}
+
+// grpcTimeout bounds a call by the timeout, or by the caller's deadline when that comes first. gRPC sends the
+// deadline along, so user-service stops working on a call nobody waits for.
+func grpcTimeout(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// grpcBasicAuth authenticates every call with the credentials
+func grpcBasicAuth(creds GRPCCredentials) grpc.UnaryClientInterceptor {
+	authorization := "Basic " + basicAuth(creds.Username, creds.Password)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, constants.Authorization, authorization)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// firstValue returns the first of the values, if any
+func firstValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// upstreamResolverBuilder resolves upstream:///<name> targets to the instances discovered for the upstream
+type upstreamResolverBuilder struct {
+	upstreams *Upstreams
+}
+
+// Build implements resolver.Builder
+func (b upstreamResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	u, err := b.upstreams.Get(target.Endpoint())
+	if err != nil {
+		return nil, err
+	}
+
+	r := &upstreamResolver{upstream: u, cc: cc, done: make(chan struct{})}
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	go r.watch()
+	return r, nil
+}
+
+// Scheme implements resolver.Builder
+func (b upstreamResolverBuilder) Scheme() string {
+	return upstreamScheme
+}
+
+// upstreamResolver passes the instances of an upstream on to a gRPC connection whenever they change
+type upstreamResolver struct {
+	upstream *Upstream
+	cc       resolver.ClientConn
+	done     chan struct{}
+	closed   sync.Once
+
+	mu   sync.Mutex
+	last []string
+}
+
+// ResolveNow implements resolver.Resolver
+func (r *upstreamResolver) ResolveNow(resolver.ResolveNowOptions) {
+	instances := r.upstream.Instances()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.last != nil && slices.Equal(instances, r.last) {
+		return
+	}
+	r.last = instances
+
+	if len(instances) == 0 {
+		r.cc.ReportError(fmt.Errorf("%s: %w", r.upstream.Name, ErrNoInstances))
+		return
+	}
+	addresses := make([]resolver.Address, 0, len(instances))
+	for _, instance := range instances {
+		addresses = append(addresses, resolver.Address{Addr: instance})
+	}
+	if err := r.cc.UpdateState(resolver.State{Addresses: addresses}); err != nil {
+		slog.Warn("Failed to update the gRPC instances of upstream", "upstream", r.upstream.Name, "error", err)
+	}
+}
+
+// watch passes the instances of the upstream on whenever they change until the resolver is closed.
+// The connection balances its calls evenly over them, whatever their priority and weight.
+func (r *upstreamResolver) watch() {
+	for {
+		changed := r.upstream.changed()
+		r.ResolveNow(resolver.ResolveNowOptions{})
+		select {
+		case <-r.done:
+			return
+		case <-changed:
+		}
+	}
+}
+
+// Close implements resolver.Resolver
+func (r *upstreamResolver) Close() {
+	r.closed.Do(func() {
+		close(r.done)
+	})
+}
@@ -0,0 +1,87 @@
+package apiclients
+
+import (
+	"errors"
+	"github.com/Mir00r/auth-service/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"net/http"
+)
+
+var (
+	breakerStateDesc = prometheus.NewDesc("outbound_circuit_breaker_state",
+		"Circuit breaker state of an upstream instance: 1 for the current state (closed, open, half-open), 0 otherwise.",
+		[]string{"upstream", "target", "state"}, nil)
+	consecutiveFailuresDesc = prometheus.NewDesc("outbound_consecutive_failures",
+		"Consecutive failed attempts counted by the circuit breaker of an upstream instance.",
+		[]string{"upstream", "target"}, nil)
+	inFlightDesc = prometheus.NewDesc("outbound_requests_in_flight",
+		"Attempts in flight to an upstream instance.",
+		[]string{"upstream", "target"}, nil)
+	attemptsDesc = prometheus.NewDesc("outbound_attempts_total",
+		"Attempts sent to an upstream instance.",
+		[]string{"upstream", "target"}, nil)
+	failuresDesc = prometheus.NewDesc("outbound_failures_total",
+		"Attempts to an upstream instance that failed with a transport error or a 5xx.",
+		[]string{"upstream", "target"}, nil)
+	retriesDesc = prometheus.NewDesc("outbound_retries_total",
+		"Failed attempts to an upstream instance that were retried.",
+		[]string{"upstream", "target"}, nil)
+	rejectedDesc = prometheus.NewDesc("outbound_rejected_total",
+		"Calls to an upstream instance rejected by its circuit breaker or bulkhead.",
+		[]string{"upstream", "target"}, nil)
+)
+
+// breakerStates are the states reported by the breaker state gauge
+var breakerStates = []string{BreakerClosed, BreakerOpen, BreakerHalfOpen}
+
+// statsCollector exposes the Stats of a WebClient as Prometheus metrics, read when they are scraped
+type statsCollector struct {
+	client *WebClient
+}
+
+// StatsCollector returns a collector exposing the circuit breaker state and call counters of every instance
+// called so far. The instances are those discovered for the configured upstreams, which bounds the series.
+func (wc *WebClient) StatsCollector() prometheus.Collector {
+	return statsCollector{client: wc}
+}
+
+// Describe implements prometheus.Collector
+func (c statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{breakerStateDesc, consecutiveFailuresDesc, inFlightDesc, attemptsDesc, failuresDesc, retriesDesc, rejectedDesc} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c statsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stats := range c.client.Stats() {
+		for _, state := range breakerStates {
+			value := 0.0
+			if stats.BreakerState == state {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(breakerStateDesc, prometheus.GaugeValue, value, stats.Upstream, stats.Target, state)
+		}
+		ch <- prometheus.MustNewConstMetric(consecutiveFailuresDesc, prometheus.GaugeValue, float64(stats.ConsecutiveFailures), stats.Upstream, stats.Target)
+		ch <- prometheus.MustNewConstMetric(inFlightDesc, prometheus.GaugeValue, float64(stats.InFlight), stats.Upstream, stats.Target)
+		ch <- prometheus.MustNewConstMetric(attemptsDesc, prometheus.CounterValue, float64(stats.Calls), stats.Upstream, stats.Target)
+		ch <- prometheus.MustNewConstMetric(failuresDesc, prometheus.CounterValue, float64(stats.Failures), stats.Upstream, stats.Target)
+		ch <- prometheus.MustNewConstMetric(retriesDesc, prometheus.CounterValue, float64(stats.Retries), stats.Upstream, stats.Target)
+		ch <- prometheus.MustNewConstMetric(rejectedDesc, prometheus.CounterValue, float64(stats.Rejected), stats.Upstream, stats.Target)
+	}
+}
+
+// outboundOutcome returns the outcome label of a call: the status class of the answer, or why there was none
+func outboundOutcome(err error) string {
+	var statusErr *StatusError
+	var unavailableErr *UnavailableError
+	switch {
+	case err == nil:
+		return metrics.StatusClass(http.StatusOK)
+	case errors.As(err, &statusErr):
+		return metrics.StatusClass(statusErr.StatusCode)
+	case errors.As(err, &unavailableErr):
+		return metrics.OutcomeRejected
+	}
+	return metrics.OutcomeError
+}
@@ -0,0 +1,507 @@
+// Package openapi generates typed Go clients from OpenAPI 3.0 documents. It understands the subset the services
+// of this repository use: JSON bodies described by component schemas, path parameters, Basic and bearer
+// security, and an envelope schema that wraps the result of an operation in its data property.
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Config selects what is generated
+type Config struct {
+	Package        string   // Go package of the generated file
+	Generator      string   // Command named in the generated code header
+	Source         string   // Spec named in the generated code header
+	Tags           []string // Operations with one of the tags are generated, every operation when empty
+	Envelope       string   // Schema wrapping results in its data property, e.g. APIResponse
+	RuntimePackage string   // Import path of the package providing Idempotent and BearerToken call options
+}
+
+// operationMethods are the keys of a path item that describe operations, in the order they are generated
+var operationMethods = []string{"get", "put", "post", "delete", "patch", "head", "options"}
+
+// pathParam matches a path parameter such as {userId}
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// initialisms are written in upper case in Go names
+var initialisms = map[string]bool{"api": true, "id": true, "jwt": true, "mfa": true, "oidc": true, "url": true, "http": true}
+
+// method is one generated client method
+type method struct {
+	name       string
+	summary    string
+	httpMethod string
+	path       string
+	pathParams []string
+	bearer     bool
+	idempotent bool
+	body       string // Go type of the request body, none when empty
+	result     string // Go type of the result, none when empty
+	enveloped  bool   // The result is the data of the envelope
+}
+
+type generator struct {
+	doc     *document
+	cfg     Config
+	buf     bytes.Buffer
+	methods []method
+	used    map[string]bool // Schemas the generated methods depend on
+}
+
+// Generate returns the gofmt-ed source of a client for the operations of the spec selected by cfg
+func Generate(spec []byte, cfg Config) ([]byte, error) {
+	var doc document
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+
+	g := &generator{doc: &doc, cfg: cfg, used: map[string]bool{}}
+	if err := g.collect(); err != nil {
+		return nil, err
+	}
+	if err := g.write(); err != nil {
+		return nil, err
+	}
+
+	source, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not compile: %w", err)
+	}
+	return source, nil
+}
+
+// collect builds the methods of the selected operations and marks the schemas they use
+func (g *generator) collect() error {
+	for _, path := range g.doc.Paths {
+		for _, httpMethod := range operationMethods {
+			op, ok := path.Value.get(httpMethod)
+			if !ok || !g.selected(op) {
+				continue
+			}
+			m, err := g.method(path.Key, httpMethod, op)
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", strings.ToUpper(httpMethod), path.Key, err)
+			}
+			g.methods = append(g.methods, m)
+		}
+	}
+
+	for _, m := range g.methods {
+		for _, name := range []string{m.body, m.result} {
+			if err := g.use(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// selected reports whether an operation has one of the configured tags
+func (g *generator) selected(op *operation) bool {
+	if len(g.cfg.Tags) == 0 {
+		return true
+	}
+	for _, tag := range op.Tags {
+		if slices.Contains(g.cfg.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// method describes the client method of an operation
+func (g *generator) method(path, httpMethod string, op *operation) (method, error) {
+	if op.OperationID == "" {
+		return method{}, fmt.Errorf("operationId is missing")
+	}
+	m := method{
+		name:       goName(op.OperationID),
+		summary:    op.Summary,
+		httpMethod: httpMethod,
+		path:       path,
+		idempotent: op.Idempotent,
+	}
+
+	for _, requirement := range op.Security {
+		if _, ok := requirement["bearerAuth"]; ok {
+			m.bearer = true
+		}
+	}
+
+	for _, p := range op.Parameters {
+		resolved, err := g.doc.parameter(p)
+		if err != nil {
+			return method{}, err
+		}
+		if resolved.In != "path" {
+			return method{}, fmt.Errorf("%s parameter %q is not supported", resolved.In, resolved.Name)
+		}
+		m.pathParams = append(m.pathParams, resolved.Name)
+	}
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		if !slices.Contains(m.pathParams, match[1]) {
+			return method{}, fmt.Errorf("path parameter %q is not declared", match[1])
+		}
+	}
+
+	if op.RequestBody != nil {
+		body, err := g.jsonSchema(op.RequestBody.Content)
+		if err != nil {
+			return method{}, fmt.Errorf("request body: %w", err)
+		}
+		if body.Ref == "" {
+			return method{}, fmt.Errorf("request body must reference a component schema")
+		}
+		if m.body, _, err = g.doc.schema(body); err != nil {
+			return method{}, err
+		}
+	}
+
+	var err error
+	m.result, m.enveloped, err = g.result(op)
+	return m, err
+}
+
+// result returns the Go type of the result of the first 2xx response of an operation
+func (g *generator) result(op *operation) (string, bool, error) {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return "", false, fmt.Errorf("no successful response")
+	}
+	sort.Strings(codes)
+
+	resp, err := g.doc.response(op.Responses[codes[0]])
+	if err != nil {
+		return "", false, err
+	}
+	if len(resp.Content) == 0 {
+		return "", false, nil
+	}
+	s, err := g.jsonSchema(resp.Content)
+	if err != nil {
+		return "", false, fmt.Errorf("response %s: %w", codes[0], err)
+	}
+
+	if s.Ref != "" {
+		name, _, err := g.doc.schema(s)
+		return name, false, err
+	}
+
+	// allOf of the envelope and an object overriding the type of its data
+	if g.cfg.Envelope != "" && len(s.AllOf) == 2 && s.AllOf[0].Ref != "" {
+		envelope, _, err := g.doc.schema(s.AllOf[0])
+		if err != nil {
+			return "", false, err
+		}
+		data, ok := s.AllOf[1].Properties.get("data")
+		if envelope == g.cfg.Envelope && ok {
+			if data.Ref == "" {
+				return "", true, nil // The data is only a message
+			}
+			name, _, err := g.doc.schema(data)
+			return name, true, err
+		}
+	}
+	return "", false, fmt.Errorf("response %s must reference a component schema or wrap one in %s", codes[0], g.cfg.Envelope)
+}
+
+// jsonSchema returns the schema of the application/json content
+func (g *generator) jsonSchema(content map[string]*mediaType) (*schema, error) {
+	media, ok := content["application/json"]
+	if !ok || media.Schema == nil {
+		return nil, fmt.Errorf("only application/json content is supported")
+	}
+	return media.Schema, nil
+}
+
+// use marks a schema and the schemas it references as used
+func (g *generator) use(name string) error {
+	if name == "" || g.used[name] {
+		return nil
+	}
+	_, s, err := g.doc.schema(&schema{Ref: "#/components/schemas/" + name})
+	if err != nil {
+		return err
+	}
+	g.used[name] = true
+	for _, prop := range s.Properties {
+		for item := prop.Value; item != nil; item = item.Items {
+			if item.Ref != "" {
+				ref, _, err := g.doc.schema(item)
+				if err != nil {
+					return err
+				}
+				if err := g.use(ref); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// write emits the client source
+func (g *generator) write() error {
+	g.printf("// Code generated by %s from %s version %s. DO NOT EDIT.\n\n", g.cfg.Generator, g.cfg.Source, g.doc.Info.Version)
+	g.printf("package %s\n\n", g.cfg.Package)
+	g.writeImports()
+
+	g.printf("// APIVersion is the version of the %s API the client was generated from\n", g.doc.Info.Title)
+	g.printf("const APIVersion = %q\n\n", g.doc.Info.Version)
+
+	for _, s := range g.doc.Components.Schemas {
+		if !g.used[s.Key] {
+			continue
+		}
+		if err := g.writeType(s.Key, s.Value); err != nil {
+			return fmt.Errorf("schema %s: %w", s.Key, err)
+		}
+	}
+
+	if slices.ContainsFunc(g.methods, func(m method) bool { return m.enveloped }) {
+		g.printf("// envelope is the %s wrapping the result of an operation\n", g.cfg.Envelope)
+		g.printf("type envelope[T any] struct {\n\tData T `json:\"data\"`\n}\n\n")
+	}
+
+	for _, m := range g.methods {
+		g.writeMethod(m)
+	}
+	return nil
+}
+
+// writeImports emits the imports the generated code needs
+func (g *generator) writeImports() {
+	imports := []string{"context", "net/http"}
+	if slices.ContainsFunc(g.methods, func(m method) bool { return len(m.pathParams) > 0 }) {
+		imports = append(imports, "net/url")
+	}
+	for name := range g.used {
+		_, s, _ := g.doc.schema(&schema{Ref: "#/components/schemas/" + name})
+		if slices.ContainsFunc(s.Properties, func(p entry[*schema]) bool { return p.Value.Format == "date-time" }) {
+			imports = append(imports, "time")
+			break
+		}
+	}
+	if slices.ContainsFunc(g.methods, func(m method) bool { return m.idempotent || m.bearer }) {
+		imports = append(imports, g.cfg.RuntimePackage)
+	}
+	sort.Strings(imports)
+
+	g.printf("import (\n")
+	for _, path := range imports {
+		g.printf("\t%q\n", path)
+	}
+	g.printf(")\n\n")
+}
+
+// writeType emits the struct of a schema
+func (g *generator) writeType(name string, s *schema) error {
+	if s.Type != "object" {
+		return fmt.Errorf("only object schemas are supported")
+	}
+	g.printf("// %s %s\n", name, docText(s.Description, "is the "+name+" schema"))
+	g.printf("type %s struct {\n", name)
+	for _, prop := range s.Properties {
+		required := slices.Contains(s.Required, prop.Key)
+		fieldType, err := g.goType(prop.Value)
+		if err != nil {
+			return fmt.Errorf("property %s: %w", prop.Key, err)
+		}
+		fieldName := prop.Value.GoName
+		if fieldName == "" {
+			fieldName = goName(prop.Key)
+		}
+		tag := prop.Key
+		if !required {
+			tag += ",omitempty"
+		}
+		g.printf("\t%s %s `json:%q`", fieldName, fieldType, tag)
+		if prop.Value.Description != "" {
+			g.printf(" // %s", prop.Value.Description)
+		}
+		g.printf("\n")
+	}
+	g.printf("}\n\n")
+	return nil
+}
+
+// goType returns the Go type of a property schema
+func (g *generator) goType(s *schema) (string, error) {
+	var goType string
+	switch {
+	case s.Ref != "":
+		name, _, err := g.doc.schema(s)
+		if err != nil {
+			return "", err
+		}
+		goType = name
+	case s.Type == "string" && s.Format == "date-time":
+		goType = "time.Time"
+	case s.Type == "string":
+		goType = "string"
+	case s.Type == "boolean":
+		goType = "bool"
+	case s.Type == "integer" && s.Format == "int64":
+		goType = "int64"
+	case s.Type == "integer" && s.Format == "int32":
+		goType = "int32"
+	case s.Type == "integer":
+		goType = "int"
+	case s.Type == "number":
+		goType = "float64"
+	case s.Type == "array" && s.Items != nil:
+		item, err := g.goType(s.Items)
+		if err != nil {
+			return "", err
+		}
+		goType = "[]" + item
+	default:
+		return "", fmt.Errorf("type %q is not supported; describe nested objects as component schemas", s.Type)
+	}
+	if s.Nullable {
+		goType = "*" + goType
+	}
+	return goType, nil
+}
+
+// writeMethod emits the client method of an operation
+func (g *generator) writeMethod(m method) {
+	g.printf("// %s %s\n//\n// %s %s\n", m.name, docText(m.summary, "calls the "+m.name+" operation"), strings.ToUpper(m.httpMethod), m.path)
+
+	params := []string{"ctx context.Context"}
+	if m.bearer {
+		params = append(params, "token string")
+	}
+	for _, p := range m.pathParams {
+		params = append(params, lowerFirst(goName(p))+" string")
+	}
+	body := "nil"
+	if m.body != "" {
+		params = append(params, "body "+m.body)
+		body = "body"
+	}
+
+	var opts []string
+	if m.idempotent {
+		opts = append(opts, "apiclients.Idempotent()")
+	}
+	if m.bearer {
+		opts = append(opts, "apiclients.BearerToken(token)")
+	}
+	args := fmt.Sprintf("ctx, http.Method%s, %s, %s", methodConst(m.httpMethod), pathExpr(m.path), body)
+
+	switch {
+	case m.result == "":
+		g.printf("func (c *Client) %s(%s) error {\n", m.name, strings.Join(params, ", "))
+		g.printf("\treturn c.send(%s, nil%s)\n}\n\n", args, joinOpts(opts))
+	default:
+		responseType, data := m.result, "response"
+		if m.enveloped {
+			responseType, data = "envelope["+m.result+"]", "response.Data"
+		}
+		g.printf("func (c *Client) %s(%s) (*%s, error) {\n", m.name, strings.Join(params, ", "), m.result)
+		g.printf("\tvar response %s\n", responseType)
+		g.printf("\tif err := c.send(%s, &response%s); err != nil {\n\t\treturn nil, err\n\t}\n", args, joinOpts(opts))
+		g.printf("\treturn &%s, nil\n}\n\n", data)
+	}
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// pathExpr returns the Go expression building a path, escaping its parameters
+func pathExpr(path string) string {
+	var parts []string
+	last := 0
+	for _, loc := range pathParam.FindAllStringSubmatchIndex(path, -1) {
+		if loc[0] > last {
+			parts = append(parts, strconv.Quote(path[last:loc[0]]))
+		}
+		parts = append(parts, "url.PathEscape("+lowerFirst(goName(path[loc[2]:loc[3]]))+")")
+		last = loc[1]
+	}
+	if last < len(path) {
+		parts = append(parts, strconv.Quote(path[last:]))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// methodConst returns the suffix of the net/http constant of a method, e.g. Post for post
+func methodConst(httpMethod string) string {
+	return strings.ToUpper(httpMethod[:1]) + httpMethod[1:]
+}
+
+func joinOpts(opts []string) string {
+	if len(opts) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(opts, ", ")
+}
+
+// docText returns a description that continues a doc comment starting with the documented name
+func docText(description, fallback string) string {
+	description = strings.TrimSpace(strings.Join(strings.Fields(description), " "))
+	if description == "" {
+		return fallback
+	}
+	description = strings.TrimSuffix(description, ".")
+	// Lower the first word unless it is an initialism such as JWT
+	if len(description) > 1 && unicode.IsUpper(rune(description[0])) && !unicode.IsUpper(rune(description[1])) {
+		description = strings.ToLower(description[:1]) + description[1:]
+	}
+	return description
+}
+
+// goName returns the exported Go name of a camelCase name, e.g. UserID for userId
+func goName(name string) string {
+	var words []string
+	start := 0
+	for i, r := range name {
+		if i > start && unicode.IsUpper(r) {
+			words = append(words, name[start:i])
+			start = i
+		}
+	}
+	words = append(words, name[start:])
+
+	var b strings.Builder
+	for _, word := range words {
+		if initialisms[strings.ToLower(word)] {
+			b.WriteString(strings.ToUpper(word))
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+// lowerFirst returns the unexported form of a Go name, e.g. userID for UserID and idToken for IDToken
+func lowerFirst(name string) string {
+	end := 0
+	for end < len(name) && unicode.IsUpper(rune(name[end])) {
+		end++
+	}
+	switch {
+	case end == len(name):
+		return strings.ToLower(name)
+	case end > 1:
+		// An initialism followed by a word keeps the capital of the word
+		return strings.ToLower(name[:end-1]) + name[end-1:]
+	}
+	return strings.ToLower(name[:end]) + name[end:]
+}
@@ -0,0 +1,160 @@
+package openapi
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+// document is the subset of an OpenAPI 3.0 document the generator understands
+type document struct {
+	Info struct {
+		Title   string `yaml:"title"`
+		Version string `yaml:"version"`
+	} `yaml:"info"`
+	Paths      ordered[pathItem] `yaml:"paths"`
+	Components struct {
+		Parameters map[string]*parameter `yaml:"parameters"`
+		Responses  map[string]*response  `yaml:"responses"`
+		Schemas    ordered[*schema]      `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+// pathItem holds the operations of one path keyed by lower-case HTTP method
+type pathItem = ordered[*operation]
+
+type operation struct {
+	OperationID string                `yaml:"operationId"`
+	Summary     string                `yaml:"summary"`
+	Tags        []string              `yaml:"tags"`
+	Idempotent  bool                  `yaml:"x-idempotent"` // A POST that may be retried, since a replay has no further effect
+	Security    []map[string][]string `yaml:"security"`
+	Parameters  []*parameter          `yaml:"parameters"`
+	RequestBody *struct {
+		Content map[string]*mediaType `yaml:"content"`
+	} `yaml:"requestBody"`
+	Responses map[string]*response `yaml:"responses"`
+}
+
+type parameter struct {
+	Ref      string  `yaml:"$ref"`
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"`
+	Required bool    `yaml:"required"`
+	Schema   *schema `yaml:"schema"`
+}
+
+type response struct {
+	Ref         string                `yaml:"$ref"`
+	Description string                `yaml:"description"`
+	Content     map[string]*mediaType `yaml:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `yaml:"schema"`
+}
+
+type schema struct {
+	Ref         string           `yaml:"$ref"`
+	Type        string           `yaml:"type"`
+	Format      string           `yaml:"format"`
+	Description string           `yaml:"description"`
+	Nullable    bool             `yaml:"nullable"`
+	Required    []string         `yaml:"required"`
+	Properties  ordered[*schema] `yaml:"properties"`
+	Items       *schema          `yaml:"items"`
+	AllOf       []*schema        `yaml:"allOf"`
+	GoName      string           `yaml:"x-go-name"` // Overrides the Go name derived from the property name
+}
+
+// entry is one key of a YAML mapping with its decoded value
+type entry[T any] struct {
+	Key   string
+	Value T
+}
+
+// ordered decodes a YAML mapping keeping the order of its keys, so generated code follows the document
+type ordered[T any] []entry[T]
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (o *ordered[T]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var value T
+		if err := node.Content[i+1].Decode(&value); err != nil {
+			return err
+		}
+		*o = append(*o, entry[T]{Key: node.Content[i].Value, Value: value})
+	}
+	return nil
+}
+
+// get returns the value of a key
+func (o ordered[T]) get(key string) (T, bool) {
+	for _, e := range o {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// refName returns the name of a local component reference such as #/components/schemas/UserResponse
+func refName(ref, kind string) (string, error) {
+	prefix := "#/components/" + kind + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("unsupported reference %q", ref)
+	}
+	return strings.TrimPrefix(ref, prefix), nil
+}
+
+// parameter resolves a parameter reference
+func (d *document) parameter(p *parameter) (*parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, err := refName(p.Ref, "parameters")
+	if err != nil {
+		return nil, err
+	}
+	resolved, ok := d.Components.Parameters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown parameter %q", name)
+	}
+	return resolved, nil
+}
+
+// response resolves a response reference
+func (d *document) response(r *response) (*response, error) {
+	if r.Ref == "" {
+		return r, nil
+	}
+	name, err := refName(r.Ref, "responses")
+	if err != nil {
+		return nil, err
+	}
+	resolved, ok := d.Components.Responses[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown response %q", name)
+	}
+	return resolved, nil
+}
+
+// schema resolves a schema reference, returning the name of the referenced schema
+func (d *document) schema(s *schema) (string, *schema, error) {
+	if s.Ref == "" {
+		return "", s, nil
+	}
+	name, err := refName(s.Ref, "schemas")
+	if err != nil {
+		return "", nil, err
+	}
+	resolved, ok := d.Components.Schemas.get(name)
+	if !ok {
+		return "", nil, fmt.Errorf("unknown schema %q", name)
+	}
+	return name, resolved, nil
+}
@@ -0,0 +1,347 @@
+package apiclients
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"    // Calls flow; consecutive failures are counted
+	BreakerOpen     = "open"      // Calls are rejected until the open timeout has passed
+	BreakerHalfOpen = "half-open" // A limited number of probes decide whether to close or reopen
+)
+
+var (
+	// ErrCircuitOpen is returned without calling the target while its circuit breaker is open
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+
+	// ErrBulkheadFull is returned when the target has too many calls in flight
+	ErrBulkheadFull = errors.New("too many concurrent calls")
+)
+
+// UnavailableError is returned when a call was rejected locally to protect a failing or saturated target
+type UnavailableError struct {
+	Target string
+	Err    error // ErrCircuitOpen or ErrBulkheadFull
+}
+
+// Error implements the error interface
+func (e *UnavailableError) Error() string {
+	return e.Target + ": " + e.Err.Error()
+}
+
+// Unwrap returns ErrCircuitOpen or ErrBulkheadFull
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// Policy tunes the resilience of the calls to one target
+type Policy struct {
+	Timeout          time.Duration // Per attempt
+	MaxAttempts      int           // Attempts of idempotent calls
+	InitialBackoff   time.Duration // Backoff before the first retry, doubled per retry and jittered
+	MaxBackoff       time.Duration
+	MaxConcurrent    int           // Calls in flight at once, unlimited when zero
+	QueueTimeout     time.Duration // Wait for a free slot before a call is rejected
+	FailureThreshold int           // Consecutive failures that open the breaker, disabled when zero
+	OpenTimeout      time.Duration // Time the breaker stays open before probing
+	HalfOpenProbes   int           // Successful probes that close the breaker again
+}
+
+// DefaultPolicy returns the policy used for targets without configuration
+func DefaultPolicy() Policy {
+	return Policy{
+		Timeout:          10 * time.Second,
+		MaxAttempts:      3,
+		InitialBackoff:   100 * time.Millisecond,
+		MaxBackoff:       2 * time.Second,
+		QueueTimeout:     time.Second,
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenProbes:   1,
+	}
+}
+
+// Policies holds the default policy and the overrides for individual upstreams and instances
+type Policies struct {
+	Default Policy
+	Targets map[string]Policy // Keyed by upstream name or instance host:port
+}
+
+// For returns the policy of an instance of an upstream; the policy of the instance takes precedence over that of the upstream
+func (p Policies) For(upstream, instance string) Policy {
+	if policy, ok := p.Targets[instance]; ok {
+		return policy
+	}
+	if policy, ok := p.Targets[upstream]; ok {
+		return policy
+	}
+	return p.Default
+}
+
+// backoff returns the jittered delay before the given retry, counted from 1
+func (p Policy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff << (retry - 1)
+	if delay <= 0 || delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// Full jitter keeps clients that failed together from retrying together
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// TargetStats is a snapshot of the calls to one instance of an upstream
+type TargetStats struct {
+	Upstream            string `json:"upstream"`
+	Target              string `json:"target"`
+	BreakerState        string `json:"breakerState"`
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	InFlight            int64  `json:"inFlight"`
+	Calls               int64  `json:"calls"`    // Attempts sent to the target
+	Failures            int64  `json:"failures"` // Attempts that failed with a transport error or a 5xx
+	Retries             int64  `json:"retries"`
+	Rejected            int64  `json:"rejected"` // Calls rejected by the breaker or the bulkhead
+}
+
+// target holds the breaker, bulkhead and counters of one instance, shared by every copy of the WebClient
+type target struct {
+	name     string // host:port of the instance
+	upstream string
+	policy   Policy
+	slots    chan struct{} // nil when concurrency is unlimited
+	breaker  breaker
+	inFlight atomic.Int64
+	calls    atomic.Int64
+	failures atomic.Int64
+	retries  atomic.Int64
+	rejected atomic.Int64
+}
+
+// outcome is the result of an admitted call as seen by the circuit breaker
+type outcome int
+
+const (
+	outcomeSuccess   outcome = iota
+	outcomeFailure           // Transport error or 5xx, counted against the target
+	outcomeAbandoned         // The caller gave up, which says nothing about the target
+)
+
+// acquire admits a call through the breaker and the bulkhead, waiting for a free slot at most the queue timeout
+// or until ctx is done. The returned function records the outcome of the call and must be called exactly once.
+func (t *target) acquire(ctx context.Context) (func(outcome), error) {
+	probe, err := t.breaker.allow(t.policy)
+	if err != nil {
+		t.rejected.Add(1)
+		return nil, &UnavailableError{Target: t.name, Err: err}
+	}
+
+	if t.slots != nil {
+		select {
+		case t.slots <- struct{}{}:
+		default:
+			timer := time.NewTimer(t.policy.QueueTimeout)
+			defer timer.Stop()
+			select {
+			case t.slots <- struct{}{}:
+			case <-ctx.Done():
+				t.breaker.release(probe)
+				return nil, ctx.Err()
+			case <-timer.C:
+				t.breaker.release(probe)
+				t.rejected.Add(1)
+				return nil, &UnavailableError{Target: t.name, Err: ErrBulkheadFull}
+			}
+		}
+	}
+
+	t.inFlight.Add(1)
+	t.calls.Add(1)
+	return func(result outcome) {
+		t.inFlight.Add(-1)
+		if t.slots != nil {
+			<-t.slots
+		}
+		switch result {
+		case outcomeAbandoned:
+			t.breaker.release(probe)
+		case outcomeFailure:
+			t.failures.Add(1)
+			t.breaker.record(t.policy, probe, true)
+		default:
+			t.breaker.record(t.policy, probe, false)
+		}
+	}, nil
+}
+
+// ejected reports whether the instance is taken out of the load balancing because its breaker is open
+func (t *target) ejected() bool {
+	state, _ := t.breaker.snapshot(t.policy)
+	return state == BreakerOpen
+}
+
+// stats returns a snapshot of the target
+func (t *target) stats() TargetStats {
+	state, failures := t.breaker.snapshot(t.policy)
+	return TargetStats{
+		Upstream:            t.upstream,
+		Target:              t.name,
+		BreakerState:        state,
+		ConsecutiveFailures: failures,
+		InFlight:            t.inFlight.Load(),
+		Calls:               t.calls.Load(),
+		Failures:            t.failures.Load(),
+		Retries:             t.retries.Load(),
+		Rejected:            t.rejected.Load(),
+	}
+}
+
+// breaker is a consecutive-failure circuit breaker with half-open probing
+type breaker struct {
+	mu        sync.Mutex
+	state     string
+	failures  int       // Consecutive failures while closed
+	openedAt  time.Time // When the breaker last opened
+	probes    int       // Probes in flight while half-open
+	successes int       // Successful probes while half-open
+}
+
+// allow reports whether a call may proceed and whether it is a half-open probe
+func (b *breaker) allow(policy Policy) (bool, error) {
+	if policy.FailureThreshold <= 0 {
+		return false, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(policy)
+	switch b.state {
+	case BreakerOpen:
+		return false, ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probes >= max(policy.HalfOpenProbes, 1) {
+			return false, ErrCircuitOpen
+		}
+		b.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// record applies the outcome of an admitted call
+func (b *breaker) record(policy Policy, probe, failed bool) {
+	if policy.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case probe && b.state == BreakerHalfOpen:
+		b.probes--
+		if failed {
+			b.open()
+			return
+		}
+		b.successes++
+		if b.successes >= max(policy.HalfOpenProbes, 1) {
+			b.state, b.failures = BreakerClosed, 0
+		}
+	case !probe && (b.state == "" || b.state == BreakerClosed):
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= policy.FailureThreshold {
+			b.open()
+		}
+	}
+	// Outcomes of calls admitted before the breaker changed state are ignored
+}
+
+// release gives back a probe that was admitted but never sent or abandoned by its caller
+func (b *breaker) release(probe bool) {
+	if !probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen {
+		b.probes--
+	}
+}
+
+// snapshot returns the current state and consecutive failures
+func (b *breaker) snapshot(policy Policy) (string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(policy)
+	if b.state == "" {
+		return BreakerClosed, b.failures
+	}
+	return b.state, b.failures
+}
+
+// advance moves an open breaker to half-open once the open timeout has passed
+func (b *breaker) advance(policy Policy) {
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= policy.OpenTimeout {
+		b.state, b.probes, b.successes = BreakerHalfOpen, 0, 0
+	}
+}
+
+// open rejects calls until the open timeout has passed
+func (b *breaker) open() {
+	b.state, b.openedAt, b.probes, b.successes = BreakerOpen, time.Now(), 0, 0
+}
+
+// targetRegistry creates the state of each target on first use
+type targetRegistry struct {
+	mu       sync.Mutex
+	policies Policies
+	targets  map[string]*target
+}
+
+func newTargetRegistry(policies Policies) *targetRegistry {
+	return &targetRegistry{policies: policies, targets: map[string]*target{}}
+}
+
+// get returns the state of an instance of an upstream
+func (r *targetRegistry) get(upstream, name string) *target {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.targets[name]; ok {
+		return t
+	}
+
+	t := &target{name: name, upstream: upstream, policy: r.policies.For(upstream, name)}
+	if t.policy.MaxConcurrent > 0 {
+		t.slots = make(chan struct{}, t.policy.MaxConcurrent)
+	}
+	r.targets[name] = t
+	return t
+}
+
+// stats returns a snapshot of every target called so far, ordered by name
+func (r *targetRegistry) stats() []TargetStats {
+	r.mu.Lock()
+	targets := make([]*target, 0, len(r.targets))
+	for _, t := range r.targets {
+		targets = append(targets, t)
+	}
+	r.mu.Unlock()
+
+	stats := make([]TargetStats, 0, len(targets))
+	for _, t := range targets {
+		stats = append(stats, t.stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Target < stats[j].Target })
+	return stats
+}
@@ -0,0 +1,466 @@
+package apiclients
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrUnknownUpstream is returned for a call to an upstream that is not configured
+	ErrUnknownUpstream = errors.New("unknown upstream")
+
+	// ErrNoInstances is returned while discovery has not found any instance of an upstream
+	ErrNoInstances = errors.New("no instances of the upstream are known")
+)
+
+// Resolver discovers the instances of an upstream as host:port addresses
+type Resolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// WeightedInstance is an instance of an upstream with the order and share of the calls it takes. Instances of a
+// lower priority value take all the calls while any of them is available, which are then split among them in
+// proportion to their weights; instances weighted zero are only called when none of the others of their
+// priority is available.
+type WeightedInstance struct {
+	Address  string // host:port
+	Priority uint16
+	Weight   uint16
+}
+
+// WeightedResolver is implemented by resolvers whose instances are not all equal, such as DNS SRV targets
+type WeightedResolver interface {
+	Resolver
+	ResolveWeighted(ctx context.Context) ([]WeightedInstance, error)
+}
+
+// Watcher is implemented by resolvers that can tell when their instances may have changed, so that they are
+// resolved again at once rather than at the next refresh. The channel is closed once ctx is done.
+type Watcher interface {
+	Watch(ctx context.Context) (<-chan struct{}, error)
+}
+
+// maxWeightSlots bounds the round-robin schedule of the instances of one priority
+const maxWeightSlots = 100
+
+// StaticResolver always returns the same instances
+type StaticResolver []string
+
+// Resolve implements Resolver
+func (r StaticResolver) Resolve(_ context.Context) ([]string, error) {
+	return r, nil
+}
+
+// SRVResolver looks the instances up in a DNS SRV record, e.g. _http._tcp.user-service.internal,
+// honouring the priority and weight of its targets as described by WeightedInstance
+type SRVResolver struct {
+	Name     string
+	Resolver *net.Resolver // net.DefaultResolver when nil
+}
+
+// Resolve implements Resolver
+func (r SRVResolver) Resolve(ctx context.Context) ([]string, error) {
+	weighted, err := r.ResolveWeighted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]string, 0, len(weighted))
+	for _, instance := range weighted {
+		instances = append(instances, instance.Address)
+	}
+	return instances, nil
+}
+
+// ResolveWeighted implements WeightedResolver
+func (r SRVResolver) ResolveWeighted(ctx context.Context) ([]WeightedInstance, error) {
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	_, records, err := resolver.LookupSRV(ctx, "", "", r.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]WeightedInstance, 0, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		instances = append(instances, WeightedInstance{
+			Address:  net.JoinHostPort(host, strconv.Itoa(int(record.Port))),
+			Priority: record.Priority,
+			Weight:   record.Weight,
+		})
+	}
+	return instances, nil
+}
+
+// FileResolver reads the instances from a file listing one host:port per line.
+// Blank lines and lines starting with # are ignored.
+type FileResolver struct {
+	Path string
+}
+
+// Resolve implements Resolver
+func (r FileResolver) Resolve(_ context.Context) ([]string, error) {
+	file, err := os.Open(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var instances []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(line); err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Path, err)
+		}
+		instances = append(instances, line)
+	}
+	return instances, scanner.Err()
+}
+
+// Watch implements Watcher. The directory of the file is watched rather than the file itself, so that a file
+// replaced by a rename, as editors and Kubernetes volumes do, is still followed.
+func (r FileResolver) Watch(ctx context.Context) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(r.Path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				select {
+				case changes <- struct{}{}:
+				default: // A resolution is already pending
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.ErrorContext(ctx, "Failed to watch the endpoints file", "path", r.Path, "error", err)
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// Upstream is a named service whose instances are discovered by a resolver and balanced round-robin,
+// in proportion to their weights when the resolver is a WeightedResolver
+type Upstream struct {
+	Name    string
+	Scheme  string        // http when empty
+	Refresh time.Duration // Interval between resolutions; when zero, only a change reported by a Watcher triggers one
+
+	resolver  Resolver
+	mu        sync.RWMutex
+	instances []string   // Distinct instances, sorted
+	tiers     [][]string // Round-robin schedule of the instances, by priority
+	updated   chan struct{}
+	next      atomic.Uint64
+}
+
+// NewUpstream creates an upstream and resolves its instances once. A failed resolution is logged
+// and retried by Run, so a service can start while an upstream is not reachable yet.
+func NewUpstream(ctx context.Context, name, scheme string, resolver Resolver, refresh time.Duration) *Upstream {
+	u := &Upstream{Name: name, Scheme: scheme, Refresh: refresh, resolver: resolver, updated: make(chan struct{})}
+	if err := u.resolve(ctx); err != nil {
+		slog.WarnContext(ctx, "Failed to resolve the instances of upstream", "upstream", name, "error", err)
+	}
+	return u
+}
+
+// NewStaticUpstream creates an upstream with a fixed list of instances
+func NewStaticUpstream(name, scheme string, instances ...string) *Upstream {
+	return NewUpstream(context.Background(), name, scheme, StaticResolver(instances), 0)
+}
+
+// Instances returns the currently known instances
+func (u *Upstream) Instances() []string {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return append([]string(nil), u.instances...)
+}
+
+// changed returns a channel closed by the next change of the instances
+func (u *Upstream) changed() <-chan struct{} {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.updated
+}
+
+// url returns the URL of path on an instance
+func (u *Upstream) url(instance, path string) string {
+	scheme := u.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return scheme + "://" + instance + path
+}
+
+// pick returns the next instance of the first priority that is not in skip and for which available reports
+// true. When every instance is skipped or unavailable, it falls back to the next one not in skip, and then to
+// any one, so the caller still gets a definite error from the instance instead of none at all.
+func (u *Upstream) pick(skip map[string]bool, available func(instance string) bool) (string, error) {
+	u.mu.RLock()
+	tiers := u.tiers
+	u.mu.RUnlock()
+	if len(tiers) == 0 {
+		return "", fmt.Errorf("%s: %w", u.Name, ErrNoInstances)
+	}
+
+	start := u.next.Add(1) - 1
+	fallback := ""
+	for _, tier := range tiers {
+		for i := 0; i < len(tier); i++ {
+			instance := tier[(start+uint64(i))%uint64(len(tier))]
+			if skip[instance] {
+				continue
+			}
+			if available(instance) {
+				return instance, nil
+			}
+			if fallback == "" {
+				fallback = instance
+			}
+		}
+	}
+	if fallback != "" {
+		return fallback, nil
+	}
+	return tiers[0][start%uint64(len(tiers[0]))], nil
+}
+
+// resolve replaces the known instances with a fresh resolution. An empty result keeps the previous
+// instances, as a briefly empty endpoint list is more likely a discovery glitch than a fleet that is gone.
+func (u *Upstream) resolve(ctx context.Context) error {
+	var weighted []WeightedInstance
+	if resolver, ok := u.resolver.(WeightedResolver); ok {
+		var err error
+		if weighted, err = resolver.ResolveWeighted(ctx); err != nil {
+			return err
+		}
+	} else {
+		instances, err := u.resolver.Resolve(ctx)
+		if err != nil {
+			return err
+		}
+		for _, instance := range instances {
+			weighted = append(weighted, WeightedInstance{Address: instance, Weight: 1})
+		}
+	}
+	if len(weighted) == 0 {
+		return fmt.Errorf("%s: %w", u.Name, ErrNoInstances)
+	}
+
+	instances, tiers := schedule(weighted)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if slices.Equal(instances, u.instances) && slices.EqualFunc(tiers, u.tiers, slices.Equal) {
+		return nil
+	}
+	u.instances, u.tiers = instances, tiers
+	close(u.updated)
+	u.updated = make(chan struct{})
+	return nil
+}
+
+// schedule returns the distinct addresses of the instances, sorted, and their round-robin schedule: one tier per
+// priority in ascending order, followed by one for its instances weighted zero, in which each instance appears in
+// proportion to its weight. The appearances of the instances are interleaved so that no instance takes a burst
+// of consecutive calls.
+func schedule(weighted []WeightedInstance) ([]string, [][]string) {
+	weighted = slices.Clone(weighted)
+	slices.SortFunc(weighted, func(a, b WeightedInstance) int {
+		return cmp.Or(cmp.Compare(a.Priority, b.Priority), cmp.Compare(a.Address, b.Address))
+	})
+
+	instances := make([]string, 0, len(weighted))
+	var tiers [][]string
+	for start := 0; start < len(weighted); {
+		end := start
+		for end < len(weighted) && weighted[end].Priority == weighted[start].Priority {
+			end++
+		}
+		tier, zero := weightedTier(weighted[start:end])
+		if len(tier) > 0 {
+			tiers = append(tiers, tier)
+		}
+		if len(zero) > 0 {
+			tiers = append(tiers, zero)
+		}
+		for _, instance := range weighted[start:end] {
+			instances = append(instances, instance.Address)
+		}
+		start = end
+	}
+
+	slices.Sort(instances)
+	return slices.Compact(instances), tiers
+}
+
+// weightedTier returns the round-robin schedule of instances of one priority, sorted by address, and apart from
+// it those weighted zero. The weights are reduced by their greatest common divisor, and scaled down when their
+// sum still exceeds maxWeightSlots.
+func weightedTier(group []WeightedInstance) (tier, zero []string) {
+	var order []string
+	counts := make(map[string]int, len(group))
+	divisor, total := 0, 0
+	for _, instance := range group {
+		if instance.Weight == 0 {
+			zero = append(zero, instance.Address)
+			continue
+		}
+		if _, ok := counts[instance.Address]; !ok {
+			order = append(order, instance.Address)
+		}
+		counts[instance.Address] += int(instance.Weight)
+		total += int(instance.Weight)
+	}
+	for _, count := range counts {
+		divisor = gcd(divisor, count)
+	}
+
+	rounds := 0
+	for address, count := range counts {
+		if total/divisor > maxWeightSlots {
+			count = max(1, count*maxWeightSlots/total)
+		} else {
+			count /= divisor
+		}
+		counts[address] = count
+		rounds = max(rounds, count)
+	}
+	for round := 0; round < rounds; round++ {
+		for _, address := range order {
+			if counts[address] > round {
+				tier = append(tier, address)
+			}
+		}
+	}
+	return tier, slices.Compact(zero)
+}
+
+// gcd returns the greatest common divisor of a and b
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// run resolves the instances every refresh interval, and whenever a resolver that is a Watcher reports a
+// change, until ctx is done
+func (u *Upstream) run(ctx context.Context) {
+	var changes <-chan struct{}
+	if watcher, ok := u.resolver.(Watcher); ok {
+		var err error
+		if changes, err = watcher.Watch(ctx); err != nil {
+			slog.ErrorContext(ctx, "Failed to watch the instances of upstream, refreshing them on a timer only", "upstream", u.Name, "refresh", u.Refresh.String(), "error", err)
+		}
+	}
+	var refresh <-chan time.Time
+	if u.Refresh > 0 {
+		ticker := time.NewTicker(u.Refresh)
+		defer ticker.Stop()
+		refresh = ticker.C
+	}
+	if refresh == nil && changes == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-refresh:
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil
+				continue
+			}
+		}
+		if err := u.resolve(ctx); err != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "Failed to refresh the instances of upstream", "upstream", u.Name, "error", err)
+		}
+	}
+}
+
+// Upstreams is the registry of the services the WebClient calls by name
+type Upstreams struct {
+	byName map[string]*Upstream
+}
+
+// NewUpstreams creates a registry of the given upstreams
+func NewUpstreams(upstreams ...*Upstream) *Upstreams {
+	byName := make(map[string]*Upstream, len(upstreams))
+	for _, u := range upstreams {
+		byName[u.Name] = u
+	}
+	return &Upstreams{byName: byName}
+}
+
+// Get returns the upstream with the name
+func (r *Upstreams) Get(name string) (*Upstream, error) {
+	if r != nil {
+		if u, ok := r.byName[name]; ok {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", name, ErrUnknownUpstream)
+}
+
+// Run keeps the instances of every upstream up to date until ctx is done
+func (r *Upstreams) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, u := range r.byName {
+		wg.Add(1)
+		go func(u *Upstream) {
+			defer wg.Done()
+			u.run(ctx)
+		}(u)
+	}
+	wg.Wait()
+}
+
+// pick returns the state of the next instance of the upstream that was not tried yet, passing over ejected instances
+func (r *targetRegistry) pick(u *Upstream, tried map[string]bool) (*target, error) {
+	instance, err := u.pick(tried, func(instance string) bool {
+		return !r.get(u.Name, instance).ejected()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.get(u.Name, instance), nil
+}
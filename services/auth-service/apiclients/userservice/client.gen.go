@@ -0,0 +1,294 @@
+// Code generated by gen-userclient from user-service docs/openapi.yaml version 1.5.0. DO NOT EDIT.
+
+package userservice
+
+import (
+	"context"
+	"github.com/Mir00r/auth-service/apiclients"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// APIVersion is the version of the user-service API the client was generated from
+const APIVersion = "1.5.0"
+
+// CreateUserRequest creates a new account
+type CreateUserRequest struct {
+	Name           string  `json:"name"`
+	Email          string  `json:"email"`
+	Password       string  `json:"password"`
+	Phone          string  `json:"phone,omitempty"` // E.164 format
+	Role           *string `json:"role,omitempty"`
+	DateOfBirth    string  `json:"dateOfBirth,omitempty"`
+	Address        *string `json:"address,omitempty"`
+	ProfilePicture *string `json:"profilePicture,omitempty"`
+	Locale         *string `json:"locale,omitempty"` // e.g. en-US
+	Timezone       *string `json:"timezone,omitempty"`
+}
+
+// UpdateUserRequest updates the profile of an account; empty fields are left unchanged
+type UpdateUserRequest struct {
+	Name           string  `json:"name,omitempty"`
+	Phone          string  `json:"phone,omitempty"`
+	DateOfBirth    *string `json:"dateOfBirth,omitempty"`
+	Address        *string `json:"address,omitempty"`
+	ProfilePicture string  `json:"profilePicture,omitempty"`
+	Locale         *string `json:"locale,omitempty"`
+	Timezone       *string `json:"timezone,omitempty"`
+}
+
+// ValidateRequest carries the credentials to check
+type ValidateRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// ExternalUserRequest finds or creates the account of an external (OIDC) identity
+type ExternalUserRequest struct {
+	Provider      string `json:"provider"`
+	Subject       string `json:"subject"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"emailVerified"`
+	Name          string `json:"name"`
+}
+
+// SetPasswordRequest replaces the password of an account
+type SetPasswordRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// BatchUsersRequest asks for the profiles of several accounts
+type BatchUsersRequest struct {
+	UserIDs []string `json:"userIds"`
+}
+
+// BatchUsersResponse holds the profiles found for a BatchUsersRequest
+type BatchUsersResponse struct {
+	Users []UserResponse `json:"users"`
+}
+
+// LastLoginRequest records a successful login reported by auth-service
+type LastLoginRequest struct {
+	Email      string    `json:"email"`
+	LoggedInAt time.Time `json:"loggedInAt"`
+}
+
+// RegistrationRequest creates the profile of a registration. Only the password hash is sent, as the same hash has to be replayed when the step is retried
+type RegistrationRequest struct {
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	PasswordHash string `json:"passwordHash"`
+}
+
+// EmailVerifiedRequest records that the owner of an account confirmed its email address
+type EmailVerifiedRequest struct {
+	Email string `json:"email"`
+}
+
+// AccountStatusRequest asks whether the account with the email may sign in
+type AccountStatusRequest struct {
+	Email string `json:"email"`
+}
+
+// AccountStatusResponse is the sign-in relevant state of an account. An unknown email is reported as a missing account rather than an error
+type AccountStatusResponse struct {
+	UserID  string `json:"userId,omitempty"`
+	Exists  bool   `json:"exists"`
+	Active  bool   `json:"active"`
+	Deleted bool   `json:"deleted"`
+}
+
+// UserResponse is the profile of an account
+type UserResponse struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Email          string     `json:"email"`
+	Phone          string     `json:"phone,omitempty"`
+	IsActive       bool       `json:"isActive"`
+	IsVerified     bool       `json:"isVerified"`
+	ProfilePicture string     `json:"profilePicture,omitempty"`
+	Role           *string    `json:"role"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	LastLogin      *time.Time `json:"lastLogin,omitempty"`
+	DateOfBirth    *time.Time `json:"dateOfBirth,omitempty"`
+	Address        *string    `json:"address,omitempty"`
+	Locale         string     `json:"locale"`
+	Timezone       string     `json:"timezone"`
+	TenantID       string     `json:"tenantId,omitempty"`
+	MFAEnabled     bool       `json:"isMfaEnabled"`
+}
+
+// PaginatedUserResponse is a page of accounts
+type PaginatedUserResponse struct {
+	Users      []UserResponse `json:"auth"`
+	TotalCount int64          `json:"totalCount"`
+	Page       int            `json:"page"`
+	PerPage    int            `json:"perPage"`
+}
+
+// envelope is the APIResponse wrapping the result of an operation
+type envelope[T any] struct {
+	Data T `json:"data"`
+}
+
+// ListUsers returns the first page of accounts (admin only)
+//
+// GET /v1/protected/user
+func (c *Client) ListUsers(ctx context.Context, token string) (*PaginatedUserResponse, error) {
+	var response PaginatedUserResponse
+	if err := c.send(ctx, http.MethodGet, "/v1/protected/user", nil, &response, apiclients.BearerToken(token)); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetUser returns the profile of an account (admin or the account itself)
+//
+// GET /v1/protected/user/{userId}
+func (c *Client) GetUser(ctx context.Context, token string, userID string) (*UserResponse, error) {
+	var response UserResponse
+	if err := c.send(ctx, http.MethodGet, "/v1/protected/user/"+url.PathEscape(userID), nil, &response, apiclients.BearerToken(token)); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// UpdateUser updates the profile of an account (admin or the account itself)
+//
+// PUT /v1/protected/user/{userId}
+func (c *Client) UpdateUser(ctx context.Context, token string, userID string, body UpdateUserRequest) (*UserResponse, error) {
+	var response envelope[UserResponse]
+	if err := c.send(ctx, http.MethodPut, "/v1/protected/user/"+url.PathEscape(userID), body, &response, apiclients.BearerToken(token)); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// DeleteUser schedules the deletion of an account (admin only); auth-service deletes it after the grace period
+//
+// DELETE /v1/protected/user/{userId}
+func (c *Client) DeleteUser(ctx context.Context, token string, userID string) error {
+	return c.send(ctx, http.MethodDelete, "/v1/protected/user/"+url.PathEscape(userID), nil, nil, apiclients.BearerToken(token))
+}
+
+// CreateUser creates an account
+//
+// POST /v1/internal/user
+func (c *Client) CreateUser(ctx context.Context, body CreateUserRequest) (*UserResponse, error) {
+	var response envelope[UserResponse]
+	if err := c.send(ctx, http.MethodPost, "/v1/internal/user", body, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// ValidateUser checks the credentials of an account and returns its profile
+//
+// POST /v1/internal/user/validate
+func (c *Client) ValidateUser(ctx context.Context, body ValidateRequest) (*UserResponse, error) {
+	var response envelope[UserResponse]
+	if err := c.send(ctx, http.MethodPost, "/v1/internal/user/validate", body, &response, apiclients.Idempotent()); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// ResolveExternalUser finds or creates the account of an external (OIDC) identity
+//
+// POST /v1/internal/user/external
+func (c *Client) ResolveExternalUser(ctx context.Context, body ExternalUserRequest) (*UserResponse, error) {
+	var response envelope[UserResponse]
+	if err := c.send(ctx, http.MethodPost, "/v1/internal/user/external", body, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// GetAccountStatus reports whether an account may sign in
+//
+// POST /v1/internal/user/account-status
+func (c *Client) GetAccountStatus(ctx context.Context, body AccountStatusRequest) (*AccountStatusResponse, error) {
+	var response envelope[AccountStatusResponse]
+	if err := c.send(ctx, http.MethodPost, "/v1/internal/user/account-status", body, &response, apiclients.Idempotent()); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// SetPassword replaces the password of an account
+//
+// PUT /v1/internal/user/password
+func (c *Client) SetPassword(ctx context.Context, body SetPasswordRequest) error {
+	return c.send(ctx, http.MethodPut, "/v1/internal/user/password", body, nil)
+}
+
+// CreateRegistration creates the profile of a registration saga; a replay with the same password hash returns the same profile
+//
+// POST /v1/internal/user/registrations
+func (c *Client) CreateRegistration(ctx context.Context, body RegistrationRequest) (*UserResponse, error) {
+	var response envelope[UserResponse]
+	if err := c.send(ctx, http.MethodPost, "/v1/internal/user/registrations", body, &response, apiclients.Idempotent()); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// DiscardRegistration discards the profile of a rolled back registration
+//
+// DELETE /v1/internal/user/registrations/{userId}
+func (c *Client) DiscardRegistration(ctx context.Context, userID string) error {
+	return c.send(ctx, http.MethodDelete, "/v1/internal/user/registrations/"+url.PathEscape(userID), nil, nil)
+}
+
+// MarkEmailVerified records that the owner of an account confirmed its email address
+//
+// PUT /v1/internal/user/email-verified
+func (c *Client) MarkEmailVerified(ctx context.Context, body EmailVerifiedRequest) error {
+	return c.send(ctx, http.MethodPut, "/v1/internal/user/email-verified", body, nil)
+}
+
+// BatchGetUsers returns the profiles of up to 100 accounts; unknown ids are left out
+//
+// POST /v1/internal/user/batch
+func (c *Client) BatchGetUsers(ctx context.Context, body BatchUsersRequest) (*BatchUsersResponse, error) {
+	var response envelope[BatchUsersResponse]
+	if err := c.send(ctx, http.MethodPost, "/v1/internal/user/batch", body, &response, apiclients.Idempotent()); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// RecordLogin records a successful login; an older login never replaces a newer one
+//
+// PUT /v1/internal/user/last-login
+func (c *Client) RecordLogin(ctx context.Context, body LastLoginRequest) error {
+	return c.send(ctx, http.MethodPut, "/v1/internal/user/last-login", body, nil)
+}
+
+// GetUserDetails returns the profile of an account with its internal fields
+//
+// GET /v1/internal/user/{userId}/details
+func (c *Client) GetUserDetails(ctx context.Context, userID string) (*UserResponse, error) {
+	var response UserResponse
+	if err := c.send(ctx, http.MethodGet, "/v1/internal/user/"+url.PathEscape(userID)+"/details", nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DeactivateUser stops an account from signing in
+//
+// PUT /v1/internal/user/{userId}/deactivate
+func (c *Client) DeactivateUser(ctx context.Context, userID string) error {
+	return c.send(ctx, http.MethodPut, "/v1/internal/user/"+url.PathEscape(userID)+"/deactivate", nil, nil)
+}
+
+// AnonymizeUser removes the personal data of a deleted account
+//
+// POST /v1/internal/user/{userId}/anonymize
+func (c *Client) AnonymizeUser(ctx context.Context, userID string) error {
+	return c.send(ctx, http.MethodPost, "/v1/internal/user/"+url.PathEscape(userID)+"/anonymize", nil, nil, apiclients.Idempotent())
+}
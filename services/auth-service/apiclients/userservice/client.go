@@ -0,0 +1,82 @@
+// Package userservice is the typed client of the internal and protected APIs of user-service.
+// The operations and types in client.gen.go are generated from docs/openapi.yaml of user-service;
+// change the spec and run go generate instead of editing them.
+package userservice
+
+import (
+	"context"
+	"github.com/Mir00r/auth-service/apiclients"
+	"github.com/Mir00r/auth-service/apiclients/openapi"
+	"github.com/Mir00r/auth-service/apiclients/userservice/userinternalv1"
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/constants"
+	"io"
+	"log"
+)
+
+//go:generate go run ../../cmd/gen-userclient -spec ../../../user-service/docs/openapi.yaml -out client.gen.go
+
+// Client calls user-service through a WebClient, which balances the calls across its instances
+type Client struct {
+	WebClient apiclients.WebClient
+	Upstream  string
+	Internal  InternalAPI // Operations offered over both HTTP and gRPC, over the configured transport
+	conn      io.Closer   // gRPC connection of Internal, nil over HTTP
+}
+
+// NewClient returns a client calling the user-service upstream, with the internal API over HTTP
+func NewClient(webClient apiclients.WebClient) *Client {
+	client := &Client{WebClient: webClient, Upstream: constants.UpstreamUserService}
+	client.Internal = NewHTTPInternalAPI(client)
+	return client
+}
+
+// NewClientFromConfig returns a client calling the user-service upstream, with the internal API over the
+// configured transport. An unknown transport or a gRPC upstream that cannot be dialed stops the service.
+func NewClientFromConfig(webClient apiclients.WebClient, cfg config.Config) *Client {
+	client := NewClient(webClient)
+	switch cfg.UserService.Transport {
+	case constants.TransportHTTP, "":
+	case constants.TransportGRPC:
+		conn, err := apiclients.NewGRPCConn(
+			webClient.Upstreams,
+			constants.UpstreamUserServiceGRPC,
+			webClient.Policies.For(constants.UpstreamUserServiceGRPC, ""),
+			apiclients.GRPCCredentials{Username: cfg.InternalSecurity.UserName, Password: cfg.InternalSecurity.Password},
+			userinternalv1.UserInternalService_ServiceDesc.ServiceName, // Every method may be retried
+		)
+		if err != nil {
+			log.Fatalf("Failed to set up the gRPC connection to user-service: %v", err)
+		}
+		client.Internal = NewGRPCInternalAPI(conn)
+		client.conn = conn
+	default:
+		log.Fatalf("Unknown user-service transport %q", cfg.UserService.Transport)
+	}
+	return client
+}
+
+// Close releases the gRPC connection of the client, if any
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// send calls an operation of user-service
+func (c *Client) send(ctx context.Context, method, path string, body, response interface{}, opts ...apiclients.CallOption) error {
+	return c.WebClient.Send(ctx, method, c.Upstream, path, body, response, opts...)
+}
+
+// Generate returns the source of client.gen.go for a version of the user-service spec
+func Generate(spec []byte) ([]byte, error) {
+	return openapi.Generate(spec, openapi.Config{
+		Package:        "userservice",
+		Generator:      "gen-userclient",
+		Source:         "user-service docs/openapi.yaml",
+		Tags:           []string{"internal", "protected"},
+		Envelope:       "APIResponse",
+		RuntimePackage: "github.com/Mir00r/auth-service/apiclients",
+	})
+}
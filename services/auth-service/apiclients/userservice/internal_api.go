@@ -0,0 +1,178 @@
+package userservice
+
+import (
+	"context"
+	"fmt"
+	"github.com/Mir00r/auth-service/apiclients"
+	"github.com/Mir00r/auth-service/apiclients/userservice/userinternalv1"
+	"github.com/Mir00r/auth-service/constants"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net/http"
+	"time"
+)
+
+// InternalAPI is the part of the internal API of user-service that is served over both HTTP and gRPC.
+// Failures answered by user-service are returned as an *apiclients.StatusError with the HTTP status of the
+// failure over either transport, so callers handle both alike.
+type InternalAPI interface {
+	ValidateCredentials(ctx context.Context, email, password string) (*UserResponse, error)
+	GetUser(ctx context.Context, userID string) (*UserResponse, error)
+	BatchGetUsers(ctx context.Context, userIDs []string) ([]UserResponse, error)
+	UpdateLastLogin(ctx context.Context, email string, loggedInAt time.Time) error
+}
+
+// httpInternalAPI calls the internal API through the JSON routes of the client
+type httpInternalAPI struct {
+	client *Client
+}
+
+// NewHTTPInternalAPI returns the internal API over the JSON routes called by the client
+func NewHTTPInternalAPI(client *Client) InternalAPI {
+	return httpInternalAPI{client: client}
+}
+
+// ValidateCredentials implements InternalAPI
+func (a httpInternalAPI) ValidateCredentials(ctx context.Context, email, password string) (*UserResponse, error) {
+	return a.client.ValidateUser(ctx, ValidateRequest{Email: email, Password: password})
+}
+
+// GetUser implements InternalAPI
+func (a httpInternalAPI) GetUser(ctx context.Context, userID string) (*UserResponse, error) {
+	return a.client.GetUserDetails(ctx, userID)
+}
+
+// BatchGetUsers implements InternalAPI
+func (a httpInternalAPI) BatchGetUsers(ctx context.Context, userIDs []string) ([]UserResponse, error) {
+	response, err := a.client.BatchGetUsers(ctx, BatchUsersRequest{UserIDs: userIDs})
+	if err != nil {
+		return nil, err
+	}
+	return response.Users, nil
+}
+
+// UpdateLastLogin implements InternalAPI
+func (a httpInternalAPI) UpdateLastLogin(ctx context.Context, email string, loggedInAt time.Time) error {
+	return a.client.RecordLogin(ctx, LastLoginRequest{Email: email, LoggedInAt: loggedInAt})
+}
+
+// grpcInternalAPI calls the internal API over gRPC
+type grpcInternalAPI struct {
+	client userinternalv1.UserInternalServiceClient
+}
+
+// NewGRPCInternalAPI returns the internal API over a gRPC connection to user-service
+func NewGRPCInternalAPI(conn grpc.ClientConnInterface) InternalAPI {
+	return grpcInternalAPI{client: userinternalv1.NewUserInternalServiceClient(conn)}
+}
+
+// ValidateCredentials implements InternalAPI
+func (a grpcInternalAPI) ValidateCredentials(ctx context.Context, email, password string) (*UserResponse, error) {
+	response, err := a.client.ValidateCredentials(ctx, &userinternalv1.ValidateCredentialsRequest{Email: email, Password: password})
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+	return fromProtoUser(response.GetUser()), nil
+}
+
+// GetUser implements InternalAPI
+func (a grpcInternalAPI) GetUser(ctx context.Context, userID string) (*UserResponse, error) {
+	response, err := a.client.GetUser(ctx, &userinternalv1.GetUserRequest{UserId: userID})
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+	return fromProtoUser(response.GetUser()), nil
+}
+
+// BatchGetUsers implements InternalAPI
+func (a grpcInternalAPI) BatchGetUsers(ctx context.Context, userIDs []string) ([]UserResponse, error) {
+	response, err := a.client.BatchGetUsers(ctx, &userinternalv1.BatchGetUsersRequest{UserIds: userIDs})
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+
+	users := make([]UserResponse, 0, len(response.GetUsers()))
+	for _, user := range response.GetUsers() {
+		users = append(users, *fromProtoUser(user))
+	}
+	return users, nil
+}
+
+// UpdateLastLogin implements InternalAPI
+func (a grpcInternalAPI) UpdateLastLogin(ctx context.Context, email string, loggedInAt time.Time) error {
+	_, err := a.client.UpdateLastLogin(ctx, &userinternalv1.UpdateLastLoginRequest{Email: email, LoggedInAt: timestamppb.New(loggedInAt)})
+	return fromGRPCError(err)
+}
+
+// fromProtoUser converts a protobuf user to the profile returned by the JSON routes
+func fromProtoUser(user *userinternalv1.User) *UserResponse {
+	return &UserResponse{
+		ID:             user.GetId(),
+		Name:           user.GetName(),
+		Email:          user.GetEmail(),
+		Phone:          user.GetPhone(),
+		IsActive:       user.GetIsActive(),
+		IsVerified:     user.GetIsVerified(),
+		ProfilePicture: user.GetProfilePicture(),
+		Role:           user.Role,
+		CreatedAt:      user.GetCreatedAt().AsTime(),
+		UpdatedAt:      user.GetUpdatedAt().AsTime(),
+		LastLogin:      fromTimestamp(user.GetLastLogin()),
+		DateOfBirth:    fromTimestamp(user.GetDateOfBirth()),
+		Address:        user.Address,
+		Locale:         user.GetLocale(),
+		Timezone:       user.GetTimezone(),
+		TenantID:       user.GetTenantId(),
+		MFAEnabled:     user.GetMfaEnabled(),
+	}
+}
+
+// fromTimestamp converts an optional timestamp, keeping an unset field as nil
+func fromTimestamp(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+// fromGRPCError converts the status of a failed call to the StatusError of the matching HTTP status, as the
+// JSON routes would have answered it
+func fromGRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	statusCode, ok := grpcHTTPStatus[st.Code()]
+	if !ok {
+		statusCode = http.StatusInternalServerError
+	}
+	return &apiclients.StatusError{
+		Target:     constants.UpstreamUserServiceGRPC,
+		StatusCode: statusCode,
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		Body: &apiclients.ErrorBody{
+			Code:       statusCode,
+			CodeStatus: http.StatusText(statusCode),
+			Message:    st.Message(),
+		},
+	}
+}
+
+// grpcHTTPStatus maps the gRPC codes answered by user-service back to the HTTP status it answers over JSON
+var grpcHTTPStatus = map[codes.Code]int{
+	codes.InvalidArgument:   http.StatusBadRequest,
+	codes.Unauthenticated:   http.StatusUnauthorized,
+	codes.PermissionDenied:  http.StatusForbidden,
+	codes.NotFound:          http.StatusNotFound,
+	codes.AlreadyExists:     http.StatusConflict,
+	codes.ResourceExhausted: http.StatusTooManyRequests,
+	codes.Unavailable:       http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:  http.StatusGatewayTimeout,
+}
@@ -0,0 +1,5 @@
+// Package userinternalv1 holds the messages and gRPC stubs of the internal API of user-service, generated from
+// proto/userinternal/v1/user_internal.proto of user-service; change the .proto there and run go generate here.
+package userinternalv1
+
+//go:generate protoc -I ../../../../user-service/proto --go_out=../../.. --go_opt=module=github.com/Mir00r/auth-service --go_opt=Muserinternal/v1/user_internal.proto=github.com/Mir00r/auth-service/apiclients/userservice/userinternalv1 --go-grpc_out=../../.. --go-grpc_opt=module=github.com/Mir00r/auth-service --go-grpc_opt=Muserinternal/v1/user_internal.proto=github.com/Mir00r/auth-service/apiclients/userservice/userinternalv1 userinternal/v1/user_internal.proto
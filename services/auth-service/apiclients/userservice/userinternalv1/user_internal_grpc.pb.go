@@ -0,0 +1,255 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: userinternal/v1/user_internal.proto
+
+package userinternalv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	UserInternalService_ValidateCredentials_FullMethodName = "/userinternal.v1.UserInternalService/ValidateCredentials"
+	UserInternalService_GetUser_FullMethodName             = "/userinternal.v1.UserInternalService/GetUser"
+	UserInternalService_BatchGetUsers_FullMethodName       = "/userinternal.v1.UserInternalService/BatchGetUsers"
+	UserInternalService_UpdateLastLogin_FullMethodName     = "/userinternal.v1.UserInternalService/UpdateLastLogin"
+)
+
+// UserInternalServiceClient is the client API for UserInternalService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// UserInternalService is the internal API of user-service for auth-service. It is served over gRPC next to
+// the JSON API under /v1/internal/user and accepts the same internal credentials, sent as basic auth in the
+// authorization metadata.
+type UserInternalServiceClient interface {
+	// ValidateCredentials checks the password of an account and returns the account. It fails with
+	// UNAUTHENTICATED for a wrong password and NOT_FOUND for an unknown email address.
+	ValidateCredentials(ctx context.Context, in *ValidateCredentialsRequest, opts ...grpc.CallOption) (*ValidateCredentialsResponse, error)
+	// GetUser returns an account with all its internal fields
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*GetUserResponse, error)
+	// BatchGetUsers returns the accounts of up to 100 ids in one call. Unknown ids are left out.
+	BatchGetUsers(ctx context.Context, in *BatchGetUsersRequest, opts ...grpc.CallOption) (*BatchGetUsersResponse, error)
+	// UpdateLastLogin records a successful login. An older login never replaces a newer one, and
+	// logins of unknown accounts are ignored.
+	UpdateLastLogin(ctx context.Context, in *UpdateLastLoginRequest, opts ...grpc.CallOption) (*UpdateLastLoginResponse, error)
+}
+
+type userInternalServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUserInternalServiceClient(cc grpc.ClientConnInterface) UserInternalServiceClient {
+	return &userInternalServiceClient{cc}
+}
+
+func (c *userInternalServiceClient) ValidateCredentials(ctx context.Context, in *ValidateCredentialsRequest, opts ...grpc.CallOption) (*ValidateCredentialsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateCredentialsResponse)
+	err := c.cc.Invoke(ctx, UserInternalService_ValidateCredentials_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userInternalServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*GetUserResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUserResponse)
+	err := c.cc.Invoke(ctx, UserInternalService_GetUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userInternalServiceClient) BatchGetUsers(ctx context.Context, in *BatchGetUsersRequest, opts ...grpc.CallOption) (*BatchGetUsersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchGetUsersResponse)
+	err := c.cc.Invoke(ctx, UserInternalService_BatchGetUsers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userInternalServiceClient) UpdateLastLogin(ctx context.Context, in *UpdateLastLoginRequest, opts ...grpc.CallOption) (*UpdateLastLoginResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateLastLoginResponse)
+	err := c.cc.Invoke(ctx, UserInternalService_UpdateLastLogin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserInternalServiceServer is the server API for UserInternalService service.
+// All implementations must embed UnimplementedUserInternalServiceServer
+// for forward compatibility.
+//
+// UserInternalService is the internal API of user-service for auth-service. It is served over gRPC next to
+// the JSON API under /v1/internal/user and accepts the same internal credentials, sent as basic auth in the
+// authorization metadata.
+type UserInternalServiceServer interface {
+	// ValidateCredentials checks the password of an account and returns the account. It fails with
+	// UNAUTHENTICATED for a wrong password and NOT_FOUND for an unknown email address.
+	ValidateCredentials(context.Context, *ValidateCredentialsRequest) (*ValidateCredentialsResponse, error)
+	// GetUser returns an account with all its internal fields
+	GetUser(context.Context, *GetUserRequest) (*GetUserResponse, error)
+	// BatchGetUsers returns the accounts of up to 100 ids in one call. Unknown ids are left out.
+	BatchGetUsers(context.Context, *BatchGetUsersRequest) (*BatchGetUsersResponse, error)
+	// UpdateLastLogin records a successful login. An older login never replaces a newer one, and
+	// logins of unknown accounts are ignored.
+	UpdateLastLogin(context.Context, *UpdateLastLoginRequest) (*UpdateLastLoginResponse, error)
+	mustEmbedUnimplementedUserInternalServiceServer()
+}
+
+// UnimplementedUserInternalServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedUserInternalServiceServer struct{}
+
+func (UnimplementedUserInternalServiceServer) ValidateCredentials(context.Context, *ValidateCredentialsRequest) (*ValidateCredentialsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateCredentials not implemented")
+}
+func (UnimplementedUserInternalServiceServer) GetUser(context.Context, *GetUserRequest) (*GetUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedUserInternalServiceServer) BatchGetUsers(context.Context, *BatchGetUsersRequest) (*BatchGetUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetUsers not implemented")
+}
+func (UnimplementedUserInternalServiceServer) UpdateLastLogin(context.Context, *UpdateLastLoginRequest) (*UpdateLastLoginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateLastLogin not implemented")
+}
+func (UnimplementedUserInternalServiceServer) mustEmbedUnimplementedUserInternalServiceServer() {}
+func (UnimplementedUserInternalServiceServer) testEmbeddedByValue()                             {}
+
+// UnsafeUserInternalServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UserInternalServiceServer will
+// result in compilation errors.
+type UnsafeUserInternalServiceServer interface {
+	mustEmbedUnimplementedUserInternalServiceServer()
+}
+
+func RegisterUserInternalServiceServer(s grpc.ServiceRegistrar, srv UserInternalServiceServer) {
+	// If the following call pancis, it indicates UnimplementedUserInternalServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&UserInternalService_ServiceDesc, srv)
+}
+
+func _UserInternalService_ValidateCredentials_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateCredentialsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserInternalServiceServer).ValidateCredentials(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserInternalService_ValidateCredentials_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserInternalServiceServer).ValidateCredentials(ctx, req.(*ValidateCredentialsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserInternalService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserInternalServiceServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserInternalService_GetUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserInternalServiceServer).GetUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserInternalService_BatchGetUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserInternalServiceServer).BatchGetUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserInternalService_BatchGetUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserInternalServiceServer).BatchGetUsers(ctx, req.(*BatchGetUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserInternalService_UpdateLastLogin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateLastLoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserInternalServiceServer).UpdateLastLogin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserInternalService_UpdateLastLogin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserInternalServiceServer).UpdateLastLogin(ctx, req.(*UpdateLastLoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// UserInternalService_ServiceDesc is the grpc.ServiceDesc for UserInternalService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var UserInternalService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "userinternal.v1.UserInternalService",
+	HandlerType: (*UserInternalServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ValidateCredentials",
+			Handler:    _UserInternalService_ValidateCredentials_Handler,
+		},
+		{
+			MethodName: "GetUser",
+			Handler:    _UserInternalService_GetUser_Handler,
+		},
+		{
+			MethodName: "BatchGetUsers",
+			Handler:    _UserInternalService_BatchGetUsers_Handler,
+		},
+		{
+			MethodName: "UpdateLastLogin",
+			Handler:    _UserInternalService_UpdateLastLogin_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "userinternal/v1/user_internal.proto",
+}
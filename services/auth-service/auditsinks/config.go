@@ -0,0 +1,55 @@
+package auditsinks
+
+import (
+	"fmt"
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/shared/auditsinks"
+	"log"
+)
+
+// Sink types
+const (
+	SinkTypeSyslog    = "syslog"
+	SinkTypeJSONLines = "jsonl"
+)
+
+// NewDispatcherFromConfig builds the configured sinks and starts a dispatcher for them.
+// An invalid sink configuration stops the service, since it would silently lose audit events.
+func NewDispatcherFromConfig(cfg config.AuditConfig, service string) *auditsinks.Dispatcher {
+	sinks := make([]auditsinks.Sink, 0, len(cfg.Sinks))
+	for _, sinkCfg := range cfg.Sinks {
+		sink, err := newSink(sinkCfg, service)
+		if err != nil {
+			log.Fatalf("Invalid %s audit sink configuration: %v", sinkCfg.Type, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	return auditsinks.NewDispatcher(sinks, cfg.BufferSize)
+}
+
+// newSink builds one sink from its configuration
+func newSink(cfg config.AuditSinkConfig, service string) (auditsinks.Sink, error) {
+	switch cfg.Type {
+	case SinkTypeSyslog:
+		appName := cfg.AppName
+		if appName == "" {
+			appName = service
+		}
+		return auditsinks.NewSyslogSink(auditsinks.SyslogConfig{
+			Network:            cfg.Network,
+			Address:            cfg.Address,
+			Format:             cfg.Format,
+			AppName:            appName,
+			CAFile:             cfg.CAFile,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		})
+	case SinkTypeJSONLines:
+		return auditsinks.NewJSONLinesSink(auditsinks.JSONLinesConfig{
+			Path:       cfg.Path,
+			MaxSizeMB:  cfg.MaxSizeMB,
+			MaxBackups: cfg.MaxBackups,
+		})
+	default:
+		return nil, fmt.Errorf("unknown audit sink type %q", cfg.Type)
+	}
+}
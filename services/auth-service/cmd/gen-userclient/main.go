@@ -0,0 +1,32 @@
+// Command gen-userclient generates the user-service client in apiclients/userservice from the OpenAPI spec
+// of user-service. It is run by go generate; test/apiclients fails when the generated client is stale.
+//
+//	go run ./cmd/gen-userclient [-spec ../user-service/docs/openapi.yaml] [-out apiclients/userservice/client.gen.go]
+package main
+
+import (
+	"flag"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	"log"
+	"log/slog"
+	"os"
+)
+
+func main() {
+	specPath := flag.String("spec", "../user-service/docs/openapi.yaml", "OpenAPI spec of user-service")
+	out := flag.String("out", "apiclients/userservice/client.gen.go", "generated client file")
+	flag.Parse()
+
+	spec, err := os.ReadFile(*specPath)
+	if err != nil {
+		log.Fatalf("Failed to read the spec: %v", err)
+	}
+	source, err := userservice.Generate(spec)
+	if err != nil {
+		log.Fatalf("Failed to generate the client: %v", err)
+	}
+	if err := os.WriteFile(*out, source, 0o644); err != nil {
+		log.Fatalf("Failed to write the client: %v", err)
+	}
+	slog.Info("Generated the client", "path", *out)
+}
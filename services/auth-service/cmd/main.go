@@ -1,47 +1,118 @@
 package main
 
 import (
+	"context"
 	config "github.com/Mir00r/auth-service/configs"
 	"github.com/Mir00r/auth-service/containers"
 	database "github.com/Mir00r/auth-service/db"
 	"github.com/Mir00r/auth-service/internal/api/routes"
+	"github.com/Mir00r/auth-service/lifecycle"
+	"github.com/Mir00r/auth-service/logging"
+	"github.com/Mir00r/auth-service/telemetry"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
 	// Step 1: Load Configuration
 	configPath := getConfigPath()
 	if err := config.LoadConfig(configPath); err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Step 2: Initialize Database
+	// Step 2: Write structured logs with secrets masked
+	logging.SetupFromConfig(config.AppConfig.Logging)
+
+	// Step 3: Trace requests across the services
+	telemetry.SetupFromConfig(config.AppConfig.Tracing)
+
+	// Step 4: Initialize Database
 	if err := database.Connect(); err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	// Step 3: Run Database Migrations
-	migrationPath, _ := database.MigrationPath()
-	log.Printf("Resolved migration path: %s", migrationPath)
-	if err := database.RunMigrations(migrationPath, config.AppConfig.Database.DSN); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	// Step 5: Migrate the schema, or refuse to serve when it is not the one this release expects
+	if err := database.PrepareSchema(config.AppConfig.Database); err != nil {
+		log.Fatalf("Database schema not ready: %v", err)
+	}
+
+	// Step 6: Initialize Dependencies
+	appContainer, err := containers.NewContainer()
+	if err != nil {
+		log.Fatalf("Failed to initialize dependencies: %v", err)
+	}
+
+	// Step 7: Run the background workers until shutdown
+	workers := lifecycle.NewWorkers()
+	workers.Go(appContainer.Upstreams.Run) // Keep the discovered instances of upstream services up to date
+	if appContainer.OutboxRelay != nil {
+		workers.Go(appContainer.OutboxRelay.Run) // Publish domain events from the outbox
 	}
+	workers.Go(appContainer.SagaOrchestrator.Run) // Resume sagas interrupted by failures or a restart
+	workers.Go(config.ReloadOnSignal)             // Apply the reloadable configuration on SIGHUP
 
-	// Step 4: Initialize Dependencies
-	appContainer := containers.NewContainer()
+	// Step 8: Consume events from user-service
+	if appContainer.EventSubscriber != nil {
+		if err := appContainer.UserEventConsumer.Start(context.Background(), appContainer.EventSubscriber, config.AppConfig.Messaging.UserStream); err != nil {
+			log.Fatalf("Failed to subscribe to user-service events: %v", err)
+		}
+	}
 
-	// Step 5: Setup Router
-	router := gin.Default()
+	// Step 9: Setup Router
+	router := gin.New()
+	router.Use(gin.Recovery())
 	routes.SetupRoutes(router,
-		appContainer.PublicAuthController, appContainer.ProtectedAuthController,
-		appContainer.InternalAuthController,
+		appContainer.PublicAuthController, appContainer.RegistrationController, appContainer.PasswordlessController, appContainer.FederatedAuthController,
+		appContainer.ProtectedAuthController, appContainer.IdentityController, appContainer.AccountDeletionController,
+		appContainer.InternalAuthController, appContainer.AuditController, appContainer.SagaController, appContainer.HealthController,
 	)
 
-	// Step 6: Start Server
-	startServer(router)
+	// Step 10: Serve until SIGINT or SIGTERM
+	server := &http.Server{Addr: ":" + config.AppConfig.Server.Port, Handler: router}
+	serve(server)
+
+	// Step 11: Drain the requests in flight and stop the rest in order
+	shutdown := lifecycle.NewShutdownFromConfig(config.AppConfig.Server)
+	shutdown.Add("readiness", func(ctx context.Context) error {
+		appContainer.HealthRegistry.Drain()
+		return lifecycle.Wait(ctx, shutdown.DrainDelay)
+	})
+	shutdown.Add("HTTP server", func(ctx context.Context) error {
+		if err := server.Shutdown(ctx); err != nil {
+			_ = server.Close() // Cut the requests that outlived the deadline
+			return err
+		}
+		return nil
+	})
+	if appContainer.EventSubscriber != nil {
+		shutdown.Add("event subscriber", func(context.Context) error { return appContainer.EventSubscriber.Close() })
+	}
+	shutdown.Add("background workers", workers.Stop)
+	if appContainer.OutboxRelay != nil {
+		shutdown.Add("outbox relay", func(context.Context) error { return appContainer.OutboxRelay.Close() })
+	}
+	shutdown.Add("user-service client", func(context.Context) error { return appContainer.UserClient.Close() })
+	shutdown.Add("audit log", appContainer.AuditService.Close)
+	shutdown.Add("audit sinks", appContainer.AuditDispatcher.Close)
+	shutdown.Add("tracing", telemetry.Shutdown)
+	shutdown.Add("database", func(context.Context) error { return database.Close() })
+	if err := shutdown.Run(); err != nil {
+		log.Fatalf("Shutdown incomplete: %v", err)
+	}
+	slog.Info("Server stopped")
 }
 
 // getConfigPath determines the configuration file path
@@ -53,15 +124,21 @@ func getConfigPath() string {
 	return configPath
 }
 
-// startServer starts the Gin server on the configured port
-func startServer(router *gin.Engine) {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8081" // Default port
-	}
+// serve serves requests until the service receives SIGINT or SIGTERM. It stops the service when the server
+// cannot listen.
+func serve(server *http.Server) {
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting server", "addr", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
 
-	log.Printf("Starting server on port %s\n", port)
-	if err := router.Run(":" + port); err != nil {
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-serverErr:
 		log.Fatalf("Failed to start server: %v", err)
+	case <-signals.Done():
+		slog.Info("Shutting down")
 	}
 }
@@ -0,0 +1,117 @@
+// Command reconcile-credentials moves the password hashes still stored by auth-service to user-service,
+// which is the only store of credentials. It prints the plan by default and changes data only with -apply.
+//
+//	go run ./cmd/reconcile-credentials -user-dsn postgres://... [-strategy newest|user-service|auth-service] [-apply]
+package main
+
+import (
+	"flag"
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/internal/reconcile"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"log"
+	"log/slog"
+	"os"
+)
+
+func main() {
+	configPath := flag.String("config", "./configs/config.yaml", "auth-service configuration file")
+	authDSN := flag.String("auth-dsn", "", "auth-service database DSN, defaults to the configured one")
+	userDSN := flag.String("user-dsn", "", "user-service database DSN")
+	strategyName := flag.String("strategy", string(reconcile.PreferNewest), "winner when both services hold different hashes: newest, user-service or auth-service")
+	apply := flag.Bool("apply", false, "apply the plan instead of only printing it")
+	flag.Parse()
+
+	strategy, err := reconcile.ParseStrategy(*strategyName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *userDSN == "" {
+		log.Fatal("-user-dsn is required")
+	}
+	if *authDSN == "" {
+		if envPath := os.Getenv("CONFIG_PATH"); envPath != "" {
+			*configPath = envPath
+		}
+		if err := config.LoadConfig(*configPath); err != nil {
+			log.Fatalf("Failed to load configuration: %v", err)
+		}
+		*authDSN = config.AppConfig.Database.URL()
+	}
+
+	authDB := openDB(*authDSN)
+	userDB := openDB(*userDSN)
+
+	local := loadCredentials(authDB)
+	remote := loadCredentials(userDB)
+	actions := reconcile.Plan(local, remote, strategy)
+
+	counts := map[reconcile.ActionType]int{}
+	for _, action := range actions {
+		counts[action.Type]++
+		slog.Info("Planned", "action", action.Type, "email", action.Credential.Email, "reason", action.Reason)
+	}
+	slog.Info("Accounts to reconcile", "total", len(actions), "create", counts[reconcile.CreateInUserService],
+		"update", counts[reconcile.UpdateUserService], "keep", counts[reconcile.KeepUserService])
+
+	if !*apply {
+		slog.Info("Dry run, nothing changed. Re-run with -apply to reconcile.")
+		return
+	}
+
+	failed := 0
+	for _, action := range actions {
+		if err := applyAction(authDB, userDB, action); err != nil {
+			failed++
+			slog.Error("Failed to reconcile", "email", action.Credential.Email, "error", err)
+		}
+	}
+	if failed > 0 {
+		log.Fatalf("%d of %d accounts failed; re-running the tool retries them", failed, len(actions))
+	}
+	slog.Info("Credentials reconciled")
+}
+
+// openDB connects to a Postgres database
+func openDB(dsn string) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Warn)})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	return db
+}
+
+// loadCredentials reads the credentials of every account in the users table of a service
+func loadCredentials(db *gorm.DB) []reconcile.Credential {
+	var credentials []reconcile.Credential
+	err := db.Raw(`SELECT email, name, password AS password_hash, updated_at FROM auth.users WHERE deleted_at IS NULL`).
+		Scan(&credentials).Error
+	if err != nil {
+		log.Fatalf("Failed to read credentials: %v", err)
+	}
+	return credentials
+}
+
+// applyAction writes the winning hash to user-service and then clears the local copy.
+// Each step is idempotent, so an interrupted run is completed by running the tool again.
+func applyAction(authDB, userDB *gorm.DB, action reconcile.Action) error {
+	credential := action.Credential
+
+	var err error
+	switch action.Type {
+	case reconcile.CreateInUserService:
+		err = userDB.Exec(`INSERT INTO auth.users (name, email, password, is_active) VALUES (?, ?, ?, TRUE)
+			ON CONFLICT (email) DO NOTHING`, credential.Name, credential.Email, credential.PasswordHash).Error
+	case reconcile.UpdateUserService:
+		err = userDB.Exec(`UPDATE auth.users SET password = ?, updated_at = now() WHERE LOWER(email) = LOWER(?)`,
+			credential.PasswordHash, credential.Email).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	return authDB.Exec(`UPDATE auth.users SET password = '' WHERE LOWER(email) = LOWER(?) AND password = ?`,
+		credential.Email, credential.PasswordHash).Error
+}
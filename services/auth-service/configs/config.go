@@ -1,70 +1,247 @@
+// Package config holds the configuration of the service. It is built in layers: defaults, then the YAML file,
+// then environment variables, then secret files named by environment variables, and it is validated as a whole
+// before the service starts. Fields tagged secret:"true" are masked wherever the configuration is shown, and
+// fields tagged reload:"true" can be changed without a restart by sending the service SIGHUP.
 package config
 
 import (
-	"gopkg.in/yaml.v3"
-	"log"
+	"log/slog"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
 )
 
 type Config struct {
-	Server           ServerConfig           `yaml:"server"`
-	JWT              JWTConfig              `yaml:"jwt"`
-	Database         DatabaseConfig         `yaml:"database"`
-	Redis            RedisConfig            `yaml:"redis"`
-	Password         PasswordConfig         `yaml:"password"`
-	InternalSecurity InternalSecurityConfig `yaml:"internal-security"`
+	Server           ServerConfig              `yaml:"server"`
+	JWT              JWTConfig                 `yaml:"jwt"`
+	Database         DatabaseConfig            `yaml:"database"`
+	Redis            RedisConfig               `yaml:"redis"`
+	Password         PasswordConfig            `yaml:"password"`
+	Passwordless     PasswordlessConfig        `yaml:"passwordless"`
+	OIDC             OIDCConfig                `yaml:"oidc"`
+	Identity         IdentityConfig            `yaml:"identity"`
+	InternalSecurity InternalSecurityConfig    `yaml:"internal-security"`
+	AdminSecurity    AdminSecurityConfig       `yaml:"admin-security"`
+	Audit            AuditConfig               `yaml:"audit"`
+	Messaging        MessagingConfig           `yaml:"messaging"`
+	Registration     RegistrationConfig        `yaml:"registration"`
+	Saga             SagaConfig                `yaml:"saga"`
+	AccountDeletion  AccountDeletionConfig     `yaml:"account-deletion"`
+	Outbound         OutboundConfig            `yaml:"outbound"`
+	Upstreams        map[string]UpstreamConfig `yaml:"upstreams"` // Services called by name, e.g. user-service
+	UserService      UserServiceConfig         `yaml:"user-service"`
+	Tracing          TracingConfig             `yaml:"tracing"`
+	Logging          LoggingConfig             `yaml:"logging"`
+	Health           HealthConfig              `yaml:"health" reload:"true"`
 }
 
 type ServerConfig struct {
-	Port string `yaml:"port"`
+	Port            string   `yaml:"port" env:"PORT"`
+	DrainDelay      Duration `yaml:"drain-delay"`      // Time readiness fails before the server stops accepting connections, none when zero
+	ShutdownTimeout Duration `yaml:"shutdown-timeout"` // Time in-flight requests and background work get to finish on SIGTERM, 30s when zero
 }
 
 type JWTConfig struct {
-	Secret             string `yaml:"secret"`
-	Expiry             string `yaml:"expiry"`
-	RefreshTokenExpiry string `yaml:"refresh-token-expiry"`
+	Secret             string   `yaml:"secret" secret:"true"`
+	Expiry             Duration `yaml:"expiry"`
+	RefreshTokenExpiry Duration `yaml:"refresh-token-expiry"`
 }
 
 type DatabaseConfig struct {
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
-	DBName   string `yaml:"dbname"`
-	DSN      string `yaml:"dsn"`
+	Host       string           `yaml:"host"`
+	Port       int              `yaml:"port"`
+	User       string           `yaml:"user"`
+	Password   string           `yaml:"password" secret:"true"`
+	DBName     string           `yaml:"dbname"`
+	DSN        string           `yaml:"dsn" secret:"true"` // URL of the database for migrations, built from the fields above when empty
+	Migrations MigrationsConfig `yaml:"migrations"`
+}
+
+type MigrationsConfig struct {
+	Mode        string   `yaml:"mode"`         // up applies the pending migrations at startup, verify only checks the schema is at the version of the release
+	LockTimeout Duration `yaml:"lock-timeout"` // Time to wait for another replica migrating the schema
+}
+
+// URL returns the URL of the database, the DSN when set
+func (c DatabaseConfig) URL() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+	return (&url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.DBName,
+		RawQuery: "sslmode=disable",
+	}).String()
 }
 
 type RedisConfig struct {
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
-	Password string `yaml:"password"`
+	Password string `yaml:"password" secret:"true"`
 }
 
 type PasswordConfig struct {
-	PasswordResetURL string `yaml:"PasswordResetURL"`
+	PasswordResetURL string   `yaml:"PasswordResetURL"`
+	ResetTokenExpiry Duration `yaml:"reset-token-expiry"`
+}
+
+type PasswordlessConfig struct {
+	LoginURL        string   `yaml:"login-url"`
+	LinkExpiry      Duration `yaml:"link-expiry"`
+	CodeExpiry      Duration `yaml:"code-expiry"`
+	MaxCodeAttempt  int      `yaml:"max-code-attempts"`
+	MaxChallenges   int      `yaml:"max-challenges"` // Links and codes issued to an account per challenge-window
+	ChallengeWindow Duration `yaml:"challenge-window"`
+}
+
+type OIDCConfig struct {
+	StateExpiry Duration             `yaml:"state-expiry"`
+	Providers   []OIDCProviderConfig `yaml:"providers"`
+}
+
+type OIDCProviderConfig struct {
+	Name         string   `yaml:"name"`
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client-id"`
+	ClientSecret string   `yaml:"client-secret" secret:"true"`
+	RedirectURL  string   `yaml:"redirect-url"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+type IdentityConfig struct {
+	ReauthMaxAge Duration `yaml:"reauth-max-age"`
 }
 
 type InternalSecurityConfig struct {
-	BaseUrl  string `yaml:"base-url:"`
+	BaseUrl  string `yaml:"base-url"` // user-service URL, used only when no user-service upstream is configured
+	UserName string `yaml:"username"`
+	Password string `yaml:"password" secret:"true"`
+}
+
+type AdminSecurityConfig struct {
 	UserName string `yaml:"username"`
-	Password string `yaml:"password"`
+	Password string `yaml:"password" secret:"true"`
+}
+
+type AuditConfig struct {
+	BufferSize int               `yaml:"buffer-size"`
+	ChainKey   string            `yaml:"chain-key" secret:"true"` // Keys the hash chain of the audit log; never stored in the database
+	Sinks      []AuditSinkConfig `yaml:"sinks"`
+}
+
+type AuditSinkConfig struct {
+	Type               string `yaml:"type"`    // syslog or jsonl
+	Network            string `yaml:"network"` // syslog: udp, tcp or tls
+	Address            string `yaml:"address"` // syslog: host:port
+	Format             string `yaml:"format"`  // syslog: cef or json
+	AppName            string `yaml:"app-name"`
+	CAFile             string `yaml:"ca-file"`
+	InsecureSkipVerify bool   `yaml:"insecure-skip-verify"`
+	Path               string `yaml:"path"` // jsonl: file path
+	MaxSizeMB          int    `yaml:"max-size-mb"`
+	MaxBackups         int    `yaml:"max-backups"`
 }
 
+type MessagingConfig struct {
+	NatsURL   string       `yaml:"nats-url"`  // The outbox relay is disabled when empty
+	JetStream bool         `yaml:"jetstream"` // Without JetStream events published while no subscriber is connected are lost
+	Outbox    OutboxConfig `yaml:"outbox"`
+	// Stream is the JetStream stream capturing the auth.> events this service publishes
+	Stream string `yaml:"stream"`
+	// UserStream is the JetStream stream capturing the user.> events of user-service
+	UserStream string `yaml:"user-stream"`
+}
+
+type OutboxConfig struct {
+	PollInterval Duration `yaml:"poll-interval"`
+	BatchSize    int      `yaml:"batch-size"`
+}
+
+type RegistrationConfig struct {
+	VerificationURL    string   `yaml:"verification-url"`
+	VerificationExpiry Duration `yaml:"verification-expiry"`
+}
+
+type SagaConfig struct {
+	PollInterval Duration `yaml:"poll-interval"`
+	MaxAttempts  int      `yaml:"max-attempts"` // Attempts of a step before the saga is rolled back
+}
+
+type AccountDeletionConfig struct {
+	GracePeriod Duration `yaml:"grace-period"` // Time the owner has to cancel a requested deletion
+}
+
+// OutboundConfig holds the resilience policies of calls to other services
+type OutboundConfig struct {
+	Default OutboundPolicyConfig            `yaml:"default"`
+	Targets map[string]OutboundPolicyConfig `yaml:"targets"` // Keyed by upstream name or instance host:port; unset fields fall back to the default
+}
+
+type OutboundPolicyConfig struct {
+	Timeout          Duration `yaml:"timeout"`           // Per attempt
+	MaxAttempts      int      `yaml:"max-attempts"`      // Attempts of idempotent calls
+	InitialBackoff   Duration `yaml:"initial-backoff"`   // Backoff before the first retry, doubled per retry
+	MaxBackoff       Duration `yaml:"max-backoff"`       // Upper bound of the backoff
+	MaxConcurrent    int      `yaml:"max-concurrent"`    // Calls in flight at once, unlimited when zero
+	QueueTimeout     Duration `yaml:"queue-timeout"`     // Wait for a free slot before a call is rejected
+	FailureThreshold int      `yaml:"failure-threshold"` // Consecutive failures that open the circuit breaker
+	OpenTimeout      Duration `yaml:"open-timeout"`      // Time the breaker stays open before probing
+	HalfOpenProbes   int      `yaml:"half-open-probes"`  // Successful probes that close the breaker again
+}
+
+// UpstreamConfig tells how the instances of an upstream service are discovered
+type UpstreamConfig struct {
+	Discovery       string   `yaml:"discovery"`        // static, dns-srv or file
+	Scheme          string   `yaml:"scheme"`           // http or https, http when empty
+	Endpoints       []string `yaml:"endpoints"`        // static: host:port of each instance
+	SRV             string   `yaml:"srv"`              // dns-srv: record name, e.g. _http._tcp.user-service.internal; priorities and weights are honoured
+	File            string   `yaml:"file"`             // file: path of a list of host:port, one per line, re-read as soon as it changes
+	RefreshInterval Duration `yaml:"refresh-interval"` // dns-srv and file: interval between lookups; for file, a fallback to watching it
+}
+
+// UserServiceConfig tells how the calls to user-service that are offered over both HTTP and gRPC are made
+type UserServiceConfig struct {
+	Transport string `yaml:"transport"` // http or grpc, http when empty; grpc calls the user-service-grpc upstream
+}
+
+// TracingConfig tells where the OpenTelemetry spans of the service are exported
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`                   // otlp, stdout, file or none; spans are not recorded when empty or none
+	Endpoint    string  `yaml:"endpoint"`                   // otlp: host:port of the collector, OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4317 when empty
+	Insecure    bool    `yaml:"insecure"`                   // otlp: connect to the collector without TLS
+	Path        string  `yaml:"path"`                       // file: file the spans are appended to, one JSON object per line
+	SampleRatio float64 `yaml:"sample-ratio" reload:"true"` // Share of the traces started by the service that are sampled, all when zero
+}
+
+// LoggingConfig tells how the records of the service are written to stdout
+type LoggingConfig struct {
+	Level  string `yaml:"level" reload:"true"` // debug, info, warn or error, info when empty; adjustable at runtime
+	Format string `yaml:"format"`              // json or text, json when empty
+}
+
+// HealthConfig tells how the dependencies of the service are checked by its readiness probe
+type HealthConfig struct {
+	CacheTTL Duration `yaml:"cache-ttl"` // Time the results of the checks are reused, 5s when zero
+	Timeout  Duration `yaml:"timeout"`   // Time a check may take before it fails, 2s when zero
+}
+
+// AppConfig is the configuration the service started with. It is set by LoadConfig before anything reads it and
+// is left as it is by a reload, since requests read it concurrently; reloaded fields reach the parts of the
+// service using them through OnReload.
 var AppConfig Config
 
+// LoadConfig loads the configuration from the file at path and the environment into AppConfig, as described by
+// Load, and returns every problem found at once
 func LoadConfig(path string) error {
-	file, err := os.Open(path)
+	cfg, err := Load(path, os.LookupEnv)
 	if err != nil {
-		log.Fatalf("Failed to open config file: %v", err)
-		return err
-	}
-	defer file.Close()
-
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&AppConfig); err != nil {
-		log.Fatalf("Failed to decode config file: %v", err)
 		return err
 	}
-	log.Println("Configuration loaded successfully")
+	AppConfig = *cfg
+	setCurrent(path, cfg)
+	slog.Info("Configuration loaded successfully")
 	return nil
 }
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+)
+
+// redacted replaces the value of a secret field that is set
+const redacted = "[REDACTED]"
+
+// Redacted returns the configuration as it would be written in YAML, with the value of every secret field that
+// is set replaced by [REDACTED], so it can be shown to operators without leaking credentials
+func (c *Config) Redacted() map[string]any {
+	return redactSection(reflect.ValueOf(*c))
+}
+
+// redactSection returns the fields of the section v keyed by YAML name
+func redactSection(v reflect.Value) map[string]any {
+	fields := make(map[string]any, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := fieldPath("", field)
+		if name == "" {
+			continue
+		}
+		if field.Tag.Get("secret") == "true" {
+			if v.Field(i).IsZero() {
+				fields[name] = ""
+			} else {
+				fields[name] = redacted
+			}
+			continue
+		}
+		fields[name] = redactValue(v.Field(i))
+	}
+	return fields
+}
+
+// redactValue returns value with the secrets of the sections it holds redacted
+func redactValue(value reflect.Value) any {
+	if marshaler, ok := value.Interface().(encoding.TextMarshaler); ok {
+		text, _ := marshaler.MarshalText()
+		return string(text)
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		return redactSection(value)
+	case reflect.Map:
+		entries := make(map[string]any, value.Len())
+		for iter := value.MapRange(); iter.Next(); {
+			entries[iter.Key().String()] = redactValue(iter.Value())
+		}
+		return entries
+	case reflect.Slice:
+		items := make([]any, value.Len())
+		for i := range items {
+			items[i] = redactValue(value.Index(i))
+		}
+		return items
+	default:
+		return value.Interface()
+	}
+}
+
+// Handler serves the configuration the service currently runs with, secrets redacted, including the fields
+// changed by the latest reload
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		cfg := Current()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(cfg.Redacted())
+	})
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"time"
+)
+
+// Duration is a duration of the configuration, written as in Go, such as 500ms, 30s or 24h.
+// It is parsed when the configuration loads, so a malformed value stops the service at startup rather than
+// failing the first request that needs it.
+type Duration time.Duration
+
+// Duration returns the duration as a time.Duration
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// String formats the duration as it is written in the configuration
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. An empty text is zero.
+func (d *Duration) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*d = 0
+		return nil
+	}
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q, expected a value such as 30s or 5m", text)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler. A malformed duration is returned as a *yaml.TypeError, so the
+// decoder goes on and reports it together with the other errors of the file.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: expected a duration", node.Line)}}
+	}
+	if err := d.UnmarshalText([]byte(node.Value)); err != nil {
+		return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: %v", node.Line, err)}}
+	}
+	return nil
+}
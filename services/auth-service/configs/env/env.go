@@ -2,6 +2,7 @@ package env
 
 import (
 	"log"
+	"log/slog"
 	"os"
 	"strconv"
 
@@ -11,7 +12,7 @@ import (
 // LoadEnv loads environment variables from a `.env` file, if it exists
 func LoadEnv() {
 	if _, err := os.Stat(".env"); err == nil {
-		log.Println("Loading environment variables from .env file")
+		slog.Info("Loading environment variables from .env file")
 		if err := godotenv.Load(); err != nil {
 			log.Fatalf("Error loading .env file: %v", err)
 		}
@@ -24,7 +25,7 @@ func LoadYamlEnv() {
 	if envPath := os.Getenv("CONFIG_PATH"); envPath != "" {
 		configPath = envPath
 	}
-	log.Println("Loading environment variables from .yaml file")
+	slog.Info("Loading environment variables from .yaml file")
 	file, err := os.Open(configPath)
 	if err != nil {
 		log.Fatalf("Failed to open config file: %v", err)
@@ -55,7 +56,7 @@ func GetEnvAsInt(key string, defaultValue int) int {
 
 	value, err := strconv.Atoi(valueStr)
 	if err != nil {
-		log.Printf("Invalid integer for key %s: %v. Using default: %d", key, err, defaultValue)
+		slog.Warn("Invalid integer, using the default", "key", key, "default", defaultValue, "error", err)
 		return defaultValue
 	}
 
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// hook is a function called when a reload changes the field at path or a field under it
+type hook struct {
+	path  string
+	apply func(cfg Config)
+}
+
+var (
+	current atomic.Pointer[Config] // Configuration the service runs with, AppConfig until the first reload
+
+	reloadMu   sync.Mutex // Serializes reloads and guards the fields below
+	loadedFrom string     // File the configuration was loaded from
+	hooks      []hook
+)
+
+// setCurrent records the configuration loaded from path as the one the service runs with
+func setCurrent(path string, cfg *Config) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	loadedFrom = path
+	current.Store(cfg)
+}
+
+// Current returns the configuration the service runs with: AppConfig with the fields changed by reloads
+func Current() Config {
+	if cfg := current.Load(); cfg != nil {
+		return *cfg
+	}
+	return AppConfig
+}
+
+// OnReload registers apply to be called with the new configuration whenever a reload changes the reloadable
+// field at path, such as logging.level, or a field under it
+func OnReload(path string, apply func(cfg Config)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	hooks = append(hooks, hook{path: path, apply: apply})
+}
+
+// Reload loads the configuration again from the file and the environment. The fields tagged reload:"true" take
+// their new values, which are passed to the hooks registered with OnReload; a change to any other field is
+// logged and waits for a restart. An invalid configuration is rejected as a whole and changes nothing.
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	next, err := Load(loadedFrom, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+	cfg := Current()
+	reloaded, pending := mergeReloadable(reflect.ValueOf(&cfg).Elem(), reflect.ValueOf(*next), "")
+	for _, path := range pending {
+		slog.Warn("Configuration changed, restart the service to apply it", "field", path)
+	}
+	if len(reloaded) == 0 {
+		slog.Info("Configuration reloaded, no reloadable field changed")
+		return nil
+	}
+
+	current.Store(&cfg)
+	for _, h := range hooks {
+		if slices.ContainsFunc(reloaded, func(path string) bool { return affects(path, h.path) }) {
+			h.apply(cfg)
+		}
+	}
+	slog.Info("Configuration reloaded", "applied", reloaded)
+	return nil
+}
+
+// ReloadOnSignal reloads the configuration whenever the service receives SIGHUP, until ctx is done
+func ReloadOnSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if err := Reload(); err != nil {
+				slog.ErrorContext(ctx, "Configuration not reloaded", "error", err)
+			}
+		}
+	}
+}
+
+// mergeReloadable copies the reloadable fields of the section next that changed into the section cfg, at the YAML
+// path prefix. It returns the paths of the fields copied and of the changed fields that need a restart.
+func mergeReloadable(cfg, next reflect.Value, prefix string) (reloaded, pending []string) {
+	for i := 0; i < cfg.NumField(); i++ {
+		field := cfg.Type().Field(i)
+		path := fieldPath(prefix, field)
+		if path == "" || reflect.DeepEqual(cfg.Field(i).Interface(), next.Field(i).Interface()) {
+			continue
+		}
+		switch {
+		case field.Tag.Get("reload") == "true":
+			cfg.Field(i).Set(next.Field(i))
+			reloaded = append(reloaded, path)
+		case isSection(cfg.Field(i)):
+			sectionReloaded, sectionPending := mergeReloadable(cfg.Field(i), next.Field(i), path)
+			reloaded = append(reloaded, sectionReloaded...)
+			pending = append(pending, sectionPending...)
+		default:
+			pending = append(pending, path)
+		}
+	}
+	return reloaded, pending
+}
+
+// affects tells whether a change of the field at changed concerns a hook on the field at path
+func affects(changed, path string) bool {
+	return changed == path || strings.HasPrefix(changed, path+".") || strings.HasPrefix(path, changed+".")
+}
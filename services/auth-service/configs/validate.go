@@ -0,0 +1,197 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// problems collects the problems of a configuration, each prefixed with the YAML path of its field
+type problems []error
+
+func (p *problems) add(path, format string, args ...any) {
+	*p = append(*p, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+func (p *problems) required(path, value string) {
+	if strings.TrimSpace(value) == "" {
+		p.add(path, "required")
+	}
+}
+
+func (p *problems) positive(path string, value Duration) {
+	if value <= 0 {
+		p.add(path, "must be greater than zero, got %s", value)
+	}
+}
+
+func (p *problems) notNegative(path string, value Duration) {
+	if value < 0 {
+		p.add(path, "must not be negative, got %s", value)
+	}
+}
+
+func (p *problems) port(path string, value int) {
+	if value < 1 || value > 65535 {
+		p.add(path, "must be a port between 1 and 65535, got %d", value)
+	}
+}
+
+func (p *problems) listenPort(path, value string) {
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		p.add(path, "must be a number, got %q", value)
+		return
+	}
+	p.port(path, port)
+}
+
+func (p *problems) oneOf(path, value string, allowed ...string) {
+	if !slices.Contains(allowed, strings.ToLower(value)) {
+		p.add(path, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+}
+
+// Validate checks the configuration as a whole and returns all its problems joined, nil when there are none
+func (c *Config) Validate() error {
+	var p problems
+
+	p.listenPort("server.port", c.Server.Port)
+	p.notNegative("server.drain-delay", c.Server.DrainDelay)
+	p.notNegative("server.shutdown-timeout", c.Server.ShutdownTimeout)
+	if c.Server.ShutdownTimeout > 0 && c.Server.DrainDelay >= c.Server.ShutdownTimeout {
+		p.add("server.drain-delay", "must be shorter than server.shutdown-timeout (%s), got %s", c.Server.ShutdownTimeout, c.Server.DrainDelay)
+	}
+
+	p.required("jwt.secret", c.JWT.Secret)
+	p.positive("jwt.expiry", c.JWT.Expiry)
+	p.positive("jwt.refresh-token-expiry", c.JWT.RefreshTokenExpiry)
+
+	p.required("database.host", c.Database.Host)
+	p.port("database.port", c.Database.Port)
+	p.required("database.user", c.Database.User)
+	p.required("database.dbname", c.Database.DBName)
+	if c.Database.DSN == "" { // A DSN carries its own credentials
+		p.required("database.password", c.Database.Password)
+	}
+	p.oneOf("database.migrations.mode", c.Database.Migrations.Mode, "up", "verify")
+	p.positive("database.migrations.lock-timeout", c.Database.Migrations.LockTimeout)
+
+	if c.Redis.Host != "" {
+		p.port("redis.port", c.Redis.Port)
+	}
+
+	p.positive("password.reset-token-expiry", c.Password.ResetTokenExpiry)
+	p.positive("passwordless.link-expiry", c.Passwordless.LinkExpiry)
+	p.positive("passwordless.code-expiry", c.Passwordless.CodeExpiry)
+	if c.Passwordless.MaxCodeAttempt < 0 {
+		p.add("passwordless.max-code-attempts", "must not be negative, got %d", c.Passwordless.MaxCodeAttempt)
+	}
+	if c.Passwordless.MaxChallenges < 0 {
+		p.add("passwordless.max-challenges", "must not be negative, got %d", c.Passwordless.MaxChallenges)
+	}
+	p.notNegative("passwordless.challenge-window", c.Passwordless.ChallengeWindow)
+
+	p.positive("oidc.state-expiry", c.OIDC.StateExpiry)
+	for i, provider := range c.OIDC.Providers {
+		path := fmt.Sprintf("oidc.providers[%d]", i)
+		p.required(path+".name", provider.Name)
+		p.required(path+".issuer", provider.Issuer)
+		p.required(path+".client-id", provider.ClientID)
+		p.required(path+".redirect-url", provider.RedirectURL)
+	}
+	p.positive("identity.reauth-max-age", c.Identity.ReauthMaxAge)
+
+	p.required("internal-security.username", c.InternalSecurity.UserName)
+	p.required("internal-security.password", c.InternalSecurity.Password)
+	if c.InternalSecurity.BaseUrl != "" {
+		if baseURL, err := url.Parse(c.InternalSecurity.BaseUrl); err != nil || baseURL.Host == "" {
+			p.add("internal-security.base-url", "must be an absolute URL, got %q", c.InternalSecurity.BaseUrl)
+		}
+	}
+
+	p.required("audit.chain-key", c.Audit.ChainKey)
+	for i, sink := range c.Audit.Sinks {
+		path := fmt.Sprintf("audit.sinks[%d]", i)
+		switch sink.Type {
+		case "syslog":
+			p.required(path+".address", sink.Address)
+		case "jsonl":
+			p.required(path+".path", sink.Path)
+		default:
+			p.oneOf(path+".type", sink.Type, "syslog", "jsonl")
+		}
+	}
+
+	p.notNegative("messaging.outbox.poll-interval", c.Messaging.Outbox.PollInterval)
+	if c.Messaging.NatsURL != "" && c.Messaging.JetStream {
+		p.required("messaging.stream", c.Messaging.Stream)
+		p.required("messaging.user-stream", c.Messaging.UserStream)
+	}
+	p.positive("registration.verification-expiry", c.Registration.VerificationExpiry)
+	p.notNegative("saga.poll-interval", c.Saga.PollInterval)
+	p.positive("account-deletion.grace-period", c.AccountDeletion.GracePeriod)
+
+	c.Outbound.Default.validate(&p, "outbound.default")
+	for _, name := range slices.Sorted(maps.Keys(c.Outbound.Targets)) {
+		c.Outbound.Targets[name].validate(&p, "outbound.targets."+name)
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Upstreams)) {
+		c.Upstreams[name].validate(&p, "upstreams."+name)
+	}
+
+	p.oneOf("user-service.transport", c.UserService.Transport, "", "http", "grpc")
+	p.oneOf("tracing.exporter", c.Tracing.Exporter, "", "none", "otlp", "stdout", "file")
+	if c.Tracing.Exporter == "file" {
+		p.required("tracing.path", c.Tracing.Path)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		p.add("tracing.sample-ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+	p.oneOf("logging.level", c.Logging.Level, "", "debug", "info", "warn", "error")
+	p.oneOf("logging.format", c.Logging.Format, "", "json", "text")
+	p.notNegative("health.cache-ttl", c.Health.CacheTTL)
+	p.notNegative("health.timeout", c.Health.Timeout)
+
+	return errors.Join(p...)
+}
+
+// validate checks one outbound policy
+func (c OutboundPolicyConfig) validate(p *problems, path string) {
+	p.notNegative(path+".timeout", c.Timeout)
+	p.notNegative(path+".initial-backoff", c.InitialBackoff)
+	p.notNegative(path+".max-backoff", c.MaxBackoff)
+	p.notNegative(path+".queue-timeout", c.QueueTimeout)
+	p.notNegative(path+".open-timeout", c.OpenTimeout)
+	if c.MaxBackoff > 0 && c.InitialBackoff > c.MaxBackoff {
+		p.add(path+".initial-backoff", "must not exceed max-backoff (%s), got %s", c.MaxBackoff, c.InitialBackoff)
+	}
+}
+
+// validate checks the discovery of one upstream
+func (c UpstreamConfig) validate(p *problems, path string) {
+	switch c.Discovery {
+	case "", "static":
+		if len(c.Endpoints) == 0 {
+			p.add(path+".endpoints", "required for static discovery")
+		}
+		for _, endpoint := range c.Endpoints {
+			if _, _, err := net.SplitHostPort(endpoint); err != nil {
+				p.add(path+".endpoints", "must be host:port, got %q", endpoint)
+			}
+		}
+	case "dns-srv":
+		p.required(path+".srv", c.SRV)
+	case "file":
+		p.required(path+".file", c.File)
+	default:
+		p.oneOf(path+".discovery", c.Discovery, "static", "dns-srv", "file")
+	}
+	p.oneOf(path+".scheme", c.Scheme, "", "http", "https")
+	p.notNegative(path+".refresh-interval", c.RefreshInterval)
+}
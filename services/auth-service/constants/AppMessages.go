@@ -39,6 +39,20 @@ const (
 	ErrFailedToVerifyMFA              = "Failed to verify MFA"
 	ErrFailedToSendOTPEmail           = "Failed to send OTP email"
 	ErrFailedToMarkMFA                = "Failed to mark MFA as used"
+	ErrFailedToStartPasswordless      = "Failed to start passwordless login"
+	ErrFailedToVerifyPasswordless     = "Failed to verify passwordless login"
+	ErrFailedToStartFederatedLogin    = "Failed to start federated login"
+	ErrFailedToProvisionUser          = "Failed to provision federated user"
+	ErrFailedToLinkIdentity           = "Failed to link identity"
+	ErrFailedToUnlinkIdentity         = "Failed to unlink identity"
+	ErrFailedToFetchIdentities        = "Failed to fetch linked identities"
+	ErrFailedToFetchAuditLog          = "Failed to fetch audit log"
+	ErrFailedToCheckAccountStatus     = "Failed to check the account status"
+	ErrCredentialStoreUnavailable     = "Credential store is unavailable"
+	ErrFailedToSendVerificationEmail  = "Failed to send the verification email"
+	ErrFailedToVerifyEmail            = "Failed to verify the email address"
+	ErrFailedToScheduleDeletion       = "Failed to schedule the account deletion"
+	ErrFailedToFetchSagas             = "Failed to fetch sagas"
 )
 
 // Error variables for use throughout the project
@@ -84,11 +98,23 @@ const (
 	MsgLogoutSuccessful             = "Logout successful"
 	MsgUserRegSuccessful            = "User registration successful"
 	MFAVerifySuccessful             = "MFA verification successful"
+	MFAChallengeSent                = "MFA code sent to your email"
 	PasswordResetLinkSentSuccessful = "Password reset link sent successfully"
+	PasswordResetRequestAccepted    = "If an account exists for this email, a password reset link has been sent"
+	PasswordResetSuccessful         = "Password has been reset successfully"
+	PasswordChangedSuccessful       = "Password changed successfully"
+	PasswordlessConfirmationNeeded  = "Login link was opened on another device; confirm it on the device that requested it"
+	PasswordlessLoginConfirmed      = "Login confirmed; continue on the other device"
+	IdentityUnlinkedSuccessful      = "Identity unlinked successfully"
+	RegistrationInProgress          = "Registration is being completed; a verification email will follow"
+	EmailVerifiedSuccessful         = "Email address verified successfully"
+	AccountDeletionCancelled        = "Account deletion cancelled"
 )
 
 // Api Header
 const (
-	Authorization = "Authorization"
-	Bearer        = "Bearer "
+	Authorization        = "Authorization"
+	Bearer               = "Bearer "
+	RequestTimeoutHeader = "X-Request-Timeout-Ms" // Milliseconds the caller still waits for the response
+	RequestIDHeader      = "X-Request-Id"         // Correlates the logs of one call across services
 )
@@ -0,0 +1,15 @@
+package constants
+
+// SagaTypeAccountDeletion is the saga that deletes an account across auth-service and user-service
+const SagaTypeAccountDeletion = "account_deletion"
+
+// Account deletion saga steps
+const (
+	DeletionStepGracePeriod      = "grace_period"      // Waits for the grace period; the deletion can be cancelled until it ends
+	DeletionStepRevokeSessions   = "revoke_sessions"   // Deactivates the profile and revokes sessions and login links
+	DeletionStepPurgeAuthData    = "purge_auth_data"   // Deletes the account record with its tokens, MFA and identities
+	DeletionStepAnonymizeProfile = "anonymize_profile" // Anonymizes the profile in user-service, which emits user.deleted
+)
+
+// DeletionRequestedBySelf marks a deletion the account owner requested; admin requests carry the admin's user ID
+const DeletionRequestedBySelf = "self"
@@ -0,0 +1,53 @@
+package constants
+
+// ServiceName identifies this service in audit events shipped to external systems
+const ServiceName = "auth-service"
+
+// AuditEventType identifies the authentication event an audit record describes
+type AuditEventType string
+
+const (
+	AuditLogin                  AuditEventType = "login"
+	AuditLogout                 AuditEventType = "logout"
+	AuditTokenRefresh           AuditEventType = "token_refresh"
+	AuditPasswordResetRequested AuditEventType = "password_reset_requested"
+	AuditPasswordResetCompleted AuditEventType = "password_reset_completed"
+	AuditMFAEnable              AuditEventType = "mfa_enable"
+	AuditMFAVerify              AuditEventType = "mfa_verify"
+	AuditMFAChallenge           AuditEventType = "mfa_challenge"
+	AuditPasswordChange         AuditEventType = "password_change"
+	AuditPasswordlessRequest    AuditEventType = "passwordless_request"
+	AuditPasswordlessVerify     AuditEventType = "passwordless_verify"
+	AuditPasswordlessConfirm    AuditEventType = "passwordless_confirm"
+	AuditOIDCLogin              AuditEventType = "oidc_login"
+	AuditOIDCCallback           AuditEventType = "oidc_callback"
+)
+
+// Audit outcomes
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// Audit reason codes
+const (
+	AuditReasonInvalidCredentials  = "invalid_credentials"
+	AuditReasonInvalidPayload      = "invalid_payload"
+	AuditReasonInvalidToken        = "invalid_token"
+	AuditReasonInvalidRefreshToken = "invalid_refresh_token"
+	AuditReasonInvalidResetToken   = "invalid_reset_token"
+	AuditReasonResetTokenUsed      = "reset_token_used"
+	AuditReasonWeakPassword        = "weak_password"
+	AuditReasonInvalidOTP          = "invalid_otp"
+	AuditReasonOTPExpired          = "otp_expired"
+	AuditReasonUserNotFound        = "user_not_found"
+	AuditReasonAccountDisabled     = "account_disabled"
+	AuditReasonUnauthorized        = "unauthorized"
+	AuditReasonForbidden           = "forbidden"
+	AuditReasonNotFound            = "not_found"
+	AuditReasonBadRequest          = "bad_request"
+	AuditReasonInternalError       = "internal_error"
+)
+
+// AuditGenesisHash is the previous hash of the first record in the audit chain
+const AuditGenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
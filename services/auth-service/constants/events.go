@@ -0,0 +1,25 @@
+package constants
+
+// Domain event types published through the outbox; the type doubles as the message subject
+const (
+	EventLoginSucceeded  = "auth.login_succeeded"
+	EventPasswordChanged = "auth.password_changed"
+)
+
+// Event types consumed from user-service
+const (
+	EventUserDeletionRequested = "user.deletion_requested"
+)
+
+// Subjects captured by the JetStream streams
+const (
+	EventSubjects     = "auth.>" // Events published by this service
+	UserEventSubjects = "user.>" // Events published by user-service
+)
+
+// Sign-in methods reported in login events
+const (
+	LoginMethodPassword     = "password"
+	LoginMethodPasswordless = "passwordless"
+	LoginMethodFederated    = "federated"
+)
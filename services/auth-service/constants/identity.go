@@ -0,0 +1,4 @@
+package constants
+
+// IdentityProviderPassword is the provider name of the local email and password credential
+const IdentityProviderPassword = "password"
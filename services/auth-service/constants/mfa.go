@@ -0,0 +1,7 @@
+package constants
+
+// MFA challenge purposes; a code only verifies the flow that issued it
+const (
+	MFAPurposeEnrollment = "enrollment" // Confirms the user can receive codes before MFA is turned on
+	MFAPurposeStepUp     = "step_up"    // Re-authenticates the user before a sensitive change
+)
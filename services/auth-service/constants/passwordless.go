@@ -0,0 +1,18 @@
+package constants
+
+// Passwordless login methods
+const (
+	PasswordlessMethodLink = "link" // Magic link delivered by email
+	PasswordlessMethodCode = "code" // 6-digit code delivered by email
+)
+
+// PasswordlessStatus is the lifecycle state of a passwordless login challenge
+type PasswordlessStatus string
+
+const (
+	PasswordlessPending              PasswordlessStatus = "pending"               // Issued and waiting to be redeemed
+	PasswordlessAwaitingConfirmation PasswordlessStatus = "awaiting_confirmation" // Opened on another device, waiting for the requesting device
+	PasswordlessConfirmed            PasswordlessStatus = "confirmed"             // Cross-device redemption approved by the requesting device
+	PasswordlessRedeemed             PasswordlessStatus = "redeemed"              // Exchanged for tokens
+	PasswordlessRevoked              PasswordlessStatus = "revoked"               // Superseded or locked after too many attempts
+)
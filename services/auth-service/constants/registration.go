@@ -0,0 +1,11 @@
+package constants
+
+// SagaTypeRegistration is the saga that creates an account across user-service and auth-service
+const SagaTypeRegistration = "registration"
+
+// Registration saga steps
+const (
+	RegistrationStepCreateProfile    = "create_profile"          // Profile and password hash in user-service
+	RegistrationStepCreateAccount    = "create_account"          // Local account record and password identity
+	RegistrationStepSendVerification = "send_verification_email" // Email verification link
+)
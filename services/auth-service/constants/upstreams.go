@@ -0,0 +1,18 @@
+package constants
+
+const (
+	// UpstreamUserService is the name under which the WebClient calls user-service
+	UpstreamUserService = "user-service"
+
+	// UpstreamUserServiceGRPC is the name of the instances serving the internal gRPC API of user-service
+	UpstreamUserServiceGRPC = "user-service-grpc"
+
+	// UserServiceLivenessPath is the liveness probe of user-service, called to check it can be reached
+	UserServiceLivenessPath = "/health/live"
+)
+
+// Transports of the calls to user-service that are offered over both HTTP and gRPC
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+)
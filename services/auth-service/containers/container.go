@@ -1,56 +1,209 @@
 package containers
 
 import (
+	"context"
+	"fmt"
 	"github.com/Mir00r/auth-service/apiclients"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	"github.com/Mir00r/auth-service/auditsinks"
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/constants"
 	database "github.com/Mir00r/auth-service/db"
+	"github.com/Mir00r/auth-service/health"
 	"github.com/Mir00r/auth-service/internal/api/controllers"
+	"github.com/Mir00r/auth-service/internal/consumers"
 	"github.com/Mir00r/auth-service/internal/repositories"
 	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/messaging"
+	"github.com/Mir00r/auth-service/metrics"
+	"github.com/Mir00r/auth-service/saga"
+	sharedauditsinks "github.com/Mir00r/shared/auditsinks"
+	sharedhealth "github.com/Mir00r/shared/health"
+	"net"
+	"net/http"
+	"strconv"
 )
 
 // Container struct holds all application dependencies
 type Container struct {
-	UserRepository          repositories.UserRepository
-	TokenRepository         repositories.TokenRepository
-	MFARepository           repositories.MFARepository
-	AuthService             services.AuthService
-	TokenService            services.TokenServiceInterface
-	MFAService              services.MFAService
-	PublicAuthController    *controllers.PublicAuthController
-	ProtectedAuthController *controllers.ProtectedAuthController
-	InternalAuthController  *controllers.InternalAuthController
+	UserRepository            repositories.UserRepository
+	TokenRepository           repositories.TokenRepository
+	MFARepository             repositories.MFARepository
+	PasswordlessRepository    repositories.PasswordlessRepository
+	OIDCStateRepository       repositories.OIDCStateRepository
+	IdentityRepository        repositories.IdentityRepository
+	AuditRepository           repositories.AuditRepository
+	OutboxRepository          repositories.OutboxRepository
+	SagaRepository            repositories.SagaRepository
+	VerificationRepository    repositories.EmailVerificationRepository
+	AuthService               services.AuthService
+	TokenService              services.TokenServiceInterface
+	MFAService                services.MFAService
+	PasswordlessService       services.PasswordlessService
+	FederatedAuthService      services.FederatedAuthService
+	IdentityService           services.IdentityService
+	AuditService              services.AuditService
+	RegistrationService       services.RegistrationService
+	AccountDeletionService    services.AccountDeletionService
+	SagaService               services.SagaService
+	Upstreams                 *apiclients.Upstreams
+	UserClient                *userservice.Client
+	AuditDispatcher           *sharedauditsinks.Dispatcher
+	OutboxRelay               *messaging.Relay          // nil when messaging is not configured
+	EventSubscriber           *messaging.NATSSubscriber // nil when messaging is not configured
+	UserEventConsumer         *consumers.UserEventConsumer
+	SagaOrchestrator          *saga.Orchestrator
+	HealthRegistry            *sharedhealth.Registry
+	PublicAuthController      *controllers.PublicAuthController
+	RegistrationController    *controllers.RegistrationController
+	PasswordlessController    *controllers.PasswordlessAuthController
+	FederatedAuthController   *controllers.FederatedAuthController
+	ProtectedAuthController   *controllers.ProtectedAuthController
+	IdentityController        *controllers.IdentityController
+	AccountDeletionController *controllers.AccountDeletionController
+	InternalAuthController    *controllers.InternalAuthController
+	AuditController           *controllers.AuditController
+	SagaController            *controllers.SagaController
+	HealthController          *controllers.HealthController
 }
 
 // NewContainer initializes all dependencies and returns a Container instance
-func NewContainer() *Container {
+func NewContainer() (*Container, error) {
 	// Initialize WebClient
-	webClient := apiclients.NewWebClient() // Base URL and timeout
+	upstreams, err := apiclients.UpstreamsFromConfig(context.Background(), config.AppConfig)
+	if err != nil {
+		return nil, err
+	}
+	webClient := apiclients.NewWebClient(upstreams) // Upstreams and outbound policies
+	webClient.PublishStats("outbound")
+	metrics.MustRegister(webClient.StatsCollector())
+	userClient := userservice.NewClientFromConfig(webClient, config.AppConfig) // Internal API over HTTP or gRPC
+	oidcProviders := apiclients.NewOIDCProviders(config.AppConfig.OIDC.Providers)
+	auditDispatcher := auditsinks.NewDispatcherFromConfig(config.AppConfig.Audit, constants.ServiceName)
+	healthRegistry, err := newHealthRegistry(webClient)
+	if err != nil {
+		return nil, err
+	}
 
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(database.DB)
 	tokenRepo := repositories.NewTokenRepository(database.DB)
 	mfaRepo := repositories.NewMFARepository(database.DB)
+	passwordlessRepo := repositories.NewPasswordlessRepository(database.DB)
+	oidcStateRepo := repositories.NewOIDCStateRepository(database.DB)
+	identityRepo := repositories.NewIdentityRepository(database.DB)
+	auditRepo := repositories.NewAuditRepository(database.DB)
+	outboxRepo := repositories.NewOutboxRepository(database.DB)
+	outboxRelay := messaging.NewRelayFromConfig(config.AppConfig.Messaging, &outboxRepo)
+	eventSubscriber := messaging.NewSubscriberFromConfig(config.AppConfig.Messaging)
+	sagaRepo := repositories.NewSagaRepository(database.DB)
+	verificationRepo := repositories.NewEmailVerificationRepository(database.DB)
 
 	// Initialize services
-	authService := services.NewAuthService(userRepo, tokenRepo, webClient)
-	internalAuthService := services.NewInternalAuthService(userRepo)
-	tokenService := services.NewTokenService(tokenRepo, userRepo)
 	mfaService := services.NewMFAService(mfaRepo, userRepo)
+	authService := services.NewAuthService(userRepo, tokenRepo, identityRepo, outboxRepo, mfaService, userClient)
+	internalAuthService := services.NewInternalAuthService(userRepo, webClient, healthRegistry)
+	tokenService := services.NewTokenService(tokenRepo, userRepo, identityRepo, outboxRepo, userClient)
+	passwordlessService := services.NewPasswordlessService(passwordlessRepo, userRepo, tokenRepo, outboxRepo, userClient)
+	federatedAuthService := services.NewFederatedAuthService(oidcProviders, oidcStateRepo, identityRepo, userRepo, tokenRepo, outboxRepo, userClient)
+	identityService := services.NewIdentityService(identityRepo, userRepo, tokenRepo, mfaService, federatedAuthService, userClient)
+	auditService := services.NewAuditService(auditRepo, userRepo, auditDispatcher)
+	sagaOrchestrator := saga.NewOrchestratorFromConfig(config.AppConfig.Saga, &sagaRepo,
+		services.NewRegistrationSaga(userRepo, identityRepo, verificationRepo, userClient),
+		services.NewAccountDeletionSaga(userRepo, tokenRepo, passwordlessRepo, oidcStateRepo, userClient),
+	)
+	registrationService := services.NewRegistrationService(sagaOrchestrator, userRepo, verificationRepo, userClient)
+	accountDeletionService := services.NewAccountDeletionService(sagaOrchestrator, userRepo, identityService, userClient)
+	sagaService := services.NewSagaService(sagaRepo)
+
+	// Initialize consumers
+	userEventConsumer := consumers.NewUserEventConsumer(accountDeletionService)
 
 	// Initialize controllers
 	publicAuthController := controllers.NewPublicAuthController(authService, tokenService)
+	registrationController := controllers.NewRegistrationController(registrationService)
+	passwordlessController := controllers.NewPasswordlessAuthController(passwordlessService)
+	federatedAuthController := controllers.NewFederatedAuthController(federatedAuthService)
 	protectedAuthController := controllers.NewProtectedAuthController(authService, tokenService, mfaService)
+	identityController := controllers.NewIdentityController(identityService)
+	accountDeletionController := controllers.NewAccountDeletionController(accountDeletionService)
 	internalAuthController := controllers.NewInternalAuthController(internalAuthService)
+	auditController := controllers.NewAuditController(auditService)
+	sagaController := controllers.NewSagaController(sagaService)
+	healthController := controllers.NewHealthController(healthRegistry)
 
 	return &Container{
-		UserRepository:          userRepo,
-		TokenRepository:         tokenRepo,
-		MFARepository:           mfaRepo,
-		AuthService:             authService,
-		TokenService:            tokenService,
-		MFAService:              mfaService,
-		PublicAuthController:    publicAuthController,
-		ProtectedAuthController: protectedAuthController,
-		InternalAuthController:  internalAuthController,
+		UserRepository:            userRepo,
+		TokenRepository:           tokenRepo,
+		MFARepository:             mfaRepo,
+		PasswordlessRepository:    passwordlessRepo,
+		OIDCStateRepository:       oidcStateRepo,
+		IdentityRepository:        identityRepo,
+		AuditRepository:           auditRepo,
+		OutboxRepository:          outboxRepo,
+		SagaRepository:            sagaRepo,
+		VerificationRepository:    verificationRepo,
+		AuthService:               authService,
+		TokenService:              tokenService,
+		MFAService:                mfaService,
+		PasswordlessService:       passwordlessService,
+		FederatedAuthService:      federatedAuthService,
+		IdentityService:           identityService,
+		AuditService:              auditService,
+		RegistrationService:       registrationService,
+		AccountDeletionService:    accountDeletionService,
+		SagaService:               sagaService,
+		Upstreams:                 upstreams,
+		UserClient:                userClient,
+		AuditDispatcher:           auditDispatcher,
+		OutboxRelay:               outboxRelay,
+		EventSubscriber:           eventSubscriber,
+		UserEventConsumer:         userEventConsumer,
+		SagaOrchestrator:          sagaOrchestrator,
+		HealthRegistry:            healthRegistry,
+		PublicAuthController:      publicAuthController,
+		RegistrationController:    registrationController,
+		PasswordlessController:    passwordlessController,
+		FederatedAuthController:   federatedAuthController,
+		ProtectedAuthController:   protectedAuthController,
+		IdentityController:        identityController,
+		AccountDeletionController: accountDeletionController,
+		InternalAuthController:    internalAuthController,
+		AuditController:           auditController,
+		SagaController:            sagaController,
+		HealthController:          healthController,
+	}, nil
+}
+
+// newHealthRegistry registers the checks of the dependencies the service needs to be ready:
+// its database and schema, the key signing its tokens, Redis when configured and user-service
+func newHealthRegistry(webClient apiclients.WebClient) (*sharedhealth.Registry, error) {
+	registry := health.NewRegistryFromConfig(config.AppConfig.Health)
+	config.OnReload("health", func(cfg config.Config) {
+		registry.Configure(cfg.Health.CacheTTL.Duration(), cfg.Health.Timeout.Duration())
+	})
+
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		return nil, fmt.Errorf("database health check: %w", err)
+	}
+	registry.Register(sharedhealth.Postgres(sqlDB))
+	registry.Register(sharedhealth.Migrations(sqlDB, database.Migrations()))
+	registry.Register(sharedhealth.SigningKey(func() []byte { return []byte(config.AppConfig.JWT.Secret) }))
+
+	if redis := config.AppConfig.Redis; redis.Host != "" {
+		registry.Register(sharedhealth.Redis(net.JoinHostPort(redis.Host, strconv.Itoa(redis.Port)), redis.Password))
 	}
+
+	// user-service is not critical: the tokens issued before it went away are still validated
+	registry.Register(sharedhealth.Check{
+		Name: constants.UpstreamUserService,
+		Probe: func(ctx context.Context) error {
+			if err := webClient.Send(ctx, http.MethodGet, constants.UpstreamUserService, constants.UserServiceLivenessPath, nil, nil); err != nil {
+				return fmt.Errorf("unreachable: %w", err)
+			}
+			return nil
+		},
+	})
+	return registry, nil
 }
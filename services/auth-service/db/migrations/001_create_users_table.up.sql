@@ -1,5 +1,7 @@
 -- Dec 08, 2024
 
+CREATE SCHEMA IF NOT EXISTS auth;
+
 CREATE TABLE IF NOT EXISTS auth.users (
                        id UUID PRIMARY KEY DEFAULT gen_random_uuid(), -- Unique user ID
                        name VARCHAR(100) NOT NULL,                   -- User's full name
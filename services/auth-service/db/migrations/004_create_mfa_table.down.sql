@@ -0,0 +1,3 @@
+-- Oct 19, 2026
+
+DROP TABLE IF EXISTS auth.mfa;
@@ -0,0 +1,6 @@
+-- Oct 19, 2026
+
+ALTER TABLE auth.tokens
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS refresh_token_expires_at,
+    DROP COLUMN IF EXISTS refresh_token;
@@ -0,0 +1,5 @@
+-- Oct 19, 2026
+
+-- Tokens invalidated by the up migration stay used
+DROP INDEX IF EXISTS auth.idx_password_reset_token_token;
+DROP INDEX IF EXISTS auth.idx_password_reset_token_active_user;
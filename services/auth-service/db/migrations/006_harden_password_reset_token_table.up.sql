@@ -0,0 +1,14 @@
+-- Oct 19, 2026
+
+-- Tokens issued before this migration were signed JWTs stored in clear text; they can no longer be redeemed.
+UPDATE auth.password_reset_token
+SET used = TRUE
+WHERE used = FALSE;
+
+-- At most one redeemable reset token per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_token_active_user
+    ON auth.password_reset_token (user_id)
+    WHERE used = FALSE AND deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_token_token
+    ON auth.password_reset_token (token);
@@ -0,0 +1,4 @@
+-- Oct 19, 2026
+
+ALTER TABLE auth.users
+    DROP COLUMN IF EXISTS mfa_enabled;
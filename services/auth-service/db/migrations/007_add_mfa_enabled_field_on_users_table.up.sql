@@ -0,0 +1,4 @@
+-- Oct 19, 2026
+
+ALTER TABLE auth.users
+    ADD COLUMN IF NOT EXISTS mfa_enabled BOOLEAN NOT NULL DEFAULT FALSE;
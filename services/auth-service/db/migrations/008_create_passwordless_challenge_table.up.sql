@@ -0,0 +1,26 @@
+-- Oct 19, 2026
+
+CREATE TABLE IF NOT EXISTS auth.passwordless_challenge
+(
+    id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id             UUID        NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+    method              VARCHAR(10) NOT NULL,                 -- 'link' or 'code'
+    token_hash          TEXT        NULL,                     -- SHA-256 of the magic link token
+    code_hash           TEXT        NULL,                     -- SHA-256 of the email code
+    device_hash         TEXT        NOT NULL,                 -- SHA-256 of the requesting device ID
+    pending_device_hash TEXT        NULL,                     -- Device waiting for cross-device confirmation
+    status              VARCHAR(30) NOT NULL DEFAULT 'pending',
+    attempts            INT         NOT NULL DEFAULT 0,       -- Failed code attempts
+    expires_at          TIMESTAMP   NOT NULL,
+    redeemed_at         TIMESTAMP   NULL,
+    created_at          TIMESTAMP   NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMP   NOT NULL DEFAULT now(),
+    deleted_at          TIMESTAMP   NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_passwordless_challenge_token_hash
+    ON auth.passwordless_challenge (token_hash)
+    WHERE token_hash IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_passwordless_challenge_user_id
+    ON auth.passwordless_challenge (user_id);
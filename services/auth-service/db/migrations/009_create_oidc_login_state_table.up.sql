@@ -0,0 +1,12 @@
+-- Oct 19, 2026
+
+CREATE TABLE IF NOT EXISTS auth.oidc_login_state
+(
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    state         TEXT UNIQUE  NOT NULL,                 -- Opaque value round-tripped through the provider
+    provider      VARCHAR(50)  NOT NULL,                 -- Name of the configured identity provider
+    nonce         TEXT         NOT NULL,                 -- Expected ID token nonce
+    code_verifier TEXT         NOT NULL,                 -- PKCE code verifier
+    expires_at    TIMESTAMP    NOT NULL,
+    created_at    TIMESTAMP    NOT NULL DEFAULT now()
+);
@@ -0,0 +1,6 @@
+-- Oct 19, 2026
+
+ALTER TABLE auth.oidc_login_state
+    DROP COLUMN IF EXISTS link_user_id;
+
+DROP TABLE IF EXISTS auth.user_identity;
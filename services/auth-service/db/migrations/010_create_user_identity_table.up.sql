@@ -0,0 +1,26 @@
+-- Oct 19, 2026
+
+CREATE TABLE IF NOT EXISTS auth.user_identity
+(
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      UUID         NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+    provider     VARCHAR(50)  NOT NULL,                 -- "password" or the name of a configured identity provider
+    subject      TEXT         NOT NULL,                 -- Provider subject; the user ID for the password identity
+    email        VARCHAR(100) NULL,                     -- Email reported by the provider when linked
+    last_used_at TIMESTAMP    NULL,
+    created_at   TIMESTAMP    NOT NULL DEFAULT now(),
+    CONSTRAINT uq_user_identity_provider_subject UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identity_user_id ON auth.user_identity (user_id);
+
+-- Every existing account signs in with a password
+INSERT INTO auth.user_identity (user_id, provider, subject, email)
+SELECT id, 'password', id::text, email
+FROM auth.users
+WHERE deleted_at IS NULL
+ON CONFLICT (provider, subject) DO NOTHING;
+
+-- Federated logins started from account settings link to the signed-in user instead of logging in
+ALTER TABLE auth.oidc_login_state
+    ADD COLUMN IF NOT EXISTS link_user_id UUID NULL;
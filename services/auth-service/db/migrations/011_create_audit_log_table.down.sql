@@ -0,0 +1,5 @@
+-- Oct 19, 2026
+
+-- Drops the audit trail with the table; export it first when it must be kept
+DROP TABLE IF EXISTS auth.audit_log;
+DROP FUNCTION IF EXISTS auth.reject_audit_log_change();
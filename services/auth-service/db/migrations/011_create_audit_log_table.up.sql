@@ -0,0 +1,39 @@
+-- Oct 19, 2026
+
+CREATE TABLE IF NOT EXISTS auth.audit_log
+(
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    sequence   BIGINT UNIQUE NOT NULL,                -- Position in the hash chain, starting at 1
+    event_type VARCHAR(50)   NOT NULL,                -- e.g. login, logout, token_refresh
+    user_id    UUID          NULL,                    -- Affected account when known
+    actor      VARCHAR(255)  NOT NULL DEFAULT '',     -- User ID or submitted email of the caller
+    ip         VARCHAR(64)   NOT NULL DEFAULT '',
+    user_agent TEXT          NOT NULL DEFAULT '',
+    outcome    VARCHAR(10)   NOT NULL,                -- success or failure
+    reason     VARCHAR(50)   NOT NULL DEFAULT '',     -- Machine readable failure reason
+    created_at TIMESTAMPTZ   NOT NULL,
+    prev_hash  CHAR(64)      NOT NULL,                -- Hash of the previous record, zeros for the first
+    hash       CHAR(64)      NOT NULL                 -- HMAC-SHA-256 over prev_hash and this record, keyed outside the database
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_id ON auth.audit_log (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_event_type ON auth.audit_log (event_type, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON auth.audit_log (created_at);
+
+-- The audit log is append-only
+CREATE OR REPLACE FUNCTION auth.reject_audit_log_change() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'auth.audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_audit_log_append_only ON auth.audit_log;
+CREATE TRIGGER trg_audit_log_append_only
+    BEFORE UPDATE OR DELETE ON auth.audit_log
+    FOR EACH ROW EXECUTE FUNCTION auth.reject_audit_log_change();
+
+DROP TRIGGER IF EXISTS trg_audit_log_no_truncate ON auth.audit_log;
+CREATE TRIGGER trg_audit_log_no_truncate
+    BEFORE TRUNCATE ON auth.audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION auth.reject_audit_log_change();
@@ -0,0 +1,17 @@
+-- Oct 19, 2026
+
+CREATE TABLE IF NOT EXISTS auth.outbox_event
+(
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_type      VARCHAR(100) NOT NULL,                 -- Published as the message subject, e.g. auth.login_succeeded
+    aggregate_id    VARCHAR(100) NOT NULL,                 -- ID of the entity the event is about
+    payload         JSONB        NOT NULL,                 -- Event envelope
+    attempts        INT          NOT NULL DEFAULT 0,       -- Failed publish attempts
+    next_attempt_at TIMESTAMPTZ  NOT NULL DEFAULT now(),   -- Earliest time of the next publish attempt
+    locked_until    TIMESTAMPTZ  NULL,                     -- Lease held by the relay instance publishing the event
+    last_error      TEXT         NULL,
+    published_at    TIMESTAMPTZ  NULL,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_event_pending ON auth.outbox_event (next_attempt_at) WHERE published_at IS NULL;
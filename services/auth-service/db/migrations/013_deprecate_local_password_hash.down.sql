@@ -0,0 +1,6 @@
+-- Oct 19, 2026
+
+COMMENT ON COLUMN auth.users.password IS NULL;
+
+ALTER TABLE auth.users
+    ALTER COLUMN password DROP DEFAULT;
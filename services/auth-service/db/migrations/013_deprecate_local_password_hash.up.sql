@@ -0,0 +1,7 @@
+-- Oct 19, 2026
+
+-- Passwords are stored by user-service only; new accounts are created without a local hash
+ALTER TABLE auth.users
+    ALTER COLUMN password SET DEFAULT '';
+
+COMMENT ON COLUMN auth.users.password IS 'Deprecated: credentials live in user-service. Cleared by cmd/reconcile-credentials.';
@@ -0,0 +1,22 @@
+-- Oct 19, 2026
+
+CREATE TABLE IF NOT EXISTS auth.saga_instance
+(
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    saga_type       VARCHAR(50)  NOT NULL,                 -- Definition the saga runs, e.g. registration
+    saga_key        VARCHAR(255) NOT NULL,                 -- Business key; one unfinished saga per type and key
+    status          VARCHAR(20)  NOT NULL,                 -- running, compensating, completed, compensated or failed
+    step            INT          NOT NULL DEFAULT 0,       -- Index of the step being executed or compensated
+    step_name       VARCHAR(50)  NOT NULL DEFAULT '',      -- Name of that step, empty once the saga has ended
+    data            JSONB        NOT NULL DEFAULT '{}',    -- Step inputs and outputs
+    attempts        INT          NOT NULL DEFAULT 0,       -- Failed attempts of the current step
+    next_attempt_at TIMESTAMPTZ  NOT NULL DEFAULT now(),   -- Earliest time of the next attempt
+    locked_until    TIMESTAMPTZ  NULL,                     -- Lease held by the instance advancing the saga
+    last_error      TEXT         NULL,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    ended_at        TIMESTAMPTZ  NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_saga_instance_unfinished ON auth.saga_instance (saga_type, saga_key) WHERE ended_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_saga_instance_due ON auth.saga_instance (next_attempt_at) WHERE ended_at IS NULL;
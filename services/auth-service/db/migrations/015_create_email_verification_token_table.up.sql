@@ -0,0 +1,13 @@
+-- Oct 19, 2026
+
+CREATE TABLE IF NOT EXISTS auth.email_verification_token
+(
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID         NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+    token_hash  VARCHAR(64)  NOT NULL UNIQUE, -- SHA-256 of the emailed token
+    expires_at  TIMESTAMPTZ  NOT NULL,
+    used_at     TIMESTAMPTZ  NULL,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verification_token_user_id ON auth.email_verification_token (user_id);
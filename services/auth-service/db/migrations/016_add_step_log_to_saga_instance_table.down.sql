@@ -0,0 +1,6 @@
+-- Oct 19, 2026
+
+COMMENT ON COLUMN auth.saga_instance.status IS NULL;
+
+ALTER TABLE auth.saga_instance
+    DROP COLUMN IF EXISTS step_log;
@@ -0,0 +1,7 @@
+-- Oct 19, 2026
+
+-- Progress of every step, shown to administrators; sagas may now also end as cancelled
+ALTER TABLE auth.saga_instance
+    ADD COLUMN IF NOT EXISTS step_log JSONB NOT NULL DEFAULT '[]';
+
+COMMENT ON COLUMN auth.saga_instance.status IS 'running, compensating, completed, compensated, failed or cancelled';
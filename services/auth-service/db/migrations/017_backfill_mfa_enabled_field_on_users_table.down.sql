@@ -0,0 +1,4 @@
+-- Oct 19, 2026
+
+-- The backfilled accounts cannot be told apart from those enabling MFA since, so they keep MFA on
+SELECT 1;
@@ -0,0 +1,7 @@
+-- Oct 19, 2026
+
+-- Accounts that verified an MFA code before mfa_enabled existed keep being asked for one
+UPDATE auth.users
+SET mfa_enabled = TRUE
+WHERE mfa_enabled = FALSE
+  AND id IN (SELECT user_id FROM auth.mfa WHERE used = TRUE);
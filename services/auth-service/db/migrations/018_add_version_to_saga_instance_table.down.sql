@@ -0,0 +1,4 @@
+-- Oct 19, 2026
+
+ALTER TABLE auth.saga_instance
+    DROP COLUMN IF EXISTS version;
@@ -0,0 +1,5 @@
+-- Oct 19, 2026
+
+-- Bumped by every write, so an instance whose lease was taken over or whose saga was cancelled cannot overwrite it
+ALTER TABLE auth.saga_instance
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- Oct 19, 2026
+
+ALTER TABLE auth.mfa
+    DROP COLUMN IF EXISTS purpose;
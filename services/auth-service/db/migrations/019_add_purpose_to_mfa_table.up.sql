@@ -0,0 +1,5 @@
+-- Oct 19, 2026
+
+-- Binds each code to the flow that issued it, so an enrollment code cannot pass a step-up check
+ALTER TABLE auth.mfa
+    ADD COLUMN IF NOT EXISTS purpose TEXT NOT NULL DEFAULT 'enrollment';
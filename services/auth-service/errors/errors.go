@@ -19,6 +19,30 @@ var (
 	ErrResetTokenAlreadyUsed         = NewAppError(http.StatusBadRequest, "Reset token already used", nil)
 	ErrFailedToUpdatePassword        = NewAppError(http.StatusInternalServerError, "Failed to update password", nil)
 	ErrInvalidOrExpiredRefreshToken  = NewAppError(http.StatusUnauthorized, "Invalid or expired refresh token", nil)
+	ErrIncorrectCurrentPassword      = NewAppError(http.StatusUnauthorized, "Current password is incorrect", nil)
+	ErrMFACodeRequired               = NewAppError(http.StatusUnauthorized, "MFA code is required", nil)
+	ErrWeakPassword                  = NewAppError(http.StatusBadRequest, "Password does not meet the password policy", nil)
+	ErrPasswordReused                = NewAppError(http.StatusBadRequest, "New password must differ from the current password", nil)
+	ErrInvalidOrExpiredLoginLink     = NewAppError(http.StatusUnauthorized, "Invalid or expired login link or code", nil)
+	ErrLoginNotConfirmed             = NewAppError(http.StatusForbidden, "Login has not been confirmed on the requesting device", nil)
+	ErrLoginConfirmationPending      = NewAppError(http.StatusConflict, "Login is already waiting for the confirmation of another device", nil)
+	ErrUnknownIdentityProvider       = NewAppError(http.StatusNotFound, "Unknown identity provider", nil)
+	ErrInvalidOIDCState              = NewAppError(http.StatusBadRequest, "Invalid or expired login state", nil)
+	ErrFederatedLoginFailed          = NewAppError(http.StatusUnauthorized, "Federated login failed", nil)
+	ErrIdentityNotFound              = NewAppError(http.StatusNotFound, "Linked identity not found", nil)
+	ErrIdentityAlreadyLinked         = NewAppError(http.StatusConflict, "This identity is already linked to another account", nil)
+	ErrIdentityEmailConflict         = NewAppError(http.StatusConflict, "An account with this email already exists; sign in to it and link the provider from your account settings", nil)
+	ErrCannotUnlinkLastIdentity      = NewAppError(http.StatusConflict, "The last sign-in method of an account cannot be unlinked", nil)
+	ErrReauthenticationRequired      = NewAppError(http.StatusUnauthorized, "Recent authentication is required for this operation", nil)
+	ErrAccountDisabled               = NewAppError(http.StatusForbidden, "This account is disabled", nil)
+	ErrEmailAlreadyRegistered        = NewAppError(http.StatusConflict, "An account with this email already exists", nil)
+	ErrRegistrationInProgress        = NewAppError(http.StatusConflict, "A registration for this email is already in progress", nil)
+	ErrInvalidOrExpiredVerification  = NewAppError(http.StatusBadRequest, "Invalid or expired verification link", nil)
+	ErrDeletionAlreadyScheduled      = NewAppError(http.StatusConflict, "A deletion of this account is already scheduled", nil)
+	ErrDeletionNotFound              = NewAppError(http.StatusNotFound, "No deletion of this account was requested", nil)
+	ErrDeletionNotCancellable        = NewAppError(http.StatusConflict, "The account deletion is in progress and can no longer be cancelled", nil)
+	ErrFailedToDeleteAccount         = NewAppError(http.StatusInternalServerError, "Failed to delete the account", nil)
+	ErrSagaNotFound                  = NewAppError(http.StatusNotFound, "Saga not found", nil)
 )
 
 // AppError represents a generic application error
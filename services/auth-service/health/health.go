@@ -0,0 +1,13 @@
+// Package health builds the readiness registry of the service, described in github.com/Mir00r/shared/health,
+// from the application configuration.
+package health
+
+import (
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/shared/health"
+)
+
+// NewRegistryFromConfig creates a registry from the application configuration
+func NewRegistryFromConfig(cfg config.HealthConfig) *health.Registry {
+	return health.NewRegistry(cfg.CacheTTL.Duration(), cfg.Timeout.Duration())
+}
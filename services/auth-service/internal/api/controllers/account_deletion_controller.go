@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// AccountDeletionController lets users delete their own account
+type AccountDeletionController struct {
+	AccountDeletionService services.AccountDeletionService // Schedules and cancels account deletions
+}
+
+// NewAccountDeletionController initializes a new AccountDeletionController instance
+func NewAccountDeletionController(accountDeletionService services.AccountDeletionService) *AccountDeletionController {
+	return &AccountDeletionController{
+		AccountDeletionService: accountDeletionService,
+	}
+}
+
+// RequestDeletion re-authenticates the user and schedules the deletion of their account after the grace period
+// @Summary Request the deletion of the account
+// @Tags Protected Authentication
+// @Accept json
+// @Produce json
+// @Param request body dtos.ReauthenticationRequest true "Re-authentication payload"
+// @Success 202 {object} dtos.AccountDeletionResponse
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /v1/protected/auth/account/deletion [post]
+func (ctrl *AccountDeletionController) RequestDeletion(c *gin.Context) {
+	req, issuedAt, ok := bindReauthentication(c)
+	if !ok {
+		return
+	}
+
+	claims, _ := utils.ExtractClaimsFromContext(c.Request.Context())
+	response, err := ctrl.AccountDeletionService.RequestDeletion(c.Request.Context(), claims.UserID, issuedAt, req)
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(c, http.StatusAccepted, response)
+}
+
+// CancelDeletion cancels the scheduled deletion of the account while the grace period is running
+// @Summary Cancel the deletion of the account
+// @Tags Protected Authentication
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /v1/protected/auth/account/deletion [delete]
+func (ctrl *AccountDeletionController) CancelDeletion(c *gin.Context) {
+	userID, err := utils.ExtractUserIDFromContext(c)
+	if err != nil {
+		utils.ErrorResponseCtx(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := ctrl.AccountDeletionService.CancelDeletion(c.Request.Context(), userID); err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(c, http.StatusOK, constants.AccountDeletionCancelled)
+}
+
+// GetDeletion reports the state of the most recent deletion requested for the account
+// @Summary Get the deletion status of the account
+// @Tags Protected Authentication
+// @Produce json
+// @Success 200 {object} dtos.AccountDeletionResponse
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/protected/auth/account/deletion [get]
+func (ctrl *AccountDeletionController) GetDeletion(c *gin.Context) {
+	userID, err := utils.ExtractUserIDFromContext(c)
+	if err != nil {
+		utils.ErrorResponseCtx(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	response, err := ctrl.AccountDeletionService.GetDeletion(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(c, http.StatusOK, response)
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// AuditController exposes the authentication audit log to administrators
+type AuditController struct {
+	AuditService services.AuditService // Handles recording and querying of audit events
+}
+
+// NewAuditController initializes a new AuditController instance
+func NewAuditController(auditService services.AuditService) *AuditController {
+	return &AuditController{
+		AuditService: auditService,
+	}
+}
+
+// ListEvents returns audit records filtered by user, event type, outcome and time range
+// @Summary Query the audit log
+// @Tags Admin
+// @Produce json
+// @Param userId query string false "Affected user ID"
+// @Param eventType query string false "Event type, e.g. login"
+// @Param outcome query string false "success or failure"
+// @Param from query string false "Inclusive lower bound (RFC 3339)"
+// @Param to query string false "Exclusive upper bound (RFC 3339)"
+// @Param page query int false "Page number, starting at 1"
+// @Param perPage query int false "Records per page, at most 500"
+// @Success 200 {object} dtos.PaginatedAuditResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /v1/admin/audit/events [get]
+func (ctrl *AuditController) ListEvents(c *gin.Context) {
+	var query dtos.AuditQuery
+
+	// Parse and validate the query parameters
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = c.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+	if err := services.ValidateRequest(query); err != nil {
+		_ = c.Error(errors.NewAppError(http.StatusBadRequest, constants.ErrInvalidRqPayload, err))
+		return
+	}
+
+	response, err := ctrl.AuditService.ListEvents(c.Request.Context(), query)
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(c, http.StatusOK, response)
+}
+
+// VerifyChain recomputes the audit hash chain and reports the first tampered record, if any
+// @Summary Verify the audit log hash chain
+// @Tags Admin
+// @Produce json
+// @Param sequence query int false "Sequence of a record known from outside the database, e.g. the last one a SIEM received"
+// @Param hash query string false "Hash of that record"
+// @Success 200 {object} dtos.AuditChainVerification
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /v1/admin/audit/verify [get]
+func (ctrl *AuditController) VerifyChain(c *gin.Context) {
+	var anchor dtos.AuditChainAnchor
+
+	// Parse and validate the query parameters
+	if err := c.ShouldBindQuery(&anchor); err != nil {
+		_ = c.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+	if err := services.ValidateRequest(anchor); err != nil {
+		_ = c.Error(errors.NewAppError(http.StatusBadRequest, constants.ErrInvalidRqPayload, err))
+		return
+	}
+
+	response, err := ctrl.AuditService.VerifyChain(c.Request.Context(), anchor)
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(c, http.StatusOK, response)
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// FederatedAuthController manages "Sign in with <provider>" through external OIDC identity providers
+type FederatedAuthController struct {
+	FederatedAuthService services.FederatedAuthService // Handles the OIDC authorization code flow
+}
+
+// NewFederatedAuthController initializes a new FederatedAuthController instance
+func NewFederatedAuthController(federatedAuthService services.FederatedAuthService) *FederatedAuthController {
+	return &FederatedAuthController{
+		FederatedAuthService: federatedAuthService,
+	}
+}
+
+// StartLogin redirects the browser to the identity provider
+// @Summary Start login with an external identity provider
+// @Tags Public Authentication
+// @Param provider path string true "Configured provider name"
+// @Success 302
+// @Failure 404 {object} map[string]string
+// @Router /v1/public/auth/oidc/{provider}/login [get]
+func (ctrl *FederatedAuthController) StartLogin(c *gin.Context) {
+	response, err := ctrl.FederatedAuthService.StartLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	c.Redirect(http.StatusFound, response.AuthorizationURL)
+}
+
+// Callback completes the login when the identity provider redirects back
+// @Summary Complete login with an external identity provider
+// @Tags Public Authentication
+// @Produce json
+// @Param provider path string true "Configured provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "Login state"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /v1/public/auth/oidc/{provider}/callback [get]
+func (ctrl *FederatedAuthController) Callback(c *gin.Context) {
+	// The provider reports denied consent and similar failures through the error parameter
+	if c.Query("error") != "" {
+		_ = c.Error(errors.NewAppError(errors.ErrFederatedLoginFailed.Code, errors.ErrFederatedLoginFailed.Message+": "+c.Query("error"), nil))
+		return
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		_ = c.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+
+	token, err := ctrl.FederatedAuthService.CompleteLogin(c.Request.Context(), c.Param("provider"), code, state)
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	// Return the generated token
+	utils.JSONResponseCtx(c, http.StatusOK, token)
+}
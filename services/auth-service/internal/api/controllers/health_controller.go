@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/Mir00r/shared/health"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// HealthController serves the liveness and readiness probes of load balancers and orchestrators
+type HealthController struct {
+	Registry *health.Registry // Checks the dependencies of the service
+}
+
+// NewHealthController initializes a new HealthController instance
+func NewHealthController(registry *health.Registry) *HealthController {
+	return &HealthController{
+		Registry: registry,
+	}
+}
+
+// Live tells that the process is alive. It checks no dependency, so an outage of one does not get every
+// instance restarted.
+// @Summary Liveness probe
+// @Tags Health
+// @Produce json
+// @Success 200 {object} dtos.LivenessResponse
+// @Router /health/live [get]
+func (ctrl *HealthController) Live(c *gin.Context) {
+	utils.GinJSONResponse(c, http.StatusOK, dtos.LivenessResponse{
+		Status:    health.StatusUp,
+		StartedAt: ctrl.Registry.StartedAt().Format(time.RFC3339),
+		Uptime:    ctrl.Registry.Uptime().Round(time.Second).String(),
+	})
+}
+
+// Ready tells whether the service is ready to receive traffic: 200 while it is up or degraded, and 503
+// while a critical check fails
+// @Summary Readiness probe
+// @Tags Health
+// @Produce json
+// @Success 200 {object} dtos.ReadinessResponse
+// @Failure 503 {object} dtos.ReadinessResponse
+// @Router /health/ready [get]
+func (ctrl *HealthController) Ready(c *gin.Context) {
+	report := ctrl.Registry.Check(c.Request.Context())
+
+	response := dtos.ReadinessResponse{
+		Status:    report.Status,
+		CheckedAt: report.CheckedAt.Format(time.RFC3339),
+		Checks:    make([]dtos.CheckSummary, 0, len(report.Checks)),
+	}
+	for _, result := range report.Checks {
+		response.Checks = append(response.Checks, dtos.CheckSummary{Name: result.Name, Status: result.Status, LatencyMs: result.LatencyMs})
+	}
+
+	status := http.StatusOK
+	if report.Status == health.StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+	utils.GinJSONResponse(c, status, response)
+}
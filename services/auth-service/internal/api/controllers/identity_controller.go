@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// IdentityController manages the sign-in methods linked to the authenticated user's account
+type IdentityController struct {
+	IdentityService services.IdentityService // Handles linking and unlinking of identities
+}
+
+// NewIdentityController initializes a new IdentityController instance
+func NewIdentityController(identityService services.IdentityService) *IdentityController {
+	return &IdentityController{
+		IdentityService: identityService,
+	}
+}
+
+// ListIdentities returns the password and external identities linked to the account
+// @Summary List linked identities
+// @Tags Protected Authentication
+// @Produce json
+// @Success 200 {array} dtos.IdentityResponse
+// @Failure 401 {object} map[string]string
+// @Router /v1/protected/auth/identities [get]
+func (ctrl *IdentityController) ListIdentities(c *gin.Context) {
+	userID, err := utils.ExtractUserIDFromContext(c)
+	if err != nil {
+		utils.ErrorResponseCtx(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	identities, err := ctrl.IdentityService.ListIdentities(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(c, http.StatusOK, identities)
+}
+
+// StartLink re-authenticates the user and returns the provider URL that completes the link
+// @Summary Link an external identity
+// @Tags Protected Authentication
+// @Accept json
+// @Produce json
+// @Param provider path string true "Configured provider name"
+// @Param request body dtos.ReauthenticationRequest true "Re-authentication payload"
+// @Success 200 {object} dtos.FederatedLoginStartResponse
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/protected/auth/identities/link/{provider} [post]
+func (ctrl *IdentityController) StartLink(c *gin.Context) {
+	req, issuedAt, ok := bindReauthentication(c)
+	if !ok {
+		return
+	}
+
+	claims, _ := utils.ExtractClaimsFromContext(c.Request.Context())
+	response, err := ctrl.IdentityService.StartLink(c.Request.Context(), claims.UserID, c.Param("provider"), issuedAt, req)
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	// The client redirects the browser itself since this call carries a bearer token
+	utils.JSONResponseCtx(c, http.StatusOK, response)
+}
+
+// Unlink removes a sign-in method from the account after re-authentication
+// @Summary Unlink an identity
+// @Tags Protected Authentication
+// @Accept json
+// @Produce json
+// @Param identityId path string true "Identity ID"
+// @Param request body dtos.ReauthenticationRequest true "Re-authentication payload"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /v1/protected/auth/identities/{identityId}/unlink [post]
+func (ctrl *IdentityController) Unlink(c *gin.Context) {
+	req, issuedAt, ok := bindReauthentication(c)
+	if !ok {
+		return
+	}
+
+	claims, _ := utils.ExtractClaimsFromContext(c.Request.Context())
+	if err := ctrl.IdentityService.Unlink(c.Request.Context(), claims.UserID, c.Param("identityId"), issuedAt, req); err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(c, http.StatusOK, constants.IdentityUnlinkedSuccessful)
+}
+
+// bindReauthentication parses the re-authentication payload and the issue time of the caller's access token.
+// Reports false after propagating the error when the request is invalid.
+func bindReauthentication(c *gin.Context) (dtos.ReauthenticationRequest, time.Time, bool) {
+	var req dtos.ReauthenticationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return req, time.Time{}, false
+	}
+	if err := services.ValidateRequest(req); err != nil {
+		_ = c.Error(errors.NewAppError(http.StatusBadRequest, constants.ErrInvalidRqPayload, err))
+		return req, time.Time{}, false
+	}
+
+	claims, err := utils.ExtractClaimsFromContext(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponseCtx(c, http.StatusUnauthorized, err.Error())
+		return req, time.Time{}, false
+	}
+
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	return req, issuedAt, true
+}
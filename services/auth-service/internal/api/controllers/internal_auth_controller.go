@@ -54,15 +54,15 @@ func (ctrl *InternalAuthController) ValidateToken(c *gin.Context) {
 
 // ServiceHealth checks the health status of the authentication service
 // @Summary Checks the health of the authentication service
-// @Description Returns the health status of the service
+// @Description Returns the health status of the service, with the results of its checks and the circuit breakers of the called services
 // @Tags Internal APIs
 // @Accept json
 // @Produce json
-// @Success 200 {object} response.ServiceHealthResponse
+// @Success 200 {object} dtos.ServiceHealthResponse
 // @Router /internal/v1/service-health [get]
 func (ctrl *InternalAuthController) ServiceHealth(c *gin.Context) {
 	// Fetch the health status from the service layer
-	health := ctrl.InternalAuthService.CheckHealth()
+	health := ctrl.InternalAuthService.CheckHealth(c.Request.Context())
 
 	// Respond with the health status
 	utils.JSONResponseCtx(c, http.StatusOK, health)
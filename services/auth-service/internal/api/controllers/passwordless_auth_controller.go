@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// PasswordlessAuthController manages passwordless login via magic link or email code
+type PasswordlessAuthController struct {
+	PasswordlessService services.PasswordlessService // Handles passwordless login logic
+}
+
+// NewPasswordlessAuthController initializes a new PasswordlessAuthController instance
+func NewPasswordlessAuthController(passwordlessService services.PasswordlessService) *PasswordlessAuthController {
+	return &PasswordlessAuthController{
+		PasswordlessService: passwordlessService,
+	}
+}
+
+// RequestLogin sends a magic link or a 6-digit code to the user's email
+// @Summary Request a passwordless login
+// @Tags Public Authentication
+// @Accept json
+// @Produce json
+// @Param request body request.PasswordlessLoginRequest true "Passwordless login request payload"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /v1/public/auth/passwordless/request [post]
+func (ctrl *PasswordlessAuthController) RequestLogin(c *gin.Context) {
+	var req dtos.PasswordlessLoginRequest
+
+	// Parse and validate the request payload
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+	if err := services.ValidateRequest(req); err != nil {
+		_ = c.Error(errors.NewAppError(http.StatusBadRequest, constants.ErrInvalidRqPayload, err))
+		return
+	}
+
+	// Issue the challenge
+	response, err := ctrl.PasswordlessService.RequestLogin(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	// The response is identical whether or not the email is registered
+	utils.JSONResponseCtx(c, http.StatusOK, response)
+}
+
+// VerifyLogin redeems a magic link token or an email code for a LoginResponse
+// @Summary Redeem a passwordless login
+// @Tags Public Authentication
+// @Accept json
+// @Produce json
+// @Param request body request.PasswordlessVerifyRequest true "Passwordless verify request payload"
+// @Success 200 {object} map[string]string
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /v1/public/auth/passwordless/verify [post]
+func (ctrl *PasswordlessAuthController) VerifyLogin(c *gin.Context) {
+	var req dtos.PasswordlessVerifyRequest
+
+	// Parse and validate the request payload
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+	if err := services.ValidateRequest(req); err != nil {
+		_ = c.Error(errors.NewAppError(http.StatusBadRequest, constants.ErrInvalidRqPayload, err))
+		return
+	}
+
+	// Redeem the challenge
+	result, err := ctrl.PasswordlessService.VerifyLogin(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	// Opened on another device: the requesting device has to confirm first
+	if result.ConfirmationRequired {
+		c.JSON(http.StatusAccepted, dtos.APIResponse{
+			Code:    http.StatusAccepted,
+			Message: constants.PasswordlessConfirmationNeeded,
+			Data:    result,
+		})
+		return
+	}
+
+	// Return the standard login response
+	utils.JSONResponseCtx(c, http.StatusOK, result.Login)
+}
+
+// ConfirmLogin approves a login link that was opened on another device
+// @Summary Confirm a cross-device passwordless login
+// @Tags Public Authentication
+// @Accept json
+// @Produce json
+// @Param request body request.PasswordlessConfirmRequest true "Passwordless confirm request payload"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /v1/public/auth/passwordless/confirm [post]
+func (ctrl *PasswordlessAuthController) ConfirmLogin(c *gin.Context) {
+	var req dtos.PasswordlessConfirmRequest
+
+	// Parse and validate the request payload
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+	if err := services.ValidateRequest(req); err != nil {
+		_ = c.Error(errors.NewAppError(http.StatusBadRequest, constants.ErrInvalidRqPayload, err))
+		return
+	}
+
+	// Approve the pending device
+	if err := ctrl.PasswordlessService.ConfirmLogin(c.Request.Context(), req); err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(c, http.StatusOK, constants.PasswordlessLoginConfirmed)
+}
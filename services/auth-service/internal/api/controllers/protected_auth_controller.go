@@ -50,7 +50,7 @@ func (ctrl *ProtectedAuthController) ProtectedLogout(c *gin.Context) {
 	tokenString := strings.TrimPrefix(authHeader, constants.Bearer)
 
 	// Call the logout service to invalidate the token
-	if err := ctrl.TokenService.Logout(tokenString, userID); err != nil {
+	if err := ctrl.TokenService.Logout(c.Request.Context(), tokenString, userID); err != nil {
 		utils.ErrorResponseCtx(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -70,7 +70,7 @@ func (ctrl *ProtectedAuthController) ProtectedUserProfile(c *gin.Context) {
 	}
 
 	// Fetch the user's profile using the AuthService
-	userProfile, err := ctrl.AuthService.GetUserProfile(userID.(string))
+	userProfile, err := ctrl.AuthService.GetUserProfile(c.Request.Context(), userID.(string))
 	if err != nil {
 		utils.ErrorResponseCtx(c, http.StatusInternalServerError, constants.ErrFailedToFetchProfile)
 		return
@@ -81,25 +81,25 @@ func (ctrl *ProtectedAuthController) ProtectedUserProfile(c *gin.Context) {
 }
 
 // EnableMFA enables multi-factor authentication (MFA) for the currently authenticated user.
-// This endpoint generates an OTP (One-Time Password) to initiate the MFA setup process.
+// This endpoint emails an OTP (One-Time Password) to initiate the MFA setup process.
 func (ctrl *ProtectedAuthController) EnableMFA(c *gin.Context) {
 	// Extract user ID from JWT claims
 	claims, _ := utils.ExtractClaimsFromContext(c.Request.Context())
 	userID := claims.UserID
 
-	// Call the MFAService to enable MFA and generate an OTP
-	response, err := ctrl.MFAService.EnableMFA(userID)
+	// Call the MFAService to enable MFA and email an OTP
+	response, err := ctrl.MFAService.EnableMFA(c.Request.Context(), userID)
 	if err != nil {
 		_ = c.Error(err) // Propagate error to middlewares
 		return
 	}
 
-	// Respond with the generated OTP and success message
+	// Respond with a success message; the OTP is only delivered by email
 	utils.JSONResponseCtx(c, http.StatusOK, response)
 }
 
 // VerifyMFA verifies the provided OTP for the currently authenticated user.
-// This endpoint validates the OTP submitted by the user during the MFA process.
+// This endpoint validates the OTP submitted by the user during the MFA setup process.
 func (ctrl *ProtectedAuthController) VerifyMFA(c *gin.Context) {
 	var req dtos.VerifyMFARequest
 
@@ -113,8 +113,8 @@ func (ctrl *ProtectedAuthController) VerifyMFA(c *gin.Context) {
 	claims, _ := utils.ExtractClaimsFromContext(c.Request.Context())
 	userID := claims.UserID
 
-	// Call the MFAService to verify the OTP
-	if err := ctrl.MFAService.VerifyMFA(userID, req.OTP); err != nil {
+	// Call the MFAService to confirm the enrollment OTP
+	if err := ctrl.MFAService.ConfirmMFA(c.Request.Context(), userID, req.OTP); err != nil {
 		_ = c.Error(err) // Propagate error to middlewares
 		return
 	}
@@ -123,6 +123,58 @@ func (ctrl *ProtectedAuthController) VerifyMFA(c *gin.Context) {
 	utils.JSONResponseCtx(c, http.StatusOK, constants.MFAVerifySuccessful)
 }
 
+// RequestMFAChallenge emails a step-up OTP to the currently authenticated user.
+// The OTP is required by sensitive operations, such as changing the password, when MFA is enabled.
+func (ctrl *ProtectedAuthController) RequestMFAChallenge(c *gin.Context) {
+	// Extract user ID from JWT claims
+	claims, _ := utils.ExtractClaimsFromContext(c.Request.Context())
+	userID := claims.UserID
+
+	// Call the MFAService to email a step-up OTP
+	if err := ctrl.MFAService.RequestStepUp(c.Request.Context(), userID); err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	// Respond with a success message; the OTP is only delivered by email
+	utils.JSONResponseCtx(c, http.StatusOK, constants.MFAChallengeSent)
+}
+
+// ChangePassword changes the password of the currently authenticated user.
+// The current password (and an OTP when MFA is enabled) must be supplied; other sessions can optionally be revoked.
+func (ctrl *ProtectedAuthController) ChangePassword(c *gin.Context) {
+	var req dtos.ChangePasswordRequest
+
+	// Bind the request payload to the ChangePasswordRequest struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+
+	// Validate the request
+	if err := services.ValidateRequest(req); err != nil {
+		_ = c.Error(errors.NewAppError(http.StatusBadRequest, constants.ErrInvalidRqPayload, err))
+		return
+	}
+
+	// Extract the user ID using the helper function
+	userID, err := utils.ExtractUserIDFromContext(c)
+	if err != nil {
+		utils.ErrorResponseCtx(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// Call the AuthService to change the password
+	response, err := ctrl.AuthService.ChangePassword(c.Request.Context(), userID, req)
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	// Respond with the result of the change
+	utils.JSONResponseCtx(c, http.StatusOK, response)
+}
+
 func (ctrl *ProtectedAuthController) RefreshToken(c *gin.Context) {
 	var req dtos.RefreshTokenRequest
 
@@ -139,7 +191,7 @@ func (ctrl *ProtectedAuthController) RefreshToken(c *gin.Context) {
 	}
 
 	// Refresh the token
-	response, err := ctrl.TokenService.RefreshToken(req)
+	response, err := ctrl.TokenService.RefreshToken(c.Request.Context(), req)
 	if err != nil {
 		_ = c.Error(err) // Propagate error to middlewares
 		return
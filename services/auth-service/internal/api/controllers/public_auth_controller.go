@@ -44,7 +44,7 @@ func (ctrl *PublicAuthController) PublicLogin(c *gin.Context) {
 	}
 
 	// Authenticate the user
-	token, err := ctrl.AuthService.Authenticate(req)
+	token, err := ctrl.AuthService.Authenticate(c.Request.Context(), req)
 	if err != nil || token == nil {
 		_ = c.Error(err) // Propagate error to middlewares
 		return
@@ -54,35 +54,6 @@ func (ctrl *PublicAuthController) PublicLogin(c *gin.Context) {
 	utils.JSONResponseCtx(c, http.StatusOK, token)
 }
 
-// PublicRegister handles user registration requests
-// @Summary Register a new user
-// @Tags Public Authentication
-// @Accept json
-// @Produce json
-// @Param request body request.RegisterRequest true "Registration request payload"
-// @Success 201 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
-// @Router /v1/public/auth/register [post]
-func (ctrl *PublicAuthController) PublicRegister(c *gin.Context) {
-	var req dtos.RegisterRequest
-
-	// Parse and validate the request payload
-	if err := c.ShouldBindJSON(&req); err != nil {
-		_ = c.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
-		return
-	}
-
-	// Register the user
-	if err := ctrl.AuthService.RegisterUser(req); err != nil {
-		_ = c.Error(err) // Propagate error to middlewares
-		return
-	}
-
-	// Return a success message
-	utils.JSONResponseCtx(c, http.StatusCreated, constants.MsgUserRegSuccessful)
-}
-
 // PasswordReset initiates a password reset process for a user
 // @Summary Initiate password reset
 // @Tags Public Authentication
@@ -91,7 +62,6 @@ func (ctrl *PublicAuthController) PublicRegister(c *gin.Context) {
 // @Param request body request.PasswordResetRequest true "Password reset request payload"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /v1/public/auth/password-reset [post]
 func (ctrl *PublicAuthController) PasswordReset(c *gin.Context) {
@@ -104,13 +74,13 @@ func (ctrl *PublicAuthController) PasswordReset(c *gin.Context) {
 	}
 
 	// Initiate the password reset process
-	if err := ctrl.TokenService.InitiatePasswordReset(req); err != nil {
+	if err := ctrl.TokenService.InitiatePasswordReset(c.Request.Context(), req); err != nil {
 		_ = c.Error(err) // Propagate error to middlewares
 		return
 	}
 
-	// Return a success message
-	utils.JSONResponseCtx(c, http.StatusOK, constants.PasswordResetLinkSentSuccessful)
+	// Return the same message whether or not the email is registered
+	utils.JSONResponseCtx(c, http.StatusOK, constants.PasswordResetRequestAccepted)
 }
 
 // ConfirmPasswordReset finalizes the password reset process
@@ -134,11 +104,11 @@ func (ctrl *PublicAuthController) ConfirmPasswordReset(c *gin.Context) {
 	}
 
 	// Confirm the password reset
-	if err := ctrl.TokenService.ResetPassword(req); err != nil {
+	if err := ctrl.TokenService.ResetPassword(c.Request.Context(), req); err != nil {
 		_ = c.Error(err) // Propagate error to middlewares
 		return
 	}
 
 	// Return a success message
-	utils.JSONResponseCtx(c, http.StatusOK, constants.PasswordResetLinkSentSuccessful)
+	utils.JSONResponseCtx(c, http.StatusOK, constants.PasswordResetSuccessful)
 }
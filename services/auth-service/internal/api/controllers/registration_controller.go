@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/Mir00r/auth-service/saga"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// RegistrationController manages account registration and email verification
+type RegistrationController struct {
+	RegistrationService services.RegistrationService // Handles registration logic
+}
+
+// NewRegistrationController initializes a new RegistrationController instance
+func NewRegistrationController(registrationService services.RegistrationService) *RegistrationController {
+	return &RegistrationController{
+		RegistrationService: registrationService,
+	}
+}
+
+// Register handles user registration requests
+// @Summary Register a new user
+// @Tags Public Authentication
+// @Accept json
+// @Produce json
+// @Param request body request.RegisterRequest true "Registration request payload"
+// @Success 201 {object} map[string]string
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /v1/public/auth/register [post]
+func (ctrl *RegistrationController) Register(c *gin.Context) {
+	var req dtos.RegisterRequest
+
+	// Parse and validate the request payload
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+	if err := services.ValidateRequest(req); err != nil {
+		_ = c.Error(errors.NewAppError(http.StatusBadRequest, constants.ErrInvalidRqPayload, err))
+		return
+	}
+
+	// Register the user
+	response, err := ctrl.RegistrationService.Register(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	// A registration waiting for a retry is accepted and completed in the background
+	status := http.StatusCreated
+	if response.Status != saga.StatusCompleted {
+		status = http.StatusAccepted
+	}
+	utils.JSONResponseCtx(c, status, response)
+}
+
+// VerifyEmail redeems an email verification link
+// @Summary Verify the email address of a new account
+// @Tags Public Authentication
+// @Accept json
+// @Produce json
+// @Param request body request.VerifyEmailRequest true "Verify email request payload"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /v1/public/auth/verify-email [post]
+func (ctrl *RegistrationController) VerifyEmail(c *gin.Context) {
+	var req dtos.VerifyEmailRequest
+
+	// Parse and validate the request payload
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+	if err := services.ValidateRequest(req); err != nil {
+		_ = c.Error(errors.NewAppError(http.StatusBadRequest, constants.ErrInvalidRqPayload, err))
+		return
+	}
+
+	// Redeem the verification link
+	if err := ctrl.RegistrationService.VerifyEmail(c.Request.Context(), req); err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(c, http.StatusOK, constants.EmailVerifiedSuccessful)
+}
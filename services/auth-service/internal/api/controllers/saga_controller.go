@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// SagaController exposes the state of registration and deletion workflows to administrators
+type SagaController struct {
+	SagaService services.SagaService // Handles querying of sagas
+}
+
+// NewSagaController initializes a new SagaController instance
+func NewSagaController(sagaService services.SagaService) *SagaController {
+	return &SagaController{
+		SagaService: sagaService,
+	}
+}
+
+// ListSagas returns sagas filtered by type, status and key
+// @Summary Query sagas
+// @Tags Admin
+// @Produce json
+// @Param type query string false "Saga type, e.g. account_deletion"
+// @Param status query string false "running, compensating, completed, compensated, failed or cancelled"
+// @Param key query string false "Business key, e.g. the profile ID of a deleted account"
+// @Param page query int false "Page number, starting at 1"
+// @Param perPage query int false "Sagas per page, at most 500"
+// @Success 200 {object} dtos.PaginatedSagaResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /v1/admin/sagas [get]
+func (ctrl *SagaController) ListSagas(c *gin.Context) {
+	var query dtos.SagaQuery
+
+	// Parse and validate the query parameters
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = c.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+	if err := services.ValidateRequest(query); err != nil {
+		_ = c.Error(errors.NewAppError(http.StatusBadRequest, constants.ErrInvalidRqPayload, err))
+		return
+	}
+
+	response, err := ctrl.SagaService.ListSagas(c.Request.Context(), query)
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(c, http.StatusOK, response)
+}
+
+// GetSaga returns a saga with the status of each of its steps
+// @Summary Get a saga
+// @Tags Admin
+// @Produce json
+// @Param sagaId path string true "Saga ID"
+// @Success 200 {object} dtos.SagaResponse
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/admin/sagas/{sagaId} [get]
+func (ctrl *SagaController) GetSaga(c *gin.Context) {
+	response, err := ctrl.SagaService.GetSaga(c.Request.Context(), c.Param("sagaId"))
+	if err != nil {
+		_ = c.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(c, http.StatusOK, response)
+}
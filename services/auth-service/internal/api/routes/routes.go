@@ -1,8 +1,14 @@
 package routes
 
 import (
+	"expvar"
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/constants"
 	"github.com/Mir00r/auth-service/internal/api/controllers"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/metrics"
 	"github.com/Mir00r/auth-service/middlewares"
+	"github.com/Mir00r/shared/logging"
 	"github.com/gin-gonic/gin"
 )
 
@@ -10,47 +16,138 @@ import (
 func SetupRoutes(
 	router *gin.Engine,
 	publicAuthController *controllers.PublicAuthController,
+	registrationController *controllers.RegistrationController,
+	passwordlessAuthController *controllers.PasswordlessAuthController,
+	federatedAuthController *controllers.FederatedAuthController,
 	protectedAuthController *controllers.ProtectedAuthController,
+	identityController *controllers.IdentityController,
+	accountDeletionController *controllers.AccountDeletionController,
 	internalAuthController *controllers.InternalAuthController,
+	auditController *controllers.AuditController,
+	sagaController *controllers.SagaController,
+	healthController *controllers.HealthController,
 ) {
+	// Log every request with its request ID, including the work of the other middlewares
+	router.Use(middlewares.RequestLogging())
+
+	// Trace every request, including the work of the other middlewares
+	router.Use(middlewares.Tracing())
+
+	// Count every request and time it, including the work of the other middlewares
+	router.Use(middlewares.Metrics())
+
 	// Attach exception middlewares
 	router.Use(middlewares.ErrorHandler())
 
+	// Authentication events are recorded through the audit service
+	auditService := auditController.AuditService
+
+	// Initialize liveness and readiness probes
+	initializeHealthRoutes(router, healthController)
+
 	// Initialize Public API routes
-	initializePublicRoutes(router, publicAuthController)
+	initializePublicRoutes(router, publicAuthController, registrationController, auditService)
+
+	// Initialize Passwordless API routes
+	initializePasswordlessRoutes(router, passwordlessAuthController, auditService)
+
+	// Initialize Federated login API routes
+	initializeFederatedRoutes(router, federatedAuthController, auditService)
 
 	// Initialize Protected API routes
-	initializeProtectedRoutes(router, protectedAuthController)
+	initializeProtectedRoutes(router, protectedAuthController, auditService)
+
+	// Initialize linked identity API routes
+	initializeIdentityRoutes(router, identityController)
+
+	// Initialize account deletion API routes
+	initializeAccountDeletionRoutes(router, accountDeletionController)
 
 	// Initialize Internal API routes
 	initializeInternalRoutes(router, internalAuthController)
+
+	// Initialize Admin API routes
+	initializeAdminRoutes(router, auditController, sagaController)
+}
+
+// initializeHealthRoutes sets up the probes of load balancers and orchestrators, which need no credentials
+func initializeHealthRoutes(router *gin.Engine, controller *controllers.HealthController) {
+	healthGroup := router.Group("/health")
+	{
+		healthGroup.GET("/live", controller.Live)
+		healthGroup.GET("/ready", controller.Ready)
+	}
 }
 
 // initializePublicRoutes sets up routes for Public APIs
-func initializePublicRoutes(router *gin.Engine, controller *controllers.PublicAuthController) {
+func initializePublicRoutes(router *gin.Engine, controller *controllers.PublicAuthController, registrationController *controllers.RegistrationController, auditService services.AuditService) {
 	publicGroup := router.Group("/v1/public/auth")
 	{
-		publicGroup.POST("/login", controller.PublicLogin)
-		publicGroup.POST("/register", controller.PublicRegister)
-		publicGroup.POST("/password-reset", controller.PasswordReset)
-		publicGroup.POST("/confirm-password-reset", controller.ConfirmPasswordReset)
+		publicGroup.POST("/login", middlewares.Audit(auditService, constants.AuditLogin), controller.PublicLogin)
+		publicGroup.POST("/register", registrationController.Register)
+		publicGroup.POST("/verify-email", registrationController.VerifyEmail)
+		publicGroup.POST("/password-reset", middlewares.Audit(auditService, constants.AuditPasswordResetRequested), controller.PasswordReset)
+		publicGroup.POST("/confirm-password-reset", middlewares.Audit(auditService, constants.AuditPasswordResetCompleted), controller.ConfirmPasswordReset)
+	}
+}
+
+// initializePasswordlessRoutes sets up routes for passwordless login APIs
+func initializePasswordlessRoutes(router *gin.Engine, controller *controllers.PasswordlessAuthController, auditService services.AuditService) {
+	passwordlessGroup := router.Group("/v1/public/auth/passwordless")
+	{
+		passwordlessGroup.POST("/request", middlewares.Audit(auditService, constants.AuditPasswordlessRequest), controller.RequestLogin)
+		passwordlessGroup.POST("/verify", middlewares.Audit(auditService, constants.AuditPasswordlessVerify), controller.VerifyLogin)
+		passwordlessGroup.POST("/confirm", middlewares.Audit(auditService, constants.AuditPasswordlessConfirm), controller.ConfirmLogin)
+	}
+}
+
+// initializeFederatedRoutes sets up routes for login through external identity providers
+func initializeFederatedRoutes(router *gin.Engine, controller *controllers.FederatedAuthController, auditService services.AuditService) {
+	federatedGroup := router.Group("/v1/public/auth/oidc")
+	{
+		federatedGroup.GET("/:provider/login", middlewares.Audit(auditService, constants.AuditOIDCLogin), controller.StartLogin)
+		federatedGroup.GET("/:provider/callback", middlewares.Audit(auditService, constants.AuditOIDCCallback), controller.Callback)
 	}
 }
 
 // initializeProtectedRoutes sets up routes for Protected APIs
-func initializeProtectedRoutes(router *gin.Engine, controller *controllers.ProtectedAuthController) {
+func initializeProtectedRoutes(router *gin.Engine, controller *controllers.ProtectedAuthController, auditService services.AuditService) {
 	protectedGroup := router.Group("/v1/protected/auth")
 	protectedGroup.Use(middlewares.AuthMiddleware()) // Apply JWT validation middlewares
 	{
-		protectedGroup.POST("/logout", controller.ProtectedLogout)
-		protectedGroup.POST("/refresh-token", controller.RefreshToken)
-		protectedGroup.POST("/mfa/enable", controller.EnableMFA)
-		protectedGroup.POST("/mfa/verify", controller.VerifyMFA)
+		protectedGroup.POST("/logout", middlewares.Audit(auditService, constants.AuditLogout), controller.ProtectedLogout)
+		protectedGroup.POST("/refresh-token", middlewares.Audit(auditService, constants.AuditTokenRefresh), controller.RefreshToken)
+		protectedGroup.POST("/change-password", middlewares.Audit(auditService, constants.AuditPasswordChange), controller.ChangePassword)
+		protectedGroup.POST("/mfa/enable", middlewares.Audit(auditService, constants.AuditMFAEnable), controller.EnableMFA)
+		protectedGroup.POST("/mfa/verify", middlewares.Audit(auditService, constants.AuditMFAVerify), controller.VerifyMFA)
+		protectedGroup.POST("/mfa/challenge", middlewares.Audit(auditService, constants.AuditMFAChallenge), controller.RequestMFAChallenge)
 
 		protectedGroup.GET("/user-profile", controller.ProtectedUserProfile)
 	}
 }
 
+// initializeIdentityRoutes sets up routes for managing the sign-in methods of an account
+func initializeIdentityRoutes(router *gin.Engine, controller *controllers.IdentityController) {
+	identityGroup := router.Group("/v1/protected/auth/identities")
+	identityGroup.Use(middlewares.AuthMiddleware()) // Apply JWT validation middlewares
+	{
+		identityGroup.GET("", controller.ListIdentities)
+		identityGroup.POST("/link/:provider", controller.StartLink)
+		identityGroup.POST("/:identityId/unlink", controller.Unlink)
+	}
+}
+
+// initializeAccountDeletionRoutes sets up routes for deleting the authenticated user's account
+func initializeAccountDeletionRoutes(router *gin.Engine, controller *controllers.AccountDeletionController) {
+	deletionGroup := router.Group("/v1/protected/auth/account/deletion")
+	deletionGroup.Use(middlewares.AuthMiddleware()) // Apply JWT validation middlewares
+	{
+		deletionGroup.POST("", controller.RequestDeletion)
+		deletionGroup.GET("", controller.GetDeletion)
+		deletionGroup.DELETE("", controller.CancelDeletion)
+	}
+}
+
 // initializeInternalRoutes sets up routes for Internal APIs
 func initializeInternalRoutes(router *gin.Engine, controller *controllers.InternalAuthController) {
 	internalGroup := router.Group("/v1/internal/auth")
@@ -58,5 +155,27 @@ func initializeInternalRoutes(router *gin.Engine, controller *controllers.Intern
 	{
 		internalGroup.POST("/validate-token", controller.ValidateToken)
 		internalGroup.GET("/service-health", controller.ServiceHealth)
+		internalGroup.GET("/debug/vars", gin.WrapH(expvar.Handler()))      // Runtime and outbound call metrics
+		internalGroup.GET("/metrics", gin.WrapH(metrics.Handler()))        // Prometheus metrics
+		internalGroup.GET("/log-level", gin.WrapH(logging.LevelHandler())) // Current log level
+		internalGroup.PUT("/log-level", gin.WrapH(logging.LevelHandler())) // Change the log level until restart
+		internalGroup.GET("/config", gin.WrapH(config.Handler()))          // Configuration in effect, secrets redacted
+	}
+}
+
+// initializeAdminRoutes sets up routes for Admin APIs
+func initializeAdminRoutes(router *gin.Engine, controller *controllers.AuditController, sagaController *controllers.SagaController) {
+	adminGroup := router.Group("/v1/admin/audit")
+	adminGroup.Use(middlewares.AdminBasicAuthMiddleware) // Apply admin Basic Auth middlewares
+	{
+		adminGroup.GET("/events", controller.ListEvents)
+		adminGroup.GET("/verify", controller.VerifyChain)
+	}
+
+	sagaGroup := router.Group("/v1/admin/sagas")
+	sagaGroup.Use(middlewares.AdminBasicAuthMiddleware) // Apply admin Basic Auth middlewares
+	{
+		sagaGroup.GET("", sagaController.ListSagas)
+		sagaGroup.GET("/:sagaId", sagaController.GetSaga)
 	}
 }
@@ -0,0 +1,46 @@
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/messaging"
+	"log/slog"
+)
+
+// deletionRequestedEvent is the envelope of the user.deletion_requested event published by user-service
+type deletionRequestedEvent struct {
+	Data struct {
+		UserID      string `json:"userId"`
+		Email       string `json:"email"`
+		RequestedBy string `json:"requestedBy"`
+	} `json:"data"`
+}
+
+// UserEventConsumer applies events published by user-service to the accounts
+type UserEventConsumer struct {
+	AccountDeletionService services.AccountDeletionService
+}
+
+// NewUserEventConsumer initializes a new UserEventConsumer
+func NewUserEventConsumer(accountDeletionService services.AccountDeletionService) *UserEventConsumer {
+	return &UserEventConsumer{AccountDeletionService: accountDeletionService}
+}
+
+// Start subscribes to the user-service events
+func (c *UserEventConsumer) Start(ctx context.Context, subscriber *messaging.NATSSubscriber, stream string) error {
+	return subscriber.Subscribe(ctx, stream, constants.ServiceName+"-deletion", constants.EventUserDeletionRequested, c.HandleDeletionRequested)
+}
+
+// HandleDeletionRequested schedules the deletion of the account. Scheduling is idempotent while
+// the deletion is pending, so a redelivered event does not start a second one.
+func (c *UserEventConsumer) HandleDeletionRequested(ctx context.Context, msg messaging.Message) error {
+	var event deletionRequestedEvent
+	if err := json.Unmarshal(msg.Payload, &event); err != nil || event.Data.UserID == "" || event.Data.Email == "" {
+		// A malformed event will never succeed, so it is dropped instead of redelivered
+		slog.WarnContext(ctx, "Dropping malformed event", "subject", msg.Subject, "event_id", msg.ID)
+		return nil
+	}
+	return c.AccountDeletionService.ScheduleDeletion(ctx, event.Data.UserID, event.Data.Email, event.Data.RequestedBy)
+}
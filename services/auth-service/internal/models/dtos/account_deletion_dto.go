@@ -0,0 +1,17 @@
+package dtos
+
+import (
+	"github.com/Mir00r/auth-service/saga"
+	"time"
+)
+
+// AccountDeletionResponse reports the state of the deletion of the caller's account
+type AccountDeletionResponse struct {
+	DeletionID   string           `json:"deletionId"`
+	Status       string           `json:"status"`                 // running, completed, failed or cancelled
+	Cancellable  bool             `json:"cancellable"`            // Whether the grace period is still running
+	ScheduledFor *time.Time       `json:"scheduledFor,omitempty"` // End of the grace period while it is running
+	Steps        []saga.StepState `json:"steps"`
+	RequestedAt  time.Time        `json:"requestedAt"`
+	EndedAt      *time.Time       `json:"endedAt,omitempty"`
+}
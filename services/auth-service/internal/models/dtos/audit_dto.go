@@ -0,0 +1,54 @@
+package dtos
+
+import (
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"time"
+)
+
+// AuditEvent describes an authentication event to be appended to the audit log
+type AuditEvent struct {
+	EventType constants.AuditEventType
+	UserID    string // Affected account when known
+	Actor     string // User ID or submitted email of the caller
+	IP        string
+	UserAgent string
+	Outcome   string // constants.AuditOutcomeSuccess or constants.AuditOutcomeFailure
+	Reason    string // Reason code for failures
+}
+
+// AuditQuery represents the filters accepted by the audit log admin API
+type AuditQuery struct {
+	UserID    string     `form:"userId" validate:"omitempty,uuid"`
+	EventType string     `form:"eventType"`
+	Outcome   string     `form:"outcome" validate:"omitempty,oneof=success failure"`
+	From      *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"` // Inclusive lower bound
+	To        *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`   // Exclusive upper bound
+	Page      int        `form:"page" validate:"omitempty,min=1"`
+	PerPage   int        `form:"perPage" validate:"omitempty,min=1,max=500"`
+}
+
+// PaginatedAuditResponse is used for returning audit records with pagination
+type PaginatedAuditResponse struct {
+	Events     []entities.AuditLog `json:"events"`
+	TotalCount int64               `json:"totalCount"`
+	Page       int                 `json:"page"`
+	PerPage    int                 `json:"perPage"`
+}
+
+// AuditChainAnchor is a record of the chain known from outside the database, such as the last event a SIEM
+// sink received. Verifying against it detects records removed from the end of the chain.
+type AuditChainAnchor struct {
+	Sequence int64  `form:"sequence" validate:"omitempty,min=1"`
+	Hash     string `form:"hash" validate:"required_with=Sequence,omitempty,len=64,hexadecimal"`
+}
+
+// AuditChainVerification reports whether the audit hash chain is intact
+type AuditChainVerification struct {
+	Valid                bool   `json:"valid"`
+	RecordsChecked       int64  `json:"recordsChecked"`
+	FirstInvalidSequence int64  `json:"firstInvalidSequence,omitempty"` // First record whose hash or link does not match
+	Truncated            bool   `json:"truncated,omitempty"`            // The anchor is past the last record
+	HeadSequence         int64  `json:"headSequence"`                   // Last record of the chain, to keep outside the database
+	HeadHash             string `json:"headHash,omitempty"`
+}
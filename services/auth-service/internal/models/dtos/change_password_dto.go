@@ -0,0 +1,17 @@
+package dtos
+
+// ChangePasswordRequest represents the payload for changing the password of the authenticated user
+type ChangePasswordRequest struct {
+	CurrentPassword     string `json:"currentPassword" validate:"required"`
+	NewPassword         string `json:"newPassword" validate:"required,min=8"`
+	OTP                 string `json:"otp" validate:"omitempty,len=6"` // Required only when MFA is enabled
+	RevokeOtherSessions bool   `json:"revokeOtherSessions"`
+}
+
+// ChangePasswordResponse represents the result of a password change.
+// When other sessions are revoked, a fresh token pair is issued for the caller.
+type ChangePasswordResponse struct {
+	Message         string         `json:"message"`
+	SessionsRevoked bool           `json:"sessionsRevoked"`
+	Tokens          *LoginResponse `json:"tokens,omitempty"`
+}
@@ -0,0 +1,7 @@
+package dtos
+
+// FederatedLoginStartResponse carries the provider URL the browser has to be redirected to
+type FederatedLoginStartResponse struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+	State            string `json:"state"`
+}
@@ -0,0 +1,45 @@
+package dtos
+
+import "github.com/Mir00r/shared/health"
+
+// ServiceHealthResponse reports the health of the service, of its dependencies and of the services it calls
+type ServiceHealthResponse struct {
+	Status    health.Status    `json:"status"`    // up, degraded while a dependency is impaired or a circuit breaker is not closed, or down
+	StartedAt string           `json:"startedAt"` // RFC3339
+	Uptime    string           `json:"uptime"`    // Time since the service started, e.g. 26h3m12s
+	Version   string           `json:"version"`
+	CheckedAt string           `json:"checkedAt"` // RFC3339 time the checks ran, as results are cached briefly
+	Checks    []health.Result  `json:"checks"`
+	Upstreams []UpstreamHealth `json:"upstreams"`
+}
+
+// UpstreamHealth reports the circuit breaker of one instance of a called service
+type UpstreamHealth struct {
+	Upstream            string `json:"upstream"`
+	Target              string `json:"target"` // host:port of the instance
+	BreakerState        string `json:"breakerState"`
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	InFlight            int64  `json:"inFlight"`
+}
+
+// LivenessResponse tells that the process is alive and able to serve requests
+type LivenessResponse struct {
+	Status    health.Status `json:"status"`
+	StartedAt string        `json:"startedAt"` // RFC3339
+	Uptime    string        `json:"uptime"`
+}
+
+// ReadinessResponse tells whether the service is ready to receive traffic. It is served without
+// authentication, so it leaves out the errors of the checks, which name hosts and versions.
+type ReadinessResponse struct {
+	Status    health.Status  `json:"status"`
+	CheckedAt string         `json:"checkedAt"` // RFC3339
+	Checks    []CheckSummary `json:"checks"`
+}
+
+// CheckSummary is the outcome of one readiness check
+type CheckSummary struct {
+	Name      string        `json:"name"`
+	Status    health.Status `json:"status"`
+	LatencyMs float64       `json:"latencyMs"`
+}
@@ -0,0 +1,19 @@
+package dtos
+
+import "time"
+
+// ReauthenticationRequest proves the caller is the account owner before a sign-in method is changed.
+// The password is required when the account has one; otherwise the access token must be recent.
+type ReauthenticationRequest struct {
+	Password string `json:"password"`
+	OTP      string `json:"otp" validate:"omitempty,len=6"` // Required only when MFA is enabled
+}
+
+// IdentityResponse describes one sign-in method linked to the account
+type IdentityResponse struct {
+	ID         string     `json:"id"`
+	Provider   string     `json:"provider"`
+	Email      *string    `json:"email,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
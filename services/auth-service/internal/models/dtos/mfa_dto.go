@@ -10,5 +10,4 @@ type VerifyMFAResponse struct {
 
 type EnableMFAResponse struct {
 	Message string `json:"message"`
-	OTP     string `json:"otp"`
 }
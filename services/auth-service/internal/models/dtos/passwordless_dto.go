@@ -0,0 +1,38 @@
+package dtos
+
+// PasswordlessLoginRequest represents the payload for requesting a magic link or email code
+type PasswordlessLoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Method   string `json:"method" validate:"required,oneof=link code"`
+	DeviceID string `json:"deviceId" validate:"required,max=255"` // Opaque identifier of the requesting device
+}
+
+// PasswordlessLoginResponse is returned for every request, whether or not the email is registered
+type PasswordlessLoginResponse struct {
+	ChallengeID string `json:"challengeId"`
+	Method      string `json:"method"`
+	ExpiresIn   int64  `json:"expiresIn"` // Time in seconds until the link or code expires
+}
+
+// PasswordlessVerifyRequest redeems either a magic link token or a challenge ID with its email code
+type PasswordlessVerifyRequest struct {
+	Token       string `json:"token" validate:"omitempty"`
+	ChallengeID string `json:"challengeId" validate:"omitempty,uuid"`
+	Code        string `json:"code" validate:"omitempty,len=6,numeric"`
+	DeviceID    string `json:"deviceId" validate:"required,max=255"`
+}
+
+// PasswordlessConfirmRequest lets the requesting device approve a login started on another device
+type PasswordlessConfirmRequest struct {
+	ChallengeID   string `json:"challengeId" validate:"required,uuid"`
+	DeviceID      string `json:"deviceId" validate:"required,max=255"`
+	PendingDevice string `json:"pendingDevice" validate:"required,len=64,hexadecimal"` // Device shown in the confirmation prompt
+}
+
+// PasswordlessVerifyResult is either a LoginResponse or a request for cross-device confirmation
+type PasswordlessVerifyResult struct {
+	Login                *LoginResponse `json:"login,omitempty"`
+	ConfirmationRequired bool           `json:"confirmationRequired"`
+	ChallengeID          string         `json:"challengeId,omitempty"`
+	PendingDevice        string         `json:"pendingDevice,omitempty"` // Identity of the device waiting for approval, sent back to confirm it
+}
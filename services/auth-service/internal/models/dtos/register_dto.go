@@ -9,3 +9,16 @@ type RegisterRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
 }
+
+// RegistrationResponse reports the outcome of a registration. A registration still in progress
+// is retried in the background and the account becomes usable once it completes.
+type RegistrationResponse struct {
+	RegistrationID string `json:"registrationId"`
+	Status         string `json:"status"` // completed or running
+	Message        string `json:"message"`
+}
+
+// VerifyEmailRequest redeems the token of an email verification link
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
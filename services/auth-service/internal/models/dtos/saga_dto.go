@@ -0,0 +1,39 @@
+package dtos
+
+import (
+	"github.com/Mir00r/auth-service/saga"
+	"time"
+)
+
+// SagaQuery represents the filters accepted by the saga admin API
+type SagaQuery struct {
+	Type    string `form:"type"`
+	Status  string `form:"status" validate:"omitempty,oneof=running compensating completed compensated failed cancelled"`
+	Key     string `form:"key"`
+	Page    int    `form:"page" validate:"omitempty,min=1"`
+	PerPage int    `form:"perPage" validate:"omitempty,min=1,max=500"`
+}
+
+// SagaResponse describes a saga and the progress of each of its steps
+type SagaResponse struct {
+	ID            string           `json:"id"`
+	Type          string           `json:"type"`
+	Key           string           `json:"key"`
+	Status        string           `json:"status"`
+	CurrentStep   string           `json:"currentStep,omitempty"` // Empty once the saga has ended
+	Attempts      int              `json:"attempts"`              // Failed attempts of the current step
+	NextAttemptAt time.Time        `json:"nextAttemptAt"`
+	LastError     *string          `json:"lastError,omitempty"`
+	Steps         []saga.StepState `json:"steps"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	UpdatedAt     time.Time        `json:"updatedAt"`
+	EndedAt       *time.Time       `json:"endedAt,omitempty"`
+}
+
+// PaginatedSagaResponse is used for returning sagas with pagination
+type PaginatedSagaResponse struct {
+	Sagas      []SagaResponse `json:"sagas"`
+	TotalCount int64          `json:"totalCount"`
+	Page       int            `json:"page"`
+	PerPage    int            `json:"perPage"`
+}
@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"github.com/Mir00r/auth-service/constants"
+	"time"
+)
+
+// AuditLog is one append-only, hash-chained record of an authentication event
+type AuditLog struct {
+	ID        string                   `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Sequence  int64                    `gorm:"uniqueIndex;not null" json:"sequence"`        // Position in the hash chain
+	EventType constants.AuditEventType `gorm:"type:varchar(50);not null" json:"event_type"` // Authentication event
+	UserID    *string                  `gorm:"type:uuid" json:"user_id,omitempty"`          // Affected account when known
+	Actor     string                   `gorm:"type:varchar(255)" json:"actor"`              // User ID or submitted email of the caller
+	IP        string                   `gorm:"type:varchar(64)" json:"ip"`                  // Client IP address
+	UserAgent string                   `gorm:"type:text" json:"user_agent"`                 // Client user agent
+	Outcome   string                   `gorm:"type:varchar(10);not null" json:"outcome"`    // success or failure
+	Reason    string                   `gorm:"type:varchar(50)" json:"reason,omitempty"`    // Failure reason code
+	CreatedAt time.Time                `gorm:"not null" json:"created_at"`                  // Set before hashing, microsecond precision
+	PrevHash  string                   `gorm:"type:char(64);not null" json:"prev_hash"`     // Hash of the previous record
+	Hash      string                   `gorm:"type:char(64);not null" json:"hash"`          // Hash over PrevHash and this record
+}
+
+// TableName overrides the default table name
+func (AuditLog) TableName() string {
+	return "auth.audit_log"
+}
@@ -0,0 +1,19 @@
+package entities
+
+import "time"
+
+// EmailVerificationToken is the emailed proof that a user owns the address they registered with.
+// Only the hash of the token is stored.
+type EmailVerificationToken struct {
+	ID        string     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    string     `gorm:"type:uuid;not null" json:"user_id"`
+	TokenHash string     `gorm:"type:varchar(64);not null;unique" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (EmailVerificationToken) TableName() string {
+	return "auth.email_verification_token"
+}
@@ -0,0 +1,20 @@
+package entities
+
+import "time"
+
+// OIDCLoginState holds the per-attempt secrets of an authorization code flow until the provider calls back
+type OIDCLoginState struct {
+	ID           string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	State        string    `gorm:"type:text;uniqueIndex;not null" json:"state"` // Opaque value round-tripped through the provider
+	Provider     string    `gorm:"type:varchar(50);not null" json:"provider"`   // Name of the configured identity provider
+	Nonce        string    `gorm:"type:text;not null" json:"-"`                 // Expected ID token nonce
+	CodeVerifier string    `gorm:"type:text;not null" json:"-"`                 // PKCE code verifier
+	LinkUserID   *string   `gorm:"type:uuid" json:"link_user_id,omitempty"`     // Signed-in user the identity is linked to, nil for logins
+	ExpiresAt    time.Time `gorm:"not null" json:"expires_at"`                  // State expiration timestamp
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`            // Automatically set at creation
+}
+
+// TableName overrides the default table name
+func (OIDCLoginState) TableName() string {
+	return "auth.oidc_login_state"
+}
@@ -0,0 +1,30 @@
+package entities
+
+import (
+	"github.com/Mir00r/auth-service/constants"
+	"gorm.io/gorm"
+	"time"
+)
+
+// PasswordlessChallenge represents a magic link or email code issued for passwordless login
+type PasswordlessChallenge struct {
+	ID                string                       `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID            string                       `gorm:"type:uuid;not null;index" json:"user_id"`
+	Method            string                       `gorm:"type:varchar(10);not null" json:"method"`                 // "link" or "code"
+	TokenHash         *string                      `gorm:"type:text" json:"-"`                                      // Hash of the magic link token
+	CodeHash          *string                      `gorm:"type:text" json:"-"`                                      // Hash of the email code
+	DeviceHash        string                       `gorm:"type:text;not null" json:"-"`                             // Hash of the requesting device ID
+	PendingDeviceHash *string                      `gorm:"type:text" json:"-"`                                      // Device waiting for confirmation
+	Status            constants.PasswordlessStatus `gorm:"type:varchar(30);not null;default:pending" json:"status"` // Lifecycle state
+	Attempts          int                          `gorm:"not null;default:0" json:"attempts"`                      // Failed code attempts
+	ExpiresAt         time.Time                    `gorm:"not null" json:"expires_at"`
+	RedeemedAt        *time.Time                   `json:"redeemed_at,omitempty"`
+	CreatedAt         time.Time                    `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time                    `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt         gorm.DeletedAt               `gorm:"index" json:"-"`
+}
+
+// TableName overrides the default table name
+func (PasswordlessChallenge) TableName() string {
+	return "auth.passwordless_challenge"
+}
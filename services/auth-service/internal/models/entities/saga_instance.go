@@ -0,0 +1,32 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SagaInstance is the persisted state of a saga, advanced step by step by the orchestrator
+// and resumed from here after a failure or a crash
+type SagaInstance struct {
+	ID            string          `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	SagaType      string          `gorm:"type:varchar(50);not null" json:"saga_type"` // Definition the saga runs
+	SagaKey       string          `gorm:"type:varchar(255);not null" json:"saga_key"` // Business key, e.g. the email being registered
+	Status        string          `gorm:"type:varchar(20);not null" json:"status"`    // running, compensating, completed, compensated, failed or cancelled
+	Step          int             `gorm:"not null;default:0" json:"step"`             // Index of the step being executed or compensated
+	StepName      string          `gorm:"type:varchar(50);not null" json:"step_name"` // Name of that step, empty once the saga has ended
+	Data          json.RawMessage `gorm:"type:jsonb;not null" json:"-"`               // Step inputs and outputs
+	StepLog       json.RawMessage `gorm:"type:jsonb;not null" json:"step_log"`        // Progress of every step
+	Attempts      int             `gorm:"not null;default:0" json:"attempts"`         // Failed attempts of the current step
+	NextAttemptAt time.Time       `gorm:"not null" json:"next_attempt_at"`            // Earliest time of the next attempt
+	LockedUntil   *time.Time      `json:"locked_until,omitempty"`                     // Lease of the instance advancing the saga
+	Version       int64           `gorm:"not null;default:0" json:"-"`                // Bumped by every write to the saga
+	LastError     *string         `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt     time.Time       `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time       `gorm:"autoUpdateTime" json:"updated_at"`
+	EndedAt       *time.Time      `json:"ended_at,omitempty"`
+}
+
+// TableName overrides the default table name
+func (SagaInstance) TableName() string {
+	return "auth.saga_instance"
+}
@@ -0,0 +1,19 @@
+package entities
+
+import "time"
+
+// UserIdentity is one way of signing in to an account: the local password or an external provider identity
+type UserIdentity struct {
+	ID         string     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID     string     `gorm:"type:uuid;not null;index" json:"user_id"`                                                 // Foreign key to User
+	Provider   string     `gorm:"type:varchar(50);not null;uniqueIndex:uq_user_identity_provider_subject" json:"provider"` // "password" or a configured provider name
+	Subject    string     `gorm:"type:text;not null;uniqueIndex:uq_user_identity_provider_subject" json:"subject"`         // Provider subject; the user ID for passwords
+	Email      *string    `gorm:"type:varchar(100)" json:"email,omitempty"`                                                // Email reported by the provider when linked
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`                                                                  // Last successful sign-in with this identity
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`                                                        // Automatically set at creation
+}
+
+// TableName overrides the default table name
+func (UserIdentity) TableName() string {
+	return "auth.user_identity"
+}
@@ -0,0 +1,93 @@
+package reconcile
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Credential is the password hash of one account as stored by either service
+type Credential struct {
+	Email        string
+	Name         string
+	PasswordHash string
+	UpdatedAt    time.Time
+}
+
+// Strategy decides which hash wins when both services hold different hashes for an account
+type Strategy string
+
+const (
+	PreferNewest      Strategy = "newest"       // The most recently updated row wins
+	PreferUserService Strategy = "user-service" // user-service keeps its hash
+	PreferAuthService Strategy = "auth-service" // The auth-service hash replaces the user-service one
+)
+
+// ParseStrategy validates a strategy name
+func ParseStrategy(name string) (Strategy, error) {
+	switch strategy := Strategy(name); strategy {
+	case PreferNewest, PreferUserService, PreferAuthService:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown strategy %q", name)
+	}
+}
+
+// ActionType is the change made to user-service for one account
+type ActionType string
+
+const (
+	CreateInUserService ActionType = "create" // The account only exists in auth-service
+	UpdateUserService   ActionType = "update" // The auth-service hash replaces the user-service one
+	KeepUserService     ActionType = "keep"   // user-service already holds the winning hash
+)
+
+// Action reconciles one account. After the user-service change is applied, the local hash is cleared
+// so user-service is the only store of the credential.
+type Action struct {
+	Type       ActionType
+	Credential Credential // The auth-service credential
+	Reason     string
+}
+
+// Plan compares the credentials of both services and returns the actions that move every
+// auth-service hash to user-service. Emails match case-insensitively, and auth-service rows
+// without a hash are already migrated.
+func Plan(local, remote []Credential, strategy Strategy) []Action {
+	remoteByEmail := make(map[string]Credential, len(remote))
+	for _, credential := range remote {
+		remoteByEmail[strings.ToLower(credential.Email)] = credential
+	}
+
+	var actions []Action
+	for _, credential := range local {
+		if credential.PasswordHash == "" {
+			continue
+		}
+
+		existing, ok := remoteByEmail[strings.ToLower(credential.Email)]
+		switch {
+		case !ok:
+			actions = append(actions, Action{Type: CreateInUserService, Credential: credential, Reason: "missing in user-service"})
+		case existing.PasswordHash == credential.PasswordHash:
+			actions = append(actions, Action{Type: KeepUserService, Credential: credential, Reason: "hashes match"})
+		case authServiceWins(credential, existing, strategy):
+			actions = append(actions, Action{Type: UpdateUserService, Credential: credential, Reason: "hashes differ, auth-service wins"})
+		default:
+			actions = append(actions, Action{Type: KeepUserService, Credential: credential, Reason: "hashes differ, user-service wins"})
+		}
+	}
+	return actions
+}
+
+// authServiceWins resolves a conflict between two different hashes
+func authServiceWins(local, remote Credential, strategy Strategy) bool {
+	switch strategy {
+	case PreferAuthService:
+		return true
+	case PreferUserService:
+		return false
+	default:
+		return local.UpdatedAt.After(remote.UpdatedAt)
+	}
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"gorm.io/gorm"
+	"time"
+)
+
+// auditChainLockKey is the advisory lock serializing appends to the audit hash chain
+const auditChainLockKey = 7_310_031
+
+// AuditFilter narrows an audit log query; zero values match everything
+type AuditFilter struct {
+	UserID    string
+	EventType string
+	Outcome   string
+	From      *time.Time
+	To        *time.Time
+	Offset    int
+	Limit     int
+}
+
+// AuditRepository defines methods for interacting with the audit log table
+type AuditRepository struct {
+	DB *gorm.DB
+}
+
+// NewAuditRepository creates a new instance of AuditRepository
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return AuditRepository{DB: db}
+}
+
+// WithContext returns a repository whose queries are cancelled with ctx
+func (repo *AuditRepository) WithContext(ctx context.Context) AuditRepository {
+	return AuditRepository{DB: repo.DB.WithContext(ctx)}
+}
+
+// Append links the records, in order, to the end of the hash chain and stores them in one transaction.
+// Appends are serialized across replicas with a transaction-scoped advisory lock so every record sees its true
+// predecessor. Records keep the CreatedAt they were given, or get the current time.
+func (repo *AuditRepository) Append(records ...*entities.AuditLog) error {
+	return repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLockKey).Error; err != nil {
+			return err
+		}
+
+		prevHash, sequence := constants.AuditGenesisHash, int64(0)
+		var last entities.AuditLog
+		err := tx.Order("sequence DESC").First(&last).Error
+		switch {
+		case err == nil:
+			prevHash, sequence = last.Hash, last.Sequence
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		for _, record := range records {
+			sequence++
+			record.PrevHash, record.Sequence = prevHash, sequence
+			if record.CreatedAt.IsZero() {
+				record.CreatedAt = time.Now()
+			}
+			// Postgres stores microseconds, so hash exactly what will be read back
+			record.CreatedAt = record.CreatedAt.UTC().Truncate(time.Microsecond)
+			record.Hash = utils.AuditRecordHash(record.PrevHash, record)
+			prevHash = record.Hash
+		}
+		return tx.Create(records).Error
+	})
+}
+
+// Find retrieves the records matching the filter, newest first, with the total match count
+func (repo *AuditRepository) Find(filter AuditFilter) ([]entities.AuditLog, int64, error) {
+	query := repo.DB.Model(&entities.AuditLog{})
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []entities.AuditLog
+	err := query.Order("sequence DESC").Offset(filter.Offset).Limit(filter.Limit).Find(&records).Error
+	return records, total, err
+}
+
+// FindAfterSequence retrieves up to limit records following the given sequence, in chain order
+func (repo *AuditRepository) FindAfterSequence(sequence int64, limit int) ([]entities.AuditLog, error) {
+	var records []entities.AuditLog
+	err := repo.DB.Where("sequence > ?", sequence).Order("sequence").Limit(limit).Find(&records).Error
+	return records, err
+}
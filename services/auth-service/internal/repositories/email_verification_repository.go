@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"gorm.io/gorm"
+	"time"
+)
+
+// EmailVerificationRepository defines methods for interacting with the email_verification_token table
+type EmailVerificationRepository struct {
+	DB *gorm.DB
+}
+
+// NewEmailVerificationRepository creates a new instance of EmailVerificationRepository
+func NewEmailVerificationRepository(db *gorm.DB) EmailVerificationRepository {
+	return EmailVerificationRepository{DB: db}
+}
+
+// WithContext returns a repository whose queries are cancelled with ctx
+func (repo *EmailVerificationRepository) WithContext(ctx context.Context) EmailVerificationRepository {
+	return EmailVerificationRepository{DB: repo.DB.WithContext(ctx)}
+}
+
+// ReplaceToken stores a new verification token for the user, revoking the ones sent before
+func (repo *EmailVerificationRepository) ReplaceToken(token *entities.EmailVerificationToken) error {
+	return repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Delete(&entities.EmailVerificationToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// DeleteTokens removes the unused verification tokens of the user
+func (repo *EmailVerificationRepository) DeleteTokens(userID string) error {
+	return repo.DB.Where("user_id = ? AND used_at IS NULL", userID).
+		Delete(&entities.EmailVerificationToken{}).Error
+}
+
+// FindValidToken retrieves an unused, unexpired token by its hash
+func (repo *EmailVerificationRepository) FindValidToken(tokenHash string) (*entities.EmailVerificationToken, error) {
+	var token entities.EmailVerificationToken
+	err := repo.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, time.Now()).
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed records that a token was redeemed
+func (repo *EmailVerificationRepository) MarkUsed(id string) error {
+	return repo.DB.Model(&entities.EmailVerificationToken{}).
+		Where("id = ?", id).
+		Update("used_at", time.Now()).
+		Error
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// IdentityRepository defines methods for interacting with the user identity table
+type IdentityRepository struct {
+	DB *gorm.DB
+}
+
+// NewIdentityRepository creates a new instance of IdentityRepository
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return IdentityRepository{DB: db}
+}
+
+// WithTx returns a repository that works inside the given transaction
+func (repo *IdentityRepository) WithTx(tx *gorm.DB) IdentityRepository {
+	return IdentityRepository{DB: tx}
+}
+
+// WithContext returns a repository whose queries are cancelled with ctx
+func (repo *IdentityRepository) WithContext(ctx context.Context) IdentityRepository {
+	return IdentityRepository{DB: repo.DB.WithContext(ctx)}
+}
+
+// CreateIdentity links a new identity to a user
+func (repo *IdentityRepository) CreateIdentity(identity *entities.UserIdentity) error {
+	return repo.DB.Create(identity).Error
+}
+
+// EnsureIdentity links the identity unless the user already holds it
+func (repo *IdentityRepository) EnsureIdentity(identity *entities.UserIdentity) error {
+	return repo.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(identity).Error
+}
+
+// FindByProviderSubject retrieves the identity a provider reports for a subject.
+// Returns nil if no account holds the identity.
+func (repo *IdentityRepository) FindByProviderSubject(provider, subject string) (*entities.UserIdentity, error) {
+	var identity entities.UserIdentity
+	if err := repo.DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// FindByUserID retrieves every identity linked to a user, oldest first
+func (repo *IdentityRepository) FindByUserID(userID string) ([]entities.UserIdentity, error) {
+	var identities []entities.UserIdentity
+	err := repo.DB.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error
+	return identities, err
+}
+
+// TouchLastUsed records a successful sign-in with the identity
+func (repo *IdentityRepository) TouchLastUsed(id string) error {
+	return repo.DB.Model(&entities.UserIdentity{}).
+		Where("id = ?", id).
+		Update("last_used_at", time.Now()).
+		Error
+}
+
+// DeleteIdentityIfNotLast unlinks an identity unless it is the user's only remaining one.
+// The user's identities are locked so concurrent unlinks cannot remove the last two at once.
+// Returns false if the identity does not exist or is the last one.
+func (repo *IdentityRepository) DeleteIdentityIfNotLast(userID, id string) (bool, error) {
+	deleted := false
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		var identities []entities.UserIdentity
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).
+			Find(&identities).Error; err != nil {
+			return err
+		}
+		if len(identities) < 2 {
+			return nil
+		}
+
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&entities.UserIdentity{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected == 1
+		return nil
+	})
+	return deleted, err
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"github.com/Mir00r/auth-service/internal/models/entities"
 	"gorm.io/gorm"
 	"time"
@@ -14,6 +15,11 @@ func NewMFARepository(db *gorm.DB) MFARepository {
 	return MFARepository{DB: db}
 }
 
+// WithContext returns a repository whose queries are cancelled with ctx
+func (repo *MFARepository) WithContext(ctx context.Context) MFARepository {
+	return MFARepository{DB: repo.DB.WithContext(ctx)}
+}
+
 func (repo *MFARepository) SaveMFA(userID, otp string, expiry time.Time) error {
 	mfa := entities.Mfa{
 		OTP:       otp,
@@ -42,11 +48,12 @@ func (repo *MFARepository) GetMFA(userID string) (string, time.Time, error) {
 	return otp, expiry, err
 }
 
-func (repo *MFARepository) GetUnusedMFAByUserId(userID string) (*entities.Mfa, error) {
+// GetUnusedMFAByUserId returns the latest unused code issued to the user for purpose
+func (repo *MFARepository) GetUnusedMFAByUserId(userID, purpose string) (*entities.Mfa, error) {
 	var mfa entities.Mfa
 
-	// Query the database to find the MFA record for the given user ID
-	err := repo.DB.Where("user_id = ? AND used = ?", userID, false).Order("expires_at DESC").First(&mfa).Error
+	// Query the database to find the MFA record for the given user ID and purpose
+	err := repo.DB.Where("user_id = ? AND purpose = ? AND used = ?", userID, purpose, false).Order("expires_at DESC").First(&mfa).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil // Return nil if no record is found
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"gorm.io/gorm"
+)
+
+// OIDCStateRepository defines methods for interacting with the OIDC login state table
+type OIDCStateRepository struct {
+	DB *gorm.DB
+}
+
+// NewOIDCStateRepository creates a new instance of OIDCStateRepository
+func NewOIDCStateRepository(db *gorm.DB) OIDCStateRepository {
+	return OIDCStateRepository{DB: db}
+}
+
+// WithContext returns a repository whose queries are cancelled with ctx
+func (repo *OIDCStateRepository) WithContext(ctx context.Context) OIDCStateRepository {
+	return OIDCStateRepository{DB: repo.DB.WithContext(ctx)}
+}
+
+// SaveState stores the secrets of a new authorization attempt
+func (repo *OIDCStateRepository) SaveState(state *entities.OIDCLoginState) error {
+	return repo.DB.Create(state).Error
+}
+
+// DeleteLinkStates deletes the pending attempts to link an identity to the user
+func (repo *OIDCStateRepository) DeleteLinkStates(userID string) error {
+	return repo.DB.Where("link_user_id = ?", userID).Delete(&entities.OIDCLoginState{}).Error
+}
+
+// ConsumeState retrieves and deletes a login state so it can only be used once.
+// Returns nil if the state does not exist or was already consumed.
+func (repo *OIDCStateRepository) ConsumeState(state string) (*entities.OIDCLoginState, error) {
+	var loginState entities.OIDCLoginState
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state = ?", state).First(&loginState).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", loginState.ID).Delete(&entities.OIDCLoginState{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &loginState, nil
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"gorm.io/gorm"
+	"time"
+)
+
+// OutboxRepository defines methods for interacting with the outbox table
+type OutboxRepository struct {
+	DB *gorm.DB
+}
+
+// NewOutboxRepository creates a new instance of OutboxRepository
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return OutboxRepository{DB: db}
+}
+
+// WithTx returns a repository that works inside the given transaction
+func (repo *OutboxRepository) WithTx(tx *gorm.DB) OutboxRepository {
+	return OutboxRepository{DB: tx}
+}
+
+// WithContext returns a repository whose queries are cancelled with ctx
+func (repo *OutboxRepository) WithContext(ctx context.Context) OutboxRepository {
+	return OutboxRepository{DB: repo.DB.WithContext(ctx)}
+}
+
+// Enqueue stores an event for publishing; call it on a transaction-bound repository
+// so the event is only kept if the state change commits
+func (repo *OutboxRepository) Enqueue(event *entities.OutboxEvent) error {
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = time.Now()
+	}
+	return repo.DB.Create(event).Error
+}
+
+// ClaimPending leases up to limit due, unpublished events to the caller.
+// Rows locked by another relay instance are skipped, and an expired lease makes an event claimable again,
+// so an event is delivered at least once even if a relay dies mid-batch.
+func (repo *OutboxRepository) ClaimPending(limit int, lease time.Duration) ([]entities.OutboxEvent, error) {
+	var events []entities.OutboxEvent
+	err := repo.DB.Raw(`
+		UPDATE auth.outbox_event SET locked_until = now() + make_interval(secs => ?)
+		WHERE id IN (
+			SELECT id FROM auth.outbox_event
+			WHERE published_at IS NULL
+			  AND next_attempt_at <= now()
+			  AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY created_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, lease.Seconds(), limit).
+		Scan(&events).Error
+	return events, err
+}
+
+// MarkPublished records a successful publish
+func (repo *OutboxRepository) MarkPublished(id string) error {
+	return repo.DB.Model(&entities.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"published_at": time.Now(), "locked_until": nil}).
+		Error
+}
+
+// MarkFailed records a failed publish and schedules the next attempt
+func (repo *OutboxRepository) MarkFailed(id string, nextAttemptAt time.Time, lastError string) error {
+	return repo.DB.Model(&entities.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": nextAttemptAt,
+			"locked_until":    nil,
+			"last_error":      lastError,
+		}).
+		Error
+}
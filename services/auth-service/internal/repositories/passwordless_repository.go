@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"gorm.io/gorm"
+	"time"
+)
+
+// PasswordlessRepository defines methods for interacting with the passwordless challenge table
+type PasswordlessRepository struct {
+	DB *gorm.DB
+}
+
+// NewPasswordlessRepository creates a new instance of PasswordlessRepository
+func NewPasswordlessRepository(db *gorm.DB) PasswordlessRepository {
+	return PasswordlessRepository{DB: db}
+}
+
+// WithContext returns a repository whose queries are cancelled with ctx
+func (repo *PasswordlessRepository) WithContext(ctx context.Context) PasswordlessRepository {
+	return PasswordlessRepository{DB: repo.DB.WithContext(ctx)}
+}
+
+// CreateChallenge inserts a new passwordless challenge
+func (repo *PasswordlessRepository) CreateChallenge(challenge *entities.PasswordlessChallenge) error {
+	return repo.DB.Create(challenge).Error
+}
+
+// RevokeOpenChallenges revokes every challenge of a user that has not been redeemed yet
+func (repo *PasswordlessRepository) RevokeOpenChallenges(userID string) error {
+	return repo.DB.Model(&entities.PasswordlessChallenge{}).
+		Where("user_id = ? AND status IN ?", userID, openPasswordlessStatuses()).
+		Update("status", constants.PasswordlessRevoked).
+		Error
+}
+
+// FindByID retrieves a challenge by its ID
+func (repo *PasswordlessRepository) FindByID(id string) (*entities.PasswordlessChallenge, error) {
+	var challenge entities.PasswordlessChallenge
+	if err := repo.DB.Where("id = ?", id).First(&challenge).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// FindByTokenHash retrieves a magic link challenge by the hash of its token
+func (repo *PasswordlessRepository) FindByTokenHash(tokenHash string) (*entities.PasswordlessChallenge, error) {
+	var challenge entities.PasswordlessChallenge
+	if err := repo.DB.Where("token_hash = ?", tokenHash).First(&challenge).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// ReserveCodeAttempt counts an attempt at the code of an open challenge before the code is compared, so
+// concurrent guesses cannot exceed maxAttempts. It reports false once maxAttempts attempts were made.
+func (repo *PasswordlessRepository) ReserveCodeAttempt(id string, maxAttempts int) (bool, error) {
+	result := repo.DB.Model(&entities.PasswordlessChallenge{}).
+		Where("id = ? AND status IN ? AND attempts < ?", id, openPasswordlessStatuses(), maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	return result.RowsAffected == 1, result.Error
+}
+
+// RevokeExhausted revokes an open challenge whose code was attempted maxAttempts times
+func (repo *PasswordlessRepository) RevokeExhausted(id string, maxAttempts int) error {
+	return repo.DB.Model(&entities.PasswordlessChallenge{}).
+		Where("id = ? AND status IN ? AND attempts >= ?", id, openPasswordlessStatuses(), maxAttempts).
+		Update("status", constants.PasswordlessRevoked).
+		Error
+}
+
+// CountChallengesSince counts the challenges issued to a user since the given time, whatever became of them
+func (repo *PasswordlessRepository) CountChallengesSince(userID string, since time.Time) (int64, error) {
+	var count int64
+	err := repo.DB.Model(&entities.PasswordlessChallenge{}).
+		Where("user_id = ? AND created_at > ?", userID, since).
+		Count(&count).
+		Error
+	return count, err
+}
+
+// RequestConfirmation parks a pending challenge until the requesting device approves the given device.
+// It reports false when another device is already waiting for, or was given, the approval; the same
+// device asking again keeps its place.
+func (repo *PasswordlessRepository) RequestConfirmation(id string, deviceHash string) (bool, error) {
+	result := repo.DB.Model(&entities.PasswordlessChallenge{}).
+		Where("id = ? AND (status = ? OR (status = ? AND pending_device_hash = ?))", id,
+			constants.PasswordlessPending, constants.PasswordlessAwaitingConfirmation, deviceHash).
+		Updates(map[string]interface{}{
+			"status":              constants.PasswordlessAwaitingConfirmation,
+			"pending_device_hash": deviceHash,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Confirm approves the pending device of a challenge awaiting confirmation, provided it is still the device
+// the requesting device was shown
+func (repo *PasswordlessRepository) Confirm(id string, pendingDeviceHash string) (bool, error) {
+	result := repo.DB.Model(&entities.PasswordlessChallenge{}).
+		Where("id = ? AND status = ? AND pending_device_hash = ?", id, constants.PasswordlessAwaitingConfirmation, pendingDeviceHash).
+		Update("status", constants.PasswordlessConfirmed)
+	return result.RowsAffected == 1, result.Error
+}
+
+// Redeem atomically marks an open challenge as redeemed.
+// It reports false when the challenge was already redeemed, revoked or is not yet approved.
+func (repo *PasswordlessRepository) Redeem(id string, allowed ...constants.PasswordlessStatus) (bool, error) {
+	result := repo.DB.Model(&entities.PasswordlessChallenge{}).
+		Where("id = ? AND status IN ? AND expires_at > ?", id, allowed, time.Now()).
+		Updates(map[string]interface{}{
+			"status":      constants.PasswordlessRedeemed,
+			"redeemed_at": time.Now(),
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// openPasswordlessStatuses lists the statuses of challenges that can still lead to a login
+func openPasswordlessStatuses() []constants.PasswordlessStatus {
+	return []constants.PasswordlessStatus{
+		constants.PasswordlessPending,
+		constants.PasswordlessAwaitingConfirmation,
+		constants.PasswordlessConfirmed,
+	}
+}
@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/saga"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// SagaFilter narrows a saga query; zero values match everything
+type SagaFilter struct {
+	SagaType string
+	Status   string
+	SagaKey  string
+	Offset   int
+	Limit    int
+}
+
+// SagaRepository defines methods for interacting with the saga_instance table
+type SagaRepository struct {
+	DB *gorm.DB
+}
+
+// NewSagaRepository creates a new instance of SagaRepository
+func NewSagaRepository(db *gorm.DB) SagaRepository {
+	return SagaRepository{DB: db}
+}
+
+// WithContext returns a repository whose queries are cancelled with ctx
+func (repo *SagaRepository) WithContext(ctx context.Context) SagaRepository {
+	return SagaRepository{DB: repo.DB.WithContext(ctx)}
+}
+
+// Create inserts a new saga unless an unfinished saga of the same type and key exists
+func (repo *SagaRepository) Create(instance *entities.SagaInstance) error {
+	result := repo.DB.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "saga_type"}, {Name: "saga_key"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "ended_at IS NULL"}}},
+		DoNothing:   true,
+	}).Create(instance)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return saga.ErrAlreadyRunning
+	}
+	return nil
+}
+
+// Save stores the current state of a saga, provided nothing wrote it since it was read: another instance taking
+// over an expired lease or a cancellation bumps the version, and Save then returns saga.ErrLeaseLost.
+func (repo *SagaRepository) Save(instance *entities.SagaInstance) error {
+	read := instance.Version
+	instance.Version++
+	result := repo.DB.Model(instance).Where("version = ?", read).Select("*").Omit("created_at").Updates(instance)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = saga.ErrLeaseLost
+	}
+	if result.Error != nil {
+		instance.Version = read
+	}
+	return result.Error
+}
+
+// ClaimDue leases up to limit unfinished sagas that wait for a retry or were abandoned by a crashed instance.
+// Rows locked by another instance are skipped, so each saga is advanced by one instance at a time.
+func (repo *SagaRepository) ClaimDue(limit int, lease time.Duration) ([]entities.SagaInstance, error) {
+	var instances []entities.SagaInstance
+	err := repo.DB.Raw(`
+		UPDATE auth.saga_instance SET locked_until = now() + make_interval(secs => ?), version = version + 1
+		WHERE id IN (
+			SELECT id FROM auth.saga_instance
+			WHERE ended_at IS NULL
+			  AND next_attempt_at <= now()
+			  AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, lease.Seconds(), limit).
+		Scan(&instances).Error
+	return instances, err
+}
+
+// Cancel ends the unfinished saga of the type and key as cancelled, provided it has not reached step beforeStep
+// and no instance is advancing it. The conditional update keeps a cancellation from racing the orchestrator.
+func (repo *SagaRepository) Cancel(sagaType, key string, beforeStep int) (*entities.SagaInstance, error) {
+	var instances []entities.SagaInstance
+	err := repo.DB.Raw(`
+		UPDATE auth.saga_instance SET status = ?, ended_at = now(), updated_at = now(), version = version + 1
+		WHERE saga_type = ?
+		  AND saga_key = ?
+		  AND ended_at IS NULL
+		  AND status = ?
+		  AND step < ?
+		  AND (locked_until IS NULL OR locked_until < now())
+		RETURNING *`, saga.StatusCancelled, sagaType, key, saga.StatusRunning, beforeStep).
+		Scan(&instances).Error
+	if err != nil || len(instances) == 0 {
+		return nil, err
+	}
+	return &instances[0], nil
+}
+
+// FindLatest retrieves the most recent saga of the type and key
+func (repo *SagaRepository) FindLatest(sagaType, key string) (*entities.SagaInstance, error) {
+	var instance entities.SagaInstance
+	err := repo.DB.Where("saga_type = ? AND saga_key = ?", sagaType, key).
+		Order("created_at DESC").
+		First(&instance).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// FindByID retrieves a saga by its ID
+func (repo *SagaRepository) FindByID(id string) (*entities.SagaInstance, error) {
+	var instance entities.SagaInstance
+	if err := repo.DB.Where("id = ?", id).First(&instance).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// Find returns the sagas matching the filter, newest first, and the total number of matches
+func (repo *SagaRepository) Find(filter SagaFilter) ([]entities.SagaInstance, int64, error) {
+	query := repo.DB.Model(&entities.SagaInstance{})
+	if filter.SagaType != "" {
+		query = query.Where("saga_type = ?", filter.SagaType)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.SagaKey != "" {
+		query = query.Where("saga_key = ?", filter.SagaKey)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var instances []entities.SagaInstance
+	err := query.Order("created_at DESC").Offset(filter.Offset).Limit(filter.Limit).Find(&instances).Error
+	return instances, total, err
+}
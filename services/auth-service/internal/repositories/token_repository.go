@@ -1,9 +1,12 @@
 package repositories
 
 import (
+	"context"
 	"errors"
 	"github.com/Mir00r/auth-service/internal/models/entities"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type TokenRepository struct {
@@ -14,13 +17,59 @@ func NewTokenRepository(db *gorm.DB) TokenRepository {
 	return TokenRepository{DB: db}
 }
 
-// SaveResetToken saves a password reset token in the database
-func (repo *TokenRepository) SaveResetToken(token string, userID string) error {
+// WithTx returns a repository that works inside the given transaction
+func (repo *TokenRepository) WithTx(tx *gorm.DB) TokenRepository {
+	return TokenRepository{DB: tx}
+}
+
+// WithContext returns a repository whose queries are cancelled with ctx
+func (repo *TokenRepository) WithContext(ctx context.Context) TokenRepository {
+	return TokenRepository{DB: repo.DB.WithContext(ctx)}
+}
+
+// SaveResetToken saves the hash of a password reset token in the database. It saves nothing and returns false when
+// the user already has an active reset token, which happens when two resets of the user race.
+func (repo *TokenRepository) SaveResetToken(tokenHash string, userID string, expiresAt time.Time) (bool, error) {
 	resetToken := entities.PasswordResetToken{
-		Token:  token,
-		UserID: userID,
+		Token:     tokenHash,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}
+	result := repo.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&resetToken)
+	return result.RowsAffected == 1, result.Error
+}
+
+// RedeemResetToken marks the reset token with the hash as used and returns it, nil when no unused and unexpired
+// token has the hash. Checking and marking the token is one statement, so a token is redeemed once at most.
+func (repo *TokenRepository) RedeemResetToken(tokenHash string) (*entities.PasswordResetToken, error) {
+	var redeemed []entities.PasswordResetToken
+	err := repo.DB.Model(&redeemed).
+		Clauses(clause.Returning{}).
+		Where("token = ? AND used = ? AND expires_at > ?", tokenHash, false, time.Now()).
+		Update("used", true).
+		Error
+	if err != nil || len(redeemed) == 0 {
+		return nil, err
 	}
-	return repo.DB.Create(&resetToken).Error
+	return &redeemed[0], nil
+}
+
+// ReleaseResetToken makes a redeemed reset token usable again until it expires, for a reset that failed after
+// redeeming it. It returns false when the token expired meanwhile or the user was issued another one.
+func (repo *TokenRepository) ReleaseResetToken(resetToken *entities.PasswordResetToken) (bool, error) {
+	result := repo.DB.Model(&entities.PasswordResetToken{}).
+		Where("id = ? AND used = ? AND expires_at > ?", resetToken.ID, true, time.Now()).
+		Where("NOT EXISTS (SELECT 1 FROM auth.password_reset_token WHERE user_id = ? AND used = ?)", resetToken.UserID, false).
+		Update("used", false)
+	return result.RowsAffected == 1, result.Error
+}
+
+// InvalidateResetTokens marks every outstanding password reset token of a user as used
+func (repo *TokenRepository) InvalidateResetTokens(userID string) error {
+	return repo.DB.Model(&entities.PasswordResetToken{}).
+		Where("user_id = ? AND used = ?", userID, false).
+		Update("used", true).
+		Error
 }
 
 // CreateToken saves a refresh token in the database
@@ -39,7 +88,7 @@ func (repo *TokenRepository) MarkTokenAsUsed(token string) error {
 		Error
 }
 
-// FindToken retrieves a password reset token by its value
+// FindToken retrieves a password reset token by its stored hash
 func (repo *TokenRepository) FindToken(token string) (*entities.PasswordResetToken, error) {
 	var resetToken entities.PasswordResetToken
 	if err := repo.DB.Where("token = ?", token).First(&resetToken).Error; err != nil {
@@ -80,6 +129,11 @@ func (repo *TokenRepository) IsTokenBlacklisted(tokenString string) (bool, error
 	return true, nil
 }
 
+// RevokeUserTokens deletes every session token issued to a user
+func (repo *TokenRepository) RevokeUserTokens(userID string) error {
+	return repo.DB.Where("user_id = ?", userID).Delete(&entities.Token{}).Error
+}
+
 // DeleteToken deletes a refresh token (optional, e.g., during logout)
 func (repo *TokenRepository) DeleteToken(token string) error {
 	return repo.DB.Where("token = ?", token).Delete(&entities.Token{}).Error
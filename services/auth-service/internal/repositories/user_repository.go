@@ -1,10 +1,11 @@
 package repositories
 
 import (
+	"context"
 	"errors"
 	"github.com/Mir00r/auth-service/internal/models/entities"
 	"gorm.io/gorm"
-	"log"
+	"log/slog"
 )
 
 // UserRepository defines methods for interacting with the auth table
@@ -17,6 +18,16 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return UserRepository{DB: db}
 }
 
+// WithTx returns a repository that works inside the given transaction
+func (repo *UserRepository) WithTx(tx *gorm.DB) UserRepository {
+	return UserRepository{DB: tx}
+}
+
+// WithContext returns a repository whose queries are cancelled with ctx
+func (repo *UserRepository) WithContext(ctx context.Context) UserRepository {
+	return UserRepository{DB: repo.DB.WithContext(ctx)}
+}
+
 // CreateUser inserts a new user record into the database
 func (repo *UserRepository) CreateUser(user *entities.User) error {
 	if err := repo.DB.Create(user).Error; err != nil {
@@ -29,15 +40,13 @@ func (repo *UserRepository) CreateUser(user *entities.User) error {
 func (repo *UserRepository) FindUserByEmail(email string) (*entities.User, error) {
 	var user entities.User
 
-	//log.Printf("Querying user by email: %v", email)
-
 	// Use LOWER() for case-insensitive matching
 	if err := repo.DB.Where("LOWER(email) = LOWER(?)", email).First(&user).Error; err != nil {
-		log.Printf("Error querying user from DB: %v using email: %v", err, email)
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Printf("No user found with email: %v", email)
+			slog.DebugContext(repo.DB.Statement.Context, "No user found", "email", email)
 			return nil, nil
 		}
+		slog.ErrorContext(repo.DB.Statement.Context, "Failed to query user", "email", email, "error", err)
 		return nil, err
 	}
 	return &user, nil
@@ -55,10 +64,27 @@ func (repo *UserRepository) FindUserByID(id string) (*entities.User, error) {
 	return &user, nil
 }
 
-// UpdatePassword updates the user's password in the database
-func (repo *UserRepository) UpdatePassword(userID string, hashedPassword string) error {
+// DeleteUser permanently removes a user together with their tokens, reset tokens, MFA records, login links,
+// identities and verification links, so the email address can be registered again. The schema cascades the
+// deletion as well; deleting them here keeps a user from being removed while any of them is left.
+func (repo *UserRepository) DeleteUser(id string) error {
+	return repo.DB.Transaction(func(tx *gorm.DB) error {
+		for _, dependent := range []interface{}{
+			&entities.Token{}, &entities.PasswordResetToken{}, &entities.Mfa{},
+			&entities.PasswordlessChallenge{}, &entities.UserIdentity{}, &entities.EmailVerificationToken{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", id).Delete(dependent).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id = ?", id).Delete(&entities.User{}).Error
+	})
+}
+
+// EnableMFA flags the user as having multi-factor authentication enabled
+func (repo *UserRepository) EnableMFA(userID string) error {
 	return repo.DB.Model(&entities.User{}).
 		Where("id = ?", userID).
-		Update("password", hashedPassword).
+		Update("mfa_enabled", true).
 		Error
 }
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/saga"
+	"net/http"
+)
+
+// Keys of the account deletion saga data
+const (
+	deletionProfileID   = "profileId"   // ID of the profile in user-service, the saga key
+	deletionEmail       = "email"       // Removed once the saga has ended
+	deletionUserID      = "userId"      // ID of the local account record, empty when there is none
+	deletionRequestedBy = "requestedBy" // constants.DeletionRequestedBySelf or the ID of the requesting admin
+)
+
+// deletionSteps holds the dependencies of the account deletion saga steps
+type deletionSteps struct {
+	UserRepo         repositories.UserRepository
+	TokenRepo        repositories.TokenRepository
+	PasswordlessRepo repositories.PasswordlessRepository
+	OIDCStateRepo    repositories.OIDCStateRepository
+	UserClient       *userservice.Client
+}
+
+// NewAccountDeletionSaga defines the saga that deletes an account:
+// 1. Waits for the grace period, during which the deletion can be cancelled.
+// 2. Deactivates the profile in user-service, so no new session can start, and revokes the refresh tokens
+// and open login links. Access tokens are not revoked: they are JWTs checked against no denylist, so those
+// already issued stay valid until they expire, at most jwt.expiry after this step.
+// 3. Deletes the local account record, taking its reset tokens, MFA records and identities with it.
+// The audit log is kept.
+// 4. Anonymizes the profile in user-service, which emits the user.deleted event.
+//
+// A deleted account cannot be restored, so the saga is forward-only: failed steps are retried
+// until they succeed, and a rejected step leaves the saga failed for an operator.
+func NewAccountDeletionSaga(
+	userRepo repositories.UserRepository,
+	tokenRepo repositories.TokenRepository,
+	passwordlessRepo repositories.PasswordlessRepository,
+	oidcStateRepo repositories.OIDCStateRepository,
+	userClient *userservice.Client,
+) saga.Definition {
+	steps := &deletionSteps{
+		UserRepo:         userRepo,
+		TokenRepo:        tokenRepo,
+		PasswordlessRepo: passwordlessRepo,
+		OIDCStateRepo:    oidcStateRepo,
+		UserClient:       userClient,
+	}
+	return saga.Definition{
+		Type: constants.SagaTypeAccountDeletion,
+		Steps: []saga.Step{
+			{Name: constants.DeletionStepGracePeriod, Execute: steps.gracePeriod},
+			{Name: constants.DeletionStepRevokeSessions, Execute: steps.revokeSessions},
+			{Name: constants.DeletionStepPurgeAuthData, Execute: steps.purgeAuthData},
+			{Name: constants.DeletionStepAnonymizeProfile, Execute: steps.anonymizeProfile},
+		},
+		Sensitive:        []string{deletionEmail},
+		ForwardOnly:      true,
+		CancellableSteps: 1,
+	}
+}
+
+// gracePeriod ends the grace period. The saga is scheduled to start once the period is over,
+// so there is nothing left to wait for when the step runs.
+func (s *deletionSteps) gracePeriod(_ context.Context, _ saga.Data) error {
+	return nil
+}
+
+// revokeSessions deactivates the profile and ends the sessions of the local account. Only the refresh tokens are
+// revoked, so no session is renewed; the access tokens already issued keep working until they expire.
+func (s *deletionSteps) revokeSessions(ctx context.Context, data saga.Data) error {
+	err := s.UserClient.DeactivateUser(ctx, data[deletionProfileID])
+	if err != nil && upstreamStatus(err) != http.StatusNotFound {
+		return upstreamStepError(err, errors.ErrFailedToDeleteAccount)
+	}
+
+	userID, err := s.localUserID(data)
+	if err != nil || userID == "" {
+		return err
+	}
+	if err := s.TokenRepo.RevokeUserTokens(userID); err != nil {
+		return errors.NewAppError(errors.ErrFailedToDeleteAccount.Code, errors.ErrFailedToDeleteAccount.Message, err)
+	}
+	if err := s.PasswordlessRepo.RevokeOpenChallenges(userID); err != nil {
+		return errors.NewAppError(errors.ErrFailedToDeleteAccount.Code, errors.ErrFailedToDeleteAccount.Message, err)
+	}
+	return nil
+}
+
+// purgeAuthData deletes the local account record with its tokens, reset tokens, MFA records, login links,
+// identities and verification links
+func (s *deletionSteps) purgeAuthData(_ context.Context, data saga.Data) error {
+	userID, err := s.localUserID(data)
+	if err != nil || userID == "" {
+		return err
+	}
+
+	if err := s.OIDCStateRepo.DeleteLinkStates(userID); err != nil {
+		return errors.NewAppError(errors.ErrFailedToDeleteAccount.Code, errors.ErrFailedToDeleteAccount.Message, err)
+	}
+	if err := s.UserRepo.DeleteUser(userID); err != nil {
+		return errors.NewAppError(errors.ErrFailedToDeleteAccount.Code, errors.ErrFailedToDeleteAccount.Message, err)
+	}
+	return nil
+}
+
+// anonymizeProfile has user-service remove the personal data of the profile; a profile that is gone counts as done
+func (s *deletionSteps) anonymizeProfile(ctx context.Context, data saga.Data) error {
+	err := s.UserClient.AnonymizeUser(ctx, data[deletionProfileID])
+	if err != nil && upstreamStatus(err) != http.StatusNotFound {
+		return upstreamStepError(err, errors.ErrFailedToDeleteAccount)
+	}
+	return nil
+}
+
+// localUserID returns the ID of the local account record, looking it up by email the first time.
+// Accounts that never signed in to auth-service have no record, reported as an empty ID.
+func (s *deletionSteps) localUserID(data saga.Data) (string, error) {
+	if userID := data[deletionUserID]; userID != "" {
+		return userID, nil
+	}
+
+	user, err := s.UserRepo.FindUserByEmail(data[deletionEmail])
+	if err != nil {
+		return "", errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+	if user == nil {
+		return "", nil
+	}
+	data[deletionUserID] = user.ID
+	return user.ID, nil
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	goerrors "errors"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/Mir00r/auth-service/saga"
+	"net/http"
+	"time"
+)
+
+// AccountDeletionService defines the methods for deleting accounts
+type AccountDeletionService interface {
+	RequestDeletion(ctx context.Context, userID string, issuedAt time.Time, req dtos.ReauthenticationRequest) (*dtos.AccountDeletionResponse, error)
+	CancelDeletion(ctx context.Context, userID string) error
+	GetDeletion(ctx context.Context, userID string) (*dtos.AccountDeletionResponse, error)
+	ScheduleDeletion(ctx context.Context, profileID, email, requestedBy string) error
+}
+
+// accountDeletionService is the concrete implementation of AccountDeletionService
+type accountDeletionService struct {
+	Orchestrator    *saga.Orchestrator          // Runs the account deletion saga
+	UserRepo        repositories.UserRepository // Repository for user data
+	IdentityService IdentityService             // Re-authenticates the owner before a deletion
+	UserClient      *userservice.Client         // Resolves the profile of the account in user-service
+}
+
+// NewAccountDeletionService initializes a new instance of AccountDeletionService
+func NewAccountDeletionService(
+	orchestrator *saga.Orchestrator,
+	userRepo repositories.UserRepository,
+	identityService IdentityService,
+	userClient *userservice.Client,
+) AccountDeletionService {
+	return &accountDeletionService{
+		Orchestrator:    orchestrator,
+		UserRepo:        userRepo,
+		IdentityService: identityService,
+		UserClient:      userClient,
+	}
+}
+
+// RequestDeletion schedules the deletion of the caller's account
+//
+// This function performs the following steps:
+// 1. Re-authenticates the caller, as a deletion cannot be undone once the grace period is over.
+// 2. Resolves the profile of the account in user-service, which keys the deletion.
+// 3. Schedules the account deletion saga to start when the grace period ends.
+//
+// Parameters:
+// - userID: The unique identifier of the authenticated user.
+// - issuedAt: Issue time of the caller's access token.
+// - req: ReauthenticationRequest carrying the password and OTP when the account has them.
+//
+// Returns:
+// - An AccountDeletionResponse describing the scheduled deletion.
+// - An error if re-authentication fails or a deletion is already scheduled.
+func (svc *accountDeletionService) RequestDeletion(ctx context.Context, userID string, issuedAt time.Time, req dtos.ReauthenticationRequest) (*dtos.AccountDeletionResponse, error) {
+	user, err := svc.IdentityService.Reauthenticate(ctx, userID, issuedAt, req)
+	if err != nil {
+		return nil, err
+	}
+
+	profileID, err := svc.profileID(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := svc.Orchestrator.Schedule(constants.SagaTypeAccountDeletion, profileID, saga.Data{
+		deletionProfileID:   profileID,
+		deletionEmail:       user.Email,
+		deletionUserID:      user.ID,
+		deletionRequestedBy: constants.DeletionRequestedBySelf,
+	}, time.Now().Add(utils.DeletionGracePeriod()))
+	if goerrors.Is(err, saga.ErrAlreadyRunning) {
+		return nil, errors.ErrDeletionAlreadyScheduled
+	}
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToScheduleDeletion, err)
+	}
+
+	return toDeletionResponse(instance)
+}
+
+// CancelDeletion cancels the scheduled deletion of the caller's account while the grace period is running
+func (svc *accountDeletionService) CancelDeletion(ctx context.Context, userID string) error {
+	user, err := svc.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	profileID, err := svc.profileID(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.Orchestrator.Cancel(constants.SagaTypeAccountDeletion, profileID)
+	if goerrors.Is(err, saga.ErrNotCancellable) {
+		// Tell a deletion that is past its grace period apart from one that was never requested
+		latest, latestErr := svc.Orchestrator.Latest(constants.SagaTypeAccountDeletion, profileID)
+		if latestErr == nil && latest != nil && latest.EndedAt == nil {
+			return errors.ErrDeletionNotCancellable
+		}
+		return errors.ErrDeletionNotFound
+	}
+	if err != nil {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToScheduleDeletion, err)
+	}
+	return nil
+}
+
+// GetDeletion returns the most recent deletion requested for the caller's account
+func (svc *accountDeletionService) GetDeletion(ctx context.Context, userID string) (*dtos.AccountDeletionResponse, error) {
+	user, err := svc.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	profileID, err := svc.profileID(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := svc.Orchestrator.Latest(constants.SagaTypeAccountDeletion, profileID)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToFetchSagas, err)
+	}
+	if instance == nil {
+		return nil, errors.ErrDeletionNotFound
+	}
+	return toDeletionResponse(instance)
+}
+
+// ScheduleDeletion schedules the deletion of an account requested through user-service, e.g. by an admin.
+// A deletion that is already scheduled is left as it is, so redelivered requests are harmless.
+func (svc *accountDeletionService) ScheduleDeletion(ctx context.Context, profileID, email, requestedBy string) error {
+	_, err := svc.Orchestrator.Schedule(constants.SagaTypeAccountDeletion, profileID, saga.Data{
+		deletionProfileID:   profileID,
+		deletionEmail:       email,
+		deletionRequestedBy: requestedBy,
+	}, time.Now().Add(utils.DeletionGracePeriod()))
+	if err != nil && !goerrors.Is(err, saga.ErrAlreadyRunning) {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToScheduleDeletion, err)
+	}
+	return nil
+}
+
+// findUser retrieves the local account record of the caller
+func (svc *accountDeletionService) findUser(ctx context.Context, userID string) (*entities.User, error) {
+	userRepo := svc.UserRepo.WithContext(ctx)
+
+	user, err := userRepo.FindUserByID(userID)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+	if user == nil {
+		return nil, errors.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// profileID returns the ID of the user-service profile of the account
+func (svc *accountDeletionService) profileID(ctx context.Context, user *entities.User) (string, error) {
+	status, err := fetchAccountStatus(ctx, svc.UserClient, user.Email)
+	if err != nil {
+		return "", err
+	}
+	if !status.Exists || status.UserID == "" {
+		return "", errors.ErrUserNotFound
+	}
+	return status.UserID, nil
+}
+
+// toDeletionResponse describes an account deletion saga to the account owner
+func toDeletionResponse(instance *entities.SagaInstance) (*dtos.AccountDeletionResponse, error) {
+	steps, err := saga.StepLog(instance)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToFetchSagas, err)
+	}
+
+	response := &dtos.AccountDeletionResponse{
+		DeletionID:  instance.ID,
+		Status:      instance.Status,
+		Steps:       steps,
+		RequestedAt: instance.CreatedAt,
+		EndedAt:     instance.EndedAt,
+	}
+	if instance.Status == saga.StatusRunning && instance.Step == 0 {
+		scheduledFor := instance.NextAttemptAt
+		response.Cancellable = true
+		response.ScheduledFor = &scheduledFor
+	}
+	return response, nil
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"log/slog"
+	"net/http"
+)
+
+// ensureAccountActive refuses a login when user-service reports the account as inactive, deleted or missing.
+// user-service owns the account state, so logins fail closed while it cannot be reached.
+func ensureAccountActive(ctx context.Context, userClient *userservice.Client, email string) error {
+	status, err := fetchAccountStatus(ctx, userClient, email)
+	if err != nil {
+		return err
+	}
+	return CheckAccountStatus(*status)
+}
+
+// fetchAccountStatus asks user-service for the state of the account with the email
+func fetchAccountStatus(ctx context.Context, userClient *userservice.Client, email string) (*userservice.AccountStatusResponse, error) {
+	status, err := userClient.GetAccountStatus(ctx, userservice.AccountStatusRequest{Email: email})
+	if err != nil {
+		slog.ErrorContext(ctx, "Account status check failed", "error", err)
+		return nil, errors.NewAppError(http.StatusServiceUnavailable, constants.ErrFailedToCheckAccountStatus, err)
+	}
+	return status, nil
+}
+
+// CheckAccountStatus maps the account state reported by user-service to a login decision
+func CheckAccountStatus(status userservice.AccountStatusResponse) error {
+	if !status.Exists || status.Deleted || !status.Active {
+		return errors.ErrAccountDisabled
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	goerrors "errors"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/Mir00r/shared/auditsinks"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// auditVerifyBatchSize is the number of records loaded at a time while verifying the chain
+const auditVerifyBatchSize = 1000
+
+// auditReasons maps error messages returned by the services to audit reason codes
+var auditReasons = map[string]string{
+	errors.ErrInvalidCredentials.Message:           constants.AuditReasonInvalidCredentials,
+	constants.InvalidCredentials:                   constants.AuditReasonInvalidCredentials,
+	errors.ErrInvalidPayload.Message:               constants.AuditReasonInvalidPayload,
+	errors.ErrInvalidOrExpiredRefreshToken.Message: constants.AuditReasonInvalidRefreshToken,
+	errors.ErrInvalidOrExpiredResetToken.Message:   constants.AuditReasonInvalidResetToken,
+	errors.ErrResetTokenAlreadyUsed.Message:        constants.AuditReasonResetTokenUsed,
+	errors.ErrWeakPassword.Message:                 constants.AuditReasonWeakPassword,
+	errors.ErrUserNotFound.Message:                 constants.AuditReasonUserNotFound,
+	errors.ErrAccountDisabled.Message:              constants.AuditReasonAccountDisabled,
+	constants.ErrInvalidOTP:                        constants.AuditReasonInvalidOTP,
+	constants.ErrOTPExpired:                        constants.AuditReasonOTPExpired,
+	constants.ErrInvalidToken:                      constants.AuditReasonInvalidToken,
+}
+
+// AuditService defines the methods for recording and reviewing authentication events
+type AuditService interface {
+	Record(event dtos.AuditEvent)
+	Close(ctx context.Context) error
+	ListEvents(ctx context.Context, query dtos.AuditQuery) (*dtos.PaginatedAuditResponse, error)
+	VerifyChain(ctx context.Context, anchor dtos.AuditChainAnchor) (*dtos.AuditChainVerification, error)
+}
+
+// auditService is the concrete implementation of AuditService
+type auditService struct {
+	AuditRepo  repositories.AuditRepository // Repository for the audit log
+	UserRepo   repositories.UserRepository  // Resolves accounts from submitted emails
+	Dispatcher *auditsinks.Dispatcher       // Streams recorded events to the configured SIEM sinks
+	writer     *auditWriter                 // Appends recorded events to the hash chain off the request path
+}
+
+// NewAuditService initializes a new instance of AuditService
+func NewAuditService(
+	auditRepo repositories.AuditRepository,
+	userRepo repositories.UserRepository,
+	dispatcher *auditsinks.Dispatcher,
+) AuditService {
+	svc := &auditService{
+		AuditRepo:  auditRepo,
+		UserRepo:   userRepo,
+		Dispatcher: dispatcher,
+	}
+	svc.writer = newAuditWriter(auditRepo, svc.publish)
+	return svc
+}
+
+// Record queues an event to be appended to the audit log and handed to the SIEM sinks.
+// The event keeps the time it was recorded at. Appending happens in the background, so the request it describes
+// only waits when the queue is full; records the database refuses are retried rather than dropped.
+func (svc *auditService) Record(event dtos.AuditEvent) {
+	record := &entities.AuditLog{
+		EventType: event.EventType,
+		Actor:     event.Actor,
+		IP:        event.IP,
+		UserAgent: event.UserAgent,
+		Outcome:   event.Outcome,
+		Reason:    event.Reason,
+		CreatedAt: time.Now(),
+	}
+
+	// Attribute attempts made with an email to the account it belongs to
+	if event.UserID == "" && strings.Contains(event.Actor, "@") {
+		if user, err := svc.UserRepo.FindUserByEmail(event.Actor); err == nil && user != nil {
+			event.UserID = user.ID
+		}
+	}
+	if event.UserID != "" {
+		record.UserID = &event.UserID
+	}
+
+	if svc.writer.enqueue(record) {
+		return
+	}
+	// The writer is closed, so the few events recorded during shutdown are appended in place
+	if err := svc.writer.write([]*entities.AuditLog{record}); err != nil {
+		slog.Error("Failed to record audit event", "event_type", event.EventType, "error", err)
+	}
+}
+
+// Close appends the events still queued, waiting until ctx expires
+func (svc *auditService) Close(ctx context.Context) error {
+	return svc.writer.Close(ctx)
+}
+
+// publish hands a record stored in the audit log to the SIEM sinks
+func (svc *auditService) publish(record *entities.AuditLog) {
+	var userID string
+	if record.UserID != nil {
+		userID = *record.UserID
+	}
+	svc.Dispatcher.Publish(auditsinks.Event{
+		Time:      record.CreatedAt,
+		Service:   constants.ServiceName,
+		EventType: string(record.EventType),
+		UserID:    userID,
+		Actor:     record.Actor,
+		IP:        record.IP,
+		UserAgent: record.UserAgent,
+		Outcome:   record.Outcome,
+		Reason:    record.Reason,
+		Sequence:  record.Sequence,
+		Hash:      record.Hash,
+	})
+}
+
+// ListEvents returns the audit records matching the query, newest first
+func (svc *auditService) ListEvents(ctx context.Context, query dtos.AuditQuery) (*dtos.PaginatedAuditResponse, error) {
+	auditRepo := svc.AuditRepo.WithContext(ctx)
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PerPage == 0 {
+		query.PerPage = 50
+	}
+
+	records, total, err := auditRepo.Find(repositories.AuditFilter{
+		UserID:    query.UserID,
+		EventType: query.EventType,
+		Outcome:   query.Outcome,
+		From:      query.From,
+		To:        query.To,
+		Offset:    (query.Page - 1) * query.PerPage,
+		Limit:     query.PerPage,
+	})
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToFetchAuditLog, err)
+	}
+
+	return &dtos.PaginatedAuditResponse{
+		Events:     records,
+		TotalCount: total,
+		Page:       query.Page,
+		PerPage:    query.PerPage,
+	}, nil
+}
+
+// VerifyChain recomputes the hash chain from the first record and reports the first record that does not match.
+// A chain cut short at its end is still internally consistent, so when an anchor is given its record must be
+// present with the anchored hash.
+func (svc *auditService) VerifyChain(ctx context.Context, anchor dtos.AuditChainAnchor) (*dtos.AuditChainVerification, error) {
+	auditRepo := svc.AuditRepo.WithContext(ctx)
+
+	result := &dtos.AuditChainVerification{Valid: true}
+	prevHash, prevSequence := constants.AuditGenesisHash, int64(0)
+
+	for {
+		records, err := auditRepo.FindAfterSequence(prevSequence, auditVerifyBatchSize)
+		if err != nil {
+			return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToFetchAuditLog, err)
+		}
+		if len(records) == 0 {
+			break
+		}
+
+		intact, ok := utils.VerifyAuditChain(prevHash, prevSequence, records)
+		if !ok {
+			result.Valid = false
+			result.FirstInvalidSequence = records[intact].Sequence
+			result.RecordsChecked += int64(intact)
+			return result, nil
+		}
+
+		last := records[len(records)-1]
+		result.RecordsChecked += int64(len(records))
+		prevHash, prevSequence = last.Hash, last.Sequence
+
+		// The chain is intact up to here, so the anchored record is either in this batch or still ahead
+		if anchor.Sequence > 0 && anchor.Sequence <= last.Sequence {
+			anchored := records[anchor.Sequence-records[0].Sequence]
+			if !strings.EqualFold(anchored.Hash, anchor.Hash) {
+				result.Valid = false
+				result.FirstInvalidSequence = anchored.Sequence
+				return result, nil
+			}
+			anchor.Sequence = 0
+		}
+	}
+
+	result.HeadSequence = prevSequence
+	if prevSequence > 0 {
+		result.HeadHash = prevHash
+	}
+	if anchor.Sequence > 0 {
+		result.Valid = false
+		result.Truncated = true
+	}
+	return result, nil
+}
+
+// AuditReason maps an error returned to the client to an audit reason code
+func AuditReason(err error, status int) string {
+	var appErr *errors.AppError
+	if goerrors.As(err, &appErr) {
+		if reason, ok := auditReasons[appErr.Message]; ok {
+			return reason
+		}
+		status = appErr.Code
+	} else if err != nil {
+		if reason, ok := auditReasons[err.Error()]; ok {
+			return reason
+		}
+		status = http.StatusInternalServerError
+	}
+
+	switch {
+	case status == http.StatusBadRequest:
+		return constants.AuditReasonBadRequest
+	case status == http.StatusUnauthorized:
+		return constants.AuditReasonUnauthorized
+	case status == http.StatusForbidden:
+		return constants.AuditReasonForbidden
+	case status == http.StatusNotFound:
+		return constants.AuditReasonNotFound
+	default:
+		return constants.AuditReasonInternalError
+	}
+}
+
+// metricOutcome returns the outcome label of an authentication flow: success, or the audit reason of the
+// failure, which keeps the label to a fixed set of values
+func metricOutcome(err error) string {
+	if err == nil {
+		return constants.AuditOutcomeSuccess
+	}
+	return AuditReason(err, http.StatusInternalServerError)
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/metrics"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Limits of the audit writer
+const (
+	auditQueueSize       = 4096                   // Records waiting to be appended
+	auditAppendBatchSize = 200                    // Records appended under one lock of the chain
+	auditRetryMinBackoff = 100 * time.Millisecond // First wait after a failed append
+	auditRetryMaxBackoff = 5 * time.Second        // Longest wait between retries
+)
+
+// auditWriter appends audit records to the hash chain from a single goroutine, so requests never wait for the
+// lock of the chain. Queued records are appended in batches; a batch the database refuses is counted and retried
+// in order until it is stored. Nothing is dropped: while the database is down the queue fills up and Record waits
+// for room.
+type auditWriter struct {
+	repo    repositories.AuditRepository
+	stored  func(record *entities.AuditLog) // Called with every record once it is in the chain
+	records chan *entities.AuditLog
+	closing chan struct{} // Closed when Close starts, releasing the callers waiting for room
+	abandon chan struct{} // Closed when Close gives up on the records the database keeps refusing
+	done    chan struct{} // Closed when the writer goroutine returns
+	closeMu sync.RWMutex
+	closed  bool
+
+	closeOnce, abandonOnce sync.Once
+}
+
+// newAuditWriter starts a writer appending to repo
+func newAuditWriter(repo repositories.AuditRepository, stored func(record *entities.AuditLog)) *auditWriter {
+	w := &auditWriter{
+		repo:    repo,
+		stored:  stored,
+		records: make(chan *entities.AuditLog, auditQueueSize),
+		closing: make(chan struct{}),
+		abandon: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// enqueue queues the record, waiting for room when the queue is full.
+// It reports false once the writer is closing, in which case the caller appends the record itself.
+func (w *auditWriter) enqueue(record *entities.AuditLog) bool {
+	w.closeMu.RLock()
+	defer w.closeMu.RUnlock()
+	if w.closed {
+		return false
+	}
+	select {
+	case w.records <- record:
+		return true
+	case <-w.closing:
+		return false
+	}
+}
+
+// write appends the records once and hands them to stored when the database accepted them
+func (w *auditWriter) write(records []*entities.AuditLog) error {
+	if err := w.repo.Append(records...); err != nil {
+		metrics.AuditWriteFailures.Add(float64(len(records)))
+		return err
+	}
+	for _, record := range records {
+		w.stored(record)
+	}
+	return nil
+}
+
+// run appends the queued records until the queue is closed and drained
+func (w *auditWriter) run() {
+	defer close(w.done)
+	for record := range w.records {
+		batch := []*entities.AuditLog{record}
+	collect:
+		for len(batch) < auditAppendBatchSize {
+			select {
+			case next, ok := <-w.records:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, next)
+			default:
+				break collect
+			}
+		}
+		if !w.store(batch) {
+			slog.Error("Audit writer stopped with records not stored", "records", len(batch)+len(w.records))
+			return
+		}
+	}
+}
+
+// store retries the batch until it is appended, and reports false when Close gave up on it first
+func (w *auditWriter) store(batch []*entities.AuditLog) bool {
+	backoff := auditRetryMinBackoff
+	for {
+		err := w.write(batch)
+		if err == nil {
+			return true
+		}
+		slog.Warn("Failed to append audit records, retrying", "records", len(batch), "backoff", backoff.String(), "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-w.abandon:
+			return false
+		}
+		backoff = min(backoff*2, auditRetryMaxBackoff)
+	}
+}
+
+// Close stops accepting records and waits until the queued ones are stored or ctx expires
+func (w *auditWriter) Close(ctx context.Context) error {
+	w.closeOnce.Do(func() {
+		close(w.closing)
+		w.closeMu.Lock() // Wait for the callers of enqueue to leave before closing the queue
+		w.closed = true
+		close(w.records)
+		w.closeMu.Unlock()
+	})
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.abandonOnce.Do(func() { close(w.abandon) })
+		<-w.done
+		return fmt.Errorf("audit records left unstored: %w", ctx.Err())
+	}
+}
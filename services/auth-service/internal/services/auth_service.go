@@ -1,7 +1,8 @@
 package services
 
 import (
-	"github.com/Mir00r/auth-service/apiclients"
+	"context"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
 	config "github.com/Mir00r/auth-service/configs"
 	"github.com/Mir00r/auth-service/constants"
 	"github.com/Mir00r/auth-service/errors"
@@ -9,70 +10,141 @@ import (
 	"github.com/Mir00r/auth-service/internal/models/entities"
 	"github.com/Mir00r/auth-service/internal/repositories"
 	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/Mir00r/auth-service/messaging"
+	"github.com/Mir00r/auth-service/metrics"
+	"gorm.io/gorm"
+	"log/slog"
 	"net/http"
 	"time"
 )
 
 // AuthService defines the methods for authentication
 type AuthService interface {
-	Authenticate(req dtos.LoginRequest) (*dtos.LoginResponse, error)
-	RegisterUser(req dtos.RegisterRequest) error
-	GetUserProfile(userID string) (*entities.User, error)
+	Authenticate(ctx context.Context, req dtos.LoginRequest) (*dtos.LoginResponse, error)
+	GetUserProfile(ctx context.Context, userID string) (*entities.User, error)
+	ChangePassword(ctx context.Context, userID string, req dtos.ChangePasswordRequest) (*dtos.ChangePasswordResponse, error)
 }
 
 // authService is the concrete implementation of AuthService
 type authService struct {
-	UserRepo          repositories.UserRepository  // Repository for user data
-	TokenRepo         repositories.TokenRepository // Repository for token data
-	InternalWebClient apiclients.WebClient
+	UserRepo     repositories.UserRepository     // Repository for user data
+	TokenRepo    repositories.TokenRepository    // Repository for token data
+	IdentityRepo repositories.IdentityRepository // Repository for linked identities
+	OutboxRepo   repositories.OutboxRepository   // Stores domain events for publishing
+	MFAService   MFAService                      // Verifies MFA codes for sensitive operations
+	UserClient   *userservice.Client
 }
 
 // NewAuthService initializes a new instance of AuthService
 func NewAuthService(
 	userRepo repositories.UserRepository,
 	tokenRepo repositories.TokenRepository,
-	internalWebClient apiclients.WebClient,
+	identityRepo repositories.IdentityRepository,
+	outboxRepo repositories.OutboxRepository,
+	mfaService MFAService,
+	userClient *userservice.Client,
 ) AuthService {
 	return &authService{
-		UserRepo:          userRepo,
-		TokenRepo:         tokenRepo,
-		InternalWebClient: internalWebClient,
+		UserRepo:     userRepo,
+		TokenRepo:    tokenRepo,
+		IdentityRepo: identityRepo,
+		OutboxRepo:   outboxRepo,
+		MFAService:   mfaService,
+		UserClient:   userClient,
 	}
 }
 
 // Authenticate validates user credentials and generates a JWT token
 //
 // This function performs the following steps:
-// 1. Validates the provided email and password against the database.
-// 2. Generates a JWT token for the authenticated user.
-// 3. Returns the generated token or an error if authentication fails.
+// 1. Validates the provided email and password against the credential held by user-service.
+// 2. Refuses accounts that user-service reports as inactive, e.g. while they are being deleted.
+// 3. Resolves the local account record, creating it for accounts registered directly in user-service.
+// 4. Generates a JWT token for the authenticated user.
+// 5. Returns the generated token or an error if authentication fails.
 //
 // Parameters:
+// - ctx: Context of the request; the calls to user-service and the database stop when it is done.
 // - req: LoginRequest containing email and password.
 //
 // Returns:
 // - A map containing the access token.
 // - An error if authentication fails.
-func (svc *authService) Authenticate(req dtos.LoginRequest) (*dtos.LoginResponse, error) {
+func (svc *authService) Authenticate(ctx context.Context, req dtos.LoginRequest) (login *dtos.LoginResponse, err error) {
+	defer func() { metrics.Logins.WithLabelValues(constants.LoginMethodPassword, metricOutcome(err)).Inc() }()
 
-	err := svc.InternalWebClient.Send(http.MethodPost,
-		"http://localhost:8082/v1/internal/user/validate",
-		dtos.LoginRequest{Email: req.Email, Password: req.Password},
-		map[string]string{})
+	// Verify the password against user-service
+	profile, err := verifyPassword(ctx, svc.UserClient, req.Email, req.Password)
 	if err != nil {
 		return nil, err
 	}
-	// Retrieve the user from the database by email
-	user, err := svc.UserRepo.FindUserByEmail(req.Email)
-	if err != nil || user == nil {
-		return nil, errors.ErrInvalidCredentials
+
+	// Only active accounts may sign in; checked first so a deactivated account gets no local record back
+	if err := ensureAccountActive(ctx, svc.UserClient, profile.Email); err != nil {
+		return nil, err
+	}
+
+	// Retrieve the local account record
+	user, err := ensureLocalUser(svc.UserRepo.WithContext(ctx), *profile)
+	if err != nil {
+		return nil, err
+	}
+	identityRepo := svc.IdentityRepo.WithContext(ctx)
+	if err := identityRepo.EnsureIdentity(passwordIdentity(user)); err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+
+	return issueLoginTokens(ctx, svc.TokenRepo, svc.OutboxRepo, svc.UserClient, user, constants.LoginMethodPassword)
+}
+
+// issueLoginTokens issues tokens for a completed login and records the login_succeeded event
+// in the same transaction as the new session. user-service keys profiles by email and updates
+// the last login time from this event, so the profile catches up once the event is delivered.
+// Without a broker the event is never delivered, so the login time is then sent to user-service directly.
+func issueLoginTokens(ctx context.Context, tokenRepo repositories.TokenRepository, outboxRepo repositories.OutboxRepository, userClient *userservice.Client, user *entities.User, method string) (*dtos.LoginResponse, error) {
+	loggedInAt := time.Now()
+	event, err := messaging.NewOutboxEvent(constants.EventLoginSucceeded, user.ID, map[string]string{
+		"userId": user.ID,
+		"email":  user.Email,
+		"method": method,
+	})
+	if err != nil {
+		return nil, errors.NewAppError(errors.ErrSaveToken.Code, errors.ErrSaveToken.Message, err)
+	}
+
+	var login *dtos.LoginResponse
+	err = tokenRepo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if login, err = issueTokens(tokenRepo.WithTx(tx), user); err != nil {
+			return err
+		}
+		txOutbox := outboxRepo.WithTx(tx)
+		if err := txOutbox.Enqueue(event); err != nil {
+			return errors.NewAppError(errors.ErrSaveToken.Code, errors.ErrSaveToken.Message, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Verify the provided password against the hashed password
-	if !utils.VerifyPassword(user.Password, req.Password) {
-		return nil, errors.ErrInvalidCredentials
+	if config.AppConfig.Messaging.NatsURL == "" {
+		recordLastLogin(ctx, userClient, user.Email, loggedInAt)
 	}
+	return login, nil
+}
 
+// recordLastLogin sends the login time to user-service in place of the login_succeeded event.
+// user-service keeps the newest time, so a later delivery of the event changes nothing; a failure is
+// logged and does not fail the login.
+func recordLastLogin(ctx context.Context, userClient *userservice.Client, email string, loggedInAt time.Time) {
+	if err := userClient.Internal.UpdateLastLogin(ctx, email, loggedInAt); err != nil {
+		slog.WarnContext(ctx, "Failed to record the last login in user-service", "error", err)
+	}
+}
+
+// issueTokens generates an access/refresh token pair for the user and persists the session
+func issueTokens(tokenRepo repositories.TokenRepository, user *entities.User) (*dtos.LoginResponse, error) {
 	// Generate a JWT token for the authenticated user
 	accessToken, err := utils.GenerateJWT(user.ID, user.Email, config.AppConfig.JWT.Secret, utils.TokenExpiry())
 	if err != nil {
@@ -86,13 +158,13 @@ func (svc *authService) Authenticate(req dtos.LoginRequest) (*dtos.LoginResponse
 	}
 
 	// Save the refresh token in the database
-	err = svc.TokenRepo.CreateToken(&entities.Token{
+	err = tokenRepo.CreateToken(&entities.Token{
 		UserID:                user.ID,
 		Token:                 refreshToken,
 		RefreshToken:          refreshToken,
 		Type:                  constants.AccessToken,
 		ExpiresAt:             time.Now().Add(utils.TokenExpiry()),
-		RefreshTokenExpiresAt: time.Now().Add(utils.ConvertTokenExpiry(config.AppConfig.JWT.RefreshTokenExpiry)),
+		RefreshTokenExpiresAt: time.Now().Add(utils.RefreshTokenExpiry()),
 	})
 	if err != nil {
 		return nil, errors.NewAppError(errors.ErrSaveToken.Code, errors.ErrSaveToken.Message, err)
@@ -103,41 +175,19 @@ func (svc *authService) Authenticate(req dtos.LoginRequest) (*dtos.LoginResponse
 		AccessToken:           accessToken,
 		RefreshToken:          refreshToken,
 		ExpiresIn:             int64(utils.TokenExpiry().Seconds()),
-		RefreshTokenExpiresIn: int64(utils.ConvertTokenExpiry(config.AppConfig.JWT.RefreshTokenExpiry).Seconds()),
+		RefreshTokenExpiresIn: int64(utils.RefreshTokenExpiry().Seconds()),
 	}, nil
 }
 
-// RegisterUser creates a new user account in the system
-//
-// This function performs the following steps:
-// 1. Hashes the provided password.
-// 2. Creates a new user instance and saves it in the database.
-//
-// Parameters:
-// - req: RegisterRequest containing user registration details (name, email, password).
-//
-// Returns:
-// - An error if registration fails.
-func (svc *authService) RegisterUser(req dtos.RegisterRequest) error {
-	// Hash the user's password
-	hashedPassword, err := utils.HashPassword(req.Password)
-	if err != nil {
-		return errors.ErrHashPassword
+// passwordIdentity builds the identity record of the account's password
+func passwordIdentity(user *entities.User) *entities.UserIdentity {
+	email := user.Email
+	return &entities.UserIdentity{
+		UserID:   user.ID,
+		Provider: constants.IdentityProviderPassword,
+		Subject:  user.ID,
+		Email:    &email,
 	}
-
-	// Create a new user entity
-	newUser := &entities.User{
-		Name:     req.Name,
-		Email:    req.Email,
-		Password: hashedPassword,
-	}
-
-	// Save the new user in the database
-	if err := svc.UserRepo.CreateUser(newUser); err != nil {
-		return errors.ErrFailedToRegisterUser
-	}
-
-	return nil
 }
 
 // GetUserProfile retrieves the profile of a user by their ID
@@ -147,14 +197,16 @@ func (svc *authService) RegisterUser(req dtos.RegisterRequest) error {
 // 2. Returns the user's profile or an error if the user does not exist.
 //
 // Parameters:
+// - ctx: Context of the request.
 // - userID: The unique identifier of the user.
 //
 // Returns:
 // - A pointer to the User entity.
 // - An error if the user does not exist or retrieval fails.
-func (svc *authService) GetUserProfile(userID string) (*entities.User, error) {
+func (svc *authService) GetUserProfile(ctx context.Context, userID string) (*entities.User, error) {
 	// Retrieve the user by their ID
-	user, err := svc.UserRepo.FindUserByID(userID)
+	userRepo := svc.UserRepo.WithContext(ctx)
+	user, err := userRepo.FindUserByID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -166,3 +218,112 @@ func (svc *authService) GetUserProfile(userID string) (*entities.User, error) {
 
 	return user, nil
 }
+
+// ChangePassword changes the password of an authenticated user
+//
+// This function performs the following steps:
+// 1. Verifies the current password and, if MFA is enabled, the provided OTP.
+// 2. Applies the password policy to the new password.
+// 3. Stores the new password in user-service and invalidates outstanding reset tokens.
+// 4. Optionally revokes every session and issues a fresh token pair for the caller.
+// 5. Notifies the user that the password was changed.
+//
+// Parameters:
+// - ctx: Context of the request; the calls to user-service and the database stop when it is done.
+// - userID: The unique identifier of the authenticated user.
+// - req: ChangePasswordRequest containing the current and new passwords.
+//
+// Returns:
+// - A ChangePasswordResponse, carrying new tokens when other sessions were revoked.
+// - An error if the change is rejected or fails.
+func (svc *authService) ChangePassword(ctx context.Context, userID string, req dtos.ChangePasswordRequest) (*dtos.ChangePasswordResponse, error) {
+	userRepo := svc.UserRepo.WithContext(ctx)
+	user, err := userRepo.FindUserByID(userID)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+	if user == nil {
+		return nil, errors.ErrUserNotFound
+	}
+
+	// Re-authenticate the caller
+	profile, err := verifyCurrentPassword(ctx, svc.UserClient, user.Email, req.CurrentPassword)
+	if err != nil {
+		return nil, err
+	}
+	if requiresMFA(user, profile) {
+		if req.OTP == "" {
+			return nil, errors.ErrMFACodeRequired
+		}
+		if err := svc.MFAService.VerifyMFA(ctx, user.ID, req.OTP); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the password policy
+	if !utils.IsStrongPassword(req.NewPassword) {
+		return nil, errors.ErrWeakPassword
+	}
+	reused, err := isCurrentPassword(ctx, svc.UserClient, user.Email, req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, errors.ErrPasswordReused
+	}
+
+	event, err := messaging.NewOutboxEvent(constants.EventPasswordChanged, user.ID, map[string]interface{}{
+		"userId":          user.ID,
+		"reason":          "change",
+		"sessionsRevoked": req.RevokeOtherSessions,
+	})
+	if err != nil {
+		return nil, errors.ErrFailedToUpdatePassword
+	}
+
+	// Store the new password in user-service
+	if err := setPassword(ctx, svc.UserClient, user.Email, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	// The invalidated reset links, revoked sessions and the event commit together
+	response := &dtos.ChangePasswordResponse{Message: constants.PasswordChangedSuccessful}
+	err = svc.TokenRepo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txTokenRepo := svc.TokenRepo.WithTx(tx)
+		txOutboxRepo := svc.OutboxRepo.WithTx(tx)
+
+		// Reset links issued for the old password must not be redeemable any more
+		if err := txTokenRepo.InvalidateResetTokens(user.ID); err != nil {
+			return errors.ErrFailedToUpdatePassword
+		}
+
+		if req.RevokeOtherSessions {
+			if err := txTokenRepo.RevokeUserTokens(user.ID); err != nil {
+				return errors.ErrFailedToUpdatePassword
+			}
+
+			// Keep the caller signed in with a fresh session
+			tokens, err := issueTokens(txTokenRepo, user)
+			if err != nil {
+				return err
+			}
+			response.SessionsRevoked = true
+			response.Tokens = tokens
+		}
+
+		if err := txOutboxRepo.Enqueue(event); err != nil {
+			return errors.ErrFailedToUpdatePassword
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The password is already changed, so a notification failure is only logged
+	if err := NewEmailService().SendPasswordChangedEmail(user.Email); err != nil {
+		slog.ErrorContext(ctx, "Failed to send password changed notification", "error", err)
+	}
+
+	return response, nil
+}
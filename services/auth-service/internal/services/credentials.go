@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	goerrors "errors"
+	"github.com/Mir00r/auth-service/apiclients"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/telemetry"
+	"log/slog"
+	"net/http"
+)
+
+// Passwords are stored only by user-service. auth-service keeps a local account record per email
+// for sessions, MFA and linked identities, but no password hash.
+
+// verifyPassword checks a password against the credential held by user-service and returns the profile
+func verifyPassword(ctx context.Context, userClient *userservice.Client, email, password string) (*userservice.UserResponse, error) {
+	profile, err := userClient.Internal.ValidateCredentials(ctx, email, password)
+	if err != nil {
+		var statusErr *apiclients.StatusError
+		if goerrors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusNotFound) {
+			return nil, errors.ErrInvalidCredentials
+		}
+		slog.ErrorContext(ctx, "Credential check failed", "error", err)
+		return nil, errors.NewAppError(http.StatusServiceUnavailable, constants.ErrCredentialStoreUnavailable, err)
+	}
+	telemetry.SetUser(ctx, profile.ID, profile.TenantID)
+	return profile, nil
+}
+
+// verifyCurrentPassword re-authenticates a signed-in user with their current password and returns their profile
+func verifyCurrentPassword(ctx context.Context, userClient *userservice.Client, email, password string) (*userservice.UserResponse, error) {
+	profile, err := verifyPassword(ctx, userClient, email, password)
+	if err == errors.ErrInvalidCredentials {
+		return nil, errors.ErrIncorrectCurrentPassword
+	}
+	return profile, err
+}
+
+// requiresMFA tells whether a sensitive operation of the user takes an MFA code: once the user verified one in
+// auth-service, or when user-service holds an MFA secret for the account. profile may be nil.
+func requiresMFA(user *entities.User, profile *userservice.UserResponse) bool {
+	return user.MFAEnabled || (profile != nil && profile.MFAEnabled)
+}
+
+// isCurrentPassword reports whether password is the account's current password
+func isCurrentPassword(ctx context.Context, userClient *userservice.Client, email, password string) (bool, error) {
+	_, err := verifyPassword(ctx, userClient, email, password)
+	switch {
+	case err == nil:
+		return true, nil
+	case err == errors.ErrInvalidCredentials:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// setPassword replaces the password held by user-service; callers apply the password policy first
+func setPassword(ctx context.Context, userClient *userservice.Client, email, password string) error {
+	if err := userClient.SetPassword(ctx, userservice.SetPasswordRequest{Email: email, Password: password}); err != nil {
+		slog.ErrorContext(ctx, "Password update failed", "error", err)
+		return errors.NewAppError(errors.ErrFailedToUpdatePassword.Code, errors.ErrFailedToUpdatePassword.Message, err)
+	}
+	return nil
+}
+
+// ensureLocalUser returns the local account record of a user-service profile, creating it on first use
+func ensureLocalUser(userRepo repositories.UserRepository, profile userservice.UserResponse) (*entities.User, error) {
+	user, err := userRepo.FindUserByEmail(profile.Email)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+	if user != nil {
+		return user, nil
+	}
+
+	user = &entities.User{
+		Name:  profile.Name,
+		Email: profile.Email,
+	}
+	if err := userRepo.CreateUser(user); err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+	return user, nil
+}
@@ -1,6 +1,6 @@
 package services
 
-import "fmt"
+import "log/slog"
 
 type EmailService struct{}
 
@@ -10,6 +10,28 @@ func NewEmailService() *EmailService {
 
 func (svc *EmailService) SendPasswordResetEmail(email, resetLink string) error {
 	// Implement your email sending logic here (e.g., using an SMTP server or a third-party service)
-	fmt.Printf("Sending password reset email to %s: %s\n", email, resetLink)
+	// Links and codes carry one-time tokens, so they stay out of the logs
+	slog.Info("Sending password reset email", "email", email)
+	return nil
+}
+
+func (svc *EmailService) SendMagicLinkEmail(email, loginLink string) error {
+	slog.Info("Sending magic login link", "email", email)
+	return nil
+}
+
+func (svc *EmailService) SendLoginCodeEmail(email, code string) error {
+	slog.Info("Sending login code", "email", email)
+	return nil
+}
+
+func (svc *EmailService) SendVerificationEmail(email, verificationLink string) error {
+	slog.Info("Sending email verification link", "email", email)
+	return nil
+}
+
+func (svc *EmailService) SendPasswordChangedEmail(email string) error {
+	// Let the account owner know so an unexpected change can be reported
+	slog.Info("Sending password changed notification", "email", email)
 	return nil
 }
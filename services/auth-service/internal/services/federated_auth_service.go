@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"github.com/Mir00r/auth-service/apiclients"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/Mir00r/auth-service/metrics"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// FederatedAuthService defines the methods for login through external OIDC identity providers
+type FederatedAuthService interface {
+	StartLogin(ctx context.Context, provider string) (*dtos.FederatedLoginStartResponse, error)
+	StartLink(ctx context.Context, userID, provider string) (*dtos.FederatedLoginStartResponse, error)
+	CompleteLogin(ctx context.Context, provider, code, state string) (*dtos.LoginResponse, error)
+}
+
+// federatedAuthService is the concrete implementation of FederatedAuthService
+type federatedAuthService struct {
+	Providers    map[string]*apiclients.OIDCProvider // Configured upstream providers by name
+	StateRepo    repositories.OIDCStateRepository    // Repository for in-flight login state
+	IdentityRepo repositories.IdentityRepository     // Repository for linked identities
+	UserRepo     repositories.UserRepository         // Repository for user data
+	TokenRepo    repositories.TokenRepository        // Repository for token data
+	OutboxRepo   repositories.OutboxRepository       // Stores domain events for publishing
+	UserClient   *userservice.Client
+}
+
+// NewFederatedAuthService initializes a new instance of FederatedAuthService
+func NewFederatedAuthService(
+	providers map[string]*apiclients.OIDCProvider,
+	stateRepo repositories.OIDCStateRepository,
+	identityRepo repositories.IdentityRepository,
+	userRepo repositories.UserRepository,
+	tokenRepo repositories.TokenRepository,
+	outboxRepo repositories.OutboxRepository,
+	userClient *userservice.Client,
+) FederatedAuthService {
+	return &federatedAuthService{
+		Providers:    providers,
+		StateRepo:    stateRepo,
+		IdentityRepo: identityRepo,
+		UserRepo:     userRepo,
+		TokenRepo:    tokenRepo,
+		OutboxRepo:   outboxRepo,
+		UserClient:   userClient,
+	}
+}
+
+// StartLogin begins the authorization code flow against the named provider
+//
+// This function performs the following steps:
+// 1. Generates state, nonce and a PKCE code verifier and persists them.
+// 2. Builds the provider's authorization URL the browser has to be redirected to.
+func (svc *federatedAuthService) StartLogin(ctx context.Context, provider string) (*dtos.FederatedLoginStartResponse, error) {
+	return svc.startFlow(ctx, provider, nil)
+}
+
+// StartLink begins the authorization code flow that links an external identity to a signed-in user.
+// The caller is expected to have re-authenticated the user.
+func (svc *federatedAuthService) StartLink(ctx context.Context, userID, provider string) (*dtos.FederatedLoginStartResponse, error) {
+	return svc.startFlow(ctx, provider, &userID)
+}
+
+// startFlow persists the secrets of a new authorization attempt and builds the provider URL
+func (svc *federatedAuthService) startFlow(ctx context.Context, provider string, linkUserID *string) (*dtos.FederatedLoginStartResponse, error) {
+	stateRepo := svc.StateRepo.WithContext(ctx)
+
+	oidcProvider, ok := svc.Providers[provider]
+	if !ok {
+		return nil, errors.ErrUnknownIdentityProvider
+	}
+
+	loginState := &entities.OIDCLoginState{
+		Provider:   provider,
+		LinkUserID: linkUserID,
+		ExpiresAt:  time.Now().Add(utils.OIDCStateExpiry()),
+	}
+	for _, secret := range []*string{&loginState.State, &loginState.Nonce, &loginState.CodeVerifier} {
+		value, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToStartFederatedLogin, err)
+		}
+		*secret = value
+	}
+
+	authURL, err := oidcProvider.AuthCodeURL(ctx, loginState.State, loginState.Nonce, loginState.CodeVerifier)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusBadGateway, constants.ErrFailedToStartFederatedLogin, err)
+	}
+
+	if err := stateRepo.SaveState(loginState); err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToStartFederatedLogin, err)
+	}
+
+	return &dtos.FederatedLoginStartResponse{AuthorizationURL: authURL, State: loginState.State}, nil
+}
+
+// CompleteLogin finishes the authorization code flow and issues the standard login response
+//
+// This function performs the following steps:
+// 1. Consumes the login state and exchanges the code for tokens at the provider.
+// 2. Validates the ID token against the provider's keys and the stored nonce.
+// 3. For a link flow, attaches the identity to the user that started it.
+// 4. Otherwise signs in the account holding the identity, or resolves the account in user-service
+// and links the identity on first login.
+// 5. Issues tokens for the resulting account.
+func (svc *federatedAuthService) CompleteLogin(ctx context.Context, provider, code, state string) (login *dtos.LoginResponse, err error) {
+	defer func() { metrics.Logins.WithLabelValues(constants.LoginMethodFederated, metricOutcome(err)).Inc() }()
+
+	stateRepo := svc.StateRepo.WithContext(ctx)
+	identityRepo := svc.IdentityRepo.WithContext(ctx)
+	userRepo := svc.UserRepo.WithContext(ctx)
+
+	oidcProvider, ok := svc.Providers[provider]
+	if !ok {
+		return nil, errors.ErrUnknownIdentityProvider
+	}
+
+	loginState, err := stateRepo.ConsumeState(state)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToStartFederatedLogin, err)
+	}
+	if loginState == nil || loginState.Provider != provider || time.Now().After(loginState.ExpiresAt) {
+		return nil, errors.ErrInvalidOIDCState
+	}
+
+	tokens, err := oidcProvider.Exchange(ctx, code, loginState.CodeVerifier)
+	if err != nil {
+		slog.WarnContext(ctx, "OIDC code exchange failed", "provider", provider, "error", err)
+		return nil, errors.NewAppError(errors.ErrFederatedLoginFailed.Code, errors.ErrFederatedLoginFailed.Message, err)
+	}
+
+	claims, err := oidcProvider.VerifyIDToken(ctx, tokens.IDToken, loginState.Nonce)
+	if err != nil {
+		slog.WarnContext(ctx, "OIDC ID token rejected", "provider", provider, "error", err)
+		return nil, errors.NewAppError(errors.ErrFederatedLoginFailed.Code, errors.ErrFederatedLoginFailed.Message, err)
+	}
+	if claims.Email == "" {
+		return nil, errors.NewAppError(errors.ErrFederatedLoginFailed.Code, "Identity provider did not share an email address", nil)
+	}
+
+	identity, err := identityRepo.FindByProviderSubject(provider, claims.Subject)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+
+	var user *entities.User
+	switch {
+	case loginState.LinkUserID != nil:
+		user, err = svc.linkIdentity(ctx, *loginState.LinkUserID, identity, provider, claims)
+	case identity != nil:
+		user, err = userRepo.FindUserByID(identity.UserID)
+		if err == nil && user == nil {
+			err = errors.ErrUserNotFound
+		}
+	default:
+		user, err = svc.provisionUser(ctx, provider, claims)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if identity != nil {
+		if err := identityRepo.TouchLastUsed(identity.ID); err != nil {
+			slog.ErrorContext(ctx, "Failed to record use of identity", "identity_id", identity.ID, "error", err)
+		}
+	}
+
+	// Only active accounts may sign in
+	if err := ensureAccountActive(ctx, svc.UserClient, user.Email); err != nil {
+		return nil, err
+	}
+
+	return issueLoginTokens(ctx, svc.TokenRepo, svc.OutboxRepo, svc.UserClient, user, constants.LoginMethodFederated)
+}
+
+// linkIdentity attaches an external identity to the signed-in user that started the link flow.
+// An identity held by another account, or whose email belongs to another account, is rejected.
+func (svc *federatedAuthService) linkIdentity(ctx context.Context, userID string, identity *entities.UserIdentity, provider string, claims *apiclients.OIDCClaims) (*entities.User, error) {
+	userRepo := svc.UserRepo.WithContext(ctx)
+	identityRepo := svc.IdentityRepo.WithContext(ctx)
+
+	user, err := userRepo.FindUserByID(userID)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+	if user == nil {
+		return nil, errors.ErrUserNotFound
+	}
+
+	if identity != nil {
+		if identity.UserID != user.ID {
+			return nil, errors.ErrIdentityAlreadyLinked
+		}
+		return user, nil
+	}
+
+	owner, err := userRepo.FindUserByEmail(claims.Email)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+	if owner != nil && owner.ID != user.ID {
+		return nil, errors.ErrIdentityEmailConflict
+	}
+
+	if err := identityRepo.CreateIdentity(newExternalIdentity(user.ID, provider, claims)); err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToLinkIdentity, err)
+	}
+	return user, nil
+}
+
+// provisionUser signs in an identity seen for the first time
+//
+// An account already registered with the same email is only linked automatically when the
+// provider has verified the email; otherwise the owner has to sign in and link the provider
+// from their account settings. Unknown emails get a new account in user-service.
+func (svc *federatedAuthService) provisionUser(ctx context.Context, provider string, claims *apiclients.OIDCClaims) (*entities.User, error) {
+	userRepo := svc.UserRepo.WithContext(ctx)
+	identityRepo := svc.IdentityRepo.WithContext(ctx)
+
+	existing, err := userRepo.FindUserByEmail(claims.Email)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+	if existing != nil && !claims.EmailVerified {
+		return nil, errors.ErrIdentityEmailConflict
+	}
+
+	// Create or link the account in user-service
+	profile, err := svc.UserClient.ResolveExternalUser(ctx, userservice.ExternalUserRequest{
+		Provider:      provider,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	})
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusBadGateway, constants.ErrFailedToProvisionUser, err)
+	}
+
+	user, err := ensureLocalUser(svc.UserRepo, *profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := identityRepo.CreateIdentity(newExternalIdentity(user.ID, provider, claims)); err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToLinkIdentity, err)
+	}
+	return user, nil
+}
+
+// newExternalIdentity builds the identity record of a verified provider subject
+func newExternalIdentity(userID, provider string, claims *apiclients.OIDCClaims) *entities.UserIdentity {
+	email := claims.Email
+	now := time.Now()
+	return &entities.UserIdentity{
+		UserID:     userID,
+		Provider:   provider,
+		Subject:    claims.Subject,
+		Email:      &email,
+		LastUsedAt: &now,
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+// IdentityService defines the methods for managing the sign-in methods linked to an account
+type IdentityService interface {
+	ListIdentities(ctx context.Context, userID string) ([]dtos.IdentityResponse, error)
+	StartLink(ctx context.Context, userID, provider string, issuedAt time.Time, req dtos.ReauthenticationRequest) (*dtos.FederatedLoginStartResponse, error)
+	Unlink(ctx context.Context, userID, identityID string, issuedAt time.Time, req dtos.ReauthenticationRequest) error
+	Reauthenticate(ctx context.Context, userID string, issuedAt time.Time, req dtos.ReauthenticationRequest) (*entities.User, error)
+}
+
+// identityService is the concrete implementation of IdentityService
+type identityService struct {
+	IdentityRepo         repositories.IdentityRepository // Repository for linked identities
+	UserRepo             repositories.UserRepository     // Repository for user data
+	TokenRepo            repositories.TokenRepository    // Repository for token data
+	MFAService           MFAService                      // Verifies MFA codes for sensitive operations
+	FederatedAuthService FederatedAuthService            // Starts the provider flow when linking
+	UserClient           *userservice.Client             // Reaches the credentials held by user-service
+}
+
+// NewIdentityService initializes a new instance of IdentityService
+func NewIdentityService(
+	identityRepo repositories.IdentityRepository,
+	userRepo repositories.UserRepository,
+	tokenRepo repositories.TokenRepository,
+	mfaService MFAService,
+	federatedAuthService FederatedAuthService,
+	userClient *userservice.Client,
+) IdentityService {
+	return &identityService{
+		IdentityRepo:         identityRepo,
+		UserRepo:             userRepo,
+		TokenRepo:            tokenRepo,
+		MFAService:           mfaService,
+		FederatedAuthService: federatedAuthService,
+		UserClient:           userClient,
+	}
+}
+
+// ListIdentities returns every sign-in method linked to the user
+func (svc *identityService) ListIdentities(ctx context.Context, userID string) ([]dtos.IdentityResponse, error) {
+	identityRepo := svc.IdentityRepo.WithContext(ctx)
+
+	identities, err := identityRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToFetchIdentities, err)
+	}
+
+	response := make([]dtos.IdentityResponse, 0, len(identities))
+	for _, identity := range identities {
+		response = append(response, dtos.IdentityResponse{
+			ID:         identity.ID,
+			Provider:   identity.Provider,
+			Email:      identity.Email,
+			LastUsedAt: identity.LastUsedAt,
+			CreatedAt:  identity.CreatedAt,
+		})
+	}
+	return response, nil
+}
+
+// StartLink re-authenticates the user and starts the provider flow that links a new external identity.
+// The identity is attached when the provider redirects back to the federated login callback.
+func (svc *identityService) StartLink(ctx context.Context, userID, provider string, issuedAt time.Time, req dtos.ReauthenticationRequest) (*dtos.FederatedLoginStartResponse, error) {
+	if _, _, err := svc.reauthenticate(ctx, userID, issuedAt, req); err != nil {
+		return nil, err
+	}
+	return svc.FederatedAuthService.StartLink(ctx, userID, provider)
+}
+
+// Unlink removes a sign-in method from the account
+//
+// This function performs the following steps:
+// 1. Re-authenticates the user.
+// 2. Refuses to remove the last identity left on the account.
+// 3. When the password identity is removed, first replaces the password in user-service so password login stays
+// disabled until a new password is set.
+// 4. Deletes the identity and, for the password identity, drops pending reset links in one transaction.
+func (svc *identityService) Unlink(ctx context.Context, userID, identityID string, issuedAt time.Time, req dtos.ReauthenticationRequest) error {
+	user, identities, err := svc.reauthenticate(ctx, userID, issuedAt, req)
+	if err != nil {
+		return err
+	}
+
+	var target *entities.UserIdentity
+	for i := range identities {
+		if identities[i].ID == identityID {
+			target = &identities[i]
+		}
+	}
+	if target == nil {
+		return errors.ErrIdentityNotFound
+	}
+	if len(identities) < 2 {
+		return errors.ErrCannotUnlinkLastIdentity
+	}
+
+	// The password is scrambled before the identity goes, so a failure of user-service leaves the password
+	// identity linked and usable rather than unlinked with the old password still working
+	unlinkPassword := target.Provider == constants.IdentityProviderPassword
+	if unlinkPassword {
+		unusablePassword, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToUnlinkIdentity, err)
+		}
+		if err := setPassword(ctx, svc.UserClient, user.Email, unusablePassword); err != nil {
+			return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToUnlinkIdentity, err)
+		}
+	}
+
+	// The identity and the reset links that could bring the password back go together
+	return svc.IdentityRepo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txIdentityRepo := svc.IdentityRepo.WithTx(tx)
+		txTokenRepo := svc.TokenRepo.WithTx(tx)
+
+		deleted, err := txIdentityRepo.DeleteIdentityIfNotLast(userID, identityID)
+		if err != nil {
+			return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToUnlinkIdentity, err)
+		}
+		if !deleted {
+			// Another identity was unlinked concurrently. A scrambled password is recovered through a reset link.
+			return errors.ErrCannotUnlinkLastIdentity
+		}
+		if unlinkPassword {
+			if err := txTokenRepo.InvalidateResetTokens(userID); err != nil {
+				return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToUnlinkIdentity, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Reauthenticate checks that the caller recently proved ownership of the account before a sensitive operation
+func (svc *identityService) Reauthenticate(ctx context.Context, userID string, issuedAt time.Time, req dtos.ReauthenticationRequest) (*entities.User, error) {
+	user, _, err := svc.reauthenticate(ctx, userID, issuedAt, req)
+	return user, err
+}
+
+// reauthenticate checks that the caller recently proved ownership of the account.
+// Accounts with a password must supply it; accounts that only sign in through providers
+// must present an access token issued within the re-authentication window. An OTP is
+// required on top when MFA is enabled.
+func (svc *identityService) reauthenticate(ctx context.Context, userID string, issuedAt time.Time, req dtos.ReauthenticationRequest) (*entities.User, []entities.UserIdentity, error) {
+	userRepo := svc.UserRepo.WithContext(ctx)
+	identityRepo := svc.IdentityRepo.WithContext(ctx)
+
+	user, err := userRepo.FindUserByID(userID)
+	if err != nil {
+		return nil, nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+	if user == nil {
+		return nil, nil, errors.ErrUserNotFound
+	}
+
+	identities, err := identityRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToFetchIdentities, err)
+	}
+
+	var profile *userservice.UserResponse
+	hasPassword := false
+	for _, identity := range identities {
+		if identity.Provider == constants.IdentityProviderPassword {
+			hasPassword = true
+		}
+	}
+
+	if hasPassword {
+		if req.Password == "" {
+			return nil, nil, errors.ErrReauthenticationRequired
+		}
+		if profile, err = verifyCurrentPassword(ctx, svc.UserClient, user.Email, req.Password); err != nil {
+			return nil, nil, err
+		}
+	} else if time.Since(issuedAt) > utils.ReauthMaxAge() {
+		return nil, nil, errors.ErrReauthenticationRequired
+	}
+
+	if requiresMFA(user, profile) {
+		if req.OTP == "" {
+			return nil, nil, errors.ErrMFACodeRequired
+		}
+		if err := svc.MFAService.VerifyMFA(ctx, user.ID, req.OTP); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return user, identities, nil
+}
@@ -1,30 +1,37 @@
 package services
 
 import (
+	"context"
+	"github.com/Mir00r/auth-service/apiclients"
 	"github.com/Mir00r/auth-service/constants"
 	"github.com/Mir00r/auth-service/errors"
 	"github.com/Mir00r/auth-service/internal/models/dtos"
 	"github.com/Mir00r/auth-service/internal/repositories"
 	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/Mir00r/shared/health"
 	"net/http"
 	"time"
 )
 
 type InternalAuthService interface {
 	ValidateToken(token string) (*dtos.ValidateTokenResponse, error)
-	CheckHealth() map[string]string
+	CheckHealth(ctx context.Context) *dtos.ServiceHealthResponse
 }
 
 // InternalAuthService handles internal authentication-related operations.
 type internalAuthService struct {
-	UserRepo repositories.UserRepository // Repository for interacting with the User data
+	UserRepo          repositories.UserRepository // Repository for interacting with the User data
+	InternalWebClient apiclients.WebClient        // Reports the circuit breakers of the called services
+	HealthRegistry    *health.Registry            // Checks the dependencies of the service
 }
 
 // NewInternalAuthService creates a new instance of InternalAuthService with the required dependencies.
 // This uses Dependency Injection to ensure testability and modularity.
-func NewInternalAuthService(userRepo repositories.UserRepository) InternalAuthService {
+func NewInternalAuthService(userRepo repositories.UserRepository, internalWebClient apiclients.WebClient, healthRegistry *health.Registry) InternalAuthService {
 	return &internalAuthService{
-		UserRepo: userRepo,
+		UserRepo:          userRepo,
+		InternalWebClient: internalWebClient,
+		HealthRegistry:    healthRegistry,
 	}
 }
 
@@ -51,12 +58,33 @@ func (svc *internalAuthService) ValidateToken(token string) (*dtos.ValidateToken
 }
 
 // CheckHealth provides the health status of the authentication service.
+// The service is down while a critical check fails, and degraded while another check fails or the circuit
+// breaker of a called service is not closed. Check results are cached briefly by the registry.
 // Returns:
-// - A map containing the service health status, uptime, and version.
-func (svc *internalAuthService) CheckHealth() map[string]string {
-	return map[string]string{
-		"status":  "healthy",                       // Indicates the service is operational
-		"uptime":  time.Now().Format(time.RFC3339), // Current server time in RFC3339 format
-		"version": "1.0.0",                         // Service version
+// - A ServiceHealthResponse containing the service health status, uptime, version, checks and called services.
+func (svc *internalAuthService) CheckHealth(ctx context.Context) *dtos.ServiceHealthResponse {
+	report := svc.HealthRegistry.Check(ctx)
+	response := &dtos.ServiceHealthResponse{
+		Status:    report.Status,
+		StartedAt: svc.HealthRegistry.StartedAt().Format(time.RFC3339),
+		Uptime:    svc.HealthRegistry.Uptime().Round(time.Second).String(),
+		Version:   "1.0.0", // Service version
+		CheckedAt: report.CheckedAt.Format(time.RFC3339),
+		Checks:    report.Checks,
+		Upstreams: []dtos.UpstreamHealth{},
 	}
+
+	for _, stats := range svc.InternalWebClient.Stats() {
+		if stats.BreakerState != apiclients.BreakerClosed && response.Status == health.StatusUp {
+			response.Status = health.StatusDegraded
+		}
+		response.Upstreams = append(response.Upstreams, dtos.UpstreamHealth{
+			Upstream:            stats.Upstream,
+			Target:              stats.Target,
+			BreakerState:        stats.BreakerState,
+			ConsecutiveFailures: stats.ConsecutiveFailures,
+			InFlight:            stats.InFlight,
+		})
+	}
+	return response
 }
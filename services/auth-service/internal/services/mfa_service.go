@@ -1,20 +1,24 @@
 package services
 
 import (
+	"context"
 	"github.com/Mir00r/auth-service/constants"
 	"github.com/Mir00r/auth-service/errors"
 	"github.com/Mir00r/auth-service/internal/models/dtos"
 	"github.com/Mir00r/auth-service/internal/models/entities"
 	"github.com/Mir00r/auth-service/internal/repositories"
 	"github.com/Mir00r/auth-service/internal/utils"
-	"log"
+	"github.com/Mir00r/auth-service/metrics"
+	"log/slog"
 	"net/http"
 	"time"
 )
 
 type MFAService interface {
-	EnableMFA(userID string) (*dtos.EnableMFAResponse, error)
-	VerifyMFA(userID, otp string) error
+	EnableMFA(ctx context.Context, userID string) (*dtos.EnableMFAResponse, error)
+	ConfirmMFA(ctx context.Context, userID, otp string) error
+	RequestStepUp(ctx context.Context, userID string) error
+	VerifyMFA(ctx context.Context, userID, otp string) error
 }
 
 // MFAService handles multi-factor authentication logic
@@ -31,13 +35,54 @@ func NewMFAService(mfaRepo repositories.MFARepository, userRepo repositories.Use
 	}
 }
 
-// EnableMFA generates and stores an OTP for enabling MFA and sends it to the user's email
-func (svc *mfaService) EnableMFA(userID string) (*dtos.EnableMFAResponse, error) {
+// EnableMFA starts MFA enrollment by sending an enrollment code to the user's email.
+// The code is never returned to the caller, so only the owner of the mailbox can confirm it.
+func (svc *mfaService) EnableMFA(ctx context.Context, userID string) (*dtos.EnableMFAResponse, error) {
+	if err := svc.issueChallenge(ctx, userID, constants.MFAPurposeEnrollment); err != nil {
+		return nil, err
+	}
+
+	// Return the EnableMFAResponse object
+	return &dtos.EnableMFAResponse{
+		Message: constants.MFAChallengeSent,
+	}, nil
+}
+
+// ConfirmMFA checks an enrollment code and turns MFA on for the account
+func (svc *mfaService) ConfirmMFA(ctx context.Context, userID, otp string) error {
+	if err := svc.verifyChallenge(ctx, userID, constants.MFAPurposeEnrollment, otp); err != nil {
+		return err
+	}
+
+	// A successful enrollment turns MFA on for the account
+	userRepo := svc.UserRepo.WithContext(ctx)
+	if err := userRepo.EnableMFA(userID); err != nil {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToEnableMFA, err)
+	}
+
+	return nil
+}
+
+// RequestStepUp sends a step-up code to the user's email for a sensitive change such as a password change
+func (svc *mfaService) RequestStepUp(ctx context.Context, userID string) error {
+	return svc.issueChallenge(ctx, userID, constants.MFAPurposeStepUp)
+}
+
+// VerifyMFA checks a step-up code and marks it as used. Codes issued for enrollment are rejected.
+func (svc *mfaService) VerifyMFA(ctx context.Context, userID, otp string) error {
+	return svc.verifyChallenge(ctx, userID, constants.MFAPurposeStepUp, otp)
+}
+
+// issueChallenge generates and stores a code for purpose and sends it to the user's email
+func (svc *mfaService) issueChallenge(ctx context.Context, userID, purpose string) error {
+	userRepo := svc.UserRepo.WithContext(ctx)
+	mfaRepo := svc.MFARepo.WithContext(ctx)
+
 	// Validate user existence
-	user, err := svc.UserRepo.FindUserByID(userID)
+	user, err := userRepo.FindUserByID(userID)
 	if err != nil {
-		log.Printf("Failed to fetch user: %v", err)
-		return nil, errors.NewAppError(http.StatusNotFound, constants.ErrUserNotFound, err)
+		slog.WarnContext(ctx, "Failed to fetch user", "error", err)
+		return errors.NewAppError(http.StatusNotFound, constants.ErrUserNotFound, err)
 	}
 
 	// Generate OTP
@@ -46,33 +91,34 @@ func (svc *mfaService) EnableMFA(userID string) (*dtos.EnableMFAResponse, error)
 	// Prepare MFA entity
 	mfa := &entities.Mfa{
 		OTP:       otp,
+		Purpose:   purpose,
 		UserID:    userID,
 		ExpiresAt: time.Now().Add(5 * time.Minute),
 	}
 
 	// Save OTP in the database
-	if err := svc.MFARepo.CreateMFA(mfa); err != nil {
-		log.Printf("Failed to save MFA record: %v", err)
-		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToEnableMFA, err)
+	if err := mfaRepo.CreateMFA(mfa); err != nil {
+		slog.ErrorContext(ctx, "Failed to save MFA record", "purpose", purpose, "error", err)
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToEnableMFA, err)
 	}
 
 	// Send OTP to the user's email
 	if err := utils.SendOTPEmail(user.Email, otp); err != nil {
-		log.Printf("Failed to send OTP email: %v", err)
-		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToSendOTPEmail, err)
+		slog.ErrorContext(ctx, "Failed to send OTP email", "purpose", purpose, "error", err)
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToSendOTPEmail, err)
 	}
 
-	// Return the EnableMFAResponse object
-	return &dtos.EnableMFAResponse{
-		Message: "MFA enabled successfully",
-		OTP:     otp,
-	}, nil
+	return nil
 }
 
-// VerifyMFA checks if the provided OTP matches the stored OTP for the user and marks it as used
-func (svc *mfaService) VerifyMFA(userID, otp string) error {
-	// Retrieve the latest unused MFA record for the user
-	mfa, err := svc.MFARepo.GetUnusedMFAByUserId(userID)
+// verifyChallenge checks that otp matches the latest unused code issued to the user for purpose and marks it as used
+func (svc *mfaService) verifyChallenge(ctx context.Context, userID, purpose, otp string) (err error) {
+	defer func() { metrics.MFAVerifications.WithLabelValues(metricOutcome(err)).Inc() }()
+
+	mfaRepo := svc.MFARepo.WithContext(ctx)
+
+	// Retrieve the latest unused MFA record for the user and purpose
+	mfa, err := mfaRepo.GetUnusedMFAByUserId(userID, purpose)
 	if err != nil {
 		return errors.NewAppError(http.StatusNotFound, constants.ErrFailedToVerifyMFA, err)
 	}
@@ -91,7 +137,7 @@ func (svc *mfaService) VerifyMFA(userID, otp string) error {
 	}
 
 	// Mark the OTP as used
-	if err := svc.MFARepo.UpdateUsed(mfa.ID, true); err != nil {
+	if err := mfaRepo.UpdateUsed(mfa.ID, true); err != nil {
 		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToMarkMFA, err)
 	}
 
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/Mir00r/auth-service/metrics"
+	"github.com/google/uuid"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Limits applied when none is configured
+const (
+	defaultMaxCodeAttempts = 5                // Bounds guessing of email codes
+	defaultMaxChallenges   = 5                // Bounds the emails sent to one account per window
+	defaultChallengeWindow = 15 * time.Minute // Window over which issued challenges are counted
+)
+
+// PasswordlessService defines the methods for passwordless login
+type PasswordlessService interface {
+	RequestLogin(ctx context.Context, req dtos.PasswordlessLoginRequest) (*dtos.PasswordlessLoginResponse, error)
+	VerifyLogin(ctx context.Context, req dtos.PasswordlessVerifyRequest) (*dtos.PasswordlessVerifyResult, error)
+	ConfirmLogin(ctx context.Context, req dtos.PasswordlessConfirmRequest) error
+}
+
+// passwordlessService is the concrete implementation of PasswordlessService
+type passwordlessService struct {
+	PasswordlessRepo repositories.PasswordlessRepository // Repository for passwordless challenges
+	UserRepo         repositories.UserRepository         // Repository for user data
+	TokenRepo        repositories.TokenRepository        // Repository for token data
+	OutboxRepo       repositories.OutboxRepository       // Stores domain events for publishing
+	UserClient       *userservice.Client                 // Checks the account state in user-service
+}
+
+// NewPasswordlessService initializes a new instance of PasswordlessService
+func NewPasswordlessService(
+	passwordlessRepo repositories.PasswordlessRepository,
+	userRepo repositories.UserRepository,
+	tokenRepo repositories.TokenRepository,
+	outboxRepo repositories.OutboxRepository,
+	userClient *userservice.Client,
+) PasswordlessService {
+	return &passwordlessService{
+		PasswordlessRepo: passwordlessRepo,
+		UserRepo:         userRepo,
+		TokenRepo:        tokenRepo,
+		OutboxRepo:       outboxRepo,
+		UserClient:       userClient,
+	}
+}
+
+// RequestLogin issues a magic link or a 6-digit email code bound to the requesting device.
+// Unknown email addresses receive an indistinguishable response and no email is sent.
+func (svc *passwordlessService) RequestLogin(ctx context.Context, req dtos.PasswordlessLoginRequest) (*dtos.PasswordlessLoginResponse, error) {
+	userRepo := svc.UserRepo.WithContext(ctx)
+	passwordlessRepo := svc.PasswordlessRepo.WithContext(ctx)
+
+	expiry := utils.PasswordlessExpiry(req.Method)
+	response := &dtos.PasswordlessLoginResponse{
+		ChallengeID: uuid.NewString(),
+		Method:      req.Method,
+		ExpiresIn:   int64(expiry.Seconds()),
+	}
+
+	user, err := userRepo.FindUserByEmail(req.Email)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+	if user == nil {
+		slog.InfoContext(ctx, "Passwordless login requested for unknown email")
+		return response, nil
+	}
+
+	// Cap the links and codes emailed to one account. The response stays the same, so the cap reveals
+	// nothing about the account and only stops the mailbox from being flooded.
+	maxChallenges, window := config.AppConfig.Passwordless.MaxChallenges, config.AppConfig.Passwordless.ChallengeWindow.Duration()
+	if maxChallenges <= 0 {
+		maxChallenges = defaultMaxChallenges
+	}
+	if window <= 0 {
+		window = defaultChallengeWindow
+	}
+	issued, err := passwordlessRepo.CountChallengesSince(user.ID, time.Now().Add(-window))
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToStartPasswordless, err)
+	}
+	if issued >= int64(maxChallenges) {
+		slog.WarnContext(ctx, "Passwordless login requested too often, no email sent", "user_id", user.ID)
+		return response, nil
+	}
+
+	// A new request supersedes any challenge that is still open
+	if err := passwordlessRepo.RevokeOpenChallenges(user.ID); err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToStartPasswordless, err)
+	}
+
+	challenge := &entities.PasswordlessChallenge{
+		ID:         response.ChallengeID,
+		UserID:     user.ID,
+		Method:     req.Method,
+		DeviceHash: utils.HashToken(req.DeviceID),
+		Status:     constants.PasswordlessPending,
+		ExpiresAt:  time.Now().Add(expiry),
+	}
+
+	// Generate the secret delivered by email; only its hash is stored
+	var secret string
+	if req.Method == constants.PasswordlessMethodCode {
+		secret = utils.GenerateOTP()
+	} else {
+		secret, err = utils.GenerateOpaqueToken()
+		if err != nil {
+			return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToStartPasswordless, err)
+		}
+	}
+	secretHash := utils.HashToken(secret)
+	if req.Method == constants.PasswordlessMethodCode {
+		challenge.CodeHash = &secretHash
+	} else {
+		challenge.TokenHash = &secretHash
+	}
+
+	if err := passwordlessRepo.CreateChallenge(challenge); err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToStartPasswordless, err)
+	}
+
+	// Deliver the link or code
+	if req.Method == constants.PasswordlessMethodCode {
+		err = NewEmailService().SendLoginCodeEmail(user.Email, secret)
+	} else {
+		loginLink := fmt.Sprintf("%s/magic-login?token=%s", config.AppConfig.Passwordless.LoginURL, secret)
+		err = NewEmailService().SendMagicLinkEmail(user.Email, loginLink)
+	}
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToSendOTPEmail, err)
+	}
+
+	return response, nil
+}
+
+// VerifyLogin redeems a magic link or email code exactly once.
+// When the secret is presented from a device other than the one that requested it, the challenge
+// is parked until the requesting device confirms, and a confirmation-required result is returned.
+func (svc *passwordlessService) VerifyLogin(ctx context.Context, req dtos.PasswordlessVerifyRequest) (result *dtos.PasswordlessVerifyResult, err error) {
+	defer func() {
+		// A login parked for confirmation is counted once the requesting device completes it
+		if result == nil || !result.ConfirmationRequired {
+			metrics.Logins.WithLabelValues(constants.LoginMethodPasswordless, metricOutcome(err)).Inc()
+		}
+	}()
+
+	passwordlessRepo := svc.PasswordlessRepo.WithContext(ctx)
+	userRepo := svc.UserRepo.WithContext(ctx)
+
+	challenge, err := svc.findChallenge(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Enforce device binding
+	deviceHash := utils.HashToken(req.DeviceID)
+	if !hashEquals(deviceHash, challenge.DeviceHash) {
+		approved := challenge.Status == constants.PasswordlessConfirmed &&
+			challenge.PendingDeviceHash != nil && hashEquals(deviceHash, *challenge.PendingDeviceHash)
+		if !approved {
+			// Only the first other device may ask; it cannot be replaced once shown to the requesting device
+			requested, err := passwordlessRepo.RequestConfirmation(challenge.ID, deviceHash)
+			if err != nil {
+				return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToVerifyPasswordless, err)
+			}
+			if !requested {
+				return nil, errors.ErrLoginConfirmationPending
+			}
+			return &dtos.PasswordlessVerifyResult{ConfirmationRequired: true, ChallengeID: challenge.ID, PendingDevice: deviceHash}, nil
+		}
+	}
+
+	// Mark the challenge as redeemed before issuing tokens so it can only be used once.
+	// The requesting device may finish the login in any open state.
+	redeemed, err := passwordlessRepo.Redeem(challenge.ID,
+		constants.PasswordlessPending, constants.PasswordlessAwaitingConfirmation, constants.PasswordlessConfirmed)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToVerifyPasswordless, err)
+	}
+	if !redeemed {
+		return nil, errors.ErrInvalidOrExpiredLoginLink
+	}
+
+	user, err := userRepo.FindUserByID(challenge.UserID)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+	if user == nil {
+		return nil, errors.ErrInvalidOrExpiredLoginLink
+	}
+
+	// Only active accounts may sign in
+	if err := ensureAccountActive(ctx, svc.UserClient, user.Email); err != nil {
+		return nil, err
+	}
+
+	login, err := issueLoginTokens(ctx, svc.TokenRepo, svc.OutboxRepo, svc.UserClient, user, constants.LoginMethodPasswordless)
+	if err != nil {
+		return nil, err
+	}
+	return &dtos.PasswordlessVerifyResult{Login: login}, nil
+}
+
+// ConfirmLogin approves, from the requesting device, a login link opened on another device.
+// The request names the pending device from the confirmation prompt, so only that device is approved.
+func (svc *passwordlessService) ConfirmLogin(ctx context.Context, req dtos.PasswordlessConfirmRequest) error {
+	passwordlessRepo := svc.PasswordlessRepo.WithContext(ctx)
+
+	challenge, err := passwordlessRepo.FindByID(req.ChallengeID)
+	if err != nil {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToVerifyPasswordless, err)
+	}
+	if challenge == nil || time.Now().After(challenge.ExpiresAt) ||
+		!hashEquals(utils.HashToken(req.DeviceID), challenge.DeviceHash) {
+		return errors.ErrInvalidOrExpiredLoginLink
+	}
+
+	confirmed, err := passwordlessRepo.Confirm(challenge.ID, strings.ToLower(req.PendingDevice))
+	if err != nil {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToVerifyPasswordless, err)
+	}
+	if !confirmed {
+		return errors.ErrLoginNotConfirmed
+	}
+	return nil
+}
+
+// findChallenge resolves the challenge referenced by a magic link token or by challenge ID and code
+func (svc *passwordlessService) findChallenge(ctx context.Context, req dtos.PasswordlessVerifyRequest) (*entities.PasswordlessChallenge, error) {
+	passwordlessRepo := svc.PasswordlessRepo.WithContext(ctx)
+
+	var challenge *entities.PasswordlessChallenge
+	var err error
+
+	switch {
+	case req.Token != "":
+		challenge, err = passwordlessRepo.FindByTokenHash(utils.HashToken(req.Token))
+	case req.ChallengeID != "" && req.Code != "":
+		challenge, err = passwordlessRepo.FindByID(req.ChallengeID)
+	default:
+		return nil, errors.ErrInvalidPayload
+	}
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToVerifyPasswordless, err)
+	}
+	if challenge == nil || time.Now().After(challenge.ExpiresAt) ||
+		challenge.Status == constants.PasswordlessRedeemed || challenge.Status == constants.PasswordlessRevoked {
+		return nil, errors.ErrInvalidOrExpiredLoginLink
+	}
+
+	// Codes are short, so attempts are counted and the challenge is revoked after too many wrong ones
+	if req.Token == "" {
+		maxAttempts := config.AppConfig.Passwordless.MaxCodeAttempt
+		if maxAttempts <= 0 {
+			maxAttempts = defaultMaxCodeAttempts
+		}
+		reserved, err := passwordlessRepo.ReserveCodeAttempt(challenge.ID, maxAttempts)
+		if err != nil {
+			return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToVerifyPasswordless, err)
+		}
+		if !reserved {
+			return nil, errors.ErrInvalidOrExpiredLoginLink
+		}
+		if challenge.CodeHash == nil || !hashEquals(utils.HashToken(req.Code), *challenge.CodeHash) {
+			if err := passwordlessRepo.RevokeExhausted(challenge.ID, maxAttempts); err != nil {
+				slog.ErrorContext(ctx, "Failed to revoke passwordless challenge after too many attempts", "error", err)
+			}
+			return nil, errors.ErrInvalidOrExpiredLoginLink
+		}
+	}
+
+	return challenge, nil
+}
+
+// hashEquals compares two hex digests in constant time
+func hashEquals(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"github.com/Mir00r/auth-service/apiclients"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/Mir00r/auth-service/saga"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+// Keys of the registration saga data
+const (
+	registrationName         = "name"
+	registrationEmail        = "email"
+	registrationPasswordHash = "passwordHash" // Removed once the saga has ended
+	registrationProfileID    = "profileId"    // ID of the profile in user-service
+	registrationUserID       = "userId"       // ID of the local account record
+)
+
+// registrationSteps holds the dependencies of the registration saga steps
+type registrationSteps struct {
+	UserRepo         repositories.UserRepository
+	IdentityRepo     repositories.IdentityRepository
+	VerificationRepo repositories.EmailVerificationRepository
+	UserClient       *userservice.Client
+}
+
+// NewRegistrationSaga defines the saga that registers an account:
+// 1. Creates the profile and password hash in user-service; compensated by discarding the profile.
+// 2. Creates the local account record and its password identity; compensated by deleting them.
+// 3. Sends the email verification link; compensated by revoking the link.
+func NewRegistrationSaga(
+	userRepo repositories.UserRepository,
+	identityRepo repositories.IdentityRepository,
+	verificationRepo repositories.EmailVerificationRepository,
+	userClient *userservice.Client,
+) saga.Definition {
+	steps := &registrationSteps{
+		UserRepo:         userRepo,
+		IdentityRepo:     identityRepo,
+		VerificationRepo: verificationRepo,
+		UserClient:       userClient,
+	}
+	return saga.Definition{
+		Type: constants.SagaTypeRegistration,
+		Steps: []saga.Step{
+			{Name: constants.RegistrationStepCreateProfile, Execute: steps.createProfile, Compensate: steps.discardProfile},
+			{Name: constants.RegistrationStepCreateAccount, Execute: steps.createAccount, Compensate: steps.deleteAccount},
+			{Name: constants.RegistrationStepSendVerification, Execute: steps.sendVerificationEmail, Compensate: steps.revokeVerification},
+		},
+		MaxAttempts: config.AppConfig.Saga.MaxAttempts,
+		Sensitive:   []string{registrationPasswordHash},
+	}
+}
+
+// createProfile creates the profile in user-service, which treats a replay with the same password hash as success
+func (s *registrationSteps) createProfile(ctx context.Context, data saga.Data) error {
+	profile, err := s.UserClient.CreateRegistration(ctx, userservice.RegistrationRequest{
+		Name:         data[registrationName],
+		Email:        data[registrationEmail],
+		PasswordHash: data[registrationPasswordHash],
+	})
+	if err != nil {
+		if upstreamStatus(err) == http.StatusConflict {
+			return saga.Permanent(errors.ErrEmailAlreadyRegistered)
+		}
+		return upstreamStepError(err, errors.ErrFailedToRegisterUser)
+	}
+
+	data[registrationProfileID] = profile.ID
+	return nil
+}
+
+// discardProfile deletes the profile created by createProfile
+func (s *registrationSteps) discardProfile(ctx context.Context, data saga.Data) error {
+	profileID := data[registrationProfileID]
+	if profileID == "" {
+		return nil
+	}
+
+	if err := s.UserClient.DiscardRegistration(ctx, profileID); err != nil {
+		if upstreamStatus(err) == http.StatusNotFound {
+			return nil
+		}
+		return upstreamStepError(err, errors.ErrFailedToRegisterUser)
+	}
+	return nil
+}
+
+// createAccount creates the local account record and records the password as its sign-in method
+func (s *registrationSteps) createAccount(_ context.Context, data saga.Data) error {
+	profile := userservice.UserResponse{
+		ID:    data[registrationProfileID],
+		Name:  data[registrationName],
+		Email: data[registrationEmail],
+	}
+
+	return s.UserRepo.DB.Transaction(func(tx *gorm.DB) error {
+		txUserRepo := s.UserRepo.WithTx(tx)
+		txIdentityRepo := s.IdentityRepo.WithTx(tx)
+
+		user, err := ensureLocalUser(txUserRepo, profile)
+		if err != nil {
+			return err
+		}
+		if err := txIdentityRepo.EnsureIdentity(passwordIdentity(user)); err != nil {
+			return errors.NewAppError(errors.ErrFailedToRegisterUser.Code, errors.ErrFailedToRegisterUser.Message, err)
+		}
+
+		data[registrationUserID] = user.ID
+		return nil
+	})
+}
+
+// deleteAccount deletes the local account record created by createAccount. The record is looked up by email
+// when a crash lost its ID; the email belongs to this registration, as user-service accepted its profile.
+func (s *registrationSteps) deleteAccount(_ context.Context, data saga.Data) error {
+	userID := data[registrationUserID]
+	if userID == "" {
+		user, err := s.UserRepo.FindUserByEmail(data[registrationEmail])
+		if err != nil {
+			return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+		}
+		if user == nil {
+			return nil
+		}
+		userID = user.ID
+	}
+
+	if err := s.UserRepo.DeleteUser(userID); err != nil {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRegisterUser, err)
+	}
+	return nil
+}
+
+// sendVerificationEmail emails a link proving ownership of the address; a retry replaces the earlier link
+func (s *registrationSteps) sendVerificationEmail(_ context.Context, data saga.Data) error {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToSendVerificationEmail, err)
+	}
+
+	err = s.VerificationRepo.ReplaceToken(&entities.EmailVerificationToken{
+		UserID:    data[registrationUserID],
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(utils.VerificationTokenExpiry()),
+	})
+	if err != nil {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToSendVerificationEmail, err)
+	}
+
+	verificationLink := fmt.Sprintf("%s/verify-email?token=%s", config.AppConfig.Registration.VerificationURL, token)
+	if err := NewEmailService().SendVerificationEmail(data[registrationEmail], verificationLink); err != nil {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToSendVerificationEmail, err)
+	}
+	return nil
+}
+
+// revokeVerification invalidates the verification links of a rolled back registration
+func (s *registrationSteps) revokeVerification(_ context.Context, data saga.Data) error {
+	userID := data[registrationUserID]
+	if userID == "" {
+		return nil
+	}
+	if err := s.VerificationRepo.DeleteTokens(userID); err != nil {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToSendVerificationEmail, err)
+	}
+	return nil
+}
+
+// upstreamStatus returns the status code of a failed call to user-service, or 0 when it was not answered
+func upstreamStatus(err error) int {
+	var statusErr *apiclients.StatusError
+	if goerrors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+	return 0
+}
+
+// upstreamStepError classifies a failed call to user-service for the saga: a rejected request is
+// permanent and reported as rejected, while an unreachable or failing user-service is retried
+func upstreamStepError(err error, rejected *errors.AppError) error {
+	status := upstreamStatus(err)
+	if status >= 400 && status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests {
+		return saga.Permanent(errors.NewAppError(rejected.Code, rejected.Message, err))
+	}
+	return errors.NewAppError(http.StatusServiceUnavailable, constants.ErrCredentialStoreUnavailable, err)
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	goerrors "errors"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/Mir00r/auth-service/metrics"
+	"github.com/Mir00r/auth-service/saga"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// RegistrationService defines the methods for registering accounts
+type RegistrationService interface {
+	Register(ctx context.Context, req dtos.RegisterRequest) (*dtos.RegistrationResponse, error)
+	VerifyEmail(ctx context.Context, req dtos.VerifyEmailRequest) error
+}
+
+// registrationService is the concrete implementation of RegistrationService
+type registrationService struct {
+	Orchestrator     *saga.Orchestrator                       // Runs the registration saga
+	UserRepo         repositories.UserRepository              // Repository for user data
+	VerificationRepo repositories.EmailVerificationRepository // Repository for email verification tokens
+	UserClient       *userservice.Client                      // Records verified email addresses in user-service
+}
+
+// NewRegistrationService initializes a new instance of RegistrationService
+func NewRegistrationService(
+	orchestrator *saga.Orchestrator,
+	userRepo repositories.UserRepository,
+	verificationRepo repositories.EmailVerificationRepository,
+	userClient *userservice.Client,
+) RegistrationService {
+	return &registrationService{
+		Orchestrator:     orchestrator,
+		UserRepo:         userRepo,
+		VerificationRepo: verificationRepo,
+		UserClient:       userClient,
+	}
+}
+
+// Register creates a new account through the registration saga
+//
+// This function performs the following steps:
+// 1. Applies the password policy and hashes the password; the plain password is never persisted.
+// 2. Starts the registration saga, which creates the profile in user-service, the local account
+// and sends the verification email, undoing earlier steps when a later one cannot succeed.
+// 3. Reports the registration as completed, or as running when a step waits to be retried.
+//
+// Parameters:
+// - req: RegisterRequest containing user registration details (name, email, password).
+//
+// Returns:
+// - A RegistrationResponse with the registration ID and status.
+// - An error if the registration was rejected or rolled back.
+func (svc *registrationService) Register(ctx context.Context, req dtos.RegisterRequest) (registration *dtos.RegistrationResponse, err error) {
+	defer func() { metrics.Registrations.WithLabelValues(metricOutcome(err)).Inc() }()
+
+	if !utils.IsStrongPassword(req.Password) {
+		return nil, errors.ErrWeakPassword
+	}
+	passwordHash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return nil, errors.NewAppError(errors.ErrHashPassword.Code, errors.ErrHashPassword.Message, err)
+	}
+
+	// The saga outlives the request, so a client that gives up must not fail one of its steps
+	instance, err := svc.Orchestrator.Start(context.WithoutCancel(ctx), constants.SagaTypeRegistration, strings.ToLower(req.Email), saga.Data{
+		registrationName:         req.Name,
+		registrationEmail:        req.Email,
+		registrationPasswordHash: passwordHash,
+	})
+	if goerrors.Is(err, saga.ErrAlreadyRunning) {
+		return nil, errors.ErrRegistrationInProgress
+	}
+	if instance == nil {
+		return nil, errors.NewAppError(errors.ErrFailedToRegisterUser.Code, errors.ErrFailedToRegisterUser.Message, err)
+	}
+
+	switch instance.Status {
+	case saga.StatusCompleted:
+		return &dtos.RegistrationResponse{
+			RegistrationID: instance.ID,
+			Status:         instance.Status,
+			Message:        constants.MsgUserRegSuccessful,
+		}, nil
+	case saga.StatusRunning:
+		// The failed step is retried in the background
+		slog.WarnContext(ctx, "Registration waits for a retry", "saga_id", instance.ID, "step", instance.StepName, "error", err)
+		return &dtos.RegistrationResponse{
+			RegistrationID: instance.ID,
+			Status:         instance.Status,
+			Message:        constants.RegistrationInProgress,
+		}, nil
+	default:
+		var appErr *errors.AppError
+		if goerrors.As(saga.Cause(err), &appErr) {
+			return nil, appErr
+		}
+		return nil, errors.NewAppError(errors.ErrFailedToRegisterUser.Code, errors.ErrFailedToRegisterUser.Message, err)
+	}
+}
+
+// VerifyEmail redeems an email verification link and records the verified address in user-service
+func (svc *registrationService) VerifyEmail(ctx context.Context, req dtos.VerifyEmailRequest) error {
+	verificationRepo := svc.VerificationRepo.WithContext(ctx)
+	userRepo := svc.UserRepo.WithContext(ctx)
+
+	token, err := verificationRepo.FindValidToken(utils.HashToken(req.Token))
+	if err != nil {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToVerifyEmail, err)
+	}
+	if token == nil {
+		return errors.ErrInvalidOrExpiredVerification
+	}
+
+	user, err := userRepo.FindUserByID(token.UserID)
+	if err != nil {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+	}
+	if user == nil {
+		return errors.ErrInvalidOrExpiredVerification
+	}
+
+	// Recording the address is idempotent, so the token is only used up once user-service has it
+	if err := svc.UserClient.MarkEmailVerified(ctx, userservice.EmailVerifiedRequest{Email: user.Email}); err != nil {
+		slog.ErrorContext(ctx, "Recording the verified email failed", "error", err)
+		return errors.NewAppError(http.StatusServiceUnavailable, constants.ErrFailedToVerifyEmail, err)
+	}
+
+	if err := verificationRepo.MarkUsed(token.ID); err != nil {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToVerifyEmail, err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/saga"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+// SagaService defines the methods for reviewing the sagas run by the orchestrator
+type SagaService interface {
+	ListSagas(ctx context.Context, query dtos.SagaQuery) (*dtos.PaginatedSagaResponse, error)
+	GetSaga(ctx context.Context, sagaID string) (*dtos.SagaResponse, error)
+}
+
+// sagaService is the concrete implementation of SagaService
+type sagaService struct {
+	SagaRepo repositories.SagaRepository // Repository for saga state
+}
+
+// NewSagaService initializes a new instance of SagaService
+func NewSagaService(sagaRepo repositories.SagaRepository) SagaService {
+	return &sagaService{
+		SagaRepo: sagaRepo,
+	}
+}
+
+// ListSagas returns the sagas matching the query, newest first
+func (svc *sagaService) ListSagas(ctx context.Context, query dtos.SagaQuery) (*dtos.PaginatedSagaResponse, error) {
+	sagaRepo := svc.SagaRepo.WithContext(ctx)
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PerPage == 0 {
+		query.PerPage = 50
+	}
+
+	instances, total, err := sagaRepo.Find(repositories.SagaFilter{
+		SagaType: query.Type,
+		Status:   query.Status,
+		SagaKey:  query.Key,
+		Offset:   (query.Page - 1) * query.PerPage,
+		Limit:    query.PerPage,
+	})
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToFetchSagas, err)
+	}
+
+	sagas := make([]dtos.SagaResponse, 0, len(instances))
+	for i := range instances {
+		response, err := toSagaResponse(&instances[i])
+		if err != nil {
+			return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToFetchSagas, err)
+		}
+		sagas = append(sagas, *response)
+	}
+
+	return &dtos.PaginatedSagaResponse{
+		Sagas:      sagas,
+		TotalCount: total,
+		Page:       query.Page,
+		PerPage:    query.PerPage,
+	}, nil
+}
+
+// GetSaga returns a saga with the status of each of its steps
+func (svc *sagaService) GetSaga(ctx context.Context, sagaID string) (*dtos.SagaResponse, error) {
+	sagaRepo := svc.SagaRepo.WithContext(ctx)
+
+	if _, err := uuid.Parse(sagaID); err != nil {
+		return nil, errors.ErrSagaNotFound
+	}
+
+	instance, err := sagaRepo.FindByID(sagaID)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToFetchSagas, err)
+	}
+	if instance == nil {
+		return nil, errors.ErrSagaNotFound
+	}
+
+	response, err := toSagaResponse(instance)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToFetchSagas, err)
+	}
+	return response, nil
+}
+
+// toSagaResponse describes a saga together with its decoded step log
+func toSagaResponse(instance *entities.SagaInstance) (*dtos.SagaResponse, error) {
+	steps, err := saga.StepLog(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.SagaResponse{
+		ID:            instance.ID,
+		Type:          instance.SagaType,
+		Key:           instance.SagaKey,
+		Status:        instance.Status,
+		CurrentStep:   instance.StepName,
+		Attempts:      instance.Attempts,
+		NextAttemptAt: instance.NextAttemptAt,
+		LastError:     instance.LastError,
+		Steps:         steps,
+		CreatedAt:     instance.CreatedAt,
+		UpdatedAt:     instance.UpdatedAt,
+		EndedAt:       instance.EndedAt,
+	}, nil
+}
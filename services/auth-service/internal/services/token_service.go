@@ -1,59 +1,90 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
 	"github.com/Mir00r/auth-service/configs"
 	"github.com/Mir00r/auth-service/constants"
 	"github.com/Mir00r/auth-service/errors"
 	"github.com/Mir00r/auth-service/internal/models/dtos"
 	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/metrics"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/Mir00r/auth-service/internal/repositories"
 	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/Mir00r/auth-service/messaging"
+	"gorm.io/gorm"
 )
 
 // TokenServiceInterface defines the methods for the TokenService
 type TokenServiceInterface interface {
-	InitiatePasswordReset(req dtos.PasswordResetRequest) error
-	ResetPassword(req dtos.ConfirmPasswordResetRequest) error
-	Logout(tokenString string, userID string) error
-	RefreshToken(req dtos.RefreshTokenRequest) (*dtos.RefreshTokenResponse, error)
+	InitiatePasswordReset(ctx context.Context, req dtos.PasswordResetRequest) error
+	ResetPassword(ctx context.Context, req dtos.ConfirmPasswordResetRequest) error
+	Logout(ctx context.Context, tokenString string, userID string) error
+	RefreshToken(ctx context.Context, req dtos.RefreshTokenRequest) (*dtos.RefreshTokenResponse, error)
 }
 
 // TokenService is the concrete implementation of TokenServiceInterface
 type TokenService struct {
-	TokenRepo repositories.TokenRepository
-	UserRepo  repositories.UserRepository
+	TokenRepo    repositories.TokenRepository
+	UserRepo     repositories.UserRepository
+	IdentityRepo repositories.IdentityRepository
+	OutboxRepo   repositories.OutboxRepository
+	UserClient   *userservice.Client // Reaches the credentials held by user-service
 }
 
 // NewTokenService initializes a new instance of TokenService
-func NewTokenService(repo repositories.TokenRepository, userRepo repositories.UserRepository) TokenServiceInterface {
-	return &TokenService{TokenRepo: repo, UserRepo: userRepo}
+func NewTokenService(repo repositories.TokenRepository, userRepo repositories.UserRepository, identityRepo repositories.IdentityRepository, outboxRepo repositories.OutboxRepository, userClient *userservice.Client) TokenServiceInterface {
+	return &TokenService{TokenRepo: repo, UserRepo: userRepo, IdentityRepo: identityRepo, OutboxRepo: outboxRepo, UserClient: userClient}
 }
 
-func (svc *TokenService) InitiatePasswordReset(req dtos.PasswordResetRequest) error {
+// InitiatePasswordReset issues a single-use reset token and emails the reset link to the user.
+// Unknown email addresses are accepted silently so the response never reveals whether an account exists.
+func (svc *TokenService) InitiatePasswordReset(ctx context.Context, req dtos.PasswordResetRequest) (err error) {
+	defer func() {
+		metrics.PasswordResets.WithLabelValues(metrics.PasswordResetRequested, metricOutcome(err)).Inc()
+	}()
+
+	userRepo := svc.UserRepo.WithContext(ctx)
+
 	// Check if the user exists
-	user, err := svc.UserRepo.FindUserByEmail(req.Email)
+	user, err := userRepo.FindUserByEmail(req.Email)
 	if err != nil {
 		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
 	}
 	if user == nil {
-		return errors.ErrUserNotFound // Defined as a domain-specific error
+		slog.InfoContext(ctx, "Password reset requested for unknown email")
+		return nil
 	}
 
-	// Generate a reset token
-	resetToken, err := utils.GeneratePasswordResetToken(user.ID)
+	// Generate an opaque reset token
+	resetToken, err := utils.GenerateOpaqueToken()
 	if err != nil {
 		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToGenerateResetToken, err)
 	}
 
-	// Save the token in the database
-	err = svc.TokenRepo.SaveResetToken(resetToken, user.ID)
+	// Only one reset token may be active per user, so retire any outstanding ones first. Of two resets of the
+	// user racing, the one saving its token second sends nothing and answers like an unknown email.
+	saved := false
+	err = svc.TokenRepo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txTokenRepo := svc.TokenRepo.WithTx(tx)
+		if err := txTokenRepo.InvalidateResetTokens(user.ID); err != nil {
+			return err
+		}
+		saved, err = txTokenRepo.SaveResetToken(utils.HashToken(resetToken), user.ID, time.Now().Add(utils.ResetTokenExpiry()))
+		return err
+	})
 	if err != nil {
 		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToSaveResetToken, err)
 	}
+	if !saved {
+		slog.InfoContext(ctx, "Password reset requested again while another one was being issued")
+		return nil
+	}
 
 	// Send the reset email
 	resetLink := fmt.Sprintf("%s/reset-password?token=%s", config.AppConfig.Password.PasswordResetURL, resetToken)
@@ -65,44 +96,129 @@ func (svc *TokenService) InitiatePasswordReset(req dtos.PasswordResetRequest) er
 	return nil
 }
 
-func (svc *TokenService) ResetPassword(req dtos.ConfirmPasswordResetRequest) error {
-	// Verify the reset token
-	userID, err := utils.VerifyPasswordResetToken(req.Token)
-	if err != nil {
-		return errors.ErrInvalidOrExpiredResetToken
-	}
+// ResetPassword redeems a reset token, updates the password and invalidates every
+// outstanding reset token and session of the user. Accounts that only signed in through
+// external providers gain a password identity this way.
+func (svc *TokenService) ResetPassword(ctx context.Context, req dtos.ConfirmPasswordResetRequest) (err error) {
+	defer func() {
+		metrics.PasswordResets.WithLabelValues(metrics.PasswordResetCompleted, metricOutcome(err)).Inc()
+	}()
 
-	// Check if the token has already been used
-	resetToken, err := svc.TokenRepo.FindToken(req.Token)
-	if err != nil || resetToken.Used {
-		return errors.ErrResetTokenAlreadyUsed
+	// Apply the password policy
+	if !utils.IsStrongPassword(req.NewPassword) {
+		return errors.ErrWeakPassword
 	}
 
-	// Hash the new password
-	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	// The redemption commits before user-service is called, so no row lock is held across the call. A concurrent
+	// redemption of the same token finds it used.
+	resetToken, user, err := svc.redeemResetToken(ctx, utils.HashToken(req.Token))
 	if err != nil {
-		return errors.ErrHashPassword
+		return err
 	}
 
-	// Update the user's password
-	err = svc.UserRepo.UpdatePassword(userID, hashedPassword)
-	if err != nil {
-		return errors.ErrFailedToUpdatePassword
+	if err := svc.completeReset(ctx, resetToken, user, req.NewPassword); err != nil {
+		// The link can be used again to retry, which sets the password again should it have been stored already
+		tokenRepo := svc.TokenRepo.WithContext(ctx)
+		if released, releaseErr := tokenRepo.ReleaseResetToken(resetToken); releaseErr != nil || !released {
+			slog.ErrorContext(ctx, "Failed to release the reset token of a failed reset", "released", released, "error", releaseErr)
+		}
+		return err
 	}
+	return nil
+}
+
+// redeemResetToken marks the reset token with the hash as used and returns it with its user. The other reset
+// tokens and the sessions of the user are revoked along with it, as they are once the password changes.
+func (svc *TokenService) redeemResetToken(ctx context.Context, tokenHash string) (resetToken *entities.PasswordResetToken, user *entities.User, err error) {
+	err = svc.TokenRepo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txTokenRepo := svc.TokenRepo.WithTx(tx)
+		txUserRepo := svc.UserRepo.WithTx(tx)
+
+		resetToken, err = txTokenRepo.RedeemResetToken(tokenHash)
+		if err != nil {
+			return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToFindToken, err)
+		}
+		if resetToken == nil {
+			return unredeemableResetToken(txTokenRepo, tokenHash)
+		}
+
+		user, err = txUserRepo.FindUserByID(resetToken.UserID)
+		if err != nil {
+			return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
+		}
+		if user == nil {
+			return errors.ErrInvalidOrExpiredResetToken
+		}
+
+		// Invalidate any other outstanding reset token
+		if err := txTokenRepo.InvalidateResetTokens(resetToken.UserID); err != nil {
+			return errors.ErrFailedToUpdatePassword
+		}
+
+		// Revoke every session issued with the old password
+		if err := txTokenRepo.RevokeUserTokens(resetToken.UserID); err != nil {
+			return errors.ErrFailedToUpdatePassword
+		}
+		return nil
+	})
+	return resetToken, user, err
+}
 
-	// Mark the reset token as used
-	err = svc.TokenRepo.MarkTokenAsUsed(req.Token)
+// completeReset stores the new password in user-service, then links the password identity and records the change
+func (svc *TokenService) completeReset(ctx context.Context, resetToken *entities.PasswordResetToken, user *entities.User, password string) error {
+	event, err := messaging.NewOutboxEvent(constants.EventPasswordChanged, resetToken.UserID, map[string]interface{}{
+		"userId":          resetToken.UserID,
+		"reason":          "reset",
+		"sessionsRevoked": true,
+	})
 	if err != nil {
 		return errors.ErrFailedToUpdatePassword
 	}
 
-	return nil
+	// Store the new password in user-service
+	if err := setPassword(ctx, svc.UserClient, user.Email, password); err != nil {
+		return err
+	}
+
+	return svc.TokenRepo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txIdentityRepo := svc.IdentityRepo.WithTx(tx)
+		txOutboxRepo := svc.OutboxRepo.WithTx(tx)
+
+		// Make sure the password is a linked sign-in method
+		err := txIdentityRepo.EnsureIdentity(&entities.UserIdentity{
+			UserID:   resetToken.UserID,
+			Provider: constants.IdentityProviderPassword,
+			Subject:  resetToken.UserID,
+		})
+		if err != nil {
+			return errors.ErrFailedToUpdatePassword
+		}
+
+		if err := txOutboxRepo.Enqueue(event); err != nil {
+			return errors.ErrFailedToUpdatePassword
+		}
+		return nil
+	})
+}
+
+// unredeemableResetToken tells why the reset token with the hash could not be redeemed
+func unredeemableResetToken(tokenRepo repositories.TokenRepository, tokenHash string) error {
+	resetToken, err := tokenRepo.FindToken(tokenHash)
+	if err != nil {
+		return errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToFindToken, err)
+	}
+	if resetToken != nil && resetToken.Used && time.Now().Before(resetToken.ExpiresAt) {
+		return errors.ErrResetTokenAlreadyUsed
+	}
+	return errors.ErrInvalidOrExpiredResetToken
 }
 
 // Logout invalidates the current token (via blacklisting or other mechanisms)
-func (svc *TokenService) Logout(tokenString string, userID string) error {
+func (svc *TokenService) Logout(ctx context.Context, tokenString string, userID string) error {
+	tokenRepo := svc.TokenRepo.WithContext(ctx)
+
 	// Optionally check if the token is already blacklisted
-	isBlacklisted, err := svc.TokenRepo.IsTokenBlacklisted(tokenString)
+	isBlacklisted, err := tokenRepo.IsTokenBlacklisted(tokenString)
 	if err != nil {
 		return err
 	}
@@ -117,13 +233,18 @@ func (svc *TokenService) Logout(tokenString string, userID string) error {
 		Type:      "access",                  // Assuming token type is "access"
 		ExpiresAt: time.Now().Add(time.Hour), // Set expiration for blacklisted entry
 	}
-	return svc.TokenRepo.BlacklistToken(token)
+	return tokenRepo.BlacklistToken(token)
 }
 
-// RefreshToken generates a new access token using a valid refresh token
-func (svc *TokenService) RefreshToken(req dtos.RefreshTokenRequest) (*dtos.RefreshTokenResponse, error) {
+// RefreshToken generates a new access token using a valid refresh token of an active account
+func (svc *TokenService) RefreshToken(ctx context.Context, req dtos.RefreshTokenRequest) (refreshed *dtos.RefreshTokenResponse, err error) {
+	defer func() { metrics.TokenRefreshes.WithLabelValues(metricOutcome(err)).Inc() }()
+
+	tokenRepo := svc.TokenRepo.WithContext(ctx)
+	userRepo := svc.UserRepo.WithContext(ctx)
+
 	// Validate the refresh token
-	token, err := svc.TokenRepo.FindRefreshToken(req.RefreshToken)
+	token, err := tokenRepo.FindRefreshToken(req.RefreshToken)
 	if err != nil {
 		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToFindToken, err)
 	}
@@ -132,7 +253,7 @@ func (svc *TokenService) RefreshToken(req dtos.RefreshTokenRequest) (*dtos.Refre
 	}
 
 	// Find the user associated with the refresh token
-	user, err := svc.UserRepo.FindUserByID(token.UserID)
+	user, err := userRepo.FindUserByID(token.UserID)
 	if err != nil {
 		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToRetrieveUser, err)
 	}
@@ -140,6 +261,16 @@ func (svc *TokenService) RefreshToken(req dtos.RefreshTokenRequest) (*dtos.Refre
 		return nil, errors.ErrUserNotFound // Domain-specific error
 	}
 
+	// A session outlives neither the deactivation nor the deletion of its account, which user-service owns
+	if err := ensureAccountActive(ctx, svc.UserClient, user.Email); err != nil {
+		if err == errors.ErrAccountDisabled {
+			if revokeErr := tokenRepo.RevokeUserTokens(user.ID); revokeErr != nil {
+				slog.ErrorContext(ctx, "Failed to revoke the sessions of a disabled account", "error", revokeErr)
+			}
+		}
+		return nil, err
+	}
+
 	// Generate a new access token
 	accessToken, err := utils.GenerateJWT(user.ID, user.Email, config.AppConfig.JWT.Secret, utils.TokenExpiry())
 	if err != nil {
@@ -158,8 +289,8 @@ func (svc *TokenService) RefreshToken(req dtos.RefreshTokenRequest) (*dtos.Refre
 	token.Type = constants.RefreshToken
 	token.ExpiresAt = time.Now().Add(utils.TokenExpiry())
 	token.UpdatedAt = time.Now()
-	token.RefreshTokenExpiresAt = time.Now().Add(utils.ConvertTokenExpiry(config.AppConfig.JWT.RefreshTokenExpiry))
-	if err := svc.TokenRepo.UpdateToken(token); err != nil {
+	token.RefreshTokenExpiresAt = time.Now().Add(utils.RefreshTokenExpiry())
+	if err := tokenRepo.UpdateToken(token); err != nil {
 		return nil, errors.NewAppError(http.StatusInternalServerError, constants.ErrFailedToUpdateToken, err)
 	}
 
@@ -167,7 +298,7 @@ func (svc *TokenService) RefreshToken(req dtos.RefreshTokenRequest) (*dtos.Refre
 	return &dtos.RefreshTokenResponse{
 		AccessToken:           accessToken,
 		RefreshToken:          newRefreshToken,
-		ExpiresIn:             int64(utils.TokenExpiry().Seconds()),                                               // 2 hours
-		RefreshTokenExpiresIn: int64(utils.ConvertTokenExpiry(config.AppConfig.JWT.RefreshTokenExpiry).Seconds()), // 24 hours
+		ExpiresIn:             int64(utils.TokenExpiry().Seconds()),        // 2 hours
+		RefreshTokenExpiresIn: int64(utils.RefreshTokenExpiry().Seconds()), // 24 hours
 	}, nil
 }
@@ -3,8 +3,8 @@ package utils
 import (
 	"errors"
 	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/constants"
 	"github.com/gin-gonic/gin"
-	"log"
 	"net/http/httptest"
 	"time"
 )
@@ -26,22 +26,56 @@ func ExtractUserIDFromContext(c *gin.Context) (string, error) {
 	return userIDStr, nil
 }
 
+// TokenExpiry returns the configured lifetime of an access token
 func TokenExpiry() time.Duration {
-	// Parse the "24h" string into a time.Duration
-	duration, err := time.ParseDuration(config.AppConfig.JWT.Expiry)
-	if err != nil {
-		log.Fatalf("Failed to parse JWT expiry duration: %v", err)
+	return config.AppConfig.JWT.Expiry.Duration()
+}
+
+// RefreshTokenExpiry returns the configured lifetime of a refresh token
+func RefreshTokenExpiry() time.Duration {
+	return config.AppConfig.JWT.RefreshTokenExpiry.Duration()
+}
+
+// ResetTokenExpiry returns the configured lifetime of a password reset token, defaulting to 30 minutes
+func ResetTokenExpiry() time.Duration {
+	return orDefault(config.AppConfig.Password.ResetTokenExpiry, 30*time.Minute)
+}
+
+// PasswordlessExpiry returns the configured lifetime of a passwordless challenge for the given method
+func PasswordlessExpiry(method string) time.Duration {
+	expiry := config.AppConfig.Passwordless.LinkExpiry
+	if method == constants.PasswordlessMethodCode {
+		expiry = config.AppConfig.Passwordless.CodeExpiry
 	}
-	return duration
+	return orDefault(expiry, 10*time.Minute)
+}
+
+// VerificationTokenExpiry returns the configured lifetime of an email verification link, defaulting to 24 hours
+func VerificationTokenExpiry() time.Duration {
+	return orDefault(config.AppConfig.Registration.VerificationExpiry, 24*time.Hour)
+}
+
+// DeletionGracePeriod returns how long a requested account deletion can be cancelled, defaulting to 7 days
+func DeletionGracePeriod() time.Duration {
+	return orDefault(config.AppConfig.AccountDeletion.GracePeriod, 7*24*time.Hour)
+}
+
+// OIDCStateExpiry returns how long a federated login may take between redirect and callback
+func OIDCStateExpiry() time.Duration {
+	return orDefault(config.AppConfig.OIDC.StateExpiry, 10*time.Minute)
+}
+
+// ReauthMaxAge returns how recent a sign-in must be to count as re-authentication, defaulting to 5 minutes
+func ReauthMaxAge() time.Duration {
+	return orDefault(config.AppConfig.Identity.ReauthMaxAge, 5*time.Minute)
 }
 
-func ConvertTokenExpiry(expiry string) time.Duration {
-	// Parse the "24h" string into a time.Duration
-	duration, err := time.ParseDuration(expiry)
-	if err != nil {
-		log.Fatalf("Failed to parse JWT expiry duration: %v", err)
+// orDefault returns the configured duration, or fallback when it is not set
+func orDefault(configured config.Duration, fallback time.Duration) time.Duration {
+	if configured <= 0 {
+		return fallback
 	}
-	return duration
+	return configured.Duration()
 }
 
 // CreateTestContext initializes a mock Gin context for testing
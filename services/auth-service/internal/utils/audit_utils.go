@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"strconv"
+	"strings"
+)
+
+// AuditRecordHash computes the chain hash of an audit record from its predecessor's hash and its own fields.
+// Fields are joined with the ASCII unit separator so no value can shift into a neighbouring field. The hash is
+// an HMAC keyed with the configured audit chain key, which is kept out of the database, so someone who can
+// only write to the database cannot recompute the chain after editing it.
+func AuditRecordHash(prevHash string, record *entities.AuditLog) string {
+	userID := ""
+	if record.UserID != nil {
+		userID = *record.UserID
+	}
+
+	canonical := strings.Join([]string{
+		prevHash,
+		strconv.FormatInt(record.Sequence, 10),
+		string(record.EventType),
+		userID,
+		record.Actor,
+		record.IP,
+		record.UserAgent,
+		record.Outcome,
+		record.Reason,
+		strconv.FormatInt(record.CreatedAt.UnixMicro(), 10),
+	}, "\x1f")
+
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.Audit.ChainKey))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyAuditChain checks a contiguous run of audit records, ordered by sequence, against the hash of the record before it.
+// Returns the number of intact records preceding the first broken one, and whether the whole run is intact.
+func VerifyAuditChain(prevHash string, prevSequence int64, records []entities.AuditLog) (int, bool) {
+	for i := range records {
+		record := &records[i]
+		if record.Sequence != prevSequence+1 || record.PrevHash != prevHash || AuditRecordHash(prevHash, record) != record.Hash {
+			return i, false
+		}
+		prevHash, prevSequence = record.Hash, record.Sequence
+	}
+	return len(records), true
+}
@@ -1,9 +1,10 @@
 package utils
 
-import "log"
+import "log/slog"
 
 func SendOTPEmail(email, otp string) error {
-	log.Printf("Sending OTP %s to email %s", otp, email)
+	// The code stays out of the logs, so it can only be read from the email
+	slog.Info("Sending OTP email", "email", email)
 	// Placeholder: Integrate an actual email service (e.g., SendGrid, SES)
 	return nil
 }
@@ -3,6 +3,7 @@ package utils
 import (
 	"crypto/rand"
 	"fmt"
+	"log/slog"
 	"math/big"
 )
 
@@ -12,7 +13,7 @@ func GenerateOTP() string {
 	n, err := rand.Int(rand.Reader, maxIn)
 	if err != nil {
 		// Handle the error appropriately (log, return a default value, etc.)
-		fmt.Println("Error generating OTP:", err)
+		slog.Error("Failed to generate OTP", "error", err)
 		return "000000"
 	}
 	return fmt.Sprintf("%06d", n) // Format the OTP to always have 6 digits
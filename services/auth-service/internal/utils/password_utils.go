@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// IsStrongPassword checks if the provided password meets the password policy:
+// at least 8 characters with upper and lower case letters, a digit and a special character.
+func IsStrongPassword(password string) bool {
+	var hasMinLen, hasUpper, hasLower, hasDigit, hasSpecial bool
+
+	// Check minimum length
+	if len(password) >= 8 {
+		hasMinLen = true
+	}
+
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		case strings.ContainsRune("!@#$%^&*()-_=+[]{}|;:'\",.<>?/`~", char):
+			hasSpecial = true
+		}
+	}
+
+	// Password is strong if it satisfies all conditions
+	return hasMinLen && hasUpper && hasLower && hasDigit && hasSpecial
+}
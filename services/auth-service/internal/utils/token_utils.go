@@ -1,59 +1,29 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
-	"github.com/golang-jwt/jwt/v4"
-	"time"
 )
 
-var ResetTokenSecret = []byte("7CD0WF6Yuu") // Replace with a secure key
+// opaqueTokenBytes is the amount of entropy carried by an opaque token
+const opaqueTokenBytes = 32
 
-// VerifyPasswordResetToken verifies the password reset token and extracts the user ID
-func VerifyPasswordResetToken(tokenString string) (string, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return ResetTokenSecret, nil
-	})
-
-	if err != nil {
-		return "", err
-	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
-		return "", errors.New("invalid token")
+// GenerateOpaqueToken generates a URL-safe random token, e.g. for password reset or magic links.
+// The token carries no claims; its meaning is established solely by the stored hash.
+func GenerateOpaqueToken() (string, error) {
+	bytes := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", errors.New("failed to generate token")
 	}
-
-	// Ensure the token is for password reset
-	if purpose, ok := claims["purpose"].(string); !ok || purpose != "password_reset" {
-		return "", errors.New("invalid token purpose")
-	}
-
-	// Check token expiration
-	exp, ok := claims["exp"].(float64)
-	if !ok || time.Now().Unix() > int64(exp) {
-		return "", errors.New("token expired")
-	}
-
-	// Extract and return the user ID
-	userID, ok := claims["user_id"].(string)
-	if !ok {
-		return "", errors.New("invalid token claims")
-	}
-
-	return userID, nil
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
 }
 
-// GeneratePasswordResetToken generates a JWT for password reset
-func GeneratePasswordResetToken(userID string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"purpose": "password_reset",                      // Purpose for password reset
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	//log.Printf("Env reset secret key: %v\n", config.GetConfig().ResetTokenSecret)
-	return token.SignedString(ResetTokenSecret)
+// HashToken returns the SHA-256 digest of a token, hex-encoded.
+// Only the digest is persisted so a database leak does not expose usable tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
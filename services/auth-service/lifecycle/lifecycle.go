@@ -0,0 +1,118 @@
+// Package lifecycle stops the service without dropping work. Once the service is asked to stop, Shutdown runs
+// its steps in the order they were added, all within one deadline: readiness fails first so load balancers
+// stop routing new requests, in-flight requests drain, background workers stop, and the connections the
+// earlier steps still needed, such as the database pool, close last.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	config "github.com/Mir00r/auth-service/configs"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultShutdownTimeout is the time the shutdown may take when the configuration sets none
+const DefaultShutdownTimeout = 30 * time.Second
+
+// step is one stage of a shutdown
+type step struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// Shutdown stops the parts of the service in order
+type Shutdown struct {
+	DrainDelay time.Duration // Time readiness fails before the servers stop accepting connections
+	Timeout    time.Duration // Time the whole shutdown may take
+
+	steps []step
+}
+
+// NewShutdownFromConfig creates a shutdown from the server configuration
+func NewShutdownFromConfig(cfg config.ServerConfig) *Shutdown {
+	shutdown := &Shutdown{DrainDelay: cfg.DrainDelay.Duration(), Timeout: cfg.ShutdownTimeout.Duration()}
+	if shutdown.Timeout <= 0 {
+		shutdown.Timeout = DefaultShutdownTimeout
+	}
+	return shutdown
+}
+
+// Add appends a step to the shutdown. The context passed to stop expires at the deadline of the whole shutdown.
+func (s *Shutdown) Add(name string, stop func(ctx context.Context) error) {
+	s.steps = append(s.steps, step{name: name, stop: stop})
+}
+
+// Run runs the steps in order within the timeout. A failing step does not keep the later ones from running,
+// so the connections they close are released even when draining took too long; the failures are returned.
+func (s *Shutdown) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+
+	var errs []error
+	for _, step := range s.steps {
+		start := time.Now()
+		if err := step.stop(ctx); err != nil {
+			slog.ErrorContext(ctx, "Failed to stop", "step", step.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", step.name, err))
+			continue
+		}
+		slog.InfoContext(ctx, "Stopped", "step", step.name, "duration_ms", time.Since(start).Milliseconds())
+	}
+	return errors.Join(errs...)
+}
+
+// Wait waits for d, or until ctx is done
+func Wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Workers runs the background loops of the service, such as the outbox relay, until they are stopped
+type Workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWorkers creates an empty set of workers
+func NewWorkers() *Workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Workers{ctx: ctx, cancel: cancel}
+}
+
+// Go runs a worker in the background until Stop is called. run must return once its context is done.
+func (w *Workers) Go(run func(ctx context.Context)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		run(w.ctx)
+	}()
+}
+
+// Stop cancels the context of the workers and waits for them to return, letting each finish the batch it is
+// working on, until ctx is done
+func (w *Workers) Stop(ctx context.Context) error {
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,109 @@
+// Package logging writes the logs of the service as structured records with log/slog. Records logged with the
+// context of a request carry its request ID and trace, secrets, tokens, passwords and personal data are masked
+// before anything is written, and the level can be changed while the service runs.
+//
+// SetupFromConfig installs the logger as the default of both log/slog and log, so the records written through
+// log.Printf are structured and redacted as well.
+package logging
+
+import (
+	"context"
+	"fmt"
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/shared/logging"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Formats of the logging configuration
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New creates a logger writing the records at or above the configured level to w in the configured format
+func New(w io.Writer, cfg config.LoggingConfig) (*slog.Logger, error) {
+	if err := logging.SetLevel(cfg.Level); err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: logging.Leveler()}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+	return slog.New(logging.NewRedactingHandler(contextHandler{next: handler})), nil
+}
+
+// SetupFromConfig installs the configured logger as the default logger, writing to stdout, and follows the
+// changes of logging.level made by reloading the configuration. It stops the service when the configuration is
+// invalid.
+func SetupFromConfig(cfg config.LoggingConfig) {
+	logger, err := New(os.Stdout, cfg)
+	if err != nil {
+		log.Fatalf("Invalid logging configuration: %v", err)
+	}
+	slog.SetDefault(logger)
+	config.OnReload("logging.level", func(cfg config.Config) {
+		previous := logging.Level()
+		if err := logging.SetLevel(cfg.Logging.Level); err != nil {
+			slog.Error("Log level not reloaded", "error", err)
+			return
+		}
+		slog.Info("Log level changed", "from", previous.String(), "to", logging.Level().String())
+	})
+}
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the ID correlating the logs of one call across the services
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by the context, if any
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// contextHandler adds the request ID and the trace carried by the context to every record
+type contextHandler struct {
+	next slog.Handler
+}
+
+// Enabled implements slog.Handler
+func (h contextHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+// Handle implements slog.Handler
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		r.AddAttrs(slog.String("request_id", requestID))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		r.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,61 @@
+package messaging
+
+import (
+	"context"
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/constants"
+	"log"
+	"log/slog"
+	"time"
+)
+
+// streamTimeout bounds the creation of the JetStream streams at startup
+const streamTimeout = 10 * time.Second
+
+// NewRelayFromConfig connects to the configured broker and builds the outbox relay.
+// It returns nil when no broker is configured; events then stay in the outbox until one is.
+func NewRelayFromConfig(cfg config.MessagingConfig, store OutboxStore) *Relay {
+	if cfg.NatsURL == "" {
+		slog.Warn("Messaging is not configured, domain events stay in the outbox and last logins are sent to user-service directly")
+		return nil
+	}
+
+	broker, err := NewNATSBroker(cfg.NatsURL, cfg.JetStream)
+	if err != nil {
+		log.Fatalf("Failed to connect to NATS: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), streamTimeout)
+	defer cancel()
+	if err := broker.EnsureStream(ctx, cfg.Stream, constants.EventSubjects); err != nil {
+		log.Fatalf("Failed to create the %s stream: %v", cfg.Stream, err)
+	}
+	if !cfg.JetStream {
+		slog.Warn("Publishing to core NATS, events published while no subscriber is connected are lost")
+	}
+
+	return NewRelay(store, broker, RelayConfig{
+		PollInterval: cfg.Outbox.PollInterval.Duration(),
+		BatchSize:    cfg.Outbox.BatchSize,
+	})
+}
+
+// NewSubscriberFromConfig connects a subscriber to the configured broker.
+// It returns nil when no broker is configured.
+func NewSubscriberFromConfig(cfg config.MessagingConfig) *NATSSubscriber {
+	if cfg.NatsURL == "" {
+		return nil
+	}
+
+	subscriber, err := NewNATSSubscriber(cfg.NatsURL, cfg.JetStream)
+	if err != nil {
+		log.Fatalf("Failed to connect to NATS: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), streamTimeout)
+	defer cancel()
+	if err := subscriber.EnsureStream(ctx, cfg.UserStream, constants.UserEventSubjects); err != nil {
+		log.Fatalf("Failed to create the %s stream: %v", cfg.UserStream, err)
+	}
+	return subscriber
+}
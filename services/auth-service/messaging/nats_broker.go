@@ -0,0 +1,65 @@
+package messaging
+
+import (
+	"context"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATSBroker publishes messages to NATS.
+// With JetStream the server acknowledges each message and drops duplicates by message ID;
+// with core NATS a message counts as accepted once the server has processed the publish, and is lost when no
+// subscriber is connected at that moment, so core NATS delivers events at most once.
+type NATSBroker struct {
+	conn *nats.Conn
+	js   jetstream.JetStream // nil when publishing to core NATS
+}
+
+// NewNATSBroker connects to the NATS server at url
+func NewNATSBroker(url string, useJetStream bool) (*NATSBroker, error) {
+	conn, err := nats.Connect(url, nats.Name("auth-service"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+
+	broker := &NATSBroker{conn: conn}
+	if useJetStream {
+		broker.js, err = jetstream.New(conn)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return broker, nil
+}
+
+// EnsureStream creates or updates the JetStream stream name capturing subjects, so events published before any
+// consumer subscribed are kept for it. It does nothing with core NATS.
+func (b *NATSBroker) EnsureStream(ctx context.Context, name, subjects string) error {
+	if b.js == nil {
+		return nil
+	}
+	return ensureStream(ctx, b.js, name, subjects)
+}
+
+// Publish sends the message with its ID in the Nats-Msg-Id header
+func (b *NATSBroker) Publish(ctx context.Context, msg Message) error {
+	natsMsg := nats.NewMsg(msg.Subject)
+	natsMsg.Data = msg.Payload
+	natsMsg.Header.Set(jetstream.MsgIDHeader, msg.ID)
+
+	if b.js != nil {
+		_, err := b.js.PublishMsg(ctx, natsMsg, jetstream.WithMsgID(msg.ID))
+		return err
+	}
+
+	if err := b.conn.PublishMsg(natsMsg); err != nil {
+		return err
+	}
+	return b.conn.FlushWithContext(ctx)
+}
+
+// Close drains pending publishes and closes the connection
+func (b *NATSBroker) Close() error {
+	return b.conn.Drain()
+}
@@ -0,0 +1,26 @@
+package messaging
+
+import (
+	"context"
+	"github.com/nats-io/nats.go/jetstream"
+	"time"
+)
+
+// Retention of a JetStream stream: the publisher and the consumer both declare it, so it must match in every service
+const (
+	streamMaxAge          = 7 * 24 * time.Hour // Bounds the backlog kept for a consumer that is down
+	streamDuplicateWindow = 2 * time.Minute    // Drops an event the relay publishes again within the window
+)
+
+// ensureStream creates the stream name capturing subjects, or updates it to this configuration. The service
+// publishing to the stream and the ones consuming from it all call it at startup, so either may start first.
+func ensureStream(ctx context.Context, js jetstream.JetStream, name, subjects string) error {
+	_, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       name,
+		Subjects:   []string{subjects},
+		Storage:    jetstream.FileStorage,
+		MaxAge:     streamMaxAge,
+		Duplicates: streamDuplicateWindow,
+	})
+	return err
+}
@@ -0,0 +1,98 @@
+package messaging
+
+import (
+	"context"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"log/slog"
+	"time"
+)
+
+// Handler processes one delivered message; returning an error asks for a redelivery
+type Handler func(ctx context.Context, msg Message) error
+
+// NATSSubscriber consumes messages published by other services.
+// With JetStream a durable consumer acknowledges a message once the handler succeeded and redelivers it
+// otherwise, giving at-least-once delivery; with core NATS messages published while the service is down are missed.
+type NATSSubscriber struct {
+	conn     *nats.Conn
+	js       jetstream.JetStream // nil when consuming from core NATS
+	consumes []jetstream.ConsumeContext
+}
+
+// NewNATSSubscriber connects to the NATS server at url
+func NewNATSSubscriber(url string, useJetStream bool) (*NATSSubscriber, error) {
+	conn, err := nats.Connect(url, nats.Name(constants.ServiceName), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+
+	subscriber := &NATSSubscriber{conn: conn}
+	if useJetStream {
+		subscriber.js, err = jetstream.New(conn)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return subscriber, nil
+}
+
+// EnsureStream creates or updates the JetStream stream name capturing subjects, so the consumers can be created
+// before the publishing service first started. It does nothing with core NATS.
+func (s *NATSSubscriber) EnsureStream(ctx context.Context, name, subjects string) error {
+	if s.js == nil {
+		return nil
+	}
+	return ensureStream(ctx, s.js, name, subjects)
+}
+
+// Subscribe delivers messages on subject to handler. Instances sharing the durable name split the messages
+// between them; with JetStream, stream names the stream that captures the subject.
+func (s *NATSSubscriber) Subscribe(ctx context.Context, stream, durable, subject string, handler Handler) error {
+	if s.js == nil {
+		_, err := s.conn.QueueSubscribe(subject, durable, func(msg *nats.Msg) {
+			if err := handler(ctx, toMessage(msg.Subject, msg.Header, msg.Data)); err != nil {
+				slog.ErrorContext(ctx, "Failed to handle message", "subject", msg.Subject, "error", err)
+			}
+		})
+		return err
+	}
+
+	consumer, err := s.js.CreateOrUpdateConsumer(ctx, stream, jetstream.ConsumerConfig{
+		Durable:       durable,
+		FilterSubject: subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		return err
+	}
+
+	consume, err := consumer.Consume(func(msg jetstream.Msg) {
+		if err := handler(ctx, toMessage(msg.Subject(), msg.Headers(), msg.Data())); err != nil {
+			slog.WarnContext(ctx, "Failed to handle message, it will be redelivered", "subject", msg.Subject(), "error", err)
+			_ = msg.NakWithDelay(5 * time.Second)
+			return
+		}
+		_ = msg.Ack()
+	})
+	if err != nil {
+		return err
+	}
+	s.consumes = append(s.consumes, consume)
+	return nil
+}
+
+// Close stops the consumers and drains the connection
+func (s *NATSSubscriber) Close() error {
+	for _, consume := range s.consumes {
+		consume.Stop()
+	}
+	return s.conn.Drain()
+}
+
+// toMessage converts a delivered NATS message
+func toMessage(subject string, header nats.Header, data []byte) Message {
+	return Message{ID: header.Get(jetstream.MsgIDHeader), Subject: subject, Payload: data}
+}
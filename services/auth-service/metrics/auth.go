@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Stages of a password reset
+const (
+	PasswordResetRequested = "requested"
+	PasswordResetCompleted = "completed"
+)
+
+// The outcome label of the authentication flows is success, or the audit reason code of the failure
+var (
+	// Logins counts the completed logins, by login method and outcome
+	Logins = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_logins_total",
+		Help: "Logins, by login method (password, passwordless, federated) and outcome.",
+	}, []string{"method", "outcome"})
+
+	// MFAVerifications counts the one-time passwords verified to enable MFA, by outcome
+	MFAVerifications = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_mfa_verifications_total",
+		Help: "MFA one-time password verifications, by outcome.",
+	}, []string{"outcome"})
+
+	// TokenRefreshes counts the refreshes of access tokens, by outcome
+	TokenRefreshes = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_token_refreshes_total",
+		Help: "Access token refreshes, by outcome.",
+	}, []string{"outcome"})
+
+	// PasswordResets counts the password resets, by stage and outcome
+	PasswordResets = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_password_resets_total",
+		Help: "Password resets, by stage (requested, completed) and outcome.",
+	}, []string{"stage", "outcome"})
+
+	// Registrations counts the registrations of new accounts, by outcome
+	Registrations = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_registrations_total",
+		Help: "Registrations of new accounts, by outcome.",
+	}, []string{"outcome"})
+)
+
+// AuditWriteFailures counts the audit records the database refused to store. They are retried until stored, so a
+// rising count means the audit log is falling behind rather than losing records.
+var AuditWriteFailures = factory.NewCounter(prometheus.CounterOpts{
+	Name: "auth_audit_write_failures_total",
+	Help: "Audit records that failed to be appended to the audit log and were retried.",
+})
@@ -0,0 +1,100 @@
+// Package metrics exposes the metrics of the service in the Prometheus text format: the rate, errors and duration
+// of the requests it serves, the connection pool of its database, the calls it makes to other services and the
+// outcome of the authentication flows. Labels only take values from bounded sets, such as route templates and
+// configured upstream names, so the number of series stays bounded whatever the traffic.
+package metrics
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+)
+
+// Outcomes of outbound calls that got no answer from the target
+const (
+	OutcomeError    = "error"    // The target could not be reached or did not answer in time
+	OutcomeRejected = "rejected" // Rejected locally by the circuit breaker or the bulkhead
+)
+
+// UnmatchedRoute is the route label of requests that matched no route
+const UnmatchedRoute = "unmatched"
+
+// registry holds the metrics of the service, next to those of the Go runtime and the process
+var registry = prometheus.NewRegistry()
+
+// factory registers the metrics of the package with the registry
+var factory = promauto.With(registry)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+var (
+	// HTTPRequests counts the requests served, by method, route template and status
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_server_requests_total",
+		Help: "Requests served, by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration observes the time taken to serve requests, by method, route template and status
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_server_request_duration_seconds",
+		Help:    "Time taken to serve requests, by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// HTTPInFlight is the number of requests being served
+	HTTPInFlight = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "http_server_requests_in_flight",
+		Help: "Requests being served.",
+	})
+
+	// OutboundDuration observes the time taken by calls to other services, retries included, by upstream,
+	// method and outcome
+	OutboundDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "outbound_request_duration_seconds",
+		Help:    "Time taken by calls to upstream services including retries, by upstream, method and outcome: the status class (2xx, 4xx, 5xx) or gRPC code of the answer, error or rejected.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"upstream", "method", "outcome"})
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// MustRegister registers collectors of other packages with the metrics of the service.
+// It panics when a collector clashes with one already registered.
+func MustRegister(cs ...prometheus.Collector) {
+	registry.MustRegister(cs...)
+}
+
+// RegisterDB exposes the statistics of the connection pool of the database
+func RegisterDB(db *sql.DB, name string) error {
+	return registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Method returns the method label of a request, folding methods the service does not serve into OTHER
+func Method(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
+
+// StatusClass returns the outcome label of an HTTP answer, such as 2xx
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return OutcomeError
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/gin-gonic/gin"
+	"io"
+)
+
+// maxAuditPeekBytes bounds the part of a request body read for the submitted email; the audited routes take
+// small JSON bodies
+const maxAuditPeekBytes = 64 << 10
+
+// Audit records the outcome of the wrapped handler as an audit event of the given type.
+// It must run inside ErrorHandler so the handler's errors are still available on the context.
+func Audit(auditService services.AuditService, eventType constants.AuditEventType) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		submittedEmail := peekEmail(c)
+
+		c.Next()
+
+		event := dtos.AuditEvent{
+			EventType: eventType,
+			Actor:     submittedEmail,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Outcome:   constants.AuditOutcomeSuccess,
+		}
+		if userID := c.GetString("userID"); userID != "" {
+			event.UserID, event.Actor = userID, userID
+		}
+
+		if err := c.Errors.Last(); err != nil {
+			event.Outcome = constants.AuditOutcomeFailure
+			event.Reason = services.AuditReason(err.Err, c.Writer.Status())
+		} else if c.Writer.Status() >= 400 {
+			event.Outcome = constants.AuditOutcomeFailure
+			event.Reason = services.AuditReason(nil, c.Writer.Status())
+		}
+
+		auditService.Record(event)
+	}
+}
+
+// peekEmail reads the email field of a JSON body without consuming the body for the handler.
+// Only the first maxAuditPeekBytes are buffered; a longer body reaches the handler whole but yields no email.
+func peekEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAuditPeekBytes+1))
+	c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(body), c.Request.Body), c.Request.Body}
+	if err != nil || len(body) > maxAuditPeekBytes {
+		return ""
+	}
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	return payload.Email
+}
+
+// readCloser reads the peeked bytes and the rest of a body, and closes the body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
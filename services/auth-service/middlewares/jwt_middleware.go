@@ -1,10 +1,12 @@
 package middlewares
 
 import (
+	"crypto/subtle"
 	config "github.com/Mir00r/auth-service/configs"
 	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/telemetry"
 	"github.com/gin-gonic/gin"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -32,25 +34,40 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Verify the JWT and extract claims
 		claims, err := utils.VerifyJWT(tokenString)
 		if err != nil {
-			log.Printf("JWT verification error: %v", err)
+			slog.WarnContext(c.Request.Context(), "Rejected JWT", "error", err)
 			utils.GinErrorResponse(c, http.StatusUnauthorized, constants.ErrInvalidToken)
 			c.Abort()
 			return
 		}
-		log.Printf("Extracted user ID: %s", claims.UserID)
-
 		// Add claims to the Go context
 		ctx := utils.AddClaimsToContext(c.Request.Context(), claims)
+		telemetry.SetUser(ctx, claims.UserID, "")
 
 		// Inject claims into the Gin context
 		c.Set("userID", claims.UserID)
 		c.Request = c.Request.WithContext(ctx) // Attach the modified context to the request
 
-		log.Printf("Extracted user ID: %s", claims.UserID)
 		c.Next()
 	}
 }
 
+// AdminBasicAuthMiddleware validates requests to the admin APIs using the admin Basic Auth credentials.
+// Admin APIs stay closed while no admin password is configured.
+func AdminBasicAuthMiddleware(c *gin.Context) {
+	expectedUsername := config.AppConfig.AdminSecurity.UserName
+	expectedPassword := config.AppConfig.AdminSecurity.Password
+
+	username, password, hasAuth := c.Request.BasicAuth()
+	if expectedPassword != "" && hasAuth &&
+		subtle.ConstantTimeCompare([]byte(username), []byte(expectedUsername)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(expectedPassword)) == 1 {
+		c.Next()
+		return
+	}
+
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+}
+
 // BasicAuthMiddleware validates requests using Basic Auth or API keys
 func BasicAuthMiddleware(c *gin.Context) {
 	// Fetch expected credentials from environment variables
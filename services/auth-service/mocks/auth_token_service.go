@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	dtos "github.com/Mir00r/auth-service/internal/models/dtos"
@@ -36,45 +37,46 @@ func (m *MockAuthService) EXPECT() *MockAuthServiceMockRecorder {
 }
 
 // Authenticate mocks base method.
-func (m *MockAuthService) Authenticate(req dtos.LoginRequest) (*dtos.LoginResponse, error) {
+func (m *MockAuthService) Authenticate(ctx context.Context, req dtos.LoginRequest) (*dtos.LoginResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Authenticate", req)
+	ret := m.ctrl.Call(m, "Authenticate", ctx, req)
 	ret0, _ := ret[0].(*dtos.LoginResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Authenticate indicates an expected call of Authenticate.
-func (mr *MockAuthServiceMockRecorder) Authenticate(req interface{}) *gomock.Call {
+func (mr *MockAuthServiceMockRecorder) Authenticate(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockAuthService)(nil).Authenticate), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockAuthService)(nil).Authenticate), ctx, req)
 }
 
-// GetUserProfile mocks base method.
-func (m *MockAuthService) GetUserProfile(userID string) (*entities.User, error) {
+// ChangePassword mocks base method.
+func (m *MockAuthService) ChangePassword(ctx context.Context, userID string, req dtos.ChangePasswordRequest) (*dtos.ChangePasswordResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserProfile", userID)
-	ret0, _ := ret[0].(*entities.User)
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, userID, req)
+	ret0, _ := ret[0].(*dtos.ChangePasswordResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserProfile indicates an expected call of GetUserProfile.
-func (mr *MockAuthServiceMockRecorder) GetUserProfile(userID interface{}) *gomock.Call {
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockAuthServiceMockRecorder) ChangePassword(ctx, userID, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserProfile", reflect.TypeOf((*MockAuthService)(nil).GetUserProfile), userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockAuthService)(nil).ChangePassword), ctx, userID, req)
 }
 
-// RegisterUser mocks base method.
-func (m *MockAuthService) RegisterUser(req dtos.RegisterRequest) error {
+// GetUserProfile mocks base method.
+func (m *MockAuthService) GetUserProfile(ctx context.Context, userID string) (*entities.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RegisterUser", req)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "GetUserProfile", ctx, userID)
+	ret0, _ := ret[0].(*entities.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// RegisterUser indicates an expected call of RegisterUser.
-func (mr *MockAuthServiceMockRecorder) RegisterUser(req interface{}) *gomock.Call {
+// GetUserProfile indicates an expected call of GetUserProfile.
+func (mr *MockAuthServiceMockRecorder) GetUserProfile(ctx, userID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterUser", reflect.TypeOf((*MockAuthService)(nil).RegisterUser), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserProfile", reflect.TypeOf((*MockAuthService)(nil).GetUserProfile), ctx, userID)
 }
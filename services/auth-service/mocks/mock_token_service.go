@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	dtos "github.com/Mir00r/auth-service/internal/models/dtos"
@@ -35,58 +36,58 @@ func (m *MockTokenServiceInterface) EXPECT() *MockTokenServiceInterfaceMockRecor
 }
 
 // InitiatePasswordReset mocks base method.
-func (m *MockTokenServiceInterface) InitiatePasswordReset(req dtos.PasswordResetRequest) error {
+func (m *MockTokenServiceInterface) InitiatePasswordReset(ctx context.Context, req dtos.PasswordResetRequest) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InitiatePasswordReset", req)
+	ret := m.ctrl.Call(m, "InitiatePasswordReset", ctx, req)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // InitiatePasswordReset indicates an expected call of InitiatePasswordReset.
-func (mr *MockTokenServiceInterfaceMockRecorder) InitiatePasswordReset(req interface{}) *gomock.Call {
+func (mr *MockTokenServiceInterfaceMockRecorder) InitiatePasswordReset(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiatePasswordReset", reflect.TypeOf((*MockTokenServiceInterface)(nil).InitiatePasswordReset), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiatePasswordReset", reflect.TypeOf((*MockTokenServiceInterface)(nil).InitiatePasswordReset), ctx, req)
 }
 
 // Logout mocks base method.
-func (m *MockTokenServiceInterface) Logout(tokenString, userID string) error {
+func (m *MockTokenServiceInterface) Logout(ctx context.Context, tokenString, userID string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Logout", tokenString, userID)
+	ret := m.ctrl.Call(m, "Logout", ctx, tokenString, userID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Logout indicates an expected call of Logout.
-func (mr *MockTokenServiceInterfaceMockRecorder) Logout(tokenString, userID interface{}) *gomock.Call {
+func (mr *MockTokenServiceInterfaceMockRecorder) Logout(ctx, tokenString, userID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockTokenServiceInterface)(nil).Logout), tokenString, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockTokenServiceInterface)(nil).Logout), ctx, tokenString, userID)
 }
 
 // RefreshToken mocks base method.
-func (m *MockTokenServiceInterface) RefreshToken(req dtos.RefreshTokenRequest) (*dtos.RefreshTokenResponse, error) {
+func (m *MockTokenServiceInterface) RefreshToken(ctx context.Context, req dtos.RefreshTokenRequest) (*dtos.RefreshTokenResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RefreshToken", req)
+	ret := m.ctrl.Call(m, "RefreshToken", ctx, req)
 	ret0, _ := ret[0].(*dtos.RefreshTokenResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // RefreshToken indicates an expected call of RefreshToken.
-func (mr *MockTokenServiceInterfaceMockRecorder) RefreshToken(req interface{}) *gomock.Call {
+func (mr *MockTokenServiceInterfaceMockRecorder) RefreshToken(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockTokenServiceInterface)(nil).RefreshToken), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockTokenServiceInterface)(nil).RefreshToken), ctx, req)
 }
 
 // ResetPassword mocks base method.
-func (m *MockTokenServiceInterface) ResetPassword(req dtos.ConfirmPasswordResetRequest) error {
+func (m *MockTokenServiceInterface) ResetPassword(ctx context.Context, req dtos.ConfirmPasswordResetRequest) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ResetPassword", req)
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, req)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // ResetPassword indicates an expected call of ResetPassword.
-func (mr *MockTokenServiceInterfaceMockRecorder) ResetPassword(req interface{}) *gomock.Call {
+func (mr *MockTokenServiceInterfaceMockRecorder) ResetPassword(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockTokenServiceInterface)(nil).ResetPassword), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockTokenServiceInterface)(nil).ResetPassword), ctx, req)
 }
@@ -0,0 +1,10 @@
+package saga
+
+import (
+	config "github.com/Mir00r/auth-service/configs"
+)
+
+// NewOrchestratorFromConfig builds the orchestrator for the given saga definitions from the application configuration
+func NewOrchestratorFromConfig(cfg config.SagaConfig, store Store, definitions ...Definition) *Orchestrator {
+	return NewOrchestrator(store, Config{PollInterval: cfg.PollInterval.Duration()}, definitions...)
+}
@@ -0,0 +1,441 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// defaultMaxAttempts bounds the attempts of a step when the definition sets no limit
+const defaultMaxAttempts = 5
+
+var (
+	// ErrAlreadyRunning is returned when an unfinished saga of the same type and key exists
+	ErrAlreadyRunning = errors.New("saga is already running for this key")
+
+	// ErrNotCancellable is returned by Cancel when no saga of the key waits before its cancellable steps
+	ErrNotCancellable = errors.New("saga can no longer be cancelled")
+
+	// ErrLeaseLost is returned by Store.Save when the saga was written since it was read, because another instance
+	// took it over after the lease expired or it was cancelled. The run advancing it stops without writing.
+	ErrLeaseLost = errors.New("saga was changed by another instance")
+)
+
+// Store is the part of the saga repository the orchestrator needs
+type Store interface {
+	// Create persists a new saga, returning ErrAlreadyRunning when an unfinished saga has the same type and key
+	Create(instance *entities.SagaInstance) error
+	// Save stores the saga unless it was written since it was read, returning ErrLeaseLost then. Claiming and
+	// cancelling a saga count as writes.
+	Save(instance *entities.SagaInstance) error
+	ClaimDue(limit int, lease time.Duration) ([]entities.SagaInstance, error)
+	// Cancel ends the unfinished, unleased saga of the type and key if it has not reached step beforeStep,
+	// returning nil when there is no such saga
+	Cancel(sagaType, key string, beforeStep int) (*entities.SagaInstance, error)
+	// FindLatest returns the most recent saga of the type and key, or nil
+	FindLatest(sagaType, key string) (*entities.SagaInstance, error)
+}
+
+// Config tunes the orchestrator
+type Config struct {
+	PollInterval time.Duration // Wait between polls for sagas to resume, 5s when zero
+	BatchSize    int           // Sagas claimed per poll, 20 when zero
+	Lease        time.Duration // How long an instance may advance a saga before others may take over, 1m when zero
+	MaxBackoff   time.Duration // Upper bound of the retry delay, 5m when zero
+}
+
+// Orchestrator executes sagas: it runs the steps in order, persisting the state after each one,
+// retries failed steps with backoff and, when a step cannot succeed, runs the compensations in reverse order.
+// Sagas interrupted by a crash are resumed by Run once their lease expires.
+type Orchestrator struct {
+	store       Store
+	definitions map[string]Definition
+	cfg         Config
+}
+
+// run is a saga being advanced, with its decoded data and step log
+type run struct {
+	definition Definition
+	instance   *entities.SagaInstance
+	data       Data
+	steps      []StepState
+}
+
+// NewOrchestrator initializes an orchestrator for the given saga definitions, filling in defaults for unset configuration
+func NewOrchestrator(store Store, cfg Config, definitions ...Definition) *Orchestrator {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 20
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+
+	byType := make(map[string]Definition, len(definitions))
+	for _, definition := range definitions {
+		if definition.MaxAttempts <= 0 {
+			definition.MaxAttempts = defaultMaxAttempts
+		}
+		byType[definition.Type] = definition
+	}
+	return &Orchestrator{store: store, definitions: byType, cfg: cfg}
+}
+
+// Start persists a new saga and advances it right away.
+// The returned error is the failure of the step the saga stopped at: the saga is then either compensated
+// or, when the failure may be temporary, still running and waiting to be resumed.
+func (o *Orchestrator) Start(ctx context.Context, sagaType, key string, data Data) (*entities.SagaInstance, error) {
+	// The new saga is leased to the caller, so Run does not advance it concurrently
+	r, err := o.create(sagaType, key, data, time.Now(), true)
+	if err != nil {
+		return nil, err
+	}
+	return r.instance, o.advance(ctx, r)
+}
+
+// Schedule persists a new saga whose first step runs at startAt, advanced by Run from then on
+func (o *Orchestrator) Schedule(sagaType, key string, data Data, startAt time.Time) (*entities.SagaInstance, error) {
+	r, err := o.create(sagaType, key, data, startAt, false)
+	if err != nil {
+		return nil, err
+	}
+	return r.instance, nil
+}
+
+// Cancel ends the unfinished saga of the type and key, provided it has not reached a step
+// past the definition's cancellable steps and is not being advanced right now
+func (o *Orchestrator) Cancel(sagaType, key string) (*entities.SagaInstance, error) {
+	definition, ok := o.definitions[sagaType]
+	if !ok {
+		return nil, fmt.Errorf("unknown saga type %q", sagaType)
+	}
+
+	instance, err := o.store.Cancel(sagaType, key, definition.CancellableSteps)
+	if err != nil {
+		return nil, err
+	}
+	if instance == nil {
+		return nil, ErrNotCancellable
+	}
+
+	r, err := o.load(definition, instance)
+	if err != nil {
+		return nil, err
+	}
+	r.mark(instance.Step, StepCancelled, nil)
+	o.end(r, StatusCancelled)
+	return instance, o.save(r)
+}
+
+// Latest returns the most recent saga of the type and key, or nil when there is none
+func (o *Orchestrator) Latest(sagaType, key string) (*entities.SagaInstance, error) {
+	return o.store.FindLatest(sagaType, key)
+}
+
+// Run resumes due sagas until ctx is cancelled. The sagas claimed when ctx is cancelled still advance, so a
+// shutdown does not interrupt a step half way.
+func (o *Orchestrator) Run(ctx context.Context) {
+	for {
+		resumed, err := o.ResumeOnce(context.WithoutCancel(ctx))
+		if err != nil {
+			slog.ErrorContext(ctx, "Saga orchestrator failed to claim sagas", "error", err)
+		}
+
+		// Keep going while full batches are waiting
+		if err == nil && resumed == o.cfg.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(o.cfg.PollInterval):
+		}
+	}
+}
+
+// ResumeOnce claims one batch of sagas that are due or whose lease expired and advances them,
+// returning the number of sagas claimed
+func (o *Orchestrator) ResumeOnce(ctx context.Context) (int, error) {
+	instances, err := o.store.ClaimDue(o.cfg.BatchSize, o.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range instances {
+		instance := &instances[i]
+		definition, ok := o.definitions[instance.SagaType]
+		if !ok {
+			slog.ErrorContext(ctx, "Cannot resume saga of unknown type", "saga_id", instance.ID, "saga_type", instance.SagaType)
+			continue
+		}
+		r, err := o.load(definition, instance)
+		if err != nil {
+			slog.ErrorContext(ctx, "Cannot resume saga", "saga_id", instance.ID, "error", err)
+			continue
+		}
+		if err := o.advance(ctx, r); err != nil {
+			slog.WarnContext(ctx, "Saga stopped", "saga_type", instance.SagaType, "saga_id", instance.ID, "step", instance.StepName, "error", err)
+		}
+	}
+	return len(instances), nil
+}
+
+// create persists a new saga, leased to the caller when leased is set
+func (o *Orchestrator) create(sagaType, key string, data Data, startAt time.Time, leased bool) (*run, error) {
+	definition, ok := o.definitions[sagaType]
+	if !ok || len(definition.Steps) == 0 {
+		return nil, fmt.Errorf("unknown saga type %q", sagaType)
+	}
+
+	steps := make([]StepState, len(definition.Steps))
+	for i, step := range definition.Steps {
+		steps[i] = StepState{Name: step.Name, Status: StepPending}
+	}
+	r := &run{
+		definition: definition,
+		instance: &entities.SagaInstance{
+			SagaType:      sagaType,
+			SagaKey:       key,
+			Status:        StatusRunning,
+			NextAttemptAt: startAt,
+		},
+		data:  data,
+		steps: steps,
+	}
+	if leased {
+		lockedUntil := time.Now().Add(o.cfg.Lease)
+		r.instance.LockedUntil = &lockedUntil
+	}
+	if err := o.encode(r); err != nil {
+		return nil, err
+	}
+
+	if err := o.store.Create(r.instance); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load decodes the persisted data and step log of a saga
+func (o *Orchestrator) load(definition Definition, instance *entities.SagaInstance) (*run, error) {
+	r := &run{definition: definition, instance: instance, data: Data{}}
+	if len(instance.Data) > 0 {
+		if err := json.Unmarshal(instance.Data, &r.data); err != nil {
+			return nil, err
+		}
+	}
+
+	steps, err := StepLog(instance)
+	if err != nil {
+		return nil, err
+	}
+	r.steps = make([]StepState, len(definition.Steps))
+	for i, step := range definition.Steps {
+		r.steps[i] = StepState{Name: step.Name, Status: StepPending}
+		if i < len(steps) && steps[i].Name == step.Name {
+			r.steps[i] = steps[i]
+		}
+	}
+	return r, nil
+}
+
+// advance executes the saga from its current step until it ends or a step has to wait for a retry
+func (o *Orchestrator) advance(ctx context.Context, r *run) error {
+	definition, instance := r.definition, r.instance
+
+	var failure error
+	for instance.Status == StatusRunning {
+		if instance.Step >= len(definition.Steps) {
+			o.end(r, StatusCompleted)
+			return o.save(r)
+		}
+
+		if err := o.renew(r); err != nil {
+			return err
+		}
+		err := definition.Steps[instance.Step].Execute(ctx, r.data)
+		if err == nil {
+			r.mark(instance.Step, StepCompleted, nil)
+			instance.Step++
+			instance.Attempts = 0
+			instance.LastError = nil
+			if err := o.save(r); err != nil {
+				return err
+			}
+			continue
+		}
+
+		failure = err
+		if !IsPermanent(err) && (definition.ForwardOnly || instance.Attempts+1 < definition.MaxAttempts) {
+			r.mark(instance.Step, StepRetrying, err)
+			return o.retry(r, err)
+		}
+
+		r.mark(instance.Step, StepFailed, err)
+		instance.Attempts = 0
+		instance.LastError = errorText(err)
+		if definition.ForwardOnly {
+			slog.ErrorContext(ctx, "Saga failed and needs an operator", "saga_type", instance.SagaType, "saga_id", instance.ID, "step", instance.StepName, "error", err)
+			o.end(r, StatusFailed)
+			if saveErr := o.save(r); saveErr != nil {
+				return saveErr
+			}
+			return err
+		}
+
+		// The failed step is compensated too, as a timed out call may still have taken effect
+		slog.WarnContext(ctx, "Saga failed, compensating", "saga_type", instance.SagaType, "saga_id", instance.ID, "step", instance.StepName, "error", err)
+		instance.Status = StatusCompensating
+		if err := o.save(r); err != nil {
+			return err
+		}
+	}
+
+	for instance.Status == StatusCompensating {
+		if instance.Step < 0 {
+			o.end(r, StatusCompensated)
+			if err := o.save(r); err != nil {
+				return err
+			}
+			return failure
+		}
+
+		if compensate := definition.Steps[instance.Step].Compensate; compensate != nil {
+			if err := o.renew(r); err != nil {
+				return err
+			}
+			if err := compensate(ctx, r.data); err != nil {
+				r.mark(instance.Step, StepCompensating, err)
+				if IsPermanent(err) {
+					slog.ErrorContext(ctx, "Saga cannot compensate step", "saga_type", instance.SagaType, "saga_id", instance.ID, "step", instance.StepName, "error", err)
+					instance.LastError = errorText(err)
+					o.end(r, StatusFailed)
+					if saveErr := o.save(r); saveErr != nil {
+						return saveErr
+					}
+					return err
+				}
+				retryErr := o.retry(r, err)
+				if failure != nil {
+					return failure
+				}
+				return retryErr
+			}
+		}
+
+		r.mark(instance.Step, StepCompensated, nil)
+		instance.Step--
+		instance.Attempts = 0
+		if err := o.save(r); err != nil {
+			return err
+		}
+	}
+	return failure
+}
+
+// renew extends the lease before a step runs, so a saga advancing through many steps is not taken over by another
+// instance. It fails when the saga was taken over or cancelled since the last write.
+func (o *Orchestrator) renew(r *run) error {
+	lockedUntil := time.Now().Add(o.cfg.Lease)
+	r.instance.LockedUntil = &lockedUntil
+	return o.save(r)
+}
+
+// retry records a failed attempt of the current step and releases the lease until the backoff has passed
+func (o *Orchestrator) retry(r *run, err error) error {
+	instance := r.instance
+	instance.Attempts++
+	instance.NextAttemptAt = time.Now().Add(o.backoff(instance.Attempts))
+	instance.LockedUntil = nil
+	instance.LastError = errorText(err)
+	r.steps[instance.Step].Attempts = instance.Attempts
+	if saveErr := o.save(r); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// end moves the saga to a final status and drops data that must not outlive it
+func (o *Orchestrator) end(r *run, status string) {
+	now := time.Now()
+	r.instance.Status = status
+	r.instance.EndedAt = &now
+	r.instance.LockedUntil = nil
+	for _, key := range r.definition.Sensitive {
+		delete(r.data, key)
+	}
+}
+
+// save persists the saga state
+func (o *Orchestrator) save(r *run) error {
+	if err := o.encode(r); err != nil {
+		return err
+	}
+	return o.store.Save(r.instance)
+}
+
+// encode writes the data, step log and current step name into the saga record
+func (o *Orchestrator) encode(r *run) error {
+	data, err := json.Marshal(r.data)
+	if err != nil {
+		return err
+	}
+	steps, err := json.Marshal(r.steps)
+	if err != nil {
+		return err
+	}
+
+	instance := r.instance
+	instance.Data = data
+	instance.StepLog = steps
+	instance.StepName = ""
+	if instance.EndedAt == nil && instance.Step >= 0 && instance.Step < len(r.definition.Steps) {
+		instance.StepName = r.definition.Steps[instance.Step].Name
+	}
+	return nil
+}
+
+// mark records the status of a step in the step log
+func (r *run) mark(index int, status string, err error) {
+	if index < 0 || index >= len(r.steps) {
+		return
+	}
+	now := time.Now()
+	r.steps[index].Status = status
+	r.steps[index].UpdatedAt = &now
+	switch {
+	case err != nil:
+		r.steps[index].LastError = err.Error()
+	case status == StepCompleted:
+		r.steps[index].LastError = ""
+	}
+}
+
+// backoff returns the exponential retry delay after the given number of failed attempts
+func (o *Orchestrator) backoff(attempts int) time.Duration {
+	delay := time.Duration(math.Pow(2, float64(attempts))) * time.Second
+	if delay <= 0 || delay > o.cfg.MaxBackoff {
+		return o.cfg.MaxBackoff
+	}
+	return delay
+}
+
+// errorText returns the message of err for the saga record
+func errorText(err error) *string {
+	message := err.Error()
+	return &message
+}
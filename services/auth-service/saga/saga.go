@@ -0,0 +1,109 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"time"
+)
+
+// Saga statuses
+const (
+	StatusRunning      = "running"      // Executing steps
+	StatusCompensating = "compensating" // Undoing the steps of a failed saga
+	StatusCompleted    = "completed"    // Every step succeeded
+	StatusCompensated  = "compensated"  // A step failed and its predecessors were undone
+	StatusFailed       = "failed"       // A step or compensation failed permanently and needs an operator
+	StatusCancelled    = "cancelled"    // Cancelled before a step that may not be cancelled ran
+)
+
+// Step statuses shown in the step log
+const (
+	StepPending      = "pending"      // Not started
+	StepRetrying     = "retrying"     // Failed and waiting to be retried
+	StepCompleted    = "completed"    // Executed successfully
+	StepFailed       = "failed"       // Failed permanently or ran out of attempts
+	StepCompensating = "compensating" // Compensation failed and waits to be retried
+	StepCompensated  = "compensated"  // Undone
+	StepCancelled    = "cancelled"    // The saga was cancelled while this step was pending
+)
+
+// StepState is the progress of one step, kept with the saga so operators can see where it stands
+type StepState struct {
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	Attempts  int        `json:"attempts,omitempty"`
+	LastError string     `json:"lastError,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// StepLog decodes the progress of every step of a saga
+func StepLog(instance *entities.SagaInstance) ([]StepState, error) {
+	var steps []StepState
+	if len(instance.StepLog) == 0 {
+		return steps, nil
+	}
+	err := json.Unmarshal(instance.StepLog, &steps)
+	return steps, err
+}
+
+// Data carries the inputs and outputs of the steps. It is persisted after every step,
+// so a resumed saga sees what earlier steps produced.
+type Data map[string]string
+
+// Step is one local transaction of a saga and the action that undoes it.
+// Both run at least once and must therefore be idempotent; Compensate must also treat work
+// that was never done as undone, because it runs for the failed step as well.
+type Step struct {
+	Name       string
+	Execute    func(ctx context.Context, data Data) error
+	Compensate func(ctx context.Context, data Data) error // nil when the step leaves nothing to undo
+}
+
+// Definition describes a type of saga
+type Definition struct {
+	Type        string
+	Steps       []Step
+	MaxAttempts int      // Attempts of a step before the saga compensates, 5 when zero
+	Sensitive   []string // Data keys removed once the saga has ended, e.g. password hashes
+
+	// ForwardOnly sagas cannot be undone: failed steps are retried until they succeed,
+	// and a permanent failure leaves the saga failed for an operator instead of compensating
+	ForwardOnly bool
+
+	// CancellableSteps is the number of leading steps the saga may be cancelled before, e.g. a grace period
+	CancellableSteps int
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as a failure that retrying cannot fix, e.g. a rejected request.
+// A step failing permanently is compensated at once; other failures are retried with backoff.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Cause returns the error a step failed with, without the Permanent marker
+func Cause(err error) error {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return permanent.err
+	}
+	return err
+}
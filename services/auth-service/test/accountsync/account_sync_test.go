@@ -0,0 +1,150 @@
+package accountsync
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/messaging"
+)
+
+func TestCheckAccountStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status userservice.AccountStatusResponse
+		want   error
+	}{
+		{"active account", userservice.AccountStatusResponse{UserID: "u1", Exists: true, Active: true}, nil},
+		{"inactive account", userservice.AccountStatusResponse{UserID: "u1", Exists: true}, errors.ErrAccountDisabled},
+		{"deleted account", userservice.AccountStatusResponse{UserID: "u1", Exists: true, Active: true, Deleted: true}, errors.ErrAccountDisabled},
+		{"unknown account", userservice.AccountStatusResponse{}, errors.ErrAccountDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, services.CheckAccountStatus(tt.status))
+		})
+	}
+}
+
+// staticStore hands out a fixed list of events once
+type staticStore struct {
+	mu     sync.Mutex
+	events []entities.OutboxEvent
+}
+
+func (s *staticStore) ClaimPending(int, time.Duration) ([]entities.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := s.events
+	s.events = nil
+	return events, nil
+}
+func (s *staticStore) MarkPublished(string) error                 { return nil }
+func (s *staticStore) MarkFailed(string, time.Time, string) error { return nil }
+
+// loginEvent builds a login_succeeded outbox event that occurred at the given time
+func loginEvent(t *testing.T, email string, at time.Time) entities.OutboxEvent {
+	t.Helper()
+	event, err := messaging.NewOutboxEvent(constants.EventLoginSucceeded, "user-1", map[string]string{
+		"userId": "user-1",
+		"email":  email,
+		"method": constants.LoginMethodPassword,
+	})
+	require.NoError(t, err)
+
+	var envelope map[string]interface{}
+	require.NoError(t, json.Unmarshal(event.Payload, &envelope))
+	envelope["occurredAt"] = at
+	event.Payload, err = json.Marshal(envelope)
+	require.NoError(t, err)
+	return *event
+}
+
+// The last login in user-service is eventually consistent: login events reach it at least once,
+// possibly late, duplicated or out of order. Every event carries the account email and the login time,
+// so a consumer that keeps the newest time per email converges on the latest login.
+func TestLoginEvents_ConvergeOnLatestLogin(t *testing.T) {
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true, JetStream: true, StoreDir: t.TempDir()})
+	require.NoError(t, err)
+	go srv.Start()
+	require.True(t, srv.ReadyForConnections(5*time.Second))
+	defer srv.Shutdown()
+
+	conn, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+	ctx := context.Background()
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{Name: "AUTH", Subjects: []string{"auth.>"}})
+	require.NoError(t, err)
+
+	broker, err := messaging.NewNATSBroker(srv.ClientURL(), true)
+	require.NoError(t, err)
+	defer broker.Close()
+
+	base := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	first := loginEvent(t, "john@example.com", base)
+	latest := loginEvent(t, "john@example.com", base.Add(time.Hour))
+
+	// The newer login is relayed first and the older one is relayed twice
+	store := &staticStore{events: []entities.OutboxEvent{latest, first, first}}
+	_, err = messaging.NewRelay(store, broker, messaging.RelayConfig{}).RelayOnce(ctx)
+	require.NoError(t, err)
+
+	// The duplicate publish of the older login is dropped by its message ID
+	stream, err := js.Stream(ctx, "AUTH")
+	require.NoError(t, err)
+	info, err := stream.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), info.State.Msgs)
+
+	consumer, err := js.CreateOrUpdateConsumer(ctx, "AUTH", jetstream.ConsumerConfig{
+		Durable:       "user-service-login",
+		FilterSubject: constants.EventLoginSucceeded,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	require.NoError(t, err)
+
+	// The consumer fails on the first delivery and receives that event again
+	lastLogin := map[string]time.Time{}
+	acked, failedOnce := 0, false
+	for deadline := time.Now().Add(5 * time.Second); acked < 2 && time.Now().Before(deadline); {
+		batch, err := consumer.Fetch(10, jetstream.FetchMaxWait(500*time.Millisecond))
+		require.NoError(t, err)
+		for msg := range batch.Messages() {
+			if !failedOnce {
+				failedOnce = true
+				require.NoError(t, msg.Nak())
+				continue
+			}
+
+			var envelope struct {
+				OccurredAt time.Time         `json:"occurredAt"`
+				Data       map[string]string `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(msg.Data(), &envelope))
+			if envelope.OccurredAt.After(lastLogin[envelope.Data["email"]]) {
+				lastLogin[envelope.Data["email"]] = envelope.OccurredAt
+			}
+			require.NoError(t, msg.Ack())
+			acked++
+		}
+	}
+
+	require.Equal(t, 2, acked)
+	assert.True(t, base.Add(time.Hour).Equal(lastLogin["john@example.com"]))
+}
@@ -0,0 +1,151 @@
+package apiclients
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Mir00r/auth-service/apiclients"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	"github.com/Mir00r/auth-service/apiclients/userservice/userinternalv1"
+)
+
+// fakeUserInternal serves the internal gRPC API of user-service, failing with err when it is set
+type fakeUserInternal struct {
+	userinternalv1.UnimplementedUserInternalServiceServer
+	err   error
+	calls atomic.Int32
+	md    atomic.Pointer[metadata.MD]
+	left  atomic.Int64 // Time left until the deadline of the last call
+}
+
+func (f *fakeUserInternal) ValidateCredentials(ctx context.Context, req *userinternalv1.ValidateCredentialsRequest) (*userinternalv1.ValidateCredentialsResponse, error) {
+	f.calls.Add(1)
+	md, _ := metadata.FromIncomingContext(ctx)
+	f.md.Store(&md)
+	if deadline, ok := ctx.Deadline(); ok {
+		f.left.Store(int64(time.Until(deadline)))
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &userinternalv1.ValidateCredentialsResponse{User: &userinternalv1.User{
+		Id:        "u1",
+		Email:     req.GetEmail(),
+		IsActive:  true,
+		CreatedAt: timestamppb.New(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+	}}, nil
+}
+
+// grpcServer serves the fake on a loopback port and returns its address
+func grpcServer(t *testing.T, fake *fakeUserInternal) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	userinternalv1.RegisterUserInternalServiceServer(server, fake)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+// newGRPCInternalAPI returns the internal API over a gRPC connection to the instances
+func newGRPCInternalAPI(t *testing.T, instances ...string) userservice.InternalAPI {
+	t.Helper()
+	upstreams := apiclients.NewUpstreams(apiclients.NewStaticUpstream(upstream, "", instances...))
+	conn, err := apiclients.NewGRPCConn(upstreams, upstream, testPolicy(),
+		apiclients.GRPCCredentials{Username: "internal", Password: "secret"},
+		userinternalv1.UserInternalService_ServiceDesc.ServiceName)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return userservice.NewGRPCInternalAPI(conn)
+}
+
+func TestGRPCInternalAPI_ValidatesCredentials(t *testing.T) {
+	fake := &fakeUserInternal{}
+	api := newGRPCInternalAPI(t, grpcServer(t, fake))
+
+	profile, err := api.ValidateCredentials(context.Background(), "a@b.c", "secret")
+
+	require.NoError(t, err)
+	assert.Equal(t, "u1", profile.ID)
+	assert.Equal(t, "a@b.c", profile.Email)
+	assert.True(t, profile.IsActive)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), profile.CreatedAt)
+	assert.Nil(t, profile.LastLogin)
+}
+
+func TestGRPCInternalAPI_SendsCredentialsRequestIDAndDeadline(t *testing.T) {
+	fake := &fakeUserInternal{}
+	api := newGRPCInternalAPI(t, grpcServer(t, fake))
+
+	_, err := api.ValidateCredentials(context.Background(), "a@b.c", "secret")
+
+	require.NoError(t, err)
+	md := *fake.md.Load()
+	assert.Equal(t, []string{"Basic " + base64.StdEncoding.EncodeToString([]byte("internal:secret"))}, md.Get("authorization"))
+	assert.Len(t, md.Get("x-request-id"), 1)
+	// The call carries the timeout of the policy although the caller set no deadline
+	assert.Greater(t, time.Duration(fake.left.Load()), time.Duration(0))
+	assert.LessOrEqual(t, time.Duration(fake.left.Load()), testPolicy().Timeout)
+}
+
+func TestGRPCInternalAPI_ContinuesRequestID(t *testing.T) {
+	fake := &fakeUserInternal{}
+	api := newGRPCInternalAPI(t, grpcServer(t, fake))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-1")
+
+	_, err := api.ValidateCredentials(ctx, "a@b.c", "secret")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"req-1"}, (*fake.md.Load()).Get("x-request-id"))
+}
+
+func TestGRPCInternalAPI_ReturnsStatusAsHTTPStatusError(t *testing.T) {
+	fake := &fakeUserInternal{err: status.Error(codes.Unauthenticated, "Invalid credentials")}
+	api := newGRPCInternalAPI(t, grpcServer(t, fake))
+
+	_, err := api.ValidateCredentials(context.Background(), "a@b.c", "wrong")
+
+	var statusErr *apiclients.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusUnauthorized, statusErr.StatusCode)
+	assert.Equal(t, "Invalid credentials", statusErr.Body.Message)
+	assert.Equal(t, int32(1), fake.calls.Load(), "a definite answer is not retried")
+}
+
+func TestGRPCInternalAPI_BalancesAcrossInstances(t *testing.T) {
+	first, second := &fakeUserInternal{}, &fakeUserInternal{}
+	api := newGRPCInternalAPI(t, grpcServer(t, first), grpcServer(t, second))
+
+	// round_robin only spreads the calls once it has connected to both instances
+	require.Eventually(t, func() bool {
+		_, err := api.ValidateCredentials(context.Background(), "a@b.c", "secret")
+		require.NoError(t, err)
+		return first.calls.Load() > 0 && second.calls.Load() > 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestGRPCInternalAPI_RetriesUnavailableInstance(t *testing.T) {
+	failing := &fakeUserInternal{err: status.Error(codes.Unavailable, "draining")}
+	healthy := &fakeUserInternal{}
+	api := newGRPCInternalAPI(t, grpcServer(t, failing), grpcServer(t, healthy))
+
+	for i := 0; i < 10; i++ {
+		_, err := api.ValidateCredentials(context.Background(), "a@b.c", "secret")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(10), healthy.calls.Load())
+}
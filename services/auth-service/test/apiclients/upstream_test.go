@@ -0,0 +1,345 @@
+package apiclients
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/Mir00r/auth-service/apiclients"
+	config "github.com/Mir00r/auth-service/configs"
+)
+
+// countingServer answers every call with the status and counts the calls
+func countingServer(t *testing.T, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// writeEndpoints atomically replaces the endpoints file with the given lines
+func writeEndpoints(t *testing.T, path string, lines string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path+".tmp", []byte(lines), 0o600))
+	require.NoError(t, os.Rename(path+".tmp", path))
+}
+
+func TestSend_BalancesAcrossInstances(t *testing.T) {
+	first, firstCalls := countingServer(t, http.StatusOK)
+	second, secondCalls := countingServer(t, http.StatusOK)
+	client := newClient(testPolicy(), first, second)
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, client.Send(context.Background(), http.MethodGet, upstream, "/", nil, nil))
+	}
+
+	assert.Equal(t, int32(2), firstCalls.Load())
+	assert.Equal(t, int32(2), secondCalls.Load())
+}
+
+func TestSend_RetriesOnAnotherInstance(t *testing.T) {
+	failing, failingCalls := countingServer(t, http.StatusServiceUnavailable)
+	healthy, healthyCalls := countingServer(t, http.StatusOK)
+	client := newClient(testPolicy(), failing, healthy)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, client.Send(context.Background(), http.MethodGet, upstream, "/", nil, nil))
+	}
+
+	// Each call reaches the healthy instance within its first two attempts and none hits the failing one twice
+	assert.Equal(t, int32(2), healthyCalls.Load())
+	assert.LessOrEqual(t, failingCalls.Load(), int32(2))
+}
+
+func TestSend_EjectsInstanceWithOpenBreaker(t *testing.T) {
+	failing, failingCalls := countingServer(t, http.StatusInternalServerError)
+	healthy, healthyCalls := countingServer(t, http.StatusOK)
+	client := newClient(testPolicy(), failing, healthy)
+
+	for i := 0; i < 10; i++ {
+		_ = client.Send(context.Background(), http.MethodPost, upstream, "/", nil, nil)
+	}
+
+	// Two failures open the breaker of the failing instance, after which every call goes to the healthy one
+	assert.Equal(t, int32(2), failingCalls.Load())
+	assert.Equal(t, int32(8), healthyCalls.Load())
+	for _, stats := range client.Stats() {
+		assert.Equal(t, upstream, stats.Upstream)
+		if stats.Target == failing.Listener.Addr().String() {
+			assert.Equal(t, apiclients.BreakerOpen, stats.BreakerState)
+			assert.Equal(t, int64(0), stats.Rejected)
+		}
+	}
+}
+
+func TestSend_UnknownUpstream(t *testing.T) {
+	srv, calls := countingServer(t, http.StatusOK)
+	client := newClient(testPolicy(), srv)
+
+	err := client.Send(context.Background(), http.MethodGet, "unknown", "/", nil, nil)
+
+	assert.ErrorIs(t, err, apiclients.ErrUnknownUpstream)
+	assert.Equal(t, int32(0), calls.Load())
+}
+
+func TestSend_UpstreamWithoutInstances(t *testing.T) {
+	client := newClient(testPolicy())
+
+	err := client.Send(context.Background(), http.MethodGet, upstream, "/", nil, nil)
+
+	assert.ErrorIs(t, err, apiclients.ErrNoInstances)
+}
+
+func TestPolicies_InstanceOverridesUpstream(t *testing.T) {
+	policies := apiclients.Policies{
+		Default: apiclients.Policy{MaxAttempts: 1},
+		Targets: map[string]apiclients.Policy{
+			upstream:        {MaxAttempts: 2},
+			"10.0.0.1:8082": {MaxAttempts: 3},
+		},
+	}
+
+	assert.Equal(t, 3, policies.For(upstream, "10.0.0.1:8082").MaxAttempts)
+	assert.Equal(t, 2, policies.For(upstream, "10.0.0.2:8082").MaxAttempts)
+	assert.Equal(t, 1, policies.For("other", "10.0.0.2:8082").MaxAttempts)
+}
+
+func TestFileResolver_SkipsCommentsAndBlankLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "endpoints")
+	writeEndpoints(t, path, "# user-service\n10.0.0.1:8082\n\n  10.0.0.2:8082  \n")
+
+	instances, err := apiclients.FileResolver{Path: path}.Resolve(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:8082", "10.0.0.2:8082"}, instances)
+}
+
+func TestFileResolver_RejectsEndpointWithoutPort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "endpoints")
+	writeEndpoints(t, path, "10.0.0.1\n")
+
+	_, err := apiclients.FileResolver{Path: path}.Resolve(context.Background())
+
+	assert.Error(t, err)
+}
+
+func TestUpstreams_RunPicksUpChangedEndpoints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "endpoints")
+	writeEndpoints(t, path, "10.0.0.1:8082\n")
+	u := apiclients.NewUpstream(context.Background(), upstream, "http", apiclients.FileResolver{Path: path}, 5*time.Millisecond)
+	require.Equal(t, []string{"10.0.0.1:8082"}, u.Instances())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		apiclients.NewUpstreams(u).Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	writeEndpoints(t, path, "10.0.0.2:8082\n10.0.0.3:8082\n")
+	require.Eventually(t, func() bool {
+		return len(u.Instances()) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"10.0.0.2:8082", "10.0.0.3:8082"}, u.Instances())
+
+	// An empty list is taken for a discovery glitch and keeps the known instances
+	writeEndpoints(t, path, "# drained\n")
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, []string{"10.0.0.2:8082", "10.0.0.3:8082"}, u.Instances())
+}
+
+func TestUpstreams_WatchPicksUpChangedEndpointsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "endpoints")
+	writeEndpoints(t, path, "10.0.0.1:8082\n")
+	// Never refreshed on a timer, so only watching the file can pick up the change
+	u := apiclients.NewUpstream(context.Background(), upstream, "http", apiclients.FileResolver{Path: path}, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		apiclients.NewUpstreams(u).Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// The file is replaced until the change is seen, as the watch starts in the background
+	require.Eventually(t, func() bool {
+		if err := os.WriteFile(path+".tmp", []byte("10.0.0.2:8082\n"), 0o600); err == nil {
+			_ = os.Rename(path+".tmp", path)
+		}
+		return slices.Equal(u.Instances(), []string{"10.0.0.2:8082"})
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSRVResolver_ReturnsPriorityAndWeightOfTargets(t *testing.T) {
+	resolver := apiclients.SRVResolver{Name: srvName, Resolver: srvServer(t,
+		net.SRV{Target: "a.internal.", Port: 8082, Priority: 10, Weight: 3},
+		net.SRV{Target: "b.internal.", Port: 8082, Priority: 20, Weight: 0},
+	)}
+
+	instances, err := resolver.ResolveWeighted(context.Background())
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []apiclients.WeightedInstance{
+		{Address: "a.internal:8082", Priority: 10, Weight: 3},
+		{Address: "b.internal:8082", Priority: 20, Weight: 0},
+	}, instances)
+}
+
+func TestSend_SplitsCallsByWeightOfSRVTargets(t *testing.T) {
+	heavy, heavyCalls := countingServer(t, http.StatusOK)
+	light, lightCalls := countingServer(t, http.StatusOK)
+	backup, backupCalls := countingServer(t, http.StatusOK)
+	client := newSRVClient(t,
+		srvTarget(heavy, 10, 3),
+		srvTarget(light, 10, 1),
+		srvTarget(backup, 20, 1),
+	)
+
+	for i := 0; i < 8; i++ {
+		require.NoError(t, client.Send(context.Background(), http.MethodGet, upstream, "/", nil, nil))
+	}
+
+	assert.Equal(t, int32(6), heavyCalls.Load())
+	assert.Equal(t, int32(2), lightCalls.Load())
+	assert.Equal(t, int32(0), backupCalls.Load())
+}
+
+func TestSend_FallsBackToHigherPriorityValueOfSRVTargets(t *testing.T) {
+	primary, primaryCalls := countingServer(t, http.StatusInternalServerError)
+	backup, backupCalls := countingServer(t, http.StatusOK)
+	client := newSRVClient(t, srvTarget(primary, 10, 1), srvTarget(backup, 20, 1))
+
+	for i := 0; i < 10; i++ {
+		_ = client.Send(context.Background(), http.MethodPost, upstream, "/", nil, nil)
+	}
+
+	// The backup takes the calls only once two failures have opened the breaker of the primary
+	assert.Equal(t, int32(2), primaryCalls.Load())
+	assert.Equal(t, int32(8), backupCalls.Load())
+}
+
+func TestUpstreamsFromConfig_RejectsInvalidConfiguration(t *testing.T) {
+	cases := map[string]config.Config{
+		"upstream backend: no endpoints configured": {
+			Upstreams: map[string]config.UpstreamConfig{"backend": {Discovery: apiclients.DiscoveryStatic}},
+		},
+		`internal security base URL "user-service:8082" has no host`: {
+			InternalSecurity: config.InternalSecurityConfig{BaseUrl: "user-service:8082"},
+		},
+	}
+	for want, cfg := range cases {
+		_, err := apiclients.UpstreamsFromConfig(context.Background(), cfg)
+		assert.EqualError(t, err, want)
+	}
+}
+
+// srvName is the SRV record served by srvServer
+const srvName = "_http._tcp.backend.internal."
+
+// srvTarget returns an SRV target for the server, named localhost as SRV targets cannot be IP addresses
+func srvTarget(srv *httptest.Server, priority, weight uint16) net.SRV {
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+	return net.SRV{Target: "localhost.", Port: uint16(port), Priority: priority, Weight: weight}
+}
+
+// newSRVClient returns a client calling the targets of an SRV record as the instances of one upstream
+func newSRVClient(t *testing.T, targets ...net.SRV) apiclients.WebClient {
+	t.Helper()
+	resolver := apiclients.SRVResolver{Name: srvName, Resolver: srvServer(t, targets...)}
+	u := apiclients.NewUpstream(context.Background(), upstream, "http", resolver, 0)
+	require.Len(t, u.Instances(), len(targets))
+	return apiclients.WebClient{
+		Client:    &http.Client{},
+		Upstreams: apiclients.NewUpstreams(u),
+	}.WithPolicies(apiclients.Policies{Default: testPolicy()})
+}
+
+// srvServer starts a DNS server answering the SRV queries for srvName with the records, and returns a resolver
+// asking it
+func srvServer(t *testing.T, records ...net.SRV) *net.Resolver {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if answer, err := answerSRV(buf[:n], records); err == nil {
+				_, _ = conn.WriteTo(answer, addr)
+			}
+		}
+	}()
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "udp", conn.LocalAddr().String())
+		},
+	}
+}
+
+// answerSRV builds the answer to a DNS query, with the records when it asks for srvName
+func answerSRV(query []byte, records []net.SRV) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true})
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(question); err != nil {
+		return nil, err
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if question.Type == dnsmessage.TypeSRV && strings.EqualFold(question.Name.String(), srvName) {
+		for _, record := range records {
+			err := builder.SRVResource(
+				dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60},
+				dnsmessage.SRVResource{Priority: record.Priority, Weight: record.Weight, Port: record.Port, Target: dnsmessage.MustNewName(record.Target)},
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return builder.Finish()
+}
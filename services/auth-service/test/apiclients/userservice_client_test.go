@@ -0,0 +1,109 @@
+package apiclients
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/apiclients"
+	"github.com/Mir00r/auth-service/apiclients/openapi"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+)
+
+// userServiceSpec is the OpenAPI spec of user-service, next to auth-service in the repository
+const userServiceSpec = "../../../user-service/docs/openapi.yaml"
+
+// newUserClient returns a user-service client calling the server
+func newUserClient(srv *httptest.Server) *userservice.Client {
+	client := userservice.NewClient(newClient(testPolicy(), srv))
+	client.Upstream = upstream
+	return client
+}
+
+func TestUserServiceClient_MatchesSpec(t *testing.T) {
+	spec, err := os.ReadFile(userServiceSpec)
+	if os.IsNotExist(err) {
+		t.Skip("the user-service spec is not part of this checkout")
+	}
+	require.NoError(t, err)
+
+	generated, err := userservice.Generate(spec)
+	require.NoError(t, err)
+	committed, err := os.ReadFile("../../apiclients/userservice/client.gen.go")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(generated), string(committed),
+		"apiclients/userservice is stale; run go generate ./apiclients/userservice")
+}
+
+func TestUserServiceClient_UnwrapsEnvelope(t *testing.T) {
+	var request userservice.ValidateRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/internal/user/validate", r.URL.Path)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		_, _ = w.Write([]byte(`{"error":false,"code":200,"message":"OK","data":{"id":"u1","email":"a@b.c","isActive":true,"isMfaEnabled":true}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	profile, err := newUserClient(srv).ValidateUser(context.Background(), userservice.ValidateRequest{Email: "a@b.c", Password: "secret"})
+
+	require.NoError(t, err)
+	assert.Equal(t, userservice.ValidateRequest{Email: "a@b.c", Password: "secret"}, request)
+	assert.Equal(t, "u1", profile.ID)
+	assert.True(t, profile.IsActive)
+	assert.True(t, profile.MFAEnabled)
+}
+
+func TestUserServiceClient_ProtectedCallSendsBearerTokenAndEscapesPath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer user-jwt", r.Header.Get("Authorization"))
+		assert.Equal(t, "/v1/protected/user/a%2Fb", r.URL.EscapedPath())
+		_, _ = w.Write([]byte(`{"id":"a/b","name":"Ann"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	profile, err := newUserClient(srv).GetUser(context.Background(), "user-jwt", "a/b")
+
+	require.NoError(t, err)
+	assert.Equal(t, "Ann", profile.Name)
+}
+
+func TestUserServiceClient_ReturnsStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":true,"code":404,"codeStatus":"Not Found","message":"User not found"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	err := newUserClient(srv).DeactivateUser(context.Background(), "u1")
+
+	var statusErr *apiclients.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	assert.Equal(t, "User not found", statusErr.Body.Message)
+}
+
+func TestGenerate_RejectsUnsupportedParameters(t *testing.T) {
+	spec := `
+openapi: 3.0.3
+info: {title: test, version: 1.0.0}
+paths:
+  /users:
+    get:
+      operationId: listUsers
+      parameters:
+        - {name: page, in: query, schema: {type: integer}}
+      responses:
+        '200': {description: ok}
+`
+	_, err := openapi.Generate([]byte(spec), openapi.Config{Package: "test"})
+
+	assert.ErrorContains(t, err, `query parameter "page" is not supported`)
+}
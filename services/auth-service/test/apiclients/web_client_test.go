@@ -0,0 +1,293 @@
+package apiclients
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/apiclients"
+	"github.com/Mir00r/auth-service/constants"
+)
+
+// testPolicy retries quickly and opens the breaker after two failures
+func testPolicy() apiclients.Policy {
+	return apiclients.Policy{
+		Timeout:          time.Second,
+		MaxAttempts:      3,
+		InitialBackoff:   time.Millisecond,
+		MaxBackoff:       5 * time.Millisecond,
+		QueueTimeout:     20 * time.Millisecond,
+		FailureThreshold: 2,
+		OpenTimeout:      50 * time.Millisecond,
+		HalfOpenProbes:   1,
+	}
+}
+
+// upstream is the name under which the test servers are called
+const upstream = "backend"
+
+// newClient returns a client with the given policy for every instance, calling the servers as instances of one upstream
+func newClient(policy apiclients.Policy, servers ...*httptest.Server) apiclients.WebClient {
+	instances := make([]string, 0, len(servers))
+	for _, srv := range servers {
+		instances = append(instances, srv.Listener.Addr().String())
+	}
+	return apiclients.WebClient{
+		Client:    &http.Client{},
+		Upstreams: apiclients.NewUpstreams(apiclients.NewStaticUpstream(upstream, "http", instances...)),
+	}.WithPolicies(apiclients.Policies{Default: policy})
+}
+
+// statusServer answers every call with the statuses in order, repeating the last one
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		status := statuses[min(n, len(statuses)-1)]
+		w.WriteHeader(status)
+		if status >= 300 {
+			_, _ = w.Write([]byte(`{"error":true,"message":"upstream says no"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"message":"ok"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestSend_RetriesIdempotentCallOnUnavailable(t *testing.T) {
+	srv, calls := statusServer(t, http.StatusServiceUnavailable, http.StatusOK)
+	client := newClient(testPolicy(), srv)
+
+	var response struct {
+		Message string `json:"message"`
+	}
+	err := client.Send(context.Background(), http.MethodGet, upstream, "/status", nil, &response)
+
+	require.NoError(t, err)
+	assert.Equal(t, "ok", response.Message)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, int64(1), client.Stats()[0].Retries)
+}
+
+func TestSend_DoesNotRetryPost(t *testing.T) {
+	srv, calls := statusServer(t, http.StatusServiceUnavailable, http.StatusOK)
+	client := newClient(testPolicy(), srv)
+
+	err := client.Send(context.Background(), http.MethodPost, upstream, "/users", map[string]string{"name": "a"}, nil)
+
+	require.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestSend_RetriesPostMarkedIdempotent(t *testing.T) {
+	srv, calls := statusServer(t, http.StatusBadGateway, http.StatusOK)
+	client := newClient(testPolicy(), srv)
+
+	err := client.Send(context.Background(), http.MethodPost, upstream, "/validate", map[string]string{"email": "a@b.c"}, nil, apiclients.Idempotent())
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestSend_DoesNotRetryClientErrors(t *testing.T) {
+	srv, calls := statusServer(t, http.StatusNotFound)
+	client := newClient(testPolicy(), srv)
+
+	err := client.Send(context.Background(), http.MethodGet, upstream, "/missing", nil, nil)
+
+	var statusErr *apiclients.StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestSend_StatusErrorCarriesDecodedBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"error":true,"code":409,"codeStatus":"Conflict","message":"Email already exists"}`))
+	}))
+	t.Cleanup(srv.Close)
+	client := newClient(testPolicy(), srv)
+
+	err := client.Send(context.Background(), http.MethodPost, upstream, "/users", nil, nil)
+
+	var statusErr *apiclients.StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusConflict, statusErr.StatusCode)
+	assert.Equal(t, strings.TrimPrefix(srv.URL, "http://"), statusErr.Target)
+	require.NotNil(t, statusErr.Body)
+	assert.Equal(t, 409, statusErr.Body.Code)
+	assert.Equal(t, "Email already exists", statusErr.Body.Message)
+	assert.Contains(t, err.Error(), "Email already exists")
+}
+
+func TestSend_StatusErrorWithoutEnvelope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+	client := newClient(apiclients.Policy{Timeout: time.Second}, srv)
+
+	err := client.Send(context.Background(), http.MethodPost, upstream, "/users", nil, nil)
+
+	var statusErr *apiclients.StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Nil(t, statusErr.Body)
+	assert.Equal(t, "bad gateway\n", statusErr.RawBody)
+}
+
+func TestSend_BreakerOpensAndRecoversThroughProbe(t *testing.T) {
+	var healthy atomic.Bool
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	client := newClient(testPolicy(), srv)
+
+	// Two consecutive failures open the breaker
+	for i := 0; i < 2; i++ {
+		require.Error(t, client.Send(context.Background(), http.MethodPost, upstream, "/", nil, nil))
+	}
+	assert.Equal(t, apiclients.BreakerOpen, client.Stats()[0].BreakerState)
+
+	// While open, calls are rejected without reaching the target
+	err := client.Send(context.Background(), http.MethodPost, upstream, "/", nil, nil)
+	assert.ErrorIs(t, err, apiclients.ErrCircuitOpen)
+	var unavailable *apiclients.UnavailableError
+	require.True(t, errors.As(err, &unavailable))
+	assert.Equal(t, int32(2), calls.Load())
+
+	// After the open timeout a successful probe closes it again
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, apiclients.BreakerHalfOpen, client.Stats()[0].BreakerState)
+	healthy.Store(true)
+	require.NoError(t, client.Send(context.Background(), http.MethodPost, upstream, "/", nil, nil))
+
+	stats := client.Stats()[0]
+	assert.Equal(t, apiclients.BreakerClosed, stats.BreakerState)
+	assert.Equal(t, int64(1), stats.Rejected)
+}
+
+func TestSend_FailedProbeReopensBreaker(t *testing.T) {
+	srv, _ := statusServer(t, http.StatusInternalServerError)
+	client := newClient(testPolicy(), srv)
+
+	for i := 0; i < 2; i++ {
+		require.Error(t, client.Send(context.Background(), http.MethodPost, upstream, "/", nil, nil))
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	err := client.Send(context.Background(), http.MethodPost, upstream, "/", nil, nil)
+	var statusErr *apiclients.StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, apiclients.BreakerOpen, client.Stats()[0].BreakerState)
+	assert.ErrorIs(t, client.Send(context.Background(), http.MethodPost, upstream, "/", nil, nil), apiclients.ErrCircuitOpen)
+}
+
+func TestSend_ClientErrorsDoNotOpenBreaker(t *testing.T) {
+	srv, _ := statusServer(t, http.StatusUnauthorized)
+	client := newClient(testPolicy(), srv)
+
+	for i := 0; i < 5; i++ {
+		require.Error(t, client.Send(context.Background(), http.MethodPost, upstream, "/", nil, nil))
+	}
+	assert.Equal(t, apiclients.BreakerClosed, client.Stats()[0].BreakerState)
+}
+
+func TestSend_BulkheadRejectsWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+
+	policy := testPolicy()
+	policy.MaxConcurrent = 1
+	client := newClient(policy, srv)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, client.Send(context.Background(), http.MethodPost, upstream, "/", nil, nil))
+	}()
+	require.Eventually(t, func() bool {
+		stats := client.Stats()
+		return len(stats) == 1 && stats[0].InFlight == 1
+	}, time.Second, time.Millisecond)
+
+	err := client.Send(context.Background(), http.MethodPost, upstream, "/", nil, nil)
+	assert.ErrorIs(t, err, apiclients.ErrBulkheadFull)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int64(1), client.Stats()[0].Rejected)
+}
+
+func TestSend_CopiesShareTargetState(t *testing.T) {
+	srv, _ := statusServer(t, http.StatusInternalServerError)
+	client := newClient(testPolicy(), srv)
+	copied := client
+
+	for i := 0; i < 2; i++ {
+		require.Error(t, client.Send(context.Background(), http.MethodPost, upstream, "/", nil, nil))
+	}
+	assert.ErrorIs(t, copied.Send(context.Background(), http.MethodPost, upstream, "/", nil, nil), apiclients.ErrCircuitOpen)
+}
+
+func TestSend_PropagatesRemainingDeadline(t *testing.T) {
+	var header atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header.Store(r.Header.Get(constants.RequestTimeoutHeader))
+	}))
+	t.Cleanup(srv.Close)
+	client := newClient(apiclients.Policy{Timeout: 10 * time.Second}, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, client.Send(ctx, http.MethodGet, upstream, "/", nil, nil))
+
+	remaining, err := strconv.Atoi(header.Load().(string))
+	require.NoError(t, err)
+	assert.Greater(t, remaining, 1000)
+	assert.LessOrEqual(t, remaining, 2000)
+}
+
+func TestSend_StopsWhenCallerGivesUp(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	client := newClient(testPolicy(), srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	err := client.Send(ctx, http.MethodGet, upstream, "/", nil, nil)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// The caller's deadline says nothing about the target, so the breaker keeps counting from zero
+	stats := client.Stats()[0]
+	assert.Equal(t, apiclients.BreakerClosed, stats.BreakerState)
+	assert.Equal(t, 0, stats.ConsecutiveFailures)
+	assert.Equal(t, int64(0), stats.Failures)
+}
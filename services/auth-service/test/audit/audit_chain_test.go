@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/internal/utils"
+)
+
+// buildChain links records the same way AuditRepository.Append does
+func buildChain(n int) []entities.AuditLog {
+	records := make([]entities.AuditLog, 0, n)
+	prevHash := constants.AuditGenesisHash
+	for i := 1; i <= n; i++ {
+		record := entities.AuditLog{
+			Sequence:  int64(i),
+			EventType: constants.AuditLogin,
+			Actor:     "jane@example.com",
+			IP:        "10.0.0.1",
+			UserAgent: "test-agent",
+			Outcome:   constants.AuditOutcomeSuccess,
+			CreatedAt: time.Date(2026, 10, 19, 12, 0, i, 0, time.UTC),
+			PrevHash:  prevHash,
+		}
+		record.Hash = utils.AuditRecordHash(prevHash, &record)
+		prevHash = record.Hash
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestVerifyAuditChain_Intact(t *testing.T) {
+	records := buildChain(5)
+
+	intact, ok := utils.VerifyAuditChain(constants.AuditGenesisHash, 0, records)
+	assert.True(t, ok)
+	assert.Equal(t, 5, intact)
+
+	// Verification can resume from any record, as it does across batches
+	intact, ok = utils.VerifyAuditChain(records[1].Hash, records[1].Sequence, records[2:])
+	assert.True(t, ok)
+	assert.Equal(t, 3, intact)
+}
+
+func TestVerifyAuditChain_DetectsTampering(t *testing.T) {
+	tests := []struct {
+		name          string
+		tamper        func([]entities.AuditLog) []entities.AuditLog
+		expectedIndex int
+	}{
+		{
+			name: "edited field",
+			tamper: func(records []entities.AuditLog) []entities.AuditLog {
+				records[2].Outcome = constants.AuditOutcomeFailure
+				return records
+			},
+			expectedIndex: 2,
+		},
+		{
+			name: "edited field with recomputed hash",
+			tamper: func(records []entities.AuditLog) []entities.AuditLog {
+				records[2].IP = "203.0.113.9"
+				records[2].Hash = utils.AuditRecordHash(records[2].PrevHash, &records[2])
+				return records
+			},
+			expectedIndex: 3,
+		},
+		{
+			name: "chain rebuilt without the chain key",
+			tamper: func(records []entities.AuditLog) []entities.AuditLog {
+				key := config.AppConfig.Audit.ChainKey
+				config.AppConfig.Audit.ChainKey = "guessed-key"
+				defer func() { config.AppConfig.Audit.ChainKey = key }()
+
+				prevHash := constants.AuditGenesisHash
+				for i := range records {
+					records[i].Outcome = constants.AuditOutcomeFailure
+					records[i].PrevHash = prevHash
+					records[i].Hash = utils.AuditRecordHash(prevHash, &records[i])
+					prevHash = records[i].Hash
+				}
+				return records
+			},
+			expectedIndex: 0,
+		},
+		{
+			name: "deleted record",
+			tamper: func(records []entities.AuditLog) []entities.AuditLog {
+				return append(records[:1], records[2:]...)
+			},
+			expectedIndex: 1,
+		},
+		{
+			name: "truncated head",
+			tamper: func(records []entities.AuditLog) []entities.AuditLog {
+				return records[1:]
+			},
+			expectedIndex: 0,
+		},
+		{
+			name: "swapped records",
+			tamper: func(records []entities.AuditLog) []entities.AuditLog {
+				records[3], records[4] = records[4], records[3]
+				return records
+			},
+			expectedIndex: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := tt.tamper(buildChain(5))
+
+			intact, ok := utils.VerifyAuditChain(constants.AuditGenesisHash, 0, records)
+			assert.False(t, ok)
+			assert.Equal(t, tt.expectedIndex, intact)
+		})
+	}
+}
+
+func TestAuditReason(t *testing.T) {
+	assert.Equal(t, constants.AuditReasonInvalidCredentials, services.AuditReason(errors.ErrInvalidCredentials, http.StatusUnauthorized))
+	assert.Equal(t, constants.AuditReasonInvalidCredentials, services.AuditReason(constants.ErrInvalidCredentials, http.StatusOK))
+	assert.Equal(t, constants.AuditReasonInvalidOTP, services.AuditReason(errors.NewAppError(http.StatusUnauthorized, constants.ErrInvalidOTP, nil), http.StatusOK))
+	assert.Equal(t, constants.AuditReasonForbidden, services.AuditReason(errors.ErrLoginNotConfirmed, http.StatusOK))
+	assert.Equal(t, constants.AuditReasonUnauthorized, services.AuditReason(nil, http.StatusUnauthorized))
+	assert.Equal(t, constants.AuditReasonInternalError, services.AuditReason(nil, http.StatusBadGateway))
+}
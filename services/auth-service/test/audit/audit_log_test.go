@@ -0,0 +1,169 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/metrics"
+	"github.com/Mir00r/auth-service/middlewares"
+	"github.com/Mir00r/auth-service/test/testenv"
+)
+
+// recorder is an AuditService keeping the recorded events
+type recorder struct {
+	services.AuditService
+	mu     sync.Mutex
+	events []dtos.AuditEvent
+}
+
+func (r *recorder) Record(event dtos.AuditEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// serveAudited posts body to a route audited with r and returns the body the handler read
+func serveAudited(t *testing.T, r *recorder, body io.Reader) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	var read string
+	router.POST("/login", middlewares.Audit(r, constants.AuditLogin), func(c *gin.Context) {
+		b, err := io.ReadAll(c.Request.Body)
+		require.NoError(t, err)
+		read = string(b)
+		c.Status(http.StatusOK)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/login", body))
+	return read
+}
+
+func TestAuditMiddleware_RecordsSubmittedEmail(t *testing.T) {
+	r := &recorder{}
+	body := `{"email":"jane@example.com","password":"secret"}`
+
+	read := serveAudited(t, r, strings.NewReader(body))
+
+	assert.Equal(t, body, read, "the handler reads the whole body")
+	require.Len(t, r.events, 1)
+	assert.Equal(t, "jane@example.com", r.events[0].Actor)
+}
+
+func TestAuditMiddleware_BoundsTheBodyItReads(t *testing.T) {
+	r := &recorder{}
+	body := `{"email":"jane@example.com","padding":"` + strings.Repeat("x", 1<<20) + `"}`
+
+	read := serveAudited(t, r, bytes.NewReader([]byte(body)))
+
+	assert.Equal(t, body, read, "the handler still reads the whole body")
+	require.Len(t, r.events, 1)
+	assert.Empty(t, r.events[0].Actor, "a body over the limit is not parsed")
+}
+
+// newAuditService returns an audit service storing its log in a database private to the test
+func newAuditService(t *testing.T) (services.AuditService, *gorm.DB) {
+	t.Helper()
+	db := testenv.NewDB(t, []interface{}{&entities.User{}, &entities.AuditLog{}})
+	svc := services.NewAuditService(repositories.NewAuditRepository(db), repositories.NewUserRepository(db), nil)
+	return svc, db
+}
+
+func TestAuditService_ConcurrentRecordsFormOneChain(t *testing.T) {
+	svc, _ := newAuditService(t)
+
+	var wg sync.WaitGroup
+	for i := range 300 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			svc.Record(dtos.AuditEvent{EventType: constants.AuditLogin, Actor: fmt.Sprintf("user-%d", i), Outcome: constants.AuditOutcomeSuccess})
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, svc.Close(context.Background()))
+
+	verification, err := svc.VerifyChain(context.Background(), dtos.AuditChainAnchor{})
+	require.NoError(t, err)
+	assert.True(t, verification.Valid)
+	assert.EqualValues(t, 300, verification.RecordsChecked)
+}
+
+func TestAuditService_RetriesRecordsTheDatabaseRefused(t *testing.T) {
+	svc, db := newAuditService(t)
+	failures := testutil.ToFloat64(metrics.AuditWriteFailures)
+	require.NoError(t, db.Exec("ALTER TABLE auth.audit_log RENAME TO audit_log_offline").Error)
+
+	svc.Record(dtos.AuditEvent{EventType: constants.AuditLogin, Actor: "jane@example.com", Outcome: constants.AuditOutcomeFailure})
+	require.Eventually(t, func() bool { return testutil.ToFloat64(metrics.AuditWriteFailures) > failures }, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, db.Exec("ALTER TABLE auth.audit_log_offline RENAME TO audit_log").Error)
+	require.NoError(t, svc.Close(context.Background()))
+
+	verification, err := svc.VerifyChain(context.Background(), dtos.AuditChainAnchor{})
+	require.NoError(t, err)
+	assert.True(t, verification.Valid)
+	assert.EqualValues(t, 1, verification.RecordsChecked, "the refused record is stored once the database takes it")
+}
+
+func TestAuditService_CloseGivesUpWhenTheDatabaseStaysDown(t *testing.T) {
+	svc, db := newAuditService(t)
+	require.NoError(t, db.Exec("ALTER TABLE auth.audit_log RENAME TO audit_log_offline").Error)
+	svc.Record(dtos.AuditEvent{EventType: constants.AuditLogin, Actor: "jane@example.com", Outcome: constants.AuditOutcomeSuccess})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, svc.Close(ctx), context.DeadlineExceeded)
+}
+
+func TestAuditService_VerifyChainAgainstAnAnchor(t *testing.T) {
+	svc, db := newAuditService(t)
+	for range 3 {
+		svc.Record(dtos.AuditEvent{EventType: constants.AuditLogin, Actor: "jane@example.com", Outcome: constants.AuditOutcomeSuccess})
+	}
+	require.NoError(t, svc.Close(context.Background()))
+
+	verification, err := svc.VerifyChain(context.Background(), dtos.AuditChainAnchor{})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, verification.HeadSequence)
+	anchor := dtos.AuditChainAnchor{Sequence: verification.HeadSequence, Hash: verification.HeadHash}
+
+	verification, err = svc.VerifyChain(context.Background(), anchor)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid, "the anchored head is still there")
+
+	verification, err = svc.VerifyChain(context.Background(), dtos.AuditChainAnchor{Sequence: 2, Hash: anchor.Hash})
+	require.NoError(t, err)
+	assert.False(t, verification.Valid, "the anchored record has another hash")
+	assert.EqualValues(t, 2, verification.FirstInvalidSequence)
+
+	require.NoError(t, db.Exec("DELETE FROM auth.audit_log WHERE sequence = 3").Error)
+
+	verification, err = svc.VerifyChain(context.Background(), dtos.AuditChainAnchor{})
+	require.NoError(t, err)
+	assert.True(t, verification.Valid, "without an anchor a shortened chain is still consistent")
+
+	verification, err = svc.VerifyChain(context.Background(), anchor)
+	require.NoError(t, err)
+	assert.False(t, verification.Valid)
+	assert.True(t, verification.Truncated)
+	assert.EqualValues(t, 2, verification.HeadSequence)
+}
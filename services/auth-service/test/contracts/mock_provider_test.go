@@ -0,0 +1,211 @@
+package contracts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Pact is a pact-style contract: the requests a consumer sends to a provider with the responses it relies on.
+// It follows pact specification 2.0, so the files stay readable by other pact tooling.
+type Pact struct {
+	Consumer     Pacticipant   `json:"consumer"`
+	Provider     Pacticipant   `json:"provider"`
+	Interactions []Interaction `json:"interactions"`
+	Metadata     Metadata      `json:"metadata"`
+}
+
+// Pacticipant names a side of a pact
+type Pacticipant struct {
+	Name string `json:"name"`
+}
+
+// Metadata tells the pact specification a file follows
+type Metadata struct {
+	PactSpecification struct {
+		Version string `json:"version"`
+	} `json:"pactSpecification"`
+}
+
+// Interaction is one request of the consumer with the response it expects, in the state the provider must be in
+type Interaction struct {
+	Description   string   `json:"description"`
+	ProviderState string   `json:"providerState,omitempty"`
+	Request       Request  `json:"request"`
+	Response      Response `json:"response"`
+}
+
+// Request is the request the consumer sends. Headers are the ones the provider relies on; credentials are left
+// out, as the provider verifies with its own.
+type Request struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// Response is the part of the response the consumer relies on. Values below the paths of a "type" matching rule
+// are examples: the provider only has to answer them with the same JSON type.
+type Response struct {
+	Status        int                     `json:"status"`
+	Headers       map[string]string       `json:"headers,omitempty"`
+	Body          json.RawMessage         `json:"body,omitempty"`
+	MatchingRules map[string]MatchingRule `json:"matchingRules,omitempty"`
+}
+
+// MatchingRule relaxes the comparison of the values below a path such as $.body.data.id
+type MatchingRule struct {
+	Match string `json:"match"`
+}
+
+// typeRule marks the values below a path as examples of their type
+var typeRule = MatchingRule{Match: "type"}
+
+// updatePacts rewrites the committed pact files instead of comparing them when set to 1
+const updatePacts = "UPDATE_PACTS"
+
+// mockProvider stands in for the provider: it answers the expected interaction and records every interaction
+// that the consumer sent as expected
+type mockProvider struct {
+	Server *httptest.Server
+
+	mu       sync.Mutex
+	pact     Pact
+	expected *Interaction
+	received int
+	failures []string
+}
+
+// newMockProvider starts a mock of the provider for the consumer
+func newMockProvider(t *testing.T, consumer, provider string) *mockProvider {
+	t.Helper()
+	p := &mockProvider{pact: Pact{
+		Consumer:     Pacticipant{Name: consumer},
+		Provider:     Pacticipant{Name: provider},
+		Interactions: []Interaction{},
+	}}
+	p.pact.Metadata.PactSpecification.Version = "2.0.0"
+	p.Server = httptest.NewServer(http.HandlerFunc(p.serve))
+	t.Cleanup(p.Server.Close)
+	return p
+}
+
+// Verify makes call, which must send the request of the interaction exactly once, and records the interaction
+// in the pact when it did
+func (p *mockProvider) Verify(t *testing.T, interaction Interaction, call func()) {
+	t.Helper()
+	p.mu.Lock()
+	p.expected, p.received, p.failures = &interaction, 0, nil
+	p.mu.Unlock()
+
+	call()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expected = nil
+	for _, failure := range p.failures {
+		t.Error(failure)
+	}
+	if !assert.Equal(t, 1, p.received, "requests matching %q", interaction.Description) || len(p.failures) > 0 {
+		return
+	}
+	p.pact.Interactions = append(p.pact.Interactions, interaction)
+}
+
+// serve answers a request matching the expected interaction with its response, and fails any other request
+func (p *mockProvider) serve(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.expected == nil {
+		p.failures = append(p.failures, fmt.Sprintf("unexpected request %s %s", r.Method, r.URL.Path))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if mismatch := matchRequest(p.expected.Request, r); mismatch != "" {
+		p.failures = append(p.failures, fmt.Sprintf("%s: %s", p.expected.Description, mismatch))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	p.received++
+
+	response := p.expected.Response
+	for key, value := range response.Headers {
+		w.Header().Set(key, value)
+	}
+	if len(response.Body) > 0 && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	w.WriteHeader(response.Status)
+	_, _ = w.Write(response.Body)
+}
+
+// matchRequest describes how the request differs from the expected one, or returns "" when it matches
+func matchRequest(expected Request, r *http.Request) string {
+	if r.Method != expected.Method || r.URL.EscapedPath() != expected.Path {
+		return fmt.Sprintf("got %s %s, want %s %s", r.Method, r.URL.EscapedPath(), expected.Method, expected.Path)
+	}
+	for key, value := range expected.Headers {
+		if got := r.Header.Get(key); got != value {
+			return fmt.Sprintf("header %s is %q, want %q", key, got, value)
+		}
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err.Error()
+	}
+	if len(expected.Body) == 0 {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return fmt.Sprintf("got body %s, want none", body)
+		}
+		return ""
+	}
+	var got, want interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		return fmt.Sprintf("body is not JSON: %v", err)
+	}
+	if err := json.Unmarshal(expected.Body, &want); err != nil {
+		return fmt.Sprintf("expected body is not JSON: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		return fmt.Sprintf("got body %s, want %s", body, expected.Body)
+	}
+	return ""
+}
+
+// WritePact compares the recorded pact with the file the provider replays, or rewrites the file when
+// UPDATE_PACTS=1. The comparison is skipped when the provider is not part of the checkout.
+func (p *mockProvider) WritePact(t *testing.T, path string) {
+	t.Helper()
+	if t.Failed() {
+		return // An incomplete pact must not replace the committed one
+	}
+
+	recorded, err := json.MarshalIndent(p.pact, "", "  ")
+	require.NoError(t, err)
+	recorded = append(recorded, '\n')
+
+	if os.Getenv(updatePacts) == "1" {
+		require.NoError(t, os.WriteFile(path, recorded, 0o644))
+		return
+	}
+	committed, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Skipf("%s is not part of this checkout", path)
+	}
+	require.NoError(t, err)
+	assert.Equal(t, string(committed), string(recorded),
+		"%s is stale; run the contract tests with %s=1 and have %s verify it",
+		path, updatePacts, p.pact.Provider.Name)
+}
@@ -34,7 +34,7 @@ func TestPublicLogin_ValidCredentials(t *testing.T) {
 		ExpiresIn:             3600,
 		RefreshTokenExpiresIn: 7200,
 	}
-	mockAuthService.EXPECT().Authenticate(reqBody).Return(&respBody, nil)
+	mockAuthService.EXPECT().Authenticate(gomock.Any(), reqBody).Return(&respBody, nil)
 
 	// Create a mock Gin context
 	w := httptest.NewRecorder()
@@ -76,7 +76,7 @@ func TestPublicLogin_InvalidCredentials(t *testing.T) {
 		Email:    "invalid@example.com",
 		Password: "wrongpassword",
 	}
-	mockAuthService.EXPECT().Authenticate(reqBody).Return(nil, constants.ErrInvalidCredentials)
+	mockAuthService.EXPECT().Authenticate(gomock.Any(), reqBody).Return(nil, constants.ErrInvalidCredentials)
 
 	// Create a mock Gin context
 	w := httptest.NewRecorder()
@@ -134,7 +134,7 @@ func TestPublicLogin_ServiceError(t *testing.T) {
 	}
 
 	// Ensure the second return value is of type error
-	mockAuthService.EXPECT().Authenticate(reqBody).Return(nil, constants.ErrGenerateTokenVar)
+	mockAuthService.EXPECT().Authenticate(gomock.Any(), reqBody).Return(nil, constants.ErrGenerateTokenVar)
 
 	// Create a mock Gin context
 	w := httptest.NewRecorder()
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	database "github.com/Mir00r/auth-service/db"
+)
+
+var migrationName = regexp.MustCompile(`^(\d+)_\w+\.(up|down)\.sql$`)
+
+func TestMigrations_EveryUpHasADown(t *testing.T) {
+	entries, err := fs.ReadDir(database.Migrations(), ".")
+	require.NoError(t, err)
+
+	directions := map[int][]string{}
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		require.NotNil(t, match, "unexpected file %s", entry.Name())
+		version, _ := strconv.Atoi(match[1])
+		directions[version] = append(directions[version], match[2])
+	}
+
+	require.NotEmpty(t, directions)
+	for version := 1; version <= len(directions); version++ {
+		assert.ElementsMatch(t, []string{"up", "down"}, directions[version], "migration %03d", version)
+	}
+}
+
+func TestStatus_Check(t *testing.T) {
+	cases := map[string]struct {
+		status database.Status
+		want   string
+	}{
+		"current": {database.Status{Version: 16, Latest: 16}, ""},
+		"none":    {database.Status{Latest: 16}, "no migration applied, expected version 16: run `migrate up`"},
+		"behind":  {database.Status{Version: 14, Latest: 16}, "schema at version 14, expected 16: run `migrate up`"},
+		"ahead": {database.Status{Version: 17, Latest: 16},
+			"schema at version 17 is newer than the expected 16: run the release that migrated it, or `migrate goto 16` from it"},
+		"dirty": {database.Status{Version: 16, Dirty: true, Latest: 16},
+			"migration 16 failed and left the schema dirty: repair it by hand, then run `migrate force V` with the version it is at"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if c.want == "" {
+				assert.NoError(t, c.status.Check())
+				return
+			}
+			assert.EqualError(t, c.status.Check(), c.want)
+		})
+	}
+}
+
+func TestStatus_String(t *testing.T) {
+	assert.Equal(t, "schema version none, latest migration 16", fmt.Sprint(database.Status{Latest: 16}))
+	assert.Equal(t, "schema version 15 (dirty), latest migration 16", fmt.Sprint(database.Status{Version: 15, Dirty: true, Latest: 16}))
+}
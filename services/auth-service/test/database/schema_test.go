@@ -0,0 +1,138 @@
+package database
+
+import (
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	database "github.com/Mir00r/auth-service/db"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+)
+
+// testDatabaseURL names the variable holding the URL of a disposable Postgres database to run the migrations against
+const testDatabaseURL = "TEST_DATABASE_URL"
+
+// models are the entities the service reads and writes through GORM
+var models = []interface{}{
+	&entities.AuditLog{},
+	&entities.EmailVerificationToken{},
+	&entities.Mfa{},
+	&entities.OIDCLoginState{},
+	&entities.OutboxEvent{},
+	&entities.PasswordResetToken{},
+	&entities.PasswordlessChallenge{},
+	&entities.SagaInstance{},
+	&entities.Token{},
+	&entities.User{},
+	&entities.UserIdentity{},
+}
+
+var (
+	createTable = regexp.MustCompile(`(?is)CREATE TABLE IF NOT EXISTS\s+([\w.]+)\s*\((.*?)\n\s*\);`)
+	addColumn   = regexp.MustCompile(`(?i)ALTER TABLE\s+([\w.]+)\s+ADD COLUMN\s+(?:IF NOT EXISTS\s+)?(\w+)`)
+	dropColumn  = regexp.MustCompile(`(?i)ALTER TABLE\s+([\w.]+)\s+DROP COLUMN\s+(?:IF EXISTS\s+)?(\w+)`)
+)
+
+// migratedSchema returns the columns of every table the up migrations leave behind, applying them in order
+func migratedSchema(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+	entries, err := fs.ReadDir(database.Migrations(), ".")
+	require.NoError(t, err)
+
+	tables := map[string]map[string]bool{}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".up.sql") {
+			continue
+		}
+		sql, err := fs.ReadFile(database.Migrations(), entry.Name())
+		require.NoError(t, err)
+
+		for _, match := range createTable.FindAllStringSubmatch(string(sql), -1) {
+			columns := map[string]bool{}
+			for _, line := range strings.Split(match[2], "\n") {
+				line, _, _ = strings.Cut(line, "--")
+				fields := strings.Fields(line)
+				if len(fields) == 0 {
+					continue
+				}
+				switch name := strings.ToLower(fields[0]); name {
+				case "constraint", "primary", "unique", "foreign", "check":
+				default:
+					columns[name] = true
+				}
+			}
+			tables[match[1]] = columns
+		}
+		for _, match := range addColumn.FindAllStringSubmatch(string(sql), -1) {
+			_, created := tables[match[1]]
+			require.True(t, created, "%s alters %s, which no migration creates", entry.Name(), match[1])
+			tables[match[1]][strings.ToLower(match[2])] = true
+		}
+		for _, match := range dropColumn.FindAllStringSubmatch(string(sql), -1) {
+			delete(tables[match[1]], strings.ToLower(match[2]))
+		}
+	}
+	return tables
+}
+
+// columns returns the table and columns GORM maps model to
+func columns(t *testing.T, model interface{}) (string, []string) {
+	t.Helper()
+	s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+	return s.Table, s.DBNames
+}
+
+func TestMigrations_CreateEveryTableAndColumnTheEntitiesUse(t *testing.T) {
+	tables := migratedSchema(t)
+
+	for _, model := range models {
+		table, names := columns(t, model)
+		t.Run(table, func(t *testing.T) {
+			_, created := tables[table]
+			require.True(t, created, "no migration creates the table")
+			for _, name := range names {
+				assert.True(t, tables[table][name], "no migration adds the column %s", name)
+			}
+		})
+	}
+}
+
+func TestMigrations_RunAgainstPostgres(t *testing.T) {
+	dsn := os.Getenv(testDatabaseURL)
+	if dsn == "" {
+		t.Skipf("set %s to a disposable Postgres database to run the migrations", testDatabaseURL)
+	}
+
+	migrator, err := database.NewMigrator(dsn, 0)
+	require.NoError(t, err)
+	defer migrator.Close()
+
+	require.NoError(t, migrator.Up())
+	status, err := migrator.Status()
+	require.NoError(t, err)
+	require.NoError(t, status.Check())
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	for _, model := range models {
+		table, names := columns(t, model)
+		require.True(t, db.Migrator().HasTable(table), "table %s", table)
+		for _, name := range names {
+			assert.True(t, db.Migrator().HasColumn(model, name), "column %s.%s", table, name)
+		}
+	}
+
+	// Every migration reverts cleanly and applies again
+	require.NoError(t, migrator.Down(int(status.Latest)))
+	require.NoError(t, migrator.Up())
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/internal/api/controllers"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/shared/health"
+)
+
+// failing returns a critical check whose probe fails with err
+func failing(name string, err error) health.Check {
+	return health.Check{Name: name, Critical: true, Probe: func(context.Context) error { return err }}
+}
+
+func TestHealthController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := health.NewRegistry(time.Minute, time.Second)
+	registry.Register(failing("postgres", errors.New("dial tcp 10.0.0.5:5432: connection refused")))
+	controller := controllers.NewHealthController(registry)
+	router := gin.New()
+	router.GET("/health/live", controller.Live)
+	router.GET("/health/ready", controller.Ready)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var live dtos.LivenessResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &live))
+	assert.Equal(t, health.StatusUp, live.Status)
+	assert.NotEmpty(t, live.Uptime)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "10.0.0.5", "errors are left out of the unauthenticated probe")
+	var ready dtos.ReadinessResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ready))
+	assert.Equal(t, health.StatusDown, ready.Status)
+	require.Len(t, ready.Checks, 1)
+	assert.Equal(t, dtos.CheckSummary{Name: "postgres", Status: health.StatusDown, LatencyMs: ready.Checks[0].LatencyMs}, ready.Checks[0])
+}
@@ -0,0 +1,135 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/lifecycle"
+	"github.com/Mir00r/shared/health"
+)
+
+func TestNewShutdownFromConfig(t *testing.T) {
+	shutdown := lifecycle.NewShutdownFromConfig(config.ServerConfig{})
+	assert.Equal(t, lifecycle.DefaultShutdownTimeout, shutdown.Timeout)
+	assert.Zero(t, shutdown.DrainDelay)
+
+	shutdown = lifecycle.NewShutdownFromConfig(config.ServerConfig{DrainDelay: config.Duration(5 * time.Second), ShutdownTimeout: config.Duration(time.Minute)})
+	assert.Equal(t, 5*time.Second, shutdown.DrainDelay)
+	assert.Equal(t, time.Minute, shutdown.Timeout)
+}
+
+func TestShutdown_RunsStepsInOrderDespiteFailures(t *testing.T) {
+	shutdown := &lifecycle.Shutdown{Timeout: time.Second}
+	var order []string
+	shutdown.Add("server", func(context.Context) error {
+		order = append(order, "server")
+		return errors.New("requests still in flight")
+	})
+	shutdown.Add("database", func(context.Context) error {
+		order = append(order, "database")
+		return nil
+	})
+
+	err := shutdown.Run()
+
+	assert.Equal(t, []string{"server", "database"}, order)
+	assert.EqualError(t, err, "server: requests still in flight")
+}
+
+func TestShutdown_SharesOneDeadline(t *testing.T) {
+	shutdown := &lifecycle.Shutdown{Timeout: 50 * time.Millisecond}
+	shutdown.Add("slow", func(ctx context.Context) error {
+		return lifecycle.Wait(ctx, time.Minute)
+	})
+	var laterCtxErr error
+	shutdown.Add("later", func(ctx context.Context) error {
+		laterCtxErr = ctx.Err()
+		return nil
+	})
+
+	start := time.Now()
+	err := shutdown.Run()
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, laterCtxErr, context.DeadlineExceeded, "later steps still run once the deadline passed")
+}
+
+func TestWorkers_StopWaitsForWorkers(t *testing.T) {
+	workers := lifecycle.NewWorkers()
+	var finished atomic.Int32
+	for i := 0; i < 3; i++ {
+		workers.Go(func(ctx context.Context) {
+			<-ctx.Done()
+			time.Sleep(10 * time.Millisecond) // Finish the batch in progress
+			finished.Add(1)
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, workers.Stop(ctx))
+	assert.Equal(t, int32(3), finished.Load())
+}
+
+func TestWorkers_StopGivesUpAtDeadline(t *testing.T) {
+	workers := lifecycle.NewWorkers()
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	workers.Go(func(context.Context) { <-release }) // Ignores cancellation
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, workers.Stop(ctx), context.DeadlineExceeded)
+}
+
+func TestShutdown_DrainsInFlightRequests(t *testing.T) {
+	registry := health.NewRegistry(time.Minute, time.Second)
+	started := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = io.WriteString(w, "done")
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{Handler: mux}
+	go func() { _ = server.Serve(listener) }()
+
+	response := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+	<-started
+
+	shutdown := &lifecycle.Shutdown{Timeout: 5 * time.Second, DrainDelay: 10 * time.Millisecond}
+	shutdown.Add("readiness", func(ctx context.Context) error {
+		registry.Drain()
+		return lifecycle.Wait(ctx, shutdown.DrainDelay)
+	})
+	shutdown.Add("HTTP server", server.Shutdown)
+	require.NoError(t, shutdown.Run())
+
+	assert.Equal(t, "done", <-response, "the request in flight completes")
+	assert.Equal(t, health.StatusDown, registry.Check(context.Background()).Status, "readiness fails once draining")
+	_, err = http.Get("http://" + listener.Addr().String() + "/slow")
+	assert.Error(t, err, "new connections are refused")
+}
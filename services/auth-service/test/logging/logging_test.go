@@ -0,0 +1,165 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/apiclients"
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/logging"
+	"github.com/Mir00r/auth-service/middlewares"
+	sharedlogging "github.com/Mir00r/shared/logging"
+)
+
+// capture installs a JSON logger writing to the returned buffer as the default logger for the test
+func capture(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, config.LoggingConfig{Level: "debug", Format: logging.FormatJSON})
+	require.NoError(t, err)
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		require.NoError(t, sharedlogging.SetLevel("info"))
+	})
+	return &buf
+}
+
+// records decodes the records written to buf
+func records(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		out = append(out, record)
+	}
+	return out
+}
+
+func TestLogger_RedactsAttributesGroupsAndErrors(t *testing.T) {
+	buf := capture(t)
+
+	slog.With("client_secret", "abc").Info("Login for bob@example.com",
+		"password", "hunter2",
+		"error", errors.New("postgres://auth:s3cret@db failed"),
+		slog.Group("request", "accessToken", "abc", "path", "/login"),
+		"attempts", 3,
+	)
+
+	out := buf.String()
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "s3cret")
+	assert.NotContains(t, out, "bob@example.com")
+	assert.NotContains(t, out, `"abc"`)
+
+	record := records(t, buf)[0]
+	assert.Equal(t, "Login for b***@example.com", record["msg"])
+	assert.Equal(t, sharedlogging.Redacted, record["password"])
+	assert.Equal(t, sharedlogging.Redacted, record["client_secret"])
+	assert.Equal(t, "postgres://auth:"+sharedlogging.Redacted+"@db failed", record["error"])
+	assert.Equal(t, map[string]interface{}{"accessToken": sharedlogging.Redacted, "path": "/login"}, record["request"])
+	assert.Equal(t, float64(3), record["attempts"])
+}
+
+func TestLogger_AddsRequestIDFromContext(t *testing.T) {
+	buf := capture(t)
+
+	slog.InfoContext(logging.WithRequestID(context.Background(), "req-1"), "Handled")
+	slog.Info("Background")
+
+	out := records(t, buf)
+	require.Len(t, out, 2)
+	assert.Equal(t, "req-1", out[0]["request_id"])
+	assert.NotContains(t, out[1], "request_id")
+}
+
+func TestNew_RejectsUnknownLevelAndFormat(t *testing.T) {
+	_, err := logging.New(&bytes.Buffer{}, config.LoggingConfig{Level: "loud"})
+	assert.Error(t, err)
+	_, err = logging.New(&bytes.Buffer{}, config.LoggingConfig{Format: "xml"})
+	assert.Error(t, err)
+}
+
+func TestRequestLogging_ContinuesRequestIDAndLogsRequest(t *testing.T) {
+	buf := capture(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middlewares.RequestLogging())
+	router.GET("/v1/items/:id", func(c *gin.Context) {
+		c.Set("userID", "42")
+		slog.InfoContext(c.Request.Context(), "Loading item")
+		c.Status(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/items/7", nil)
+	req.Header.Set(constants.RequestIDHeader, "caller-id-1")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, "caller-id-1", rec.Header().Get(constants.RequestIDHeader))
+	out := records(t, buf)
+	require.Len(t, out, 2)
+	assert.Equal(t, "caller-id-1", out[0]["request_id"])
+
+	served := out[1]
+	assert.Equal(t, "Request served", served["msg"])
+	assert.Equal(t, "WARN", served["level"])
+	assert.Equal(t, "caller-id-1", served["request_id"])
+	assert.Equal(t, "/v1/items/:id", served["route"])
+	assert.Equal(t, "/v1/items/7", served["path"])
+	assert.Equal(t, float64(http.StatusNotFound), served["status"])
+	assert.Equal(t, "42", served["user_id"])
+	assert.Contains(t, served, "latency_ms")
+}
+
+func TestRequestLogging_ReplacesInvalidRequestID(t *testing.T) {
+	capture(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middlewares.RequestLogging())
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(constants.RequestIDHeader, "bad id\nforged line")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	requestID := rec.Header().Get(constants.RequestIDHeader)
+	assert.NotEmpty(t, requestID)
+	assert.NotContains(t, requestID, "forged")
+}
+
+func TestWebClient_ForwardsRequestID(t *testing.T) {
+	received := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(constants.RequestIDHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	client := apiclients.WebClient{
+		Client:    &http.Client{},
+		Upstreams: apiclients.NewUpstreams(apiclients.NewStaticUpstream("logging-test", "http", srv.Listener.Addr().String())),
+	}.WithPolicies(apiclients.Policies{Default: apiclients.Policy{Timeout: time.Second, MaxAttempts: 1}})
+
+	ctx := logging.WithRequestID(context.Background(), "req-42")
+	require.NoError(t, client.Send(ctx, http.MethodGet, "logging-test", "/items", nil, nil))
+	assert.Equal(t, "req-42", <-received)
+}
@@ -0,0 +1,293 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/messaging"
+)
+
+// startNATS runs an embedded NATS server on a random port
+func startNATS(t *testing.T, jetStream bool) *server.Server {
+	t.Helper()
+	opts := &server.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true, JetStream: jetStream}
+	if jetStream {
+		opts.StoreDir = t.TempDir()
+	}
+	srv, err := server.NewServer(opts)
+	require.NoError(t, err)
+	go srv.Start()
+	require.True(t, srv.ReadyForConnections(5*time.Second))
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+// fakeStore is an in-memory outbox
+type fakeStore struct {
+	mu        sync.Mutex
+	events    []*entities.OutboxEvent
+	published map[string]int
+	failed    map[string]int
+}
+
+func newFakeStore(events ...*entities.OutboxEvent) *fakeStore {
+	return &fakeStore{events: events, published: map[string]int{}, failed: map[string]int{}}
+}
+
+func (s *fakeStore) ClaimPending(limit int, _ time.Duration) ([]entities.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []entities.OutboxEvent
+	for _, event := range s.events {
+		if len(claimed) == limit {
+			break
+		}
+		if event.PublishedAt == nil && !event.NextAttemptAt.After(time.Now()) {
+			claimed = append(claimed, *event)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *fakeStore) MarkPublished(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, event := range s.events {
+		if event.ID == id {
+			event.PublishedAt = &now
+		}
+	}
+	s.published[id]++
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(id string, nextAttemptAt time.Time, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range s.events {
+		if event.ID == id {
+			event.Attempts++
+			event.NextAttemptAt = nextAttemptAt
+			event.LastError = &lastError
+		}
+	}
+	s.failed[id]++
+	return nil
+}
+
+func newEvent(t *testing.T, eventType, aggregateID string) *entities.OutboxEvent {
+	t.Helper()
+	event, err := messaging.NewOutboxEvent(eventType, aggregateID, map[string]string{"userId": aggregateID})
+	require.NoError(t, err)
+	return event
+}
+
+func TestNewOutboxEvent_Envelope(t *testing.T) {
+	event := newEvent(t, "auth.login_succeeded", "user-1")
+
+	var envelope messaging.Envelope
+	require.NoError(t, json.Unmarshal(event.Payload, &envelope))
+	assert.Equal(t, event.ID, envelope.ID)
+	assert.Equal(t, "auth.login_succeeded", envelope.Type)
+	assert.Equal(t, "auth-service", envelope.Source)
+	assert.Equal(t, "user-1", event.AggregateID)
+	assert.WithinDuration(t, time.Now(), envelope.OccurredAt, time.Minute)
+}
+
+func TestRelay_PublishesToCoreNATS(t *testing.T) {
+	srv := startNATS(t, false)
+
+	sub, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer sub.Close()
+	msgs := make(chan *nats.Msg, 10)
+	_, err = sub.ChanSubscribe("auth.>", msgs)
+	require.NoError(t, err)
+	require.NoError(t, sub.Flush())
+
+	broker, err := messaging.NewNATSBroker(srv.ClientURL(), false)
+	require.NoError(t, err)
+	defer broker.Close()
+
+	login := newEvent(t, "auth.login_succeeded", "user-1")
+	changed := newEvent(t, "auth.password_changed", "user-1")
+	store := newFakeStore(login, changed)
+
+	relay := messaging.NewRelay(store, broker, messaging.RelayConfig{})
+	claimed, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, claimed)
+
+	for _, want := range []*entities.OutboxEvent{login, changed} {
+		select {
+		case msg := <-msgs:
+			assert.Equal(t, want.EventType, msg.Subject)
+			assert.Equal(t, want.ID, msg.Header.Get(jetstream.MsgIDHeader))
+			assert.JSONEq(t, string(want.Payload), string(msg.Data))
+		case <-time.After(5 * time.Second):
+			t.Fatal("event was not delivered")
+		}
+	}
+	assert.Equal(t, 1, store.published[login.ID])
+	assert.Equal(t, 1, store.published[changed.ID])
+
+	// Nothing is left to publish
+	claimed, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, claimed)
+}
+
+func TestRelay_JetStreamDeduplicatesRedelivery(t *testing.T) {
+	srv := startNATS(t, true)
+
+	conn, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+	stream, err := js.CreateStream(context.Background(), jetstream.StreamConfig{Name: "AUTH", Subjects: []string{"auth.>"}})
+	require.NoError(t, err)
+
+	broker, err := messaging.NewNATSBroker(srv.ClientURL(), true)
+	require.NoError(t, err)
+	defer broker.Close()
+
+	event := newEvent(t, "auth.login_succeeded", "user-1")
+
+	// A relay that died before marking the event published delivers it again
+	for i := 0; i < 2; i++ {
+		store := newFakeStore(event)
+		_, err := messaging.NewRelay(store, broker, messaging.RelayConfig{}).RelayOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, store.published[event.ID])
+		event.PublishedAt = nil
+	}
+
+	info, err := stream.Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), info.State.Msgs)
+}
+
+// failingBroker rejects every publish
+type failingBroker struct{ calls int }
+
+func (b *failingBroker) Publish(context.Context, messaging.Message) error {
+	b.calls++
+	return errors.New("broker unavailable")
+}
+func (b *failingBroker) Close() error { return nil }
+
+func TestRelay_FailedPublishIsRetriedWithBackoff(t *testing.T) {
+	event := newEvent(t, "auth.password_changed", "user-1")
+	store := newFakeStore(event)
+	broker := &failingBroker{}
+	relay := messaging.NewRelay(store, broker, messaging.RelayConfig{MaxBackoff: time.Hour})
+
+	_, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.failed[event.ID])
+	assert.Zero(t, store.published[event.ID])
+	assert.Equal(t, "broker unavailable", *event.LastError)
+	assert.WithinDuration(t, time.Now().Add(2*time.Second), event.NextAttemptAt, time.Second)
+
+	// The event is not due again until the backoff elapses
+	claimed, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, claimed)
+	assert.Equal(t, 1, broker.calls)
+
+	// Each further failure doubles the delay
+	event.NextAttemptAt = time.Now()
+	_, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(4*time.Second), event.NextAttemptAt, time.Second)
+}
+
+func TestRelay_RunStopsOnCancel(t *testing.T) {
+	srv := startNATS(t, false)
+	broker, err := messaging.NewNATSBroker(srv.ClientURL(), false)
+	require.NoError(t, err)
+	defer broker.Close()
+
+	event := newEvent(t, "auth.login_succeeded", "user-1")
+	store := newFakeStore(event)
+	relay := messaging.NewRelay(store, broker, messaging.RelayConfig{PollInterval: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return store.published[event.ID] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("relay did not stop")
+	}
+}
+
+func TestNATSBroker_EnsureStreamKeepsEventsPublishedBeforeAnySubscriber(t *testing.T) {
+	srv := startNATS(t, true)
+	ctx := context.Background()
+
+	broker, err := messaging.NewNATSBroker(srv.ClientURL(), true)
+	require.NoError(t, err)
+	defer broker.Close()
+	require.NoError(t, broker.EnsureStream(ctx, "AUTH", constants.EventSubjects))
+
+	event := newEvent(t, constants.EventLoginSucceeded, "user-1")
+	store := newFakeStore(event)
+	_, err = messaging.NewRelay(store, broker, messaging.RelayConfig{}).RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.published[event.ID])
+
+	// The consuming service declares the same stream at its own startup, which leaves the event in place
+	subscriber, err := messaging.NewNATSSubscriber(srv.ClientURL(), true)
+	require.NoError(t, err)
+	defer subscriber.Close()
+	require.NoError(t, subscriber.EnsureStream(ctx, "AUTH", constants.EventSubjects))
+
+	delivered := make(chan messaging.Message, 1)
+	require.NoError(t, subscriber.Subscribe(ctx, "AUTH", "user-service-login", constants.EventLoginSucceeded,
+		func(_ context.Context, msg messaging.Message) error {
+			delivered <- msg
+			return nil
+		}))
+	select {
+	case msg := <-delivered:
+		assert.Equal(t, event.ID, msg.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event published before the subscriber was not delivered")
+	}
+}
+
+func TestNATSBroker_EnsureStreamIsANoOpOnCoreNATS(t *testing.T) {
+	srv := startNATS(t, false)
+
+	broker, err := messaging.NewNATSBroker(srv.ClientURL(), false)
+	require.NoError(t, err)
+	defer broker.Close()
+
+	assert.NoError(t, broker.EnsureStream(context.Background(), "AUTH", constants.EventSubjects))
+}
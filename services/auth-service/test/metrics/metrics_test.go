@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/apiclients"
+	"github.com/Mir00r/auth-service/metrics"
+	"github.com/Mir00r/auth-service/middlewares"
+)
+
+// scrape returns the metrics as served to Prometheus
+func scrape(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+// newClient returns a client calling the server as the only instance of the upstream, opening the breaker
+// after two failures
+func newClient(upstream string, srv *httptest.Server) *apiclients.WebClient {
+	client := apiclients.WebClient{
+		Client:    &http.Client{},
+		Upstreams: apiclients.NewUpstreams(apiclients.NewStaticUpstream(upstream, "http", srv.Listener.Addr().String())),
+	}.WithPolicies(apiclients.Policies{Default: apiclients.Policy{
+		Timeout:          time.Second,
+		MaxAttempts:      1,
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		HalfOpenProbes:   1,
+	}})
+	return &client
+}
+
+func TestMetrics_CountsRequestsByRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middlewares.Metrics())
+	router.GET("/v1/metrics-test/:id", func(c *gin.Context) { c.Status(http.StatusAccepted) })
+	served := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/v1/metrics-test/:id", "202"))
+	unmatched := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, metrics.UnmatchedRoute, "404"))
+
+	for _, path := range []string{"/v1/metrics-test/1", "/v1/metrics-test/2", "/unknown/a", "/unknown/b"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, served+2, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/v1/metrics-test/:id", "202")))
+	assert.Equal(t, unmatched+2, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, metrics.UnmatchedRoute, "404")))
+	assert.Zero(t, testutil.ToFloat64(metrics.HTTPInFlight))
+	assert.Contains(t, scrape(t), `http_server_request_duration_seconds_count{method="GET",route="/v1/metrics-test/:id",status="202"}`)
+	assert.NotContains(t, scrape(t), "/unknown/")
+}
+
+func TestMetrics_FoldsUnknownMethods(t *testing.T) {
+	assert.Equal(t, http.MethodPatch, metrics.Method(http.MethodPatch))
+	assert.Equal(t, "OTHER", metrics.Method("PROPFIND"))
+}
+
+func TestWebClient_ObservesCallsByUpstreamAndOutcome(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+	client := newClient("metrics-failing", srv)
+
+	for i := 0; i < 3; i++ {
+		_ = client.Send(context.Background(), http.MethodGet, "metrics-failing", "/items", nil, nil)
+	}
+
+	scraped := scrape(t)
+	assert.Contains(t, scraped, `outbound_request_duration_seconds_count{method="GET",outcome="5xx",upstream="metrics-failing"} 2`)
+	// The third call is rejected by the breaker opened by the first two
+	assert.Contains(t, scraped, `outbound_request_duration_seconds_count{method="GET",outcome="rejected",upstream="metrics-failing"} 1`)
+}
+
+func TestWebClient_StatsCollectorExposesBreakerState(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+	client := newClient("metrics-breaker", srv)
+	target := srv.Listener.Addr().String()
+	for i := 0; i < 3; i++ {
+		_ = client.Send(context.Background(), http.MethodGet, "metrics-breaker", "/items", nil, nil)
+	}
+
+	expected := `
+# HELP outbound_circuit_breaker_state Circuit breaker state of an upstream instance: 1 for the current state (closed, open, half-open), 0 otherwise.
+# TYPE outbound_circuit_breaker_state gauge
+outbound_circuit_breaker_state{state="closed",target="TARGET",upstream="metrics-breaker"} 0
+outbound_circuit_breaker_state{state="half-open",target="TARGET",upstream="metrics-breaker"} 0
+outbound_circuit_breaker_state{state="open",target="TARGET",upstream="metrics-breaker"} 1
+# HELP outbound_failures_total Attempts to an upstream instance that failed with a transport error or a 5xx.
+# TYPE outbound_failures_total counter
+outbound_failures_total{target="TARGET",upstream="metrics-breaker"} 2
+# HELP outbound_rejected_total Calls to an upstream instance rejected by its circuit breaker or bulkhead.
+# TYPE outbound_rejected_total counter
+outbound_rejected_total{target="TARGET",upstream="metrics-breaker"} 1
+`
+	err := testutil.CollectAndCompare(client.StatsCollector(), strings.NewReader(strings.ReplaceAll(expected, "TARGET", target)),
+		"outbound_circuit_breaker_state", "outbound_failures_total", "outbound_rejected_total")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,220 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/apiclients"
+	config "github.com/Mir00r/auth-service/configs"
+)
+
+// mockProvider is an in-process OIDC provider issuing RS256-signed ID tokens
+type mockProvider struct {
+	t         *testing.T
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	kid       string
+	clientID  string
+	nonce     string
+	claims    jwt.MapClaims // Overrides applied to the next ID token
+	lastForm  url.Values
+	jwksCalls int
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p := &mockProvider{t: t, key: key, kid: "key-1", clientID: "auth-service"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.jwksCalls++
+		writeJSON(w, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": p.kid,
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		p.lastForm = r.Form
+		if r.Form.Get("code") != "valid-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"access_token": "provider-access-token",
+			"token_type":   "Bearer",
+			"id_token":     p.idToken(),
+			"expires_in":   3600,
+		})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *mockProvider) idToken() string {
+	claims := jwt.MapClaims{
+		"iss":            p.server.URL,
+		"aud":            p.clientID,
+		"sub":            "external-user-1",
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"name":           "Jane Doe",
+		"nonce":          p.nonce,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.kid
+	signed, err := token.SignedString(p.key)
+	require.NoError(p.t, err)
+	return signed
+}
+
+func (p *mockProvider) client() *apiclients.OIDCProvider {
+	return apiclients.NewOIDCProvider(config.OIDCProviderConfig{
+		Name:         "mock",
+		Issuer:       p.server.URL,
+		ClientID:     p.clientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8081/v1/public/auth/oidc/mock/callback",
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestAuthCodeURL_IncludesStateNonceAndPKCE(t *testing.T) {
+	provider := newMockProvider(t)
+
+	authURL, err := provider.client().AuthCodeURL(context.Background(), "state-1", "nonce-1", "verifier-1")
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, provider.server.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, "auth-service", query.Get("client_id"))
+	assert.Equal(t, "state-1", query.Get("state"))
+	assert.Equal(t, "nonce-1", query.Get("nonce"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, apiclients.CodeChallengeS256("verifier-1"), query.Get("code_challenge"))
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+}
+
+func TestExchangeAndVerifyIDToken_Success(t *testing.T) {
+	provider := newMockProvider(t)
+	provider.nonce = "nonce-1"
+	client := provider.client()
+
+	tokens, err := client.Exchange(context.Background(), "valid-code", "verifier-1")
+	require.NoError(t, err)
+	assert.Equal(t, "verifier-1", provider.lastForm.Get("code_verifier"))
+
+	claims, err := client.VerifyIDToken(context.Background(), tokens.IDToken, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "external-user-1", claims.Subject)
+	assert.Equal(t, "jane@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, "Jane Doe", claims.Name)
+}
+
+func TestExchange_InvalidCode(t *testing.T) {
+	provider := newMockProvider(t)
+
+	_, err := provider.client().Exchange(context.Background(), "bad-code", "verifier-1")
+	assert.Error(t, err)
+}
+
+func TestVerifyIDToken_Rejections(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		nonce  string
+	}{
+		{name: "nonce mismatch", nonce: "other-nonce"},
+		{name: "wrong audience", claims: jwt.MapClaims{"aud": "someone-else"}, nonce: "nonce-1"},
+		{name: "wrong issuer", claims: jwt.MapClaims{"iss": "https://evil.example.com"}, nonce: "nonce-1"},
+		{name: "expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}, nonce: "nonce-1"},
+		{name: "missing subject", claims: jwt.MapClaims{"sub": ""}, nonce: "nonce-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newMockProvider(t)
+			provider.nonce = "nonce-1"
+			provider.claims = tt.claims
+
+			_, err := provider.client().VerifyIDToken(context.Background(), provider.idToken(), tt.nonce)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestVerifyIDToken_ForeignSignature(t *testing.T) {
+	provider := newMockProvider(t)
+	provider.nonce = "nonce-1"
+	client := provider.client()
+	foreignToken := provider.idToken()
+
+	// Rotate the provider key: the token signed with the previous key must be rejected
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	provider.key = rotated
+
+	_, err = client.VerifyIDToken(context.Background(), foreignToken, "nonce-1")
+	assert.Error(t, err)
+}
+
+func TestVerifyIDToken_RefreshesKeysOnRotation(t *testing.T) {
+	provider := newMockProvider(t)
+	provider.nonce = "nonce-1"
+	client := provider.client()
+
+	_, err := client.VerifyIDToken(context.Background(), provider.idToken(), "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.jwksCalls)
+
+	// A new kid forces a single refetch of the key set
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	provider.key, provider.kid = rotated, "key-2"
+
+	_, err = client.VerifyIDToken(context.Background(), provider.idToken(), "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, provider.jwksCalls)
+}
@@ -0,0 +1,73 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/internal/reconcile"
+)
+
+var (
+	older = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer = older.Add(24 * time.Hour)
+)
+
+func TestPlan_MovesEveryLocalHashToUserService(t *testing.T) {
+	local := []reconcile.Credential{
+		{Email: "only-local@example.com", Name: "Local", PasswordHash: "hash-a", UpdatedAt: older},
+		{Email: "Same@Example.com", PasswordHash: "hash-b", UpdatedAt: older},
+		{Email: "migrated@example.com", PasswordHash: "", UpdatedAt: older},
+	}
+	remote := []reconcile.Credential{
+		{Email: "same@example.com", PasswordHash: "hash-b", UpdatedAt: newer},
+		{Email: "migrated@example.com", PasswordHash: "hash-c", UpdatedAt: newer},
+		{Email: "only-remote@example.com", PasswordHash: "hash-d", UpdatedAt: newer},
+	}
+
+	actions := reconcile.Plan(local, remote, reconcile.PreferNewest)
+
+	require.Len(t, actions, 2)
+	assert.Equal(t, reconcile.CreateInUserService, actions[0].Type)
+	assert.Equal(t, "only-local@example.com", actions[0].Credential.Email)
+	assert.Equal(t, "hash-a", actions[0].Credential.PasswordHash)
+	assert.Equal(t, reconcile.KeepUserService, actions[1].Type)
+	assert.Equal(t, "Same@Example.com", actions[1].Credential.Email)
+}
+
+func TestPlan_DivergentHashes(t *testing.T) {
+	tests := []struct {
+		name        string
+		strategy    reconcile.Strategy
+		localUpdate time.Time
+		want        reconcile.ActionType
+	}{
+		{"newest keeps the newer user-service hash", reconcile.PreferNewest, older, reconcile.KeepUserService},
+		{"newest takes the newer auth-service hash", reconcile.PreferNewest, newer.Add(time.Hour), reconcile.UpdateUserService},
+		{"user-service always wins", reconcile.PreferUserService, newer.Add(time.Hour), reconcile.KeepUserService},
+		{"auth-service always wins", reconcile.PreferAuthService, older, reconcile.UpdateUserService},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := []reconcile.Credential{{Email: "john@example.com", PasswordHash: "local", UpdatedAt: tt.localUpdate}}
+			remote := []reconcile.Credential{{Email: "john@example.com", PasswordHash: "remote", UpdatedAt: newer}}
+
+			actions := reconcile.Plan(local, remote, tt.strategy)
+
+			require.Len(t, actions, 1)
+			assert.Equal(t, tt.want, actions[0].Type)
+			assert.Equal(t, "local", actions[0].Credential.PasswordHash)
+		})
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	strategy, err := reconcile.ParseStrategy("auth-service")
+	require.NoError(t, err)
+	assert.Equal(t, reconcile.PreferAuthService, strategy)
+
+	_, err = reconcile.ParseStrategy("latest")
+	assert.Error(t, err)
+}
@@ -0,0 +1,580 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/saga"
+)
+
+// memoryStore keeps saga instances in memory, mimicking the leasing and versioning of the saga repository
+type memoryStore struct {
+	mu         sync.Mutex
+	instances  map[string]entities.SagaInstance
+	nextID     int
+	saves      int
+	failOnSave int // Number of the save that fails, simulating a crash; 0 never fails
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{instances: map[string]entities.SagaInstance{}}
+}
+
+func (s *memoryStore) Create(instance *entities.SagaInstance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.instances {
+		if existing.SagaType == instance.SagaType && existing.SagaKey == instance.SagaKey && existing.EndedAt == nil {
+			return saga.ErrAlreadyRunning
+		}
+	}
+	s.nextID++
+	instance.ID = fmt.Sprintf("saga-%d", s.nextID)
+	s.instances[instance.ID] = *instance
+	return nil
+}
+
+func (s *memoryStore) Save(instance *entities.SagaInstance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saves++
+	if s.saves == s.failOnSave {
+		return errors.New("connection lost")
+	}
+	if s.instances[instance.ID].Version != instance.Version {
+		return saga.ErrLeaseLost
+	}
+	instance.Version++
+	s.instances[instance.ID] = *instance
+	return nil
+}
+
+func (s *memoryStore) ClaimDue(limit int, lease time.Duration) ([]entities.SagaInstance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var claimed []entities.SagaInstance
+	for id, instance := range s.instances {
+		if len(claimed) == limit {
+			break
+		}
+		if instance.EndedAt != nil || instance.NextAttemptAt.After(now) ||
+			(instance.LockedUntil != nil && instance.LockedUntil.After(now)) {
+			continue
+		}
+		lockedUntil := now.Add(lease)
+		instance.LockedUntil = &lockedUntil
+		instance.Version++
+		s.instances[id] = instance
+		claimed = append(claimed, instance)
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) Cancel(sagaType, key string, beforeStep int) (*entities.SagaInstance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, instance := range s.instances {
+		if instance.SagaType != sagaType || instance.SagaKey != key || instance.EndedAt != nil ||
+			instance.Status != saga.StatusRunning || instance.Step >= beforeStep ||
+			(instance.LockedUntil != nil && instance.LockedUntil.After(now)) {
+			continue
+		}
+		instance.Status = saga.StatusCancelled
+		instance.EndedAt = &now
+		instance.Version++
+		s.instances[id] = instance
+		return &instance, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) FindLatest(sagaType, key string) (*entities.SagaInstance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var latest *entities.SagaInstance
+	for _, instance := range s.instances {
+		if instance.SagaType == sagaType && instance.SagaKey == key &&
+			(latest == nil || instance.NextAttemptAt.After(latest.NextAttemptAt)) {
+			instance := instance
+			latest = &instance
+		}
+	}
+	return latest, nil
+}
+
+func (s *memoryStore) get(id string) entities.SagaInstance {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.instances[id]
+}
+
+// elapse makes a waiting or leased saga due, as if its backoff and lease had passed
+func (s *memoryStore) elapse(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	instance := s.instances[id]
+	instance.NextAttemptAt = time.Now().Add(-time.Second)
+	instance.LockedUntil = nil
+	s.instances[id] = instance
+}
+
+// recorder builds steps that record their executions and compensations
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) step(name string, fail func() error) saga.Step {
+	return saga.Step{
+		Name: name,
+		Execute: func(_ context.Context, data saga.Data) error {
+			r.record("execute " + name)
+			if fail != nil {
+				if err := fail(); err != nil {
+					return err
+				}
+			}
+			data[name] = "done"
+			return nil
+		},
+		Compensate: func(_ context.Context, data saga.Data) error {
+			r.record("compensate " + name)
+			delete(data, name)
+			return nil
+		},
+	}
+}
+
+func failing(err error, times int) func() error {
+	return func() error {
+		if times > 0 {
+			times--
+			return err
+		}
+		return nil
+	}
+}
+
+func TestOrchestrator_CompletesStepsInOrderAndDropsSensitiveData(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	orchestrator := saga.NewOrchestrator(store, saga.Config{}, saga.Definition{
+		Type:      "registration",
+		Steps:     []saga.Step{rec.step("profile", nil), rec.step("account", nil), rec.step("email", nil)},
+		Sensitive: []string{"passwordHash"},
+	})
+
+	instance, err := orchestrator.Start(context.Background(), "registration", "john@example.com", saga.Data{"passwordHash": "hash"})
+
+	require.NoError(t, err)
+	assert.Equal(t, saga.StatusCompleted, instance.Status)
+	assert.Equal(t, []string{"execute profile", "execute account", "execute email"}, rec.calls)
+
+	stored := store.get(instance.ID)
+	assert.Equal(t, saga.StatusCompleted, stored.Status)
+	assert.Empty(t, stored.StepName)
+	assert.NotNil(t, stored.EndedAt)
+	assert.Nil(t, stored.LockedUntil)
+	assert.NotContains(t, string(stored.Data), "passwordHash")
+	assert.Contains(t, string(stored.Data), `"email":"done"`)
+}
+
+func TestOrchestrator_PermanentFailureCompensatesInReverseOrder(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	rejected := errors.New("email already registered")
+	orchestrator := saga.NewOrchestrator(store, saga.Config{}, saga.Definition{
+		Type: "registration",
+		Steps: []saga.Step{
+			rec.step("profile", nil),
+			rec.step("account", nil),
+			rec.step("email", failing(saga.Permanent(rejected), 1)),
+		},
+		Sensitive: []string{"passwordHash"},
+	})
+
+	instance, err := orchestrator.Start(context.Background(), "registration", "john@example.com", saga.Data{"passwordHash": "hash"})
+
+	require.Error(t, err)
+	assert.Same(t, rejected, saga.Cause(err))
+	assert.Equal(t, saga.StatusCompensated, instance.Status)
+	assert.Equal(t, []string{
+		"execute profile", "execute account", "execute email",
+		"compensate email", "compensate account", "compensate profile",
+	}, rec.calls)
+
+	stored := store.get(instance.ID)
+	assert.Equal(t, saga.StatusCompensated, stored.Status)
+	require.NotNil(t, stored.LastError)
+	assert.Contains(t, *stored.LastError, "email already registered")
+	assert.NotContains(t, string(stored.Data), "passwordHash")
+}
+
+func TestOrchestrator_TemporaryFailureIsRetriedLater(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	orchestrator := saga.NewOrchestrator(store, saga.Config{}, saga.Definition{
+		Type:  "registration",
+		Steps: []saga.Step{rec.step("profile", failing(errors.New("user-service unavailable"), 1)), rec.step("account", nil)},
+	})
+
+	instance, err := orchestrator.Start(context.Background(), "registration", "john@example.com", saga.Data{})
+
+	require.Error(t, err)
+	assert.False(t, saga.IsPermanent(err))
+	waiting := store.get(instance.ID)
+	assert.Equal(t, saga.StatusRunning, waiting.Status)
+	assert.Equal(t, "profile", waiting.StepName)
+	assert.Equal(t, 1, waiting.Attempts)
+	assert.True(t, waiting.NextAttemptAt.After(time.Now()))
+	assert.Nil(t, waiting.LockedUntil)
+
+	// Nothing is due before the backoff has passed
+	resumed, err := orchestrator.ResumeOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, resumed)
+
+	store.elapse(instance.ID)
+	resumed, err = orchestrator.ResumeOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, resumed)
+
+	assert.Equal(t, saga.StatusCompleted, store.get(instance.ID).Status)
+	assert.Equal(t, []string{"execute profile", "execute profile", "execute account"}, rec.calls)
+}
+
+func TestOrchestrator_CompensatesOnceAttemptsAreExhausted(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	orchestrator := saga.NewOrchestrator(store, saga.Config{}, saga.Definition{
+		Type:        "registration",
+		Steps:       []saga.Step{rec.step("profile", nil), rec.step("email", failing(errors.New("smtp down"), 10))},
+		MaxAttempts: 2,
+	})
+
+	instance, err := orchestrator.Start(context.Background(), "registration", "john@example.com", saga.Data{})
+	require.Error(t, err)
+	assert.Equal(t, saga.StatusRunning, store.get(instance.ID).Status)
+
+	store.elapse(instance.ID)
+	_, err = orchestrator.ResumeOnce(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, saga.StatusCompensated, store.get(instance.ID).Status)
+	assert.Equal(t, []string{
+		"execute profile", "execute email", "execute email", "compensate email", "compensate profile",
+	}, rec.calls)
+}
+
+func TestOrchestrator_ResumesAfterCrashFromPersistedStep(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	definition := saga.Definition{
+		Type:  "registration",
+		Steps: []saga.Step{rec.step("profile", nil), rec.step("account", nil), rec.step("email", nil)},
+	}
+
+	// The instance dies after the second step ran but before its progress was saved; every step renews the lease
+	// before it runs and saves the progress after
+	store.failOnSave = 4
+	instance, err := saga.NewOrchestrator(store, saga.Config{}, definition).
+		Start(context.Background(), "registration", "john@example.com", saga.Data{})
+	require.Error(t, err)
+	crashed := store.get(instance.ID)
+	assert.Equal(t, saga.StatusRunning, crashed.Status)
+	assert.Equal(t, "account", crashed.StepName)
+
+	// The lease keeps other instances away until it expires
+	restarted := saga.NewOrchestrator(store, saga.Config{}, definition)
+	resumed, err := restarted.ResumeOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, resumed)
+
+	store.elapse(instance.ID)
+	resumed, err = restarted.ResumeOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, resumed)
+
+	assert.Equal(t, saga.StatusCompleted, store.get(instance.ID).Status)
+	assert.Equal(t, []string{"execute profile", "execute account", "execute account", "execute email"}, rec.calls)
+}
+
+func TestOrchestrator_RejectsSecondSagaForTheSameKey(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	orchestrator := saga.NewOrchestrator(store, saga.Config{}, saga.Definition{
+		Type:  "registration",
+		Steps: []saga.Step{rec.step("profile", failing(errors.New("user-service unavailable"), 1))},
+	})
+
+	_, err := orchestrator.Start(context.Background(), "registration", "john@example.com", saga.Data{})
+	require.Error(t, err)
+
+	_, err = orchestrator.Start(context.Background(), "registration", "john@example.com", saga.Data{})
+	assert.ErrorIs(t, err, saga.ErrAlreadyRunning)
+}
+
+func TestOrchestrator_PermanentCompensationFailureNeedsAnOperator(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	profile := rec.step("profile", nil)
+	profile.Compensate = func(context.Context, saga.Data) error {
+		return saga.Permanent(errors.New("profile is already verified"))
+	}
+	orchestrator := saga.NewOrchestrator(store, saga.Config{}, saga.Definition{
+		Type:  "registration",
+		Steps: []saga.Step{profile, rec.step("account", failing(saga.Permanent(errors.New("rejected")), 1))},
+	})
+
+	instance, err := orchestrator.Start(context.Background(), "registration", "john@example.com", saga.Data{})
+
+	require.Error(t, err)
+	stored := store.get(instance.ID)
+	assert.Equal(t, saga.StatusFailed, stored.Status)
+	assert.NotNil(t, stored.EndedAt)
+	require.NotNil(t, stored.LastError)
+	assert.Contains(t, *stored.LastError, "already verified")
+}
+
+// deletionDefinition mirrors the account deletion saga: a cancellable grace period followed by forward-only steps
+func deletionDefinition(rec *recorder, purge func() error) saga.Definition {
+	return saga.Definition{
+		Type: "account_deletion",
+		Steps: []saga.Step{
+			{Name: "grace_period", Execute: func(context.Context, saga.Data) error {
+				rec.record("execute grace_period")
+				return nil
+			}},
+			{Name: "revoke_sessions", Execute: rec.step("revoke_sessions", nil).Execute},
+			{Name: "purge_auth_data", Execute: rec.step("purge_auth_data", purge).Execute},
+		},
+		Sensitive:        []string{"email"},
+		ForwardOnly:      true,
+		CancellableSteps: 1,
+	}
+}
+
+func TestOrchestrator_ScheduledSagaCanBeCancelledDuringGracePeriod(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	orchestrator := saga.NewOrchestrator(store, saga.Config{}, deletionDefinition(rec, nil))
+
+	instance, err := orchestrator.Schedule("account_deletion", "profile-1", saga.Data{"email": "john@example.com"}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, saga.StatusRunning, instance.Status)
+	assert.Equal(t, "grace_period", instance.StepName)
+
+	// Nothing runs before the grace period has passed
+	resumed, err := orchestrator.ResumeOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, resumed)
+
+	cancelled, err := orchestrator.Cancel("account_deletion", "profile-1")
+	require.NoError(t, err)
+	assert.Equal(t, saga.StatusCancelled, cancelled.Status)
+
+	stored := store.get(instance.ID)
+	assert.Equal(t, saga.StatusCancelled, stored.Status)
+	assert.NotNil(t, stored.EndedAt)
+	assert.NotContains(t, string(stored.Data), "john@example.com")
+	steps, err := saga.StepLog(&stored)
+	require.NoError(t, err)
+	assert.Equal(t, []string{saga.StepCancelled, saga.StepPending, saga.StepPending}, stepStatuses(steps))
+	assert.Empty(t, rec.calls)
+
+	// A cancelled deletion can be requested again
+	_, err = orchestrator.Schedule("account_deletion", "profile-1", saga.Data{}, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+}
+
+func TestOrchestrator_CancelIsRefusedOnceGracePeriodIsOver(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	orchestrator := saga.NewOrchestrator(store, saga.Config{}, deletionDefinition(rec, nil))
+
+	instance, err := orchestrator.Schedule("account_deletion", "profile-1", saga.Data{}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	store.elapse(instance.ID)
+	_, err = orchestrator.ResumeOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, saga.StatusCompleted, store.get(instance.ID).Status)
+
+	_, err = orchestrator.Cancel("account_deletion", "profile-1")
+	assert.ErrorIs(t, err, saga.ErrNotCancellable)
+	assert.Equal(t, []string{"execute grace_period", "execute revoke_sessions", "execute purge_auth_data"}, rec.calls)
+
+	latest, err := orchestrator.Latest("account_deletion", "profile-1")
+	require.NoError(t, err)
+	assert.Equal(t, instance.ID, latest.ID)
+}
+
+func TestOrchestrator_ForwardOnlySagaRetriesBeyondMaxAttempts(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	definition := deletionDefinition(rec, failing(errors.New("database unavailable"), 3))
+	definition.MaxAttempts = 2
+	orchestrator := saga.NewOrchestrator(store, saga.Config{}, definition)
+
+	instance, err := orchestrator.Schedule("account_deletion", "profile-1", saga.Data{}, time.Now())
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		store.elapse(instance.ID)
+		_, err = orchestrator.ResumeOnce(context.Background())
+		require.NoError(t, err)
+	}
+
+	stored := store.get(instance.ID)
+	assert.Equal(t, saga.StatusCompleted, stored.Status)
+	steps, err := saga.StepLog(&stored)
+	require.NoError(t, err)
+	assert.Equal(t, []string{saga.StepCompleted, saga.StepCompleted, saga.StepCompleted}, stepStatuses(steps))
+	assert.Equal(t, 3, steps[2].Attempts)
+	assert.Empty(t, steps[2].LastError) // Cleared once the step completed
+}
+
+func TestOrchestrator_ForwardOnlySagaFailsWithoutCompensating(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	orchestrator := saga.NewOrchestrator(store, saga.Config{}, deletionDefinition(rec, failing(saga.Permanent(errors.New("rejected")), 1)))
+
+	instance, err := orchestrator.Schedule("account_deletion", "profile-1", saga.Data{}, time.Now())
+	require.NoError(t, err)
+	_, err = orchestrator.ResumeOnce(context.Background())
+	require.NoError(t, err)
+
+	stored := store.get(instance.ID)
+	assert.Equal(t, saga.StatusFailed, stored.Status)
+	require.NotNil(t, stored.LastError)
+	assert.Contains(t, *stored.LastError, "rejected")
+	steps, err := saga.StepLog(&stored)
+	require.NoError(t, err)
+	assert.Equal(t, []string{saga.StepCompleted, saga.StepCompleted, saga.StepFailed}, stepStatuses(steps))
+	assert.Equal(t, "rejected", steps[2].LastError)
+	assert.NotContains(t, rec.calls, "compensate revoke_sessions")
+}
+
+func stepStatuses(steps []saga.StepState) []string {
+	statuses := make([]string, len(steps))
+	for i, step := range steps {
+		statuses[i] = step.Status
+	}
+	return statuses
+}
+
+// hanging returns a step that hangs on its first execution until release is closed, closing started once it hangs
+func hanging(rec *recorder, name string, started, release chan struct{}) saga.Step {
+	step := rec.step(name, nil)
+	execute := step.Execute
+	var once sync.Once
+	step.Execute = func(ctx context.Context, data saga.Data) error {
+		first := false
+		once.Do(func() { first = true })
+		if first {
+			close(started)
+			<-release
+		}
+		return execute(ctx, data)
+	}
+	return step
+}
+
+// startInBackground starts a saga from another instance and returns the channel receiving the error of Start
+func startInBackground(store *memoryStore, definition saga.Definition, key string) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := saga.NewOrchestrator(store, saga.Config{}, definition).Start(context.Background(), definition.Type, key, saga.Data{})
+		done <- err
+	}()
+	return done
+}
+
+func TestOrchestrator_InstanceThatLostItsLeaseStopsWithoutWriting(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	started, release := make(chan struct{}), make(chan struct{})
+	definition := saga.Definition{
+		Type:  "registration",
+		Steps: []saga.Step{hanging(rec, "profile", started, release), rec.step("account", nil)},
+	}
+
+	first := startInBackground(store, definition, "john@example.com")
+	<-started
+
+	// The lease of the first instance expires while its step hangs, and a second instance takes the saga over
+	store.elapse("saga-1")
+	resumed, err := saga.NewOrchestrator(store, saga.Config{}, definition).ResumeOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, resumed)
+	completed := store.get("saga-1")
+	assert.Equal(t, saga.StatusCompleted, completed.Status)
+
+	close(release)
+	assert.ErrorIs(t, <-first, saga.ErrLeaseLost)
+	assert.Equal(t, completed, store.get("saga-1"), "the first instance does not overwrite the completed saga")
+	assert.Equal(t, []string{"execute profile", "execute account", "execute profile"}, rec.calls,
+		"the first instance runs no step past the one it lost the lease in")
+}
+
+func TestOrchestrator_CancelDuringARunSticks(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	started, release := make(chan struct{}), make(chan struct{})
+	definition := saga.Definition{
+		Type:             "account_deletion",
+		Steps:            []saga.Step{hanging(rec, "grace_period", started, release), rec.step("purge_auth_data", nil)},
+		ForwardOnly:      true,
+		CancellableSteps: 1,
+	}
+
+	running := startInBackground(store, definition, "profile-1")
+	<-started
+
+	// The step outlives the lease, so the user can cancel while the run still holds the saga in memory
+	store.elapse("saga-1")
+	cancelled, err := saga.NewOrchestrator(store, saga.Config{}, definition).Cancel("account_deletion", "profile-1")
+	require.NoError(t, err)
+	assert.Equal(t, saga.StatusCancelled, cancelled.Status)
+
+	close(release)
+	assert.ErrorIs(t, <-running, saga.ErrLeaseLost)
+	stored := store.get("saga-1")
+	assert.Equal(t, saga.StatusCancelled, stored.Status, "the run does not write the saga back as running")
+	assert.NotNil(t, stored.EndedAt)
+	assert.NotContains(t, rec.calls, "execute purge_auth_data")
+}
+
+func TestOrchestrator_RenewsTheLeaseBeforeEachStep(t *testing.T) {
+	store := newMemoryStore()
+	var leases []time.Time
+	step := func(name string) saga.Step {
+		return saga.Step{Name: name, Execute: func(context.Context, saga.Data) error {
+			leases = append(leases, *store.get("saga-1").LockedUntil)
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		}}
+	}
+	definition := saga.Definition{Type: "registration", Steps: []saga.Step{step("profile"), step("account")}}
+
+	_, err := saga.NewOrchestrator(store, saga.Config{Lease: time.Minute}, definition).
+		Start(context.Background(), "registration", "john@example.com", saga.Data{})
+
+	require.NoError(t, err)
+	require.Len(t, leases, 2)
+	assert.True(t, leases[1].After(leases[0]), "the second step runs under a lease renewed after the first")
+}
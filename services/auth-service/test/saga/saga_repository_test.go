@@ -0,0 +1,42 @@
+package saga
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/saga"
+	"github.com/Mir00r/auth-service/test/testenv"
+)
+
+func TestSagaRepository_SaveRefusesAStaleCopy(t *testing.T) {
+	repo := repositories.NewSagaRepository(testenv.NewDB(t, []interface{}{&entities.SagaInstance{}},
+		"CREATE UNIQUE INDEX auth.uq_saga_instance_unfinished ON saga_instance (saga_type, saga_key) WHERE ended_at IS NULL",
+	))
+	instance := &entities.SagaInstance{
+		SagaType: "registration", SagaKey: "john@example.com", Status: saga.StatusRunning,
+		Data: []byte("{}"), StepLog: []byte("[]"), NextAttemptAt: time.Now(),
+	}
+	require.NoError(t, repo.Create(instance))
+	stale := *instance
+
+	instance.Step = 1
+	require.NoError(t, repo.Save(instance))
+	assert.EqualValues(t, 1, instance.Version)
+
+	stale.Status = saga.StatusCompleted
+	assert.ErrorIs(t, repo.Save(&stale), saga.ErrLeaseLost)
+	assert.EqualValues(t, 0, stale.Version, "a refused save leaves the copy as it was read")
+
+	stored, err := repo.FindByID(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, saga.StatusRunning, stored.Status)
+	assert.Equal(t, 1, stored.Step)
+
+	instance.Step = 2
+	require.NoError(t, repo.Save(instance), "the current copy keeps saving")
+}
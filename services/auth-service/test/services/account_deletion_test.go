@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/saga"
+)
+
+// deletionService returns the account deletion service with the orchestrator running its saga
+func (f *fixture) deletionService() (services.AccountDeletionService, *saga.Orchestrator) {
+	orchestrator := saga.NewOrchestrator(f.sagaStore(), saga.Config{Lease: time.Minute}, services.NewAccountDeletionSaga(
+		repositories.NewUserRepository(f.db),
+		repositories.NewTokenRepository(f.db),
+		repositories.NewPasswordlessRepository(f.db),
+		repositories.NewOIDCStateRepository(f.db),
+		f.users.Client,
+	))
+	svc := services.NewAccountDeletionService(orchestrator, repositories.NewUserRepository(f.db), f.identityService(), f.users.Client)
+	return svc, orchestrator
+}
+
+// requestDeletion has the user request the deletion of their account, confirmed with their password
+func (f *fixture) requestDeletion(t *testing.T, svc services.AccountDeletionService) *dtos.AccountDeletionResponse {
+	t.Helper()
+	f.identity(t, constants.IdentityProviderPassword, f.user.ID)
+	response, err := svc.RequestDeletion(context.Background(), f.user.ID, time.Now(), dtos.ReauthenticationRequest{Password: "old-password"})
+	require.NoError(t, err)
+	return response
+}
+
+// runDeletion makes the deletion due, as if its grace period or retry backoff were over, and resumes it once
+func (f *fixture) runDeletion(t *testing.T, orchestrator *saga.Orchestrator, deletionID string) *entities.SagaInstance {
+	t.Helper()
+	require.NoError(t, f.db.Model(&entities.SagaInstance{}).Where("id = ?", deletionID).
+		Update("next_attempt_at", time.Now()).Error)
+	resumed, err := orchestrator.ResumeOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, resumed)
+
+	instance, err := f.sagaStore().FindLatest(constants.SagaTypeAccountDeletion, f.user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, instance)
+	return instance
+}
+
+func TestRequestDeletion_CancelledDuringTheGracePeriod(t *testing.T) {
+	f := newFixture(t)
+	svc, orchestrator := f.deletionService()
+	f.session(t)
+
+	response := f.requestDeletion(t, svc)
+	assert.Equal(t, saga.StatusRunning, response.Status)
+	assert.True(t, response.Cancellable)
+	require.NotNil(t, response.ScheduledFor)
+	assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), *response.ScheduledFor, time.Minute)
+
+	resumed, err := orchestrator.ResumeOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, resumed, "nothing runs before the grace period is over")
+
+	require.NoError(t, svc.CancelDeletion(context.Background(), f.user.ID))
+	deletion, err := svc.GetDeletion(context.Background(), f.user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, saga.StatusCancelled, deletion.Status)
+	assert.False(t, deletion.Cancellable)
+
+	assert.True(t, f.users.Get(f.user.Email).Active, "the profile is left active")
+	assert.EqualValues(t, 1, f.count(t, &entities.User{}, "id = ?", f.user.ID))
+	assert.EqualValues(t, 1, f.count(t, &entities.Token{}, "user_id = ?", f.user.ID), "the session keeps working")
+
+	_, err = svc.RequestDeletion(context.Background(), f.user.ID, time.Now(), dtos.ReauthenticationRequest{Password: "old-password"})
+	assert.NoError(t, err, "the account can be scheduled for deletion again")
+}
+
+func TestRequestDeletion_RequiresThePassword(t *testing.T) {
+	f := newFixture(t)
+	svc, _ := f.deletionService()
+	f.identity(t, constants.IdentityProviderPassword, f.user.ID)
+
+	_, err := svc.RequestDeletion(context.Background(), f.user.ID, time.Now(), dtos.ReauthenticationRequest{Password: "wrong-password"})
+
+	assert.Error(t, err)
+	_, err = svc.GetDeletion(context.Background(), f.user.ID)
+	assert.Equal(t, errors.ErrDeletionNotFound, err)
+}
+
+func TestRequestDeletion_RefusesASecondDeletion(t *testing.T) {
+	f := newFixture(t)
+	svc, _ := f.deletionService()
+	f.requestDeletion(t, svc)
+
+	_, err := svc.RequestDeletion(context.Background(), f.user.ID, time.Now(), dtos.ReauthenticationRequest{Password: "old-password"})
+
+	assert.Equal(t, errors.ErrDeletionAlreadyScheduled, err)
+}
+
+func TestCancelDeletion_RefusedOnceTheGracePeriodIsOver(t *testing.T) {
+	f := newFixture(t)
+	svc, orchestrator := f.deletionService()
+	assert.Equal(t, errors.ErrDeletionNotFound, svc.CancelDeletion(context.Background(), f.user.ID))
+
+	response := f.requestDeletion(t, svc)
+	f.users.Fail("PUT /v1/internal/user/{userId}/deactivate")
+	instance := f.runDeletion(t, orchestrator, response.DeletionID)
+	require.Equal(t, constants.DeletionStepRevokeSessions, instance.StepName)
+
+	assert.Equal(t, errors.ErrDeletionNotCancellable, svc.CancelDeletion(context.Background(), f.user.ID))
+	deletion, err := svc.GetDeletion(context.Background(), f.user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, saga.StatusRunning, deletion.Status)
+	assert.False(t, deletion.Cancellable)
+}
+
+func TestAccountDeletion_RetriesForwardWhileUserServiceFails(t *testing.T) {
+	f := newFixture(t)
+	svc, orchestrator := f.deletionService()
+	response := f.requestDeletion(t, svc)
+	f.users.Fail("POST /v1/internal/user/{userId}/anonymize")
+
+	instance := f.runDeletion(t, orchestrator, response.DeletionID)
+	assert.Equal(t, saga.StatusRunning, instance.Status, "a failed step is retried rather than undone")
+	assert.Equal(t, constants.DeletionStepAnonymizeProfile, instance.StepName)
+	assert.Equal(t, []string{saga.StepCompleted, saga.StepCompleted, saga.StepCompleted, saga.StepRetrying}, stepStatuses(t, instance))
+	assert.EqualValues(t, 0, f.count(t, &entities.User{}, "id = ?", f.user.ID), "the steps already run are kept")
+	assert.False(t, f.users.Get(f.user.Email).Active)
+
+	instance = f.runDeletion(t, orchestrator, response.DeletionID)
+	assert.Equal(t, saga.StatusRunning, instance.Status, "the step is retried for as long as user-service fails")
+
+	f.users.Recover()
+	instance = f.runDeletion(t, orchestrator, response.DeletionID)
+	assert.Equal(t, saga.StatusCompleted, instance.Status)
+	assert.True(t, f.users.Get(f.user.Email).Deleted, "the profile is anonymized")
+	assert.NotContains(t, string(instance.Data), f.user.Email, "the email does not outlive the saga")
+}
+
+func TestAccountDeletion_PurgesTheAuthData(t *testing.T) {
+	f := newFixture(t)
+	svc, orchestrator := f.deletionService()
+	response := f.requestDeletion(t, svc)
+
+	f.session(t)
+	f.resetToken(t, time.Now().Add(time.Hour))
+	f.enableMFA(t)
+	f.challenge(t, constants.PasswordlessMethodLink, "link-token", time.Now().Add(time.Minute))
+	require.NoError(t, f.db.Create(&entities.EmailVerificationToken{UserID: f.user.ID, TokenHash: "verification-hash",
+		ExpiresAt: time.Now().Add(time.Hour)}).Error)
+	require.NoError(t, f.db.Create(&entities.OIDCLoginState{State: "link-state", Provider: "google", Nonce: "nonce",
+		CodeVerifier: "verifier", LinkUserID: &f.user.ID, ExpiresAt: time.Now().Add(time.Minute)}).Error)
+	other := entities.User{Name: "Bob", Email: "bob@example.com"}
+	require.NoError(t, f.db.Create(&other).Error)
+	require.NoError(t, f.db.Create(&entities.Token{UserID: other.ID, Token: "access-bob", RefreshToken: "refresh-bob",
+		Type: constants.RefreshToken, ExpiresAt: time.Now().Add(time.Hour), RefreshTokenExpiresAt: time.Now().Add(time.Hour)}).Error)
+
+	instance := f.runDeletion(t, orchestrator, response.DeletionID)
+	require.Equal(t, saga.StatusCompleted, instance.Status)
+
+	// Soft-deleted rows count as left behind
+	left := func(model interface{}, query string) int64 {
+		var n int64
+		require.NoError(t, f.db.Unscoped().Model(model).Where(query, f.user.ID).Count(&n).Error)
+		return n
+	}
+	assert.Zero(t, left(&entities.User{}, "id = ?"))
+	for _, model := range []interface{}{
+		&entities.Token{}, &entities.PasswordResetToken{}, &entities.Mfa{},
+		&entities.PasswordlessChallenge{}, &entities.UserIdentity{}, &entities.EmailVerificationToken{},
+	} {
+		assert.Zero(t, left(model, "user_id = ?"), "%T rows of the user are left", model)
+	}
+	assert.Zero(t, left(&entities.OIDCLoginState{}, "link_user_id = ?"))
+	assert.EqualValues(t, 1, f.count(t, &entities.Token{}, "user_id = ?", other.ID), "other accounts are left alone")
+}
+
+func TestAccountDeletion_ProfileGoneFromUserServiceCountsAsDone(t *testing.T) {
+	f := newFixture(t)
+	svc, orchestrator := f.deletionService()
+
+	// An admin deletion of an account that never signed in to auth-service, whose profile is removed meanwhile
+	require.NoError(t, svc.ScheduleDeletion(context.Background(), "missing-profile", "gone@example.com", "admin-id"))
+	require.NoError(t, svc.ScheduleDeletion(context.Background(), "missing-profile", "gone@example.com", "admin-id"),
+		"a redelivered request is harmless")
+	require.NoError(t, f.db.Model(&entities.SagaInstance{}).Where("saga_key = ?", "missing-profile").
+		Update("next_attempt_at", time.Now()).Error)
+	resumed, err := orchestrator.ResumeOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, resumed)
+
+	instance, err := f.sagaStore().FindLatest(constants.SagaTypeAccountDeletion, "missing-profile")
+	require.NoError(t, err)
+	assert.Equal(t, saga.StatusCompleted, instance.Status)
+	assert.EqualValues(t, 1, f.count(t, &entities.User{}, "id = ?", f.user.ID), "no other account is touched")
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/test/testenv"
+)
+
+const newPassword = "N3w-Passw0rd!"
+
+func (f *fixture) authService() services.AuthService {
+	userRepo := repositories.NewUserRepository(f.db)
+	return services.NewAuthService(
+		userRepo,
+		repositories.NewTokenRepository(f.db),
+		repositories.NewIdentityRepository(f.db),
+		repositories.NewOutboxRepository(f.db),
+		services.NewMFAService(repositories.NewMFARepository(f.db), userRepo),
+		f.users.Client,
+	)
+}
+
+// enableMFA turns MFA on for the user and saves an unused step-up code, which it returns
+func (f *fixture) enableMFA(t *testing.T) string {
+	t.Helper()
+	require.NoError(t, f.db.Model(&entities.User{}).Where("id = ?", f.user.ID).Update("mfa_enabled", true).Error)
+	require.NoError(t, f.db.Create(&entities.Mfa{UserID: f.user.ID, OTP: "123456", Purpose: constants.MFAPurposeStepUp,
+		ExpiresAt: time.Now().Add(time.Minute)}).Error)
+	return "123456"
+}
+
+func TestChangePassword_RequiresOTPWhenMFAIsEnabled(t *testing.T) {
+	f := newFixture(t)
+	f.enableMFA(t)
+
+	_, err := f.authService().ChangePassword(context.Background(), f.user.ID,
+		dtos.ChangePasswordRequest{CurrentPassword: "old-password", NewPassword: newPassword})
+
+	assert.Equal(t, errors.ErrMFACodeRequired, err)
+	assert.Empty(t, f.users.PasswordsSet())
+}
+
+func TestChangePassword_RequiresOTPWhenUserServiceHoldsAnMFASecret(t *testing.T) {
+	f := newFixture(t)
+	f.users.Update(f.user.Email, func(account *testenv.Account) { account.MFA = true })
+
+	_, err := f.authService().ChangePassword(context.Background(), f.user.ID,
+		dtos.ChangePasswordRequest{CurrentPassword: "old-password", NewPassword: newPassword})
+
+	assert.Equal(t, errors.ErrMFACodeRequired, err)
+	assert.Empty(t, f.users.PasswordsSet())
+}
+
+func TestChangePassword_RejectsWrongOTP(t *testing.T) {
+	f := newFixture(t)
+	f.enableMFA(t)
+
+	_, err := f.authService().ChangePassword(context.Background(), f.user.ID,
+		dtos.ChangePasswordRequest{CurrentPassword: "old-password", NewPassword: newPassword, OTP: "654321"})
+
+	assert.Error(t, err)
+	assert.Empty(t, f.users.PasswordsSet())
+}
+
+func TestChangePassword_AcceptsValidOTP(t *testing.T) {
+	f := newFixture(t)
+	otp := f.enableMFA(t)
+
+	response, err := f.authService().ChangePassword(context.Background(), f.user.ID,
+		dtos.ChangePasswordRequest{CurrentPassword: "old-password", NewPassword: newPassword, OTP: otp})
+
+	require.NoError(t, err)
+	assert.False(t, response.SessionsRevoked)
+	assert.Equal(t, newPassword, f.users.Get(f.user.Email).Password)
+	assert.Zero(t, f.count(t, &entities.Mfa{}, "used = ?", false), "the code is single use")
+}
+
+func TestChangePassword_RejectsCodeIssuedForEnrollment(t *testing.T) {
+	f := newFixture(t)
+	f.enableMFA(t)
+	_, err := f.mfaService().EnableMFA(context.Background(), f.user.ID)
+	require.NoError(t, err)
+
+	_, err = f.authService().ChangePassword(context.Background(), f.user.ID,
+		dtos.ChangePasswordRequest{CurrentPassword: "old-password", NewPassword: newPassword, OTP: f.issuedCode(t, constants.MFAPurposeEnrollment)})
+
+	assert.Error(t, err)
+	assert.Empty(t, f.users.PasswordsSet())
+}
+
+func TestChangePassword_RejectsIncorrectCurrentPassword(t *testing.T) {
+	f := newFixture(t)
+
+	_, err := f.authService().ChangePassword(context.Background(), f.user.ID,
+		dtos.ChangePasswordRequest{CurrentPassword: "wrong-password", NewPassword: newPassword})
+
+	assert.Equal(t, errors.ErrIncorrectCurrentPassword, err)
+	assert.Empty(t, f.users.PasswordsSet())
+}
+
+func TestChangePassword_RevokesOtherSessionsAndKeepsTheCallerSignedIn(t *testing.T) {
+	f := newFixture(t)
+	other := f.session(t)
+
+	response, err := f.authService().ChangePassword(context.Background(), f.user.ID,
+		dtos.ChangePasswordRequest{CurrentPassword: "old-password", NewPassword: newPassword, RevokeOtherSessions: true})
+
+	require.NoError(t, err)
+	assert.True(t, response.SessionsRevoked)
+	require.NotNil(t, response.Tokens)
+	assert.Zero(t, f.count(t, &entities.Token{}, "refresh_token = ?", other), "the other session is revoked")
+	assert.EqualValues(t, 1, f.count(t, &entities.Token{}, "refresh_token = ?", response.Tokens.RefreshToken), "the caller gets a fresh session")
+}
+
+func TestChangePassword_KeepsOtherSessionsUnlessAsked(t *testing.T) {
+	f := newFixture(t)
+	other := f.session(t)
+
+	response, err := f.authService().ChangePassword(context.Background(), f.user.ID,
+		dtos.ChangePasswordRequest{CurrentPassword: "old-password", NewPassword: newPassword})
+
+	require.NoError(t, err)
+	assert.False(t, response.SessionsRevoked)
+	assert.Nil(t, response.Tokens)
+	assert.EqualValues(t, 1, f.count(t, &entities.Token{}, "refresh_token = ?", other))
+}
+
+func TestAuthenticate_RecordsTheLastLoginDirectlyWithoutABroker(t *testing.T) {
+	f := newFixture(t)
+	before := time.Now()
+
+	_, err := f.authService().Authenticate(context.Background(), dtos.LoginRequest{Email: f.user.Email, Password: "old-password"})
+
+	require.NoError(t, err)
+	assert.WithinRange(t, f.users.Get(f.user.Email).LastLogin, before, time.Now())
+	assert.EqualValues(t, 1, f.count(t, &entities.OutboxEvent{}, "event_type = ?", constants.EventLoginSucceeded))
+}
+
+func TestAuthenticate_LeavesTheLastLoginToTheEventWithABroker(t *testing.T) {
+	f := newFixture(t)
+	config.AppConfig.Messaging.NatsURL = "nats://localhost:4222"
+
+	_, err := f.authService().Authenticate(context.Background(), dtos.LoginRequest{Email: f.user.Email, Password: "old-password"})
+
+	require.NoError(t, err)
+	assert.Zero(t, f.users.Get(f.user.Email).LastLogin)
+	assert.EqualValues(t, 1, f.count(t, &entities.OutboxEvent{}, "event_type = ?", constants.EventLoginSucceeded))
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/services"
+)
+
+func (f *fixture) identityService() services.IdentityService {
+	userRepo := repositories.NewUserRepository(f.db)
+	return services.NewIdentityService(
+		repositories.NewIdentityRepository(f.db),
+		userRepo,
+		repositories.NewTokenRepository(f.db),
+		services.NewMFAService(repositories.NewMFARepository(f.db), userRepo),
+		nil,
+		f.users.Client,
+	)
+}
+
+// identity links an identity of the provider to the user and returns it
+func (f *fixture) identity(t *testing.T, provider, subject string) entities.UserIdentity {
+	t.Helper()
+	identity := entities.UserIdentity{UserID: f.user.ID, Provider: provider, Subject: subject}
+	require.NoError(t, f.db.Create(&identity).Error)
+	return identity
+}
+
+func TestUnlink_PasswordScramblesPasswordAndDropsResetLinks(t *testing.T) {
+	f := newFixture(t)
+	password := f.identity(t, constants.IdentityProviderPassword, f.user.ID)
+	f.identity(t, "google", "google-subject")
+	f.resetToken(t, time.Now().Add(time.Hour))
+
+	err := f.identityService().Unlink(context.Background(), f.user.ID, password.ID, time.Now(), dtos.ReauthenticationRequest{Password: "old-password"})
+
+	require.NoError(t, err)
+	assert.Len(t, f.users.PasswordsSet(), 1)
+	assert.NotEqual(t, "old-password", f.users.Get(f.user.Email).Password)
+	assert.Zero(t, f.count(t, &entities.UserIdentity{}, "id = ?", password.ID))
+	assert.Zero(t, f.count(t, &entities.PasswordResetToken{}, "used = ?", false), "no reset link brings the password back")
+}
+
+func TestUnlink_UserServiceFailureKeepsThePasswordIdentity(t *testing.T) {
+	f := newFixture(t)
+	password := f.identity(t, constants.IdentityProviderPassword, f.user.ID)
+	f.identity(t, "google", "google-subject")
+	f.resetToken(t, time.Now().Add(time.Hour))
+	f.users.Fail("PUT /v1/internal/user/password")
+
+	err := f.identityService().Unlink(context.Background(), f.user.ID, password.ID, time.Now(), dtos.ReauthenticationRequest{Password: "old-password"})
+
+	assert.Error(t, err)
+	assert.Equal(t, "old-password", f.users.Get(f.user.Email).Password)
+	assert.EqualValues(t, 1, f.count(t, &entities.UserIdentity{}, "id = ?", password.ID), "the identity stays linked")
+	assert.EqualValues(t, 1, f.count(t, &entities.PasswordResetToken{}, "used = ?", false), "the reset link stays usable")
+}
+
+func TestUnlink_RefusesLastIdentity(t *testing.T) {
+	f := newFixture(t)
+	password := f.identity(t, constants.IdentityProviderPassword, f.user.ID)
+
+	err := f.identityService().Unlink(context.Background(), f.user.ID, password.ID, time.Now(), dtos.ReauthenticationRequest{Password: "old-password"})
+
+	assert.Equal(t, errors.ErrCannotUnlinkLastIdentity, err)
+	assert.Empty(t, f.users.PasswordsSet(), "the password is not scrambled")
+	assert.EqualValues(t, 1, f.count(t, &entities.UserIdentity{}, "id = ?", password.ID))
+}
+
+func TestUnlink_ProviderKeepsThePassword(t *testing.T) {
+	f := newFixture(t)
+	f.identity(t, constants.IdentityProviderPassword, f.user.ID)
+	google := f.identity(t, "google", "google-subject")
+	f.resetToken(t, time.Now().Add(time.Hour))
+
+	err := f.identityService().Unlink(context.Background(), f.user.ID, google.ID, time.Now(), dtos.ReauthenticationRequest{Password: "old-password"})
+
+	require.NoError(t, err)
+	assert.Empty(t, f.users.PasswordsSet())
+	assert.Zero(t, f.count(t, &entities.UserIdentity{}, "id = ?", google.ID))
+	assert.EqualValues(t, 1, f.count(t, &entities.PasswordResetToken{}, "used = ?", false))
+}
+
+func TestUnlink_RequiresCurrentPassword(t *testing.T) {
+	f := newFixture(t)
+	password := f.identity(t, constants.IdentityProviderPassword, f.user.ID)
+	f.identity(t, "google", "google-subject")
+
+	err := f.identityService().Unlink(context.Background(), f.user.ID, password.ID, time.Now(), dtos.ReauthenticationRequest{Password: "wrong-password"})
+
+	assert.Equal(t, errors.ErrIncorrectCurrentPassword, err)
+	assert.Empty(t, f.users.PasswordsSet())
+	assert.EqualValues(t, 2, f.count(t, &entities.UserIdentity{}, "user_id = ?", f.user.ID))
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/services"
+)
+
+func (f *fixture) mfaService() services.MFAService {
+	return services.NewMFAService(repositories.NewMFARepository(f.db), repositories.NewUserRepository(f.db))
+}
+
+// issuedCode returns the latest code stored for purpose, standing in for the email the user receives
+func (f *fixture) issuedCode(t *testing.T, purpose string) string {
+	t.Helper()
+	var mfa entities.Mfa
+	require.NoError(t, f.db.Where("user_id = ? AND purpose = ?", f.user.ID, purpose).Order("created_at DESC").First(&mfa).Error)
+	return mfa.OTP
+}
+
+func TestEnableMFA_DeliversTheCodeOnlyByEmail(t *testing.T) {
+	f := newFixture(t)
+
+	response, err := f.mfaService().EnableMFA(context.Background(), f.user.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, constants.MFAChallengeSent, response.Message)
+	assert.Equal(t, int64(1), f.count(t, &entities.Mfa{}, "purpose = ?", constants.MFAPurposeEnrollment))
+}
+
+func TestConfirmMFA_TurnsMFAOnWithTheEnrollmentCode(t *testing.T) {
+	f := newFixture(t)
+	_, err := f.mfaService().EnableMFA(context.Background(), f.user.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, f.mfaService().ConfirmMFA(context.Background(), f.user.ID, f.issuedCode(t, constants.MFAPurposeEnrollment)))
+
+	assert.Equal(t, int64(1), f.count(t, &entities.User{}, "id = ? AND mfa_enabled = ?", f.user.ID, true))
+}
+
+func TestVerifyMFA_RejectsTheEnrollmentCode(t *testing.T) {
+	f := newFixture(t)
+	_, err := f.mfaService().EnableMFA(context.Background(), f.user.ID)
+	require.NoError(t, err)
+
+	err = f.mfaService().VerifyMFA(context.Background(), f.user.ID, f.issuedCode(t, constants.MFAPurposeEnrollment))
+
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), f.count(t, &entities.Mfa{}, "used = ?", false), "the enrollment code stays unused")
+}
+
+func TestVerifyMFA_AcceptsAStepUpCodeOnce(t *testing.T) {
+	f := newFixture(t)
+	require.NoError(t, f.mfaService().RequestStepUp(context.Background(), f.user.ID))
+	code := f.issuedCode(t, constants.MFAPurposeStepUp)
+
+	require.NoError(t, f.mfaService().VerifyMFA(context.Background(), f.user.ID, code))
+	assert.Error(t, f.mfaService().VerifyMFA(context.Background(), f.user.ID, code))
+}
+
+func TestConfirmMFA_RejectsAStepUpCode(t *testing.T) {
+	f := newFixture(t)
+	require.NoError(t, f.mfaService().RequestStepUp(context.Background(), f.user.ID))
+
+	err := f.mfaService().ConfirmMFA(context.Background(), f.user.ID, f.issuedCode(t, constants.MFAPurposeStepUp))
+
+	assert.Error(t, err)
+	assert.Zero(t, f.count(t, &entities.User{}, "id = ? AND mfa_enabled = ?", f.user.ID, true))
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/Mir00r/auth-service/test/testenv"
+)
+
+const (
+	device      = "laptop"
+	otherDevice = "phone"
+	code        = "123456"
+)
+
+func (f *fixture) passwordlessService() services.PasswordlessService {
+	return services.NewPasswordlessService(
+		repositories.NewPasswordlessRepository(f.db),
+		repositories.NewUserRepository(f.db),
+		repositories.NewTokenRepository(f.db),
+		repositories.NewOutboxRepository(f.db),
+		f.users.Client,
+	)
+}
+
+// challenge saves a challenge of the user requested from device, redeemed with the secret, and returns it
+func (f *fixture) challenge(t *testing.T, method, secret string, expiresAt time.Time) entities.PasswordlessChallenge {
+	t.Helper()
+	hash := utils.HashToken(secret)
+	challenge := entities.PasswordlessChallenge{
+		UserID:     f.user.ID,
+		Method:     method,
+		DeviceHash: utils.HashToken(device),
+		Status:     constants.PasswordlessPending,
+		ExpiresAt:  expiresAt,
+	}
+	if method == constants.PasswordlessMethodCode {
+		challenge.CodeHash = &hash
+	} else {
+		challenge.TokenHash = &hash
+	}
+	require.NoError(t, f.db.Create(&challenge).Error)
+	return challenge
+}
+
+// reload returns the challenge as stored
+func (f *fixture) reload(t *testing.T, challenge entities.PasswordlessChallenge) entities.PasswordlessChallenge {
+	t.Helper()
+	var stored entities.PasswordlessChallenge
+	require.NoError(t, f.db.First(&stored, "id = ?", challenge.ID).Error)
+	return stored
+}
+
+func TestVerifyLogin_LinkSignsInOnce(t *testing.T) {
+	f := newFixture(t)
+	f.challenge(t, constants.PasswordlessMethodLink, "link-token", time.Now().Add(time.Minute))
+	svc := f.passwordlessService()
+
+	result, err := svc.VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{Token: "link-token", DeviceID: device})
+	require.NoError(t, err)
+	require.NotNil(t, result.Login)
+
+	_, err = svc.VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{Token: "link-token", DeviceID: device})
+	assert.Equal(t, errors.ErrInvalidOrExpiredLoginLink, err)
+	assert.EqualValues(t, 1, f.count(t, &entities.Token{}, "user_id = ?", f.user.ID))
+}
+
+func TestVerifyLogin_RejectsExpiredLink(t *testing.T) {
+	f := newFixture(t)
+	f.challenge(t, constants.PasswordlessMethodLink, "link-token", time.Now().Add(-time.Second))
+
+	_, err := f.passwordlessService().VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{Token: "link-token", DeviceID: device})
+
+	assert.Equal(t, errors.ErrInvalidOrExpiredLoginLink, err)
+	assert.Zero(t, f.count(t, &entities.Token{}, "user_id = ?", f.user.ID))
+}
+
+func TestVerifyLogin_RejectsInactiveAccount(t *testing.T) {
+	f := newFixture(t)
+	f.challenge(t, constants.PasswordlessMethodLink, "link-token", time.Now().Add(time.Minute))
+	f.users.Update(f.user.Email, func(account *testenv.Account) { account.Active = false })
+
+	_, err := f.passwordlessService().VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{Token: "link-token", DeviceID: device})
+
+	assert.Error(t, err)
+	assert.Zero(t, f.count(t, &entities.Token{}, "user_id = ?", f.user.ID))
+}
+
+func TestVerifyLogin_OtherDeviceWaitsForConfirmation(t *testing.T) {
+	f := newFixture(t)
+	challenge := f.challenge(t, constants.PasswordlessMethodLink, "link-token", time.Now().Add(time.Minute))
+	svc := f.passwordlessService()
+	verify := dtos.PasswordlessVerifyRequest{Token: "link-token", DeviceID: otherDevice}
+
+	result, err := svc.VerifyLogin(context.Background(), verify)
+	require.NoError(t, err)
+	assert.True(t, result.ConfirmationRequired)
+	assert.Nil(t, result.Login)
+	assert.Equal(t, constants.PasswordlessAwaitingConfirmation, f.reload(t, challenge).Status)
+
+	assert.Equal(t, utils.HashToken(otherDevice), result.PendingDevice)
+
+	// Only the requesting device may approve
+	err = svc.ConfirmLogin(context.Background(),
+		dtos.PasswordlessConfirmRequest{ChallengeID: challenge.ID, DeviceID: otherDevice, PendingDevice: result.PendingDevice})
+	assert.Equal(t, errors.ErrInvalidOrExpiredLoginLink, err)
+
+	require.NoError(t, svc.ConfirmLogin(context.Background(),
+		dtos.PasswordlessConfirmRequest{ChallengeID: challenge.ID, DeviceID: device, PendingDevice: result.PendingDevice}))
+	result, err = svc.VerifyLogin(context.Background(), verify)
+	require.NoError(t, err)
+	assert.NotNil(t, result.Login)
+	assert.Equal(t, constants.PasswordlessRedeemed, f.reload(t, challenge).Status)
+}
+
+func TestVerifyLogin_AnotherDeviceCannotReplaceThePendingOne(t *testing.T) {
+	f := newFixture(t)
+	challenge := f.challenge(t, constants.PasswordlessMethodLink, "link-token", time.Now().Add(time.Minute))
+	svc := f.passwordlessService()
+
+	prompt, err := svc.VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{Token: "link-token", DeviceID: otherDevice})
+	require.NoError(t, err)
+
+	// A third device holding the link cannot take the place of the one shown to the user
+	_, err = svc.VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{Token: "link-token", DeviceID: "tablet"})
+	assert.Equal(t, errors.ErrLoginConfirmationPending, err)
+	assert.Equal(t, utils.HashToken(otherDevice), *f.reload(t, challenge).PendingDeviceHash)
+
+	// Opening the link again on the pending device shows the same prompt
+	again, err := svc.VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{Token: "link-token", DeviceID: otherDevice})
+	require.NoError(t, err)
+	assert.Equal(t, prompt.PendingDevice, again.PendingDevice)
+
+	// Nor once the pending device was approved
+	require.NoError(t, svc.ConfirmLogin(context.Background(),
+		dtos.PasswordlessConfirmRequest{ChallengeID: challenge.ID, DeviceID: device, PendingDevice: prompt.PendingDevice}))
+	_, err = svc.VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{Token: "link-token", DeviceID: "tablet"})
+	assert.Equal(t, errors.ErrLoginConfirmationPending, err)
+	assert.Equal(t, constants.PasswordlessConfirmed, f.reload(t, challenge).Status)
+}
+
+func TestConfirmLogin_ApprovesOnlyTheDeviceShownInThePrompt(t *testing.T) {
+	f := newFixture(t)
+	challenge := f.challenge(t, constants.PasswordlessMethodLink, "link-token", time.Now().Add(time.Minute))
+	svc := f.passwordlessService()
+	_, err := svc.VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{Token: "link-token", DeviceID: otherDevice})
+	require.NoError(t, err)
+
+	err = svc.ConfirmLogin(context.Background(),
+		dtos.PasswordlessConfirmRequest{ChallengeID: challenge.ID, DeviceID: device, PendingDevice: utils.HashToken("tablet")})
+
+	assert.Equal(t, errors.ErrLoginNotConfirmed, err)
+	assert.Equal(t, constants.PasswordlessAwaitingConfirmation, f.reload(t, challenge).Status)
+}
+
+func TestRequestLogin_CapsChallengesPerWindow(t *testing.T) {
+	f := newFixture(t)
+	config.AppConfig.Passwordless.MaxChallenges = 3
+	svc := f.passwordlessService()
+	request := dtos.PasswordlessLoginRequest{Email: f.user.Email, Method: constants.PasswordlessMethodCode, DeviceID: device}
+
+	for range 5 {
+		response, err := svc.RequestLogin(context.Background(), request)
+		require.NoError(t, err)
+		assert.NotEmpty(t, response.ChallengeID, "a capped request looks the same as any other")
+	}
+
+	assert.EqualValues(t, 3, f.count(t, &entities.PasswordlessChallenge{}, "user_id = ?", f.user.ID))
+	assert.EqualValues(t, 1, f.count(t, &entities.PasswordlessChallenge{}, "user_id = ? AND status = ?", f.user.ID, constants.PasswordlessPending))
+}
+
+func TestVerifyLogin_RevokesCodeAfterMaxAttempts(t *testing.T) {
+	f := newFixture(t)
+	config.AppConfig.Passwordless.MaxCodeAttempt = 3
+	challenge := f.challenge(t, constants.PasswordlessMethodCode, code, time.Now().Add(time.Minute))
+	svc := f.passwordlessService()
+
+	for range 3 {
+		_, err := svc.VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{ChallengeID: challenge.ID, Code: "000000", DeviceID: device})
+		assert.Equal(t, errors.ErrInvalidOrExpiredLoginLink, err)
+	}
+	assert.Equal(t, constants.PasswordlessRevoked, f.reload(t, challenge).Status)
+
+	_, err := svc.VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{ChallengeID: challenge.ID, Code: code, DeviceID: device})
+	assert.Equal(t, errors.ErrInvalidOrExpiredLoginLink, err, "the right code no longer signs in")
+	assert.Zero(t, f.count(t, &entities.Token{}, "user_id = ?", f.user.ID))
+}
+
+func TestVerifyLogin_AcceptsCodeWithinMaxAttempts(t *testing.T) {
+	f := newFixture(t)
+	config.AppConfig.Passwordless.MaxCodeAttempt = 3
+	challenge := f.challenge(t, constants.PasswordlessMethodCode, code, time.Now().Add(time.Minute))
+	svc := f.passwordlessService()
+
+	for range 2 {
+		_, err := svc.VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{ChallengeID: challenge.ID, Code: "000000", DeviceID: device})
+		assert.Equal(t, errors.ErrInvalidOrExpiredLoginLink, err)
+	}
+	result, err := svc.VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{ChallengeID: challenge.ID, Code: code, DeviceID: device})
+
+	require.NoError(t, err)
+	assert.NotNil(t, result.Login)
+}
+
+func TestVerifyLogin_ConcurrentGuessesStayWithinMaxAttempts(t *testing.T) {
+	f := newFixture(t)
+	config.AppConfig.Passwordless.MaxCodeAttempt = 3
+	challenge := f.challenge(t, constants.PasswordlessMethodCode, code, time.Now().Add(time.Minute))
+	svc := f.passwordlessService()
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = svc.VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{ChallengeID: challenge.ID, Code: "000000", DeviceID: device})
+		}()
+	}
+	wg.Wait()
+
+	stored := f.reload(t, challenge)
+	assert.Equal(t, 3, stored.Attempts)
+	assert.Equal(t, constants.PasswordlessRevoked, stored.Status)
+}
+
+func TestVerifyLogin_ConcurrentRedemptionsSignInOnce(t *testing.T) {
+	f := newFixture(t)
+	f.challenge(t, constants.PasswordlessMethodLink, "link-token", time.Now().Add(time.Minute))
+	svc := f.passwordlessService()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	logins := 0
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := svc.VerifyLogin(context.Background(), dtos.PasswordlessVerifyRequest{Token: "link-token", DeviceID: device})
+			if err == nil && result.Login != nil {
+				mu.Lock()
+				logins++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, logins)
+	assert.EqualValues(t, 1, f.count(t, &entities.Token{}, "user_id = ?", f.user.ID))
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/constants"
+	apperrors "github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/saga"
+)
+
+// registrationLease is short, so a saga left behind by a simulated crash is soon taken over
+const registrationLease = 50 * time.Millisecond
+
+// crashingStore is the saga repository of the fixture, failing the save with the given number as a crash would
+type crashingStore struct {
+	saga.Store
+	saves   int
+	crashAt int // Number of the save that fails; 0 never fails
+}
+
+func (s *crashingStore) Save(instance *entities.SagaInstance) error {
+	s.saves++
+	if s.saves == s.crashAt {
+		return errors.New("connection lost")
+	}
+	return s.Store.Save(instance)
+}
+
+// registrationSaga returns the registration saga over the fixture's database and fake user-service
+func (f *fixture) registrationSaga() saga.Definition {
+	return services.NewRegistrationSaga(
+		repositories.NewUserRepository(f.db),
+		repositories.NewIdentityRepository(f.db),
+		repositories.NewEmailVerificationRepository(f.db),
+		f.users.Client,
+	)
+}
+
+// registrationService returns the registration service running the saga through an orchestrator on store
+func (f *fixture) registrationService(store saga.Store) (services.RegistrationService, *saga.Orchestrator) {
+	orchestrator := saga.NewOrchestrator(store, saga.Config{Lease: registrationLease}, f.registrationSaga())
+	svc := services.NewRegistrationService(orchestrator, repositories.NewUserRepository(f.db),
+		repositories.NewEmailVerificationRepository(f.db), f.users.Client)
+	return svc, orchestrator
+}
+
+// registration returns the latest registration saga of the email
+func (f *fixture) registration(t *testing.T, email string) *entities.SagaInstance {
+	t.Helper()
+	instance, err := f.sagaStore().FindLatest(constants.SagaTypeRegistration, email)
+	require.NoError(t, err)
+	require.NotNil(t, instance)
+	return instance
+}
+
+// stepStatuses returns the status of every step of the saga
+func stepStatuses(t *testing.T, instance *entities.SagaInstance) []string {
+	t.Helper()
+	steps, err := saga.StepLog(instance)
+	require.NoError(t, err)
+	statuses := make([]string, 0, len(steps))
+	for _, step := range steps {
+		statuses = append(statuses, step.Status)
+	}
+	return statuses
+}
+
+var newRegistration = dtos.RegisterRequest{Name: "Bob", Email: "bob@example.com", Password: "Correct-Horse-9"}
+
+func TestRegister_RunsEveryStep(t *testing.T) {
+	f := newFixture(t)
+	svc, _ := f.registrationService(f.sagaStore())
+
+	response, err := svc.Register(context.Background(), newRegistration)
+	require.NoError(t, err)
+	assert.Equal(t, saga.StatusCompleted, response.Status)
+
+	require.True(t, f.users.Has(newRegistration.Email), "the profile is created in user-service")
+	assert.NotEqual(t, newRegistration.Password, f.users.Get(newRegistration.Email).Password, "only the hash leaves auth-service")
+	assert.EqualValues(t, 1, f.count(t, &entities.User{}, "email = ?", newRegistration.Email))
+	assert.EqualValues(t, 1, f.count(t, &entities.UserIdentity{}, "provider = ?", constants.IdentityProviderPassword))
+	assert.EqualValues(t, 1, f.count(t, &entities.EmailVerificationToken{}, "used_at IS NULL"))
+
+	instance := f.registration(t, newRegistration.Email)
+	assert.Equal(t, []string{saga.StepCompleted, saga.StepCompleted, saga.StepCompleted}, stepStatuses(t, instance))
+	assert.NotContains(t, string(instance.Data), "passwordHash", "the password hash does not outlive the saga")
+}
+
+func TestRegister_TakenEmailFailsWithoutRetrying(t *testing.T) {
+	f := newFixture(t)
+	svc, _ := f.registrationService(f.sagaStore())
+
+	_, err := svc.Register(context.Background(), dtos.RegisterRequest{Name: "Ann", Email: f.user.Email, Password: "Correct-Horse-9"})
+	assert.Equal(t, apperrors.ErrEmailAlreadyRegistered, err)
+
+	instance := f.registration(t, f.user.Email)
+	assert.Equal(t, saga.StatusCompensated, instance.Status)
+	steps, err := saga.StepLog(instance)
+	require.NoError(t, err)
+	assert.Zero(t, steps[0].Attempts, "a conflict is permanent, so the step is not retried")
+	assert.Equal(t, "old-password", f.users.Get(f.user.Email).Password, "the existing profile is left alone")
+	assert.EqualValues(t, 1, f.count(t, &entities.User{}, "email = ?", f.user.Email))
+}
+
+func TestRegister_RetriesWhileUserServiceFails(t *testing.T) {
+	f := newFixture(t)
+	svc, orchestrator := f.registrationService(f.sagaStore())
+	f.users.Fail("POST /v1/internal/user/registrations")
+
+	response, err := svc.Register(context.Background(), newRegistration)
+	require.NoError(t, err)
+	assert.Equal(t, saga.StatusRunning, response.Status, "an unavailable user-service is retried")
+	assert.False(t, f.users.Has(newRegistration.Email))
+
+	f.users.Recover()
+	require.NoError(t, f.db.Model(&entities.SagaInstance{}).Where("id = ?", response.RegistrationID).
+		Update("next_attempt_at", time.Now()).Error)
+	resumed, err := orchestrator.ResumeOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, resumed)
+
+	assert.Equal(t, saga.StatusCompleted, f.registration(t, newRegistration.Email).Status)
+	assert.True(t, f.users.Has(newRegistration.Email))
+}
+
+func TestRegister_CompensatesWhenTheVerificationEmailFails(t *testing.T) {
+	f := newFixture(t)
+	// The link is stored before the email goes out, so refusing to store it fails the step
+	require.NoError(t, f.db.Exec(`CREATE TRIGGER auth.verification_unavailable BEFORE INSERT ON email_verification_token
+		BEGIN SELECT RAISE(ABORT, 'mail relay unavailable'); END`).Error)
+	orchestrator := saga.NewOrchestrator(f.sagaStore(), saga.Config{Lease: registrationLease},
+		withMaxAttempts(f.registrationSaga(), 1))
+	svc := services.NewRegistrationService(orchestrator, repositories.NewUserRepository(f.db),
+		repositories.NewEmailVerificationRepository(f.db), f.users.Client)
+
+	_, err := svc.Register(context.Background(), newRegistration)
+	require.Error(t, err)
+
+	instance := f.registration(t, newRegistration.Email)
+	assert.Equal(t, saga.StatusCompensated, instance.Status)
+	assert.Equal(t, []string{saga.StepCompensated, saga.StepCompensated, saga.StepCompensated}, stepStatuses(t, instance))
+	assert.EqualValues(t, 0, f.count(t, &entities.User{}, "email = ?", newRegistration.Email), "the local account is deleted")
+	assert.False(t, f.users.Has(newRegistration.Email), "the profile is discarded in user-service")
+	assert.NotContains(t, string(instance.Data), "passwordHash")
+
+	// The email can be registered again once the failure is over
+	require.NoError(t, f.db.Exec("DROP TRIGGER auth.verification_unavailable").Error)
+	response, err := svc.Register(context.Background(), newRegistration)
+	require.NoError(t, err)
+	assert.Equal(t, saga.StatusCompleted, response.Status)
+}
+
+func TestRegister_CompensationRetriesWhileUserServiceFails(t *testing.T) {
+	f := newFixture(t)
+	require.NoError(t, f.db.Exec(`CREATE TRIGGER auth.verification_unavailable BEFORE INSERT ON email_verification_token
+		BEGIN SELECT RAISE(ABORT, 'mail relay unavailable'); END`).Error)
+	orchestrator := saga.NewOrchestrator(f.sagaStore(), saga.Config{Lease: registrationLease},
+		withMaxAttempts(f.registrationSaga(), 1))
+	svc := services.NewRegistrationService(orchestrator, repositories.NewUserRepository(f.db),
+		repositories.NewEmailVerificationRepository(f.db), f.users.Client)
+	f.users.Fail("DELETE /v1/internal/user/registrations/{userId}")
+
+	_, err := svc.Register(context.Background(), newRegistration)
+	require.Error(t, err)
+	instance := f.registration(t, newRegistration.Email)
+	assert.Equal(t, saga.StatusCompensating, instance.Status, "the profile still has to be discarded")
+	assert.True(t, f.users.Has(newRegistration.Email))
+
+	f.users.Recover()
+	require.NoError(t, f.db.Model(&entities.SagaInstance{}).Where("id = ?", instance.ID).
+		Update("next_attempt_at", time.Now()).Error)
+	_, err = orchestrator.ResumeOnce(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, saga.StatusCompensated, f.registration(t, newRegistration.Email).Status)
+	assert.False(t, f.users.Has(newRegistration.Email))
+}
+
+func TestRegister_ReplaysTheStepInterruptedByACrash(t *testing.T) {
+	// Saves of a saga: the lease renewal before each step, then its result
+	tests := []struct {
+		name    string
+		crashAt int
+		step    string
+	}{
+		{"after creating the profile", 2, constants.RegistrationStepCreateProfile},
+		{"after creating the account", 4, constants.RegistrationStepCreateAccount},
+		{"after sending the verification email", 6, constants.RegistrationStepSendVerification},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			crashing, _ := f.registrationService(&crashingStore{Store: f.sagaStore(), crashAt: tt.crashAt})
+
+			// What the crashed instance answered does not matter, as its caller went away with it
+			_, _ = crashing.Register(context.Background(), newRegistration)
+			instance := f.registration(t, newRegistration.Email)
+			require.Equal(t, saga.StatusRunning, instance.Status)
+			require.Equal(t, tt.step, instance.StepName, "the crash lost the result of the step")
+
+			// Another instance takes the saga over once the lease of the crashed one has expired
+			time.Sleep(2 * registrationLease)
+			_, orchestrator := f.registrationService(f.sagaStore())
+			resumed, err := orchestrator.ResumeOnce(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, resumed)
+
+			instance = f.registration(t, newRegistration.Email)
+			assert.Equal(t, saga.StatusCompleted, instance.Status)
+			assert.True(t, f.users.Has(newRegistration.Email), "user-service accepts the replayed profile")
+			assert.EqualValues(t, 1, f.count(t, &entities.User{}, "email = ?", newRegistration.Email), "the account is created once")
+			assert.EqualValues(t, 1, f.count(t, &entities.EmailVerificationToken{}, "used_at IS NULL"), "a replayed email replaces the earlier link")
+		})
+	}
+}
+
+func TestRegistrationSaga_DeleteAccountFindsTheAccountByEmail(t *testing.T) {
+	f := newFixture(t)
+	deleteAccount := f.registrationSaga().Steps[1].Compensate
+
+	// A crash right after the account was created loses its ID from the saga data
+	require.NoError(t, deleteAccount(context.Background(), saga.Data{"email": f.user.Email}))
+	assert.EqualValues(t, 0, f.count(t, &entities.User{}, "id = ?", f.user.ID))
+
+	require.NoError(t, deleteAccount(context.Background(), saga.Data{"email": f.user.Email}), "an account already gone is undone")
+}
+
+// withMaxAttempts returns the definition with the attempts of a step bounded to n
+func withMaxAttempts(definition saga.Definition, n int) saga.Definition {
+	definition.MaxAttempts = n
+	return definition
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/constants"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/utils"
+	"github.com/Mir00r/auth-service/saga"
+	"github.com/Mir00r/auth-service/test/testenv"
+)
+
+// fixture is a database and a fake user-service holding one account
+type fixture struct {
+	db    *gorm.DB
+	users *testenv.UserService
+	user  entities.User
+}
+
+// newFixture creates the tables of the services with the indexes of the migrations the tests rely on, and an
+// account known to both services
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	cfg := config.Defaults()
+	cfg.JWT.Secret = "test-secret"
+	config.AppConfig = cfg
+
+	db := testenv.NewDB(t,
+		[]interface{}{
+			&entities.User{}, &entities.Token{}, &entities.PasswordResetToken{}, &entities.Mfa{},
+			&entities.PasswordlessChallenge{}, &entities.UserIdentity{}, &entities.OutboxEvent{},
+			&entities.EmailVerificationToken{}, &entities.SagaInstance{}, &entities.OIDCLoginState{},
+		},
+		"CREATE UNIQUE INDEX auth.idx_password_reset_token_active_user ON password_reset_token (user_id) WHERE used = false",
+		"CREATE UNIQUE INDEX auth.uq_saga_instance_unfinished ON saga_instance (saga_type, saga_key) WHERE ended_at IS NULL",
+	)
+	f := &fixture{db: db, users: testenv.NewUserService(t)}
+	f.user = entities.User{Name: "Ann", Email: "ann@example.com"}
+	require.NoError(t, db.Create(&f.user).Error)
+	f.users.Add(testenv.Account{ID: f.user.ID, Name: f.user.Name, Email: f.user.Email, Password: "old-password"})
+	return f
+}
+
+// resetToken saves a reset token of the user expiring at expiresAt and returns it
+func (f *fixture) resetToken(t *testing.T, expiresAt time.Time) string {
+	t.Helper()
+	token, err := utils.GenerateOpaqueToken()
+	require.NoError(t, err)
+	tokenRepo := repositories.NewTokenRepository(f.db)
+	require.NoError(t, tokenRepo.InvalidateResetTokens(f.user.ID))
+	saved, err := tokenRepo.SaveResetToken(utils.HashToken(token), f.user.ID, expiresAt)
+	require.NoError(t, err)
+	require.True(t, saved)
+	return token
+}
+
+// session saves a session of the user and returns its refresh token
+func (f *fixture) session(t *testing.T) string {
+	t.Helper()
+	refreshToken, err := utils.GenerateRefreshToken()
+	require.NoError(t, err)
+	require.NoError(t, f.db.Create(&entities.Token{
+		UserID:                f.user.ID,
+		Token:                 "access-" + refreshToken,
+		RefreshToken:          refreshToken,
+		Type:                  constants.RefreshToken,
+		ExpiresAt:             time.Now().Add(time.Hour),
+		RefreshTokenExpiresAt: time.Now().Add(24 * time.Hour),
+	}).Error)
+	return refreshToken
+}
+
+// count returns the number of rows of the model matching the condition
+func (f *fixture) count(t *testing.T, model interface{}, query string, args ...interface{}) int64 {
+	t.Helper()
+	var n int64
+	require.NoError(t, f.db.Model(model).Where(query, args...).Count(&n).Error)
+	return n
+}
+
+// sagaStore is the saga repository of the fixture. Claiming due sagas takes a statement only Postgres runs, so
+// it is done here with portable queries to the same effect.
+type sagaStore struct {
+	*repositories.SagaRepository
+}
+
+// sagaStore returns the saga repository of the fixture
+func (f *fixture) sagaStore() saga.Store {
+	repo := repositories.NewSagaRepository(f.db)
+	return sagaStore{&repo}
+}
+
+func (s sagaStore) ClaimDue(limit int, lease time.Duration) ([]entities.SagaInstance, error) {
+	now := time.Now()
+	var due []entities.SagaInstance
+	err := s.DB.Where("ended_at IS NULL AND next_attempt_at <= ? AND (locked_until IS NULL OR locked_until < ?)", now, now).
+		Order("next_attempt_at").Limit(limit).Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := due[:0]
+	for _, instance := range due {
+		lockedUntil := now.Add(lease)
+		result := s.DB.Model(&entities.SagaInstance{}).Where("id = ? AND version = ?", instance.ID, instance.Version).
+			Updates(map[string]interface{}{"locked_until": lockedUntil, "version": instance.Version + 1})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			instance.LockedUntil, instance.Version = &lockedUntil, instance.Version+1
+			claimed = append(claimed, instance)
+		}
+	}
+	return claimed, nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/auth-service/errors"
+	"github.com/Mir00r/auth-service/internal/models/dtos"
+	"github.com/Mir00r/auth-service/internal/models/entities"
+	"github.com/Mir00r/auth-service/internal/repositories"
+	"github.com/Mir00r/auth-service/internal/services"
+	"github.com/Mir00r/auth-service/test/testenv"
+)
+
+func (f *fixture) tokenService() services.TokenServiceInterface {
+	return services.NewTokenService(
+		repositories.NewTokenRepository(f.db),
+		repositories.NewUserRepository(f.db),
+		repositories.NewIdentityRepository(f.db),
+		repositories.NewOutboxRepository(f.db),
+		f.users.Client,
+	)
+}
+
+func TestResetPassword_SetsPasswordAndRevokesSessions(t *testing.T) {
+	f := newFixture(t)
+	token := f.resetToken(t, time.Now().Add(time.Hour))
+	f.session(t)
+
+	err := f.tokenService().ResetPassword(context.Background(), dtos.ConfirmPasswordResetRequest{Token: token, NewPassword: "N3w-password"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "N3w-password", f.users.Get(f.user.Email).Password)
+	assert.Zero(t, f.count(t, &entities.Token{}, "user_id = ?", f.user.ID), "sessions are revoked")
+	assert.Zero(t, f.count(t, &entities.PasswordResetToken{}, "used = ?", false), "no reset token stays redeemable")
+}
+
+func TestResetPassword_RejectsExpiredToken(t *testing.T) {
+	f := newFixture(t)
+	token := f.resetToken(t, time.Now().Add(-time.Second))
+
+	err := f.tokenService().ResetPassword(context.Background(), dtos.ConfirmPasswordResetRequest{Token: token, NewPassword: "N3w-password"})
+
+	assert.Equal(t, errors.ErrInvalidOrExpiredResetToken, err)
+	assert.Empty(t, f.users.PasswordsSet())
+}
+
+func TestResetPassword_RejectsUnknownToken(t *testing.T) {
+	f := newFixture(t)
+
+	err := f.tokenService().ResetPassword(context.Background(), dtos.ConfirmPasswordResetRequest{Token: "unknown", NewPassword: "N3w-password"})
+
+	assert.Equal(t, errors.ErrInvalidOrExpiredResetToken, err)
+}
+
+func TestResetPassword_TokenIsSingleUse(t *testing.T) {
+	f := newFixture(t)
+	token := f.resetToken(t, time.Now().Add(time.Hour))
+	svc := f.tokenService()
+
+	require.NoError(t, svc.ResetPassword(context.Background(), dtos.ConfirmPasswordResetRequest{Token: token, NewPassword: "F1rst-password"}))
+	err := svc.ResetPassword(context.Background(), dtos.ConfirmPasswordResetRequest{Token: token, NewPassword: "S3cond-password"})
+
+	assert.Equal(t, errors.ErrResetTokenAlreadyUsed, err)
+	assert.Equal(t, []string{"F1rst-password"}, f.users.PasswordsSet())
+}
+
+func TestResetPassword_ConcurrentRedemptionsSetThePasswordOnce(t *testing.T) {
+	f := newFixture(t)
+	token := f.resetToken(t, time.Now().Add(time.Hour))
+	svc := f.tokenService()
+
+	const redemptions = 8
+	errs := make([]error, redemptions)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = svc.ResetPassword(context.Background(), dtos.ConfirmPasswordResetRequest{Token: token, NewPassword: "N3w-password"})
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.Equal(t, errors.ErrResetTokenAlreadyUsed, err)
+	}
+	assert.Equal(t, 1, succeeded)
+	assert.Len(t, f.users.PasswordsSet(), 1)
+}
+
+func TestResetPassword_UserServiceFailureKeepsTheLinkUsable(t *testing.T) {
+	f := newFixture(t)
+	token := f.resetToken(t, time.Now().Add(time.Hour))
+	svc := f.tokenService()
+
+	f.users.Fail("PUT /v1/internal/user/password")
+	err := svc.ResetPassword(context.Background(), dtos.ConfirmPasswordResetRequest{Token: token, NewPassword: "N3w-password"})
+	assert.Equal(t, errors.ErrFailedToUpdatePassword.Message, err.(*errors.AppError).Message)
+
+	f.users.Recover()
+	require.NoError(t, svc.ResetPassword(context.Background(), dtos.ConfirmPasswordResetRequest{Token: token, NewPassword: "N3w-password"}))
+	assert.Equal(t, "N3w-password", f.users.Get(f.user.Email).Password)
+}
+
+func TestResetPassword_RejectsAWeakPasswordWithoutRedeemingTheToken(t *testing.T) {
+	f := newFixture(t)
+	token := f.resetToken(t, time.Now().Add(time.Hour))
+	svc := f.tokenService()
+
+	err := svc.ResetPassword(context.Background(), dtos.ConfirmPasswordResetRequest{Token: token, NewPassword: "password"})
+	assert.Equal(t, errors.ErrWeakPassword, err)
+	assert.Empty(t, f.users.PasswordsSet())
+
+	require.NoError(t, svc.ResetPassword(context.Background(), dtos.ConfirmPasswordResetRequest{Token: token, NewPassword: "N3w-password"}))
+}
+
+func TestInitiatePasswordReset_ConcurrentRequestsLeaveOneActiveToken(t *testing.T) {
+	f := newFixture(t)
+	svc := f.tokenService()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = svc.InitiatePasswordReset(context.Background(), dtos.PasswordResetRequest{Email: f.user.Email})
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err, "every request answers like the first")
+	}
+	assert.Equal(t, int64(1), f.count(t, &entities.PasswordResetToken{}, "user_id = ? AND used = ?", f.user.ID, false))
+}
+
+func TestInitiatePasswordReset_UnknownEmailAnswersLikeAKnownOne(t *testing.T) {
+	f := newFixture(t)
+
+	err := f.tokenService().InitiatePasswordReset(context.Background(), dtos.PasswordResetRequest{Email: "nobody@example.com"})
+
+	assert.NoError(t, err)
+	assert.Zero(t, f.count(t, &entities.PasswordResetToken{}, "1 = 1"))
+}
+
+func TestSaveResetToken_RefusesASecondActiveToken(t *testing.T) {
+	f := newFixture(t)
+	f.resetToken(t, time.Now().Add(time.Hour))
+
+	tokenRepo := repositories.NewTokenRepository(f.db)
+	saved, err := tokenRepo.SaveResetToken("other-hash", f.user.ID, time.Now().Add(time.Hour))
+
+	require.NoError(t, err)
+	assert.False(t, saved)
+}
+
+func TestRefreshToken_RotatesTheSessionOfAnActiveAccount(t *testing.T) {
+	f := newFixture(t)
+	refreshToken := f.session(t)
+
+	refreshed, err := f.tokenService().RefreshToken(context.Background(), dtos.RefreshTokenRequest{RefreshToken: refreshToken})
+
+	require.NoError(t, err)
+	assert.NotEqual(t, refreshToken, refreshed.RefreshToken)
+	assert.EqualValues(t, 1, f.count(t, &entities.Token{}, "refresh_token = ?", refreshed.RefreshToken))
+}
+
+func TestRefreshToken_RefusesAndRevokesTheSessionsOfADisabledAccount(t *testing.T) {
+	for name, disable := range map[string]func(account *testenv.Account){
+		"deactivated": func(account *testenv.Account) { account.Active = false },
+		"deleted":     func(account *testenv.Account) { account.Deleted = true },
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			refreshToken := f.session(t)
+			f.session(t)
+			f.users.Update(f.user.Email, disable)
+
+			_, err := f.tokenService().RefreshToken(context.Background(), dtos.RefreshTokenRequest{RefreshToken: refreshToken})
+
+			assert.Equal(t, errors.ErrAccountDisabled, err)
+			assert.Zero(t, f.count(t, &entities.Token{}, "user_id = ?", f.user.ID), "every session of the account is revoked")
+		})
+	}
+}
+
+func TestRefreshToken_FailsClosedWhileUserServiceIsDown(t *testing.T) {
+	f := newFixture(t)
+	refreshToken := f.session(t)
+	f.users.Fail("POST /v1/internal/user/account-status")
+
+	_, err := f.tokenService().RefreshToken(context.Background(), dtos.RefreshTokenRequest{RefreshToken: refreshToken})
+
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusServiceUnavailable, appErr.Code)
+	assert.EqualValues(t, 1, f.count(t, &entities.Token{}, "refresh_token = ?", refreshToken), "the session survives the outage")
+}
@@ -0,0 +1,138 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Mir00r/auth-service/apiclients"
+	config "github.com/Mir00r/auth-service/configs"
+	"github.com/Mir00r/auth-service/middlewares"
+	"github.com/Mir00r/auth-service/telemetry"
+)
+
+// recordSpans installs a tracer provider recording the ended spans and the W3C propagator for the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+// attributes returns the attributes of a span by key
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	values := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+func TestWebClient_SendsTraceContextOfAttemptSpan(t *testing.T) {
+	recorder := recordSpans(t)
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+	client := apiclients.WebClient{
+		Client:    &http.Client{},
+		Upstreams: apiclients.NewUpstreams(apiclients.NewStaticUpstream("backend", "http", srv.Listener.Addr().String())),
+	}
+
+	ctx, parent := telemetry.Tracer().Start(context.Background(), "caller")
+	err := client.Send(ctx, http.MethodGet, "backend", "/items", nil, nil)
+	parent.End()
+
+	require.NoError(t, err)
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	attempt := spans[0]
+	assert.Equal(t, "GET backend", attempt.Name())
+	assert.Equal(t, trace.SpanKindClient, attempt.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), attempt.Parent().SpanID())
+	assert.Equal(t, int64(http.StatusOK), attributes(attempt)[semconv.HTTPResponseStatusCodeKey].AsInt64())
+	assert.Equal(t, "backend", attributes(attempt)[semconv.PeerServiceKey].AsString())
+	// The target continues the trace below the attempt
+	assert.Equal(t, "00-"+attempt.SpanContext().TraceID().String()+"-"+attempt.SpanContext().SpanID().String()+"-01", traceparent)
+}
+
+func TestTracing_RecordsServerSpanOfRouteJoiningCaller(t *testing.T) {
+	recorder := recordSpans(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middlewares.Tracing())
+	router.GET("/v1/items/:id", func(c *gin.Context) {
+		telemetry.SetUser(c.Request.Context(), "u1", "t1")
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/items/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /v1/items/:id", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.True(t, span.Parent().IsRemote())
+
+	values := attributes(span)
+	assert.Equal(t, "/v1/items/:id", values[semconv.HTTPRouteKey].AsString())
+	assert.Equal(t, int64(http.StatusNoContent), values[semconv.HTTPResponseStatusCodeKey].AsInt64())
+	assert.Equal(t, "u1", values[semconv.EnduserIDKey].AsString())
+	assert.Equal(t, "t1", values[telemetry.TenantIDKey].AsString())
+}
+
+func TestNewTracerProvider_FileExporterAppendsSpans(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	provider, err := telemetry.NewTracerProvider(config.TracingConfig{Exporter: telemetry.ExporterFile, Path: path}, "auth-service")
+	require.NoError(t, err)
+
+	_, span := provider.Tracer("test").Start(context.Background(), "exported")
+	span.End()
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"exported"`)
+	assert.Contains(t, string(data), `"Value":"auth-service"`)
+}
+
+func TestNewTracerProvider_RecordsNothingWithoutExporter(t *testing.T) {
+	provider, err := telemetry.NewTracerProvider(config.TracingConfig{Exporter: telemetry.ExporterNone}, "auth-service")
+
+	require.NoError(t, err)
+	assert.Nil(t, provider)
+}
+
+func TestNewTracerProvider_RejectsUnknownExporter(t *testing.T) {
+	_, err := telemetry.NewTracerProvider(config.TracingConfig{Exporter: "jaeger"}, "auth-service")
+
+	assert.ErrorContains(t, err, `unknown trace exporter "jaeger"`)
+}
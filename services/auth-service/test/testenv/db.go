@@ -0,0 +1,122 @@
+// Package testenv provides what the tests of the services share: a database and a fake user-service
+package testenv
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqliteDriver opens SQLite connections offering the Postgres functions the schema uses as defaults
+var sqliteDriver = &sqlite3.SQLiteDriver{
+	ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+		if err := conn.RegisterFunc("gen_random_uuid", uuid.NewString, false); err != nil {
+			return err
+		}
+		// SQLite runs one writing transaction at a time, so the advisory lock has nothing left to serialize
+		if err := conn.RegisterFunc("pg_advisory_xact_lock", func(int64) int64 { return 0 }, false); err != nil {
+			return err
+		}
+		return conn.RegisterFunc("now", func() string {
+			return time.Now().Format(sqlite3.SQLiteTimestampFormats[0])
+		}, false)
+	},
+}
+
+// connector opens connections to the main database, with the database of the auth schema attached
+type connector struct {
+	main, auth string
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := sqliteDriver.Open(c.main)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.(*sqlite3.SQLiteConn).Exec("ATTACH DATABASE ? AS auth", []driver.Value{c.auth}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return sqliteDriver
+}
+
+// NewDB returns a database private to the test with tables for the entities. It runs on SQLite with the auth
+// schema attached, so the repositories run unchanged; statements such as partial indexes are run after the
+// tables are created.
+func NewDB(t *testing.T, entities []interface{}, statements ...string) *gorm.DB {
+	t.Helper()
+	dir := t.TempDir()
+	query := "?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+	sqlDB := sql.OpenDB(connector{
+		main: "file:" + filepath.Join(dir, "main.db") + query,
+		auth: "file:" + filepath.Join(dir, "auth.db") + query,
+	})
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	db, err := gorm.Open(sqlite.New(sqlite.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("opening the test database: %v", err)
+	}
+	for _, entity := range entities {
+		if err := createTable(db, entity); err != nil {
+			t.Fatalf("creating the table of %T: %v", entity, err)
+		}
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("running %q: %v", statement, err)
+		}
+	}
+	return db
+}
+
+// createTable creates the table of entity with its indexes. The SQLite migrator of GORM drops the schema of the
+// table from the indexes it creates, so they are created here instead.
+func createTable(db *gorm.DB, entity interface{}) error {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(entity); err != nil {
+		return err
+	}
+	indexes := stmt.Schema.ParseIndexes()
+	for _, field := range stmt.Schema.Fields {
+		delete(field.TagSettings, "INDEX")
+		delete(field.TagSettings, "UNIQUEINDEX")
+		if strings.HasSuffix(field.DefaultValue, "()") {
+			field.DefaultValue = "(" + field.DefaultValue + ")" // SQLite takes function defaults in parentheses
+		}
+	}
+	if err := db.Migrator().CreateTable(entity); err != nil {
+		return err
+	}
+
+	schema, table, _ := strings.Cut(stmt.Schema.Table, ".")
+	for _, index := range indexes {
+		columns := make([]string, 0, len(index.Fields))
+		for _, option := range index.Fields {
+			columns = append(columns, option.DBName)
+		}
+		statement := fmt.Sprintf("CREATE %s INDEX %s.%s ON %s (%s)", index.Class, schema, index.Name, table, strings.Join(columns, ", "))
+		if index.Where != "" {
+			statement += " WHERE " + index.Where
+		}
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
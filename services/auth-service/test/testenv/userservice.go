@@ -0,0 +1,219 @@
+package testenv
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Mir00r/auth-service/apiclients"
+	"github.com/Mir00r/auth-service/apiclients/userservice"
+	"github.com/Mir00r/auth-service/constants"
+)
+
+// UserService is a fake user-service holding accounts in memory, answering the internal routes the services call
+type UserService struct {
+	Client *userservice.Client // Client calling the fake
+
+	mu        sync.Mutex
+	accounts  map[string]*Account // By email
+	passwords []string            // Passwords set, in order
+	failing   map[string]bool     // Routes answered with 500, by method and path
+}
+
+// credentials is the body of the routes of the fake, all of which name an account by its email
+type credentials struct {
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	Password     string    `json:"password"`
+	PasswordHash string    `json:"passwordHash"`
+	LoggedInAt   time.Time `json:"loggedInAt"`
+}
+
+// Account is an account of the fake user-service
+type Account struct {
+	ID        string
+	Name      string
+	Email     string
+	Password  string // Password, or the password hash of a registered account
+	Active    bool
+	Deleted   bool
+	MFA       bool      // Whether user-service holds an MFA secret for the account
+	LastLogin time.Time // Newest login recorded through the last-login route
+}
+
+// NewUserService starts a fake user-service for the test
+func NewUserService(t *testing.T) *UserService {
+	t.Helper()
+	s := &UserService{accounts: map[string]*Account{}, failing: map[string]bool{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /v1/internal/user/password", s.handle(func(account *Account, body credentials) (any, int) {
+		if account == nil {
+			return nil, http.StatusNotFound
+		}
+		account.Password = body.Password
+		s.passwords = append(s.passwords, body.Password)
+		return nil, http.StatusOK
+	}))
+	mux.HandleFunc("POST /v1/internal/user/account-status", s.handle(func(account *Account, _ credentials) (any, int) {
+		if account == nil {
+			return userservice.AccountStatusResponse{}, http.StatusOK
+		}
+		return userservice.AccountStatusResponse{UserID: account.ID, Exists: true, Active: account.Active, Deleted: account.Deleted}, http.StatusOK
+	}))
+	mux.HandleFunc("POST /v1/internal/user/validate", s.handle(func(account *Account, body credentials) (any, int) {
+		if account == nil || account.Password != body.Password {
+			return nil, http.StatusUnauthorized
+		}
+		return userservice.UserResponse{ID: account.ID, Name: account.Name, Email: account.Email, IsActive: account.Active, MFAEnabled: account.MFA}, http.StatusOK
+	}))
+	mux.HandleFunc("PUT /v1/internal/user/last-login", s.handle(func(account *Account, body credentials) (any, int) {
+		if account != nil && body.LoggedInAt.After(account.LastLogin) {
+			account.LastLogin = body.LoggedInAt
+		}
+		return nil, http.StatusOK
+	}))
+
+	mux.HandleFunc("POST /v1/internal/user/registrations", s.handle(func(account *Account, body credentials) (any, int) {
+		switch {
+		case account == nil:
+			account = &Account{ID: uuid.NewString(), Name: body.Name, Email: body.Email, Password: body.PasswordHash, Active: true}
+			s.accounts[body.Email] = account
+		case account.Password != body.PasswordHash:
+			return nil, http.StatusConflict
+		}
+		return userservice.UserResponse{ID: account.ID, Name: account.Name, Email: account.Email, IsActive: account.Active}, http.StatusCreated
+	}))
+	mux.HandleFunc("DELETE /v1/internal/user/registrations/{userId}", s.handleID(func(account *Account) int {
+		if account != nil {
+			delete(s.accounts, account.Email)
+		}
+		return http.StatusOK
+	}))
+	mux.HandleFunc("PUT /v1/internal/user/{userId}/deactivate", s.handleID(func(account *Account) int {
+		if account == nil {
+			return http.StatusNotFound
+		}
+		account.Active = false
+		return http.StatusOK
+	}))
+	mux.HandleFunc("POST /v1/internal/user/{userId}/anonymize", s.handleID(func(account *Account) int {
+		if account == nil {
+			return http.StatusNotFound
+		}
+		account.Name, account.Password, account.Deleted = "", "", true
+		return http.StatusOK
+	}))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	policy := apiclients.Policy{Timeout: time.Second, MaxAttempts: 1, FailureThreshold: 1000, OpenTimeout: time.Millisecond, HalfOpenProbes: 1}
+	webClient := apiclients.WebClient{
+		Client:    &http.Client{},
+		Upstreams: apiclients.NewUpstreams(apiclients.NewStaticUpstream(constants.UpstreamUserService, "http", srv.Listener.Addr().String())),
+	}.WithPolicies(apiclients.Policies{Default: policy})
+	s.Client = userservice.NewClient(webClient)
+	return s
+}
+
+// Add adds an active account
+func (s *UserService) Add(account Account) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account.Active = true
+	s.accounts[account.Email] = &account
+}
+
+// Update changes the account with the email
+func (s *UserService) Update(email string, change func(account *Account)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change(s.accounts[email])
+}
+
+// Get returns a copy of the account with the email
+func (s *UserService) Get(email string) Account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.accounts[email]
+}
+
+// Has reports whether the fake holds an account with the email
+func (s *UserService) Has(email string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accounts[email] != nil
+}
+
+// PasswordsSet returns the passwords set through the fake, in order
+func (s *UserService) PasswordsSet() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.passwords...)
+}
+
+// Fail answers the route, such as "PUT /v1/internal/user/password" or "PUT /v1/internal/user/{userId}/deactivate",
+// with 500 until Recover is called
+func (s *UserService) Fail(route string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing[route] = true
+}
+
+// Recover answers the routes normally again
+func (s *UserService) Recover() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = map[string]bool{}
+}
+
+// handle decodes a body holding an email, looks the account up and answers what serve returns in the envelope of
+// user-service
+func (s *UserService) handle(serve func(account *Account, body credentials) (any, int)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body credentials
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		s.mu.Lock()
+		if s.failing[r.Method+" "+r.URL.Path] {
+			s.mu.Unlock()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		data, status := serve(s.accounts[body.Email], body)
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": status >= 400, "code": status, "message": http.StatusText(status), "data": data})
+	}
+}
+
+// handleID looks up the account named by the ID in the path and answers the status serve returns
+func (s *UserService) handleID(serve func(account *Account) int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failing[r.Pattern] {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var found *Account
+		for _, account := range s.accounts {
+			if account.ID == r.PathValue("userId") {
+				found = account
+			}
+		}
+		status := serve(found)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": status >= 400, "code": status, "message": http.StatusText(status)})
+	}
+}
@@ -0,0 +1,73 @@
+package auditsinks
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CEF header values identifying this product
+const (
+	cefVendor  = "Mir00r"
+	cefVersion = "1.0"
+)
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+)
+
+// FormatCEF renders the event in ArcSight Common Event Format
+func FormatCEF(e Event) string {
+	severity := 3
+	if e.failed() {
+		severity = 6
+	}
+
+	var b strings.Builder
+	b.WriteString("CEF:0|")
+	b.WriteString(cefHeaderEscaper.Replace(cefVendor))
+	b.WriteByte('|')
+	b.WriteString(cefHeaderEscaper.Replace(e.Service))
+	b.WriteByte('|')
+	b.WriteString(cefHeaderEscaper.Replace(cefVersion))
+	b.WriteByte('|')
+	b.WriteString(cefHeaderEscaper.Replace(e.EventType))
+	b.WriteByte('|')
+	b.WriteString(cefHeaderEscaper.Replace(e.EventType + " " + e.Outcome))
+	b.WriteByte('|')
+	b.WriteString(strconv.Itoa(severity))
+	b.WriteByte('|')
+
+	extensions := []struct{ key, value string }{
+		{"rt", strconv.FormatInt(e.Time.UnixMilli(), 10)},
+		{"outcome", e.Outcome},
+		{"reason", e.Reason},
+		{"duid", e.UserID},
+		{"suser", e.Actor},
+		{"src", e.IP},
+		{"requestClientApplication", e.UserAgent},
+	}
+	if e.Sequence != 0 {
+		extensions = append(extensions,
+			struct{ key, value string }{"cn1", strconv.FormatInt(e.Sequence, 10)},
+			struct{ key, value string }{"cn1Label", "auditSequence"},
+			struct{ key, value string }{"cs1", e.Hash},
+			struct{ key, value string }{"cs1Label", "auditHash"},
+		)
+	}
+
+	first := true
+	for _, ext := range extensions {
+		if ext.value == "" {
+			continue
+		}
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		b.WriteString(ext.key)
+		b.WriteByte('=')
+		b.WriteString(cefExtensionEscaper.Replace(ext.value))
+	}
+	return b.String()
+}
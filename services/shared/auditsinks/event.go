@@ -0,0 +1,23 @@
+package auditsinks
+
+import "time"
+
+// Event is the SIEM-facing representation of an audit record
+type Event struct {
+	Time      time.Time `json:"time"`
+	Service   string    `json:"service"`
+	EventType string    `json:"eventType"`
+	UserID    string    `json:"userId,omitempty"`
+	Actor     string    `json:"actor,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"`
+	Sequence  int64     `json:"sequence,omitempty"` // Position in the audit hash chain, when persisted
+	Hash      string    `json:"hash,omitempty"`     // Chain hash, when persisted
+}
+
+// failed reports whether the event describes a failed attempt
+func (e Event) failed() bool {
+	return e.Outcome == "failure"
+}
@@ -0,0 +1,110 @@
+package auditsinks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// JSONLinesConfig describes a rotating JSON-lines audit file
+type JSONLinesConfig struct {
+	Path       string
+	MaxSizeMB  int // Size at which the file is rotated, 100 MB when zero
+	MaxBackups int // Rotated files kept next to the active one, 5 when zero
+}
+
+// JSONLinesSink appends one JSON document per event to a file and rotates it by size.
+// Rotated files are named <path>.1 (newest) to <path>.<MaxBackups> (oldest).
+type JSONLinesSink struct {
+	cfg  JSONLinesConfig
+	file *os.File
+	size int64
+}
+
+// NewJSONLinesSink opens (or creates) the audit file for appending
+func NewJSONLinesSink(cfg JSONLinesConfig) (*JSONLinesSink, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("jsonl path is required")
+	}
+	if cfg.MaxSizeMB <= 0 {
+		cfg.MaxSizeMB = 100
+	}
+	if cfg.MaxBackups <= 0 {
+		cfg.MaxBackups = 5
+	}
+
+	sink := &JSONLinesSink{cfg: cfg}
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+// Name identifies the sink in logs
+func (s *JSONLinesSink) Name() string {
+	return "jsonl://" + s.cfg.Path
+}
+
+// Write appends the event, rotating first if the line would push the file over its size limit
+func (s *JSONLinesSink) Write(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if s.size > 0 && s.size+int64(len(line)) > int64(s.cfg.MaxSizeMB)*1024*1024 {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+// Close closes the active file
+func (s *JSONLinesSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// open opens the active file and records its current size
+func (s *JSONLinesSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.cfg.Path), 0o750); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	s.file, s.size = file, info.Size()
+	return nil
+}
+
+// rotate shifts the backups by one, moves the active file to <path>.1 and starts a new one
+func (s *JSONLinesSink) rotate() error {
+	if err := s.Close(); err != nil {
+		return err
+	}
+
+	_ = os.Remove(fmt.Sprintf("%s.%d", s.cfg.Path, s.cfg.MaxBackups))
+	for i := s.cfg.MaxBackups - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", s.cfg.Path, i), fmt.Sprintf("%s.%d", s.cfg.Path, i+1))
+	}
+	if err := os.Rename(s.cfg.Path, s.cfg.Path+".1"); err != nil {
+		return err
+	}
+	return s.open()
+}
@@ -0,0 +1,132 @@
+package auditsinks
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sink delivers audit events to an external system.
+// Write is only ever called from a single goroutine per sink.
+type Sink interface {
+	Name() string
+	Write(event Event) error
+	Close() error
+}
+
+// dropLogInterval limits how often dropped events are reported
+const dropLogInterval = time.Minute
+
+// Dispatcher fans audit events out to sinks through per-sink buffers.
+// Publishing never blocks: when a sink falls behind and its buffer is full, new events for that sink are dropped and counted.
+type Dispatcher struct {
+	queues  []*sinkQueue
+	wg      sync.WaitGroup
+	closeMu sync.RWMutex
+	closed  bool
+}
+
+// sinkQueue is the buffer and drop counter of one sink
+type sinkQueue struct {
+	sink        Sink
+	events      chan Event
+	dropped     atomic.Int64
+	lastDropLog atomic.Int64
+}
+
+// NewDispatcher starts one delivery worker per sink with a buffer of bufferSize events
+func NewDispatcher(sinks []Sink, bufferSize int) *Dispatcher {
+	if bufferSize <= 0 {
+		bufferSize = 1024
+	}
+
+	d := &Dispatcher{}
+	for _, sink := range sinks {
+		queue := &sinkQueue{sink: sink, events: make(chan Event, bufferSize)}
+		d.queues = append(d.queues, queue)
+		d.wg.Add(1)
+		go d.deliver(queue)
+	}
+	return d
+}
+
+// Publish queues the event for every sink without waiting for delivery
+func (d *Dispatcher) Publish(event Event) {
+	if d == nil {
+		return
+	}
+
+	d.closeMu.RLock()
+	defer d.closeMu.RUnlock()
+	if d.closed {
+		return
+	}
+
+	for _, queue := range d.queues {
+		select {
+		case queue.events <- event:
+		default:
+			dropped := queue.dropped.Add(1)
+			now := time.Now().UnixNano()
+			last := queue.lastDropLog.Load()
+			if now-last >= int64(dropLogInterval) && queue.lastDropLog.CompareAndSwap(last, now) {
+				slog.Warn("Audit sink is falling behind", "sink", queue.sink.Name(), "dropped", dropped)
+			}
+		}
+	}
+}
+
+// Dropped returns the number of events dropped per sink name because the sink's buffer was full
+func (d *Dispatcher) Dropped() map[string]int64 {
+	dropped := make(map[string]int64, len(d.queues))
+	for _, queue := range d.queues {
+		dropped[queue.sink.Name()] = queue.dropped.Load()
+	}
+	return dropped
+}
+
+// Close stops accepting events, flushes what is buffered until ctx expires and closes the sinks
+func (d *Dispatcher) Close(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+
+	d.closeMu.Lock()
+	if d.closed {
+		d.closeMu.Unlock()
+		return nil
+	}
+	d.closed = true
+	for _, queue := range d.queues {
+		close(queue.events)
+	}
+	d.closeMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliver writes queued events to the sink until the queue is closed
+func (d *Dispatcher) deliver(queue *sinkQueue) {
+	defer d.wg.Done()
+	for event := range queue.events {
+		if err := queue.sink.Write(event); err != nil {
+			slog.Error("Failed to deliver audit event", "event_type", event.EventType, "sink", queue.sink.Name(), "error", err)
+		}
+	}
+	if err := queue.sink.Close(); err != nil {
+		slog.Error("Failed to close audit sink", "sink", queue.sink.Name(), "error", err)
+	}
+}
@@ -0,0 +1,205 @@
+package auditsinks
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Syslog facility and severities used for audit messages (RFC 5424 section 6.2.1)
+const (
+	syslogFacilityAuthPriv = 10
+	syslogSeverityWarning  = 4
+	syslogSeverityInfo     = 6
+)
+
+// Message body formats
+const (
+	FormatCEFName  = "cef"
+	FormatJSONName = "json"
+)
+
+// SyslogConfig describes a syslog collector
+type SyslogConfig struct {
+	Network            string // udp, tcp or tls
+	Address            string // host:port of the collector
+	Format             string // cef (default) or json
+	AppName            string
+	CAFile             string // PEM bundle used to verify the collector over TLS, system roots when empty
+	InsecureSkipVerify bool
+	DialTimeout        time.Duration
+	WriteTimeout       time.Duration
+}
+
+// SyslogSink sends events as RFC 5424 messages.
+// Stream transports use octet-counting framing (RFC 6587) and reconnect on the next event after a failure.
+type SyslogSink struct {
+	cfg       SyslogConfig
+	hostname  string
+	tlsConfig *tls.Config
+	conn      net.Conn
+}
+
+// NewSyslogSink validates the configuration and prepares the sink; the connection is opened on first use
+func NewSyslogSink(cfg SyslogConfig) (*SyslogSink, error) {
+	switch cfg.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", cfg.Network)
+	}
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("syslog address is required")
+	}
+	if cfg.Format == "" {
+		cfg.Format = FormatCEFName
+	}
+	if cfg.Format != FormatCEFName && cfg.Format != FormatJSONName {
+		return nil, fmt.Errorf("unsupported syslog format %q", cfg.Format)
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = 5 * time.Second
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	sink := &SyslogSink{cfg: cfg, hostname: hostname}
+	if cfg.Network == "tls" {
+		host, _, err := net.SplitHostPort(cfg.Address)
+		if err != nil {
+			return nil, err
+		}
+		sink.tlsConfig = &tls.Config{ServerName: host, InsecureSkipVerify: cfg.InsecureSkipVerify, MinVersion: tls.VersionTLS12}
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, err
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+			}
+			sink.tlsConfig.RootCAs = pool
+		}
+	}
+	return sink, nil
+}
+
+// Name identifies the sink in logs
+func (s *SyslogSink) Name() string {
+	return "syslog+" + s.cfg.Network + "://" + s.cfg.Address
+}
+
+// Write formats and sends one event, redialing once if the connection was lost
+func (s *SyslogSink) Write(event Event) error {
+	message, err := s.format(event)
+	if err != nil {
+		return err
+	}
+
+	frame := []byte(message)
+	if s.cfg.Network != "udp" {
+		frame = []byte(strconv.Itoa(len(message)) + " " + message)
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if err = s.dial(); err != nil {
+				return err
+			}
+		}
+		_ = s.conn.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout))
+		if _, err = s.conn.Write(frame); err == nil {
+			return nil
+		}
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+// Close closes the connection to the collector
+func (s *SyslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// dial opens the connection to the collector
+func (s *SyslogSink) dial() error {
+	dialer := &net.Dialer{Timeout: s.cfg.DialTimeout}
+	var err error
+	if s.cfg.Network == "tls" {
+		s.conn, err = tls.DialWithDialer(dialer, "tcp", s.cfg.Address, s.tlsConfig)
+	} else {
+		s.conn, err = dialer.Dial(s.cfg.Network, s.cfg.Address)
+	}
+	return err
+}
+
+// format renders the RFC 5424 message for an event
+func (s *SyslogSink) format(event Event) (string, error) {
+	body := ""
+	if s.cfg.Format == FormatJSONName {
+		encoded, err := json.Marshal(event)
+		if err != nil {
+			return "", err
+		}
+		body = string(encoded)
+	} else {
+		body = FormatCEF(event)
+	}
+	return FormatRFC5424(event, s.hostname, s.cfg.AppName, body), nil
+}
+
+// FormatRFC5424 renders an RFC 5424 syslog message carrying body for the event
+func FormatRFC5424(event Event, hostname, appName, body string) string {
+	severity := syslogSeverityInfo
+	if event.failed() {
+		severity = syslogSeverityWarning
+	}
+	if appName == "" {
+		appName = event.Service
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		syslogFacilityAuthPriv*8+severity,
+		event.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(hostname, 255),
+		syslogHeaderField(appName, 48),
+		os.Getpid(),
+		syslogHeaderField(event.EventType, 32),
+		body,
+	)
+}
+
+// syslogHeaderField restricts a header field to printable ASCII without spaces, "-" when empty
+func syslogHeaderField(value string, maxLen int) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+	if value == "" {
+		return "-"
+	}
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+	return value
+}
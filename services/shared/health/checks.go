@@ -0,0 +1,151 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// minSigningKeyLength is the length below which an HMAC signing key is weaker than the SHA-256 it keys
+const minSigningKeyLength = 32
+
+// migrationFile matches the up migrations applied by golang-migrate, such as 001_create_users_table.up.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_.*\.up\.sql$`)
+
+// Postgres checks that the database answers a ping. It is critical, as the service cannot serve without it.
+func Postgres(db *sql.DB) Check {
+	return Check{
+		Name:     "postgres",
+		Critical: true,
+		Probe: func(ctx context.Context) error {
+			return db.PingContext(ctx)
+		},
+	}
+}
+
+// Migrations checks that the schema of the database is at the version of the newest migration in migrations,
+// as recorded by golang-migrate. A schema that is behind or left dirty by a failed migration is critical;
+// one ahead of the service, as while a newer version rolls out, only degrades it.
+func Migrations(db *sql.DB, migrations fs.FS) Check {
+	return Check{
+		Name:     "migrations",
+		Critical: true,
+		Probe: func(ctx context.Context) error {
+			expected, err := latestMigration(migrations)
+			if err != nil {
+				return err
+			}
+
+			var version uint
+			var dirty bool
+			err = db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+			switch {
+			case err == sql.ErrNoRows:
+				return fmt.Errorf("no migration applied, expected version %d", expected)
+			case err != nil:
+				return err
+			case dirty:
+				return fmt.Errorf("migration %d failed and left the schema dirty", version)
+			case version < expected:
+				return fmt.Errorf("schema at version %d, expected %d", version, expected)
+			case version > expected:
+				return Degraded(fmt.Errorf("schema at version %d, ahead of the expected %d", version, expected))
+			}
+			return nil
+		},
+	}
+}
+
+// latestMigration returns the version of the newest up migration in migrations
+func latestMigration(migrations fs.FS) (uint, error) {
+	entries, err := fs.ReadDir(migrations, ".")
+	if err != nil {
+		return 0, fmt.Errorf("reading migrations: %w", err)
+	}
+	var latest uint64
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		latest = max(latest, version)
+	}
+	return uint(latest), nil
+}
+
+// Redis checks that the Redis server at addr answers a PING, authenticating first when password is set.
+// It is not critical, as Redis only caches what the database holds.
+func Redis(addr, password string) Check {
+	return Check{
+		Name: "redis",
+		Probe: func(ctx context.Context) error {
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			if deadline, ok := ctx.Deadline(); ok {
+				_ = conn.SetDeadline(deadline)
+			}
+
+			reader := bufio.NewReader(conn)
+			if password != "" {
+				if err := redisCommand(conn, reader, "+OK", "AUTH", password); err != nil {
+					return fmt.Errorf("AUTH: %w", err)
+				}
+			}
+			return redisCommand(conn, reader, "+PONG", "PING")
+		},
+	}
+}
+
+// redisCommand sends a command in the Redis protocol and checks its one line reply
+func redisCommand(conn net.Conn, reader *bufio.Reader, expected string, args ...string) error {
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.Write([]byte(command.String())); err != nil {
+		return err
+	}
+
+	reply, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if reply = strings.TrimRight(reply, "\r\n"); reply != expected {
+		return fmt.Errorf("unexpected reply %q", strings.TrimPrefix(reply, "-"))
+	}
+	return nil
+}
+
+// SigningKey checks that the key signing the tokens of the service is available. A missing key is critical,
+// as no token can be issued or verified; a key shorter than 32 bytes works but degrades the service.
+func SigningKey(key func() []byte) Check {
+	return Check{
+		Name:     "signing-key",
+		Critical: true,
+		Probe: func(context.Context) error {
+			length := len(key())
+			switch {
+			case length == 0:
+				return fmt.Errorf("no signing key configured")
+			case length < minSigningKeyLength:
+				return Degraded(fmt.Errorf("signing key of %d bytes is shorter than %d", length, minSigningKeyLength))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,219 @@
+// Package health tells whether the service is alive and whether it is ready to serve. Readiness is the
+// combined result of the checks registered with a Registry, each probing something the service depends on,
+// such as its database or another service. A failing critical check takes the service down, while any other
+// failure leaves it degraded but still serving. Results are cached briefly, so frequent probes from load
+// balancers and orchestrators do not turn into load on the dependencies.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status is the health of a check or of the whole service
+type Status string
+
+const (
+	StatusUp       Status = "up"       // Everything works
+	StatusDegraded Status = "degraded" // The service serves, but a dependency is impaired
+	StatusDown     Status = "down"     // The service cannot serve and should get no traffic
+)
+
+// Defaults of the registry configuration
+const (
+	DefaultCacheTTL = 5 * time.Second
+	DefaultTimeout  = 2 * time.Second
+)
+
+// Check probes one dependency of the service
+type Check struct {
+	Name     string
+	Critical bool          // The service is down while a critical check fails, and degraded while any other check fails
+	Timeout  time.Duration // Overrides the timeout of the registry when set
+	Probe    func(ctx context.Context) error
+}
+
+// DegradedError is returned by a probe when its dependency works but not as it should.
+// It leaves the service degraded even when the check is critical.
+type DegradedError struct {
+	Err error
+}
+
+// Degraded wraps err into a *DegradedError
+func Degraded(err error) error {
+	return &DegradedError{Err: err}
+}
+
+func (e *DegradedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DegradedError) Unwrap() error {
+	return e.Err
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name      string  `json:"name"`
+	Status    Status  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of all the checks of a registry
+type Report struct {
+	Status    Status    `json:"status"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Checks    []Result  `json:"checks"`
+}
+
+// Registry runs the checks registered with it and caches their report
+type Registry struct {
+	cacheTTL  time.Duration // Guarded by runMu
+	timeout   time.Duration // Guarded by runMu
+	startedAt time.Time
+	draining  atomic.Bool // Set once the service is shutting down
+
+	runMu sync.Mutex // Held while the checks run, so concurrent callers share a single run
+
+	mu     sync.Mutex // Guards the fields below
+	checks []Check
+	report *Report
+}
+
+// NewRegistry creates a registry caching its report for cacheTTL and giving up on a check after timeout.
+// Zero values fall back to DefaultCacheTTL and DefaultTimeout.
+func NewRegistry(cacheTTL, timeout time.Duration) *Registry {
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Registry{cacheTTL: cacheTTL, timeout: timeout, startedAt: time.Now()}
+}
+
+// Configure changes the cache TTL and the check timeout of the registry, as NewRegistry sets them.
+// The checks running meanwhile keep their timeout.
+func (r *Registry) Configure(cacheTTL, timeout time.Duration) {
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+	r.cacheTTL, r.timeout = cacheTTL, timeout
+}
+
+// Register adds a check to the registry, dropping the cached report
+func (r *Registry) Register(check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check)
+	r.report = nil
+}
+
+// StartedAt returns the time the registry was created, which is when the service started
+func (r *Registry) StartedAt() time.Time {
+	return r.startedAt
+}
+
+// Uptime returns the time since the service started
+func (r *Registry) Uptime() time.Duration {
+	return time.Since(r.startedAt)
+}
+
+// Drain makes every later report down, whatever the checks say, so load balancers stop routing requests to
+// the service before it stops accepting connections
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+// Check returns the report of the registered checks, running them unless the cached report is still fresh.
+// The checks run concurrently, each bounded by its timeout, and a caller that goes away does not cut them
+// short, so its cancellation is never reported as a failing dependency.
+func (r *Registry) Check(ctx context.Context) Report {
+	if r.draining.Load() {
+		return Report{Status: StatusDown, CheckedAt: time.Now(), Checks: []Result{
+			{Name: "shutdown", Status: StatusDown, Critical: true, Error: "the service is shutting down"},
+		}}
+	}
+
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	r.mu.Lock()
+	checks, cached := r.checks, r.report
+	r.mu.Unlock()
+	if cached != nil && time.Since(cached.CheckedAt) < r.cacheTTL {
+		return *cached
+	}
+
+	report := r.run(context.WithoutCancel(ctx), checks)
+	r.mu.Lock()
+	r.report = &report
+	r.mu.Unlock()
+	return report
+}
+
+// run runs the checks and combines their results
+func (r *Registry) run(ctx context.Context, checks []Check) Report {
+	report := Report{Status: StatusUp, CheckedAt: time.Now(), Checks: make([]Result, len(checks))}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			report.Checks[i] = r.runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		switch {
+		case result.Status == StatusDown:
+			report.Status = StatusDown
+		case result.Status == StatusDegraded && report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// runCheck runs one check within its timeout
+func (r *Registry) runCheck(ctx context.Context, check Check) Result {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = r.timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Probe(ctx)
+	result := Result{
+		Name:      check.Name,
+		Status:    StatusUp,
+		Critical:  check.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err == nil {
+		return result
+	}
+
+	result.Error = err.Error()
+	var degraded *DegradedError
+	if check.Critical && !errors.As(err, &degraded) {
+		result.Status = StatusDown
+	} else {
+		result.Status = StatusDegraded
+	}
+	return result
+}
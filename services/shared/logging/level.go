@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// level is the minimum level of the records written, shared by every logger of the service
+var level = new(slog.LevelVar)
+
+// Leveler returns the minimum level of the records written, to be set as the level of every handler so
+// SetLevel changes them all
+func Leveler() slog.Leveler {
+	return level
+}
+
+// Level returns the minimum level of the records written
+func Level() slog.Level {
+	return level.Level()
+}
+
+// SetLevel changes the minimum level of the records written, given as debug, info, warn or error.
+// An empty level stands for info.
+func SetLevel(name string) error {
+	if name == "" {
+		name = slog.LevelInfo.String()
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("unknown log level %q", name)
+	}
+	level.Set(l)
+	return nil
+}
+
+// levelBody is the body of the log level endpoint
+type levelBody struct {
+	Level string `json:"level"`
+}
+
+// LevelHandler serves the minimum level of the records written on GET, and changes it on PUT with a body such
+// as {"level":"debug"}. The change lasts until the service restarts, or until a reload of the configuration
+// changes logging.level.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var body levelBody
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Level == "" {
+				http.Error(w, `body must be {"level":"debug|info|warn|error"}`, http.StatusBadRequest)
+				return
+			}
+			previous := Level()
+			if err := SetLevel(body.Level); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			slog.InfoContext(r.Context(), "Log level changed", "from", previous.String(), "to", Level().String())
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(levelBody{Level: Level().String()})
+	})
+}
@@ -0,0 +1,84 @@
+// Package telemetry holds the OpenTelemetry instrumentation shared by the services.
+package telemetry
+
+import (
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// gormSpanKey is the key of the span of a statement in the instance values of the GORM session
+const gormSpanKey = "telemetry:span"
+
+// gormTracing traces every statement run through GORM as a client span of the context of the session
+type gormTracing struct {
+	tracer trace.Tracer
+}
+
+// NewGORMPlugin returns the GORM plugin tracing the statements of a database with the tracer of the service.
+// Statements only join the trace of a request when the session carries its context, as db.WithContext(ctx) does.
+func NewGORMPlugin(tracer trace.Tracer) gorm.Plugin {
+	return gormTracing{tracer: tracer}
+}
+
+// Name implements gorm.Plugin
+func (gormTracing) Name() string {
+	return "telemetry:tracing"
+}
+
+// Initialize implements gorm.Plugin
+func (p gormTracing) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("telemetry:before_create", p.start),
+		callbacks.Create().After("gorm:create").Register("telemetry:after_create", p.end),
+		callbacks.Query().Before("gorm:query").Register("telemetry:before_query", p.start),
+		callbacks.Query().After("gorm:query").Register("telemetry:after_query", p.end),
+		callbacks.Update().Before("gorm:update").Register("telemetry:before_update", p.start),
+		callbacks.Update().After("gorm:update").Register("telemetry:after_update", p.end),
+		callbacks.Delete().Before("gorm:delete").Register("telemetry:before_delete", p.start),
+		callbacks.Delete().After("gorm:delete").Register("telemetry:after_delete", p.end),
+		callbacks.Row().Before("gorm:row").Register("telemetry:before_row", p.start),
+		callbacks.Row().After("gorm:row").Register("telemetry:after_row", p.end),
+		callbacks.Raw().Before("gorm:raw").Register("telemetry:before_raw", p.start),
+		callbacks.Raw().After("gorm:raw").Register("telemetry:after_raw", p.end),
+	)
+}
+
+// start starts the span of a statement
+func (p gormTracing) start(db *gorm.DB) {
+	_, span := p.tracer.Start(db.Statement.Context, "gorm", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL))
+	db.InstanceSet(gormSpanKey, span)
+}
+
+// end names the span of a statement after its operation and table and ends it
+func (gormTracing) end(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	query := db.Statement.SQL.String() // With placeholders, so no values end up in the trace
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	operation = strings.ToUpper(operation)
+	if operation != "" {
+		span.SetName(strings.TrimSpace(operation + " " + db.Statement.Table))
+	}
+	span.SetAttributes(
+		semconv.DBQueryText(query),
+		semconv.DBOperationName(operation),
+		semconv.DBCollectionName(db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
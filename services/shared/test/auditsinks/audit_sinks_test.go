@@ -0,0 +1,298 @@
+package auditsinks
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/shared/auditsinks"
+)
+
+func sampleEvent() auditsinks.Event {
+	return auditsinks.Event{
+		Time:      time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+		Service:   "auth-service",
+		EventType: "login",
+		UserID:    "5f0c6c1e-3d47-4a5e-9d0b-3f7f1d1f2a10",
+		Actor:     "jane@example.com",
+		IP:        "10.0.0.1",
+		UserAgent: "curl/8.0",
+		Outcome:   "failure",
+		Reason:    "invalid_credentials",
+		Sequence:  42,
+		Hash:      strings.Repeat("ab", 32),
+	}
+}
+
+// readOctetCounted reads one RFC 6587 octet-counted frame
+func readOctetCounted(t *testing.T, r *bufio.Reader) string {
+	lengthField, err := r.ReadString(' ')
+	require.NoError(t, err)
+	length, err := strconv.Atoi(strings.TrimSpace(lengthField))
+	require.NoError(t, err)
+
+	frame := make([]byte, length)
+	_, err = io.ReadFull(r, frame)
+	require.NoError(t, err)
+	return string(frame)
+}
+
+func TestFormatCEF_EscapesValues(t *testing.T) {
+	event := sampleEvent()
+	event.Actor = "a=b\\c"
+	event.UserAgent = "line1\nline2"
+	event.EventType = "log|in"
+
+	cef := auditsinks.FormatCEF(event)
+
+	assert.True(t, strings.HasPrefix(cef, `CEF:0|Mir00r|auth-service|1.0|log\|in|log\|in failure|6|`))
+	assert.Contains(t, cef, `suser=a\=b\\c`)
+	assert.Contains(t, cef, `requestClientApplication=line1\nline2`)
+	assert.Contains(t, cef, "src=10.0.0.1")
+	assert.Contains(t, cef, "reason=invalid_credentials")
+	assert.Contains(t, cef, "cn1=42 cn1Label=auditSequence")
+}
+
+func TestSyslogSink_UDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	sink, err := auditsinks.NewSyslogSink(auditsinks.SyslogConfig{Network: "udp", Address: listener.LocalAddr().String(), AppName: "auth-service"})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write(sampleEvent()))
+
+	buf := make([]byte, 8192)
+	_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	require.NoError(t, err)
+	message := string(buf[:n])
+
+	// authpriv (10) * 8 + warning (4) for a failed attempt
+	assert.True(t, strings.HasPrefix(message, "<84>1 2026-10-19T12:00:00.000000Z "), message)
+	assert.Contains(t, message, " auth-service ")
+	assert.Contains(t, message, " login - CEF:0|Mir00r|auth-service|")
+}
+
+func TestSyslogSink_TCPFramingAndReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	sink, err := auditsinks.NewSyslogSink(auditsinks.SyslogConfig{Network: "tcp", Address: listener.Addr().String(), Format: "json"})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	// First connection: receive one message, then drop the connection
+	require.NoError(t, sink.Write(sampleEvent()))
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	message := readOctetCounted(t, bufio.NewReader(conn))
+	assert.True(t, strings.HasPrefix(message, "<84>1 "))
+
+	body := message[strings.Index(message, " - ")+3:]
+	var decoded auditsinks.Event
+	require.NoError(t, json.Unmarshal([]byte(body), &decoded))
+	assert.Equal(t, "invalid_credentials", decoded.Reason)
+	require.NoError(t, conn.Close())
+
+	// Writes after the peer closed eventually fail once and redial; keep writing until the listener sees a new connection
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		next, err := listener.Accept()
+		if err == nil {
+			accepted <- next
+		}
+	}()
+
+	success := sampleEvent()
+	success.Outcome = "success"
+	deadline := time.Now().Add(5 * time.Second)
+	var next net.Conn
+	for next == nil && time.Now().Before(deadline) {
+		_ = sink.Write(success)
+		select {
+		case next = <-accepted:
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	require.NotNil(t, next, "sink did not reconnect")
+	defer next.Close()
+
+	message = readOctetCounted(t, bufio.NewReader(next))
+	assert.True(t, strings.HasPrefix(message, "<86>1 "), message) // informational for a success
+}
+
+func TestSyslogSink_TLS(t *testing.T) {
+	certPEM, keyPEM := selfSignedCert(t)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, certPEM, 0o600))
+
+	sink, err := auditsinks.NewSyslogSink(auditsinks.SyslogConfig{Network: "tls", Address: listener.Addr().String(), CAFile: caFile})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		received <- readOctetCounted(t, bufio.NewReader(conn))
+	}()
+
+	require.NoError(t, sink.Write(sampleEvent()))
+	select {
+	case message := <-received:
+		assert.Contains(t, message, "CEF:0|Mir00r|auth-service|")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received over TLS")
+	}
+}
+
+// blockingSink never finishes a write until released
+type blockingSink struct {
+	release chan struct{}
+	written chan auditsinks.Event
+}
+
+func (s *blockingSink) Name() string { return "blocking" }
+func (s *blockingSink) Write(event auditsinks.Event) error {
+	<-s.release
+	s.written <- event
+	return nil
+}
+func (s *blockingSink) Close() error { return nil }
+
+func TestDispatcher_SlowSinkNeverBlocksPublisher(t *testing.T) {
+	slow := &blockingSink{release: make(chan struct{}), written: make(chan auditsinks.Event, 100)}
+	dispatcher := auditsinks.NewDispatcher([]auditsinks.Sink{slow}, 2)
+
+	start := time.Now()
+	for i := 0; i < 50; i++ {
+		dispatcher.Publish(sampleEvent())
+	}
+	assert.Less(t, time.Since(start), time.Second)
+
+	// One event is held by the worker and two are buffered; the rest are dropped
+	assert.GreaterOrEqual(t, dispatcher.Dropped()["blocking"], int64(47))
+
+	close(slow.release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, dispatcher.Close(ctx))
+	assert.LessOrEqual(t, len(slow.written), 3)
+
+	// Publishing after close is a no-op
+	dispatcher.Publish(sampleEvent())
+}
+
+func TestDispatcher_DeliversToSyslogListener(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	sink, err := auditsinks.NewSyslogSink(auditsinks.SyslogConfig{Network: "udp", Address: listener.LocalAddr().String()})
+	require.NoError(t, err)
+	dispatcher := auditsinks.NewDispatcher([]auditsinks.Sink{sink}, 16)
+
+	for i := 0; i < 3; i++ {
+		dispatcher.Publish(sampleEvent())
+	}
+
+	buf := make([]byte, 8192)
+	for i := 0; i < 3; i++ {
+		_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, _, err := listener.ReadFrom(buf)
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, dispatcher.Close(ctx))
+}
+
+func TestJSONLinesSink_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	sink, err := auditsinks.NewJSONLinesSink(auditsinks.JSONLinesConfig{Path: path, MaxSizeMB: 1, MaxBackups: 2})
+	require.NoError(t, err)
+
+	event := sampleEvent()
+	event.UserAgent = strings.Repeat("x", 200*1024) // ~200 KB per line
+	for i := 0; i < 12; i++ {
+		require.NoError(t, sink.Write(event))
+	}
+	require.NoError(t, sink.Close())
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		require.NoError(t, err, name)
+		assert.LessOrEqual(t, info.Size(), int64(1024*1024), name)
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err), "only MaxBackups rotated files are kept")
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 512*1024), 512*1024)
+	require.True(t, scanner.Scan())
+	var decoded auditsinks.Event
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &decoded))
+	assert.Equal(t, "login", decoded.EventType)
+}
+
+// selfSignedCert creates a certificate for 127.0.0.1 usable as its own CA
+func selfSignedCert(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "syslog-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
@@ -0,0 +1,297 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/shared/health"
+)
+
+// probe returns a check whose probe returns err and counts its calls
+func probe(name string, critical bool, err error, calls *atomic.Int32) health.Check {
+	return health.Check{Name: name, Critical: critical, Probe: func(context.Context) error {
+		if calls != nil {
+			calls.Add(1)
+		}
+		return err
+	}}
+}
+
+func TestRegistry_CombinesResults(t *testing.T) {
+	failure := errors.New("unreachable")
+	cases := []struct {
+		name   string
+		checks []health.Check
+		want   health.Status
+	}{
+		{"no checks", nil, health.StatusUp},
+		{"all pass", []health.Check{probe("db", true, nil, nil), probe("cache", false, nil, nil)}, health.StatusUp},
+		{"optional fails", []health.Check{probe("db", true, nil, nil), probe("cache", false, failure, nil)}, health.StatusDegraded},
+		{"critical degraded", []health.Check{probe("db", true, health.Degraded(failure), nil)}, health.StatusDegraded},
+		{"critical fails", []health.Check{probe("db", true, failure, nil), probe("cache", false, failure, nil)}, health.StatusDown},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := health.NewRegistry(time.Minute, time.Second)
+			for _, check := range tc.checks {
+				registry.Register(check)
+			}
+			report := registry.Check(context.Background())
+			assert.Equal(t, tc.want, report.Status)
+			require.Len(t, report.Checks, len(tc.checks))
+			for i, result := range report.Checks {
+				assert.Equal(t, tc.checks[i].Name, result.Name)
+			}
+		})
+	}
+}
+
+func TestRegistry_ReportsEachCheck(t *testing.T) {
+	registry := health.NewRegistry(time.Minute, time.Second)
+	registry.Register(probe("db", true, nil, nil))
+	registry.Register(probe("cache", false, errors.New("connection refused"), nil))
+
+	report := registry.Check(context.Background())
+
+	assert.Equal(t, health.Result{Name: "db", Status: health.StatusUp, Critical: true, LatencyMs: report.Checks[0].LatencyMs}, report.Checks[0])
+	assert.Equal(t, health.StatusDegraded, report.Checks[1].Status)
+	assert.Equal(t, "connection refused", report.Checks[1].Error)
+	assert.GreaterOrEqual(t, report.Checks[0].LatencyMs, 0.0)
+}
+
+func TestRegistry_CachesReport(t *testing.T) {
+	var calls atomic.Int32
+	registry := health.NewRegistry(50*time.Millisecond, time.Second)
+	registry.Register(probe("db", true, nil, &calls))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			registry.Check(context.Background())
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load(), "concurrent probes share one run")
+
+	time.Sleep(60 * time.Millisecond)
+	registry.Check(context.Background())
+	assert.Equal(t, int32(2), calls.Load(), "a stale report is refreshed")
+
+	registry.Register(probe("cache", false, nil, nil))
+	assert.Len(t, registry.Check(context.Background()).Checks, 2, "registering a check drops the cached report")
+}
+
+func TestRegistry_TimesOutSlowChecks(t *testing.T) {
+	registry := health.NewRegistry(time.Minute, 20*time.Millisecond)
+	registry.Register(health.Check{Name: "slow", Critical: true, Probe: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	start := time.Now()
+	report := registry.Check(context.Background())
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, health.StatusDown, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+}
+
+func TestRegistry_IgnoresCallerCancellation(t *testing.T) {
+	registry := health.NewRegistry(time.Minute, time.Second)
+	registry.Register(health.Check{Name: "db", Critical: true, Probe: func(ctx context.Context) error {
+		return ctx.Err()
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, health.StatusUp, registry.Check(ctx).Status)
+}
+
+func TestSigningKey(t *testing.T) {
+	cases := map[string]health.Status{
+		"":                      health.StatusDown,
+		"short":                 health.StatusDegraded,
+		strings.Repeat("k", 32): health.StatusUp,
+	}
+	for key, want := range cases {
+		registry := health.NewRegistry(time.Minute, time.Second)
+		registry.Register(health.SigningKey(func() []byte { return []byte(key) }))
+		assert.Equal(t, want, registry.Check(context.Background()).Status, "key of %d bytes", len(key))
+	}
+}
+
+// fakeRedis serves the replies to AUTH and PING of a Redis server with the given password
+func fakeRedis(t *testing.T, password string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				authenticated := password == ""
+				for {
+					args, err := readCommand(reader)
+					if err != nil {
+						return
+					}
+					switch {
+					case args[0] == "AUTH" && args[1] == password:
+						authenticated = true
+						_, _ = io.WriteString(conn, "+OK\r\n")
+					case args[0] == "AUTH":
+						_, _ = io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+					case !authenticated:
+						_, _ = io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+					default:
+						_, _ = io.WriteString(conn, "+PONG\r\n")
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// readCommand reads a command sent in the Redis protocol
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	var count int
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Sscanf(line, "*%d", &count); err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		if _, err := reader.ReadString('\n'); err != nil { // $<length>
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, strings.TrimRight(arg, "\r\n"))
+	}
+	return args, nil
+}
+
+func TestRedis(t *testing.T) {
+	addr := fakeRedis(t, "s3cret")
+	cases := map[string]health.Status{
+		"s3cret": health.StatusUp,
+		"wrong":  health.StatusDegraded,
+		"":       health.StatusDegraded,
+	}
+	for password, want := range cases {
+		registry := health.NewRegistry(time.Minute, time.Second)
+		registry.Register(health.Redis(addr, password))
+		report := registry.Check(context.Background())
+		assert.Equal(t, want, report.Status, "password %q", password)
+		assert.NotContains(t, report.Checks[0].Error, "s3cret")
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	migrations := fstest.MapFS{
+		"001_create_users_table.up.sql":    {},
+		"002_create_tokens_table.up.sql":   {},
+		"002_create_tokens_table.down.sql": {},
+		"README.md":                        {},
+	}
+	cases := []struct {
+		name    string
+		version int64
+		dirty   bool
+		want    health.Status
+		wantErr string
+	}{
+		{"current", 2, false, health.StatusUp, ""},
+		{"behind", 1, false, health.StatusDown, "schema at version 1, expected 2"},
+		{"dirty", 2, true, health.StatusDown, "migration 2 failed and left the schema dirty"},
+		{"ahead", 3, false, health.StatusDegraded, "schema at version 3, ahead of the expected 2"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := sql.OpenDB(schemaConnector{version: tc.version, dirty: tc.dirty})
+			t.Cleanup(func() { _ = db.Close() })
+			registry := health.NewRegistry(time.Minute, time.Second)
+			registry.Register(health.Postgres(db))
+			registry.Register(health.Migrations(db, migrations))
+
+			report := registry.Check(context.Background())
+
+			assert.Equal(t, tc.want, report.Status)
+			assert.Equal(t, health.StatusUp, report.Checks[0].Status)
+			assert.Equal(t, tc.wantErr, report.Checks[1].Error)
+		})
+	}
+}
+
+// schemaConnector is a database whose schema_migrations table holds the given version
+type schemaConnector struct {
+	version int64
+	dirty   bool
+}
+
+func (c schemaConnector) Connect(context.Context) (driver.Conn, error) { return schemaConn(c), nil }
+func (c schemaConnector) Driver() driver.Driver                        { return nil }
+
+type schemaConn schemaConnector
+
+func (c schemaConn) Prepare(string) (driver.Stmt, error) { return schemaStmt(c), nil }
+func (c schemaConn) Close() error                        { return nil }
+func (c schemaConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+type schemaStmt schemaConn
+
+func (s schemaStmt) Close() error  { return nil }
+func (s schemaStmt) NumInput() int { return 0 }
+func (s schemaStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s schemaStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &schemaRows{row: []driver.Value{s.version, s.dirty}}, nil
+}
+
+type schemaRows struct {
+	row  []driver.Value
+	done bool
+}
+
+func (r *schemaRows) Columns() []string { return []string{"version", "dirty"} }
+func (r *schemaRows) Close() error      { return nil }
+func (r *schemaRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row)
+	return nil
+}
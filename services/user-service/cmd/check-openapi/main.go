@@ -0,0 +1,101 @@
+// Command check-openapi fails when the routes registered by the service and the operations of
+// docs/openapi.yaml disagree, so the spec that clients are generated from cannot silently go stale.
+//
+//	go run ./cmd/check-openapi [-spec docs/openapi.yaml]
+package main
+
+import (
+	"flag"
+	"github.com/Mir00r/user-service/internal/api/controllers"
+	"github.com/Mir00r/user-service/routes"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+	"log"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// spec is the part of an OpenAPI document the check reads
+type spec struct {
+	Paths map[string]map[string]yaml.Node `yaml:"paths"`
+}
+
+// pathParam matches an OpenAPI path parameter such as {userId}
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// operationMethods are the keys of an OpenAPI path item that describe operations
+var operationMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true,
+}
+
+func main() {
+	specPath := flag.String("spec", "docs/openapi.yaml", "OpenAPI document of the service")
+	flag.Parse()
+
+	documented, err := loadOperations(*specPath)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *specPath, err)
+	}
+	registered := registeredOperations()
+
+	var problems []string
+	for op := range registered {
+		if !documented[op] {
+			problems = append(problems, "route not in the spec: "+op)
+		}
+	}
+	for op := range documented {
+		if !registered[op] {
+			problems = append(problems, "spec operation without a route: "+op)
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		for _, problem := range problems {
+			slog.Error("Spec out of sync", "problem", problem)
+		}
+		log.Fatalf("%s is out of sync with the routes", *specPath)
+	}
+	slog.Info("Spec matches the registered routes", "spec", *specPath, "routes", len(registered))
+}
+
+// loadOperations returns the operations of the spec as "METHOD /path/:param"
+func loadOperations(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc spec
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	operations := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method := range item {
+			if operationMethods[method] {
+				operations[strings.ToUpper(method)+" "+pathParam.ReplaceAllString(path, ":$1")] = true
+			}
+		}
+	}
+	return operations, nil
+}
+
+// registeredOperations returns the routes of the service as "METHOD /path/:param".
+// The handlers are never called, so the controllers need no dependencies.
+func registeredOperations() map[string]bool {
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	routes.SetupRoutes(router,
+		&controllers.PublicUserController{}, &controllers.ProtectedUserController{}, &controllers.InternalUserController{}, &controllers.HealthController{}, nil,
+	)
+
+	operations := map[string]bool{}
+	for _, route := range router.Routes() {
+		operations[route.Method+" "+route.Path] = true
+	}
+	return operations
+}
@@ -1,43 +1,115 @@
 package main
 
 import (
+	"context"
 	"github.com/Mir00r/user-service/configs"
 	"github.com/Mir00r/user-service/containers"
 	database "github.com/Mir00r/user-service/db"
+	"github.com/Mir00r/user-service/lifecycle"
+	"github.com/Mir00r/user-service/logging"
 	"github.com/Mir00r/user-service/routes"
+	"github.com/Mir00r/user-service/telemetry"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 	"log"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
 	// Step 1: Load Configuration
 	configPath := getConfigPath()
 	if err := configs.LoadConfig(configPath); err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Step 2: Initialize Database
+	// Step 2: Write structured logs with secrets masked
+	logging.SetupFromConfig(configs.AppConfig.Logging)
+
+	// Step 3: Trace requests across the services
+	telemetry.SetupFromConfig(configs.AppConfig.Tracing)
+
+	// Step 4: Initialize Database
 	if err := database.Connect(); err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	// Step 3: Run Database Migrations
-	migrationPath, _ := database.MigrationPath()
-	log.Printf("Resolved migration path: %s", migrationPath)
-	if err := database.RunMigrations(migrationPath, configs.AppConfig.Database.DSN); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	// Step 5: Migrate the schema, or refuse to serve when it is not the one this release expects
+	if err := database.PrepareSchema(configs.AppConfig.Database); err != nil {
+		log.Fatalf("Database schema not ready: %v", err)
 	}
 
-	// Step 4: Initialize Dependencies
+	// Step 6: Initialize Dependencies
 	appContainer := containers.NewContainer()
 
-	// Step 5: Setup Router
-	router := gin.Default()
-	routes.SetupRoutes(router, appContainer.PublicUserController, appContainer.ProtectedUserController, appContainer.InternalUserController)
+	// Step 7: Run the background workers until shutdown
+	workers := lifecycle.NewWorkers()
+	if appContainer.OutboxRelay != nil {
+		workers.Go(appContainer.OutboxRelay.Run) // Publish domain events from the outbox
+	}
+	workers.Go(configs.ReloadOnSignal) // Apply the reloadable configuration on SIGHUP
 
-	// Step 6: Start Server
-	startServer(router)
+	// Step 8: Consume events from auth-service
+	if appContainer.EventSubscriber != nil {
+		if err := appContainer.AuthEventConsumer.Start(context.Background(), appContainer.EventSubscriber, configs.AppConfig.Messaging.AuthStream); err != nil {
+			log.Fatalf("Failed to subscribe to auth-service events: %v", err)
+		}
+	}
+
+	// Step 9: Setup Router
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.ContextWithFallback = true // Services receive the gin context, which then carries the request deadline
+	routes.SetupRoutes(router, appContainer.PublicUserController, appContainer.ProtectedUserController, appContainer.InternalUserController, appContainer.HealthController, appContainer.AuditDispatcher)
+
+	// Step 10: Serve the internal API over gRPC
+	grpcServer := startGRPCServer(routes.NewGRPCServer(appContainer.UserInternalServer, appContainer.AuditDispatcher))
+
+	// Step 11: Serve until SIGINT or SIGTERM
+	server := &http.Server{Addr: ":" + configs.AppConfig.Server.Port, Handler: router}
+	serve(server)
+
+	// Step 12: Drain the requests in flight and stop the rest in order
+	shutdown := lifecycle.NewShutdownFromConfig(configs.AppConfig.Server)
+	shutdown.Add("readiness", func(ctx context.Context) error {
+		appContainer.HealthRegistry.Drain()
+		return lifecycle.Wait(ctx, shutdown.DrainDelay)
+	})
+	shutdown.Add("HTTP server", func(ctx context.Context) error {
+		if err := server.Shutdown(ctx); err != nil {
+			_ = server.Close() // Cut the requests that outlived the deadline
+			return err
+		}
+		return nil
+	})
+	if grpcServer != nil {
+		shutdown.Add("gRPC server", func(ctx context.Context) error { return stopGRPCServer(ctx, grpcServer) })
+	}
+	if appContainer.EventSubscriber != nil {
+		shutdown.Add("event subscriber", func(context.Context) error { return appContainer.EventSubscriber.Close() })
+	}
+	shutdown.Add("background workers", workers.Stop)
+	if appContainer.OutboxRelay != nil {
+		shutdown.Add("outbox relay", func(context.Context) error { return appContainer.OutboxRelay.Close() })
+	}
+	shutdown.Add("audit sinks", appContainer.AuditDispatcher.Close)
+	shutdown.Add("tracing", telemetry.Shutdown)
+	shutdown.Add("database", func(context.Context) error { return database.Close() })
+	if err := shutdown.Run(); err != nil {
+		log.Fatalf("Shutdown incomplete: %v", err)
+	}
+	slog.Info("Server stopped")
 }
 
 // getConfigPath determines the configuration file path
@@ -49,15 +121,60 @@ func getConfigPath() string {
 	return configPath
 }
 
-// startServer starts the Gin server on the configured port
-func startServer(router *gin.Engine) {
-	port := os.Getenv("PORT")
+// startGRPCServer serves the internal gRPC API in the background on the configured port.
+// It returns nil when the gRPC server is disabled.
+func startGRPCServer(server *grpc.Server) *grpc.Server {
+	port := configs.AppConfig.GRPC.Port
 	if port == "" {
-		port = "8082" // Default port
+		slog.Info("gRPC server disabled")
+		return nil
 	}
 
-	log.Printf("Starting server on port %s\n", port)
-	if err := router.Run(":" + port); err != nil {
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatalf("Failed to listen for gRPC: %v", err)
+	}
+	slog.Info("Starting gRPC server", "port", port)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			log.Fatalf("Failed to start gRPC server: %v", err)
+		}
+	}()
+	return server
+}
+
+// stopGRPCServer lets the calls in flight finish, cutting them once ctx is done
+func stopGRPCServer(ctx context.Context, server *grpc.Server) error {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return ctx.Err()
+	}
+}
+
+// serve serves requests until the service receives SIGINT or SIGTERM. It stops the service when the server
+// cannot listen.
+func serve(server *http.Server) {
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting server", "addr", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-serverErr:
 		log.Fatalf("Failed to start server: %v", err)
+	case <-signals.Done():
+		slog.Info("Shutting down")
 	}
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Mir00r/user-service/configs"
+	database "github.com/Mir00r/user-service/db"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// migrateUsage lists the commands of the migrate subcommand
+const migrateUsage = `usage: user-service migrate <command>
+
+commands:
+  up          apply every pending migration
+  down [N]    revert the last N migrations, 1 by default
+  goto V      apply or revert migrations until the schema is at version V
+  status      print the schema version and the newest migration
+  force V     record the schema as clean and at version V after repairing a failed migration, -1 for none`
+
+// runMigrate runs the migrate subcommand against the database of the configuration
+func runMigrate(args []string) error {
+	run, err := parseMigrate(args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := configs.Load(getConfigPath(), os.LookupEnv)
+	if err != nil {
+		return fmt.Errorf("loading configuration: %w", err)
+	}
+	migrator, err := database.NewMigrator(cfg.Database.URL(), cfg.Database.Migrations.LockTimeout.Duration())
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if err := run(migrator); err != nil {
+		return err
+	}
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	slog.Info("Schema status", "version", status.Version, "dirty", status.Dirty, "latest", status.Latest)
+	if err := status.Check(); err != nil && args[0] == "status" {
+		slog.Warn("Schema not ready", "error", err)
+	}
+	return nil
+}
+
+// parseMigrate returns the change of the schema the arguments of the migrate subcommand ask for
+func parseMigrate(args []string) (func(m *database.Migrator) error, error) {
+	if len(args) == 0 {
+		return nil, errors.New(migrateUsage)
+	}
+	command, args := args[0], args[1:]
+	switch {
+	case command == "up" && len(args) == 0:
+		return (*database.Migrator).Up, nil
+	case command == "down" && len(args) <= 1:
+		steps := 1
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid number of migrations %q", args[0])
+			}
+			steps = n
+		}
+		return func(m *database.Migrator) error { return m.Down(steps) }, nil
+	case command == "goto" && len(args) == 1:
+		version, err := strconv.ParseUint(args[0], 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q", args[0])
+		}
+		return func(m *database.Migrator) error { return m.Goto(uint(version)) }, nil
+	case command == "force" && len(args) == 1:
+		version, err := strconv.Atoi(args[0])
+		if err != nil || version < -1 {
+			return nil, fmt.Errorf("invalid version %q", args[0])
+		}
+		return func(m *database.Migrator) error { return m.Force(version) }, nil
+	case command == "status" && len(args) == 0:
+		return func(*database.Migrator) error { return nil }, nil
+	}
+	return nil, errors.New(migrateUsage)
+}
@@ -0,0 +1,50 @@
+// Command verify-pacts replays the contracts recorded by the consumers of the service against its routes,
+// backed by in-memory repositories, and fails when a response differs from what a consumer relies on.
+// auth-service records its contract in contracts/pacts/auth-service.json with its contract tests; go test
+// replays them as well, in test/contracts.
+//
+//	go run ./cmd/verify-pacts [-pacts contracts/pacts]
+package main
+
+import (
+	"flag"
+	"github.com/Mir00r/user-service/contracts"
+	"github.com/Mir00r/user-service/contracts/provider"
+	"github.com/gin-gonic/gin"
+	"log"
+	"log/slog"
+)
+
+func main() {
+	pactsDir := flag.String("pacts", "contracts/pacts", "directory of the pacts recorded by the consumers")
+	flag.Parse()
+
+	gin.SetMode(gin.ReleaseMode)
+
+	pacts, err := contracts.LoadPacts(*pactsDir)
+	if err != nil {
+		log.Fatalf("Failed to read the pacts: %v", err)
+	}
+	if len(pacts) == 0 {
+		log.Fatalf("No pacts in %s", *pactsDir)
+	}
+
+	failed := false
+	for _, pact := range pacts {
+		mismatches, err := provider.Verify(pact)
+		if err != nil {
+			log.Fatalf("Failed to verify the pact of %s: %v", pact.Consumer.Name, err)
+		}
+		for _, mismatch := range mismatches {
+			slog.Error("Contract broken", "consumer", pact.Consumer.Name, "mismatch", mismatch)
+		}
+		if len(mismatches) > 0 {
+			failed = true
+			continue
+		}
+		slog.Info("Contract honoured", "provider", pact.Provider.Name, "consumer", pact.Consumer.Name, "interactions", len(pact.Interactions))
+	}
+	if failed {
+		log.Fatalf("The service breaks the contracts of its consumers")
+	}
+}
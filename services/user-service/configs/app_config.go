@@ -1,45 +1,81 @@
+// Package configs holds the configuration of the service. It is built in layers: defaults, then the YAML file,
+// then environment variables, then secret files named by environment variables, and it is validated as a whole
+// before the service starts. Fields tagged secret:"true" are masked wherever the configuration is shown, and
+// fields tagged reload:"true" can be changed without a restart by sending the service SIGHUP.
 package configs
 
 import (
-	"gopkg.in/yaml.v3"
-	"log"
+	"log/slog"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
 )
 
-var AppConfig Config
-
 type Config struct {
 	Server           ServerConfig           `yaml:"server"`
+	GRPC             GRPCConfig             `yaml:"grpc"`
 	JWT              JWTConfig              `yaml:"jwt"`
 	Database         DatabaseConfig         `yaml:"database"`
 	Redis            RedisConfig            `yaml:"redis"`
 	Password         PasswordConfig         `yaml:"password"`
 	InternalSecurity InternalSecurityConfig `yaml:"internal-security"`
+	Audit            AuditConfig            `yaml:"audit"`
+	Messaging        MessagingConfig        `yaml:"messaging"`
+	Tracing          TracingConfig          `yaml:"tracing"`
+	Logging          LoggingConfig          `yaml:"logging"`
+	Health           HealthConfig           `yaml:"health" reload:"true"`
 }
 
 type ServerConfig struct {
-	Port string `yaml:"port"`
+	Port            string   `yaml:"port" env:"PORT"`
+	DrainDelay      Duration `yaml:"drain-delay"`      // Time readiness fails before the servers stop accepting connections, none when zero
+	ShutdownTimeout Duration `yaml:"shutdown-timeout"` // Time in-flight requests and background work get to finish on SIGTERM, 30s when zero
+}
+
+type GRPCConfig struct {
+	Port string `yaml:"port"` // The gRPC server of the internal API is disabled when empty
 }
 
 type JWTConfig struct {
-	Secret             string `yaml:"secret"`
-	Expiry             string `yaml:"expiry"`
-	RefreshTokenExpiry string `yaml:"refresh-token-expiry"`
+	Secret             string   `yaml:"secret" secret:"true"`
+	Expiry             Duration `yaml:"expiry"`
+	RefreshTokenExpiry Duration `yaml:"refresh-token-expiry"`
 }
 
 type DatabaseConfig struct {
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
-	DBName   string `yaml:"dbname"`
-	DSN      string `yaml:"dsn"`
+	Host       string           `yaml:"host"`
+	Port       int              `yaml:"port"`
+	User       string           `yaml:"user"`
+	Password   string           `yaml:"password" secret:"true"`
+	DBName     string           `yaml:"dbname"`
+	DSN        string           `yaml:"dsn" secret:"true"` // URL of the database for migrations, built from the fields above when empty
+	Migrations MigrationsConfig `yaml:"migrations"`
+}
+
+type MigrationsConfig struct {
+	Mode        string   `yaml:"mode"`         // up applies the pending migrations at startup, verify only checks the schema is at the version of the release
+	LockTimeout Duration `yaml:"lock-timeout"` // Time to wait for another replica migrating the schema
+}
+
+// URL returns the URL of the database, the DSN when set
+func (c DatabaseConfig) URL() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+	return (&url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.DBName,
+		RawQuery: "sslmode=disable",
+	}).String()
 }
 
 type RedisConfig struct {
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
-	Password string `yaml:"password"`
+	Password string `yaml:"password" secret:"true"`
 }
 
 type PasswordConfig struct {
@@ -48,20 +84,77 @@ type PasswordConfig struct {
 
 type InternalSecurityConfig struct {
 	UserName string `yaml:"username"`
-	Password string `yaml:"password"`
+	Password string `yaml:"password" secret:"true"`
+}
+
+type AuditConfig struct {
+	BufferSize int               `yaml:"buffer-size"`
+	Sinks      []AuditSinkConfig `yaml:"sinks"`
+}
+
+type AuditSinkConfig struct {
+	Type               string `yaml:"type"`    // syslog or jsonl
+	Network            string `yaml:"network"` // syslog: udp, tcp or tls
+	Address            string `yaml:"address"` // syslog: host:port
+	Format             string `yaml:"format"`  // syslog: cef or json
+	AppName            string `yaml:"app-name"`
+	CAFile             string `yaml:"ca-file"`
+	InsecureSkipVerify bool   `yaml:"insecure-skip-verify"`
+	Path               string `yaml:"path"` // jsonl: file path
+	MaxSizeMB          int    `yaml:"max-size-mb"`
+	MaxBackups         int    `yaml:"max-backups"`
 }
 
+type MessagingConfig struct {
+	NatsURL   string       `yaml:"nats-url"`  // The outbox relay is disabled when empty
+	JetStream bool         `yaml:"jetstream"` // Without JetStream events published while no subscriber is connected are lost
+	Outbox    OutboxConfig `yaml:"outbox"`
+	// Stream is the JetStream stream capturing the user.> events this service publishes
+	Stream string `yaml:"stream"`
+	// AuthStream is the JetStream stream capturing the auth.> events of auth-service
+	AuthStream string `yaml:"auth-stream"`
+}
+
+type OutboxConfig struct {
+	PollInterval Duration `yaml:"poll-interval"`
+	BatchSize    int      `yaml:"batch-size"`
+}
+
+// TracingConfig tells where the OpenTelemetry spans of the service are exported
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`                   // otlp, stdout, file or none; spans are not recorded when empty or none
+	Endpoint    string  `yaml:"endpoint"`                   // otlp: host:port of the collector, OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4317 when empty
+	Insecure    bool    `yaml:"insecure"`                   // otlp: connect to the collector without TLS
+	Path        string  `yaml:"path"`                       // file: file the spans are appended to, one JSON object per line
+	SampleRatio float64 `yaml:"sample-ratio" reload:"true"` // Share of the traces started by the service that are sampled, all when zero
+}
+
+// LoggingConfig tells how the records of the service are written to stdout
+type LoggingConfig struct {
+	Level  string `yaml:"level" reload:"true"` // debug, info, warn or error, info when empty; adjustable at runtime
+	Format string `yaml:"format"`              // json or text, json when empty
+}
+
+// HealthConfig tells how the dependencies of the service are checked by its readiness probe
+type HealthConfig struct {
+	CacheTTL Duration `yaml:"cache-ttl"` // Time the results of the checks are reused, 5s when zero
+	Timeout  Duration `yaml:"timeout"`   // Time a check may take before it fails, 2s when zero
+}
+
+// AppConfig is the configuration the service started with. It is set by LoadConfig before anything reads it and
+// is left as it is by a reload, since requests read it concurrently; reloaded fields reach the parts of the
+// service using them through OnReload.
+var AppConfig Config
+
+// LoadConfig loads the configuration from the file at path and the environment into AppConfig, as described by
+// Load, and returns every problem found at once
 func LoadConfig(path string) error {
-	file, err := os.Open(path)
+	cfg, err := Load(path, os.LookupEnv)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&AppConfig); err != nil {
-		return err
-	}
-	log.Println("Configuration loaded successfully")
+	AppConfig = *cfg
+	setCurrent(path, cfg)
+	slog.Info("Configuration loaded successfully")
 	return nil
 }
@@ -0,0 +1,219 @@
+package configs
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileSuffix marks the environment variable naming a file that holds the value of a field, such as a mounted secret
+const fileSuffix = "_FILE"
+
+// Defaults returns the configuration of whatever neither the file nor the environment sets
+func Defaults() Config {
+	return Config{
+		Server:    ServerConfig{Port: "8082", ShutdownTimeout: Duration(30 * time.Second)},
+		JWT:       JWTConfig{Expiry: Duration(2 * time.Hour), RefreshTokenExpiry: Duration(24 * time.Hour)},
+		Database:  DatabaseConfig{Host: "localhost", Port: 5432, Migrations: MigrationsConfig{Mode: "up", LockTimeout: Duration(time.Minute)}},
+		Audit:     AuditConfig{BufferSize: 1024},
+		Messaging: MessagingConfig{JetStream: true, Stream: "USER", AuthStream: "AUTH", Outbox: OutboxConfig{PollInterval: Duration(time.Second), BatchSize: 100}},
+		Tracing:   TracingConfig{Exporter: "none", SampleRatio: 1},
+		Logging:   LoggingConfig{Level: "info", Format: "json"},
+		Health:    HealthConfig{CacheTTL: Duration(5 * time.Second), Timeout: Duration(2 * time.Second)},
+	}
+}
+
+// Load builds the configuration from its layers, each overriding the one before:
+//   - the defaults of Defaults
+//   - the YAML file at path, skipped when path is empty
+//   - environment variables named after the YAML path of a field, such as JWT_SECRET for jwt.secret or
+//     MESSAGING_OUTBOX_POLL_INTERVAL for messaging.outbox.poll-interval
+//   - files named by the same variables suffixed with _FILE, such as JWT_SECRET_FILE, for secrets mounted as files
+//
+// Lists are read from the environment as comma-separated values, while lists of sections, such as the audit
+// sinks, are only read from the file. The result is validated, and every problem found is returned at once.
+func Load(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Defaults()
+	var errs []error
+	if path != "" {
+		errs = append(errs, decodeFile(path, &cfg)...)
+	}
+	errs = append(errs, applyEnv(reflect.ValueOf(&cfg).Elem(), "", lookupEnv)...)
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &cfg, nil
+}
+
+// decodeFile decodes the YAML file at path over cfg. Unknown keys are rejected, since a misspelt key would
+// otherwise leave its field at the default without a word.
+func decodeFile(path string, cfg *Config) []error {
+	file, err := os.Open(path)
+	if err != nil {
+		return []error{fmt.Errorf("failed to open config file: %w", err)}
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	err = decoder.Decode(cfg)
+	var typeErr *yaml.TypeError
+	switch {
+	case err == nil, errors.Is(err, io.EOF):
+		return nil
+	case errors.As(err, &typeErr):
+		errs := make([]error, 0, len(typeErr.Errors))
+		for _, message := range typeErr.Errors {
+			errs = append(errs, fmt.Errorf("%s: %s", path, message))
+		}
+		return errs
+	default:
+		return []error{fmt.Errorf("%s: %w", path, err)}
+	}
+}
+
+// applyEnv sets the fields of the section v, at the YAML path prefix, from the environment
+func applyEnv(v reflect.Value, prefix string, lookupEnv func(string) (string, bool)) []error {
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		path := fieldPath(prefix, field)
+		if path == "" {
+			continue
+		}
+		value := v.Field(i)
+		if isSection(value) {
+			errs = append(errs, applyEnv(value, path, lookupEnv)...)
+			continue
+		}
+		if !fromEnv(value) {
+			continue
+		}
+
+		raw, source, found, err := lookupField(envNames(path, field), lookupEnv)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !found {
+			continue
+		}
+		if err := setField(value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+		}
+	}
+	return errs
+}
+
+// lookupField returns the value of the first of the environment variables names that is set, or the content of
+// the file named by its _FILE variable, along with the variable it came from
+func lookupField(names []string, lookupEnv func(string) (string, bool)) (raw, source string, found bool, err error) {
+	for _, name := range names {
+		value, isSet := lookupEnv(name)
+		file, isFileSet := lookupEnv(name + fileSuffix)
+		switch {
+		case isSet && isFileSet:
+			return "", name, false, fmt.Errorf("%s and %s%s: set only one of them", name, name, fileSuffix)
+		case isSet:
+			return value, name, true, nil
+		case isFileSet:
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return "", name + fileSuffix, false, fmt.Errorf("%s%s: %w", name, fileSuffix, err)
+			}
+			return strings.TrimRight(string(content), "\r\n"), name + fileSuffix, true, nil
+		}
+	}
+	return "", "", false, nil
+}
+
+// setField parses raw into the field value
+func setField(value reflect.Value, raw string) error {
+	if unmarshaler, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(raw))
+	}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		value.SetInt(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		value.SetFloat(parsed)
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
+
+// fieldPath returns the YAML path of a field of the section at prefix, empty for fields left out of the file
+func fieldPath(prefix string, field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" || !field.IsExported() {
+		return ""
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// envNames returns the environment variables of the field at path: the one named after the path, such as
+// SERVER_PORT for server.port, then those of its env tag, kept for deployments predating the path names
+func envNames(path string, field reflect.StructField) []string {
+	names := []string{strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(path))}
+	if aliases := field.Tag.Get("env"); aliases != "" {
+		names = append(names, strings.Split(aliases, ",")...)
+	}
+	return names
+}
+
+// isSection tells whether value is a section of the configuration, whose fields have paths of their own
+func isSection(value reflect.Value) bool {
+	return value.Kind() == reflect.Struct
+}
+
+// fromEnv tells whether a field of the kind of value can be set from the environment
+func fromEnv(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return value.Type().Elem().Kind() == reflect.String
+	default:
+		return false
+	}
+}
@@ -0,0 +1,126 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// problems collects the problems of a configuration, each prefixed with the YAML path of its field
+type problems []error
+
+func (p *problems) add(path, format string, args ...any) {
+	*p = append(*p, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+func (p *problems) required(path, value string) {
+	if strings.TrimSpace(value) == "" {
+		p.add(path, "required")
+	}
+}
+
+func (p *problems) positive(path string, value Duration) {
+	if value <= 0 {
+		p.add(path, "must be greater than zero, got %s", value)
+	}
+}
+
+func (p *problems) notNegative(path string, value Duration) {
+	if value < 0 {
+		p.add(path, "must not be negative, got %s", value)
+	}
+}
+
+func (p *problems) port(path string, value int) {
+	if value < 1 || value > 65535 {
+		p.add(path, "must be a port between 1 and 65535, got %d", value)
+	}
+}
+
+func (p *problems) listenPort(path, value string) {
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		p.add(path, "must be a number, got %q", value)
+		return
+	}
+	p.port(path, port)
+}
+
+func (p *problems) oneOf(path, value string, allowed ...string) {
+	if !slices.Contains(allowed, strings.ToLower(value)) {
+		p.add(path, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+}
+
+// Validate checks the configuration as a whole and returns all its problems joined, nil when there are none
+func (c *Config) Validate() error {
+	var p problems
+
+	p.listenPort("server.port", c.Server.Port)
+	p.notNegative("server.drain-delay", c.Server.DrainDelay)
+	p.notNegative("server.shutdown-timeout", c.Server.ShutdownTimeout)
+	if c.Server.ShutdownTimeout > 0 && c.Server.DrainDelay >= c.Server.ShutdownTimeout {
+		p.add("server.drain-delay", "must be shorter than server.shutdown-timeout (%s), got %s", c.Server.ShutdownTimeout, c.Server.DrainDelay)
+	}
+	if c.GRPC.Port != "" {
+		p.listenPort("grpc.port", c.GRPC.Port)
+		if c.GRPC.Port == c.Server.Port {
+			p.add("grpc.port", "must differ from server.port, got %s", c.GRPC.Port)
+		}
+	}
+
+	p.required("jwt.secret", c.JWT.Secret)
+	p.positive("jwt.expiry", c.JWT.Expiry)
+	p.positive("jwt.refresh-token-expiry", c.JWT.RefreshTokenExpiry)
+
+	p.required("database.host", c.Database.Host)
+	p.port("database.port", c.Database.Port)
+	p.required("database.user", c.Database.User)
+	p.required("database.dbname", c.Database.DBName)
+	if c.Database.DSN == "" { // A DSN carries its own credentials
+		p.required("database.password", c.Database.Password)
+	}
+	p.oneOf("database.migrations.mode", c.Database.Migrations.Mode, "up", "verify")
+	p.positive("database.migrations.lock-timeout", c.Database.Migrations.LockTimeout)
+
+	if c.Redis.Host != "" {
+		p.port("redis.port", c.Redis.Port)
+	}
+
+	p.required("internal-security.username", c.InternalSecurity.UserName)
+	p.required("internal-security.password", c.InternalSecurity.Password)
+
+	for i, sink := range c.Audit.Sinks {
+		path := fmt.Sprintf("audit.sinks[%d]", i)
+		switch sink.Type {
+		case "syslog":
+			p.required(path+".address", sink.Address)
+		case "jsonl":
+			p.required(path+".path", sink.Path)
+		default:
+			p.oneOf(path+".type", sink.Type, "syslog", "jsonl")
+		}
+	}
+
+	p.notNegative("messaging.outbox.poll-interval", c.Messaging.Outbox.PollInterval)
+	if c.Messaging.NatsURL != "" && c.Messaging.JetStream {
+		p.required("messaging.stream", c.Messaging.Stream)
+		p.required("messaging.auth-stream", c.Messaging.AuthStream)
+	}
+
+	p.oneOf("tracing.exporter", c.Tracing.Exporter, "", "none", "otlp", "stdout", "file")
+	if c.Tracing.Exporter == "file" {
+		p.required("tracing.path", c.Tracing.Path)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		p.add("tracing.sample-ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+	p.oneOf("logging.level", c.Logging.Level, "", "debug", "info", "warn", "error")
+	p.oneOf("logging.format", c.Logging.Format, "", "json", "text")
+	p.notNegative("health.cache-ttl", c.Health.CacheTTL)
+	p.notNegative("health.timeout", c.Health.Timeout)
+
+	return errors.Join(p...)
+}
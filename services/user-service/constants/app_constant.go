@@ -89,6 +89,14 @@ const (
 
 // Api Header
 const (
-	Authorization = "Authorization"
-	Bearer        = "Bearer "
+	Authorization        = "Authorization"
+	Bearer               = "Bearer "
+	RequestTimeoutHeader = "X-Request-Timeout-Ms" // Milliseconds the caller still waits for the response
+	RequestIDHeader      = "X-Request-Id"         // Correlates the logs of one call across services
 )
+
+// MaxBatchUserIDs is the largest number of users fetched in one batch call
+const MaxBatchUserIDs = 100
+
+// AnonymizedUserName replaces the name of a deleted account
+const AnonymizedUserName = "Deleted user"
@@ -0,0 +1,19 @@
+package constants
+
+// ServiceName identifies this service in audit events shipped to external systems
+const ServiceName = "user-service"
+
+// Audit event types
+const (
+	AuditUserRegistered           = "user_registered"
+	AuditUserUpdated              = "user_updated"
+	AuditUserDeleted              = "user_deleted"
+	AuditCredentialValidation     = "credential_validation"
+	AuditExternalIdentityResolved = "external_identity_resolved"
+)
+
+// Audit outcomes
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
@@ -0,0 +1,21 @@
+package constants
+
+// Domain event types published through the outbox; the type doubles as the message subject
+const (
+	EventUserCreated = "user.created"
+	EventUserDeleted = "user.deleted"
+
+	// EventUserDeletionRequested asks auth-service to run the account deletion workflow
+	EventUserDeletionRequested = "user.deletion_requested"
+)
+
+// Event types consumed from auth-service
+const (
+	EventAuthLoginSucceeded = "auth.login_succeeded"
+)
+
+// Subjects captured by the JetStream streams
+const (
+	EventSubjects     = "user.>" // Events published by this service
+	AuthEventSubjects = "auth.>" // Events published by auth-service
+)
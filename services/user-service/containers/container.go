@@ -1,39 +1,108 @@
 package containers
 
 import (
+	sharedauditsinks "github.com/Mir00r/shared/auditsinks"
+	sharedhealth "github.com/Mir00r/shared/health"
+	"github.com/Mir00r/user-service/auditsinks"
+	"github.com/Mir00r/user-service/configs"
+	"github.com/Mir00r/user-service/constants"
 	database "github.com/Mir00r/user-service/db"
+	"github.com/Mir00r/user-service/health"
 	"github.com/Mir00r/user-service/internal/api/controllers"
+	"github.com/Mir00r/user-service/internal/api/grpchandlers"
+	"github.com/Mir00r/user-service/internal/consumers"
 	"github.com/Mir00r/user-service/internal/repositories"
 	"github.com/Mir00r/user-service/internal/services"
+	"github.com/Mir00r/user-service/messaging"
+	"log"
+	"net"
+	"strconv"
 )
 
 // Container struct holds all application dependencies
 type Container struct {
 	UserRepository          repositories.UserRepository
+	OutboxRepository        repositories.OutboxRepository
+	AuditDispatcher         *sharedauditsinks.Dispatcher
+	OutboxRelay             *messaging.Relay          // nil when messaging is not configured
+	EventSubscriber         *messaging.NATSSubscriber // nil when messaging is not configured
+	AuthEventConsumer       *consumers.AuthEventConsumer
 	PublicUserController    *controllers.PublicUserController
 	ProtectedUserController *controllers.ProtectedUserController
 	InternalUserController  *controllers.InternalUserController
+	UserInternalServer      *grpchandlers.UserInternalServer
+	HealthRegistry          *sharedhealth.Registry
+	HealthController        *controllers.HealthController
 }
 
 // NewContainer initializes all dependencies and returns a Container instance
 func NewContainer() *Container {
+	// Initialize audit sinks
+	auditDispatcher := auditsinks.NewDispatcherFromConfig(configs.AppConfig.Audit, constants.ServiceName)
+
+	// Initialize health checks
+	healthRegistry := newHealthRegistry()
 
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(database.DB)
+	outboxRepo := repositories.NewOutboxRepository(database.DB)
+	transactor := repositories.NewTransactor(database.DB)
+
+	// Initialize the outbox relay and the event subscriber
+	outboxRelay := messaging.NewRelayFromConfig(configs.AppConfig.Messaging, outboxRepo)
+	eventSubscriber := messaging.NewSubscriberFromConfig(configs.AppConfig.Messaging)
 
 	// Initialize services
-	userService := services.NewUserService(userRepo)
+	userService := services.NewUserService(userRepo, outboxRepo, transactor)
+
+	// Initialize event consumers
+	authEventConsumer := consumers.NewAuthEventConsumer(userService)
 
 	// Initialize controllers
 	publicUserController := controllers.NewPublicUserController(userService)
 	protectedUserController := controllers.NewProtectedUserController(userService)
 	internalUserController := controllers.NewInternalUserController(userService)
+	healthController := controllers.NewHealthController(healthRegistry)
+
+	// Initialize gRPC handlers
+	userInternalServer := grpchandlers.NewUserInternalServer(userService)
 
 	return &Container{
-		UserRepository: userRepo,
+		UserRepository:   userRepo,
+		OutboxRepository: outboxRepo,
+		AuditDispatcher:  auditDispatcher,
+		OutboxRelay:      outboxRelay,
+
+		EventSubscriber:   eventSubscriber,
+		AuthEventConsumer: authEventConsumer,
 
 		PublicUserController:    publicUserController,
 		ProtectedUserController: protectedUserController,
 		InternalUserController:  internalUserController,
+		UserInternalServer:      userInternalServer,
+		HealthRegistry:          healthRegistry,
+		HealthController:        healthController,
+	}
+}
+
+// newHealthRegistry registers the checks of the dependencies the service needs to be ready:
+// its database and schema, the key verifying tokens and Redis when configured
+func newHealthRegistry() *sharedhealth.Registry {
+	registry := health.NewRegistryFromConfig(configs.AppConfig.Health)
+	configs.OnReload("health", func(cfg configs.Config) {
+		registry.Configure(cfg.Health.CacheTTL.Duration(), cfg.Health.Timeout.Duration())
+	})
+
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		log.Fatalf("Failed to check the database health: %v", err)
+	}
+	registry.Register(sharedhealth.Postgres(sqlDB))
+	registry.Register(sharedhealth.Migrations(sqlDB, database.Migrations()))
+	registry.Register(sharedhealth.SigningKey(func() []byte { return []byte(configs.AppConfig.JWT.Secret) }))
+
+	if redis := configs.AppConfig.Redis; redis.Host != "" {
+		registry.Register(sharedhealth.Redis(net.JoinHostPort(redis.Host, strconv.Itoa(redis.Port)), redis.Password))
 	}
+	return registry
 }
@@ -0,0 +1,125 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+)
+
+// matchResponse describes every way in which the response differs from the expected one
+func matchResponse(expected Response, resp *http.Response) []string {
+	var details []string
+	if resp.StatusCode != expected.Status {
+		details = append(details, fmt.Sprintf("status is %d, want %d", resp.StatusCode, expected.Status))
+	}
+	for key, value := range expected.Headers {
+		if got := resp.Header.Get(key); got != value {
+			details = append(details, fmt.Sprintf("header %s is %q, want %q", key, got, value))
+		}
+	}
+	if len(expected.Body) == 0 {
+		return details
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return append(details, fmt.Sprintf("failed to read the body: %v", err))
+	}
+	var got, want interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		return append(details, fmt.Sprintf("body is not JSON: %s", body))
+	}
+	if err := json.Unmarshal(expected.Body, &want); err != nil {
+		return append(details, fmt.Sprintf("recorded body is not JSON: %v", err))
+	}
+	return append(details, matchValue("$.body", want, got, expected.MatchingRules, false)...)
+}
+
+// matchValue compares a value of the body with the recorded one. Keys the consumer did not record are ignored.
+// Below a path with a "type" matching rule only the JSON types have to agree, and every element of an array
+// has to match the type of the first recorded element.
+func matchValue(path string, want, got interface{}, rules map[string]MatchingRule, typeOnly bool) []string {
+	if rule, ok := rules[path]; ok && rule.Match == "type" {
+		typeOnly = true
+	}
+	if jsonType(want) != jsonType(got) {
+		return []string{fmt.Sprintf("%s is %s %s, want %s", path, jsonType(got), encode(got), jsonType(want))}
+	}
+
+	switch want := want.(type) {
+	case map[string]interface{}:
+		got := got.(map[string]interface{})
+		keys := make([]string, 0, len(want))
+		for key := range want {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var details []string
+		for _, key := range keys {
+			keyPath := path + "." + key
+			actual, ok := got[key]
+			if !ok {
+				details = append(details, fmt.Sprintf("%s is missing", keyPath))
+				continue
+			}
+			details = append(details, matchValue(keyPath, want[key], actual, rules, typeOnly)...)
+		}
+		return details
+	case []interface{}:
+		got := got.([]interface{})
+		if typeOnly {
+			if len(want) == 0 {
+				return nil
+			}
+			var details []string
+			for i, actual := range got {
+				details = append(details, matchValue(fmt.Sprintf("%s[%d]", path, i), want[0], actual, rules, typeOnly)...)
+			}
+			return details
+		}
+		if len(got) != len(want) {
+			return []string{fmt.Sprintf("%s has %d elements, want %d", path, len(got), len(want))}
+		}
+		var details []string
+		for i := range want {
+			details = append(details, matchValue(fmt.Sprintf("%s[%d]", path, i), want[i], got[i], rules, typeOnly)...)
+		}
+		return details
+	default:
+		if !typeOnly && !reflect.DeepEqual(want, got) {
+			return []string{fmt.Sprintf("%s is %s, want %s", path, encode(got), encode(want))}
+		}
+		return nil
+	}
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// encode formats a decoded value as JSON for a mismatch
+func encode(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,89 @@
+// Package contracts replays the pact-style contracts recorded by the consumers of user-service against its
+// routes. A consumer records every request it makes together with the response it relies on, and the
+// service passes when each request, made in the provider state the consumer assumed, is answered as recorded.
+//
+// Responses are compared the way pact specification 2.0 does: the status must be equal, the recorded headers
+// must be present with equal values, and every value of the recorded body must be present and equal, except
+// below the paths that a matching rule of type "type" marks as examples, where only the JSON types must agree.
+// Fields the consumer did not record are ignored, so the service may add fields without breaking consumers.
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Pact is the contract between one consumer and user-service
+type Pact struct {
+	Consumer     Pacticipant   `json:"consumer"`
+	Provider     Pacticipant   `json:"provider"`
+	Interactions []Interaction `json:"interactions"`
+	Metadata     Metadata      `json:"metadata"`
+}
+
+// Pacticipant names a side of a pact
+type Pacticipant struct {
+	Name string `json:"name"`
+}
+
+// Metadata tells the pact specification a file follows
+type Metadata struct {
+	PactSpecification struct {
+		Version string `json:"version"`
+	} `json:"pactSpecification"`
+}
+
+// Interaction is one request of the consumer with the response it expects
+type Interaction struct {
+	Description   string   `json:"description"`
+	ProviderState string   `json:"providerState,omitempty"`
+	Request       Request  `json:"request"`
+	Response      Response `json:"response"`
+}
+
+// Request is the request the consumer sends
+type Request struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// Response is the part of the response the consumer relies on
+type Response struct {
+	Status        int                     `json:"status"`
+	Headers       map[string]string       `json:"headers,omitempty"`
+	Body          json.RawMessage         `json:"body,omitempty"`
+	MatchingRules map[string]MatchingRule `json:"matchingRules,omitempty"` // Keyed by path, e.g. $.body.data
+}
+
+// MatchingRule relaxes the comparison of the values below a path
+type MatchingRule struct {
+	Match string `json:"match"` // Only "type" is supported
+}
+
+// LoadPacts reads the pact files of a directory, ordered by file name
+func LoadPacts(dir string) ([]Pact, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	pacts := make([]Pact, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var pact Pact
+		if err := json.Unmarshal(data, &pact); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		pacts = append(pacts, pact)
+	}
+	return pacts, nil
+}
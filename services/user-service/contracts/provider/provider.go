@@ -0,0 +1,88 @@
+// Package provider runs the service as the provider of the contracts its consumers record: the routes of the
+// service, backed by in-memory repositories set up in the provider state an interaction assumes.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/Mir00r/shared/health"
+	"github.com/Mir00r/user-service/configs"
+	"github.com/Mir00r/user-service/contracts"
+	"github.com/Mir00r/user-service/internal/api/controllers"
+	"github.com/Mir00r/user-service/internal/models/entities"
+	"github.com/Mir00r/user-service/internal/repositories/memory"
+	"github.com/Mir00r/user-service/internal/services"
+	"github.com/Mir00r/user-service/routes"
+	"github.com/Mir00r/user-service/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Credentials of the internal API while the contracts are replayed; consumers leave theirs out of the pacts
+const (
+	internalUserName = "pact-verifier"
+	internalPassword = "pact-verifier"
+)
+
+// The account of the provider state "an active account exists"
+const (
+	accountID       = "3f8b2a4e-9c1d-4e7a-b5f6-2d8c0e1a7b94"
+	accountName     = "Jane Doe"
+	accountEmail    = "jane.doe@example.com"
+	accountPassword = "S3cure-Passw0rd!"
+)
+
+// providerStates set up the rows of the provider states that consumers may assume
+var providerStates = map[string]func(ctx context.Context, store *memory.Store) error{
+	"":                  func(context.Context, *memory.Store) error { return nil },
+	"no account exists": func(context.Context, *memory.Store) error { return nil },
+	"an active account exists": func(ctx context.Context, store *memory.Store) error {
+		hashedPassword, err := utils.HashPassword(accountPassword)
+		if err != nil {
+			return err
+		}
+		role := "User"
+		_, err = memory.NewUserRepository(store).CreateUser(ctx, &entities.User{
+			ID:       accountID,
+			Name:     accountName,
+			Email:    accountEmail,
+			Password: hashedPassword,
+			Role:     &role,
+			IsActive: true,
+		})
+		return err
+	},
+}
+
+// Verify replays the interactions of the pact against the routes of the service and returns every difference
+// to the recorded responses. The internal API is called with credentials of its own, set in configs.AppConfig.
+func Verify(pact contracts.Pact) ([]contracts.Mismatch, error) {
+	configs.AppConfig.InternalSecurity = configs.InternalSecurityConfig{UserName: internalUserName, Password: internalPassword}
+	return contracts.Verify(pact, newProvider, func(req *http.Request) {
+		req.SetBasicAuth(internalUserName, internalPassword)
+	})
+}
+
+// newProvider wires the routes of the service to a fresh in-memory store in the provider state
+func newProvider(state string) (http.Handler, error) {
+	setUp, ok := providerStates[state]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider state")
+	}
+	store := memory.NewStore()
+	if err := setUp(context.Background(), store); err != nil {
+		return nil, err
+	}
+
+	userService := services.NewUserService(memory.NewUserRepository(store), memory.NewOutboxRepository(store), memory.NewTransactor(store))
+	router := gin.New()
+	router.ContextWithFallback = true
+	routes.SetupRoutes(router,
+		controllers.NewPublicUserController(userService),
+		controllers.NewProtectedUserController(userService),
+		controllers.NewInternalUserController(userService),
+		controllers.NewHealthController(health.NewRegistry(0, 0)), // No dependencies to check
+		nil, // No audit trail
+	)
+	return router, nil
+}
@@ -0,0 +1,56 @@
+package contracts
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+)
+
+// Provider sets up a fresh instance of the service in the provider state an interaction assumes and returns
+// the handler serving its routes
+type Provider func(state string) (http.Handler, error)
+
+// Mismatch is a difference between the response of the service and the response recorded by a consumer
+type Mismatch struct {
+	Consumer    string
+	Interaction string
+	Detail      string
+}
+
+// Error implements error
+func (m Mismatch) Error() string {
+	return fmt.Sprintf("%s: %s: %s", m.Consumer, m.Interaction, m.Detail)
+}
+
+// Verify replays the interactions of the pact, each against a provider set up in the state it assumes, and
+// returns every difference to the recorded responses. authorize adds what the consumer leaves out of the
+// recorded requests, such as its credentials.
+func Verify(pact Pact, provider Provider, authorize func(req *http.Request)) ([]Mismatch, error) {
+	var mismatches []Mismatch
+	for _, interaction := range pact.Interactions {
+		handler, err := provider(interaction.ProviderState)
+		if err != nil {
+			return nil, fmt.Errorf("%s: provider state %q: %w", interaction.Description, interaction.ProviderState, err)
+		}
+
+		req := httptest.NewRequest(interaction.Request.Method, interaction.Request.Path, bytes.NewReader(interaction.Request.Body))
+		for key, value := range interaction.Request.Headers {
+			req.Header.Set(key, value)
+		}
+		if authorize != nil {
+			authorize(req)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		for _, detail := range matchResponse(interaction.Response, recorder.Result()) {
+			mismatches = append(mismatches, Mismatch{
+				Consumer:    pact.Consumer.Name,
+				Interaction: interaction.Description,
+				Detail:      detail,
+			})
+		}
+	}
+	return mismatches, nil
+}
@@ -1,13 +1,15 @@
 package database
 
 import (
+	"database/sql"
 	"fmt"
+	sharedtelemetry "github.com/Mir00r/shared/telemetry"
 	"github.com/Mir00r/user-service/configs"
+	"github.com/Mir00r/user-service/metrics"
+	"github.com/Mir00r/user-service/telemetry"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"log"
-	"os"
-	"path/filepath"
+	"log/slog"
 	"sync"
 )
 
@@ -37,28 +39,41 @@ func Connect() error {
 		// Open the database connection
 		DB, dbErr = gorm.Open(postgres.Open(dsn), &gorm.Config{})
 		if dbErr != nil {
-			log.Printf("Failed to connect to the database: %v", dbErr)
+			slog.Error("Failed to connect to the database", "error", dbErr)
+			return
+		}
+
+		// Trace every statement as part of the request that runs it
+		if dbErr = DB.Use(sharedtelemetry.NewGORMPlugin(telemetry.Tracer())); dbErr != nil {
+			slog.Error("Failed to trace database statements", "error", dbErr)
+			return
+		}
+
+		// Expose the statistics of the connection pool
+		var sqlDB *sql.DB
+		if sqlDB, dbErr = DB.DB(); dbErr == nil {
+			dbErr = metrics.RegisterDB(sqlDB, configs.AppConfig.Database.DBName)
+		}
+		if dbErr != nil {
+			slog.Error("Failed to expose connection pool metrics", "error", dbErr)
 			return
 		}
 
 		// Log successful connection
-		log.Println("Database connection established successfully")
+		slog.Info("Database connection established successfully")
 	})
 
 	return dbErr
 }
 
-// MigrationPath constructs and returns the absolute path to the database migrations directory.
-// This ensures portability and compatibility across different environments.
-func MigrationPath() (string, error) {
-	// Get the current working directory
-	cwd, err := os.Getwd()
+// Close closes the connection pool. Call it last on shutdown, once nothing runs queries anymore.
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Printf("Failed to get the current working directory: %v", err)
-		return "", err
+		return err
 	}
-
-	// Construct the absolute migration path
-	absoluteMigrationPath := filepath.Join(cwd, "db", "migrations")
-	return absoluteMigrationPath, err
+	return sqlDB.Close()
 }
@@ -1,36 +1,216 @@
 package database
 
 import (
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/Mir00r/user-service/configs"
 	"github.com/golang-migrate/migrate/v4"
-	"log"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"io/fs"
+	"log/slog"
+	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
-func RunMigrations(migrationPath string, dsn string) error {
-	// Convert the Windows path to a valid URL format
-	if os.PathSeparator == '\\' {
-		migrationPath = strings.ReplaceAll(migrationPath, "\\", "/")
-		migrationPath = "file://" + migrationPath
-	} else {
-		migrationPath = "file://" + migrationPath
+// migrationFiles holds the migrations built into the binary, so they no longer depend on the working directory
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations returns the migrations built into the binary, named such as 002_create_users_table.up.sql
+func Migrations() fs.FS {
+	migrations, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		panic(err) // The directory is embedded, so only a typo in its name gets here
+	}
+	return migrations
+}
+
+// Status is the version of the schema compared with the migrations built into the binary
+type Status struct {
+	Version uint // Version of the last migration applied, 0 when none was
+	Dirty   bool // Whether the last migration failed halfway
+	Latest  uint // Version of the newest migration built into the binary
+}
+
+// Applied tells whether any migration was applied
+func (s Status) Applied() bool {
+	return s.Version != 0
+}
+
+// String describes the status
+func (s Status) String() string {
+	version := "none"
+	if s.Applied() {
+		version = fmt.Sprint(s.Version)
+	}
+	if s.Dirty {
+		version += " (dirty)"
+	}
+	return fmt.Sprintf("schema version %s, latest migration %d", version, s.Latest)
+}
+
+// Check returns an error telling how to fix the schema unless it is clean and at the version of the newest
+// migration, which is what the service expects to serve
+func (s Status) Check() error {
+	switch {
+	case s.Dirty:
+		return fmt.Errorf("migration %d failed and left the schema dirty: repair it by hand, then run `migrate force V` with the version it is at", s.Version)
+	case !s.Applied():
+		return fmt.Errorf("no migration applied, expected version %d: run `migrate up`", s.Latest)
+	case s.Version < s.Latest:
+		return fmt.Errorf("schema at version %d, expected %d: run `migrate up`", s.Version, s.Latest)
+	case s.Version > s.Latest:
+		return fmt.Errorf("schema at version %d is newer than the expected %d: run the release that migrated it, or `migrate goto %d` from it", s.Version, s.Latest, s.Latest)
+	}
+	return nil
+}
+
+// Migrator migrates the schema with the migrations built into the binary. Every change takes a Postgres advisory
+// lock on the database, so replicas starting together migrate it one at a time and the others find nothing to do.
+type Migrator struct {
+	migrate *migrate.Migrate
+	latest  uint
+}
+
+// NewMigrator connects to the database at dsn. A change waits up to lockTimeout for another replica to release
+// the lock, 15s when zero.
+func NewMigrator(dsn string, lockTimeout time.Duration) (*Migrator, error) {
+	src, err := iofs.New(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+	latest, err := latestVersion(src)
+	if err != nil {
+		return nil, err
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", src, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the database: %s", redactURL(err.Error(), dsn))
+	}
+	if lockTimeout > 0 {
+		m.LockTimeout = lockTimeout
+	}
+	m.Log = migrationLogger{}
+	return &Migrator{migrate: m, latest: latest}, nil
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.migrate.Up())
+}
+
+// Down reverts the last steps migrations
+func (m *Migrator) Down(steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be at least 1, got %d", steps)
 	}
-	log.Printf("Using migration path: %s", migrationPath)
-	log.Printf("DSN path: %s", dsn)
+	return ignoreNoChange(m.migrate.Steps(-steps))
+}
+
+// Goto applies or reverts migrations until the schema is at version
+func (m *Migrator) Goto(version uint) error {
+	return ignoreNoChange(m.migrate.Migrate(version))
+}
+
+// Force records the schema as clean and at version without running any migration, after a failed migration was
+// repaired by hand. A version of -1 records that no migration was applied.
+func (m *Migrator) Force(version int) error {
+	return m.migrate.Force(version)
+}
+
+// Status returns the version of the schema
+func (m *Migrator) Status() (Status, error) {
+	version, dirty, err := m.migrate.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return Status{}, fmt.Errorf("reading the schema version: %w", err)
+	}
+	return Status{Version: version, Dirty: dirty, Latest: m.latest}, nil
+}
 
-	m, err := migrate.New(migrationPath, dsn)
+// Close closes the connection to the database
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.migrate.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+// PrepareSchema makes sure the schema is the one the service expects before it serves. In the up mode it applies
+// the pending migrations first; in the verify mode it leaves the schema to the migrate subcommand. Either way it
+// refuses a dirty schema or one at another version than the newest migration.
+func PrepareSchema(cfg configs.DatabaseConfig) error {
+	migrator, err := NewMigrator(cfg.URL(), cfg.Migrations.LockTimeout.Duration())
 	if err != nil {
-		log.Fatalf("Failed to initialize migrations: %v", err)
+		return err
 	}
+	defer migrator.Close()
 
-	// Apply all up migrations
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		log.Fatalf("Failed to run up migrations: %v", err)
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(cfg.Migrations.Mode, "verify") && !status.Dirty && status.Version < status.Latest {
+		if err := migrator.Up(); err != nil {
+			return fmt.Errorf("applying migrations: %w", err)
+		}
+		if status, err = migrator.Status(); err != nil {
+			return err
+		}
 	}
+	if err := status.Check(); err != nil {
+		return err
+	}
+	slog.Info("Database schema ready", "version", status.Version)
+	return nil
+}
 
-	log.Println("Migrations applied successfully")
+// latestVersion returns the version of the newest migration in src
+func latestVersion(src source.Driver) (uint, error) {
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("reading migrations: %w", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("reading migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+// redactURL replaces the password of the database URL dsn wherever it appears in message
+func redactURL(message, dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return strings.ReplaceAll(message, dsn, "[REDACTED]")
+	}
+	return strings.ReplaceAll(message, dsn, u.Redacted())
+}
+
+// ignoreNoChange treats a migration finding the schema already at its target as a success
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
 	return err
 }
+
+// migrationLogger logs every migration applied or reverted
+type migrationLogger struct{}
+
+func (migrationLogger) Printf(format string, v ...interface{}) {
+	slog.Info("Migration", "step", fmt.Sprintf(strings.TrimSuffix(format, "\n"), v...))
+}
+
+func (migrationLogger) Verbose() bool {
+	return false
+}
@@ -15,12 +15,17 @@ var (
 	ErrWeakPassword                  = NewAppError(http.StatusBadRequest, "Password is week", nil)
 	ErrInvalidDateOfBirth            = NewAppError(http.StatusBadRequest, "Invalid date of birth", nil)
 	ErrInvalidUserID                 = NewAppError(http.StatusBadRequest, "Invalid UUID", nil)
+	ErrTooManyUserIDs                = NewAppError(http.StatusBadRequest, "Too many user IDs in one batch", nil)
 	ErrInvalidPagination             = NewAppError(http.StatusBadRequest, "Invalid pagination number", nil)
 	ErrInvalidPhone                  = NewAppError(http.StatusBadRequest, "Invalid phone number", nil)
 	ErrInvalidRole                   = NewAppError(http.StatusBadRequest, "Invalid role name", nil)
 	ErrEmailAlreadyExists            = NewAppError(http.StatusConflict, "Email address already exist", nil)
+	ErrUnverifiedEmailConflict       = NewAppError(http.StatusConflict, "Email address belongs to an existing account and is not verified by the identity provider", nil)
 	ErrFailedToRegisterUser          = NewAppError(http.StatusInternalServerError, "Failed to register user", nil)
+	ErrRegistrationAlreadyVerified   = NewAppError(http.StatusConflict, "The account is verified and can no longer be discarded", nil)
+	ErrFailedToAnonymizeUser         = NewAppError(http.StatusInternalServerError, "Failed to anonymize the user", nil)
 	ErrUserNotFound                  = NewAppError(http.StatusNotFound, "User not found", nil)
+	ErrFailedToRecordLogin           = NewAppError(http.StatusInternalServerError, "Failed to record the login", nil)
 	ErrFailedToFetchUser             = NewAppError(http.StatusInternalServerError, "Failed to fetch the user", nil)
 	ErrFailedToUpdateUser            = NewAppError(http.StatusInternalServerError, "Failed to update the user", nil)
 	ErrFailedToDeleteUser            = NewAppError(http.StatusInternalServerError, "Failed to delete the user", nil)
@@ -32,6 +37,7 @@ var (
 	ErrResetTokenAlreadyUsed         = NewAppError(http.StatusBadRequest, "Reset token already used", nil)
 	ErrFailedToUpdatePassword        = NewAppError(http.StatusInternalServerError, "Failed to update password", nil)
 	ErrInvalidOrExpiredRefreshToken  = NewAppError(http.StatusUnauthorized, "Invalid or expired refresh token", nil)
+	ErrDeadlineExceeded              = NewAppError(http.StatusGatewayTimeout, "The caller stopped waiting for the response", nil)
 )
 
 // AppError represents a generic application error
@@ -0,0 +1,37 @@
+package errors
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+)
+
+// GRPCStatus returns the gRPC status of the error, so a handler of the internal gRPC API can return an
+// AppError as it is and the caller sees the code matching its HTTP status
+func (e *AppError) GRPCStatus() *status.Status {
+	return status.New(GRPCCode(e.Code), e.Message)
+}
+
+// GRPCCode maps an HTTP status code to the gRPC code with the same meaning
+func GRPCCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
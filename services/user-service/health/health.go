@@ -0,0 +1,13 @@
+// Package health builds the readiness registry of the service, described in github.com/Mir00r/shared/health,
+// from the application configuration.
+package health
+
+import (
+	"github.com/Mir00r/shared/health"
+	"github.com/Mir00r/user-service/configs"
+)
+
+// NewRegistryFromConfig creates a registry from the application configuration
+func NewRegistryFromConfig(cfg configs.HealthConfig) *health.Registry {
+	return health.NewRegistry(cfg.CacheTTL.Duration(), cfg.Timeout.Duration())
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"github.com/Mir00r/shared/health"
+	"github.com/Mir00r/user-service/internal/models/dtos"
+	"github.com/Mir00r/user-service/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthController serves the liveness and readiness probes of load balancers and orchestrators
+type HealthController struct {
+	Registry *health.Registry // Checks the dependencies of the service
+}
+
+// NewHealthController initializes a new HealthController
+func NewHealthController(registry *health.Registry) *HealthController {
+	return &HealthController{
+		Registry: registry,
+	}
+}
+
+// Live tells that the process is alive. It checks no dependency, so an outage of one does not get every
+// instance restarted.
+func (c *HealthController) Live(ctx *gin.Context) {
+	utils.GinJSONResponse(ctx, http.StatusOK, dtos.LivenessResponse{
+		Status:    health.StatusUp,
+		StartedAt: c.Registry.StartedAt().Format(time.RFC3339),
+		Uptime:    c.Registry.Uptime().Round(time.Second).String(),
+	})
+}
+
+// Ready tells whether the service is ready to receive traffic: 200 while it is up or degraded, and 503
+// while a critical check fails
+func (c *HealthController) Ready(ctx *gin.Context) {
+	report := c.Registry.Check(ctx.Request.Context())
+
+	response := dtos.ReadinessResponse{
+		Status:    report.Status,
+		CheckedAt: report.CheckedAt.Format(time.RFC3339),
+		Checks:    make([]dtos.CheckSummary, 0, len(report.Checks)),
+	}
+	for _, result := range report.Checks {
+		response.Checks = append(response.Checks, dtos.CheckSummary{Name: result.Name, Status: result.Status, LatencyMs: result.LatencyMs})
+	}
+
+	status := http.StatusOK
+	if report.Status == health.StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+	utils.GinJSONResponse(ctx, status, response)
+}
+
+// ServiceHealth reports the health of the service with the errors of its checks, for operators
+func (c *HealthController) ServiceHealth(ctx *gin.Context) {
+	report := c.Registry.Check(ctx.Request.Context())
+
+	utils.JSONResponseCtx(ctx, http.StatusOK, dtos.ServiceHealthResponse{
+		Status:    report.Status,
+		StartedAt: c.Registry.StartedAt().Format(time.RFC3339),
+		Uptime:    c.Registry.Uptime().Round(time.Second).String(),
+		CheckedAt: report.CheckedAt.Format(time.RFC3339),
+		Checks:    report.Checks,
+	})
+}
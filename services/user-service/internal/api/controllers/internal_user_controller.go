@@ -56,6 +56,125 @@ func (c *InternalUserController) ValidateUser(ctx *gin.Context) {
 	utils.JSONResponseCtx(ctx, http.StatusOK, user)
 }
 
+// ResolveExternalUser finds or creates the account of an external identity for federated login
+func (c *InternalUserController) ResolveExternalUser(ctx *gin.Context) {
+	var req dtos.ExternalUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		_ = ctx.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+
+	user, err := c.UserService.ResolveExternalUser(ctx, req)
+	if err != nil {
+		_ = ctx.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(ctx, http.StatusOK, user)
+}
+
+// CreateRegistration creates the profile of a registration orchestrated by auth-service
+func (c *InternalUserController) CreateRegistration(ctx *gin.Context) {
+	var req dtos.RegistrationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		_ = ctx.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+
+	user, err := c.UserService.CreateRegistration(ctx, req)
+	if err != nil {
+		_ = ctx.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(ctx, http.StatusCreated, user)
+}
+
+// DiscardRegistration deletes the profile of a registration that auth-service rolled back
+func (c *InternalUserController) DiscardRegistration(ctx *gin.Context) {
+	userId := ctx.Param("userId")
+
+	if err := c.UserService.DiscardRegistration(ctx, userId); err != nil {
+		_ = ctx.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(ctx, http.StatusOK, "Registration discarded successfully")
+}
+
+// MarkEmailVerified records that the owner of an account confirmed its email address
+func (c *InternalUserController) MarkEmailVerified(ctx *gin.Context) {
+	var req dtos.EmailVerifiedRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		_ = ctx.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+
+	if err := c.UserService.MarkEmailVerified(ctx, req.Email); err != nil {
+		_ = ctx.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(ctx, http.StatusOK, "Email verified successfully")
+}
+
+// SetPassword replaces the password of an account
+func (c *InternalUserController) SetPassword(ctx *gin.Context) {
+	var req dtos.SetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		_ = ctx.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+
+	if err := c.UserService.SetPassword(ctx, req.Email, req.Password); err != nil {
+		_ = ctx.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(ctx, http.StatusOK, "Password updated successfully")
+}
+
+// GetAccountStatus reports whether an account may sign in
+func (c *InternalUserController) GetAccountStatus(ctx *gin.Context) {
+	var req dtos.AccountStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		_ = ctx.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+
+	status, err := c.UserService.GetAccountStatus(ctx, req.Email)
+	if err != nil {
+		_ = ctx.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(ctx, http.StatusOK, status)
+}
+
+// DeactivateUser stops a user account from signing in
+func (c *InternalUserController) DeactivateUser(ctx *gin.Context) {
+	userId := ctx.Param("userId")
+
+	if err := c.UserService.DeactivateUser(ctx, userId); err != nil {
+		_ = ctx.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(ctx, http.StatusOK, "User deactivated successfully")
+}
+
+// AnonymizeUser removes the personal data of an account deleted by auth-service
+func (c *InternalUserController) AnonymizeUser(ctx *gin.Context) {
+	userId := ctx.Param("userId")
+
+	if err := c.UserService.AnonymizeUser(ctx, userId); err != nil {
+		_ = ctx.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(ctx, http.StatusOK, "User anonymized successfully")
+}
+
 // GetUserDetails retrieves user details, including internal fields
 func (c *InternalUserController) GetUserDetails(ctx *gin.Context) {
 	userId := ctx.Param("userId")
@@ -69,6 +188,39 @@ func (c *InternalUserController) GetUserDetails(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, user)
 }
 
+// BatchGetUsers retrieves the details of several users in one call
+func (c *InternalUserController) BatchGetUsers(ctx *gin.Context) {
+	var req dtos.BatchUsersRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		_ = ctx.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+
+	users, err := c.UserService.GetUsersByIDs(ctx, req.UserIDs)
+	if err != nil {
+		_ = ctx.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(ctx, http.StatusOK, dtos.BatchUsersResponse{Users: users})
+}
+
+// RecordLogin stores the time of a successful login
+func (c *InternalUserController) RecordLogin(ctx *gin.Context) {
+	var req dtos.LastLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		_ = ctx.Error(errors.ErrInvalidPayload) // Propagate error to middlewares
+		return
+	}
+
+	if err := c.UserService.RecordLogin(ctx, req.Email, req.LoggedInAt); err != nil {
+		_ = ctx.Error(err) // Propagate error to middlewares
+		return
+	}
+
+	utils.JSONResponseCtx(ctx, http.StatusOK, "Last login recorded successfully")
+}
+
 // ActivateUser activates a user account
 //func (c *InternalUserController) ActivateUser(ctx *gin.Context) {
 //	userId := ctx.Param("userId")
@@ -86,23 +238,6 @@ func (c *InternalUserController) GetUserDetails(ctx *gin.Context) {
 //	ctx.JSON(http.StatusOK, gin.H{"message": "User activated successfully"})
 //}
 //
-//// DeactivateUser deactivates a user account
-//func (c *InternalUserController) DeactivateUser(ctx *gin.Context) {
-//	userId := ctx.Param("userId")
-//
-//	err := c.UserService.DeactivateUser(userId)
-//	if err != nil {
-//		if err.Error() == "user not found" {
-//			ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-//		} else {
-//			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-//		}
-//		return
-//	}
-//
-//	ctx.JSON(http.StatusOK, gin.H{"message": "User deactivated successfully"})
-//}
-//
 //// SearchUsers searches for auth based on filters
 //func (c *InternalUserController) SearchUsers(ctx *gin.Context) {
 //	var req dtos.UserSearchRequest
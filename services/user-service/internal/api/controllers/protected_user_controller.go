@@ -65,17 +65,19 @@ func (c *ProtectedUserController) UpdateUser(ctx *gin.Context) {
 	utils.JSONResponseCtx(ctx, http.StatusCreated, user)
 }
 
-// DeleteUser deletes a user (Admin only)
+// DeleteUser starts the deletion of a user's account (Admin only).
+// auth-service runs the deletion after its grace period, so the request is only accepted here.
 func (c *ProtectedUserController) DeleteUser(ctx *gin.Context) {
 	userId := ctx.Param("userId")
+	requestedBy, _ := utils.ExtractUserIDFromContext(ctx)
 
-	err := c.UserService.DeleteUser(ctx, userId)
+	err := c.UserService.RequestDeletion(ctx, userId, requestedBy)
 	if err != nil {
 		_ = ctx.Error(err) // Propagate error to middlewares
 		return
 	}
 
-	utils.JSONResponseCtx(ctx, http.StatusOK, "User deleted successfully")
+	utils.JSONResponseCtx(ctx, http.StatusAccepted, "User deletion scheduled")
 }
 
 // // GetUserRoles retrieves roles assigned to a user (Admin only)
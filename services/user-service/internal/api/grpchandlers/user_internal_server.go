@@ -0,0 +1,105 @@
+package grpchandlers
+
+import (
+	"context"
+	"github.com/Mir00r/user-service/errors"
+	"github.com/Mir00r/user-service/internal/models/dtos"
+	"github.com/Mir00r/user-service/internal/services"
+	userinternalv1 "github.com/Mir00r/user-service/proto/userinternal/v1"
+	"github.com/Mir00r/user-service/utils"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+// UserInternalServer serves the internal gRPC API. It offers the same operations as the matching routes of
+// InternalUserController, and errors are returned as AppErrors, which carry their own gRPC status.
+type UserInternalServer struct {
+	userinternalv1.UnimplementedUserInternalServiceServer
+	UserService services.UserService
+}
+
+// NewUserInternalServer initializes a new UserInternalServer
+func NewUserInternalServer(userService services.UserService) *UserInternalServer {
+	return &UserInternalServer{
+		UserService: userService,
+	}
+}
+
+// ValidateCredentials checks the password of an account
+func (s *UserInternalServer) ValidateCredentials(ctx context.Context, req *userinternalv1.ValidateCredentialsRequest) (*userinternalv1.ValidateCredentialsResponse, error) {
+	if !utils.IsValidEmail(req.GetEmail()) || req.GetPassword() == "" {
+		return nil, errors.ErrInvalidPayload
+	}
+
+	user, err := s.UserService.ValidateUser(ctx, req.GetEmail(), req.GetPassword())
+	if err != nil {
+		return nil, err
+	}
+	return &userinternalv1.ValidateCredentialsResponse{User: toProtoUser(user)}, nil
+}
+
+// GetUser retrieves user details, including internal fields
+func (s *UserInternalServer) GetUser(ctx context.Context, req *userinternalv1.GetUserRequest) (*userinternalv1.GetUserResponse, error) {
+	user, err := s.UserService.GetUserByID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	return &userinternalv1.GetUserResponse{User: toProtoUser(user)}, nil
+}
+
+// BatchGetUsers retrieves the details of several users in one call
+func (s *UserInternalServer) BatchGetUsers(ctx context.Context, req *userinternalv1.BatchGetUsersRequest) (*userinternalv1.BatchGetUsersResponse, error) {
+	users, err := s.UserService.GetUsersByIDs(ctx, req.GetUserIds())
+	if err != nil {
+		return nil, err
+	}
+
+	response := &userinternalv1.BatchGetUsersResponse{Users: make([]*userinternalv1.User, 0, len(users))}
+	for i := range users {
+		response.Users = append(response.Users, toProtoUser(&users[i]))
+	}
+	return response, nil
+}
+
+// UpdateLastLogin stores the time of a successful login
+func (s *UserInternalServer) UpdateLastLogin(ctx context.Context, req *userinternalv1.UpdateLastLoginRequest) (*userinternalv1.UpdateLastLoginResponse, error) {
+	if !utils.IsValidEmail(req.GetEmail()) || req.GetLoggedInAt() == nil {
+		return nil, errors.ErrInvalidPayload
+	}
+
+	if err := s.UserService.RecordLogin(ctx, req.GetEmail(), req.GetLoggedInAt().AsTime()); err != nil {
+		return nil, err
+	}
+	return &userinternalv1.UpdateLastLoginResponse{}, nil
+}
+
+// toProtoUser converts a UserResponse DTO to its protobuf message
+func toProtoUser(user *dtos.UserResponse) *userinternalv1.User {
+	return &userinternalv1.User{
+		Id:             user.ID,
+		Name:           user.Name,
+		Email:          user.Email,
+		Phone:          user.Phone,
+		IsActive:       user.IsActive,
+		IsVerified:     user.IsVerified,
+		ProfilePicture: user.ProfilePicture,
+		Role:           user.Role,
+		CreatedAt:      timestamppb.New(user.CreatedAt),
+		UpdatedAt:      timestamppb.New(user.UpdatedAt),
+		LastLogin:      toTimestamp(user.LastLogin),
+		DateOfBirth:    toTimestamp(user.DateOfBirth),
+		Address:        user.Address,
+		Locale:         user.Locale,
+		Timezone:       user.Timezone,
+		TenantId:       user.TenantID,
+		MfaEnabled:     user.MFAEnabled,
+	}
+}
+
+// toTimestamp converts an optional time, keeping nil as an unset field
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,48 @@
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Mir00r/user-service/constants"
+	"github.com/Mir00r/user-service/internal/services"
+	"github.com/Mir00r/user-service/messaging"
+	"log/slog"
+	"time"
+)
+
+// loginSucceededEvent is the envelope of the auth.login_succeeded event published by auth-service
+type loginSucceededEvent struct {
+	OccurredAt time.Time `json:"occurredAt"`
+	Data       struct {
+		Email  string `json:"email"`
+		Method string `json:"method"`
+	} `json:"data"`
+}
+
+// AuthEventConsumer applies events published by auth-service to the user profiles
+type AuthEventConsumer struct {
+	UserService services.UserService
+}
+
+// NewAuthEventConsumer initializes a new AuthEventConsumer
+func NewAuthEventConsumer(userService services.UserService) *AuthEventConsumer {
+	return &AuthEventConsumer{UserService: userService}
+}
+
+// Start subscribes to the auth-service events
+func (c *AuthEventConsumer) Start(ctx context.Context, subscriber *messaging.NATSSubscriber, stream string) error {
+	return subscriber.Subscribe(ctx, stream, constants.ServiceName+"-login", constants.EventAuthLoginSucceeded, c.HandleLoginSucceeded)
+}
+
+// HandleLoginSucceeded records the login time on the profile.
+// The update is idempotent and ignores logins older than the stored one, so redelivered
+// and reordered events converge on the latest login.
+func (c *AuthEventConsumer) HandleLoginSucceeded(ctx context.Context, msg messaging.Message) error {
+	var event loginSucceededEvent
+	if err := json.Unmarshal(msg.Payload, &event); err != nil || event.Data.Email == "" || event.OccurredAt.IsZero() {
+		// A malformed event will never succeed, so it is dropped instead of redelivered
+		slog.WarnContext(ctx, "Dropping malformed event", "subject", msg.Subject, "event_id", msg.ID)
+		return nil
+	}
+	return c.UserService.RecordLogin(ctx, event.Data.Email, event.OccurredAt)
+}
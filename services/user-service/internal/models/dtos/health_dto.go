@@ -0,0 +1,34 @@
+package dtos
+
+import "github.com/Mir00r/shared/health"
+
+// ServiceHealthResponse reports the health of the service and of its dependencies, with the errors of the checks
+type ServiceHealthResponse struct {
+	Status    health.Status   `json:"status"`    // up, degraded while a dependency is impaired, or down
+	StartedAt string          `json:"startedAt"` // RFC3339
+	Uptime    string          `json:"uptime"`    // Time since the service started, e.g. 26h3m12s
+	CheckedAt string          `json:"checkedAt"` // RFC3339 time the checks ran, as results are cached briefly
+	Checks    []health.Result `json:"checks"`
+}
+
+// LivenessResponse tells that the process is alive and able to serve requests
+type LivenessResponse struct {
+	Status    health.Status `json:"status"`
+	StartedAt string        `json:"startedAt"` // RFC3339
+	Uptime    string        `json:"uptime"`
+}
+
+// ReadinessResponse tells whether the service is ready to receive traffic. It is served without
+// authentication, so it leaves out the errors of the checks, which name hosts and versions.
+type ReadinessResponse struct {
+	Status    health.Status  `json:"status"`
+	CheckedAt string         `json:"checkedAt"` // RFC3339
+	Checks    []CheckSummary `json:"checks"`
+}
+
+// CheckSummary is the outcome of one readiness check
+type CheckSummary struct {
+	Name      string        `json:"name"`
+	Status    health.Status `json:"status"`
+	LatencyMs float64       `json:"latencyMs"`
+}
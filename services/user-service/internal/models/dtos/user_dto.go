@@ -40,6 +40,64 @@ type ValidateRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// ExternalUserRequest is used to find or create the account of an external (OIDC) identity
+type ExternalUserRequest struct {
+	Provider      string `json:"provider" binding:"required"`
+	Subject       string `json:"subject" binding:"required"`
+	Email         string `json:"email" binding:"required,email"`
+	EmailVerified bool   `json:"emailVerified"`
+	Name          string `json:"name"`
+}
+
+// SetPasswordRequest replaces the password of an account on behalf of auth-service
+type SetPasswordRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// RegistrationRequest creates the profile of a registration orchestrated by auth-service.
+// auth-service hashes the password, so a retried request carries the same hash and is recognised as a replay.
+type RegistrationRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Email        string `json:"email" binding:"required,email"`
+	PasswordHash string `json:"passwordHash" binding:"required"`
+}
+
+// EmailVerifiedRequest records that the owner of an account confirmed its email address
+type EmailVerifiedRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// BatchUsersRequest asks for the users with the given IDs
+type BatchUsersRequest struct {
+	UserIDs []string `json:"userIds" binding:"required"`
+}
+
+// BatchUsersResponse holds the users found for a BatchUsersRequest
+type BatchUsersResponse struct {
+	Users []UserResponse `json:"users"`
+}
+
+// LastLoginRequest records a successful login reported by auth-service
+type LastLoginRequest struct {
+	Email      string    `json:"email" binding:"required,email"`
+	LoggedInAt time.Time `json:"loggedInAt" binding:"required"`
+}
+
+// AccountStatusRequest asks whether the account with the email may sign in
+type AccountStatusRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// AccountStatusResponse reports the sign-in relevant state of an account.
+// An unknown email is reported as a missing account rather than an error.
+type AccountStatusResponse struct {
+	UserID  string `json:"userId,omitempty"`
+	Exists  bool   `json:"exists"`
+	Active  bool   `json:"active"`
+	Deleted bool   `json:"deleted"`
+}
+
 // UserResponse is used for retrieving user details
 type UserResponse struct {
 	ID             string     `json:"id"`
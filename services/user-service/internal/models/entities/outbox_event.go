@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event stored in the same transaction as the state change it describes,
+// waiting for the relay to publish it
+type OutboxEvent struct {
+	ID            string          `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	EventType     string          `gorm:"type:varchar(100);not null" json:"event_type"`   // Message subject
+	AggregateID   string          `gorm:"type:varchar(100);not null" json:"aggregate_id"` // Entity the event is about
+	Payload       json.RawMessage `gorm:"type:jsonb;not null" json:"payload"`             // Event envelope
+	Attempts      int             `gorm:"not null;default:0" json:"attempts"`             // Failed publish attempts
+	NextAttemptAt time.Time       `gorm:"not null" json:"next_attempt_at"`                // Earliest time of the next attempt
+	LockedUntil   *time.Time      `json:"locked_until,omitempty"`                         // Relay lease
+	LastError     *string         `gorm:"type:text" json:"last_error,omitempty"`
+	PublishedAt   *time.Time      `json:"published_at,omitempty"`
+	CreatedAt     time.Time       `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (OutboxEvent) TableName() string {
+	return "auth.outbox_event"
+}
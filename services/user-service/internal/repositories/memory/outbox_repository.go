@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"github.com/Mir00r/user-service/internal/models/entities"
+	"github.com/Mir00r/user-service/internal/repositories"
+	"github.com/google/uuid"
+	"time"
+)
+
+type outboxRepository struct {
+	store *Store
+}
+
+// NewOutboxRepository creates an outbox repository over the store
+func NewOutboxRepository(store *Store) repositories.OutboxRepository {
+	return &outboxRepository{store: store}
+}
+
+// Enqueue stores an event for publishing
+func (r *outboxRepository) Enqueue(_ context.Context, event *entities.OutboxEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = time.Now()
+	}
+	event.CreatedAt = time.Now()
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.events = append(r.store.events, *event)
+	return nil
+}
+
+// ClaimPending leases up to limit due, unpublished events to the caller
+func (r *outboxRepository) ClaimPending(_ context.Context, limit int, lease time.Duration) ([]entities.OutboxEvent, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	var claimed []entities.OutboxEvent
+	for i := range r.store.events {
+		event := &r.store.events[i]
+		if len(claimed) == limit {
+			break
+		}
+		if event.PublishedAt != nil || event.NextAttemptAt.After(now) || (event.LockedUntil != nil && event.LockedUntil.After(now)) {
+			continue
+		}
+		lockedUntil := now.Add(lease)
+		event.LockedUntil = &lockedUntil
+		claimed = append(claimed, *event)
+	}
+	return claimed, nil
+}
+
+// MarkPublished records a successful publish
+func (r *outboxRepository) MarkPublished(_ context.Context, id string) error {
+	return r.update(id, func(event *entities.OutboxEvent) {
+		publishedAt := time.Now()
+		event.PublishedAt = &publishedAt
+		event.LockedUntil = nil
+	})
+}
+
+// MarkFailed records a failed publish and schedules the next attempt
+func (r *outboxRepository) MarkFailed(_ context.Context, id string, nextAttemptAt time.Time, lastError string) error {
+	return r.update(id, func(event *entities.OutboxEvent) {
+		event.Attempts++
+		event.NextAttemptAt = nextAttemptAt
+		event.LockedUntil = nil
+		event.LastError = &lastError
+	})
+}
+
+// update applies fn to the event with the id, if there is one
+func (r *outboxRepository) update(id string, fn func(event *entities.OutboxEvent)) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for i := range r.store.events {
+		if r.store.events[i].ID == id {
+			fn(&r.store.events[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+// Package memory holds in-memory implementations of the repositories, used where the service runs without
+// a database, such as the replay of the contracts of its consumers.
+package memory
+
+import (
+	"github.com/Mir00r/user-service/internal/models/entities"
+	"sync"
+)
+
+// Store holds the rows of the in-memory repositories. Repositories sharing a store share a transaction.
+type Store struct {
+	mu     sync.Mutex
+	users  map[string]entities.User
+	events []entities.OutboxEvent
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{users: map[string]entities.User{}}
+}
+
+// snapshot returns a copy of the rows, restored by restore when a transaction rolls back
+func (s *Store) snapshot() (map[string]entities.User, []entities.OutboxEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make(map[string]entities.User, len(s.users))
+	for id, user := range s.users {
+		users[id] = user
+	}
+	return users, append([]entities.OutboxEvent(nil), s.events...)
+}
+
+// restore replaces the rows with a snapshot
+func (s *Store) restore(users map[string]entities.User, events []entities.OutboxEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = users
+	s.events = events
+}
@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"context"
+	"github.com/Mir00r/user-service/internal/repositories"
+)
+
+type transactor struct {
+	store *Store
+}
+
+// NewTransactor creates a transactor over the store
+func NewTransactor(store *Store) repositories.Transactor {
+	return &transactor{store: store}
+}
+
+// WithinTransaction keeps the changes of fn when it returns nil and rolls them back otherwise.
+// Transactions are not isolated from each other, which the single caller of a replay does not need.
+func (t *transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	users, events := t.store.snapshot()
+	if err := fn(ctx); err != nil {
+		t.store.restore(users, events)
+		return err
+	}
+	return nil
+}
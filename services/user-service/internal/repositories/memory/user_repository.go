@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/Mir00r/user-service/internal/models/entities"
+	"github.com/Mir00r/user-service/internal/repositories"
+	"github.com/google/uuid"
+	"sort"
+	"time"
+)
+
+type userRepository struct {
+	store *Store
+}
+
+// NewUserRepository creates a user repository over the store
+func NewUserRepository(store *Store) repositories.UserRepository {
+	return &userRepository{store: store}
+}
+
+// CreateUser stores a new user, filling in the column defaults of the users table
+func (r *userRepository) CreateUser(_ context.Context, user *entities.User) (*entities.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.users {
+		if existing.Email == user.Email {
+			return nil, fmt.Errorf("duplicate email %s", user.Email)
+		}
+	}
+
+	if user.ID == "" {
+		user.ID = uuid.NewString()
+	}
+	if user.TenantID == "" {
+		user.TenantID = uuid.NewString()
+	}
+	if user.Role == nil {
+		role := "user"
+		user.Role = &role
+	}
+	if user.Locale == "" {
+		user.Locale = "en-US"
+	}
+	if user.Timezone == "" {
+		user.Timezone = "UTC"
+	}
+	if !user.IsActive {
+		user.IsActive = true // A false is left out of the insert, so the column default applies
+	}
+	now := time.Now().UTC()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+
+	r.store.users[user.ID] = *user
+	return user, nil
+}
+
+// GetUserByID retrieves a user by ID
+func (r *userRepository) GetUserByID(_ context.Context, userID string) (*entities.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	user, ok := r.store.users[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+// GetUserByEmail retrieves a user by email
+func (r *userRepository) GetUserByEmail(_ context.Context, email string) (*entities.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, user := range r.store.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetUsersByIDs retrieves the users with the given IDs; IDs without a user are left out
+func (r *userRepository) GetUsersByIDs(_ context.Context, userIDs []string) ([]entities.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	users := make([]entities.User, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if user, ok := r.store.users[userID]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// GetAllUsers retrieves all users with pagination, ordered by creation
+func (r *userRepository) GetAllUsers(_ context.Context, limit, offset int) ([]entities.User, int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	users := make([]entities.User, 0, len(r.store.users))
+	for _, user := range r.store.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].CreatedAt.Before(users[j].CreatedAt)
+	})
+
+	total := int64(len(users))
+	start := min(offset, len(users))
+	end := min(start+limit, len(users))
+	return users[start:end], total, nil
+}
+
+// UpdateUser replaces a user
+func (r *userRepository) UpdateUser(_ context.Context, user *entities.User) (*entities.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	user.UpdatedAt = time.Now().UTC()
+	r.store.users[user.ID] = *user
+	return user, nil
+}
+
+// DeleteUser removes a user
+func (r *userRepository) DeleteUser(_ context.Context, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	delete(r.store.users, userID)
+	return nil
+}
+
+// UpdateLastLogin moves last_login forward to loggedInAt; an older timestamp never overwrites a newer one
+func (r *userRepository) UpdateLastLogin(_ context.Context, email string, loggedInAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for id, user := range r.store.users {
+		if user.Email == email && (user.LastLogin == nil || user.LastLogin.Before(loggedInAt)) {
+			user.LastLogin = &loggedInAt
+			r.store.users[id] = user
+		}
+	}
+	return nil
+}
+
+// UpdatePassword replaces the password hash of a user
+func (r *userRepository) UpdatePassword(_ context.Context, userID string, hashedPassword string) error {
+	return r.update(userID, func(user *entities.User) {
+		user.Password = hashedPassword
+	})
+}
+
+// DeactivateUser stops the user from signing in
+func (r *userRepository) DeactivateUser(_ context.Context, userID string) error {
+	return r.update(userID, func(user *entities.User) {
+		user.IsActive = false
+	})
+}
+
+// MarkVerified records that the user confirmed their email address
+func (r *userRepository) MarkVerified(_ context.Context, userID string) error {
+	return r.update(userID, func(user *entities.User) {
+		user.IsVerified = true
+	})
+}
+
+// AssignRoleToUser assigns a role to a user
+func (r *userRepository) AssignRoleToUser(_ context.Context, userID string, role string) error {
+	return r.update(userID, func(user *entities.User) {
+		user.Role = &role
+	})
+}
+
+// RemoveRoleFromUser resets the role of a user to the default
+func (r *userRepository) RemoveRoleFromUser(_ context.Context, userID string, _ string) error {
+	return r.update(userID, func(user *entities.User) {
+		role := "user"
+		user.Role = &role
+	})
+}
+
+// update applies fn to the user with the ID, if there is one
+func (r *userRepository) update(userID string, fn func(user *entities.User)) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if user, ok := r.store.users[userID]; ok {
+		fn(&user)
+		r.store.users[userID] = user
+	}
+	return nil
+}
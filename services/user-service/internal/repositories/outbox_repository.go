@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"github.com/Mir00r/user-service/internal/models/entities"
+	"gorm.io/gorm"
+	"time"
+)
+
+type OutboxRepository interface {
+	// Enqueue stores an event; call it inside a transaction so the event is only kept if the state change commits
+	Enqueue(ctx context.Context, event *entities.OutboxEvent) error
+
+	// Relay operations
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]entities.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// Enqueue stores an event for publishing
+func (r *outboxRepository) Enqueue(ctx context.Context, event *entities.OutboxEvent) error {
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = time.Now()
+	}
+	return conn(ctx, r.db).Create(event).Error
+}
+
+// ClaimPending leases up to limit due, unpublished events to the caller.
+// Rows locked by another relay instance are skipped, and an expired lease makes an event claimable again.
+func (r *outboxRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]entities.OutboxEvent, error) {
+	var events []entities.OutboxEvent
+	err := conn(ctx, r.db).Raw(`
+		UPDATE auth.outbox_event SET locked_until = now() + make_interval(secs => ?)
+		WHERE id IN (
+			SELECT id FROM auth.outbox_event
+			WHERE published_at IS NULL
+			  AND next_attempt_at <= now()
+			  AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY created_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, lease.Seconds(), limit).
+		Scan(&events).Error
+	return events, err
+}
+
+// MarkPublished records a successful publish
+func (r *outboxRepository) MarkPublished(ctx context.Context, id string) error {
+	return conn(ctx, r.db).Model(&entities.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"published_at": time.Now(), "locked_until": nil}).
+		Error
+}
+
+// MarkFailed records a failed publish and schedules the next attempt
+func (r *outboxRepository) MarkFailed(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error {
+	return conn(ctx, r.db).Model(&entities.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": nextAttemptAt,
+			"locked_until":    nil,
+			"last_error":      lastError,
+		}).
+		Error
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/Mir00r/user-service/internal/models/entities"
 	"gorm.io/gorm"
+	"time"
 )
 
 type UserRepository interface {
@@ -12,9 +13,14 @@ type UserRepository interface {
 	CreateUser(ctx context.Context, user *entities.User) (*entities.User, error)
 	GetUserByID(ctx context.Context, userID string) (*entities.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*entities.User, error)
+	GetUsersByIDs(ctx context.Context, userIDs []string) ([]entities.User, error)
 	GetAllUsers(ctx context.Context, limit, offset int) ([]entities.User, int64, error)
 	UpdateUser(ctx context.Context, user *entities.User) (*entities.User, error)
 	DeleteUser(ctx context.Context, userID string) error
+	UpdateLastLogin(ctx context.Context, email string, loggedInAt time.Time) error
+	UpdatePassword(ctx context.Context, userID string, hashedPassword string) error
+	MarkVerified(ctx context.Context, userID string) error
+	DeactivateUser(ctx context.Context, userID string) error
 
 	// Role management
 	AssignRoleToUser(ctx context.Context, userID string, role string) error
@@ -31,7 +37,7 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 
 // CreateUser creates a new user in the database
 func (r *userRepository) CreateUser(ctx context.Context, user *entities.User) (*entities.User, error) {
-	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+	if err := conn(ctx, r.db).Create(user).Error; err != nil {
 		return nil, err
 	}
 	return user, nil
@@ -40,7 +46,7 @@ func (r *userRepository) CreateUser(ctx context.Context, user *entities.User) (*
 // GetUserByID retrieves a user by ID
 func (r *userRepository) GetUserByID(ctx context.Context, userID string) (*entities.User, error) {
 	var user entities.User
-	if err := r.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := conn(ctx, r.db).Where("id = ?", userID).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -52,7 +58,7 @@ func (r *userRepository) GetUserByID(ctx context.Context, userID string) (*entit
 // GetUserByEmail retrieves a user by email
 func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*entities.User, error) {
 	var user entities.User
-	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+	if err := conn(ctx, r.db).Where("email = ?", email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -61,16 +67,25 @@ func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*ent
 	return &user, nil
 }
 
+// GetUsersByIDs retrieves the users with the given IDs; IDs without a user are left out
+func (r *userRepository) GetUsersByIDs(ctx context.Context, userIDs []string) ([]entities.User, error) {
+	var users []entities.User
+	if err := conn(ctx, r.db).Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 // GetAllUsers retrieves all auth with pagination
 func (r *userRepository) GetAllUsers(ctx context.Context, limit, offset int) ([]entities.User, int64, error) {
 	var users []entities.User
 	var totalCount int64
 
-	if err := r.db.WithContext(ctx).Model(&entities.User{}).Count(&totalCount).Error; err != nil {
+	if err := conn(ctx, r.db).Model(&entities.User{}).Count(&totalCount).Error; err != nil {
 		return nil, 0, err
 	}
 
-	if err := r.db.WithContext(ctx).Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+	if err := conn(ctx, r.db).Limit(limit).Offset(offset).Find(&users).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -79,7 +94,7 @@ func (r *userRepository) GetAllUsers(ctx context.Context, limit, offset int) ([]
 
 // UpdateUser updates a user's details
 func (r *userRepository) UpdateUser(ctx context.Context, user *entities.User) (*entities.User, error) {
-	if err := r.db.WithContext(ctx).Save(user).Error; err != nil {
+	if err := conn(ctx, r.db).Save(user).Error; err != nil {
 		return nil, err
 	}
 	return user, nil
@@ -87,18 +102,41 @@ func (r *userRepository) UpdateUser(ctx context.Context, user *entities.User) (*
 
 // DeleteUser soft-deletes a user by marking deleted_at
 func (r *userRepository) DeleteUser(ctx context.Context, userID string) error {
-	if err := r.db.WithContext(ctx).Where("id = ?", userID).Delete(&entities.User{}).Error; err != nil {
+	if err := conn(ctx, r.db).Where("id = ?", userID).Delete(&entities.User{}).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
+// UpdateLastLogin moves last_login forward to loggedInAt. An older timestamp never overwrites a newer one,
+// so replayed or reordered login events leave the latest login in place.
+func (r *userRepository) UpdateLastLogin(ctx context.Context, email string, loggedInAt time.Time) error {
+	return conn(ctx, r.db).Model(&entities.User{}).
+		Where("email = ? AND (last_login IS NULL OR last_login < ?)", email, loggedInAt).
+		Update("last_login", loggedInAt).Error
+}
+
+// UpdatePassword replaces the password hash of a user
+func (r *userRepository) UpdatePassword(ctx context.Context, userID string, hashedPassword string) error {
+	return conn(ctx, r.db).Model(&entities.User{}).Where("id = ?", userID).Update("password", hashedPassword).Error
+}
+
+// DeactivateUser stops the user from signing in
+func (r *userRepository) DeactivateUser(ctx context.Context, userID string) error {
+	return conn(ctx, r.db).Model(&entities.User{}).Where("id = ?", userID).Update("is_active", false).Error
+}
+
+// MarkVerified records that the user confirmed their email address
+func (r *userRepository) MarkVerified(ctx context.Context, userID string) error {
+	return conn(ctx, r.db).Model(&entities.User{}).Where("id = ?", userID).Update("is_verified", true).Error
+}
+
 // AssignRoleToUser assigns a role to a user
 func (r *userRepository) AssignRoleToUser(ctx context.Context, userID string, role string) error {
-	return r.db.WithContext(ctx).Model(&entities.User{}).Where("id = ?", userID).Update("role", role).Error
+	return conn(ctx, r.db).Model(&entities.User{}).Where("id = ?", userID).Update("role", role).Error
 }
 
 // RemoveRoleFromUser removes a role from a user
 func (r *userRepository) RemoveRoleFromUser(ctx context.Context, userID string, role string) error {
-	return r.db.WithContext(ctx).Model(&entities.User{}).Where("id = ?", userID).Update("role", "user").Error
+	return conn(ctx, r.db).Model(&entities.User{}).Where("id = ?", userID).Update("role", "user").Error
 }
@@ -2,35 +2,55 @@ package services
 
 import (
 	"context"
+	"github.com/Mir00r/user-service/constants"
 	"github.com/Mir00r/user-service/errors"
 	"github.com/Mir00r/user-service/internal/models/dtos"
 	"github.com/Mir00r/user-service/internal/models/entities"
 	"github.com/Mir00r/user-service/internal/repositories"
+	"github.com/Mir00r/user-service/messaging"
+	"github.com/Mir00r/user-service/metrics"
+	"github.com/Mir00r/user-service/telemetry"
 	utils2 "github.com/Mir00r/user-service/utils"
+	"time"
 )
 
 type UserService interface {
 	CreateUser(ctx context.Context, req dtos.CreateUserRequest) (*dtos.UserResponse, error)
 	ValidateUser(ctx context.Context, email, password string) (*dtos.UserResponse, error)
+	ResolveExternalUser(ctx context.Context, req dtos.ExternalUserRequest) (*dtos.UserResponse, error)
+	CreateRegistration(ctx context.Context, req dtos.RegistrationRequest) (*dtos.UserResponse, error)
+	DiscardRegistration(ctx context.Context, userID string) error
+	MarkEmailVerified(ctx context.Context, email string) error
+	GetAccountStatus(ctx context.Context, email string) (*dtos.AccountStatusResponse, error)
+	RecordLogin(ctx context.Context, email string, loggedInAt time.Time) error
+	SetPassword(ctx context.Context, email, password string) error
 	GetUserByID(ctx context.Context, userID string) (*dtos.UserResponse, error)
+	GetUsersByIDs(ctx context.Context, userIDs []string) ([]dtos.UserResponse, error)
 	GetAllUsers(ctx context.Context, limit, offset int) (*dtos.PaginatedUserResponse, error)
 	UpdateUser(ctx context.Context, userID string, req dtos.UpdateUserRequest) (*dtos.UserResponse, error)
 	DeleteUser(ctx context.Context, userID string) error
+	RequestDeletion(ctx context.Context, userID, requestedBy string) error
+	DeactivateUser(ctx context.Context, userID string) error
+	AnonymizeUser(ctx context.Context, userID string) error
 	AssignRole(ctx context.Context, userID string, role string) error
 	RemoveRole(ctx context.Context, userID string) error
 }
 
 type userService struct {
-	repo repositories.UserRepository
+	repo       repositories.UserRepository
+	outboxRepo repositories.OutboxRepository
+	transactor repositories.Transactor
 }
 
 // NewUserService creates a new instance of UserService
-func NewUserService(repo repositories.UserRepository) UserService {
-	return &userService{repo: repo}
+func NewUserService(repo repositories.UserRepository, outboxRepo repositories.OutboxRepository, transactor repositories.Transactor) UserService {
+	return &userService{repo: repo, outboxRepo: outboxRepo, transactor: transactor}
 }
 
 // CreateUser creates a new user
-func (s *userService) CreateUser(ctx context.Context, req dtos.CreateUserRequest) (*dtos.UserResponse, error) {
+func (s *userService) CreateUser(ctx context.Context, req dtos.CreateUserRequest) (created *dtos.UserResponse, err error) {
+	defer func() { metrics.Registrations.WithLabelValues(metrics.RegistrationDirect, metrics.Outcome(err)).Inc() }()
+
 	// Validate email
 	if !utils2.IsValidEmail(req.Email) {
 		return nil, errors.ErrInvalidEmail
@@ -41,10 +61,14 @@ func (s *userService) CreateUser(ctx context.Context, req dtos.CreateUserRequest
 		return nil, errors.ErrWeakPassword
 	}
 
-	// Validate date of birth
-	parsedDateTime, err := utils2.ConvertStringToTime(req.DateOfBirth, "2006-01-02")
-	if err != nil {
-		return nil, errors.ErrInvalidDateOfBirth
+	// Validate the optional date of birth
+	var dateOfBirth *time.Time
+	if req.DateOfBirth != "" {
+		parsedDateTime, err := utils2.ConvertStringToTime(req.DateOfBirth, "2006-01-02")
+		if err != nil {
+			return nil, errors.ErrInvalidDateOfBirth
+		}
+		dateOfBirth = &parsedDateTime
 	}
 
 	// Check if email already exists
@@ -69,23 +93,47 @@ func (s *userService) CreateUser(ctx context.Context, req dtos.CreateUserRequest
 		Password:    hashedPassword,
 		Phone:       req.Phone,
 		Role:        utils2.GetOrDefault(req.Role, &defaultRole),
-		DateOfBirth: &parsedDateTime,
+		DateOfBirth: dateOfBirth,
 		Address:     req.Address,
 	}
 
 	// Save user
-	createdUser, err := s.repo.CreateUser(ctx, user)
+	createdUser, err := s.createUser(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
+	telemetry.SetUser(ctx, createdUser.ID, createdUser.TenantID)
 	return dtos.ToUserResponse(createdUser), nil
 }
 
-func (s *userService) ValidateUser(ctx context.Context, email, password string) (*dtos.UserResponse, error) {
+// createUser saves a new user and records the user.created event in the same transaction
+func (s *userService) createUser(ctx context.Context, user *entities.User) (*entities.User, error) {
+	var createdUser *entities.User
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if createdUser, err = s.repo.CreateUser(ctx, user); err != nil {
+			return err
+		}
+
+		event, err := messaging.NewOutboxEvent(constants.EventUserCreated, createdUser.ID, dtos.ToUserResponse(createdUser))
+		if err != nil {
+			return err
+		}
+		return s.outboxRepo.Enqueue(ctx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return createdUser, nil
+}
+
+func (s *userService) ValidateUser(ctx context.Context, email, password string) (validated *dtos.UserResponse, err error) {
+	defer func() { metrics.CredentialValidations.WithLabelValues(metrics.Outcome(err)).Inc() }()
+
 	// Fetch user by email
 	user, err := s.repo.GetUserByEmail(ctx, email)
-	if err != nil {
+	if err != nil || user == nil {
 		return nil, errors.ErrUserNotFound
 	}
 
@@ -95,9 +143,190 @@ func (s *userService) ValidateUser(ctx context.Context, email, password string)
 	}
 
 	// Return user details
+	telemetry.SetUser(ctx, user.ID, user.TenantID)
 	return dtos.ToUserResponse(user), nil
 }
 
+// ResolveExternalUser returns the account of an external identity, creating it just in time.
+// An existing account is only linked when the identity provider vouches for the email address.
+func (s *userService) ResolveExternalUser(ctx context.Context, req dtos.ExternalUserRequest) (*dtos.UserResponse, error) {
+	existingUser, err := s.repo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, errors.ErrFailedToFetchUser
+	}
+	if existingUser != nil {
+		if !req.EmailVerified {
+			return nil, errors.ErrUnverifiedEmailConflict
+		}
+		telemetry.SetUser(ctx, existingUser.ID, existingUser.TenantID)
+		return dtos.ToUserResponse(existingUser), nil
+	}
+
+	// Federated accounts get an unusable random password
+	randomPassword, err := utils2.GenerateRefreshToken()
+	if err != nil {
+		return nil, errors.ErrPasswordHashing
+	}
+	hashedPassword, err := utils2.HashPassword(randomPassword)
+	if err != nil {
+		return nil, errors.ErrPasswordHashing
+	}
+
+	name := req.Name
+	if name == "" {
+		name = req.Email
+	}
+	defaultRole := "User"
+	user := &entities.User{
+		Name:       name,
+		Email:      req.Email,
+		Password:   hashedPassword,
+		Role:       &defaultRole,
+		IsVerified: req.EmailVerified,
+		IsActive:   true,
+	}
+
+	createdUser, err := s.createUser(ctx, user)
+	if err != nil {
+		return nil, errors.ErrFailedToRegisterUser
+	}
+
+	telemetry.SetUser(ctx, createdUser.ID, createdUser.TenantID)
+	return dtos.ToUserResponse(createdUser), nil
+}
+
+// CreateRegistration creates the profile of a registration orchestrated by auth-service, which applies
+// the password policy and sends the password already hashed. The request is idempotent: a retry carrying
+// the hash of the existing profile returns that profile instead of failing with a conflict.
+func (s *userService) CreateRegistration(ctx context.Context, req dtos.RegistrationRequest) (registered *dtos.UserResponse, err error) {
+	defer func() { metrics.Registrations.WithLabelValues(metrics.RegistrationSaga, metrics.Outcome(err)).Inc() }()
+
+	if !utils2.IsValidEmail(req.Email) {
+		return nil, errors.ErrInvalidEmail
+	}
+	if !utils2.IsPasswordHash(req.PasswordHash) {
+		return nil, errors.ErrInvalidPayload
+	}
+
+	existingUser, err := s.repo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, errors.ErrFailedToFetchUser
+	}
+	if existingUser != nil {
+		if existingUser.Password == req.PasswordHash {
+			telemetry.SetUser(ctx, existingUser.ID, existingUser.TenantID)
+			return dtos.ToUserResponse(existingUser), nil
+		}
+		return nil, errors.ErrEmailAlreadyExists
+	}
+
+	defaultRole := "User"
+	user := &entities.User{
+		Name:     req.Name,
+		Email:    req.Email,
+		Password: req.PasswordHash,
+		Role:     &defaultRole,
+		IsActive: true,
+	}
+
+	createdUser, err := s.createUser(ctx, user)
+	if err != nil {
+		return nil, errors.ErrFailedToRegisterUser
+	}
+
+	telemetry.SetUser(ctx, createdUser.ID, createdUser.TenantID)
+	return dtos.ToUserResponse(createdUser), nil
+}
+
+// DiscardRegistration deletes the profile of a registration that auth-service rolled back.
+// A profile that is already gone counts as discarded, and a verified profile is never discarded.
+func (s *userService) DiscardRegistration(ctx context.Context, userID string) error {
+	if !utils2.IsValidUUID(userID) {
+		return errors.ErrInvalidUserID
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.ErrFailedToFetchUser
+	}
+	if user == nil {
+		return nil
+	}
+	if user.IsVerified {
+		return errors.ErrRegistrationAlreadyVerified
+	}
+
+	if err := s.deleteUser(ctx, user); err != nil {
+		return errors.ErrFailedToDeleteUser
+	}
+	return nil
+}
+
+// MarkEmailVerified records that the owner of the account confirmed its email address
+func (s *userService) MarkEmailVerified(ctx context.Context, email string) error {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return errors.ErrFailedToFetchUser
+	}
+	if user == nil {
+		return errors.ErrUserNotFound
+	}
+
+	if err := s.repo.MarkVerified(ctx, user.ID); err != nil {
+		return errors.ErrFailedToUpdateUser
+	}
+	return nil
+}
+
+// GetAccountStatus reports whether the account with the email exists and may sign in
+func (s *userService) GetAccountStatus(ctx context.Context, email string) (*dtos.AccountStatusResponse, error) {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, errors.ErrFailedToFetchUser
+	}
+	if user == nil {
+		return &dtos.AccountStatusResponse{}, nil
+	}
+
+	deleted := user.DeletedAt != nil
+	return &dtos.AccountStatusResponse{
+		UserID:  user.ID,
+		Exists:  true,
+		Active:  user.IsActive && !deleted,
+		Deleted: deleted,
+	}, nil
+}
+
+// RecordLogin stores the time of a successful login reported by auth-service.
+// Logins of unknown accounts are ignored, and older logins never replace newer ones.
+func (s *userService) RecordLogin(ctx context.Context, email string, loggedInAt time.Time) error {
+	if err := s.repo.UpdateLastLogin(ctx, email, loggedInAt); err != nil {
+		return errors.NewAppError(errors.ErrFailedToRecordLogin.Code, errors.ErrFailedToRecordLogin.Message, err)
+	}
+	return nil
+}
+
+// SetPassword replaces the password of an account. user-service is the only store of password hashes,
+// so auth-service sets passwords through here after applying its own password policy.
+func (s *userService) SetPassword(ctx context.Context, email, password string) error {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return errors.ErrFailedToFetchUser
+	}
+	if user == nil {
+		return errors.ErrUserNotFound
+	}
+
+	hashedPassword, err := utils2.HashPassword(password)
+	if err != nil {
+		return errors.ErrPasswordHashing
+	}
+	if err := s.repo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return errors.ErrFailedToUpdatePassword
+	}
+	return nil
+}
+
 // GetUserByID retrieves a user by ID
 func (s *userService) GetUserByID(ctx context.Context, userID string) (*dtos.UserResponse, error) {
 	// Validate user ID
@@ -113,9 +342,36 @@ func (s *userService) GetUserByID(ctx context.Context, userID string) (*dtos.Use
 		return nil, errors.ErrUserNotFound
 	}
 
+	telemetry.SetUser(ctx, user.ID, user.TenantID)
 	return dtos.ToUserResponse(user), nil
 }
 
+// GetUsersByIDs retrieves the users with the given IDs in one query. IDs without a user are left out.
+func (s *userService) GetUsersByIDs(ctx context.Context, userIDs []string) ([]dtos.UserResponse, error) {
+	if len(userIDs) > constants.MaxBatchUserIDs {
+		return nil, errors.ErrTooManyUserIDs
+	}
+	for _, userID := range userIDs {
+		if !utils2.IsValidUUID(userID) {
+			return nil, errors.ErrInvalidUserID
+		}
+	}
+	if len(userIDs) == 0 {
+		return []dtos.UserResponse{}, nil
+	}
+
+	users, err := s.repo.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, errors.NewAppError(errors.ErrFailedToFetchUser.Code, errors.ErrFailedToFetchUser.Message, err)
+	}
+
+	responses := make([]dtos.UserResponse, 0, len(users))
+	for i := range users {
+		responses = append(responses, *dtos.ToUserResponse(&users[i]))
+	}
+	return responses, nil
+}
+
 // GetAllUsers retrieves a paginated list of auth
 func (s *userService) GetAllUsers(ctx context.Context, limit, offset int) (*dtos.PaginatedUserResponse, error) {
 	if limit <= 0 || offset < 0 {
@@ -173,6 +429,7 @@ func (s *userService) UpdateUser(ctx context.Context, userID string, req dtos.Up
 		return nil, err
 	}
 
+	telemetry.SetUser(ctx, updatedUser.ID, updatedUser.TenantID)
 	return dtos.ToUserResponse(updatedUser), nil
 }
 
@@ -191,7 +448,127 @@ func (s *userService) DeleteUser(ctx context.Context, userID string) error {
 		return errors.ErrUserNotFound
 	}
 
-	return s.repo.DeleteUser(ctx, userID)
+	return s.deleteUser(ctx, user)
+}
+
+// deleteUser deletes a user and records the user.deleted event in the same transaction
+func (s *userService) deleteUser(ctx context.Context, user *entities.User) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.DeleteUser(ctx, user.ID); err != nil {
+			return err
+		}
+
+		event, err := messaging.NewOutboxEvent(constants.EventUserDeleted, user.ID, map[string]string{
+			"userId": user.ID,
+			"email":  user.Email,
+		})
+		if err != nil {
+			return err
+		}
+		return s.outboxRepo.Enqueue(ctx, event)
+	})
+}
+
+// RequestDeletion asks auth-service to delete the account by recording the user.deletion_requested event.
+// auth-service revokes the sessions and purges the credentials of the account before it has the profile
+// anonymized, so the profile is left untouched here.
+func (s *userService) RequestDeletion(ctx context.Context, userID, requestedBy string) error {
+	if !utils2.IsValidUUID(userID) {
+		return errors.ErrInvalidUserID
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.ErrFailedToFetchUser
+	}
+	if user == nil || user.DeletedAt != nil {
+		return errors.ErrUserNotFound
+	}
+
+	event, err := messaging.NewOutboxEvent(constants.EventUserDeletionRequested, user.ID, map[string]string{
+		"userId":      user.ID,
+		"email":       user.Email,
+		"requestedBy": requestedBy,
+	})
+	if err != nil {
+		return errors.ErrFailedToDeleteUser
+	}
+	if err := s.outboxRepo.Enqueue(ctx, event); err != nil {
+		return errors.ErrFailedToDeleteUser
+	}
+	return nil
+}
+
+// DeactivateUser stops the account from signing in; deactivating it again has no effect
+func (s *userService) DeactivateUser(ctx context.Context, userID string) error {
+	if !utils2.IsValidUUID(userID) {
+		return errors.ErrInvalidUserID
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.ErrFailedToFetchUser
+	}
+	if user == nil {
+		return errors.ErrUserNotFound
+	}
+
+	if err := s.repo.DeactivateUser(ctx, user.ID); err != nil {
+		return errors.ErrFailedToUpdateUser
+	}
+	return nil
+}
+
+// AnonymizeUser removes the personal data of a deleted account and records the user.deleted event in the
+// same transaction. The row is kept, so references to the ID stay valid. An anonymized profile is left as it is,
+// making the call safe to repeat.
+func (s *userService) AnonymizeUser(ctx context.Context, userID string) error {
+	if !utils2.IsValidUUID(userID) {
+		return errors.ErrInvalidUserID
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.ErrFailedToFetchUser
+	}
+	if user == nil {
+		return errors.ErrUserNotFound
+	}
+	if user.DeletedAt != nil {
+		return nil
+	}
+
+	event, err := messaging.NewOutboxEvent(constants.EventUserDeleted, user.ID, map[string]string{
+		"userId": user.ID,
+		"email":  user.Email,
+	})
+	if err != nil {
+		return errors.ErrFailedToAnonymizeUser
+	}
+
+	now := time.Now()
+	user.Name = constants.AnonymizedUserName
+	user.Email = "deleted-" + user.ID + "@invalid"
+	user.Password = "" // Matches no password
+	user.Phone = ""
+	user.ProfilePicture = ""
+	user.Address = nil
+	user.DateOfBirth = nil
+	user.MFAEnabled = false
+	user.MFASecret = nil
+	user.IsActive = false
+	user.DeletedAt = &now
+
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.repo.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		return s.outboxRepo.Enqueue(ctx, event)
+	})
+	if err != nil {
+		return errors.ErrFailedToAnonymizeUser
+	}
+	return nil
 }
 
 // AssignRole assigns a role to a user
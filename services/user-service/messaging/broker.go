@@ -0,0 +1,17 @@
+package messaging
+
+import "context"
+
+// Message is a domain event ready to be published
+type Message struct {
+	ID      string // Unique event ID; consumers de-duplicate redeliveries with it
+	Subject string // Event type, e.g. user.created
+	Payload []byte // JSON event envelope
+}
+
+// Broker publishes messages to a message bus.
+// Publish must only return nil once the bus has accepted the message.
+type Broker interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
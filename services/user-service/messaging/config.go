@@ -0,0 +1,61 @@
+package messaging
+
+import (
+	"context"
+	"github.com/Mir00r/user-service/configs"
+	"github.com/Mir00r/user-service/constants"
+	"log"
+	"log/slog"
+	"time"
+)
+
+// streamTimeout bounds the creation of the JetStream streams at startup
+const streamTimeout = 10 * time.Second
+
+// NewRelayFromConfig connects to the configured broker and builds the outbox relay.
+// It returns nil when no broker is configured; events then stay in the outbox until one is.
+func NewRelayFromConfig(cfg configs.MessagingConfig, store OutboxStore) *Relay {
+	if cfg.NatsURL == "" {
+		slog.Warn("Messaging is not configured, domain events stay in the outbox")
+		return nil
+	}
+
+	broker, err := NewNATSBroker(cfg.NatsURL, cfg.JetStream)
+	if err != nil {
+		log.Fatalf("Failed to connect to NATS: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), streamTimeout)
+	defer cancel()
+	if err := broker.EnsureStream(ctx, cfg.Stream, constants.EventSubjects); err != nil {
+		log.Fatalf("Failed to create the %s stream: %v", cfg.Stream, err)
+	}
+	if !cfg.JetStream {
+		slog.Warn("Publishing to core NATS, events published while no subscriber is connected are lost")
+	}
+
+	return NewRelay(store, broker, RelayConfig{
+		PollInterval: cfg.Outbox.PollInterval.Duration(),
+		BatchSize:    cfg.Outbox.BatchSize,
+	})
+}
+
+// NewSubscriberFromConfig connects a subscriber to the configured broker.
+// It returns nil when no broker is configured.
+func NewSubscriberFromConfig(cfg configs.MessagingConfig) *NATSSubscriber {
+	if cfg.NatsURL == "" {
+		return nil
+	}
+
+	subscriber, err := NewNATSSubscriber(cfg.NatsURL, cfg.JetStream)
+	if err != nil {
+		log.Fatalf("Failed to connect to NATS: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), streamTimeout)
+	defer cancel()
+	if err := subscriber.EnsureStream(ctx, cfg.AuthStream, constants.AuthEventSubjects); err != nil {
+		log.Fatalf("Failed to create the %s stream: %v", cfg.AuthStream, err)
+	}
+	return subscriber
+}
@@ -0,0 +1,39 @@
+package messaging
+
+import (
+	"encoding/json"
+	"github.com/Mir00r/user-service/internal/models/entities"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Envelope is the JSON document published for every domain event
+type Envelope struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	Source     string      `json:"source"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       interface{} `json:"data"`
+}
+
+// NewOutboxEvent wraps event data in an envelope and prepares it for the outbox
+func NewOutboxEvent(eventType, aggregateID string, data interface{}) (*entities.OutboxEvent, error) {
+	id := uuid.NewString()
+	payload, err := json.Marshal(Envelope{
+		ID:         id,
+		Type:       eventType,
+		Source:     "user-service",
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.OutboxEvent{
+		ID:          id,
+		EventType:   eventType,
+		AggregateID: aggregateID,
+		Payload:     payload,
+	}, nil
+}
@@ -0,0 +1,120 @@
+package messaging
+
+import (
+	"context"
+	"github.com/Mir00r/user-service/internal/models/entities"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// OutboxStore is the part of the outbox repository the relay needs
+type OutboxStore interface {
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]entities.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error
+}
+
+// RelayConfig tunes the outbox relay
+type RelayConfig struct {
+	PollInterval   time.Duration // Wait between polls when the outbox is drained, 1s when zero
+	BatchSize      int           // Events claimed per poll, 100 when zero
+	Lease          time.Duration // How long a claimed batch is reserved, 30s when zero
+	PublishTimeout time.Duration // Timeout of a single publish, 5s when zero
+	MaxBackoff     time.Duration // Upper bound of the retry delay, 5m when zero
+}
+
+// Relay moves events from the outbox to the broker with at-least-once delivery:
+// an event is only marked published after the broker accepted it, so a crash in between causes a redelivery.
+type Relay struct {
+	store  OutboxStore
+	broker Broker
+	cfg    RelayConfig
+}
+
+// NewRelay initializes a relay, filling in defaults for unset configuration
+func NewRelay(store OutboxStore, broker Broker, cfg RelayConfig) *Relay {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 30 * time.Second
+	}
+	if cfg.PublishTimeout <= 0 {
+		cfg.PublishTimeout = 5 * time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	return &Relay{store: store, broker: broker, cfg: cfg}
+}
+
+// Run publishes pending events until ctx is cancelled. The batch in progress when ctx is cancelled is still
+// published, so events claimed on shutdown are not left to wait for their lease to expire.
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		published, err := r.RelayOnce(context.WithoutCancel(ctx))
+		if err != nil {
+			slog.ErrorContext(ctx, "Outbox relay failed to claim events", "error", err)
+		}
+
+		// Keep going while full batches are waiting
+		if err == nil && published == r.cfg.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.cfg.PollInterval):
+		}
+	}
+}
+
+// RelayOnce claims one batch of due events and publishes it, returning the number of events claimed
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.store.ClaimPending(ctx, r.cfg.BatchSize, r.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		publishCtx, cancel := context.WithTimeout(ctx, r.cfg.PublishTimeout)
+		err := r.broker.Publish(publishCtx, Message{ID: event.ID, Subject: event.EventType, Payload: event.Payload})
+		cancel()
+
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to publish event", "event_type", event.EventType, "event_id", event.ID, "attempt", event.Attempts+1, "error", err)
+			if markErr := r.store.MarkFailed(ctx, event.ID, time.Now().Add(r.backoff(event.Attempts+1)), err.Error()); markErr != nil {
+				slog.ErrorContext(ctx, "Failed to reschedule outbox event", "event_id", event.ID, "error", markErr)
+			}
+			continue
+		}
+
+		if err := r.store.MarkPublished(ctx, event.ID); err != nil {
+			// The lease expires and the event is published again, which consumers tolerate
+			slog.ErrorContext(ctx, "Failed to mark outbox event as published", "event_id", event.ID, "error", err)
+		}
+	}
+	return len(events), nil
+}
+
+// Close releases the broker connection
+func (r *Relay) Close() error {
+	return r.broker.Close()
+}
+
+// backoff returns the exponential retry delay after the given number of failed attempts
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := time.Duration(math.Pow(2, float64(attempts))) * time.Second
+	if delay <= 0 || delay > r.cfg.MaxBackoff {
+		return r.cfg.MaxBackoff
+	}
+	return delay
+}
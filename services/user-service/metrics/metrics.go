@@ -0,0 +1,104 @@
+// Package metrics exposes the metrics of the service in the Prometheus text format: the rate, errors and duration
+// of the HTTP and gRPC calls it serves, the connection pool of its database and the outcome of credential
+// validations and registrations. Labels only take values from bounded sets, such as route templates and gRPC
+// methods, so the number of series stays bounded whatever the traffic.
+package metrics
+
+import (
+	"database/sql"
+	goerrors "errors"
+	"github.com/Mir00r/user-service/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strings"
+)
+
+// UnmatchedRoute is the route label of requests that matched no route
+const UnmatchedRoute = "unmatched"
+
+// OutcomeSuccess is the outcome label of an operation that succeeded
+const OutcomeSuccess = "success"
+
+// registry holds the metrics of the service, next to those of the Go runtime and the process
+var registry = prometheus.NewRegistry()
+
+// factory registers the metrics of the package with the registry
+var factory = promauto.With(registry)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+var (
+	// HTTPRequests counts the requests served, by method, route template and status
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_server_requests_total",
+		Help: "Requests served, by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration observes the time taken to serve requests, by method, route template and status
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_server_request_duration_seconds",
+		Help:    "Time taken to serve requests, by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// HTTPInFlight is the number of requests being served
+	HTTPInFlight = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "http_server_requests_in_flight",
+		Help: "Requests being served.",
+	})
+
+	// GRPCDuration observes the time taken to serve gRPC calls, by full method name and status code
+	GRPCDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_call_duration_seconds",
+		Help:    "Time taken to serve gRPC calls, by full method name and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// RegisterDB exposes the statistics of the connection pool of the database
+func RegisterDB(db *sql.DB, name string) error {
+	return registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Method returns the method label of a request, folding methods the service does not serve into OTHER
+func Method(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
+
+// Outcome returns the outcome label of an operation: success, or the reason of the failure as the audit
+// events give it, such as invalid_credentials or not_found
+func Outcome(err error) string {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	status := http.StatusInternalServerError
+	var appErr *errors.AppError
+	if goerrors.As(err, &appErr) {
+		if appErr.Message == errors.ErrInvalidCredentials.Message {
+			return "invalid_credentials"
+		}
+		status = appErr.Code
+	}
+	if status >= http.StatusInternalServerError || http.StatusText(status) == "" {
+		return "internal_error"
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Flows creating a profile
+const (
+	RegistrationDirect = "direct" // Created in a single call, by the user or over the internal API
+	RegistrationSaga   = "saga"   // Created as a step of a registration saga of auth-service
+)
+
+var (
+	// CredentialValidations counts the checks of an email and password, by outcome
+	CredentialValidations = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "user_credential_validations_total",
+		Help: "Checks of an email and password, by outcome.",
+	}, []string{"outcome"})
+
+	// Registrations counts the profiles created, by flow and outcome
+	Registrations = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "user_registrations_total",
+		Help: "Profiles created, by flow (direct, saga) and outcome.",
+	}, []string{"flow", "outcome"})
+)
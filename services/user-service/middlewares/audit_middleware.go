@@ -0,0 +1,88 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	goerrors "errors"
+	"github.com/Mir00r/shared/auditsinks"
+	"github.com/Mir00r/user-service/constants"
+	"github.com/Mir00r/user-service/errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Audit streams the outcome of the wrapped handler to the audit sinks as an event of the given type.
+// It must run inside ErrorHandler so the handler's errors are still available on the context.
+// Delivery is asynchronous, so a slow sink never delays the response.
+func Audit(dispatcher *auditsinks.Dispatcher, eventType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		submittedEmail := peekEmail(c)
+
+		c.Next()
+
+		event := auditsinks.Event{
+			Time:      time.Now().UTC(),
+			Service:   constants.ServiceName,
+			EventType: eventType,
+			UserID:    c.Param("userId"),
+			Actor:     submittedEmail,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Outcome:   constants.AuditOutcomeSuccess,
+		}
+		if userID := c.GetString("userID"); userID != "" {
+			event.Actor = userID
+		}
+
+		status := c.Writer.Status()
+		if err := c.Errors.Last(); err != nil {
+			event.Outcome = constants.AuditOutcomeFailure
+			event.Reason = auditReason(err.Err, status)
+		} else if status >= http.StatusBadRequest {
+			event.Outcome = constants.AuditOutcomeFailure
+			event.Reason = auditReason(nil, status)
+		}
+
+		dispatcher.Publish(event)
+	}
+}
+
+// auditReason derives a machine readable reason code from the error returned to the client
+func auditReason(err error, status int) string {
+	var appErr *errors.AppError
+	if goerrors.As(err, &appErr) {
+		if appErr.Message == errors.ErrInvalidCredentials.Message {
+			return "invalid_credentials"
+		}
+		status = appErr.Code
+	} else if err != nil {
+		status = http.StatusInternalServerError
+	}
+	if status >= http.StatusInternalServerError {
+		return "internal_error"
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// peekEmail reads the email field of a JSON body without consuming the body for the handler
+func peekEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	return payload.Email
+}
@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"context"
+	"github.com/Mir00r/user-service/constants"
+	"github.com/Mir00r/user-service/errors"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"time"
+)
+
+// Deadline bounds the request context by the time the caller still waits for the response, as sent by
+// auth-service in the X-Request-Timeout-Ms header, so queries stop once nobody waits for their result.
+// A request whose caller has already given up is refused without doing any work.
+func Deadline() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(constants.RequestTimeoutHeader)
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		remaining, err := strconv.ParseInt(header, 10, 64)
+		if err != nil {
+			// A malformed header must not fail the call; the request just runs without a deadline
+			c.Next()
+			return
+		}
+		if remaining <= 0 {
+			_ = c.Error(errors.ErrDeadlineExceeded) // Propagate error to middlewares
+			c.Abort()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(remaining)*time.Millisecond)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -3,7 +3,7 @@ package middlewares
 import (
 	"github.com/Mir00r/user-service/errors"
 	"github.com/gin-gonic/gin"
-	"log"
+	"log/slog"
 	"net/http"
 )
 
@@ -25,7 +25,7 @@ func ErrorHandler() gin.HandlerFunc {
 				})
 			} else {
 				// Log and handle unknown errors
-				log.Printf("Unexpected error: %v", err.Err)
+				slog.ErrorContext(c.Request.Context(), "Unexpected error", "error", err.Err)
 				c.JSON(http.StatusInternalServerError, gin.H{
 					"error":      true,
 					"code":       appErr.Code,
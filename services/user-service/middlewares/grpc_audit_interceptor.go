@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"context"
+	"github.com/Mir00r/shared/auditsinks"
+	"github.com/Mir00r/user-service/constants"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"net"
+	"time"
+)
+
+// GRPCAudit streams the outcome of the gRPC methods listed in eventTypes to the audit sinks, keyed by
+// full method name, in the same shape as Audit records the matching HTTP routes. Other methods are not audited.
+func GRPCAudit(dispatcher *auditsinks.Dispatcher, eventTypes map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		eventType, ok := eventTypes[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		resp, err := handler(ctx, req)
+
+		event := auditsinks.Event{
+			Time:      time.Now().UTC(),
+			Service:   constants.ServiceName,
+			EventType: eventType,
+			IP:        peerIP(ctx),
+			UserAgent: firstMetadata(ctx, "user-agent"),
+			Outcome:   constants.AuditOutcomeSuccess,
+		}
+		if r, ok := req.(interface{ GetEmail() string }); ok {
+			event.Actor = r.GetEmail()
+		}
+		if r, ok := req.(interface{ GetUserId() string }); ok {
+			event.UserID = r.GetUserId()
+		}
+		if err != nil {
+			event.Outcome = constants.AuditOutcomeFailure
+			event.Reason = auditReason(err, 0)
+		}
+
+		dispatcher.Publish(event)
+		return resp, err
+	}
+}
+
+// peerIP returns the IP address of the caller
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,138 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/base64"
+	goerrors "errors"
+	"github.com/Mir00r/user-service/constants"
+	"github.com/Mir00r/user-service/errors"
+	"github.com/Mir00r/user-service/logging"
+	"github.com/Mir00r/user-service/metrics"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// The interceptors below are the gRPC counterparts of the Gin middlewares of the internal API.
+// routes.NewGRPCServer chains them in the order they are declared here.
+
+// GRPCMetrics times every call by method and status code, as Metrics does for HTTP requests
+func GRPCMetrics() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		metrics.GRPCDuration.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// GRPCRecovery turns a panic of a handler into an Internal error instead of crashing the server
+func GRPCRecovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				slog.ErrorContext(ctx, "Panic in gRPC handler", "method", info.FullMethod, "panic", r, "stack", string(debug.Stack()))
+				err = status.Error(codes.Internal, "An unexpected error occurred")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// GRPCTracing continues the request ID sent by the caller, or starts one, and echoes it in the response
+// header, so the logs of both services can be matched up
+func GRPCTracing() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requestID := firstMetadata(ctx, constants.RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(constants.RequestIDHeader, requestID))
+		return handler(logging.WithRequestID(ctx, requestID), req)
+	}
+}
+
+// GRPCLogging logs every call with its outcome and duration, like RequestLogging does for HTTP requests
+func GRPCLogging() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		slog.InfoContext(ctx, "gRPC call served",
+			"method", info.FullMethod, "code", status.Code(err).String(), "duration_ms", float64(time.Since(start).Microseconds())/1000)
+		return resp, err
+	}
+}
+
+// GRPCBasicAuth admits only callers presenting the internal credentials as basic auth in the
+// authorization metadata, as BasicAuthMiddleware does for the internal HTTP API
+func GRPCBasicAuth() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		username, password, ok := parseBasicAuth(firstMetadata(ctx, constants.Authorization))
+		if !ok || !isInternalCaller(username, password) {
+			return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GRPCDeadline refuses a call whose caller has already given up. The deadline itself travels with
+// every gRPC call and already bounds the context of the handler.
+func GRPCDeadline() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+			return nil, errors.ErrDeadlineExceeded
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GRPCErrors keeps unexpected errors from reaching the caller, as ErrorHandler does for HTTP. AppErrors
+// and status errors pass unchanged, and errors of a context that ended report why it ended.
+func GRPCErrors() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+
+		var appErr *errors.AppError
+		if goerrors.As(err, &appErr) {
+			return nil, appErr
+		}
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, status.FromContextError(ctxErr).Err()
+		}
+		slog.ErrorContext(ctx, "Unexpected error", "method", info.FullMethod, "error", err)
+		return nil, status.Error(codes.Internal, "An unexpected error occurred")
+	}
+}
+
+// firstMetadata returns the first value of a key of the incoming metadata
+func firstMetadata(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// parseBasicAuth parses the value of a basic authorization, as http.Request.BasicAuth does for the header
+func parseBasicAuth(authorization string) (string, string, bool) {
+	encoded, ok := strings.CutPrefix(authorization, "Basic ")
+	if !ok {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
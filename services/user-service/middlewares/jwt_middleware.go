@@ -3,9 +3,10 @@ package middlewares
 import (
 	"github.com/Mir00r/user-service/configs"
 	"github.com/Mir00r/user-service/constants"
+	"github.com/Mir00r/user-service/telemetry"
 	utils2 "github.com/Mir00r/user-service/utils"
 	"github.com/gin-gonic/gin"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 )
@@ -31,35 +32,30 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Verify the JWT and extract claims
 		claims, err := utils2.VerifyJWT(tokenString)
 		if err != nil {
-			log.Printf("JWT verification error: %v", err)
+			slog.WarnContext(c.Request.Context(), "Rejected JWT", "error", err)
 			utils2.GinErrorResponse(c, http.StatusUnauthorized, constants.ErrInvalidToken)
 			c.Abort()
 			return
 		}
-		log.Printf("Extracted user ID: %s", claims.UserID)
-
 		// Add claims to the Go context
 		ctx := utils2.AddClaimsToContext(c.Request.Context(), claims)
+		telemetry.SetUser(ctx, claims.UserID, "")
 
 		// Inject claims into the Gin context
 		c.Set("userID", claims.UserID)
 		c.Request = c.Request.WithContext(ctx) // Attach the modified context to the request
 
-		log.Printf("Extracted user ID: %s", claims.UserID)
 		c.Next()
 	}
 }
 
 // BasicAuthMiddleware validates requests using Basic Auth or API keys
 func BasicAuthMiddleware(c *gin.Context) {
-	// Fetch expected credentials from environment variables
-	expectedUsername := configs.AppConfig.InternalSecurity.UserName
-	expectedPassword := configs.AppConfig.InternalSecurity.Password
 	//expectedAPIKey := os.Getenv("API_KEY") // API key support
 
 	// Check Basic Auth credentials
 	username, password, hasAuth := c.Request.BasicAuth()
-	if hasAuth && username == expectedUsername && password == expectedPassword {
+	if hasAuth && isInternalCaller(username, password) {
 		c.Next()
 		return
 	}
@@ -67,3 +63,8 @@ func BasicAuthMiddleware(c *gin.Context) {
 	// Unauthorized if no valid credentials
 	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 }
+
+// isInternalCaller reports whether the credentials are the configured internal credentials
+func isInternalCaller(username, password string) bool {
+	return username == configs.AppConfig.InternalSecurity.UserName && password == configs.AppConfig.InternalSecurity.Password
+}
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"github.com/Mir00r/user-service/constants"
+	"github.com/Mir00r/user-service/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// validRequestID matches the request IDs accepted from callers; others are replaced, so a caller cannot
+// forge log lines or grow them without bound
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// healthRoutePrefix is the prefix of the liveness and readiness probes
+const healthRoutePrefix = "/health/"
+
+// RequestLogging continues the request ID sent by the caller in the X-Request-Id header, or starts one, echoes
+// it in the response and attaches it to the context of the request, so every record logged for the request
+// carries it. Once the request is served, it logs the route, status, latency and user of the request, at debug
+// level for successful probes.
+// Register it first, so the record covers the other middlewares.
+func RequestLogging() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := c.GetHeader(constants.RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Header(constants.RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if userID := c.GetString("userID"); userID != "" {
+			attrs = append(attrs, slog.String("user_id", userID))
+		}
+		if err := c.Errors.Last(); err != nil {
+			attrs = append(attrs, slog.Any("error", err.Err))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status < http.StatusBadRequest && strings.HasPrefix(c.FullPath(), healthRoutePrefix):
+			level = slog.LevelDebug // Probes come every few seconds and would drown the other records
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		slog.LogAttrs(c.Request.Context(), level, "Request served", attrs...)
+	}
+}
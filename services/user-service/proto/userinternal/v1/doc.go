@@ -0,0 +1,5 @@
+// Package userinternalv1 holds the messages and gRPC stubs of the internal API of user-service.
+// They are generated from user_internal.proto; change the .proto and run go generate instead of editing them.
+package userinternalv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative userinternal/v1/user_internal.proto
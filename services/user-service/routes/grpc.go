@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/Mir00r/shared/auditsinks"
+	"github.com/Mir00r/user-service/constants"
+	"github.com/Mir00r/user-service/internal/api/grpchandlers"
+	"github.com/Mir00r/user-service/middlewares"
+	userinternalv1 "github.com/Mir00r/user-service/proto/userinternal/v1"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+// grpcAuditEvents maps the audited gRPC methods to their audit event types, as the internal HTTP routes do
+var grpcAuditEvents = map[string]string{
+	userinternalv1.UserInternalService_ValidateCredentials_FullMethodName: constants.AuditCredentialValidation,
+}
+
+// NewGRPCServer creates the gRPC server of the internal API with the interceptors matching the middlewares
+// of the internal HTTP routes. Calls are traced as server spans joining the trace of the caller, and timed by
+// method and status code.
+func NewGRPCServer(userInternalServer *grpchandlers.UserInternalServer, auditDispatcher *auditsinks.Dispatcher) *grpc.Server {
+	server := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()), grpc.ChainUnaryInterceptor(
+		middlewares.GRPCMetrics(),
+		middlewares.GRPCRecovery(),
+		middlewares.GRPCTracing(),
+		middlewares.GRPCLogging(),
+		middlewares.GRPCBasicAuth(),
+		middlewares.GRPCDeadline(),
+		middlewares.GRPCAudit(auditDispatcher, grpcAuditEvents),
+		middlewares.GRPCErrors(),
+	))
+	userinternalv1.RegisterUserInternalServiceServer(server, userInternalServer)
+	return server
+}
@@ -1,7 +1,12 @@
 package routes
 
 import (
+	"github.com/Mir00r/shared/auditsinks"
+	"github.com/Mir00r/shared/logging"
+	"github.com/Mir00r/user-service/configs"
+	"github.com/Mir00r/user-service/constants"
 	"github.com/Mir00r/user-service/internal/api/controllers"
+	"github.com/Mir00r/user-service/metrics"
 	"github.com/Mir00r/user-service/middlewares"
 	"github.com/gin-gonic/gin"
 )
@@ -12,38 +17,61 @@ func SetupRoutes(
 	publicUserController *controllers.PublicUserController,
 	protectedUserController *controllers.ProtectedUserController,
 	internalUserController *controllers.InternalUserController,
+	healthController *controllers.HealthController,
+	auditDispatcher *auditsinks.Dispatcher,
 ) {
+	// Log every request with its request ID, including the work of the other middlewares
+	router.Use(middlewares.RequestLogging())
+
+	// Trace every request, including the work of the other middlewares
+	router.Use(middlewares.Tracing())
+
+	// Count every request and time it, including the work of the other middlewares
+	router.Use(middlewares.Metrics())
+
 	// Attach exception middlewares
 	router.Use(middlewares.ErrorHandler())
 
+	// Initialize liveness and readiness probes
+	initializeHealthRoutes(router, healthController)
+
 	// Initialize Public API routes
-	initializePublicRoutes(router, publicUserController)
+	initializePublicRoutes(router, publicUserController, auditDispatcher)
 
 	// Initialize Protected API routes
-	initializeProtectedRoutes(router, protectedUserController)
+	initializeProtectedRoutes(router, protectedUserController, auditDispatcher)
 
 	// Initialize Internal API routes
-	initializeInternalRoutes(router, internalUserController)
+	initializeInternalRoutes(router, internalUserController, healthController, auditDispatcher)
+}
+
+// initializeHealthRoutes sets up the probes of load balancers and orchestrators, which need no credentials
+func initializeHealthRoutes(router *gin.Engine, controller *controllers.HealthController) {
+	healthGroup := router.Group("/health")
+	{
+		healthGroup.GET("/live", controller.Live)
+		healthGroup.GET("/ready", controller.Ready)
+	}
 }
 
 // initializePublicRoutes sets up routes for Public APIs
-func initializePublicRoutes(router *gin.Engine, controller *controllers.PublicUserController) {
+func initializePublicRoutes(router *gin.Engine, controller *controllers.PublicUserController, auditDispatcher *auditsinks.Dispatcher) {
 	publicGroup := router.Group("/v1/public/user")
 	{
-		publicGroup.POST("/register", controller.CreateUser)
+		publicGroup.POST("/register", middlewares.Audit(auditDispatcher, constants.AuditUserRegistered), controller.CreateUser)
 		publicGroup.GET("/details/:userId", controller.GetUser)
 	}
 }
 
 // initializeProtectedRoutes sets up routes for Protected APIs
-func initializeProtectedRoutes(router *gin.Engine, controller *controllers.ProtectedUserController) {
+func initializeProtectedRoutes(router *gin.Engine, controller *controllers.ProtectedUserController, auditDispatcher *auditsinks.Dispatcher) {
 	protectedGroup := router.Group("/v1/protected/user")
 	protectedGroup.Use(middlewares.AuthMiddleware()) // Apply JWT validation middlewares
 	{
-		protectedGroup.GET("", controller.GetAllUsers)           // Admin only
-		protectedGroup.GET("/:userId", controller.GetUserByID)   // Admin/User (self-access)
-		protectedGroup.PUT("/:userId", controller.UpdateUser)    // Admin/User (self-access)
-		protectedGroup.DELETE("/:userId", controller.DeleteUser) // Admin only
+		protectedGroup.GET("", controller.GetAllUsers)                                                                           // Admin only
+		protectedGroup.GET("/:userId", controller.GetUserByID)                                                                   // Admin/User (self-access)
+		protectedGroup.PUT("/:userId", middlewares.Audit(auditDispatcher, constants.AuditUserUpdated), controller.UpdateUser)    // Admin/User (self-access)
+		protectedGroup.DELETE("/:userId", middlewares.Audit(auditDispatcher, constants.AuditUserDeleted), controller.DeleteUser) // Admin only
 		//protectedGroup.GET("/:userId/roles", controller.GetUserRoles)   // Admin only
 		//protectedGroup.POST("/:userId/roles", controller.AssignRoles)   // Admin only
 		//protectedGroup.DELETE("/:userId/roles/:roleId", controller.RemoveRole) // Admin only
@@ -51,15 +79,30 @@ func initializeProtectedRoutes(router *gin.Engine, controller *controllers.Prote
 }
 
 // initializeInternalRoutes sets up routes for Internal APIs
-func initializeInternalRoutes(router *gin.Engine, controller *controllers.InternalUserController) {
+func initializeInternalRoutes(router *gin.Engine, controller *controllers.InternalUserController, healthController *controllers.HealthController, auditDispatcher *auditsinks.Dispatcher) {
 	internalGroup := router.Group("/v1/internal/user")
 	internalGroup.Use(middlewares.BasicAuthMiddleware) // Apply Basic Auth middlewares
+	internalGroup.Use(middlewares.Deadline())          // Stop working once auth-service gives up
 	{
-		internalGroup.POST("", controller.CreateUser)                    // Create a new user
-		internalGroup.POST("/validate", controller.ValidateUser)         // Validate a user
-		internalGroup.GET("/:userId/details", controller.GetUserDetails) // Fetch user details (with all internal fields)
+		internalGroup.POST("", middlewares.Audit(auditDispatcher, constants.AuditUserRegistered), controller.CreateUser)                               // Create a new user
+		internalGroup.POST("/validate", middlewares.Audit(auditDispatcher, constants.AuditCredentialValidation), controller.ValidateUser)              // Validate a user
+		internalGroup.POST("/external", middlewares.Audit(auditDispatcher, constants.AuditExternalIdentityResolved), controller.ResolveExternalUser)   // Find or create the user of an external identity
+		internalGroup.POST("/account-status", controller.GetAccountStatus)                                                                             // Check whether an account may sign in
+		internalGroup.PUT("/password", controller.SetPassword)                                                                                         // Replace the password of an account
+		internalGroup.POST("/registrations", middlewares.Audit(auditDispatcher, constants.AuditUserRegistered), controller.CreateRegistration)         // Create the profile of a registration saga
+		internalGroup.DELETE("/registrations/:userId", middlewares.Audit(auditDispatcher, constants.AuditUserDeleted), controller.DiscardRegistration) // Discard the profile of a rolled back registration
+		internalGroup.PUT("/email-verified", controller.MarkEmailVerified)                                                                             // Record a confirmed email address
+		internalGroup.POST("/batch", controller.BatchGetUsers)                                                                                         // Fetch the details of several users at once
+		internalGroup.PUT("/last-login", controller.RecordLogin)                                                                                       // Record a successful login
+		internalGroup.GET("/:userId/details", controller.GetUserDetails)                                                                               // Fetch user details (with all internal fields)
+		internalGroup.PUT("/:userId/deactivate", controller.DeactivateUser)                                                                            // Stop an account from signing in
+		internalGroup.POST("/:userId/anonymize", middlewares.Audit(auditDispatcher, constants.AuditUserDeleted), controller.AnonymizeUser)             // Remove the personal data of a deleted account
+		internalGroup.GET("/service-health", healthController.ServiceHealth)                                                                           // Health of the service with the errors of its checks
+		internalGroup.GET("/metrics", gin.WrapH(metrics.Handler()))                                                                                    // Prometheus metrics
+		internalGroup.GET("/log-level", gin.WrapH(logging.LevelHandler()))                                                                             // Current log level
+		internalGroup.PUT("/log-level", gin.WrapH(logging.LevelHandler()))                                                                             // Change the log level until restart
+		internalGroup.GET("/config", gin.WrapH(configs.Handler()))                                                                                     // Configuration in effect, secrets redacted
 		//internalGroup.PUT("/:userId/activate", controllers.ActivateUser)     // Activate user account
-		//internalGroup.PUT("/:userId/deactivate", controllers.DeactivateUser) // Deactivate user account
 		//internalGroup.GET("/search", controllers.SearchUsers)                // Search auth by filters
 	}
 }
@@ -0,0 +1,182 @@
+// Package telemetry traces the work of the service with OpenTelemetry. The spans of incoming HTTP and gRPC
+// calls and of the database queries they run join the trace of the caller, such as auth-service, which
+// travels between the services in the W3C traceparent header.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"github.com/Mir00r/user-service/configs"
+	"github.com/Mir00r/user-service/constants"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"log"
+	"log/slog"
+	"os"
+	"sync/atomic"
+)
+
+// tracerName is the instrumentation scope of the spans started by the service
+const tracerName = "github.com/Mir00r/user-service"
+
+// Exporters of the tracing configuration
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterNone   = "none"
+)
+
+// TenantIDKey is the span attribute of the tenant a request acts for
+const TenantIDKey = attribute.Key("tenant.id")
+
+// provider is the installed tracer provider, nil while spans are not recorded
+var provider *sdktrace.TracerProvider
+
+// sampler decides which of the traces started by the service are recorded, shared by every tracer provider
+var sampler = &ratioSampler{}
+
+// Tracer returns the tracer of the service
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// NewTracerProvider creates a tracer provider exporting the spans of the service as configured.
+// It returns nil when the configuration records no spans.
+func NewTracerProvider(cfg configs.TracingConfig, serviceName string) (*sdktrace.TracerProvider, error) {
+	exporter, err := newExporter(cfg)
+	if err != nil || exporter == nil {
+		return nil, err
+	}
+
+	SetSampleRatio(cfg.SampleRatio)
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+		// A trace started by a caller is recorded whenever the caller recorded it
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	), nil
+}
+
+// SetSampleRatio changes the share of the traces started by the service that are recorded, all when zero
+func SetSampleRatio(ratio float64) {
+	if ratio <= 0 {
+		ratio = 1
+	}
+	sampler.current.Store(&ratioSamplerState{Sampler: sdktrace.TraceIDRatioBased(ratio)})
+}
+
+// ratioSampler samples a share of the traces that can be changed while the service runs
+type ratioSampler struct {
+	current atomic.Pointer[ratioSamplerState]
+}
+
+// ratioSamplerState holds the sampler of the current ratio
+type ratioSamplerState struct {
+	sdktrace.Sampler
+}
+
+// ShouldSample implements sdktrace.Sampler
+func (s *ratioSampler) ShouldSample(parameters sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return s.current.Load().ShouldSample(parameters)
+}
+
+// Description implements sdktrace.Sampler
+func (s *ratioSampler) Description() string {
+	return s.current.Load().Description()
+}
+
+// newExporter creates the span exporter of the configuration, nil when spans are not recorded
+func newExporter(cfg configs.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterOTLP:
+		var opts []otlptracegrpc.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(context.Background(), opts...)
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		return fileExporter{Exporter: exporter, file: file}, nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+}
+
+// fileExporter closes the file of its spans on shutdown
+type fileExporter struct {
+	*stdouttrace.Exporter
+	file *os.File
+}
+
+// Shutdown implements sdktrace.SpanExporter
+func (e fileExporter) Shutdown(ctx context.Context) error {
+	err := e.Exporter.Shutdown(ctx)
+	if closeErr := e.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// SetupFromConfig installs the tracer provider of the configuration and the W3C trace context propagator.
+// Trace context is passed on to the services called even when the service records no spans itself, and the
+// sample ratio follows the changes made by reloading the configuration. An invalid configuration stops the service.
+func SetupFromConfig(cfg configs.TracingConfig) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	tracerProvider, err := NewTracerProvider(cfg, constants.ServiceName)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	if tracerProvider == nil {
+		slog.Info("Tracing disabled")
+		return
+	}
+	otel.SetTracerProvider(tracerProvider)
+	provider = tracerProvider
+	configs.OnReload("tracing.sample-ratio", func(cfg configs.Config) {
+		SetSampleRatio(cfg.Tracing.SampleRatio)
+	})
+	slog.Info("Exporting traces", "exporter", cfg.Exporter)
+}
+
+// Shutdown exports the spans still buffered and stops the tracer provider installed by SetupFromConfig
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// SetUser records the user a request acts for on the current span, with their tenant when it is known
+func SetUser(ctx context.Context, userID, tenantID string) {
+	span := trace.SpanFromContext(ctx)
+	if userID != "" {
+		span.SetAttributes(semconv.EnduserID(userID))
+	}
+	if tenantID != "" {
+		span.SetAttributes(TenantIDKey.String(tenantID))
+	}
+}
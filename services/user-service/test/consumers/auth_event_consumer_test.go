@@ -0,0 +1,79 @@
+package consumers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/user-service/constants"
+	"github.com/Mir00r/user-service/internal/consumers"
+	"github.com/Mir00r/user-service/internal/models/entities"
+	"github.com/Mir00r/user-service/internal/repositories"
+	"github.com/Mir00r/user-service/internal/repositories/memory"
+	"github.com/Mir00r/user-service/internal/services"
+	"github.com/Mir00r/user-service/messaging"
+)
+
+// newConsumer returns a consumer over an in-memory store holding jane@example.com
+func newConsumer(t *testing.T) (*consumers.AuthEventConsumer, repositories.UserRepository) {
+	t.Helper()
+	store := memory.NewStore()
+	userRepo := memory.NewUserRepository(store)
+	_, err := userRepo.CreateUser(context.Background(), &entities.User{Name: "Jane", Email: "jane@example.com"})
+	require.NoError(t, err)
+	userService := services.NewUserService(userRepo, memory.NewOutboxRepository(store), memory.NewTransactor(store))
+	return consumers.NewAuthEventConsumer(userService), userRepo
+}
+
+// loginSucceeded returns the auth.login_succeeded event of a login by email at occurredAt
+func loginSucceeded(email string, occurredAt time.Time) messaging.Message {
+	payload := fmt.Sprintf(`{"id":"event-1","type":%q,"occurredAt":%q,"data":{"userId":"user-1","email":%q,"method":"password"}}`,
+		constants.EventAuthLoginSucceeded, occurredAt.Format(time.RFC3339Nano), email)
+	return messaging.Message{ID: "event-1", Subject: constants.EventAuthLoginSucceeded, Payload: []byte(payload)}
+}
+
+// lastLogin returns the last login recorded for jane@example.com
+func lastLogin(t *testing.T, userRepo repositories.UserRepository) *time.Time {
+	t.Helper()
+	user, err := userRepo.GetUserByEmail(context.Background(), "jane@example.com")
+	require.NoError(t, err)
+	return user.LastLogin
+}
+
+func TestHandleLoginSucceeded_RecordsTheLogin(t *testing.T) {
+	consumer, userRepo := newConsumer(t)
+	loggedInAt := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	require.NoError(t, consumer.HandleLoginSucceeded(context.Background(), loginSucceeded("jane@example.com", loggedInAt)))
+
+	require.NotNil(t, lastLogin(t, userRepo))
+	assert.True(t, loggedInAt.Equal(*lastLogin(t, userRepo)))
+}
+
+func TestHandleLoginSucceeded_KeepsTheLatestLoginWhateverTheOrder(t *testing.T) {
+	consumer, userRepo := newConsumer(t)
+	latest := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	require.NoError(t, consumer.HandleLoginSucceeded(context.Background(), loginSucceeded("jane@example.com", latest)))
+	require.NoError(t, consumer.HandleLoginSucceeded(context.Background(), loginSucceeded("jane@example.com", latest.Add(-time.Hour))))
+	require.NoError(t, consumer.HandleLoginSucceeded(context.Background(), loginSucceeded("jane@example.com", latest)), "a redelivery is harmless")
+
+	assert.True(t, latest.Equal(*lastLogin(t, userRepo)))
+}
+
+func TestHandleLoginSucceeded_DropsMalformedEvents(t *testing.T) {
+	consumer, userRepo := newConsumer(t)
+
+	for _, msg := range []messaging.Message{
+		{ID: "event-2", Subject: constants.EventAuthLoginSucceeded, Payload: []byte("not json")},
+		loginSucceeded("", time.Now()),
+		{ID: "event-3", Subject: constants.EventAuthLoginSucceeded, Payload: []byte(`{"data":{"email":"jane@example.com"}}`)},
+	} {
+		assert.NoError(t, consumer.HandleLoginSucceeded(context.Background(), msg), "%s is acknowledged, not redelivered", msg.Payload)
+	}
+	assert.Nil(t, lastLogin(t, userRepo))
+}
@@ -0,0 +1,32 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/user-service/contracts"
+	"github.com/Mir00r/user-service/contracts/provider"
+)
+
+// pactsDir holds the pacts recorded by the consumers, which cmd/verify-pacts replays in the image build
+const pactsDir = "../../contracts/pacts"
+
+func TestServiceHonoursTheContractsOfItsConsumers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pacts, err := contracts.LoadPacts(pactsDir)
+	require.NoError(t, err)
+	require.NotEmpty(t, pacts, "no pacts in %s", pactsDir)
+
+	for _, pact := range pacts {
+		t.Run(pact.Consumer.Name, func(t *testing.T) {
+			require.NotEmpty(t, pact.Interactions)
+			mismatches, err := provider.Verify(pact)
+			require.NoError(t, err)
+			for _, mismatch := range mismatches {
+				t.Errorf("contract broken: %v", mismatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/shared/health"
+	"github.com/Mir00r/user-service/configs"
+	"github.com/Mir00r/user-service/constants"
+	"github.com/Mir00r/user-service/internal/api/controllers"
+	"github.com/Mir00r/user-service/internal/models/entities"
+	"github.com/Mir00r/user-service/internal/repositories"
+	"github.com/Mir00r/user-service/internal/repositories/memory"
+	"github.com/Mir00r/user-service/internal/services"
+	"github.com/Mir00r/user-service/routes"
+)
+
+// janeID is the ID of the account the internal API holds
+const janeID = "3f8b2a4e-9c1d-4e7a-b5f6-2d8c0e1a7b94"
+
+// internalAPI is the router of the service over an in-memory store holding an active account of Jane
+type internalAPI struct {
+	router *gin.Engine
+	users  repositories.UserRepository
+	outbox repositories.OutboxRepository
+}
+
+func newInternalAPI(t *testing.T) *internalAPI {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	configs.AppConfig.InternalSecurity = configs.InternalSecurityConfig{UserName: "auth-service", Password: "internal-secret"}
+
+	store := memory.NewStore()
+	api := &internalAPI{users: memory.NewUserRepository(store), outbox: memory.NewOutboxRepository(store)}
+	mfaSecret := "mfa-secret"
+	_, err := api.users.CreateUser(context.Background(), &entities.User{
+		ID: janeID, Name: "Jane", Email: "jane@example.com", Password: "password-hash", Phone: "+4512345678",
+		MFAEnabled: true, MFASecret: &mfaSecret, IsActive: true,
+	})
+	require.NoError(t, err)
+
+	userService := services.NewUserService(api.users, api.outbox, memory.NewTransactor(store))
+	api.router = gin.New()
+	api.router.ContextWithFallback = true
+	routes.SetupRoutes(api.router,
+		controllers.NewPublicUserController(userService),
+		controllers.NewProtectedUserController(userService),
+		controllers.NewInternalUserController(userService),
+		controllers.NewHealthController(health.NewRegistry(0, 0)),
+		nil,
+	)
+	return api
+}
+
+// call sends a request to the internal route as auth-service and returns the status of the response
+func (api *internalAPI) call(method, path string) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.SetBasicAuth("auth-service", "internal-secret")
+	recorder := httptest.NewRecorder()
+	api.router.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+// jane returns the account of Jane as stored
+func (api *internalAPI) jane(t *testing.T) *entities.User {
+	t.Helper()
+	user, err := api.users.GetUserByID(context.Background(), janeID)
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	return user
+}
+
+// deletedEvents returns the user.deleted events waiting in the outbox
+func (api *internalAPI) deletedEvents(t *testing.T) []entities.OutboxEvent {
+	t.Helper()
+	pending, err := api.outbox.ClaimPending(context.Background(), 100, time.Nanosecond)
+	require.NoError(t, err)
+	var events []entities.OutboxEvent
+	for _, event := range pending {
+		if event.EventType == constants.EventUserDeleted {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestDeactivateUser_StopsTheAccountFromSigningIn(t *testing.T) {
+	api := newInternalAPI(t)
+
+	assert.Equal(t, http.StatusOK, api.call(http.MethodPut, "/v1/internal/user/"+janeID+"/deactivate"))
+	assert.False(t, api.jane(t).IsActive)
+	assert.Equal(t, "jane@example.com", api.jane(t).Email, "the profile is kept")
+
+	assert.Equal(t, http.StatusOK, api.call(http.MethodPut, "/v1/internal/user/"+janeID+"/deactivate"), "a retried call succeeds")
+}
+
+func TestDeactivateUser_UnknownAccount(t *testing.T) {
+	api := newInternalAPI(t)
+
+	assert.Equal(t, http.StatusNotFound, api.call(http.MethodPut, "/v1/internal/user/9d2c4b1e-0000-4000-8000-000000000000/deactivate"))
+	assert.Equal(t, http.StatusBadRequest, api.call(http.MethodPut, "/v1/internal/user/not-a-uuid/deactivate"))
+	assert.True(t, api.jane(t).IsActive)
+}
+
+func TestAnonymizeUser_RemovesThePersonalDataOnce(t *testing.T) {
+	api := newInternalAPI(t)
+
+	assert.Equal(t, http.StatusOK, api.call(http.MethodPost, "/v1/internal/user/"+janeID+"/anonymize"))
+
+	jane := api.jane(t)
+	assert.Equal(t, constants.AnonymizedUserName, jane.Name)
+	assert.Equal(t, "deleted-"+janeID+"@invalid", jane.Email)
+	assert.Empty(t, jane.Password)
+	assert.Empty(t, jane.Phone)
+	assert.False(t, jane.MFAEnabled)
+	assert.Nil(t, jane.MFASecret)
+	assert.False(t, jane.IsActive)
+	assert.NotNil(t, jane.DeletedAt)
+
+	assert.Equal(t, http.StatusOK, api.call(http.MethodPost, "/v1/internal/user/"+janeID+"/anonymize"), "a retried call succeeds")
+	events := api.deletedEvents(t)
+	require.Len(t, events, 1, "user.deleted is emitted once")
+	assert.Equal(t, janeID, events[0].AggregateID)
+	assert.Contains(t, string(events[0].Payload), "jane@example.com", "consumers learn which address was deleted")
+}
+
+func TestAnonymizeUser_UnknownAccount(t *testing.T) {
+	api := newInternalAPI(t)
+
+	assert.Equal(t, http.StatusNotFound, api.call(http.MethodPost, "/v1/internal/user/9d2c4b1e-0000-4000-8000-000000000000/anonymize"))
+	assert.Empty(t, api.deletedEvents(t))
+}
+
+func TestInternalRoutes_RequireTheCredentialsOfAuthService(t *testing.T) {
+	api := newInternalAPI(t)
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPut, "/v1/internal/user/" + janeID + "/deactivate"},
+		{http.MethodPost, "/v1/internal/user/" + janeID + "/anonymize"},
+	} {
+		recorder := httptest.NewRecorder()
+		api.router.ServeHTTP(recorder, httptest.NewRequest(route.method, route.path, nil))
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code, route.path)
+	}
+	assert.True(t, api.jane(t).IsActive)
+	assert.Equal(t, "Jane", api.jane(t).Name)
+}
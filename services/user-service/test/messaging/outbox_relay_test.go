@@ -0,0 +1,118 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Mir00r/user-service/internal/models/entities"
+	"github.com/Mir00r/user-service/internal/repositories"
+	"github.com/Mir00r/user-service/internal/repositories/memory"
+	"github.com/Mir00r/user-service/messaging"
+)
+
+// fakeBroker records the published messages and fails the subjects it is told to
+type fakeBroker struct {
+	mu        sync.Mutex
+	published []messaging.Message
+	failing   map[string]bool
+}
+
+func (b *fakeBroker) Publish(_ context.Context, msg messaging.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failing[msg.Subject] {
+		return errors.New("broker unavailable")
+	}
+	b.published = append(b.published, msg)
+	return nil
+}
+
+func (b *fakeBroker) Close() error {
+	return nil
+}
+
+// enqueue stores an event of the type in the outbox and returns it
+func enqueue(t *testing.T, outbox repositories.OutboxRepository, eventType string) *entities.OutboxEvent {
+	t.Helper()
+	event, err := messaging.NewOutboxEvent(eventType, "user-1", map[string]string{"userId": "user-1"})
+	require.NoError(t, err)
+	require.NoError(t, outbox.Enqueue(context.Background(), event))
+	return event
+}
+
+func TestRelay_PublishesEachEventOnce(t *testing.T) {
+	outbox := memory.NewOutboxRepository(memory.NewStore())
+	created := enqueue(t, outbox, "user.created")
+	deleted := enqueue(t, outbox, "user.deleted")
+	broker := &fakeBroker{}
+	relay := messaging.NewRelay(outbox, broker, messaging.RelayConfig{})
+
+	claimed, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, claimed)
+
+	claimed, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, claimed, "published events are not claimed again")
+
+	require.Len(t, broker.published, 2)
+	assert.Equal(t, created.ID, broker.published[0].ID)
+	assert.Equal(t, "user.created", broker.published[0].Subject)
+	assert.JSONEq(t, string(created.Payload), string(broker.published[0].Payload))
+	assert.Equal(t, deleted.ID, broker.published[1].ID)
+}
+
+func TestRelay_ReschedulesEventsTheBrokerRefused(t *testing.T) {
+	outbox := memory.NewOutboxRepository(memory.NewStore())
+	enqueue(t, outbox, "user.created")
+	enqueue(t, outbox, "user.deleted")
+	broker := &fakeBroker{failing: map[string]bool{"user.deleted": true}}
+	relay := messaging.NewRelay(outbox, broker, messaging.RelayConfig{})
+
+	claimed, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, claimed)
+	require.Len(t, broker.published, 1)
+	assert.Equal(t, "user.created", broker.published[0].Subject)
+
+	// The refused event waits for its backoff instead of being claimed again right away
+	claimed, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, claimed)
+
+	pending, err := outbox.ClaimPending(context.Background(), 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestRelay_RunStopsWithItsContext(t *testing.T) {
+	outbox := memory.NewOutboxRepository(memory.NewStore())
+	enqueue(t, outbox, "user.created")
+	broker := &fakeBroker{}
+	relay := messaging.NewRelay(outbox, broker, messaging.RelayConfig{PollInterval: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return len(broker.published) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the relay kept running after its context was cancelled")
+	}
+}
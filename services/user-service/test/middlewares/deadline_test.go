@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Mir00r/user-service/constants"
+	"github.com/Mir00r/user-service/middlewares"
+)
+
+// serveWithDeadline serves a request carrying the timeout header, unless empty, and returns the status and the
+// deadline the handler saw
+func serveWithDeadline(t *testing.T, timeout string) (int, time.Time, bool) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middlewares.ErrorHandler(), middlewares.Deadline())
+
+	var deadline time.Time
+	var hasDeadline, served bool
+	router.GET("/users", func(c *gin.Context) {
+		served = true
+		deadline, hasDeadline = c.Request.Context().Deadline()
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	if timeout != "" {
+		req.Header.Set(constants.RequestTimeoutHeader, timeout)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code == http.StatusOK {
+		assert.True(t, served)
+	} else {
+		assert.False(t, served, "a refused request does no work")
+	}
+	return w.Code, deadline, hasDeadline
+}
+
+func TestDeadline_BoundsTheRequestByTheCallersTimeout(t *testing.T) {
+	start := time.Now()
+
+	code, deadline, ok := serveWithDeadline(t, "1500")
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, ok)
+	assert.WithinDuration(t, start.Add(1500*time.Millisecond), deadline, 500*time.Millisecond)
+}
+
+func TestDeadline_RefusesARequestTheCallerGaveUpOn(t *testing.T) {
+	code, _, _ := serveWithDeadline(t, "0")
+
+	assert.Equal(t, http.StatusGatewayTimeout, code)
+}
+
+func TestDeadline_IgnoresAMissingOrMalformedHeader(t *testing.T) {
+	for _, timeout := range []string{"", "soon"} {
+		code, _, ok := serveWithDeadline(t, timeout)
+
+		assert.Equal(t, http.StatusOK, code, timeout)
+		assert.False(t, ok, "no deadline for %q", timeout)
+	}
+}
@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Mir00r/user-service/configs"
+	"github.com/Mir00r/user-service/constants"
+	"github.com/Mir00r/user-service/middlewares"
+)
+
+var info = &grpc.UnaryServerInfo{FullMethod: "/user.v1.InternalUserService/ValidateCredentials"}
+
+// call runs the interceptor around a handler and reports whether the handler ran
+func call(ctx context.Context, interceptor grpc.UnaryServerInterceptor) (bool, error) {
+	served := false
+	_, err := interceptor(ctx, nil, info, func(context.Context, interface{}) (interface{}, error) {
+		served = true
+		return "ok", nil
+	})
+	return served, err
+}
+
+// withAuthorization returns a context carrying the authorization metadata of an incoming call
+func withAuthorization(authorization string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(constants.Authorization, authorization))
+}
+
+func basic(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+func TestGRPCBasicAuth(t *testing.T) {
+	configs.AppConfig.InternalSecurity = configs.InternalSecurityConfig{UserName: "auth-service", Password: "internal-secret"}
+
+	cases := map[string]struct {
+		ctx    context.Context
+		served bool
+	}{
+		"internal credentials": {withAuthorization(basic("auth-service", "internal-secret")), true},
+		"wrong password":       {withAuthorization(basic("auth-service", "guess")), false},
+		"other scheme":         {withAuthorization("Bearer internal-secret"), false},
+		"malformed":            {withAuthorization("Basic !!!"), false},
+		"no metadata":          {context.Background(), false},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			served, err := call(c.ctx, middlewares.GRPCBasicAuth())
+
+			assert.Equal(t, c.served, served)
+			if c.served {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+}
+
+func TestGRPCDeadline_RefusesACallPastItsDeadline(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Millisecond))
+	defer cancel()
+
+	served, err := call(ctx, middlewares.GRPCDeadline())
+
+	assert.False(t, served)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestGRPCDeadline_ServesACallWithinItsDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	served, err := call(ctx, middlewares.GRPCDeadline())
+
+	require.NoError(t, err)
+	assert.True(t, served)
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/Mir00r/user-service/configs"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"log"
 	"net/http/httptest"
 	"regexp"
 	"strings"
@@ -30,22 +29,9 @@ func ExtractUserIDFromContext(c *gin.Context) (string, error) {
 	return userIDStr, nil
 }
 
+// TokenExpiry returns the configured lifetime of an access token
 func TokenExpiry() time.Duration {
-	// Parse the "24h" string into a time.Duration
-	duration, err := time.ParseDuration(configs.AppConfig.JWT.Expiry)
-	if err != nil {
-		log.Fatalf("Failed to parse JWT expiry duration: %v", err)
-	}
-	return duration
-}
-
-func ConvertTokenExpiry(expiry string) time.Duration {
-	// Parse the "24h" string into a time.Duration
-	duration, err := time.ParseDuration(expiry)
-	if err != nil {
-		log.Fatalf("Failed to parse JWT expiry duration: %v", err)
-	}
-	return duration
+	return configs.AppConfig.JWT.Expiry.Duration()
 }
 
 // CreateTestContext initializes a mock Gin context for testing
@@ -7,7 +7,6 @@ import (
 	"errors"
 	"github.com/Mir00r/user-service/configs"
 	"github.com/golang-jwt/jwt/v4"
-	"log"
 	"os"
 	"time"
 )
@@ -21,7 +20,6 @@ type JWTClaims struct {
 
 // GenerateJWT generates a new JWT token with the provided user ID and email.
 func GenerateJWT(userID, email, secret string, expiry time.Duration) (string, error) {
-	log.Printf("JWTSecret While Generating Token: %v", secret)
 	claims := JWTClaims{
 		UserID: userID,
 		Email:  email,
@@ -36,7 +34,6 @@ func GenerateJWT(userID, email, secret string, expiry time.Duration) (string, er
 	if err != nil {
 		return "", err
 	}
-	log.Printf("Generated Token: %s", signedToken)
 	return signedToken, nil
 }
 
@@ -44,11 +41,7 @@ func GenerateJWT(userID, email, secret string, expiry time.Duration) (string, er
 func VerifyJWT(tokenString string) (*JWTClaims, error) {
 	//configs.LoadConfig()
 
-	// Debug log to verify JWTSecret
-	log.Printf("Received Token: %s", tokenString)
-	log.Printf("JWTSecret in VerifyJWT: %s", configs.AppConfig.JWT.Secret)
 	secret := []byte(configs.AppConfig.JWT.Secret)
-	log.Printf("Using JWT Secret After Conevrtion Byte Data Type: %s", secret)
 
 	claims := &JWTClaims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -62,7 +55,6 @@ func VerifyJWT(tokenString string) (*JWTClaims, error) {
 	})
 
 	if err != nil {
-		log.Printf("JWT Parsing Error: %v", err)
 		return nil, err
 	}
 